@@ -104,9 +104,14 @@ func generateDirectoryStructure() string {
 	buf.WriteString("│       └── <worker-name>/  # Worker worktrees\n")
 	buf.WriteString("│\n")
 	buf.WriteString("├── messages/           # Inter-agent messages\n")
-	buf.WriteString("│   └── <repo-name>/\n")
-	buf.WriteString("│       └── <agent-name>/\n")
-	buf.WriteString("│           └── msg-<uuid>.json\n")
+	buf.WriteString("│   ├── <repo-name>/\n")
+	buf.WriteString("│   │   └── <agent-name>/\n")
+	buf.WriteString("│   │       └── msg-<uuid>.json\n")
+	buf.WriteString("│   └── .dead-letters/  # Undeliverable messages\n")
+	buf.WriteString("│       └── <repo-name>/\n")
+	buf.WriteString("│\n")
+	buf.WriteString("├── snapshots/          # Rotating state snapshots\n")
+	buf.WriteString("│   └── <id>/           # state.json, messages/, snapshot.json\n")
 	buf.WriteString("│\n")
 	buf.WriteString("└── prompts/            # Generated agent prompts\n")
 	buf.WriteString("    └── <agent-name>.md\n")
@@ -179,7 +184,10 @@ func generateDirectoryStructure() string {
   "timestamp": "2025-01-01T00:00:00Z",
   "body": "Please review PR #42",
   "status": "pending",
-  "acked_at": null
+  "acked_at": null,
+  "thread_id": "msg-abc123def456",
+  "priority": "normal",
+  "subject": "PR #42"
 }
 `)
 	buf.WriteString("```\n\n")
@@ -224,6 +232,8 @@ func generateDirectoryStructure() string {
 	buf.WriteString("ls ~/.multiclaude/messages/my-repo/supervisor/\n\n")
 	buf.WriteString("# Read a specific message\n")
 	buf.WriteString("cat ~/.multiclaude/messages/my-repo/supervisor/msg-*.json | jq .\n")
+	buf.WriteString("\n# Messages that could not be delivered\n")
+	buf.WriteString("multiclaude agent dead-letters --repo my-repo\n")
 	buf.WriteString("```\n\n")
 
 	buf.WriteString("### Clean up stale state\n\n")
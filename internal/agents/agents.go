@@ -15,9 +15,12 @@ type Definition struct {
 	// Name is the agent name, derived from the filename (without .md extension)
 	Name string
 
-	// Content is the full markdown content of the agent definition
+	// Content is the markdown content of the agent definition, without its frontmatter
 	Content string
 
+	// Metadata is parsed from the optional YAML frontmatter
+	Metadata Metadata
+
 	// SourcePath is the absolute path to the source file
 	SourcePath string
 
@@ -167,9 +170,15 @@ func readDefinitionsFromDir(dir string, source DefinitionSource) ([]Definition,
 		// Extract name from filename (without .md extension)
 		name := strings.TrimSuffix(entry.Name(), ".md")
 
+		meta, body, err := ParseFrontmatter(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+
 		definitions = append(definitions, Definition{
 			Name:       name,
-			Content:    string(content),
+			Content:    body,
+			Metadata:   meta,
 			SourcePath: filePath,
 			Source:     source,
 		})
@@ -178,6 +187,16 @@ func readDefinitionsFromDir(dir string, source DefinitionSource) ([]Definition,
 	return definitions, nil
 }
 
+// FindDefinition returns the definition with the given name
+func FindDefinition(definitions []Definition, name string) (Definition, bool) {
+	for _, def := range definitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
 // ParseTitle extracts the title from a markdown definition.
 // It looks for the first H1 heading (# Title) in the content.
 // Returns the name as-is if no H1 heading is found.
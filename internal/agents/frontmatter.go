@@ -0,0 +1,146 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Agent types a definition can declare in its frontmatter
+const (
+	// TypePersistent agents are long-running and restarted when they exit
+	TypePersistent = "persistent"
+
+	// TypeTransient agents work on a task and are cleaned up when done
+	TypeTransient = "transient"
+)
+
+// Metadata is the optional YAML frontmatter at the top of an agent definition:
+//
+//	---
+//	type: persistent
+//	model: opus
+//	wake_message: "Status check: any new issues to triage?"
+//	wake_interval: 10m
+//	auto_start: true
+//	max_instances: 1
+//	required_tools: [gh]
+//	---
+type Metadata struct {
+	// Type is "persistent" or "transient". Empty leaves the choice to whoever
+	// spawns the agent.
+	Type string `yaml:"type,omitempty"`
+
+	// Model is the Claude model the agent runs on
+	Model string `yaml:"model,omitempty"`
+
+	// WakeMessage replaces the status-check message the daemon nudges the agent with
+	WakeMessage string `yaml:"wake_message,omitempty"`
+
+	// WakeInterval is the minimum time between nudges in whole minutes; 0 disables them
+	WakeInterval *time.Duration `yaml:"wake_interval,omitempty"`
+
+	// AutoStart starts the agent on `repo init` and whenever the daemon
+	// restores the repository. Only valid for persistent agents.
+	AutoStart bool `yaml:"auto_start,omitempty"`
+
+	// MaxInstances limits how many agents can run from this definition at once; 0 means no limit
+	MaxInstances int `yaml:"max_instances,omitempty"`
+
+	// RequiredTools are commands that must be in PATH for the agent to be spawned
+	RequiredTools []string `yaml:"required_tools,omitempty"`
+}
+
+// IsZero returns true if no metadata is set
+func (m Metadata) IsZero() bool {
+	return m.Type == "" && m.Model == "" && m.WakeMessage == "" && m.WakeInterval == nil &&
+		!m.AutoStart && m.MaxInstances == 0 && len(m.RequiredTools) == 0
+}
+
+// Validate checks that the metadata values are consistent
+func (m Metadata) Validate() error {
+	switch m.Type {
+	case "", TypePersistent, TypeTransient:
+	default:
+		return fmt.Errorf("invalid type %q (must be %q or %q)", m.Type, TypePersistent, TypeTransient)
+	}
+	if m.AutoStart && m.Type != TypePersistent {
+		return fmt.Errorf("auto_start requires type: %s", TypePersistent)
+	}
+	if m.MaxInstances < 0 {
+		return fmt.Errorf("max_instances must not be negative")
+	}
+	if m.WakeInterval != nil {
+		if *m.WakeInterval < 0 || *m.WakeInterval%time.Minute != 0 {
+			return fmt.Errorf("wake_interval must be a whole number of minutes, got %s", *m.WakeInterval)
+		}
+	}
+	for _, tool := range m.RequiredTools {
+		if strings.TrimSpace(tool) == "" {
+			return fmt.Errorf("required_tools must not contain empty names")
+		}
+	}
+	return nil
+}
+
+// WakeIntervalMinutes returns the wake interval in minutes and whether one is set
+func (m Metadata) WakeIntervalMinutes() (int, bool) {
+	if m.WakeInterval == nil {
+		return 0, false
+	}
+	return int(*m.WakeInterval / time.Minute), true
+}
+
+// MissingTools returns the required tools that can't be found in PATH
+func (m Metadata) MissingTools() []string {
+	var missing []string
+	for _, tool := range m.RequiredTools {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+// ParseFrontmatter splits a definition into its frontmatter metadata and
+// markdown body. Content without frontmatter is returned unchanged with
+// empty metadata. Unknown keys are rejected so typos don't go unnoticed.
+func ParseFrontmatter(content string) (Metadata, string, error) {
+	var meta Metadata
+
+	lines := strings.SplitAfter(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if strings.TrimRight(lines[0], "\n") != "---" {
+		return meta, content, nil
+	}
+
+	closing := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\n") == "---" {
+			closing = i
+			break
+		}
+	}
+	if closing == -1 {
+		return meta, "", fmt.Errorf("frontmatter is missing its closing ---")
+	}
+	header := strings.Join(lines[1:closing], "")
+	body := strings.Join(lines[closing+1:], "")
+
+	if strings.TrimSpace(header) != "" {
+		dec := yaml.NewDecoder(strings.NewReader(header))
+		dec.KnownFields(true)
+		if err := dec.Decode(&meta); err != nil && !errors.Is(err, io.EOF) {
+			return Metadata{}, "", fmt.Errorf("invalid frontmatter: %w", err)
+		}
+	}
+	if err := meta.Validate(); err != nil {
+		return Metadata{}, "", fmt.Errorf("invalid frontmatter: %w", err)
+	}
+
+	return meta, strings.TrimLeft(body, "\n"), nil
+}
@@ -0,0 +1,150 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFrontmatter(t *testing.T) {
+	content := `---
+type: persistent
+model: opus
+wake_message: "Status check: any new issues to triage?"
+wake_interval: 10m
+auto_start: true
+max_instances: 1
+required_tools: [gh, git]
+---
+
+# Issue Triager
+
+Triage incoming issues.
+`
+	meta, body, err := ParseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("ParseFrontmatter failed: %v", err)
+	}
+
+	interval := 10 * time.Minute
+	want := Metadata{
+		Type:          TypePersistent,
+		Model:         "opus",
+		WakeMessage:   "Status check: any new issues to triage?",
+		WakeInterval:  &interval,
+		AutoStart:     true,
+		MaxInstances:  1,
+		RequiredTools: []string{"gh", "git"},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("metadata = %+v, want %+v", meta, want)
+	}
+	if !strings.HasPrefix(body, "# Issue Triager") {
+		t.Errorf("body should start with the title, got %q", body)
+	}
+	if minutes, ok := meta.WakeIntervalMinutes(); !ok || minutes != 10 {
+		t.Errorf("WakeIntervalMinutes() = %d, %v, want 10, true", minutes, ok)
+	}
+}
+
+func TestParseFrontmatterWithoutFrontmatter(t *testing.T) {
+	for _, content := range []string{"# Worker\n\nDo work.\n", "", "--- not frontmatter\n"} {
+		meta, body, err := ParseFrontmatter(content)
+		if err != nil {
+			t.Errorf("ParseFrontmatter(%q) failed: %v", content, err)
+		}
+		if !meta.IsZero() {
+			t.Errorf("ParseFrontmatter(%q) metadata = %+v, want zero", content, meta)
+		}
+		if body != content {
+			t.Errorf("ParseFrontmatter(%q) body = %q, want content unchanged", content, body)
+		}
+	}
+
+	// An empty header is allowed
+	meta, body, err := ParseFrontmatter("---\n---\n# Worker\n")
+	if err != nil || !meta.IsZero() || body != "# Worker\n" {
+		t.Errorf("empty frontmatter: meta = %+v, body = %q, err = %v", meta, body, err)
+	}
+
+	// A zero wake interval disables nudges rather than falling back to the default
+	meta, _, err = ParseFrontmatter("---\nwake_interval: 0s\n---\n")
+	if err != nil {
+		t.Fatalf("ParseFrontmatter failed: %v", err)
+	}
+	if minutes, ok := meta.WakeIntervalMinutes(); !ok || minutes != 0 {
+		t.Errorf("WakeIntervalMinutes() = %d, %v, want 0, true", minutes, ok)
+	}
+}
+
+func TestParseFrontmatterInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unclosed", "---\ntype: persistent\n# Agent\n", "closing ---"},
+		{"unknown key", "---\nmodle: opus\n---\n", "modle"},
+		{"invalid type", "---\ntype: forever\n---\n", "invalid type"},
+		{"auto start transient", "---\ntype: transient\nauto_start: true\n---\n", "auto_start requires"},
+		{"negative max instances", "---\nmax_instances: -1\n---\n", "max_instances"},
+		{"partial minutes", "---\nwake_interval: 90s\n---\n", "whole number of minutes"},
+		{"bad duration", "---\nwake_interval: soon\n---\n", "invalid frontmatter"},
+		{"empty tool", "---\nrequired_tools: [\"\"]\n---\n", "required_tools"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseFrontmatter(tt.content)
+			if err == nil {
+				t.Fatal("ParseFrontmatter should fail")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingTools(t *testing.T) {
+	meta := Metadata{RequiredTools: []string{"go", "multiclaude-no-such-tool"}}
+	missing := meta.MissingTools()
+	if len(missing) != 1 || missing[0] != "multiclaude-no-such-tool" {
+		t.Errorf("MissingTools() = %v, want [multiclaude-no-such-tool]", missing)
+	}
+}
+
+func TestReaderParsesFrontmatter(t *testing.T) {
+	localDir := t.TempDir()
+	content := "---\ntype: transient\nmodel: haiku\n---\n# Doc Writer\n\nWrites docs.\n"
+	if err := os.WriteFile(filepath.Join(localDir, "doc-writer.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := NewReader(localDir, "").ReadAllDefinitions()
+	if err != nil {
+		t.Fatalf("ReadAllDefinitions failed: %v", err)
+	}
+	def, ok := FindDefinition(defs, "doc-writer")
+	if !ok {
+		t.Fatal("doc-writer definition not found")
+	}
+	if def.Metadata.Type != TypeTransient || def.Metadata.Model != "haiku" {
+		t.Errorf("metadata = %+v", def.Metadata)
+	}
+	if def.ParseTitle() != "Doc Writer" || def.ParseDescription() != "Writes docs." {
+		t.Errorf("title/description not parsed from body: %q / %q", def.ParseTitle(), def.ParseDescription())
+	}
+
+	// A definition with invalid frontmatter is reported with its path
+	if err := os.WriteFile(filepath.Join(localDir, "broken.md"), []byte("---\ntype: forever\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewReader(localDir, "").ReadAllDefinitions()
+	if err == nil || !strings.Contains(err.Error(), "broken.md") {
+		t.Errorf("ReadAllDefinitions error = %v, want it to name broken.md", err)
+	}
+}
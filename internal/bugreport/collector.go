@@ -157,7 +157,7 @@ func (c *Collector) checkDaemonStatus() (bool, int) {
 
 // collectAgentStats loads state and counts agents
 func (c *Collector) collectAgentStats(report *Report) error {
-	st, err := state.Load(c.paths.StateFile)
+	st, err := state.LoadView(c.paths.StateFile)
 	if err != nil {
 		return err
 	}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,18 +20,22 @@ import (
 	"github.com/dlorenc/multiclaude/internal/daemon"
 	"github.com/dlorenc/multiclaude/internal/diagnostics"
 	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/forge"
 	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/github"
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/messages"
-	"github.com/dlorenc/multiclaude/internal/names"
 	"github.com/dlorenc/multiclaude/internal/prompts"
-	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
 	"github.com/dlorenc/multiclaude/internal/state"
 	"github.com/dlorenc/multiclaude/internal/templates"
 	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/client"
 	"github.com/dlorenc/multiclaude/pkg/config"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
+	"github.com/fatih/color"
 )
 
 // Version is the current version of multiclaude (set at build time via ldflags)
@@ -138,30 +144,54 @@ func (c *CLI) getClaudeBinary() (string, error) {
 	return binaryPath, nil
 }
 
-// loadState loads the state file, wrapping errors with context
-func (c *CLI) loadState() (*state.State, error) {
-	st, err := state.Load(c.paths.StateFile)
+// stateLockTimeout is how long commands wait for the state file's lock when
+// changing state directly while the daemon is down
+const stateLockTimeout = 10 * time.Second
+
+// loadState loads a read-only view of the state file, wrapping errors with
+// context. Changes go through the daemon, or updateState when it's down.
+func (c *CLI) loadState() (*state.View, error) {
+	st, err := state.LoadView(c.paths.StateFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 	return st, nil
 }
 
-// sendDaemonRequest sends a request to the daemon and handles common error cases.
-// It returns the response if successful, or an error if communication fails or the daemon returns an error.
-func (c *CLI) sendDaemonRequest(command string, args map[string]interface{}) (*socket.Response, error) {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: command,
-		Args:    args,
-	})
-	if err != nil {
-		return nil, errors.DaemonCommunicationFailed(command, err)
+// updateState changes the state file directly, under its lock. Only use it
+// when the daemon isn't running; while it runs the daemon holds the lock.
+func (c *CLI) updateState(fn func(*state.State) error) error {
+	if err := state.Update(c.paths.StateFile, stateLockTimeout, fn); err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+	return nil
+}
+
+// daemonClient returns a client for the daemon's socket API
+func (c *CLI) daemonClient() *client.Client {
+	return client.New(c.paths.DaemonSock)
+}
+
+// daemonError converts an error from a daemon request into a CLI error. If
+// the daemon rejected the request, its message is reported as the cause of
+// failure; otherwise the daemon couldn't be reached while doing operation.
+func daemonError(err error, operation, failure string) error {
+	if reqErr, ok := err.(*client.RequestError); ok {
+		return errors.Wrap(errors.CategoryRuntime, failure, reqErr)
 	}
-	if !resp.Success {
-		return nil, fmt.Errorf("%s failed: %s", command, resp.Error)
+	return errors.DaemonCommunicationFailed(operation, err)
+}
+
+// pingDaemon checks that the daemon is running and speaks a protocol version
+// this CLI understands
+func (c *CLI) pingDaemon(daemonClient *client.Client) error {
+	if _, err := daemonClient.Ping(); err != nil {
+		if versionErr, ok := err.(*client.VersionError); ok {
+			return errors.DaemonVersionMismatch(versionErr)
+		}
+		return errors.DaemonNotRunning()
 	}
-	return resp, nil
+	return nil
 }
 
 // removeDirectoryIfExists removes a directory and prints status messages.
@@ -425,7 +455,7 @@ func (c *CLI) registerCommands() {
 	workCmd := &Command{
 		Name:        "work",
 		Description: "Manage worker agents",
-		Usage:       "multiclaude work [<task>] [--repo <repo>] [--branch <branch>] [--push-to <branch>]",
+		Usage:       "multiclaude work [<task>] [--repo <repo>] [--base <branch>] [--branch <branch>] [--push-to <branch>] [--queue] [--after <worker-or-task-id>] [--model <model>] [--claude-args <args>] [--claude-env NAME=value,...]",
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -445,6 +475,30 @@ func (c *CLI) registerCommands() {
 		Run:         c.removeWorker,
 	}
 
+	queueCmd := &Command{
+		Name:        "queue",
+		Description: "List tasks waiting for a free worker slot",
+		Usage:       "multiclaude work queue [--repo <repo>]",
+		Run:         c.listTaskQueue,
+		Subcommands: make(map[string]*Command),
+	}
+
+	queueCmd.Subcommands["move"] = &Command{
+		Name:        "move",
+		Description: "Move a queued task to a new position",
+		Usage:       "multiclaude work queue move <task-id> <position> [--repo <repo>]",
+		Run:         c.moveQueuedTask,
+	}
+
+	queueCmd.Subcommands["cancel"] = &Command{
+		Name:        "cancel",
+		Description: "Remove a task from the queue",
+		Usage:       "multiclaude work queue cancel <task-id> [--repo <repo>]",
+		Run:         c.cancelQueuedTask,
+	}
+
+	workCmd.Subcommands["queue"] = queueCmd
+
 	c.rootCmd.Subcommands["work"] = workCmd
 
 	// Workspace commands
@@ -499,7 +553,7 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["sync"] = &Command{
 		Name:        "sync",
 		Description: "Sync fork with upstream",
-		Usage:       "multiclaude sync [--repo <repo>]",
+		Usage:       "multiclaude sync [--repo <repo>] [--strategy=merge|rebase]",
 		Run:         c.syncRepo,
 	}
 
@@ -521,14 +575,14 @@ func (c *CLI) registerCommands() {
 	agentCmd.Subcommands["send-message"] = &Command{
 		Name:        "send-message",
 		Description: "Send a message to another agent",
-		Usage:       "multiclaude agent send-message <recipient> <message>",
+		Usage:       "multiclaude agent send-message <recipient|@all|@workers|@persistent|glob> <message> [--reply-to <message-id>] [--priority urgent|normal|low] [--subject <text>] [--ttl <duration>]",
 		Run:         c.sendMessage,
 	}
 
 	agentCmd.Subcommands["list-messages"] = &Command{
 		Name:        "list-messages",
 		Description: "List pending messages",
-		Usage:       "multiclaude agent list-messages",
+		Usage:       "multiclaude agent list-messages [--threads]",
 		Run:         c.listMessages,
 	}
 
@@ -546,6 +600,37 @@ func (c *CLI) registerCommands() {
 		Run:         c.ackMessage,
 	}
 
+	deadLettersCmd := &Command{
+		Name:        "dead-letters",
+		Description: "List messages that could not be delivered",
+		Usage:       "multiclaude agent dead-letters [--repo <repo>]",
+		Run:         c.listDeadLetters,
+		Subcommands: make(map[string]*Command),
+	}
+
+	deadLettersCmd.Subcommands["show"] = &Command{
+		Name:        "show",
+		Description: "Show a dead letter and why it was not delivered",
+		Usage:       "multiclaude agent dead-letters show <message-id> [--repo <repo>]",
+		Run:         c.showDeadLetter,
+	}
+
+	deadLettersCmd.Subcommands["resend"] = &Command{
+		Name:        "resend",
+		Description: "Re-send a dead letter to its recipient or another agent",
+		Usage:       "multiclaude agent dead-letters resend <message-id> [--to <agent>] [--repo <repo>]",
+		Run:         c.resendDeadLetter,
+	}
+
+	deadLettersCmd.Subcommands["purge"] = &Command{
+		Name:        "purge",
+		Description: "Permanently delete a dead letter",
+		Usage:       "multiclaude agent dead-letters purge <message-id> [--repo <repo>]",
+		Run:         c.purgeDeadLetter,
+	}
+
+	agentCmd.Subcommands["dead-letters"] = deadLettersCmd
+
 	agentCmd.Subcommands["complete"] = &Command{
 		Name:        "complete",
 		Description: "Signal worker completion",
@@ -556,7 +641,7 @@ func (c *CLI) registerCommands() {
 	agentCmd.Subcommands["restart"] = &Command{
 		Name:        "restart",
 		Description: "Restart a crashed or exited agent",
-		Usage:       "multiclaude agent restart <name> [--repo <repo>] [--force]",
+		Usage:       "multiclaude agent restart <name> [--repo <repo>] [--force] [--reset]",
 		Run:         c.restartAgentCmd,
 	}
 
@@ -581,10 +666,39 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["repair"] = &Command{
 		Name:        "repair",
 		Description: "Repair state after crash",
-		Usage:       "multiclaude repair [--verbose]",
+		Usage:       "multiclaude repair [--verbose] [--migrate-only]",
 		Run:         c.repair,
 	}
 
+	stateCmd := &Command{
+		Name:        "state",
+		Description: "Inspect and restore state snapshots",
+		Subcommands: make(map[string]*Command),
+	}
+
+	stateCmd.Subcommands["snapshots"] = &Command{
+		Name:        "snapshots",
+		Description: "List the daemon's snapshots of state and messages",
+		Usage:       "multiclaude state snapshots",
+		Run:         c.listSnapshots,
+	}
+
+	stateCmd.Subcommands["diff"] = &Command{
+		Name:        "diff",
+		Description: "Show what restoring a snapshot would change",
+		Usage:       "multiclaude state diff <id>",
+		Run:         c.diffSnapshot,
+	}
+
+	stateCmd.Subcommands["restore"] = &Command{
+		Name:        "restore",
+		Description: "Restore state and messages from a snapshot",
+		Usage:       "multiclaude state restore <id> [--yes]",
+		Run:         c.restoreSnapshot,
+	}
+
+	c.rootCmd.Subcommands["state"] = stateCmd
+
 	// Claude restart command - for resuming Claude after exit
 	c.rootCmd.Subcommands["claude"] = &Command{
 		Name:        "claude",
@@ -605,10 +719,68 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["review"] = &Command{
 		Name:        "review",
 		Description: "Spawn a review agent for a PR",
-		Usage:       "multiclaude review <pr-url>",
+		Usage:       "multiclaude review <pr-number|pr-url> [--repo <repo>]",
 		Run:         c.reviewPR,
 	}
 
+	// PR commands
+	prCmd := &Command{
+		Name:        "pr",
+		Description: "Create, review and merge PRs on GitHub or the local forge",
+		Subcommands: make(map[string]*Command),
+	}
+
+	prCmd.Subcommands["create"] = &Command{
+		Name:        "create",
+		Description: "Open a PR for the current branch",
+		Usage:       "multiclaude pr create [--title <title>] [--body <body>] [--base <branch>] [--head <branch>] [--repo <repo>]",
+		Run:         c.createPR,
+	}
+
+	prCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List PRs with their CI and review status",
+		Usage:       "multiclaude pr list [--state open|merged|closed|all] [--repo <repo>]",
+		Run:         c.listPRs,
+	}
+
+	prCmd.Subcommands["view"] = &Command{
+		Name:        "view",
+		Description: "Show a PR with its checks and reviews",
+		Usage:       "multiclaude pr view <number> [--repo <repo>]",
+		Run:         c.viewPR,
+	}
+
+	prCmd.Subcommands["diff"] = &Command{
+		Name:        "diff",
+		Description: "Show the changes a PR makes",
+		Usage:       "multiclaude pr diff <number> [--repo <repo>]",
+		Run:         c.diffPR,
+	}
+
+	prCmd.Subcommands["review"] = &Command{
+		Name:        "review",
+		Description: "Approve, request changes on or comment on a PR",
+		Usage:       "multiclaude pr review <number> --approve|--request-changes|--comment [--body <text>] [--repo <repo>]",
+		Run:         c.submitPRReview,
+	}
+
+	prCmd.Subcommands["check"] = &Command{
+		Name:        "check",
+		Description: "Report a check result on a PR or branch",
+		Usage:       "multiclaude pr check <number|branch> --name <check> --state success|failure|error|pending [--url <url>] [--repo <repo>]",
+		Run:         c.setPRCheck,
+	}
+
+	prCmd.Subcommands["merge"] = &Command{
+		Name:        "merge",
+		Description: "Merge a PR into its base branch",
+		Usage:       "multiclaude pr merge <number> [--squash] [--delete-branch] [--repo <repo>]",
+		Run:         c.mergePR,
+	}
+
+	c.rootCmd.Subcommands["pr"] = prCmd
+
 	// Logs commands
 	logsCmd := &Command{
 		Name:        "logs",
@@ -642,11 +814,27 @@ func (c *CLI) registerCommands() {
 
 	c.rootCmd.Subcommands["logs"] = logsCmd
 
+	// Events command
+	c.rootCmd.Subcommands["events"] = &Command{
+		Name:        "events",
+		Description: "Show the daemon's event journal",
+		Usage:       "multiclaude events [--repo <repo>] [--agent <name>] [--type <type>[,<type>...]] [--since <duration|time>] [-n <count>] [--follow] [--json]",
+		Run:         c.showEvents,
+	}
+
+	// Watch command
+	c.rootCmd.Subcommands["watch"] = &Command{
+		Name:        "watch",
+		Description: "Stream live events and state changes from the daemon",
+		Usage:       "multiclaude watch [--repo <repo>] [--agent <name>] [--type <type>[,<type>...]] [--json]",
+		Run:         c.watchEvents,
+	}
+
 	// Config command
 	c.rootCmd.Subcommands["config"] = &Command{
 		Name:        "config",
 		Description: "View or modify repository configuration",
-		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned]",
+		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned] [--idle-minutes=N] [--stuck-minutes=N] [--escalate-stuck=true|false] [--max-workers=N] [--default-branch=BRANCH] [--resolve-conflicts=true|false] [--sync-strategy=merge|rebase|off] [--wake-type=TYPE --wake-interval=N --wake-quiet-hours=HH:MM-HH:MM|none --wake-only-when=idle,unread_messages,pr_activity|none --wake-message=TEXT] [--claude-type=TYPE --model=MODEL|none --claude-args=ARGS|none --claude-env=NAME=value,...|none]",
 		Run:         c.configRepo,
 	}
 
@@ -690,8 +878,8 @@ func (c *CLI) registerCommands() {
 
 	agentsCmd.Subcommands["spawn"] = &Command{
 		Name:        "spawn",
-		Description: "Spawn an agent from a prompt file",
-		Usage:       "multiclaude agents spawn --name <name> --class <class> --prompt-file <file> [--repo <repo>] [--task <task>]",
+		Description: "Spawn an agent from a definition or a prompt file",
+		Usage:       "multiclaude agents spawn (--definition <name> | --prompt-file <file>) [--name <name>] [--class <class>] [--repo <repo>] [--task <task>] [--model <model>] [--claude-args <args>] [--claude-env NAME=value,...]",
 		Run:         c.spawnAgentFromFile,
 	}
 
@@ -716,9 +904,8 @@ func (c *CLI) runDaemon(args []string) error {
 }
 
 func (c *CLI) stopDaemon(args []string) error {
-	_, err := c.sendDaemonRequest("stop", nil)
-	if err != nil {
-		return err
+	if err := c.daemonClient().Stop(); err != nil {
+		return daemonError(err, "stopping the daemon", "failed to stop daemon")
 	}
 
 	fmt.Println("Daemon stopped successfully")
@@ -739,32 +926,22 @@ func (c *CLI) daemonStatus(args []string) error {
 	}
 
 	// Try to connect to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "status",
-	})
+	status, err := c.daemonClient().Status()
 	if err != nil {
+		if reqErr, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("status check failed: %s", reqErr.Message)
+		}
 		fmt.Printf("Daemon PID file exists (PID: %d) but daemon is not responding\n", pid)
 		return nil
 	}
 
-	if !resp.Success {
-		return fmt.Errorf("status check failed: %s", resp.Error)
-	}
-
 	// Pretty print status
 	fmt.Println("Daemon Status:")
-	if statusMap, ok := resp.Data.(map[string]interface{}); ok {
-		fmt.Printf("  Running: %v\n", statusMap["running"])
-		fmt.Printf("  PID: %v\n", statusMap["pid"])
-		fmt.Printf("  Repos: %v\n", statusMap["repos"])
-		fmt.Printf("  Agents: %v\n", statusMap["agents"])
-		fmt.Printf("  Socket: %v\n", statusMap["socket_path"])
-	} else {
-		// Fallback: print as JSON
-		jsonData, _ := json.MarshalIndent(resp.Data, "  ", "  ")
-		fmt.Println(string(jsonData))
-	}
+	fmt.Printf("  Running: %v\n", status.Running)
+	fmt.Printf("  PID: %d\n", status.PID)
+	fmt.Printf("  Repos: %d\n", status.Repos)
+	fmt.Printf("  Agents: %d\n", status.Agents)
+	fmt.Printf("  Socket: %s\n", status.SocketPath)
 
 	return nil
 }
@@ -801,21 +978,11 @@ func (c *CLI) stopAll(args []string) error {
 	skipConfirm := flags["yes"] == "true"
 
 	// Get list of repos (try daemon first, then state file)
-	var repos []string
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{Command: "list_repos"})
-	if err == nil && resp.Success {
-		// Daemon is running, get repos from it
-		if repoList, ok := resp.Data.([]interface{}); ok {
-			for _, repo := range repoList {
-				if repoStr, ok := repo.(string); ok {
-					repos = append(repos, repoStr)
-				}
-			}
-		}
-	} else {
+	daemonClient := c.daemonClient()
+	repos, err := daemonClient.ListRepos()
+	if err != nil {
 		// Daemon not running, try to load from state file
-		st, err := state.Load(c.paths.StateFile)
+		st, err := c.loadState()
 		if err == nil {
 			repos = st.ListRepos()
 		}
@@ -894,10 +1061,11 @@ func (c *CLI) stopAll(args []string) error {
 
 	// Stop the daemon
 	fmt.Println("Stopping daemon...")
-	resp, err = client.Send(socket.Request{Command: "stop"})
-	if err != nil {
-		fmt.Printf("Daemon already stopped or not responding\n")
-	} else if resp.Success {
+	if err := daemonClient.Stop(); err != nil {
+		if _, ok := err.(*client.ConnectionError); ok {
+			fmt.Printf("Daemon already stopped or not responding\n")
+		}
+	} else {
 		fmt.Println("Daemon stopped")
 	}
 
@@ -962,16 +1130,15 @@ func (c *CLI) stopAll(args []string) error {
 			}
 		}
 
-		// Clear agent state but preserve repository entries
+		// Clear agent state but preserve repository entries. The daemon
+		// is stopped, so this changes the state file directly.
 		fmt.Println("\nClearing agent state...")
-		st, err := state.Load(c.paths.StateFile)
-		if err == nil {
-			st.ClearAllAgents()
-			if err := st.Save(); err != nil {
-				fmt.Printf("  Warning: failed to save state: %v\n", err)
-			} else {
-				fmt.Println("  Cleared all agents from state")
-			}
+		if err := c.updateState(func(st *state.State) error {
+			return st.ClearAllAgents()
+		}); err != nil {
+			fmt.Printf("  Warning: %v\n", err)
+		} else {
+			fmt.Println("  Cleared all agents from state")
 		}
 
 		// Remove daemon files (they'll be recreated on next start)
@@ -1003,6 +1170,9 @@ func (c *CLI) initRepo(args []string) error {
 	var repoName string
 	if len(posArgs) >= 2 {
 		repoName = posArgs[1]
+	} else if path, ok := forge.LocalPath(githubURL); ok {
+		// A repo on the local forge is named after its bare repo (/srv/git/app.git -> app)
+		repoName = strings.TrimSuffix(filepath.Base(path), ".git")
 	} else {
 		// Extract repo name from URL (e.g., github.com/user/repo -> repo)
 		// A valid GitHub URL has format: https://github.com/owner/repo
@@ -1060,7 +1230,11 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	fmt.Printf("Initializing repository: %s\n", repoName)
-	fmt.Printf("GitHub URL: %s\n", githubURL)
+	if forge.IsLocal(githubURL) {
+		fmt.Printf("Local forge: %s\n", githubURL)
+	} else {
+		fmt.Printf("GitHub URL: %s\n", githubURL)
+	}
 	if mqEnabled {
 		fmt.Printf("Merge queue: enabled (tracking: %s)\n", mqTrackMode)
 	} else {
@@ -1073,10 +1247,9 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
-	_, err := client.Send(socket.Request{Command: "ping"})
-	if err != nil {
-		return errors.DaemonNotRunning()
+	daemonClient := c.daemonClient()
+	if err := c.pingDaemon(daemonClient); err != nil {
+		return err
 	}
 
 	// Clone repository
@@ -1090,6 +1263,14 @@ func (c *CLI) initRepo(args []string) error {
 		return errors.GitOperationFailed("clone", err)
 	}
 
+	// Detect the branch workers start from by default
+	defaultBranch, err := worktree.NewManager(repoPath).GetDefaultBranch("origin")
+	if err != nil {
+		fmt.Printf("Warning: failed to detect default branch: %v\n", err)
+	} else {
+		fmt.Printf("Default branch: %s\n", defaultBranch)
+	}
+
 	// Copy agent templates to per-repo agents directory
 	agentsDir := c.paths.RepoAgentsDir(repoName)
 	fmt.Printf("Copying agent templates to: %s\n", agentsDir)
@@ -1160,7 +1341,7 @@ func (c *CLI) initRepo(args []string) error {
 
 	var mergeQueuePromptFile string
 	if mqEnabled {
-		mergeQueuePromptFile, err = c.writeMergeQueuePromptFile(repoPath, "merge-queue", mqConfig)
+		mergeQueuePromptFile, err = c.writeMergeQueuePromptFile(repoPath, "merge-queue", mqConfig, forge.IsLocal(githubURL))
 		if err != nil {
 			return fmt.Errorf("failed to write merge-queue prompt: %w", err)
 		}
@@ -1181,7 +1362,7 @@ func (c *CLI) initRepo(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in supervisor window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "supervisor", repoPath, supervisorSessionID, supervisorPromptFile, repoName, "")
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "supervisor", repoPath, supervisorSessionID, supervisorPromptFile, repoName, "", state.ClaudeOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to start supervisor Claude: %w", err)
 		}
@@ -1195,7 +1376,7 @@ func (c *CLI) initRepo(args []string) error {
 		// Start Claude in merge-queue window only if enabled
 		if mqEnabled {
 			fmt.Println("Starting Claude Code in merge-queue window...")
-			pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, "merge-queue", repoPath, mergeQueueSessionID, mergeQueuePromptFile, repoName, "")
+			pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, "merge-queue", repoPath, mergeQueueSessionID, mergeQueuePromptFile, repoName, "", state.ClaudeOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to start merge-queue Claude: %w", err)
 			}
@@ -1209,71 +1390,51 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Add repository to daemon state (with merge queue config and optional upstream config)
-	addRepoArgs := map[string]interface{}{
-		"name":          repoName,
-		"github_url":    githubURL,
-		"tmux_session":  tmuxSession,
-		"mq_enabled":    mqConfig.Enabled,
-		"mq_track_mode": string(mqConfig.TrackMode),
+	addRepoReq := client.AddRepoRequest{
+		Name:                repoName,
+		GithubURL:           githubURL,
+		TmuxSession:         tmuxSession,
+		MergeQueueEnabled:   client.Bool(mqConfig.Enabled),
+		MergeQueueTrackMode: string(mqConfig.TrackMode),
+		DefaultBranch:       defaultBranch,
 	}
 	if upstreamConfig != nil {
-		addRepoArgs["upstream_url"] = upstreamConfig.UpstreamURL
-		addRepoArgs["upstream_remote"] = upstreamConfig.UpstreamRemote
-		addRepoArgs["fork_remote"] = upstreamConfig.ForkRemote
-		addRepoArgs["sync_enabled"] = upstreamConfig.SyncEnabled
-		addRepoArgs["sync_interval"] = upstreamConfig.SyncInterval
-	}
-	resp, err := client.Send(socket.Request{
-		Command: "add_repo",
-		Args:    addRepoArgs,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to register repository with daemon: %w", err)
+		addRepoReq.UpstreamURL = upstreamConfig.UpstreamURL
+		addRepoReq.UpstreamRemote = upstreamConfig.UpstreamRemote
+		addRepoReq.ForkRemote = upstreamConfig.ForkRemote
+		addRepoReq.SyncEnabled = client.Bool(upstreamConfig.SyncEnabled)
+		addRepoReq.SyncInterval = upstreamConfig.SyncInterval
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register repository: %s", resp.Error)
+	if err := daemonClient.AddRepo(addRepoReq); err != nil {
+		return fmt.Errorf("failed to register repository: %w", err)
 	}
 
 	// Add supervisor agent
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         "supervisor",
-			"type":          "supervisor",
-			"worktree_path": repoPath,
-			"tmux_window":   "supervisor",
-			"session_id":    supervisorSessionID,
-			"pid":           supervisorPID,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.AddAgent(client.AddAgentRequest{
+		Repo:         repoName,
+		Agent:        "supervisor",
+		Type:         client.AgentTypeSupervisor,
+		WorktreePath: repoPath,
+		TmuxWindow:   "supervisor",
+		SessionID:    supervisorSessionID,
+		PID:          supervisorPID,
+	}); err != nil {
 		return fmt.Errorf("failed to register supervisor: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register supervisor: %s", resp.Error)
-	}
 
 	// Add merge-queue agent only if enabled
 	if mqEnabled {
-		resp, err = client.Send(socket.Request{
-			Command: "add_agent",
-			Args: map[string]interface{}{
-				"repo":          repoName,
-				"agent":         "merge-queue",
-				"type":          "merge-queue",
-				"worktree_path": repoPath,
-				"tmux_window":   "merge-queue",
-				"session_id":    mergeQueueSessionID,
-				"pid":           mergeQueuePID,
-			},
-		})
-		if err != nil {
+		if err := daemonClient.AddAgent(client.AddAgentRequest{
+			Repo:         repoName,
+			Agent:        "merge-queue",
+			Type:         client.AgentTypeMergeQueue,
+			WorktreePath: repoPath,
+			TmuxWindow:   "merge-queue",
+			SessionID:    mergeQueueSessionID,
+			PID:          mergeQueuePID,
+		}); err != nil {
 			return fmt.Errorf("failed to register merge-queue: %w", err)
 		}
-		if !resp.Success {
-			return fmt.Errorf("failed to register merge-queue: %s", resp.Error)
-		}
 	}
 
 	// Create default workspace worktree
@@ -1334,7 +1495,7 @@ func (c *CLI) initRepo(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in default workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "default", workspacePath, workspaceSessionID, workspacePromptFile, repoName, "")
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "default", workspacePath, workspaceSessionID, workspacePromptFile, repoName, "", state.ClaudeOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to start default workspace Claude: %w", err)
 		}
@@ -1347,50 +1508,70 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Add default workspace agent
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         "default",
-			"type":          "workspace",
-			"worktree_path": workspacePath,
-			"tmux_window":   "default",
-			"session_id":    workspaceSessionID,
-			"pid":           workspacePID,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.AddAgent(client.AddAgentRequest{
+		Repo:         repoName,
+		Agent:        "default",
+		Type:         client.AgentTypeWorkspace,
+		WorktreePath: workspacePath,
+		TmuxWindow:   "default",
+		SessionID:    workspaceSessionID,
+		PID:          workspacePID,
+	}); err != nil {
 		return fmt.Errorf("failed to register default workspace: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register default workspace: %s", resp.Error)
-	}
+
+	// Start the agents whose definitions ask to auto-start
+	autoStarted := c.spawnAutoStartAgents(daemonClient, repoName, repoPath)
 
 	fmt.Println()
 	fmt.Println("✓ Repository initialized successfully!")
 	fmt.Printf("  Tmux session: %s\n", tmuxSession)
+	agentNames := []string{"supervisor"}
 	if mqEnabled {
-		fmt.Printf("  Agents: supervisor, merge-queue, default (workspace)\n")
-	} else {
-		fmt.Printf("  Agents: supervisor, default (workspace)\n")
+		agentNames = append(agentNames, "merge-queue")
 	}
+	agentNames = append(agentNames, "default (workspace)")
+	agentNames = append(agentNames, autoStarted...)
+	fmt.Printf("  Agents: %s\n", strings.Join(agentNames, ", "))
 	fmt.Printf("\nAttach to session: tmux attach -t %s\n", tmuxSession)
 	fmt.Printf("Or connect to your workspace: multiclaude workspace connect default\n")
 
 	return nil
 }
 
-func (c *CLI) listRepos(args []string) error {
-	resp, err := c.sendDaemonRequest("list_repos", map[string]interface{}{
-		"rich": true,
-	})
+// spawnAutoStartAgents asks the daemon to spawn the agents whose definitions
+// have auto_start set and returns the names of the agents that were started.
+// Failures are reported as warnings so they don't abort repository init.
+func (c *CLI) spawnAutoStartAgents(daemonClient *client.Client, repoName, repoPath string) []string {
+	reader := agents.NewReader(c.paths.RepoAgentsDir(repoName), repoPath)
+	defs, err := reader.ReadAllDefinitions()
 	if err != nil {
-		return err
+		fmt.Printf("Warning: failed to read agent definitions: %v\n", err)
+		return nil
 	}
 
-	repos, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	var started []string
+	for _, def := range defs {
+		if !def.Metadata.AutoStart {
+			continue
+		}
+		fmt.Printf("Starting auto-start agent: %s\n", def.Name)
+		if _, err := daemonClient.SpawnAgent(client.SpawnAgentRequest{
+			Repo:       repoName,
+			Definition: def.Name,
+		}); err != nil {
+			fmt.Printf("Warning: failed to spawn %s: %v\n", def.Name, err)
+			continue
+		}
+		started = append(started, def.Name)
+	}
+	return started
+}
+
+func (c *CLI) listRepos(args []string) error {
+	repos, err := c.daemonClient().ListReposDetailed()
+	if err != nil {
+		return daemonError(err, "listing repositories", "failed to list repos")
 	}
 
 	if len(repos) == 0 {
@@ -1404,40 +1585,26 @@ func (c *CLI) listRepos(args []string) error {
 
 	table := format.NewColoredTable("REPO", "AGENTS", "STATUS", "SESSION")
 	for _, repo := range repos {
-		if repoMap, ok := repo.(map[string]interface{}); ok {
-			name, _ := repoMap["name"].(string)
-			totalAgents := 0
-			if v, ok := repoMap["total_agents"].(float64); ok {
-				totalAgents = int(v)
-			}
-			workerCount := 0
-			if v, ok := repoMap["worker_count"].(float64); ok {
-				workerCount = int(v)
-			}
-			sessionHealthy, _ := repoMap["session_healthy"].(bool)
-			tmuxSession, _ := repoMap["tmux_session"].(string)
-
-			// Format agent count
-			agentStr := fmt.Sprintf("%d total", totalAgents)
-			if workerCount > 0 {
-				agentStr = fmt.Sprintf("%d (%d workers)", totalAgents, workerCount)
-			}
-
-			// Format status
-			var statusCell format.ColoredCell
-			if sessionHealthy {
-				statusCell = format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
-			} else {
-				statusCell = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
-			}
+		// Format agent count
+		agentStr := fmt.Sprintf("%d total", repo.TotalAgents)
+		if repo.WorkerCount > 0 {
+			agentStr = fmt.Sprintf("%d (%d workers)", repo.TotalAgents, repo.WorkerCount)
+		}
 
-			table.AddRow(
-				format.Cell(name),
-				format.Cell(agentStr),
-				statusCell,
-				format.ColorCell(tmuxSession, format.Dim),
-			)
+		// Format status
+		var statusCell format.ColoredCell
+		if repo.SessionHealthy {
+			statusCell = format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
+		} else {
+			statusCell = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
 		}
+
+		table.AddRow(
+			format.Cell(repo.Name),
+			format.Cell(agentStr),
+			statusCell,
+			format.ColorCell(repo.TmuxSession, format.Dim),
+		)
 	}
 	table.Print()
 
@@ -1450,21 +1617,11 @@ func (c *CLI) removeRepo(args []string) error {
 		repoName = args[0]
 	} else {
 		// Interactive selection - list repos
-		client := socket.NewClient(c.paths.DaemonSock)
-		resp, err := client.Send(socket.Request{
-			Command: "list_repos",
-			Args: map[string]interface{}{
-				"rich": true,
-			},
-		})
+		repos, err := c.daemonClient().ListReposDetailed()
 		if err != nil {
-			return errors.DaemonCommunicationFailed("listing repositories", err)
-		}
-		if !resp.Success {
-			return errors.Wrap(errors.CategoryRuntime, "failed to list repos", fmt.Errorf("%s", resp.Error))
+			return daemonError(err, "listing repositories", "failed to list repos")
 		}
 
-		repos, _ := resp.Data.([]interface{})
 		items := reposToSelectableItems(repos)
 		if len(items) == 0 {
 			return errors.NoRepositoriesFound()
@@ -1483,45 +1640,29 @@ func (c *CLI) removeRepo(args []string) error {
 	fmt.Printf("Removing repository '%s'...\n", repoName)
 
 	// Get repo info from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	daemonClient := c.daemonClient()
+	agents, err := daemonClient.ListAgents(repoName)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
+		return daemonError(err, "getting repo info", "failed to get repo info")
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
-	}
-
-	// Get list of agents
-	agents, _ := resp.Data.([]interface{})
 
 	// Check for any workers with uncommitted changes
 	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "worker" || agentType == "review" {
-				wtPath, _ := agentMap["worktree_path"].(string)
-				if wtPath != "" {
-					hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
-					if err == nil && hasUncommitted {
-						agentName, _ := agentMap["name"].(string)
-						fmt.Printf("\nWarning: Agent '%s' has uncommitted changes!\n", agentName)
-						fmt.Println("Files may be lost if you continue.")
-						fmt.Print("Continue with removal? [y/N]: ")
-
-						var response string
-						fmt.Scanln(&response)
-						if response != "y" && response != "Y" {
-							fmt.Println("Removal cancelled")
-							return nil
-						}
-						break // Only ask once
+		if agent.Type == client.AgentTypeWorker || agent.Type == client.AgentTypeReview {
+			if agent.WorktreePath != "" {
+				hasUncommitted, err := worktree.HasUncommittedChanges(agent.WorktreePath)
+				if err == nil && hasUncommitted {
+					fmt.Printf("\nWarning: Agent '%s' has uncommitted changes!\n", agent.Name)
+					fmt.Println("Files may be lost if you continue.")
+					fmt.Print("Continue with removal? [y/N]: ")
+
+					var response string
+					fmt.Scanln(&response)
+					if response != "y" && response != "Y" {
+						fmt.Println("Removal cancelled")
+						return nil
 					}
+					break // Only ask once
 				}
 			}
 		}
@@ -1541,14 +1682,10 @@ func (c *CLI) removeRepo(args []string) error {
 	repoPath := c.paths.RepoDir(repoName)
 	wt := worktree.NewManager(repoPath)
 	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			wtPath, _ := agentMap["worktree_path"].(string)
-			agentName, _ := agentMap["name"].(string)
-			if wtPath != "" && wtPath != repoPath {
-				fmt.Printf("Removing worktree for '%s': %s\n", agentName, wtPath)
-				if err := wt.Remove(wtPath, true); err != nil {
-					fmt.Printf("Warning: failed to remove worktree: %v\n", err)
-				}
+		if agent.WorktreePath != "" && agent.WorktreePath != repoPath {
+			fmt.Printf("Removing worktree for '%s': %s\n", agent.Name, agent.WorktreePath)
+			if err := wt.Remove(agent.WorktreePath, true); err != nil {
+				fmt.Printf("Warning: failed to remove worktree: %v\n", err)
 			}
 		}
 	}
@@ -1572,17 +1709,8 @@ func (c *CLI) removeRepo(args []string) error {
 	}
 
 	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_repo",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("removing repo", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to remove repo from state", fmt.Errorf("%s", resp.Error))
+	if err := daemonClient.RemoveRepo(repoName); err != nil {
+		return daemonError(err, "removing repo", "failed to remove repo from state")
 	}
 
 	fmt.Println("✓ Repository removed successfully")
@@ -1598,11 +1726,8 @@ func (c *CLI) setCurrentRepo(args []string) error {
 
 	repoName := args[0]
 
-	_, err := c.sendDaemonRequest("set_current_repo", map[string]interface{}{
-		"name": repoName,
-	})
-	if err != nil {
-		return err
+	if err := c.daemonClient().SetCurrentRepo(repoName); err != nil {
+		return daemonError(err, "setting current repo", "failed to set current repo")
 	}
 
 	fmt.Printf("Current repository set to: %s\n", repoName)
@@ -1610,12 +1735,12 @@ func (c *CLI) setCurrentRepo(args []string) error {
 }
 
 func (c *CLI) getCurrentRepo(args []string) error {
-	resp, err := c.sendDaemonRequest("get_current_repo", nil)
-	if err != nil {
-		return err
+	// The daemon rejects the request when no repo is set
+	currentRepo, err := c.daemonClient().GetCurrentRepo()
+	if _, ok := err.(*client.RequestError); err != nil && !ok {
+		return errors.DaemonCommunicationFailed("getting current repo", err)
 	}
 
-	currentRepo, _ := resp.Data.(string)
 	if currentRepo == "" {
 		fmt.Println("No current repository set")
 		fmt.Println("\nUse 'multiclaude repo use <name>' to set one")
@@ -1626,9 +1751,8 @@ func (c *CLI) getCurrentRepo(args []string) error {
 }
 
 func (c *CLI) clearCurrentRepo(args []string) error {
-	_, err := c.sendDaemonRequest("clear_current_repo", nil)
-	if err != nil {
-		return err
+	if err := c.daemonClient().ClearCurrentRepo(); err != nil {
+		return daemonError(err, "clearing current repo", "failed to clear current repo")
 	}
 
 	fmt.Println("Current repository cleared")
@@ -1672,8 +1796,21 @@ func (c *CLI) configRepo(args []string) error {
 	// Check if any config flags are provided
 	hasMqEnabled := flags["mq-enabled"] != ""
 	hasMqTrack := flags["mq-track"] != ""
+	hasActivity := flags["idle-minutes"] != "" || flags["stuck-minutes"] != "" || flags["escalate-stuck"] != ""
+	hasWake := flags["wake-type"] != ""
+	hasMaxWorkers := flags["max-workers"] != ""
+	hasDefaultBranch := flags["default-branch"] != ""
+	hasResolveConflicts := flags["resolve-conflicts"] != ""
+	hasSyncStrategy := flags["sync-strategy"] != ""
+	hasClaude := flags["claude-type"] != ""
+	if !hasWake && (flags["wake-interval"] != "" || flags["wake-quiet-hours"] != "" || flags["wake-only-when"] != "" || flags["wake-message"] != "") {
+		return fmt.Errorf("--wake-interval, --wake-quiet-hours, --wake-only-when, and --wake-message require --wake-type")
+	}
+	if !hasClaude && (flags["model"] != "" || flags["claude-args"] != "" || flags["claude-env"] != "") {
+		return fmt.Errorf("--model, --claude-args, and --claude-env require --claude-type")
+	}
 
-	if !hasMqEnabled && !hasMqTrack {
+	if !hasMqEnabled && !hasMqTrack && !hasActivity && !hasWake && !hasMaxWorkers && !hasDefaultBranch && !hasResolveConflicts && !hasSyncStrategy && !hasClaude {
 		// No flags - just show current config
 		return c.showRepoConfig(repoName)
 	}
@@ -1683,349 +1820,671 @@ func (c *CLI) configRepo(args []string) error {
 }
 
 func (c *CLI) showRepoConfig(repoName string) error {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_repo_config",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
+	config, err := c.daemonClient().GetRepoConfig(repoName)
 	if err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("failed to get repo config: %w", err)
+		}
 		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
 	}
 
-	if !resp.Success {
-		return fmt.Errorf("failed to get repo config: %s", resp.Error)
-	}
+	fmt.Printf("Configuration for repository: %s\n\n", repoName)
+	fmt.Println("Merge Queue:")
 
-	// Parse response
-	configMap, ok := resp.Data.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected response format")
+	if config.MergeQueueEnabled {
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Track mode: %s\n", config.MergeQueueTrackMode)
+	} else {
+		fmt.Printf("  Enabled: false\n")
 	}
 
-	fmt.Printf("Configuration for repository: %s\n\n", repoName)
-	fmt.Println("Merge Queue:")
+	fmt.Println("\nActivity Detection:")
+	fmt.Printf("  Idle after: %d minutes without output\n", config.IdleMinutes)
+	fmt.Printf("  Stuck after: %d minutes without output (workers)\n", config.StuckMinutes)
+	fmt.Printf("  Escalate stuck workers to supervisor: %v\n", config.EscalateStuck)
+
+	fmt.Println("\nWorkers:")
+	if config.MaxWorkers > 0 {
+		fmt.Printf("  Max concurrent: %d (further tasks are queued)\n", config.MaxWorkers)
+	} else {
+		fmt.Printf("  Max concurrent: no limit\n")
+	}
+	if config.DefaultBranch != "" {
+		fmt.Printf("  Default base branch: %s\n", config.DefaultBranch)
+	} else {
+		fmt.Printf("  Default base branch: unknown (workers start from HEAD)\n")
+	}
+	fmt.Printf("  Resolve conflicts of completed workers and upstream syncs: %v\n", config.ResolveConflicts)
 
-	mqEnabled := true
-	if enabled, ok := configMap["mq_enabled"].(bool); ok {
-		mqEnabled = enabled
+	if config.SyncStrategy != "" {
+		fmt.Println("\nUpstream Sync:")
+		fmt.Printf("  Automatic: %s, pushed to the fork\n", config.SyncStrategy)
 	}
 
-	mqTrackMode := "all"
-	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
-		mqTrackMode = trackMode
+	if config.WakePolicies != nil {
+		fmt.Println("\nWake Policies:")
+		types := make([]string, 0, len(config.WakePolicies))
+		for agentType := range config.WakePolicies {
+			types = append(types, agentType)
+		}
+		sort.Strings(types)
+		for _, agentType := range types {
+			fmt.Printf("  %s: %s\n", agentType, formatWakePolicy(config.WakePolicies[agentType]))
+		}
 	}
 
-	if mqEnabled {
-		fmt.Printf("  Enabled: true\n")
-		fmt.Printf("  Track mode: %s\n", mqTrackMode)
-	} else {
-		fmt.Printf("  Enabled: false\n")
+	if len(config.ClaudeOptions) > 0 {
+		fmt.Println("\nClaude Options:")
+		types := make([]string, 0, len(config.ClaudeOptions))
+		for agentType := range config.ClaudeOptions {
+			types = append(types, agentType)
+		}
+		sort.Strings(types)
+		for _, agentType := range types {
+			fmt.Printf("  %s: %s\n", agentType, formatClaudeOptions(config.ClaudeOptions[agentType]))
+		}
 	}
 
 	fmt.Println("\nTo modify:")
 	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
 	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
+	fmt.Printf("  multiclaude config %s --idle-minutes=N --stuck-minutes=N --escalate-stuck=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --max-workers=N (0 for no limit)\n", repoName)
+	fmt.Printf("  multiclaude config %s --default-branch=BRANCH\n", repoName)
+	fmt.Printf("  multiclaude config %s --resolve-conflicts=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --sync-strategy=merge|rebase|off (repos tracking an upstream)\n", repoName)
+	fmt.Printf("  multiclaude config %s --wake-type=TYPE --wake-interval=N --wake-quiet-hours=HH:MM-HH:MM|none --wake-only-when=CONDITIONS|none\n", repoName)
+	fmt.Printf("  multiclaude config %s --claude-type=TYPE --model=MODEL|none --claude-args=ARGS|none --claude-env=NAME=value,...|none\n", repoName)
 
 	return nil
 }
 
-func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
-	// Build update args
-	updateArgs := map[string]interface{}{
-		"name": repoName,
+// formatWakePolicy renders a wake policy from get_repo_config as a single line
+func formatWakePolicy(policy client.WakePolicy) string {
+	if policy.IntervalMinutes <= 0 {
+		return "disabled"
 	}
 
-	// Parse and validate flags
-	if mqEnabled, ok := flags["mq-enabled"]; ok {
-		switch mqEnabled {
-		case "true":
-			updateArgs["mq_enabled"] = true
-		case "false":
-			updateArgs["mq_enabled"] = false
-		default:
-			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
-		}
+	parts := []string{fmt.Sprintf("every %dm", policy.IntervalMinutes)}
+	if len(policy.OnlyWhen) > 0 {
+		parts = append(parts, "only when "+strings.Join(policy.OnlyWhen, " or "))
 	}
-
-	if mqTrack, ok := flags["mq-track"]; ok {
-		switch mqTrack {
-		case "all", "author", "assigned":
-			updateArgs["mq_track_mode"] = mqTrack
-		default:
-			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
-		}
+	if policy.QuietHours != "" {
+		parts = append(parts, "quiet "+policy.QuietHours)
 	}
+	return strings.Join(parts, ", ")
+}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "update_repo_config",
-		Args:    updateArgs,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+// wakePolicyArgs builds the wake_policies update for the --wake-* config flags
+func wakePolicyArgs(flags map[string]string) (map[string]client.WakePolicyUpdate, error) {
+	wakeType := flags["wake-type"]
+	switch wakeType {
+	case "supervisor", "worker", "merge-queue", "review", "generic-persistent":
+	default:
+		return nil, fmt.Errorf("invalid --wake-type value: %s (must be 'supervisor', 'worker', 'merge-queue', 'review', or 'generic-persistent')", wakeType)
 	}
 
-	if !resp.Success {
-		return fmt.Errorf("failed to update repo config: %s", resp.Error)
+	var policy client.WakePolicyUpdate
+	set := false
+	if v, ok := flags["wake-interval"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid --wake-interval value: %s (must be a number of minutes, 0 disables nudges)", v)
+		}
+		policy.IntervalMinutes = client.Int(n)
+		set = true
+	}
+	if v, ok := flags["wake-quiet-hours"]; ok {
+		if v == "none" {
+			v = ""
+		}
+		policy.QuietHours = client.String(v)
+		set = true
+	}
+	if v, ok := flags["wake-only-when"]; ok {
+		policy.OnlyWhen = []string{}
+		if v != "none" {
+			for _, cond := range strings.Split(v, ",") {
+				if cond = strings.TrimSpace(cond); cond != "" {
+					policy.OnlyWhen = append(policy.OnlyWhen, cond)
+				}
+			}
+		}
+		set = true
+	}
+	if v, ok := flags["wake-message"]; ok {
+		policy.Message = client.String(v)
+		set = true
+	}
+	if !set {
+		return nil, fmt.Errorf("--wake-type requires at least one of --wake-interval, --wake-quiet-hours, --wake-only-when, or --wake-message")
 	}
 
-	fmt.Printf("Configuration updated for repository: %s\n", repoName)
-
-	// Show the updated config
-	return c.showRepoConfig(repoName)
+	return map[string]client.WakePolicyUpdate{wakeType: policy}, nil
 }
 
-func (c *CLI) createWorker(args []string) error {
-	flags, posArgs := ParseFlags(args)
+// formatClaudeOptions renders Claude options from get_repo_config as a single line
+func formatClaudeOptions(opts client.ClaudeOptions) string {
+	var parts []string
+	if opts.Model != "" {
+		parts = append(parts, "model "+opts.Model)
+	}
+	if len(opts.Args) > 0 {
+		parts = append(parts, "args "+strings.Join(opts.Args, " "))
+	}
+	if len(opts.Env) > 0 {
+		names := make([]string, 0, len(opts.Env))
+		for name := range opts.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, "env "+strings.Join(names, ", "))
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, "; ")
+}
 
-	// Get task description
-	task := strings.Join(posArgs, " ")
-	if task == "" {
-		return errors.InvalidUsage("usage: multiclaude work <task description>")
+// claudeOptionsConfigArgs builds the claude_options update for the
+// --claude-type config flags. "none" clears a field.
+func claudeOptionsConfigArgs(flags map[string]string) (map[string]*client.ClaudeOptionsUpdate, error) {
+	claudeType := flags["claude-type"]
+	switch claudeType {
+	case "supervisor", "worker", "merge-queue", "workspace", "review", "generic-persistent":
+	default:
+		return nil, fmt.Errorf("invalid --claude-type value: %s (must be 'supervisor', 'worker', 'merge-queue', 'workspace', 'review', or 'generic-persistent')", claudeType)
 	}
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+	// Parse the values as for a spawn, with "none" read as empty
+	values := make(map[string]string)
+	for _, flag := range []string{"model", "claude-args", "claude-env"} {
+		if v, ok := flags[flag]; ok && v != "none" {
+			values[flag] = v
+		}
+	}
+	parsed, err := claudeOptionsFromFlags(values)
 	if err != nil {
-		return errors.NotInRepo()
+		return nil, err
 	}
 
-	// Generate worker name (Docker-style)
-	workerName := names.Generate()
-	if name, ok := flags["name"]; ok {
-		workerName = name
+	var update client.ClaudeOptionsUpdate
+	set := false
+	if _, ok := flags["model"]; ok {
+		update.Model = client.String(parsed.Model)
+		set = true
 	}
-
-	// Check for --push-to flag (for iterating on existing PRs)
-	pushTo, hasPushTo := flags["push-to"]
-	if hasPushTo {
-		// --push-to requires --branch to specify the remote branch to start from
-		if _, hasBranch := flags["branch"]; !hasBranch {
-			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/multiclaude/jolly-hawk --push-to multiclaude/jolly-hawk)")
+	if _, ok := flags["claude-args"]; ok {
+		update.Args = append([]string{}, parsed.Args...)
+		set = true
+	}
+	if _, ok := flags["claude-env"]; ok {
+		update.Env = map[string]string{}
+		for name, value := range parsed.Env {
+			update.Env[name] = value
 		}
+		set = true
+	}
+	if !set {
+		return nil, fmt.Errorf("--claude-type requires at least one of --model, --claude-args, or --claude-env")
 	}
 
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
+	return map[string]*client.ClaudeOptionsUpdate{claudeType: &update}, nil
+}
 
-	// Fetch latest from origin before creating worktree
-	// This ensures workers start from the latest code, not stale local refs
-	// Note: We use "git fetch origin main" (not "main:main") because the latter
-	// fails when main is checked out in the bare repo with:
-	// "fatal: refusing to fetch into branch 'refs/heads/main' checked out at ..."
-	fmt.Println("Fetching latest from origin...")
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = repoPath
-	if err := fetchCmd.Run(); err != nil {
-		// Best effort - don't fail if offline or fetch fails
-		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+// claudeOptionsFromFlags parses the per-spawn --model, --claude-args and
+// --claude-env flags. --claude-args is split on whitespace and --claude-env
+// takes comma-separated NAME=value pairs.
+func claudeOptionsFromFlags(flags map[string]string) (state.ClaudeOptions, error) {
+	if err := checkClaudeFlagValues(flags); err != nil {
+		return state.ClaudeOptions{}, err
 	}
-
-	// Determine branch to start from
-	// Prefer origin/main if it exists (updated by fetch), otherwise fall back to HEAD
-	// This handles both normal repos and test repos without remotes
-	startBranch := "HEAD"
-	checkOriginCmd := exec.Command("git", "rev-parse", "--verify", "origin/main")
-	checkOriginCmd.Dir = repoPath
-	if err := checkOriginCmd.Run(); err == nil {
-		startBranch = "origin/main"
+	opts := state.ClaudeOptions{
+		Model: flags["model"],
+		Args:  strings.Fields(flags["claude-args"]),
 	}
-	if branch, ok := flags["branch"]; ok {
-		startBranch = branch
-		if hasPushTo {
-			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
-		} else {
-			fmt.Printf("Creating worker '%s' in repo '%s' from branch '%s'\n", workerName, repoName, branch)
+	for _, pair := range strings.Split(flags["claude-env"], ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
 		}
-	} else {
-		fmt.Printf("Creating worker '%s' in repo '%s'\n", workerName, repoName)
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return opts, errors.InvalidUsage(fmt.Sprintf("invalid --claude-env entry %q: expected NAME=value", pair))
+		}
+		if opts.Env == nil {
+			opts.Env = make(map[string]string)
+		}
+		opts.Env[strings.TrimSpace(name)] = value
 	}
-	fmt.Printf("Task: %s\n", task)
+	if err := opts.Validate(); err != nil {
+		return opts, errors.InvalidUsage(fmt.Sprintf("invalid --claude-env: %v", err))
+	}
+	return opts, nil
+}
 
-	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workerName)
+// checkClaudeFlagValues rejects Claude option flags given without a value.
+// Values starting with a dash are read as flags, so --claude-args needs the
+// --claude-args='--flag value' form.
+func checkClaudeFlagValues(flags map[string]string) error {
+	for _, name := range []string{"model", "claude-args", "claude-env"} {
+		if flags[name] == "true" {
+			return errors.InvalidUsage(fmt.Sprintf("--%s requires a value (use --%s='...' for values starting with a dash)", name, name))
+		}
+	}
+	return nil
+}
 
-	var branchName string
-	if hasPushTo {
-		// When --push-to is specified, we're iterating on an existing PR branch
-		// Create a worktree that checks out the remote branch into a local branch
-		branchName = pushTo
-		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
-		// Use git worktree add with -b to create local branch tracking the remote
-		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+// clientClaudeOptions converts Claude options for a daemon request. Unset
+// options are nil so the request omits them.
+func clientClaudeOptions(opts state.ClaudeOptions) *client.ClaudeOptions {
+	if opts.IsZero() {
+		return nil
+	}
+	return &client.ClaudeOptions{Model: opts.Model, Args: opts.Args, Env: opts.Env}
+}
+
+// repoClaudeOptions returns the Claude options to start an agent of the given
+// type with: the repo's configured options with override applied on top. If
+// the repo config can't be read, the override is used on its own.
+func (c *CLI) repoClaudeOptions(repoName string, agentType state.AgentType, override state.ClaudeOptions) state.ClaudeOptions {
+	config, err := c.daemonClient().GetRepoConfig(repoName)
+	if err != nil {
+		fmt.Printf("Warning: failed to get Claude options for %s agents: %v\n", agentType, err)
+		return override
+	}
+	opts := config.ClaudeOptions[string(agentType)]
+	defaults := state.ClaudeOptions{Model: opts.Model, Args: opts.Args, Env: opts.Env}
+	return defaults.Merge(override)
+}
+
+func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
+	// Build update request
+	update := client.UpdateRepoConfigRequest{Name: repoName}
+
+	// Parse and validate flags
+	if mqEnabled, ok := flags["mq-enabled"]; ok {
+		switch mqEnabled {
+		case "true":
+			update.MergeQueueEnabled = client.Bool(true)
+		case "false":
+			update.MergeQueueEnabled = client.Bool(false)
+		default:
+			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
 		}
-	} else {
-		// Normal case: create a new branch for this worker
-		branchName = fmt.Sprintf("multiclaude/%s", workerName)
-		fmt.Printf("Creating worktree at: %s\n", wtPath)
-		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+	}
+
+	if mqTrack, ok := flags["mq-track"]; ok {
+		switch mqTrack {
+		case "all", "author", "assigned":
+			update.MergeQueueTrackMode = client.String(mqTrack)
+		default:
+			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
 		}
 	}
 
-	// Get repository info to determine tmux session
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
+	for name, field := range map[string]**int{"idle-minutes": &update.IdleMinutes, "stuck-minutes": &update.StuckMinutes} {
+		if v, ok := flags[name]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid --%s value: %s (must be a positive number of minutes)", name, v)
+			}
+			*field = client.Int(n)
+		}
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+
+	if escalate, ok := flags["escalate-stuck"]; ok {
+		switch escalate {
+		case "true":
+			update.EscalateStuck = client.Bool(true)
+		case "false":
+			update.EscalateStuck = client.Bool(false)
+		default:
+			return fmt.Errorf("invalid --escalate-stuck value: %s (must be 'true' or 'false')", escalate)
+		}
 	}
 
-	// Get tmux session name (it's mc-<reponame>)
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	if v, ok := flags["max-workers"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --max-workers value: %s (must be a number, 0 for no limit)", v)
+		}
+		update.MaxWorkers = client.Int(n)
+	}
 
-	// Ensure tmux session exists before creating window
-	// This handles cases where the session was killed or daemon didn't restore it
-	tmuxClient := tmux.NewClient()
-	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
-	if err != nil {
-		return errors.TmuxOperationFailed("check session", err)
+	if v, ok := flags["default-branch"]; ok {
+		if v == "true" {
+			return fmt.Errorf("--default-branch requires a branch name")
+		}
+		update.DefaultBranch = client.String(v)
 	}
-	if !hasSession {
-		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
-		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-			return errors.TmuxOperationFailed("create session", err)
+
+	if resolve, ok := flags["resolve-conflicts"]; ok {
+		switch resolve {
+		case "true":
+			update.ResolveConflicts = client.Bool(true)
+		case "false":
+			update.ResolveConflicts = client.Bool(false)
+		default:
+			return fmt.Errorf("invalid --resolve-conflicts value: %s (must be 'true' or 'false')", resolve)
 		}
 	}
 
-	// Create tmux window for worker (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", workerName)
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create window", err)
+	if strategy, ok := flags["sync-strategy"]; ok {
+		switch strategy {
+		case "merge", "rebase":
+			update.SyncStrategy = client.String(strategy)
+		case "off":
+			update.SyncStrategy = client.String("")
+		default:
+			return fmt.Errorf("invalid --sync-strategy value: %s (must be 'merge', 'rebase' or 'off')", strategy)
+		}
+	}
+
+	if _, ok := flags["wake-type"]; ok {
+		policies, err := wakePolicyArgs(flags)
+		if err != nil {
+			return err
+		}
+		update.WakePolicies = policies
+	}
+
+	if _, ok := flags["claude-type"]; ok {
+		options, err := claudeOptionsConfigArgs(flags)
+		if err != nil {
+			return err
+		}
+		update.ClaudeOptions = options
+	}
+
+	if err := c.daemonClient().UpdateRepoConfig(update); err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("failed to update repo config: %w", err)
+		}
+		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+	}
+
+	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+
+	// Show the updated config
+	return c.showRepoConfig(repoName)
+}
+
+func (c *CLI) createWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// Get task description
+	task := strings.Join(posArgs, " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude work <task description>")
 	}
 
-	// Generate session ID for worker
-	workerSessionID, err := claude.GenerateSessionID()
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to generate worker session ID: %w", err)
+		return errors.NotInRepo()
 	}
 
-	// Write prompt file for worker (with push-to config if specified)
-	workerConfig := WorkerConfig{}
+	// Check for --push-to flag (for iterating on existing PRs)
+	pushTo, hasPushTo := flags["push-to"]
 	if hasPushTo {
-		workerConfig.PushToBranch = pushTo
+		// --push-to requires --branch to specify the remote branch to start from
+		if _, hasBranch := flags["branch"]; !hasBranch {
+			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/multiclaude/jolly-hawk --push-to multiclaude/jolly-hawk)")
+		}
 	}
-	workerPromptFile, err := c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
+
+	// Claude options for this worker, on top of the repo's worker defaults
+	claudeOverride, err := claudeOptionsFromFlags(flags)
 	if err != nil {
-		return fmt.Errorf("failed to write worker prompt: %w", err)
+		return err
 	}
 
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	// Queue the task if asked to; the daemon also queues it if the repo is
+	// at its worker limit
+	if flags["queue"] == "true" || flags["after"] != "" {
+		return c.enqueueWorkerTask(repoName, task, flags, claudeOverride)
 	}
 
-	// Start Claude in worker window with initial task (skip in test mode)
-	var workerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		fmt.Println("Starting Claude Code in worker window...")
-		initialMessage := fmt.Sprintf("Task: %s", task)
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, initialMessage)
-		if err != nil {
-			return fmt.Errorf("failed to start worker Claude: %w", err)
+	if base := flags["base"]; base != "" {
+		if err := worktree.ValidateBranchName(base); err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --base: %v", err))
 		}
-		workerPID = pid
+	}
 
-		// Set up output capture for worker
-		if err := c.setupOutputCapture(tmuxSession, workerName, repoName, workerName, "worker"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for worker: %v\n", err)
+	if branch, ok := flags["branch"]; ok {
+		if hasPushTo {
+			fmt.Printf("Creating worker in repo '%s' to iterate on branch '%s'\n", repoName, pushTo)
+		} else {
+			fmt.Printf("Creating worker in repo '%s' from branch '%s'\n", repoName, branch)
 		}
+	} else {
+		fmt.Printf("Creating worker in repo '%s'\n", repoName)
 	}
+	fmt.Printf("Task: %s\n", task)
 
-	// Register worker with daemon
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workerName,
-			"type":          "worker",
-			"worktree_path": wtPath,
-			"tmux_window":   workerName,
-			"task":          task,
-			"session_id":    workerSessionID,
-			"pid":           workerPID,
-		},
+	// The daemon fetches origin, creates the worktree and tmux window, and
+	// starts Claude, the same way it starts queued tasks
+	worker, err := c.daemonClient().CreateWorker(client.CreateWorkerRequest{
+		Repo:          repoName,
+		Task:          task,
+		Name:          flags["name"],
+		Branch:        flags["branch"],
+		Base:          flags["base"],
+		PushTo:        pushTo,
+		Documentation: c.documentation,
+		Claude:        clientClaudeOptions(claudeOverride),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to register worker: %w", err)
+		return daemonError(err, "creating the worker", "failed to create worker")
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register worker: %s", resp.Error)
+	if worker.Queued != nil {
+		fmt.Println()
+		c.printQueuedTask(repoName, worker.Queued)
+		return nil
 	}
 
 	fmt.Println()
 	fmt.Println("✓ Worker created successfully!")
-	fmt.Printf("  Name: %s\n", workerName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
+	fmt.Printf("  Name: %s\n", worker.Name)
+	fmt.Printf("  Branch: %s\n", worker.Branch)
+	if worker.Base != "" {
+		fmt.Printf("  Base: %s\n", worker.Base)
+	}
+	fmt.Printf("  Worktree: %s\n", worker.WorktreePath)
 	if hasPushTo {
 		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", pushTo)
 	}
-	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
+	if worker.Claude != nil && worker.Claude.Model != "" {
+		fmt.Printf("  Model: %s\n", worker.Claude.Model)
+	}
+	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", worker.TmuxSession, worker.Name)
+	fmt.Printf("Or use: multiclaude attach %s\n", worker.Name)
 
 	return nil
 }
 
-func (c *CLI) listWorkers(args []string) error {
+// repoDefaultBranch returns the branch workers in a repo are based on by
+// default, or "" if it isn't known
+func (c *CLI) repoDefaultBranch(repoName string, wt *worktree.Manager) string {
+	if config, err := c.daemonClient().GetRepoConfig(repoName); err == nil && config.DefaultBranch != "" {
+		return config.DefaultBranch
+	}
+	branch, err := wt.GetDefaultBranch("origin")
+	if err != nil {
+		return ""
+	}
+	return branch
+}
+
+// enqueueWorkerTask adds a worker task to the repo's queue
+func (c *CLI) enqueueWorkerTask(repoName, task string, flags map[string]string, claudeOpts state.ClaudeOptions) error {
+	queued, err := c.daemonClient().EnqueueTask(client.EnqueueTaskRequest{
+		Repo:          repoName,
+		Task:          task,
+		Name:          flags["name"],
+		Base:          flags["base"],
+		Branch:        flags["branch"],
+		PushTo:        flags["push-to"],
+		After:         flags["after"],
+		Claude:        clientClaudeOptions(claudeOpts),
+		Documentation: c.documentation,
+	})
+	if err != nil {
+		return daemonError(err, "queueing the task", "failed to queue task")
+	}
+
+	c.printQueuedTask(repoName, queued)
+	return nil
+}
+
+// printQueuedTask describes a queued worker task and when it will start
+func (c *CLI) printQueuedTask(repoName string, queued *client.QueuedTask) {
+	fmt.Printf("Task queued in '%s' at position %d (ID: %s)\n", repoName, queued.Position, queued.ID)
+	fmt.Printf("Task: %s\n", queued.Task)
+	if queued.After != "" {
+		fmt.Printf("It will start from the latest %s once %s's PR merges.\n", c.queuedTaskStartRef(repoName, queued), queued.After)
+	} else {
+		fmt.Println("It will start automatically when a worker slot is free.")
+	}
+	fmt.Printf("\nView the queue: multiclaude work queue --repo %s\n", repoName)
+}
+
+// queuedTaskStartRef returns the branch a queued task will start from: its
+// --branch, else its base, else the repo's default branch
+func (c *CLI) queuedTaskStartRef(repoName string, queued *client.QueuedTask) string {
+	if queued.Branch != "" {
+		return queued.Branch
+	}
+	if queued.Base != "" {
+		return queued.Base
+	}
+	if branch := c.repoDefaultBranch(repoName, worktree.NewManager(c.paths.RepoDir(repoName))); branch != "" {
+		return branch
+	}
+	return "default branch"
+}
+
+func (c *CLI) listTaskQueue(args []string) error {
 	flags, _ := ParseFlags(args)
 
-	// Determine repository
 	repoName, err := c.resolveRepo(flags)
 	if err != nil {
 		return errors.NotInRepo()
 	}
 
-	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
-		"repo": repoName,
-		"rich": true,
-	})
+	queue, err := c.daemonClient().ListTaskQueue(repoName)
 	if err != nil {
-		return err
+		return daemonError(err, "getting the task queue", "failed to get task queue")
 	}
 
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	usage := fmt.Sprintf("%d workers running, no limit", queue.ActiveWorkers)
+	if queue.MaxWorkers > 0 {
+		usage = fmt.Sprintf("%d/%d workers running", queue.ActiveWorkers, queue.MaxWorkers)
+	}
+
+	if len(queue.Tasks) == 0 {
+		fmt.Printf("No queued tasks in '%s' (%s)\n", repoName, usage)
+		return nil
+	}
+
+	format.Header("Task queue for '%s' (%d queued, %s):", repoName, len(queue.Tasks), usage)
+	for _, task := range queue.Tasks {
+		queuedAt := ""
+		if !task.EnqueuedAt.IsZero() {
+			queuedAt = format.TimeAgo(task.EnqueuedAt)
+		}
+		fmt.Printf("  %d. %s %s %s\n", task.Position, task.ID, truncateString(task.Task, 60), format.Dim.Sprintf("(queued %s)", queuedAt))
+		if task.After != "" {
+			fmt.Printf("     Waiting for %s to merge\n", task.After)
+		}
+		if task.LastError != "" {
+			fmt.Printf("     %s\n", format.Red.Sprintf("Can't start: %s", task.LastError))
+		}
+		switch {
+		case !task.RetryAt.IsZero():
+			fmt.Printf("     Retrying at %s\n", task.RetryAt.Local().Format("15:04"))
+		case task.Attempts > 0:
+			fmt.Printf("     Gave up after %d failed starts; cancel and re-queue it\n", task.Attempts)
+		}
+	}
+	fmt.Println()
+	fmt.Println("Reorder with: multiclaude work queue move <task-id> <position>")
+	fmt.Println("Cancel with: multiclaude work queue cancel <task-id>")
+	return nil
+}
+
+func (c *CLI) moveQueuedTask(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude work queue move <task-id> <position> [--repo <repo>]")
+	}
+
+	position, err := strconv.Atoi(posArgs[1])
+	if err != nil || position < 1 {
+		return errors.InvalidUsage(fmt.Sprintf("invalid position %q (must be a number, 1 is next)", posArgs[1]))
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	if err := c.daemonClient().MoveQueuedTask(repoName, posArgs[0], position); err != nil {
+		return daemonError(err, "moving the task", "failed to move queued task")
+	}
+
+	fmt.Printf("Moved task %s to position %d\n", posArgs[0], position)
+	return nil
+}
+
+func (c *CLI) cancelQueuedTask(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude work queue cancel <task-id> [--repo <repo>]")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	if _, err := c.daemonClient().CancelQueuedTask(repoName, posArgs[0]); err != nil {
+		return daemonError(err, "cancelling the task", "failed to cancel queued task")
+	}
+
+	fmt.Printf("Cancelled queued task %s\n", posArgs[0])
+	return nil
+}
+
+func (c *CLI) listWorkers(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	agents, err := c.daemonClient().ListAgentsDetailed(repoName)
+	if err != nil {
+		return daemonError(err, "listing workers", "failed to list workers")
 	}
 
 	// Filter for workers and workspace
-	workers := []map[string]interface{}{}
-	var workspace map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "worker" {
-				workers = append(workers, agentMap)
-			} else if agentType == "workspace" {
-				workspace = agentMap
-			}
+	workers := []client.Agent{}
+	var workspace *client.Agent
+	for i, agent := range agents {
+		if agent.Type == client.AgentTypeWorker {
+			workers = append(workers, agent)
+		} else if agent.Type == client.AgentTypeWorkspace {
+			workspace = &agents[i]
 		}
 	}
 
 	// Show workspace first if it exists
 	if workspace != nil {
 		format.Header("Workspace in '%s':", repoName)
-		status, _ := workspace["status"].(string)
-		statusCell := formatAgentStatusCell(status)
+		statusCell := formatAgentStatusCell(workspace.Status)
 		fmt.Printf("  workspace ")
 		fmt.Print(statusCell.Text)
 		fmt.Println()
@@ -2041,49 +2500,97 @@ func (c *CLI) listWorkers(args []string) error {
 	format.Header("Workers in '%s' (%d):", repoName, len(workers))
 	fmt.Println()
 
-	table := format.NewColoredTable("NAME", "STATUS", "BRANCH", "MSGS", "TASK")
+	table := format.NewColoredTable("NAME", "STATUS", "IDLE", "BRANCH", "MSGS", "TASK")
 	for _, worker := range workers {
-		name, _ := worker["name"].(string)
-		task, _ := worker["task"].(string)
-		status, _ := worker["status"].(string)
-		branch, _ := worker["branch"].(string)
-		msgsTotal := 0
-		if v, ok := worker["messages_total"].(float64); ok {
-			msgsTotal = int(v)
-		}
-		msgsPending := 0
-		if v, ok := worker["messages_pending"].(float64); ok {
-			msgsPending = int(v)
-		}
-
 		// Format status with color
-		statusCell := formatAgentStatusCell(status)
+		statusCell := formatAgentStatusCell(worker.Status)
 
 		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
+		branchCell := format.ColorCell(worker.Branch, format.Cyan)
+		if worker.Branch == "" {
 			branchCell = format.ColorCell("-", format.Dim)
 		}
 
 		// Format message count
-		msgStr := format.MessageBadge(msgsPending, msgsTotal)
+		msgStr := format.MessageBadge(worker.MessagesPending, worker.MessagesTotal)
 
 		// Truncate task
-		truncTask := format.Truncate(task, 40)
+		truncTask := format.Truncate(worker.Task, 40)
 
 		table.AddRow(
-			format.Cell(name),
+			format.Cell(worker.Name),
 			statusCell,
+			formatIdleCell(worker),
 			branchCell,
 			format.Cell(msgStr),
 			format.Cell(truncTask),
 		)
 	}
 	table.Print()
+	printWorkerOverlaps(workers)
 
 	return nil
 }
 
+// printWorkerOverlaps lists the workers that changed the same files, once
+// per pair, flagging predicted merge conflicts
+func printWorkerOverlaps(workers []client.Agent) {
+	var lines []string
+	for _, worker := range workers {
+		for _, overlap := range worker.Overlaps {
+			if overlap.Worker < worker.Name {
+				continue
+			}
+			line := fmt.Sprintf("  %s ↔ %s: %d shared file(s)", worker.Name, overlap.Worker, len(overlap.Files))
+			if len(overlap.Conflicts) > 0 {
+				line = format.Red.Sprintf("%s, predicted conflicts in %s", line, format.Truncate(strings.Join(overlap.Conflicts, ", "), 60))
+			} else {
+				line = format.Yellow.Sprintf("%s: %s", line, format.Truncate(strings.Join(overlap.Files, ", "), 60))
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Println()
+	format.Header("Overlapping work:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// formatIdleCell formats how long an agent has been without output, colored by
+// the daemon's activity classification. Agents not yet sampled show "-".
+func formatIdleCell(agent client.Agent) format.ColoredCell {
+	if agent.Activity == "" {
+		return format.ColorCell("-", format.Dim)
+	}
+
+	text := formatIdleDuration(time.Duration(agent.IdleSeconds) * time.Second)
+	switch agent.Activity {
+	case "stuck":
+		return format.ColorCell(text+" (stuck)", format.Red)
+	case "idle":
+		return format.ColorCell(text, format.Yellow)
+	default:
+		return format.Cell(text)
+	}
+}
+
+// formatIdleDuration formats an idle duration compactly, e.g. "<1m", "12m", "2h5m"
+func formatIdleDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
 // listAgentDefinitions lists available agent definitions for a repository
 func (c *CLI) listAgentDefinitions(args []string) error {
 	flags, _ := ParseFlags(args)
@@ -2116,7 +2623,7 @@ func (c *CLI) listAgentDefinitions(args []string) error {
 	fmt.Printf("Agent definitions for %s:\n\n", repoName)
 
 	// Create colored table
-	table := format.NewColoredTable("Name", "Source", "Title", "Description")
+	table := format.NewColoredTable("Name", "Source", "Type", "Model", "Title", "Description")
 
 	for _, def := range defs {
 		source := string(def.Source)
@@ -2132,9 +2639,25 @@ func (c *CLI) listAgentDefinitions(args []string) error {
 			sourceCell = format.ColorCell(source, format.Green)
 		}
 
+		agentType := def.Metadata.Type
+		if agentType == "" {
+			agentType = "-"
+		}
+		typeCell := format.Cell(agentType)
+		if def.Metadata.AutoStart {
+			typeCell = format.ColorCell(agentType+" (auto-start)", format.Cyan)
+		}
+
+		model := def.Metadata.Model
+		if model == "" {
+			model = "-"
+		}
+
 		table.AddRow(
 			format.Cell(def.Name),
 			sourceCell,
+			typeCell,
+			format.Cell(model),
 			format.Cell(title),
 			format.Cell(desc),
 		)
@@ -2145,28 +2668,31 @@ func (c *CLI) listAgentDefinitions(args []string) error {
 	return nil
 }
 
-// spawnAgentFromFile spawns an agent using a prompt file and the daemon's spawn_agent handler.
+// spawnAgentFromFile spawns an agent from a definition or a prompt file using
+// the daemon's spawn_agent handler.
 // This is the CLI command that connects supervisor orchestration with daemon agent spawning.
 func (c *CLI) spawnAgentFromFile(args []string) error {
 	flags, _ := ParseFlags(args)
 
-	// Get required parameters
-	agentName, ok := flags["name"]
-	if !ok || agentName == "" {
-		return errors.InvalidUsage("--name is required")
+	definition := flags["definition"]
+	promptFile := flags["prompt-file"]
+	if definition == "" && promptFile == "" {
+		return errors.InvalidUsage("--definition or --prompt-file is required")
 	}
-
-	agentClass, ok := flags["class"]
-	if !ok || agentClass == "" {
-		return errors.InvalidUsage("--class is required (persistent or ephemeral)")
+	if definition != "" && promptFile != "" {
+		return errors.InvalidUsage("--definition and --prompt-file cannot be used together")
 	}
-	if agentClass != "persistent" && agentClass != "ephemeral" {
-		return errors.InvalidUsage("--class must be 'persistent' or 'ephemeral'")
+
+	// The name defaults to the definition's; a prompt file needs one
+	agentName := flags["name"]
+	if agentName == "" && definition == "" {
+		return errors.InvalidUsage("--name is required")
 	}
 
-	promptFile, ok := flags["prompt-file"]
-	if !ok || promptFile == "" {
-		return errors.InvalidUsage("--prompt-file is required")
+	// The class may come from the definition's frontmatter
+	agentClass := flags["class"]
+	if agentClass != "" && agentClass != "persistent" && agentClass != "ephemeral" {
+		return errors.InvalidUsage("--class must be 'persistent' or 'ephemeral'")
 	}
 
 	// Determine repository
@@ -2175,39 +2701,39 @@ func (c *CLI) spawnAgentFromFile(args []string) error {
 		return errors.NotInRepo()
 	}
 
-	// Read prompt from file
-	promptContent, err := os.ReadFile(promptFile)
-	if err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
-	}
-
 	// Get optional task parameter
 	task := flags["task"]
 
-	// Send spawn_agent request to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	reqArgs := map[string]interface{}{
-		"repo":   repoName,
-		"name":   agentName,
-		"class":  agentClass,
-		"prompt": string(promptContent),
-	}
-	if task != "" {
-		reqArgs["task"] = task
-	}
-
-	resp, err := client.Send(socket.Request{
-		Command: "spawn_agent",
-		Args:    reqArgs,
-	})
+	// Get optional Claude options; the daemon applies them on top of the repo's defaults
+	claudeOpts, err := claudeOptionsFromFlags(flags)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("spawning agent", err)
+		return err
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to spawn agent", fmt.Errorf("%s", resp.Error))
+
+	// Send spawn_agent request to daemon
+	req := client.SpawnAgentRequest{
+		Repo:       repoName,
+		Definition: definition,
+		Name:       agentName,
+		Class:      agentClass,
+		Task:       task,
+		Claude:     clientClaudeOptions(claudeOpts),
+	}
+	if definition == "" {
+		// Read prompt from file; the daemon parses any frontmatter in it
+		promptContent, err := os.ReadFile(promptFile)
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
+		}
+		req.Prompt = string(promptContent)
 	}
 
-	fmt.Printf("Agent '%s' spawned successfully (class: %s)\n", agentName, agentClass)
+	spawned, err := c.daemonClient().SpawnAgent(req)
+	if err != nil {
+		return daemonError(err, "spawning agent", "failed to spawn agent")
+	}
+
+	fmt.Printf("Agent '%s' spawned successfully (class: %s)\n", spawned.Name, spawned.Class)
 	return nil
 }
 
@@ -2296,30 +2822,23 @@ func (c *CLI) showHistory(args []string) error {
 	}
 
 	// Get task history from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "task_history",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"limit": fetchLimit,
-		},
-	})
+	daemonClient := c.daemonClient()
+	history, err := daemonClient.TaskHistory(repoName, fetchLimit)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting task history", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get task history", fmt.Errorf("%s", resp.Error))
+		return daemonError(err, "getting task history", "failed to get task history")
 	}
 
-	history, ok := resp.Data.([]interface{})
-	if !ok || len(history) == 0 {
+	if len(history) == 0 {
 		fmt.Printf("No task history for repository '%s'\n", repoName)
 		format.Dimmed("\nCreate workers with: multiclaude work <task>")
 		return nil
 	}
 
-	// Query GitHub for PR status for each task with a branch
-	repoPath := c.paths.RepoDir(repoName)
+	// Query the repo's forge for PR status for each task with a branch
+	prForge, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
 
 	// Build filtered header
 	headerParts := []string{fmt.Sprintf("Task History for '%s'", repoName)}
@@ -2333,32 +2852,25 @@ func (c *CLI) showHistory(args []string) error {
 	fmt.Println()
 
 	displayedCount := 0
-	for _, item := range history {
+	for _, entry := range history {
 		// Stop once we've displayed enough
 		if displayedCount >= limit {
 			break
 		}
 
-		entry, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		name, _ := entry["name"].(string)
-		task, _ := entry["task"].(string)
-		branch, _ := entry["branch"].(string)
-		prURL, _ := entry["pr_url"].(string)
-		completedAt, _ := entry["completed_at"].(string)
-		summary, _ := entry["summary"].(string)
-		failureReason, _ := entry["failure_reason"].(string)
-		storedStatus, _ := entry["status"].(string)
+		name := entry.Name
+		task := entry.Task
+		prURL := entry.PRURL
 
-		// Try to get PR status from GitHub if we have a branch
-		prStatus, _ := c.getPRStatusForBranch(repoPath, branch, prURL)
-
-		// Use stored status if it indicates failure
-		if storedStatus == "failed" {
-			prStatus = "failed"
+		// Use the status the daemon's PR tracking recorded, falling back to
+		// asking the forge for tasks it hasn't resolved yet. Listing history
+		// doesn't record what it finds; PR tracking does that.
+		var prStatus string
+		switch entry.Status {
+		case client.TaskStatusFailed, client.TaskStatusOpen, client.TaskStatusMerged, client.TaskStatusClosed, client.TaskStatusNoPR:
+			prStatus = entry.Status
+		default:
+			prStatus, _ = c.getPRStatusForBranch(prForge, entry.Branch, prURL)
 		}
 
 		// Apply status filter
@@ -2422,19 +2934,36 @@ func (c *CLI) showHistory(args []string) error {
 			format.Dimmed("  PR: none")
 		}
 
-		// Show completed time
-		if completedAt != "" {
-			if t, err := time.Parse(time.RFC3339, completedAt); err == nil {
-				fmt.Printf("  Completed: %s\n", format.TimeAgo(t))
+		// Show the PR's checks and reviews if the daemon has looked them up
+		if entry.CIStatus != "" || entry.ReviewStatus != "" {
+			fmt.Print("  Checks: ")
+			switch entry.CIStatus {
+			case "passing":
+				format.Green.Print("passing")
+			case "failing":
+				format.Red.Print("failing")
+			case "pending":
+				format.Yellow.Print("pending")
+			default:
+				format.Dim.Print("none")
+			}
+			if entry.ReviewStatus != "" {
+				fmt.Printf(", review: %s", strings.ToLower(strings.ReplaceAll(entry.ReviewStatus, "_", " ")))
 			}
+			fmt.Println()
+		}
+
+		// Show completed time
+		if !entry.CompletedAt.IsZero() {
+			fmt.Printf("  Completed: %s\n", format.TimeAgo(entry.CompletedAt))
 		}
 
 		// Show summary or failure reason
-		if summary != "" {
-			fmt.Printf("  Summary: %s\n", summary)
+		if entry.Summary != "" {
+			fmt.Printf("  Summary: %s\n", entry.Summary)
 		}
-		if failureReason != "" {
-			format.Red.Printf("  Failure: %s\n", failureReason)
+		if entry.FailureReason != "" {
+			format.Red.Printf("  Failure: %s\n", entry.FailureReason)
 		}
 
 		fmt.Println() // Blank line between entries
@@ -2451,8 +2980,8 @@ func (c *CLI) showHistory(args []string) error {
 	return nil
 }
 
-// getPRStatusForBranch queries GitHub for the PR status of a branch
-func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (status, prLink string) {
+// getPRStatusForBranch asks the forge for the PR status of a branch
+func (c *CLI) getPRStatusForBranch(f forge.Forge, branch, existingPRURL string) (status, prLink string) {
 	// If we already have a PR URL, just return it formatted
 	if existingPRURL != "" {
 		// Extract PR number from URL for shorter display
@@ -2469,33 +2998,18 @@ func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (stat
 		return "no-pr", ""
 	}
 
-	// Query GitHub for PR associated with this branch using gh CLI
-	cmd := exec.Command("gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return "no-pr", ""
-	}
-
-	// Parse JSON output
-	var prs []struct {
-		Number int    `json:"number"`
-		State  string `json:"state"`
-		URL    string `json:"url"`
-	}
-	if err := json.Unmarshal(output, &prs); err != nil || len(prs) == 0 {
+	pr, err := f.PRForBranch(branch)
+	if err != nil || pr == nil {
 		return "no-pr", ""
 	}
-
-	pr := prs[0]
 	prLink = fmt.Sprintf("#%d", pr.Number)
 
-	switch strings.ToLower(pr.State) {
-	case "merged":
+	switch pr.State {
+	case forge.StateMerged:
 		return "merged", prLink
-	case "open":
+	case forge.StateOpen:
 		return "open", prLink
-	case "closed":
+	case forge.StateClosed:
 		return "closed", prLink
 	default:
 		return "unknown", prLink
@@ -2512,21 +3026,11 @@ func (c *CLI) removeWorker(args []string) error {
 	}
 
 	// Get worker info
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	daemonClient := c.daemonClient()
+	agents, err := daemonClient.ListAgents(repoName)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting worker info", err)
+		return daemonError(err, "getting worker info", "failed to get worker info")
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
-	}
-
-	agents, _ := resp.Data.([]interface{})
 
 	// Determine worker name - from args or interactive selection
 	var workerName string
@@ -2552,13 +3056,11 @@ func (c *CLI) removeWorker(args []string) error {
 	fmt.Printf("Removing worker '%s' from repo '%s'\n", workerName, repoName)
 
 	// Find worker
-	var workerInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			if name, _ := agentMap["name"].(string); name == workerName {
-				workerInfo = agentMap
-				break
-			}
+	var workerInfo *client.Agent
+	for i := range agents {
+		if agents[i].Name == workerName {
+			workerInfo = &agents[i]
+			break
 		}
 	}
 
@@ -2567,7 +3069,7 @@ func (c *CLI) removeWorker(args []string) error {
 	}
 
 	// Get worktree path
-	wtPath := workerInfo["worktree_path"].(string)
+	wtPath := workerInfo.WorktreePath
 
 	// Check for uncommitted changes
 	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
@@ -2593,7 +3095,7 @@ func (c *CLI) removeWorker(args []string) error {
 
 	// Kill tmux window
 	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workerInfo["tmux_window"].(string)
+	tmuxWindow := workerInfo.TmuxWindow
 	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
 	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
 	if err := cmd.Run(); err != nil {
@@ -2610,19 +3112,9 @@ func (c *CLI) removeWorker(args []string) error {
 	}
 
 	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": workerName,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.RemoveAgent(repoName, workerName); err != nil {
 		return fmt.Errorf("failed to unregister worker: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to unregister worker: %s", resp.Error)
-	}
 
 	fmt.Println("✓ Worker removed successfully")
 	return nil
@@ -2677,28 +3169,15 @@ func (c *CLI) addWorkspace(args []string) error {
 	}
 
 	// Check if workspace already exists
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	daemonClient := c.daemonClient()
+	agents, err := daemonClient.ListAgents(repoName)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("checking existing workspaces", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to check existing workspaces", fmt.Errorf("%s", resp.Error))
+		return daemonError(err, "checking existing workspaces", "failed to check existing workspaces")
 	}
 
-	agents, _ := resp.Data.([]interface{})
 	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
-			}
+		if agent.Type == client.AgentTypeWorkspace && agent.Name == workspaceName {
+			return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
 		}
 	}
 
@@ -2742,6 +3221,8 @@ func (c *CLI) addWorkspace(args []string) error {
 		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
 	}
 
+	claudeOpts := c.repoClaudeOptions(repoName, state.AgentTypeWorkspace, state.ClaudeOptions{})
+
 	// Start Claude in workspace window (skip in test mode)
 	var workspacePID int
 	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
@@ -2752,7 +3233,7 @@ func (c *CLI) addWorkspace(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, "")
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, "", claudeOpts)
 		if err != nil {
 			return fmt.Errorf("failed to start workspace Claude: %w", err)
 		}
@@ -2765,24 +3246,18 @@ func (c *CLI) addWorkspace(args []string) error {
 	}
 
 	// Register workspace with daemon
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workspaceName,
-			"type":          "workspace",
-			"worktree_path": wtPath,
-			"tmux_window":   workspaceName,
-			"session_id":    workspaceSessionID,
-			"pid":           workspacePID,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.AddAgent(client.AddAgentRequest{
+		Repo:         repoName,
+		Agent:        workspaceName,
+		Type:         client.AgentTypeWorkspace,
+		WorktreePath: wtPath,
+		TmuxWindow:   workspaceName,
+		SessionID:    workspaceSessionID,
+		PID:          workspacePID,
+		Claude:       clientClaudeOptions(claudeOpts),
+	}); err != nil {
 		return fmt.Errorf("failed to register workspace: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register workspace: %s", resp.Error)
-	}
 
 	fmt.Println()
 	fmt.Println("✓ Workspace created successfully!")
@@ -2806,22 +3281,12 @@ func (c *CLI) removeWorkspace(args []string) error {
 	}
 
 	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	daemonClient := c.daemonClient()
+	agents, err := daemonClient.ListAgents(repoName)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting workspace info", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get workspace info", fmt.Errorf("%s", resp.Error))
+		return daemonError(err, "getting workspace info", "failed to get workspace info")
 	}
 
-	agents, _ := resp.Data.([]interface{})
-
 	// Determine workspace name - from args or interactive selection
 	var workspaceName string
 	if len(remainingArgs) > 0 {
@@ -2846,15 +3311,11 @@ func (c *CLI) removeWorkspace(args []string) error {
 	fmt.Printf("Removing workspace '%s' from repo '%s'\n", workspaceName, repoName)
 
 	// Find workspace
-	var workspaceInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				workspaceInfo = agentMap
-				break
-			}
+	var workspaceInfo *client.Agent
+	for i := range agents {
+		if agents[i].Type == client.AgentTypeWorkspace && agents[i].Name == workspaceName {
+			workspaceInfo = &agents[i]
+			break
 		}
 	}
 
@@ -2863,7 +3324,7 @@ func (c *CLI) removeWorkspace(args []string) error {
 	}
 
 	// Get worktree path
-	wtPath := workspaceInfo["worktree_path"].(string)
+	wtPath := workspaceInfo.WorktreePath
 
 	// Check for uncommitted changes
 	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
@@ -2889,7 +3350,7 @@ func (c *CLI) removeWorkspace(args []string) error {
 
 	// Kill tmux window
 	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
+	tmuxWindow := workspaceInfo.TmuxWindow
 	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
 	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
 	if err := cmd.Run(); err != nil {
@@ -2906,19 +3367,9 @@ func (c *CLI) removeWorkspace(args []string) error {
 	}
 
 	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": workspaceName,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.RemoveAgent(repoName, workspaceName); err != nil {
 		return fmt.Errorf("failed to unregister workspace: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to unregister workspace: %s", resp.Error)
-	}
 
 	fmt.Println("✓ Workspace removed successfully")
 	return nil
@@ -2934,35 +3385,16 @@ func (c *CLI) listWorkspaces(args []string) error {
 		return errors.NotInRepo()
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-			"rich": true,
-		},
-	})
+	agents, err := c.daemonClient().ListAgentsDetailed(repoName)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("listing workspaces", err)
-	}
-
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
-	}
-
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+		return daemonError(err, "listing workspaces", "failed to list workspaces")
 	}
 
 	// Filter for workspaces
-	workspaces := []map[string]interface{}{}
+	workspaces := []client.Agent{}
 	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "workspace" {
-				workspaces = append(workspaces, agentMap)
-			}
+		if agent.Type == client.AgentTypeWorkspace {
+			workspaces = append(workspaces, agent)
 		}
 	}
 
@@ -2977,21 +3409,17 @@ func (c *CLI) listWorkspaces(args []string) error {
 
 	table := format.NewColoredTable("NAME", "BRANCH", "STATUS")
 	for _, ws := range workspaces {
-		name, _ := ws["name"].(string)
-		status, _ := ws["status"].(string)
-		branch, _ := ws["branch"].(string)
-
 		// Format status with color
-		statusCell := formatAgentStatusCell(status)
+		statusCell := formatAgentStatusCell(ws.Status)
 
 		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
+		branchCell := format.ColorCell(ws.Branch, format.Cyan)
+		if ws.Branch == "" {
 			branchCell = format.ColorCell("-", format.Dim)
 		}
 
 		table.AddRow(
-			format.Cell(name),
+			format.Cell(ws.Name),
 			branchCell,
 			statusCell,
 		)
@@ -3012,21 +3440,13 @@ func (c *CLI) connectWorkspace(args []string) error {
 	}
 
 	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	agents, err := c.daemonClient().ListAgents(repoName)
 	if err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("failed to get workspace info: %w", err)
+		}
 		return fmt.Errorf("failed to get workspace info: %w (is daemon running?)", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to get workspace info: %s", resp.Error)
-	}
-
-	agents, _ := resp.Data.([]interface{})
 
 	// Determine workspace name - from args or interactive selection
 	var workspaceName string
@@ -3050,15 +3470,11 @@ func (c *CLI) connectWorkspace(args []string) error {
 	}
 
 	// Find workspace
-	var workspaceInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				workspaceInfo = agentMap
-				break
-			}
+	var workspaceInfo *client.Agent
+	for i := range agents {
+		if agents[i].Type == client.AgentTypeWorkspace && agents[i].Name == workspaceName {
+			workspaceInfo = &agents[i]
+			break
 		}
 	}
 
@@ -3068,7 +3484,7 @@ func (c *CLI) connectWorkspace(args []string) error {
 
 	// Get tmux session and window
 	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
+	tmuxWindow := workspaceInfo.TmuxWindow
 
 	// Attach to tmux
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
@@ -3128,38 +3544,26 @@ func validateWorkspaceName(name string) error {
 
 // getReposList is a helper to get the list of repos
 func (c *CLI) getReposList() []string {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{Command: "list_repos"})
-	if err != nil {
+	repos, err := c.daemonClient().ListRepos()
+	if err != nil || repos == nil {
 		return []string{}
 	}
-
-	if !resp.Success {
-		return []string{}
-	}
-
-	repos, ok := resp.Data.([]interface{})
-	if !ok {
-		return []string{}
-	}
-
-	result := make([]string, 0, len(repos))
-	for _, repo := range repos {
-		if repoStr, ok := repo.(string); ok {
-			result = append(result, repoStr)
-		}
-	}
-
-	return result
+	return repos
 }
 
 func (c *CLI) sendMessage(args []string) error {
-	if len(args) < 2 {
-		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message>")
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message> [--reply-to <message-id>] [--priority urgent|normal|low] [--subject <text>] [--ttl <duration>]")
 	}
 
-	to := args[0]
-	body := strings.Join(args[1:], " ")
+	to := posArgs[0]
+	body := strings.Join(posArgs[1:], " ")
+
+	priority, err := messages.ParsePriority(flags["priority"])
+	if err != nil {
+		return errors.InvalidUsage(err.Error())
+	}
 
 	// Determine current agent and repo
 	repoName, agentName, err := c.inferAgentContext()
@@ -3170,22 +3574,69 @@ func (c *CLI) sendMessage(args []string) error {
 	// Create message manager
 	msgMgr := messages.NewManager(c.paths.MessagesDir)
 
+	opts := messages.SendOptions{
+		ReplyTo:  flags["reply-to"],
+		Priority: priority,
+		Subject:  flags["subject"],
+	}
+	if ttl, ok := flags["ttl"]; ok {
+		if opts.TTL, err = parseDuration(ttl); err != nil || opts.TTL <= 0 {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --ttl %q (use e.g. 30m, 2h, 1d)", ttl))
+		}
+	}
+	if messages.IsGroupRecipient(to) {
+		return c.broadcastMessage(msgMgr, repoName, agentName, to, body, opts)
+	}
+
 	// Send message
-	msg, err := msgMgr.Send(repoName, agentName, to, body)
+	msg, err := msgMgr.SendWithOptions(repoName, agentName, to, body, opts)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 
 	// Trigger immediate routing (best-effort, polling is fallback)
-	client := socket.NewClient(c.paths.DaemonSock)
-	_, _ = client.Send(socket.Request{Command: "route_messages"})
+	_ = c.daemonClient().RouteMessages()
 	// Ignore errors - 2-minute polling fallback will catch it
 
-	fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
+	if msg.ReplyTo != "" {
+		fmt.Printf("Reply sent to %s (ID: %s, thread: %s)\n", to, msg.ID, msg.ThreadID)
+	} else {
+		fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
+	}
+	return nil
+}
+
+// broadcastMessage sends a message to every agent matching a group
+// expression (@all, @workers, @persistent) or name glob
+func (c *CLI) broadcastMessage(msgMgr *messages.Manager, repoName, from, to, body string, opts messages.SendOptions) error {
+	if opts.ReplyTo != "" {
+		return errors.InvalidUsage("--reply-to can only be used with a single recipient")
+	}
+
+	recipients, err := c.daemonClient().ResolveRecipients(repoName, to, from)
+	if err != nil {
+		if reqErr, ok := err.(*client.RequestError); ok {
+			return errors.InvalidUsage(reqErr.Error())
+		}
+		return daemonError(err, "resolving recipients", "failed to resolve recipients")
+	}
+
+	sent, err := msgMgr.Broadcast(repoName, from, recipients, body, opts)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	_ = c.daemonClient().RouteMessages()
+
+	fmt.Printf("Message sent to %d agents matching %s (broadcast ID: %s): %s\n",
+		len(sent), to, sent[0].BroadcastID, strings.Join(recipients, ", "))
 	return nil
 }
 
 func (c *CLI) listMessages(args []string) error {
+	flags, _ := ParseFlags(args)
+
 	// Determine current agent and repo
 	repoName, agentName, err := c.inferAgentContext()
 	if err != nil {
@@ -3205,23 +3656,122 @@ func (c *CLI) listMessages(args []string) error {
 		return nil
 	}
 
+	if flags["threads"] == "true" {
+		return printMessageThreads(msgMgr, repoName, agentName, msgs)
+	}
+
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].Timestamp.Before(msgs[j].Timestamp)
+	})
+
 	fmt.Printf("Messages for %s (%d):\n", agentName, len(msgs))
 	for _, msg := range msgs {
-		status := msg.Status
-		if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
-			status = messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
-		}
-		fmt.Printf("  [%s] %s - From: %s - %s - %s\n",
+		fmt.Printf("  [%s] %s - From: %s - %s%s - %s\n",
 			msg.ID,
 			formatTime(msg.Timestamp),
 			msg.From,
-			status,
-			truncateString(msg.Body, 60))
+			formatMessageStatus(msg),
+			formatMessagePriority(msg.Priority),
+			truncateString(messageSummary(msg), 60))
+	}
+
+	return nil
+}
+
+// printMessageThreads prints an agent's messages grouped into conversations.
+// Each thread includes the agent's own messages in it, so both sides of a
+// conversation are shown.
+func printMessageThreads(msgMgr *messages.Manager, repoName, agentName string, inbox []*messages.Message) error {
+	all, err := msgMgr.ListRepo(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	threads := make(map[string][]*messages.Message)
+	for _, msg := range inbox {
+		threads[msg.Thread()] = nil
+	}
+	for _, msg := range all {
+		if _, ok := threads[msg.Thread()]; !ok {
+			continue
+		}
+		if msg.To == agentName || msg.From == agentName {
+			threads[msg.Thread()] = append(threads[msg.Thread()], msg)
+		}
+	}
+
+	// Most recently active threads first
+	ids := make([]string, 0, len(threads))
+	for id, msgs := range threads {
+		sort.SliceStable(msgs, func(i, j int) bool {
+			return msgs[i].Timestamp.Before(msgs[j].Timestamp)
+		})
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := threads[ids[i]], threads[ids[j]]
+		return a[len(a)-1].Timestamp.After(b[len(b)-1].Timestamp)
+	})
+
+	fmt.Printf("Message threads for %s (%d):\n", agentName, len(ids))
+	for _, id := range ids {
+		msgs := threads[id]
+		subject := msgs[0].Subject
+		if subject == "" {
+			subject = "(no subject)"
+		}
+		fmt.Printf("\n%s %s\n", format.Bold.Sprint(subject), format.Dim.Sprintf("[thread %s, %d messages]", id, len(msgs)))
+		for _, msg := range msgs {
+			indent := "  "
+			if msg.ReplyTo != "" {
+				indent = "    ↳ "
+			}
+			status := ""
+			if msg.To == agentName {
+				status = " - " + string(formatMessageStatus(msg))
+			}
+			fmt.Printf("%s[%s] %s %s → %s%s%s: %s\n",
+				indent,
+				msg.ID,
+				formatTime(msg.Timestamp),
+				msg.From,
+				msg.To,
+				status,
+				formatMessagePriority(msg.Priority),
+				truncateString(msg.Body, 60))
+		}
 	}
 
 	return nil
 }
 
+// formatMessageStatus returns a message's status, with the ack time if acked
+func formatMessageStatus(msg *messages.Message) messages.Status {
+	if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
+		return messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
+	}
+	return msg.Status
+}
+
+// formatMessagePriority returns a marker for non-normal priorities
+func formatMessagePriority(priority messages.Priority) string {
+	switch priority {
+	case messages.PriorityUrgent:
+		return " " + format.Red.Sprint("[urgent]")
+	case messages.PriorityLow:
+		return " " + format.Dim.Sprint("[low]")
+	}
+	return ""
+}
+
+// messageSummary returns a message's subject and body for one-line listings
+func messageSummary(msg *messages.Message) string {
+	if msg.Subject == "" {
+		return msg.Body
+	}
+	return msg.Subject + ": " + msg.Body
+}
+
 func (c *CLI) readMessage(args []string) error {
 	if len(args) < 1 {
 		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id>")
@@ -3259,8 +3809,23 @@ func (c *CLI) readMessage(args []string) error {
 	if msg.AckedAt != nil {
 		fmt.Printf("Acked: %s\n", msg.AckedAt.Format(time.RFC3339))
 	}
+	if msg.Priority != "" {
+		fmt.Printf("Priority: %s\n", msg.Priority)
+	}
+	if msg.Subject != "" {
+		fmt.Printf("Subject: %s\n", msg.Subject)
+	}
+	fmt.Printf("Thread: %s\n", msg.Thread())
+	if msg.ReplyTo != "" {
+		fmt.Printf("Reply to: %s\n", msg.ReplyTo)
+	}
+	if msg.BroadcastID != "" {
+		fmt.Printf("Broadcast: %s\n", msg.BroadcastID)
+	}
 	fmt.Println()
 	fmt.Println(msg.Body)
+	fmt.Println()
+	fmt.Printf("Reply with: multiclaude agent send-message %s --reply-to %s <message>\n", msg.From, msg.ID)
 
 	return nil
 }
@@ -3289,6 +3854,123 @@ func (c *CLI) ackMessage(args []string) error {
 	return nil
 }
 
+func (c *CLI) listDeadLetters(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	dead, err := msgMgr.ListDeadLetters(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	if len(dead) == 0 {
+		fmt.Printf("No dead letters for %s\n", repoName)
+		return nil
+	}
+
+	fmt.Printf("Dead letters for %s (%d):\n", repoName, len(dead))
+	for _, msg := range dead {
+		fmt.Printf("  [%s] %s -> %s - %s\n", msg.ID, msg.From, msg.To, messageSummary(msg))
+		fmt.Printf("    %s\n", format.Dim.Sprint(msg.DeadReason))
+	}
+	fmt.Println()
+	fmt.Println("Inspect with: multiclaude agent dead-letters show <message-id>")
+	fmt.Println("Re-send with: multiclaude agent dead-letters resend <message-id> [--to <agent>]")
+	return nil
+}
+
+func (c *CLI) showDeadLetter(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent dead-letters show <message-id> [--repo <repo>]")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.GetDeadLetter(repoName, posArgs[0])
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter: %w", err)
+	}
+
+	fmt.Printf("Message ID: %s\n", msg.ID)
+	fmt.Printf("From: %s\n", msg.From)
+	fmt.Printf("To: %s\n", msg.To)
+	fmt.Printf("Sent: %s\n", msg.Timestamp.Format(time.RFC3339))
+	if msg.ExpiresAt != nil {
+		fmt.Printf("Expires: %s\n", msg.ExpiresAt.Format(time.RFC3339))
+	}
+	if msg.DeadAt != nil {
+		fmt.Printf("Dead-lettered: %s\n", msg.DeadAt.Format(time.RFC3339))
+	}
+	fmt.Printf("Reason: %s\n", msg.DeadReason)
+	fmt.Printf("Delivery attempts: %d\n", msg.Attempts)
+	if msg.LastError != "" {
+		fmt.Printf("Last error: %s\n", msg.LastError)
+	}
+	if msg.Subject != "" {
+		fmt.Printf("Subject: %s\n", msg.Subject)
+	}
+	fmt.Println()
+	fmt.Println(msg.Body)
+	return nil
+}
+
+func (c *CLI) resendDeadLetter(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent dead-letters resend <message-id> [--to <agent>] [--repo <repo>]")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	msg, err := msgMgr.ResendDeadLetter(repoName, posArgs[0], flags["to"])
+	if err != nil {
+		return fmt.Errorf("failed to re-send dead letter: %w", err)
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	_ = c.daemonClient().RouteMessages()
+
+	fmt.Printf("Message %s re-sent to %s\n", msg.ID, msg.To)
+	return nil
+}
+
+func (c *CLI) purgeDeadLetter(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent dead-letters purge <message-id> [--repo <repo>]")
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	if _, err := msgMgr.GetDeadLetter(repoName, posArgs[0]); err != nil {
+		return fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	if err := msgMgr.DeleteDeadLetter(repoName, posArgs[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Dead letter %s purged\n", posArgs[0])
+	return nil
+}
+
 // inferRepoFromCwd infers just the repository name from the current working directory.
 // Unlike inferAgentContext, it doesn't require determining the specific agent.
 func (c *CLI) inferRepoFromCwd() (string, error) {
@@ -3433,14 +4115,8 @@ func (c *CLI) resolveRepo(flags map[string]string) (string, error) {
 	}
 
 	// 4. Check current repo from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_current_repo",
-	})
-	if err == nil && resp.Success {
-		if currentRepo, ok := resp.Data.(string); ok && currentRepo != "" {
-			return currentRepo, nil
-		}
+	if currentRepo, err := c.daemonClient().GetCurrentRepo(); err == nil && currentRepo != "" {
+		return currentRepo, nil
 	}
 
 	return "", fmt.Errorf("could not determine repository; use --repo flag or run 'multiclaude repo use <name>'")
@@ -3587,34 +4263,26 @@ func (c *CLI) completeWorker(args []string) error {
 
 	fmt.Printf("Marking agent '%s' as complete...\n", agentName)
 
-	// Build request args
-	reqArgs := map[string]interface{}{
-		"repo":  repoName,
-		"agent": agentName,
+	// Build request
+	req := client.CompleteAgentRequest{
+		Repo:  repoName,
+		Agent: agentName,
 	}
 
 	// Add optional summary
 	if summary, ok := flags["summary"]; ok && summary != "" {
-		reqArgs["summary"] = summary
+		req.Summary = summary
 		fmt.Printf("Summary: %s\n", summary)
 	}
 
 	// Add optional failure reason
 	if failureReason, ok := flags["failure"]; ok && failureReason != "" {
-		reqArgs["failure_reason"] = failureReason
+		req.FailureReason = failureReason
 		fmt.Printf("Failure reason: %s\n", failureReason)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "complete_agent",
-		Args:    reqArgs,
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("marking agent complete", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to mark agent complete", fmt.Errorf("%s", resp.Error))
+	if err := c.daemonClient().CompleteAgent(req); err != nil {
+		return daemonError(err, "marking agent complete", "failed to mark agent complete")
 	}
 
 	fmt.Println("✓ Agent marked as complete")
@@ -3628,7 +4296,7 @@ func (c *CLI) restartAgentCmd(args []string) error {
 
 	// Get agent name from args
 	if len(remaining) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force]")
+		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force] [--reset]")
 	}
 	agentName := remaining[0]
 
@@ -3644,201 +4312,622 @@ func (c *CLI) restartAgentCmd(args []string) error {
 	}
 
 	force := flags["force"] == "true"
+	reset := flags["reset"] == "true"
 
 	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, repoName)
+	if reset {
+		fmt.Println("Clearing its restart history")
+	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "restart_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": agentName,
-			"force": force,
-		},
+	result, err := c.daemonClient().RestartAgent(client.RestartAgentRequest{
+		Repo:  repoName,
+		Agent: agentName,
+		Force: force,
+		Reset: reset,
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("restarting agent", err)
+		return daemonError(err, "restarting agent", "failed to restart agent")
+	}
+
+	if result.PID > 0 {
+		fmt.Printf("✓ Agent '%s' restarted successfully (PID: %d)\n", agentName, result.PID)
+	} else {
+		fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
+	}
+
+	return nil
+}
+
+// reviewPR spawns a review agent for a PR on the repo's forge, given by
+// number or GitHub URL
+func (c *CLI) reviewPR(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) < 1 {
+		return errors.InvalidUsage("usage: multiclaude review <pr-number|pr-url>")
+	}
+
+	// Accept a PR number, or a GitHub PR URL in one of these formats:
+	// - https://github.com/owner/repo/pull/123
+	// - github.com/owner/repo/pull/123
+	number, err := parsePRNumber(positional[0])
+	if err != nil {
+		prURL := strings.TrimPrefix(positional[0], "https://")
+		prURL = strings.TrimPrefix(prURL, "http://")
+		parts := strings.Split(prURL, "/")
+		if len(parts) < 5 || parts[3] != "pull" {
+			return errors.InvalidPRURL()
+		}
+		if number, err = parsePRNumber(parts[4]); err != nil {
+			return errors.InvalidPRURL()
+		}
+	}
+	prNumber := strconv.Itoa(number)
+	fmt.Printf("Reviewing PR #%s\n", prNumber)
+
+	// Determine repository from flag or current directory
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
+	} else {
+		// Try to infer from current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		// Check if we're in a tracked repo
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoPath := c.paths.RepoDir(repo)
+			if strings.HasPrefix(cwd, repoPath) {
+				repoName = repo
+				break
+			}
+		}
+
+		if repoName == "" {
+			return errors.NotInRepo()
+		}
+	}
+
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	pr, err := f.GetPR(number)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to look up PR #%s", prNumber), err).
+			WithSuggestion("check the PR number with: multiclaude pr list --state all")
+	}
+
+	// Generate review agent name
+	reviewerName := fmt.Sprintf("review-%s", prNumber)
+
+	fmt.Printf("Creating review agent '%s' in repo '%s'\n", reviewerName, repoName)
+
+	// Get repository path
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Fetch the PR using GitHub's PR refs - this works for both same-repo and fork PRs
+	// The refs/pull/<number>/head ref always exists and points to the PR's head commit
+	// The local forge has no PR refs, so its PRs are fetched by branch
+	fmt.Printf("Fetching PR #%s...\n", prNumber)
+	prRef := fmt.Sprintf("refs/pull/%s/head", prNumber)
+	if f.Name() == "local" {
+		prRef = "refs/heads/" + pr.Head
+	}
+	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
+	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
+			WithSuggestion("ensure the PR exists and you have access to the repository")
+	}
+
+	// Create worktree for review
+	wt := worktree.NewManager(repoPath)
+	wtPath := c.paths.AgentWorktree(repoName, reviewerName)
+	reviewBranch := fmt.Sprintf("review/%s", reviewerName)
+
+	fmt.Printf("Creating worktree at: %s\n", wtPath)
+	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	// Get tmux session name
+	tmuxSession := sanitizeTmuxSessionName(repoName)
+
+	// Create tmux window for reviewer (detached so it doesn't switch focus)
+	fmt.Printf("Creating tmux window: %s\n", reviewerName)
+	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create tmux window: %w", err)
+	}
+
+	// Generate session ID for reviewer
+	reviewerSessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate reviewer session ID: %w", err)
+	}
+
+	// Write prompt file for reviewer
+	reviewerPromptFile, err := c.writePromptFile(repoPath, state.AgentTypeReview, reviewerName)
+	if err != nil {
+		return fmt.Errorf("failed to write reviewer prompt: %w", err)
+	}
+
+	// Copy hooks configuration if it exists
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
+
+	claudeOpts := c.repoClaudeOptions(repoName, state.AgentTypeReview, state.ClaudeOptions{})
+
+	// Start Claude in reviewer window with initial task (skip in test mode)
+	var reviewerPID int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		// Resolve claude binary
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in reviewer window...")
+		initialMessage := fmt.Sprintf("Review PR #%s: %s", prNumber, pr.URL)
+		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, reviewerName, wtPath, reviewerSessionID, reviewerPromptFile, repoName, initialMessage, claudeOpts)
+		if err != nil {
+			return fmt.Errorf("failed to start reviewer Claude: %w", err)
+		}
+		reviewerPID = pid
+
+		// Set up output capture for reviewer
+		if err := c.setupOutputCapture(tmuxSession, reviewerName, repoName, reviewerName, "review"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for reviewer: %v\n", err)
+		}
+	}
+
+	// Register reviewer with daemon
+	if err := c.daemonClient().AddAgent(client.AddAgentRequest{
+		Repo:         repoName,
+		Agent:        reviewerName,
+		Type:         client.AgentTypeReview,
+		WorktreePath: wtPath,
+		TmuxWindow:   reviewerName,
+		Task:         fmt.Sprintf("Review PR #%s", prNumber),
+		SessionID:    reviewerSessionID,
+		PID:          reviewerPID,
+		Claude:       clientClaudeOptions(claudeOpts),
+	}); err != nil {
+		return fmt.Errorf("failed to register reviewer: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Review agent created successfully!")
+	fmt.Printf("  Name: %s\n", reviewerName)
+	fmt.Printf("  Branch: %s\n", reviewBranch)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	fmt.Printf("\nAttach to reviewer: tmux select-window -t %s:%s\n", tmuxSession, reviewerName)
+	fmt.Printf("Or use: multiclaude attach %s\n", reviewerName)
+
+	return nil
+}
+
+// PR command implementations
+
+// repoForge returns the forge hosting a tracked repo's PRs
+func (c *CLI) repoForge(repoName string) (forge.Forge, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+	return forge.New(repo.GithubURL, c.paths.RepoDir(repoName), github.NewClient("gh")), nil
+}
+
+// usesLocalForge reports whether a tracked repo's PRs are on the local forge
+func (c *CLI) usesLocalForge(repoName string) bool {
+	st, err := c.loadState()
+	if err != nil {
+		return false
+	}
+	repo, exists := st.GetRepo(repoName)
+	return exists && forge.IsLocal(repo.GithubURL)
+}
+
+// createPR opens a PR for a pushed branch. The head defaults to the current
+// branch, the base to the agent's base branch, and the title to the head's
+// last commit subject.
+func (c *CLI) createPR(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	repo, exists := st.GetRepo(repoName)
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+	_, agentName, _ := c.inferAgentContext()
+
+	head := flags["head"]
+	if head == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		head, err = worktree.GetCurrentBranch(cwd)
+		if err != nil || head == "HEAD" {
+			return errors.InvalidUsage("could not determine the branch to open a PR for; use --head <branch>")
+		}
+	}
+
+	base := flags["base"]
+	if base == "" {
+		agent, _ := st.GetAgent(repoName, agentName)
+		base = repo.BaseBranchFor(agent)
+	}
+	if base == "" {
+		base = c.repoDefaultBranch(repoName, worktree.NewManager(c.paths.RepoDir(repoName)))
+	}
+	if base == "" {
+		return errors.InvalidUsage("could not determine the base branch; use --base <branch>")
+	}
+
+	title := flags["title"]
+	if title == "" {
+		output, err := exec.Command("git", "log", "-1", "--format=%s", head).Output()
+		title = strings.TrimSpace(string(output))
+		if err != nil || title == "" {
+			return errors.InvalidUsage("could not take the title from the last commit; use --title <title>")
+		}
+	}
+
+	author := agentName
+	if author == "" {
+		author = os.Getenv("USER")
+	}
+
+	f := forge.New(repo.GithubURL, c.paths.RepoDir(repoName), github.NewClient("gh"))
+	pr, err := f.CreatePR(forge.CreateOptions{
+		Head:   head,
+		Base:   base,
+		Title:  title,
+		Body:   flags["body"],
+		Author: author,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	fmt.Printf("✓ Created PR #%d: %s (%s → %s)\n", pr.Number, pr.Title, pr.Head, pr.Base)
+	fmt.Println(pr.URL)
+	return nil
+}
+
+// listPRs lists a repo's PRs, open ones by default
+func (c *CLI) listPRs(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	stateFilter := strings.ToLower(flags["state"])
+	var prState string
+	switch stateFilter {
+	case "", "open":
+		stateFilter = "open"
+		prState = forge.StateOpen
+	case "merged":
+		prState = forge.StateMerged
+	case "closed":
+		prState = forge.StateClosed
+	case "all":
+		prState = ""
+	default:
+		return errors.InvalidUsage(fmt.Sprintf("invalid --state %q (must be open, merged, closed or all)", stateFilter))
+	}
+
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	prs, err := f.ListPRs(prState, 50)
+	if err != nil {
+		return fmt.Errorf("failed to list PRs: %w", err)
+	}
+
+	if len(prs) == 0 {
+		fmt.Printf("No %s PRs for repository '%s'\n", stateFilter, repoName)
+		format.Dimmed("\nOpen one with: multiclaude pr create")
+		return nil
+	}
+
+	format.Header("PRs for '%s' (%s forge):", repoName, f.Name())
+	fmt.Println()
+
+	table := format.NewColoredTable("PR", "STATE", "CI", "REVIEW", "BRANCH", "TITLE")
+	for _, pr := range prs {
+		stateCell := format.Cell(strings.ToLower(pr.State))
+		switch pr.State {
+		case forge.StateOpen:
+			stateCell = format.ColorCell("open", format.Green)
+		case forge.StateMerged:
+			stateCell = format.ColorCell("merged", format.Cyan)
+		case forge.StateClosed:
+			stateCell = format.ColorCell("closed", format.Dim)
+		}
+
+		ciCell := format.ColorCell("-", format.Dim)
+		switch pr.CIStatus {
+		case forge.CIPassing:
+			ciCell = format.ColorCell(formatCIStatus("passing"), format.Green)
+		case forge.CIFailing:
+			ciCell = format.ColorCell(formatCIStatus("failing"), format.Red)
+		case forge.CIPending:
+			ciCell = format.ColorCell(formatCIStatus("pending"), format.Yellow)
+		}
+
+		reviewCell := format.ColorCell("-", format.Dim)
+		switch pr.ReviewDecision {
+		case "":
+		case forge.ReviewApproved:
+			reviewCell = format.ColorCell("approved", format.Green)
+		case forge.ReviewChangesRequested:
+			reviewCell = format.ColorCell("changes requested", format.Red)
+		default:
+			reviewCell = format.Cell(strings.ToLower(strings.ReplaceAll(pr.ReviewDecision, "_", " ")))
+		}
+
+		table.AddRow(
+			format.Cell(fmt.Sprintf("#%d", pr.Number)),
+			stateCell,
+			ciCell,
+			reviewCell,
+			format.ColorCell(fmt.Sprintf("%s → %s", pr.Head, pr.Base), format.Cyan),
+			format.Cell(format.Truncate(pr.Title, 50)),
+		)
+	}
+	table.Print()
+
+	return nil
+}
+
+// mergePR merges a PR into its base branch
+func (c *CLI) mergePR(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) < 1 {
+		return errors.InvalidUsage("usage: multiclaude pr merge <number> [--squash] [--delete-branch] [--repo <repo>]")
+	}
+	number, err := parsePRNumber(positional[0])
+	if err != nil {
+		return err
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	opts := forge.MergeOptions{
+		Method:       forge.MergeMethodMerge,
+		DeleteBranch: flags["delete-branch"] == "true",
+	}
+	if flags["squash"] == "true" {
+		opts.Method = forge.MergeMethodSquash
+	}
+
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	if err := f.MergePR(number, opts); err != nil {
+		return fmt.Errorf("failed to merge PR #%d: %w", number, err)
+	}
+
+	fmt.Printf("✓ Merged PR #%d\n", number)
+	return nil
+}
+
+// viewPR shows a PR's branches, CI status and reviews
+func (c *CLI) viewPR(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) < 1 {
+		return errors.InvalidUsage("usage: multiclaude pr view <number> [--repo <repo>]")
+	}
+	number, err := parsePRNumber(positional[0])
+	if err != nil {
+		return err
+	}
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	pr, err := f.GetPR(number)
+	if err != nil {
+		return fmt.Errorf("failed to get PR #%d: %w", number, err)
+	}
+
+	ci := pr.CIStatus
+	switch {
+	case ci == "":
+		ci = "no checks"
+	case len(pr.FailedChecks) > 0:
+		ci += fmt.Sprintf(" (failed: %s)", strings.Join(pr.FailedChecks, ", "))
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to restart agent", fmt.Errorf("%s", resp.Error))
+	review := strings.ToLower(strings.ReplaceAll(pr.ReviewDecision, "_", " "))
+	if review == "" {
+		review = "none"
 	}
 
-	// Extract PID from response
-	if data, ok := resp.Data.(map[string]interface{}); ok {
-		if pid, ok := data["pid"].(float64); ok {
-			fmt.Printf("✓ Agent '%s' restarted successfully (PID: %d)\n", agentName, int(pid))
-		} else {
-			fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
+	format.Header("PR #%d: %s", pr.Number, pr.Title)
+	fmt.Printf("  State:  %s\n", strings.ToLower(pr.State))
+	fmt.Printf("  Branch: %s → %s\n", pr.Head, pr.Base)
+	fmt.Printf("  CI:     %s\n", ci)
+	fmt.Printf("  Review: %s\n", review)
+	fmt.Printf("  URL:    %s\n", pr.URL)
+
+	if len(pr.Reviews) > 0 {
+		fmt.Println()
+		format.Header("Reviews:")
+		for _, r := range pr.Reviews {
+			author := r.Author
+			if author == "" {
+				author = "(unknown)"
+			}
+			fmt.Printf("  %s %s (%s)\n", author, strings.ToLower(strings.ReplaceAll(r.State, "_", " ")), r.SubmittedAt.Format("2006-01-02 15:04"))
+			for _, line := range strings.Split(r.Body, "\n") {
+				if line != "" {
+					fmt.Printf("    %s\n", line)
+				}
+			}
 		}
-	} else {
-		fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
 	}
-
 	return nil
 }
 
-func (c *CLI) reviewPR(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude review <pr-url>")
+// diffPR prints the changes a PR makes to its base
+func (c *CLI) diffPR(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) < 1 {
+		return errors.InvalidUsage("usage: multiclaude pr diff <number> [--repo <repo>]")
 	}
-
-	prURL := args[0]
-
-	// Parse PR URL to extract owner, repo, and PR number
-	// Expected formats:
-	// - https://github.com/owner/repo/pull/123
-	// - github.com/owner/repo/pull/123
-	prURL = strings.TrimPrefix(prURL, "https://")
-	prURL = strings.TrimPrefix(prURL, "http://")
-	parts := strings.Split(prURL, "/")
-
-	if len(parts) < 5 || parts[3] != "pull" {
-		return errors.InvalidPRURL()
+	number, err := parsePRNumber(positional[0])
+	if err != nil {
+		return err
 	}
 
-	prNumber := parts[4]
-	fmt.Printf("Reviewing PR #%s\n", prNumber)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	diff, err := f.DiffPR(number)
+	if err != nil {
+		return fmt.Errorf("failed to diff PR #%d: %w", number, err)
+	}
+	fmt.Print(diff)
+	return nil
+}
 
-	// Determine repository from flag or current directory
-	flags, _ := ParseFlags(args[1:])
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
-	} else {
-		// Try to infer from current directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
+// submitPRReview approves, requests changes on or comments on a PR. On the
+// local forge the review is recorded as the current agent, or $USER.
+func (c *CLI) submitPRReview(args []string) error {
+	flags, positional := ParseFlags(args)
+	usage := "usage: multiclaude pr review <number> --approve|--request-changes|--comment [--body <text>] [--repo <repo>]"
+	if len(positional) < 1 {
+		return errors.InvalidUsage(usage)
+	}
+	number, err := parsePRNumber(positional[0])
+	if err != nil {
+		return err
+	}
 
-		// Check if we're in a tracked repo
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoPath := c.paths.RepoDir(repo)
-			if strings.HasPrefix(cwd, repoPath) {
-				repoName = repo
-				break
-			}
+	var review forge.Review
+	for flag, reviewState := range map[string]string{
+		"approve":         forge.ReviewApproved,
+		"request-changes": forge.ReviewChangesRequested,
+		"comment":         forge.ReviewCommented,
+	} {
+		if _, ok := flags[flag]; !ok {
+			continue
 		}
-
-		if repoName == "" {
-			return errors.NotInRepo()
+		if review.State != "" {
+			return errors.InvalidUsage(usage)
 		}
+		review.State = reviewState
 	}
-
-	// Generate review agent name
-	reviewerName := fmt.Sprintf("review-%s", prNumber)
-
-	fmt.Printf("Creating review agent '%s' in repo '%s'\n", reviewerName, repoName)
-
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Fetch the PR using GitHub's PR refs - this works for both same-repo and fork PRs
-	// The refs/pull/<number>/head ref always exists and points to the PR's head commit
-	fmt.Printf("Fetching PR #%s...\n", prNumber)
-	prRef := fmt.Sprintf("refs/pull/%s/head", prNumber)
-	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
-	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
-			WithSuggestion("ensure the PR exists and you have access to the repository")
+	if review.State == "" {
+		return errors.InvalidUsage(usage)
 	}
+	review.Body = flags["body"]
 
-	// Create worktree for review
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, reviewerName)
-	reviewBranch := fmt.Sprintf("review/%s", reviewerName)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+	_, review.Author, _ = c.inferAgentContext()
+	if review.Author == "" {
+		review.Author = os.Getenv("USER")
+	}
 
-	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+	f, err := c.repoForge(repoName)
+	if err != nil {
+		return err
+	}
+	if err := f.AddReview(number, review); err != nil {
+		return fmt.Errorf("failed to review PR #%d: %w", number, err)
 	}
 
-	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	fmt.Printf("✓ Reviewed PR #%d: %s\n", number, strings.ToLower(strings.ReplaceAll(review.State, "_", " ")))
+	return nil
+}
 
-	// Create tmux window for reviewer (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", reviewerName)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create tmux window: %w", err)
+// setPRCheck records a check result on a branch or commit, or on the head
+// of a PR given by number
+func (c *CLI) setPRCheck(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) < 1 || flags["name"] == "" || flags["state"] == "" {
+		return errors.InvalidUsage("usage: multiclaude pr check <number|branch> --name <check> --state success|failure|error|pending [--url <url>] [--repo <repo>]")
 	}
 
-	// Generate session ID for reviewer
-	reviewerSessionID, err := claude.GenerateSessionID()
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to generate reviewer session ID: %w", err)
+		return errors.NotInRepo()
 	}
-
-	// Write prompt file for reviewer
-	reviewerPromptFile, err := c.writePromptFile(repoPath, state.AgentTypeReview, reviewerName)
+	f, err := c.repoForge(repoName)
 	if err != nil {
-		return fmt.Errorf("failed to write reviewer prompt: %w", err)
-	}
-
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+		return err
 	}
 
-	// Start Claude in reviewer window with initial task (skip in test mode)
-	var reviewerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		fmt.Println("Starting Claude Code in reviewer window...")
-		initialMessage := fmt.Sprintf("Review PR #%s: https://github.com/%s/%s/pull/%s", prNumber, parts[1], parts[2], prNumber)
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, reviewerName, wtPath, reviewerSessionID, reviewerPromptFile, repoName, initialMessage)
+	ref := positional[0]
+	if number, err := parsePRNumber(ref); err == nil {
+		pr, err := f.GetPR(number)
 		if err != nil {
-			return fmt.Errorf("failed to start reviewer Claude: %w", err)
-		}
-		reviewerPID = pid
-
-		// Set up output capture for reviewer
-		if err := c.setupOutputCapture(tmuxSession, reviewerName, repoName, reviewerName, "review"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for reviewer: %v\n", err)
+			return fmt.Errorf("failed to get PR #%d: %w", number, err)
 		}
+		ref = pr.Head
 	}
 
-	// Register reviewer with daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         reviewerName,
-			"type":          "review",
-			"worktree_path": wtPath,
-			"tmux_window":   reviewerName,
-			"task":          fmt.Sprintf("Review PR #%s", prNumber),
-			"session_id":    reviewerSessionID,
-			"pid":           reviewerPID,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to register reviewer: %w", err)
+	check := forge.Check{
+		Name:  flags["name"],
+		State: strings.ToLower(flags["state"]),
+		URL:   flags["url"],
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register reviewer: %s", resp.Error)
+	if err := f.SetCheck(ref, check); err != nil {
+		return fmt.Errorf("failed to set check %s on %s: %w", check.Name, ref, err)
 	}
 
-	fmt.Println()
-	fmt.Println("✓ Review agent created successfully!")
-	fmt.Printf("  Name: %s\n", reviewerName)
-	fmt.Printf("  Branch: %s\n", reviewBranch)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	fmt.Printf("\nAttach to reviewer: tmux select-window -t %s:%s\n", tmuxSession, reviewerName)
-	fmt.Printf("Or use: multiclaude attach %s\n", reviewerName)
-
+	fmt.Printf("✓ Check %s on %s: %s\n", check.Name, ref, check.State)
 	return nil
 }
 
+// parsePRNumber parses a PR number, with or without a leading #
+func parsePRNumber(arg string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || number <= 0 {
+		return 0, errors.InvalidUsage(fmt.Sprintf("invalid PR number %q", arg))
+	}
+	return number, nil
+}
+
 // Logs command implementations
 
 func (c *CLI) viewLogs(args []string) error {
@@ -4113,6 +5202,276 @@ func parseDuration(s string) (time.Duration, error) {
 	}
 }
 
+// Events command implementation
+
+// eventsFollowInterval is how often `events --follow` polls the daemon for new events
+const eventsFollowInterval = time.Second
+
+func (c *CLI) showEvents(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	query := client.EventQuery{
+		Repo:  flags["repo"],
+		Agent: flags["agent"],
+		Types: splitEventTypes(flags["type"]),
+	}
+
+	// Without a time window, default to the most recent events
+	query.Limit = 50
+	if s, ok := flags["since"]; ok {
+		since, err := parseSince(s, time.Now())
+		if err != nil {
+			return errors.InvalidUsage(fmt.Sprintf("invalid --since value %q: %v", s, err))
+		}
+		query.Since = since
+		query.Limit = 0
+	}
+	if n, ok := flags["n"]; ok {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 0 {
+			return errors.InvalidUsage(fmt.Sprintf("invalid -n value %q: must be a non-negative number", n))
+		}
+		query.Limit = v
+	}
+
+	jsonOutput := flags["json"] == "true"
+	follow := flags["follow"] == "true" || flags["f"] == "true"
+
+	// Start from now so --follow doesn't miss events emitted between the
+	// first query and the first poll
+	cursor := newFollowCursor(time.Now())
+
+	evts, err := c.queryEvents(query)
+	if err != nil {
+		return err
+	}
+	if len(evts) == 0 && !follow && !jsonOutput {
+		fmt.Println("No events found")
+		return nil
+	}
+	if err := printEvents(cursor.next(evts), jsonOutput); err != nil {
+		return err
+	}
+
+	if !follow {
+		return nil
+	}
+
+	query.Limit = 0
+	for {
+		time.Sleep(eventsFollowInterval)
+
+		query.Since = cursor.last
+		evts, err := c.queryEvents(query)
+		if err != nil {
+			return err
+		}
+		if err := printEvents(cursor.next(evts), jsonOutput); err != nil {
+			return err
+		}
+	}
+}
+
+// followCursor tracks where `events --follow` is in the journal. Polls
+// include the last timestamp printed, so events sharing it aren't lost, and
+// the cursor drops the ones already printed.
+type followCursor struct {
+	last time.Time
+	seen map[string]int // events printed at last, by followKey
+}
+
+func newFollowCursor(start time.Time) *followCursor {
+	return &followCursor{last: start, seen: make(map[string]int)}
+}
+
+// next returns the events not printed yet and records them as printed
+func (f *followCursor) next(evts []events.Event) []events.Event {
+	var fresh []events.Event
+	for _, e := range evts {
+		key := followKey(e)
+		if e.Timestamp.Equal(f.last) && f.seen[key] > 0 {
+			f.seen[key]--
+			continue
+		}
+		fresh = append(fresh, e)
+	}
+
+	// Only events at the latest timestamp can come back in the next poll
+	if len(fresh) > 0 {
+		f.last = fresh[len(fresh)-1].Timestamp
+	}
+	f.seen = make(map[string]int)
+	for _, e := range evts {
+		if e.Timestamp.Equal(f.last) {
+			f.seen[followKey(e)]++
+		}
+	}
+	return fresh
+}
+
+// followKey identifies an event among those with the same timestamp
+func followKey(e events.Event) string {
+	return string(e.Type) + "\x00" + e.RepoName + "\x00" + e.AgentName
+}
+
+// queryEvents sends an events_query request to the daemon
+func (c *CLI) queryEvents(query client.EventQuery) ([]events.Event, error) {
+	evts, err := c.daemonClient().QueryEvents(query)
+	if err != nil {
+		return nil, daemonError(err, "querying events", "failed to query events")
+	}
+
+	result := make([]events.Event, 0, len(evts))
+	for _, e := range evts {
+		result = append(result, eventFromClient(e))
+	}
+	return result, nil
+}
+
+// eventFromClient converts an event from the daemon for display
+func eventFromClient(e client.Event) events.Event {
+	return events.Event{
+		Type:      events.EventType(e.Type),
+		Timestamp: e.Timestamp,
+		RepoName:  e.RepoName,
+		AgentName: e.AgentName,
+		Data:      e.Data,
+	}
+}
+
+// splitEventTypes splits a comma-separated --type value
+func splitEventTypes(value string) []string {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// watchEvents streams events from the daemon as they happen, until the
+// daemon ends the stream or the command is interrupted
+func (c *CLI) watchEvents(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	jsonOutput := flags["json"] == "true"
+
+	sub, err := c.subscribeEvents(client.SubscribeRequest{
+		Repo:  flags["repo"],
+		Agent: flags["agent"],
+		Types: splitEventTypes(flags["type"]),
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		e, err := sub.Next()
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("daemon closed the event stream (it stopped, or this watcher fell too far behind)")
+			}
+			return err
+		}
+		if err := printEvents([]events.Event{eventFromClient(e)}, jsonOutput); err != nil {
+			return err
+		}
+	}
+}
+
+// subscribeEvents opens a subscribe stream to the daemon
+func (c *CLI) subscribeEvents(req client.SubscribeRequest) (*client.Subscription, error) {
+	sub, err := c.daemonClient().Subscribe(req)
+	if err != nil {
+		return nil, daemonError(err, "subscribing to events", "failed to subscribe to events")
+	}
+	return sub, nil
+}
+
+// printEvents prints events one per line, either as JSONL or human-readable
+func printEvents(evts []events.Event, jsonOutput bool) error {
+	for _, e := range evts {
+		if jsonOutput {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			continue
+		}
+
+		subject := e.RepoName
+		if e.AgentName != "" {
+			subject += "/" + e.AgentName
+		}
+
+		fmt.Printf("%s  ", e.Timestamp.Local().Format("2006-01-02 15:04:05"))
+		eventTypeColor(e.Type).Printf("%-14s", e.Type)
+		fmt.Printf("  %-28s  %s\n", subject, formatEventData(e.Data))
+	}
+	return nil
+}
+
+// eventTypeColor returns the color used to display an event type
+func eventTypeColor(t events.EventType) *color.Color {
+	switch t {
+	case events.EventAgentFailed, events.EventCIFailed, events.EventWorkerStuck, events.EventPRClosed:
+		return format.Red
+	case events.EventPRMerged, events.EventCIPassed, events.EventTaskComplete:
+		return format.Green
+	case events.EventAgentIdle:
+		return format.Yellow
+	default:
+		return format.Cyan
+	}
+}
+
+// formatEventData renders event data as sorted key=value pairs on one line
+func formatEventData(data map[string]interface{}) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := fmt.Sprintf("%v", data[k])
+		if value == "" {
+			continue
+		}
+		value = strings.ReplaceAll(value, "\n", " ")
+		value = format.Truncate(value, 60)
+		if strings.Contains(value, " ") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, k+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseSince parses a --since value: either a duration relative to now
+// ("30m", "1h30m", "7d") or an absolute time (RFC3339, "2006-01-02 15:04", "2006-01-02")
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected a duration (e.g. 30m, 2h, 7d) or a time (e.g. 2006-01-02 15:04)")
+}
+
 func (c *CLI) attachAgent(args []string) error {
 	flags, remainingArgs := ParseFlags(args)
 	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
@@ -4124,21 +5483,13 @@ func (c *CLI) attachAgent(args []string) error {
 	}
 
 	// Get agent info to find tmux session and window
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	agents, err := c.daemonClient().ListAgents(repoName)
 	if err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("failed to get agent info: %w", err)
+		}
 		return fmt.Errorf("failed to get agent info: %w (is daemon running?)", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to get agent info: %s", resp.Error)
-	}
-
-	agents, _ := resp.Data.([]interface{})
 
 	// Determine agent name - from args or interactive selection
 	var agentName string
@@ -4162,13 +5513,11 @@ func (c *CLI) attachAgent(args []string) error {
 	}
 
 	// Find agent
-	var agentInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			if name, _ := agentMap["name"].(string); name == agentName {
-				agentInfo = agentMap
-				break
-			}
+	var agentInfo *client.Agent
+	for i := range agents {
+		if agents[i].Name == agentName {
+			agentInfo = &agents[i]
+			break
 		}
 	}
 
@@ -4178,7 +5527,7 @@ func (c *CLI) attachAgent(args []string) error {
 
 	// Get tmux session and window
 	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := agentInfo["tmux_window"].(string)
+	tmuxWindow := agentInfo.TmuxWindow
 
 	// Attach to tmux
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
@@ -4213,28 +5562,24 @@ func (c *CLI) cleanup(args []string) error {
 		return c.cleanupMergedBranches(dryRun, verbose)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
+	daemonClient := c.daemonClient()
 
 	// Check if daemon is running
-	_, err := client.Send(socket.Request{Command: "ping"})
-	if err != nil {
+	if _, err := daemonClient.Ping(); err != nil {
+		if _, ok := err.(*client.VersionError); ok {
+			return errors.DaemonVersionMismatch(err)
+		}
 		fmt.Println("Daemon is not running. Running local cleanup...")
 		return c.localCleanup(dryRun, verbose)
 	}
 
 	// Trigger daemon cleanup
-	resp, err := client.Send(socket.Request{
-		Command: "trigger_cleanup",
-		Args: map[string]interface{}{
-			"dry_run": dryRun,
-		},
-	})
-	if err != nil {
+	if err := daemonClient.TriggerCleanup(); err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("cleanup failed: %w", err)
+		}
 		return fmt.Errorf("failed to trigger cleanup: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("cleanup failed: %s", resp.Error)
-	}
 
 	fmt.Println("Cleanup completed")
 	return nil
@@ -4277,9 +5622,15 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 
 		wt := worktree.NewManager(repoPath)
 
+		// Branches without a recorded base are checked against the repo's default branch
+		var defaultBranch string
+		if repo, ok := st.GetRepo(repoName); ok {
+			defaultBranch = repo.DefaultBranch
+		}
+
 		// Check for merged branches with common prefixes
 		for _, prefix := range []string{"multiclaude/", "work/"} {
-			mergedBranches, err := wt.FindMergedUpstreamBranches(prefix)
+			mergedBranches, err := wt.FindMergedBranches(prefix, defaultBranch)
 			if err != nil {
 				if verbose {
 					fmt.Printf("  Warning: failed to find merged branches with prefix %s: %v\n", prefix, err)
@@ -4411,10 +5762,10 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 	totalIssues := 0
 
 	// Load state for reference
-	st, err := state.Load(c.paths.StateFile)
+	st, err := state.LoadView(c.paths.StateFile)
 	if err != nil {
 		fmt.Printf("Warning: could not load state file: %v\n", err)
-		st = state.New(c.paths.StateFile)
+		st = state.New(c.paths.StateFile).View()
 	}
 
 	// Check for orphaned tmux sessions (mc-* sessions not in state)
@@ -4561,7 +5912,8 @@ func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
 		fmt.Printf("Warning: failed to read messages directory: %v\n", err)
 	} else if err == nil {
 		for _, entry := range msgEntries {
-			if !entry.IsDir() {
+			// Dot directories (the dead-letter folder) are not repositories
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 				continue
 			}
 
@@ -4646,47 +5998,281 @@ func (c *CLI) repair(args []string) error {
 	flags, _ := ParseFlags(args)
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
 
+	if flags["migrate-only"] == "true" {
+		return c.migrateState()
+	}
+
 	fmt.Println("Repairing state...")
 
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
-	_, err := client.Send(socket.Request{Command: "ping"})
-	if err != nil {
+	daemonClient := c.daemonClient()
+	if _, err := daemonClient.Ping(); err != nil {
+		if _, ok := err.(*client.VersionError); ok {
+			return errors.DaemonVersionMismatch(err)
+		}
 		// Daemon not running - do local repair
 		fmt.Println("Daemon is not running. Performing local repair...")
 		return c.localRepair(verbose)
 	}
 
 	// Trigger state repair via daemon
-	resp, err := client.Send(socket.Request{
-		Command: "repair_state",
-	})
+	result, err := daemonClient.RepairState()
 	if err != nil {
+		if _, ok := err.(*client.RequestError); ok {
+			return fmt.Errorf("repair failed: %w", err)
+		}
 		return fmt.Errorf("failed to trigger repair: %w", err)
 	}
-	if !resp.Success {
-		return fmt.Errorf("repair failed: %s", resp.Error)
-	}
 
 	fmt.Println("✓ State repaired successfully")
-	if data, ok := resp.Data.(map[string]interface{}); ok {
-		if removed, ok := data["agents_removed"].(float64); ok && removed > 0 {
-			fmt.Printf("  Removed %d dead agent(s)\n", int(removed))
+	if result.AgentsRemoved > 0 {
+		fmt.Printf("  Removed %d dead agent(s)\n", result.AgentsRemoved)
+	}
+	if result.IssuesFixed > 0 {
+		fmt.Printf("  Fixed %d issue(s)\n", result.IssuesFixed)
+	}
+
+	return nil
+}
+
+// migrateState brings the state file up to the current schema version
+// without touching agents or other resources
+func (c *CLI) migrateState() error {
+	lock, err := state.LockFile(c.paths.StateFile, stateLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer lock.Unlock()
+
+	result, err := state.Migrate(c.paths.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to migrate state: %w", err)
+	}
+
+	if !result.Migrated() {
+		fmt.Printf("✓ State is already at schema version %d\n", result.To)
+		return nil
+	}
+
+	fmt.Printf("✓ Migrated state from schema version %d to %d\n", result.From, result.To)
+	for _, description := range result.Applied {
+		fmt.Printf("  - %s\n", description)
+	}
+	fmt.Printf("  Backup of the previous state: %s\n", result.BackupPath)
+	return nil
+}
+
+// listSnapshots lists the state snapshots, newest first
+func (c *CLI) listSnapshots(args []string) error {
+	snapshots, err := snapshot.NewManager(c.paths.SnapshotsDir()).List()
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No state snapshots yet. The daemon takes one at startup and every hour.")
+		return nil
+	}
+
+	table := format.NewColoredTable("ID", "TAKEN", "REASON", "REPOS", "AGENTS")
+	for _, snap := range snapshots {
+		reposCell := format.ColorCell("-", format.Dim)
+		agentsCell := format.ColorCell("-", format.Dim)
+		if view, err := snap.LoadState(); err == nil {
+			agents := 0
+			for _, repo := range view.GetAllRepos() {
+				agents += len(repo.Agents)
+			}
+			reposCell = format.Cell(strconv.Itoa(len(view.ListRepos())))
+			agentsCell = format.Cell(strconv.Itoa(agents))
+		}
+		table.AddRow(
+			format.Cell(snap.ID),
+			format.ColorCell(format.TimeAgo(snap.CreatedAt), format.Dim),
+			format.Cell(snap.Reason),
+			reposCell,
+			agentsCell,
+		)
+	}
+	table.Print()
+	fmt.Println()
+	fmt.Println("Compare with: multiclaude state diff <id>")
+	fmt.Println("Restore with: multiclaude state restore <id>")
+	return nil
+}
+
+// diffSnapshot shows what restoring a snapshot would change
+func (c *CLI) diffSnapshot(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude state diff <id>")
+	}
+
+	snap, changes, msgDiff, err := c.snapshotChanges(posArgs[0])
+	if err != nil {
+		return err
+	}
+	printSnapshotChanges(snap, changes, msgDiff)
+	return nil
+}
+
+// restoreSnapshot restores state and messages from a snapshot, through the
+// daemon if it's running or directly if it's stopped. The current state is
+// snapshotted first either way.
+func (c *CLI) restoreSnapshot(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude state restore <id> [--yes]")
+	}
+
+	snap, changes, msgDiff, err := c.snapshotChanges(posArgs[0])
+	if err != nil {
+		return err
+	}
+	printSnapshotChanges(snap, changes, msgDiff)
+	if len(changes) == 0 && msgDiff.Empty() {
+		return nil
+	}
+
+	if flags["yes"] != "true" && flags["y"] != "true" {
+		fmt.Printf("\nRestore snapshot %s? [y/N]: ", snap.ID)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Restore cancelled")
+			return nil
 		}
-		if fixed, ok := data["issues_fixed"].(float64); ok && fixed > 0 {
-			fmt.Printf("  Fixed %d issue(s)\n", int(fixed))
+	}
+
+	result, err := c.daemonClient().RestoreSnapshot(snap.ID)
+	if err != nil {
+		switch err.(type) {
+		case *client.ConnectionError:
+			result, err = c.restoreSnapshotLocally(snap)
+			if err != nil {
+				return err
+			}
+		case *client.VersionError:
+			return errors.DaemonVersionMismatch(err)
+		default:
+			return daemonError(err, "restoring snapshot", "failed to restore snapshot")
 		}
 	}
 
+	fmt.Printf("\n✓ Restored snapshot %s\n", result.Restored)
+	if result.Backup != "" {
+		fmt.Printf("  Previous state saved as snapshot %s (undo with: multiclaude state restore %s)\n", result.Backup, result.Backup)
+	}
 	return nil
 }
 
+// restoreSnapshotLocally restores a snapshot while the daemon is stopped,
+// holding the state file's lock so a starting daemon waits for it. The
+// current state file is snapshotted as-is, even if it no longer loads.
+func (c *CLI) restoreSnapshotLocally(snap *snapshot.Snapshot) (*client.RestoreSnapshotResult, error) {
+	lock, err := state.LockFile(c.paths.StateFile, stateLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer lock.Unlock()
+
+	restored, err := snap.LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &client.RestoreSnapshotResult{Restored: snap.ID}
+	if _, err := os.Stat(c.paths.StateFile); err == nil {
+		backup, err := snapshot.NewManager(c.paths.SnapshotsDir()).Create(c.paths.StateFile, c.paths.MessagesDir, snapshot.ReasonRestore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot current state: %w", err)
+		}
+		result.Backup = backup.ID
+	}
+
+	if err := state.New(c.paths.StateFile).Restore(restored); err != nil {
+		return nil, fmt.Errorf("failed to restore state: %w", err)
+	}
+	if err := snap.RestoreMessages(c.paths.MessagesDir); err != nil {
+		return nil, fmt.Errorf("restored state, but failed to restore messages: %w", err)
+	}
+	return result, nil
+}
+
+// snapshotChanges compares the current state and messages with a snapshot's.
+// A state file that no longer loads is compared as empty, so damaged state
+// can still be restored.
+func (c *CLI) snapshotChanges(id string) (*snapshot.Snapshot, []snapshot.Change, snapshot.MessageDiff, error) {
+	var msgDiff snapshot.MessageDiff
+	snap, err := snapshot.NewManager(c.paths.SnapshotsDir()).Get(id)
+	if err != nil {
+		return nil, nil, msgDiff, err
+	}
+	restored, err := snap.LoadState()
+	if err != nil {
+		return nil, nil, msgDiff, err
+	}
+
+	current, err := c.loadState()
+	if err != nil {
+		fmt.Printf("Warning: %v; comparing the snapshot with an empty state\n\n", err)
+		current = state.New(c.paths.StateFile).View()
+	}
+
+	msgDiff, err = snapshot.DiffMessages(c.paths.MessagesDir, snap.MessagesDir())
+	if err != nil {
+		return nil, nil, msgDiff, fmt.Errorf("failed to compare messages: %w", err)
+	}
+	return snap, snapshot.Diff(current, restored), msgDiff, nil
+}
+
+// printSnapshotChanges prints what restoring a snapshot would change
+func printSnapshotChanges(snap *snapshot.Snapshot, changes []snapshot.Change, msgDiff snapshot.MessageDiff) {
+	fmt.Printf("Snapshot %s (%s, %s)\n", snap.ID, snap.Reason, format.TimeAgo(snap.CreatedAt))
+	if len(changes) == 0 && msgDiff.Empty() {
+		fmt.Println("Matches the current state; nothing to restore")
+		return
+	}
+
+	fmt.Println("Restoring it would:")
+	for _, change := range changes {
+		switch change.Kind {
+		case snapshot.RepoAdded:
+			format.Green.Printf("  + add repository %s\n", change.Repo)
+		case snapshot.RepoRemoved:
+			format.Red.Printf("  - remove repository %s\n", change.Repo)
+		case snapshot.RepoUpdated:
+			if change.Repo == "" {
+				format.Yellow.Printf("  ~ change %s\n", strings.Join(change.Fields, ", "))
+			} else {
+				format.Yellow.Printf("  ~ change repository %s: %s\n", change.Repo, strings.Join(change.Fields, ", "))
+			}
+		case snapshot.AgentAdded:
+			format.Green.Printf("  + add agent %s/%s\n", change.Repo, change.Agent)
+		case snapshot.AgentRemoved:
+			format.Red.Printf("  - remove agent %s/%s\n", change.Repo, change.Agent)
+		case snapshot.AgentUpdated:
+			format.Yellow.Printf("  ~ change agent %s/%s: %s\n", change.Repo, change.Agent, strings.Join(change.Fields, ", "))
+		}
+	}
+	if !msgDiff.Empty() {
+		fmt.Printf("  ~ messages: %d restored, %d removed, %d changed\n", len(msgDiff.Added), len(msgDiff.Removed), len(msgDiff.Changed))
+	}
+}
+
 // localRepair performs state repair without the daemon running
 func (c *CLI) localRepair(verbose bool) error {
-	// Load state from disk
-	st, err := c.loadState()
+	// Hold the state file's lock so a daemon starting meanwhile can't
+	// overwrite the repair
+	lock, err := state.LockFile(c.paths.StateFile, stateLockTimeout)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer lock.Unlock()
+
+	st, err := state.Load(c.paths.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
 	}
 
 	tmuxClient := tmux.NewClient()
@@ -4855,9 +6441,9 @@ func (c *CLI) restartClaude(args []string) error {
 	}
 
 	// Load state to get session ID
-	st, err := state.Load(c.paths.StateFile)
+	st, err := c.loadState()
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return err
 	}
 
 	agent, exists := st.GetAgent(repoName, agentName)
@@ -5027,6 +6613,11 @@ func (c *CLI) writePromptFile(repoPath string, agentType state.AgentType, agentN
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
 
+	// Point the agent at multiclaude pr when gh can't reach the repo's PRs
+	if c.usesLocalForge(filepath.Base(repoPath)) {
+		promptText = prompts.GenerateLocalForgePrompt() + promptText
+	}
+
 	// Create a prompt file in the prompts directory
 	promptDir := filepath.Join(c.paths.Root, "prompts")
 	if err := os.MkdirAll(promptDir, 0755); err != nil {
@@ -5043,7 +6634,7 @@ func (c *CLI) writePromptFile(repoPath string, agentType state.AgentType, agentN
 
 // writeMergeQueuePromptFile writes a merge-queue prompt file with tracking mode configuration.
 // It reads the merge-queue prompt from agent definitions (configurable agent system).
-func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqConfig state.MergeQueueConfig) (string, error) {
+func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqConfig state.MergeQueueConfig, localForge bool) (string, error) {
 	// Determine the repo name from the repoPath
 	repoName := filepath.Base(repoPath)
 
@@ -5107,106 +6698,9 @@ func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqCon
 	trackingConfig := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
 	promptText = trackingConfig + "\n\n" + promptText
 
-	// Create a prompt file in the prompts directory
-	promptDir := filepath.Join(c.paths.Root, "prompts")
-	if err := os.MkdirAll(promptDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create prompt directory: %w", err)
-	}
-
-	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
-	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
-		return "", fmt.Errorf("failed to write prompt file: %w", err)
-	}
-
-	return promptPath, nil
-}
-
-// WorkerConfig holds configuration for creating worker prompts
-type WorkerConfig struct {
-	PushToBranch string // Branch to push to instead of creating a new PR (for iterating on existing PRs)
-}
-
-// writeWorkerPromptFile writes a worker prompt file with optional configuration.
-// It reads the worker prompt from agent definitions (configurable agent system).
-func (c *CLI) writeWorkerPromptFile(repoPath string, agentName string, config WorkerConfig) (string, error) {
-	// Determine the repo name from the repoPath
-	repoName := filepath.Base(repoPath)
-
-	// Read worker prompt from agent definitions
-	localAgentsDir := c.paths.RepoAgentsDir(repoName)
-	reader := agents.NewReader(localAgentsDir, repoPath)
-	definitions, err := reader.ReadAllDefinitions()
-	if err != nil {
-		return "", fmt.Errorf("failed to read agent definitions: %w", err)
-	}
-
-	// Find the worker definition
-	var promptText string
-	for _, def := range definitions {
-		if def.Name == "worker" {
-			promptText = def.Content
-			break
-		}
-	}
-
-	// If no worker definition found, try to copy from templates and retry
-	if promptText == "" {
-		// Copy templates to local agents dir if it doesn't exist
-		if _, err := os.Stat(localAgentsDir); os.IsNotExist(err) {
-			if err := templates.CopyAgentTemplates(localAgentsDir); err != nil {
-				return "", fmt.Errorf("failed to copy agent templates: %w", err)
-			}
-			// Re-read definitions
-			definitions, err = reader.ReadAllDefinitions()
-			if err != nil {
-				return "", fmt.Errorf("failed to read agent definitions after template copy: %w", err)
-			}
-			for _, def := range definitions {
-				if def.Name == "worker" {
-					promptText = def.Content
-					break
-				}
-			}
-		}
-	}
-
-	if promptText == "" {
-		return "", fmt.Errorf("no worker agent definition found")
-	}
-
-	// Add CLI documentation
-	if c.documentation != "" {
-		promptText += fmt.Sprintf("\n\n---\n\n%s", c.documentation)
-	}
-
-	// Add slash commands section
-	slashCommands := prompts.GetSlashCommandsPrompt()
-	if slashCommands != "" {
-		promptText += fmt.Sprintf("\n\n---\n\n%s", slashCommands)
-	}
-
-	// Note: Custom prompts from <repo>/.multiclaude/WORKER.md are deprecated.
-	// Users should customize via <repo>/.multiclaude/agents/worker.md instead.
-
-	// Add push-to configuration if specified
-	if config.PushToBranch != "" {
-		pushToConfig := fmt.Sprintf(`## PR Iteration Mode
-
-**IMPORTANT: You are iterating on an existing PR, not creating a new one.**
-
-Instead of creating a new PR, push your changes to the existing branch: %s
-
-When your work is ready:
-1. Commit your changes
-2. Push to origin: git push origin %s
-3. Signal completion with: multiclaude agent complete
-
-Do NOT create a new PR. The existing PR will be updated automatically when you push.
-
----
-
-`, config.PushToBranch, config.PushToBranch)
-		promptText = pushToConfig + promptText
+	// Point the merge queue at multiclaude pr when gh can't reach the repo's PRs
+	if localForge {
+		promptText = prompts.GenerateLocalForgePrompt() + promptText
 	}
 
 	// Create a prompt file in the prompts directory
@@ -5248,14 +6742,15 @@ func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, a
 
 // startClaudeInTmux starts Claude Code in a tmux window with the given configuration
 // Returns the PID of the Claude process
-func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, initialMessage string) (int, error) {
+func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, initialMessage string, opts state.ClaudeOptions) (int, error) {
 	// Build Claude command - uses global ~/.claude/ for auth and slash commands are embedded in prompts
-	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions", binaryPath, sessionID)
-
-	// Add prompt file if provided
-	if promptFile != "" {
-		claudeCmd += fmt.Sprintf(" --append-system-prompt-file %s", promptFile)
-	}
+	runner := claude.NewRunner(claude.WithBinaryPath(binaryPath))
+	claudeCmd := runner.BuildCommand(sessionID, claude.Config{
+		SystemPromptFile: promptFile,
+		Model:            opts.Model,
+		ExtraArgs:        opts.Args,
+		Env:              opts.Env,
+	})
 
 	// Send command to tmux window
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
@@ -5402,9 +6897,9 @@ func (c *CLI) syncRepo(args []string) error {
 			repoName = inferredRepo
 		} else {
 			// Fall back to current repo in state
-			st, err := state.Load(c.paths.StateFile)
+			st, err := c.loadState()
 			if err != nil {
-				return fmt.Errorf("failed to load state: %w", err)
+				return err
 			}
 			repoName = st.GetCurrentRepo()
 			if repoName == "" {
@@ -5413,9 +6908,9 @@ func (c *CLI) syncRepo(args []string) error {
 		}
 	}
 
-	st, err := state.Load(c.paths.StateFile)
+	st, err := c.loadState()
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return err
 	}
 
 	repo, exists := st.GetRepo(repoName)
@@ -5427,8 +6922,27 @@ func (c *CLI) syncRepo(args []string) error {
 		return fmt.Errorf("repository %q does not have upstream tracking enabled", repoName)
 	}
 
+	strategy := flags["strategy"]
+	if strategy != "" && strategy != "merge" && strategy != "rebase" {
+		return errors.InvalidUsage(fmt.Sprintf("invalid --strategy value: %s (must be 'merge' or 'rebase')", strategy))
+	}
+
 	fmt.Printf("Syncing %s with upstream %s...\n", repoName, repo.UpstreamConfig.UpstreamURL)
 
+	// The daemon syncs in a temporary worktree and pushes the result to the
+	// fork; without it, upstream is merged into the local checkout
+	result, err := c.daemonClient().SyncUpstream(repoName, strategy)
+	if err == nil {
+		return printUpstreamSync(result)
+	}
+	if _, ok := err.(*client.ConnectionError); !ok {
+		return fmt.Errorf("failed to sync with upstream: %w", err)
+	}
+	if strategy == "rebase" {
+		return fmt.Errorf("--strategy=rebase needs the daemon (start it with: multiclaude start)")
+	}
+	fmt.Println("Daemon not running; merging upstream into the local checkout")
+
 	repoPath := c.paths.RepoDir(repoName)
 
 	// Fetch from upstream
@@ -5448,22 +6962,28 @@ func (c *CLI) syncRepo(args []string) error {
 	}
 	currentBranch := strings.TrimSpace(string(currentBranchOut))
 
-	// Check if on main branch
-	if currentBranch != "main" && currentBranch != "master" {
-		fmt.Printf("Warning: not on main branch (currently on %s). Switch to main first.\n", currentBranch)
+	// Sync only the default branch
+	wt := worktree.NewManager(repoPath)
+	defaultBranch := repo.DefaultBranch
+	if defaultBranch == "" {
+		if defaultBranch, err = wt.GetDefaultBranch("origin"); err != nil {
+			return fmt.Errorf("failed to determine default branch: %w (set it with: multiclaude config %s --default-branch=BRANCH)", err, repoName)
+		}
+	}
+	if currentBranch != defaultBranch {
+		fmt.Printf("Warning: not on the default branch (currently on %s). Switch to %s first.\n", currentBranch, defaultBranch)
 		return nil
 	}
 
 	// Get upstream commit SHA
-	upstreamSHACmd := exec.Command("git", "-C", repoPath, "rev-parse", fmt.Sprintf("%s/main", repo.UpstreamConfig.UpstreamRemote))
+	upstreamRef, err := wt.UpstreamBranchRef(repo.UpstreamConfig.UpstreamRemote, defaultBranch)
+	if err != nil {
+		return fmt.Errorf("failed to find upstream branch: %w", err)
+	}
+	upstreamSHACmd := exec.Command("git", "-C", repoPath, "rev-parse", upstreamRef)
 	upstreamSHAOut, err := upstreamSHACmd.Output()
 	if err != nil {
-		// Try master if main doesn't exist
-		upstreamSHACmd = exec.Command("git", "-C", repoPath, "rev-parse", fmt.Sprintf("%s/master", repo.UpstreamConfig.UpstreamRemote))
-		upstreamSHAOut, err = upstreamSHACmd.Output()
-		if err != nil {
-			return fmt.Errorf("failed to get upstream commit SHA: %w", err)
-		}
+		return fmt.Errorf("failed to get upstream commit SHA: %w", err)
 	}
 	upstreamSHA := strings.TrimSpace(string(upstreamSHAOut))
 
@@ -5479,8 +6999,10 @@ func (c *CLI) syncRepo(args []string) error {
 		return fmt.Errorf("merge failed: %w", err)
 	}
 
-	// Update sync time in state
-	if err := st.UpdateSyncTime(repoName, time.Now(), upstreamSHA); err != nil {
+	// The daemon isn't running, so record the sync directly
+	if err := c.updateState(func(st *state.State) error {
+		return st.UpdateSyncTime(repoName, time.Now(), upstreamSHA)
+	}); err != nil {
 		fmt.Printf("Warning: failed to update sync time in state: %v\n", err)
 	}
 
@@ -5490,6 +7012,34 @@ func (c *CLI) syncRepo(args []string) error {
 	return nil
 }
 
+// printUpstreamSync prints the outcome of a sync done by the daemon
+func printUpstreamSync(result *client.UpstreamSyncResult) error {
+	switch {
+	case result.UpToDate:
+		fmt.Println("\n✓ Already up to date with upstream")
+	case len(result.Conflicts) > 0:
+		fmt.Println("\nSyncing conflicts in:")
+		for _, file := range result.Conflicts {
+			fmt.Printf("  - %s\n", file)
+		}
+		fmt.Println("\nNothing was pushed. Merge upstream into a branch, resolve the conflicts and open a PR.")
+		return fmt.Errorf("sync with upstream %s conflicts", shortCommit(result.UpstreamSHA))
+	default:
+		fmt.Println("\n✓ Successfully synced with upstream")
+		fmt.Printf("  Upstream SHA: %s\n", shortCommit(result.UpstreamSHA))
+		fmt.Printf("  Pushed to fork: %s\n", shortCommit(result.SHA))
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit SHA for display
+func shortCommit(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
 // ciStatus checks dual-layer CI status
 func (c *CLI) ciStatus(args []string) error {
 	flags, _ := ParseFlags(args)
@@ -5505,9 +7055,9 @@ func (c *CLI) ciStatus(args []string) error {
 			repoName = inferredRepo
 		} else {
 			// Fall back to current repo in state
-			st, err := state.Load(c.paths.StateFile)
+			st, err := c.loadState()
 			if err != nil {
-				return fmt.Errorf("failed to load state: %w", err)
+				return err
 			}
 			repoName = st.GetCurrentRepo()
 			if repoName == "" {
@@ -5516,9 +7066,9 @@ func (c *CLI) ciStatus(args []string) error {
 		}
 	}
 
-	st, err := state.Load(c.paths.StateFile)
+	st, err := c.loadState()
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return err
 	}
 
 	repo, exists := st.GetRepo(repoName)
@@ -5554,6 +7104,9 @@ func (c *CLI) ciStatus(args []string) error {
 	if repo.DualCIStatus.ForkCI.FailingSince != nil {
 		fmt.Printf("  Failing Since: %s\n", repo.DualCIStatus.ForkCI.FailingSince.Format("2006-01-02 15:04:05"))
 	}
+	if repo.DualCIStatus.ForkCI.CheckURL != "" {
+		fmt.Printf("  Checks: %s\n", repo.DualCIStatus.ForkCI.CheckURL)
+	}
 
 	fmt.Println()
 
@@ -5569,6 +7122,9 @@ func (c *CLI) ciStatus(args []string) error {
 	if repo.DualCIStatus.UpstreamCI.FailingSince != nil {
 		fmt.Printf("  Failing Since: %s\n", repo.DualCIStatus.UpstreamCI.FailingSince.Format("2006-01-02 15:04:05"))
 	}
+	if repo.DualCIStatus.UpstreamCI.CheckURL != "" {
+		fmt.Printf("  Checks: %s\n", repo.DualCIStatus.UpstreamCI.CheckURL)
+	}
 
 	fmt.Println()
 
@@ -5583,6 +7139,12 @@ func (c *CLI) ciStatus(args []string) error {
 	if !repo.DualCIStatus.LastSyncTime.IsZero() {
 		fmt.Printf("Last Sync: %s\n", repo.DualCIStatus.LastSyncTime.Format("2006-01-02 15:04:05"))
 	}
+	if len(repo.DualCIStatus.SyncConflicts) > 0 {
+		fmt.Printf("\nSyncing with upstream %s conflicts in:\n", shortCommit(repo.DualCIStatus.SyncConflictSHA))
+		for _, file := range repo.DualCIStatus.SyncConflicts {
+			fmt.Printf("  - %s\n", file)
+		}
+	}
 
 	return nil
 }
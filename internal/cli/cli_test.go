@@ -10,9 +10,15 @@ import (
 	"time"
 
 	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/forge"
+	"github.com/dlorenc/multiclaude/internal/github"
 	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/client"
 	"github.com/dlorenc/multiclaude/pkg/config"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
@@ -633,6 +639,272 @@ func TestCLISendMessageTriggersImmediateRouting(t *testing.T) {
 	}
 }
 
+func TestCLISendMessageReplyAndPriority(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoName := "test-repo"
+	paths := d.GetPaths()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	worktreeDir := filepath.Join(paths.WorktreesDir, repoName, "test-worker")
+	worker := state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: worktreeDir,
+		TmuxWindow:   "test-worker",
+		Task:         "Test task",
+		CreatedAt:    time.Now(),
+	}
+	if err := d.GetState().AddAgent(repoName, "test-worker", worker); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+	if err := os.MkdirAll(worktreeDir, 0755); err != nil {
+		t.Fatalf("Failed to create worktree dir: %v", err)
+	}
+
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(worktreeDir); err != nil {
+		t.Fatalf("Failed to change to worktree: %v", err)
+	}
+
+	// The supervisor asks the worker a question
+	msgMgr := messages.NewManager(paths.MessagesDir)
+	question, err := msgMgr.SendWithOptions(repoName, "supervisor", "test-worker", "Ready for review?", messages.SendOptions{Subject: "Status"})
+	if err != nil {
+		t.Fatalf("Failed to send question: %v", err)
+	}
+
+	if err := cli.Execute([]string{"agent", "send-message", "supervisor", "--reply-to", question.ID, "--priority", "urgent", "Yes,", "PR", "is", "up"}); err != nil {
+		t.Fatalf("send-message reply failed: %v", err)
+	}
+
+	msgs, err := msgMgr.List(repoName, "supervisor")
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(msgs))
+	}
+	reply := msgs[0]
+	if reply.Body != "Yes, PR is up" || reply.ReplyTo != question.ID || reply.ThreadID != question.ID {
+		t.Errorf("reply = %+v", reply)
+	}
+	if reply.Priority != messages.PriorityUrgent || reply.Subject != "Status" {
+		t.Errorf("reply Priority = %q, Subject = %q", reply.Priority, reply.Subject)
+	}
+
+	// Both listing views work with threaded messages
+	if err := cli.Execute([]string{"agent", "list-messages"}); err != nil {
+		t.Errorf("list-messages failed: %v", err)
+	}
+	if err := cli.Execute([]string{"agent", "list-messages", "--threads"}); err != nil {
+		t.Errorf("list-messages --threads failed: %v", err)
+	}
+
+	invalid := [][]string{
+		{"agent", "send-message", "supervisor", "--priority", "critical", "Hi"},
+		{"agent", "send-message", "supervisor", "--reply-to", "msg-missing", "Hi"},
+	}
+	for _, args := range invalid {
+		if err := cli.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
+	}
+}
+
+func TestCLISendMessageBroadcast(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoName := "test-repo"
+	paths := d.GetPaths()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	agents := map[string]state.Agent{
+		"supervisor":   {Type: state.AgentTypeSupervisor, WorktreePath: paths.RepoDir(repoName), TmuxWindow: "supervisor"},
+		"workspace":    {Type: state.AgentTypeWorkspace, TmuxWindow: "workspace"},
+		"clever-fox":   {Type: state.AgentTypeWorker, TmuxWindow: "clever-fox"},
+		"swift-eagle":  {Type: state.AgentTypeWorker, TmuxWindow: "swift-eagle"},
+		"review-pr-12": {Type: state.AgentTypeReview, TmuxWindow: "review-pr-12"},
+	}
+	for name, agent := range agents {
+		agent.CreatedAt = time.Now()
+		if err := d.GetState().AddAgent(repoName, name, agent); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	if err := os.MkdirAll(paths.RepoDir(repoName), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(paths.RepoDir(repoName)); err != nil {
+		t.Fatalf("Failed to change to repo dir: %v", err)
+	}
+
+	if err := cli.Execute([]string{"agent", "send-message", "@workers", "--priority", "urgent", "Main is broken, stop pushing"}); err != nil {
+		t.Fatalf("send-message @workers failed: %v", err)
+	}
+
+	msgMgr := messages.NewManager(paths.MessagesDir)
+	var broadcastID string
+	for _, worker := range []string{"clever-fox", "swift-eagle"} {
+		msgs, err := msgMgr.List(repoName, worker)
+		if err != nil {
+			t.Fatalf("Failed to list messages: %v", err)
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("%s has %d messages, want 1", worker, len(msgs))
+		}
+		msg := msgs[0]
+		if msg.From != "supervisor" || msg.Body != "Main is broken, stop pushing" || msg.Priority != messages.PriorityUrgent {
+			t.Errorf("%s message = %+v", worker, msg)
+		}
+		if broadcastID == "" {
+			broadcastID = msg.BroadcastID
+		}
+		if msg.BroadcastID == "" || msg.BroadcastID != broadcastID {
+			t.Errorf("%s BroadcastID = %q, want shared ID %q", worker, msg.BroadcastID, broadcastID)
+		}
+	}
+	for _, other := range []string{"review-pr-12", "workspace", "supervisor"} {
+		if msgs, _ := msgMgr.List(repoName, other); len(msgs) != 0 {
+			t.Errorf("%s should not receive the @workers broadcast", other)
+		}
+	}
+
+	if err := cli.Execute([]string{"agent", "send-message", "review-*", "Please prioritize PR 12"}); err != nil {
+		t.Fatalf("send-message glob failed: %v", err)
+	}
+	if msgs, _ := msgMgr.List(repoName, "review-pr-12"); len(msgs) != 1 {
+		t.Errorf("review-pr-12 has %d messages, want 1", len(msgs))
+	}
+
+	invalid := [][]string{
+		{"agent", "send-message", "@everyone", "Hi"},
+		{"agent", "send-message", "nobody-*", "Hi"},
+		{"agent", "send-message", "@all", "--reply-to", "msg-1", "Hi"},
+	}
+	for _, args := range invalid {
+		if err := cli.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
+	}
+}
+
+func TestCLIDeadLetters(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoName := "test-repo"
+	paths := d.GetPaths()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.GetState().AddAgent(repoName, "supervisor", state.Agent{
+		Type:         state.AgentTypeSupervisor,
+		WorktreePath: paths.RepoDir(repoName),
+		TmuxWindow:   "supervisor",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add supervisor: %v", err)
+	}
+
+	if err := os.MkdirAll(paths.RepoDir(repoName), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(paths.RepoDir(repoName)); err != nil {
+		t.Fatalf("Failed to change to repo dir: %v", err)
+	}
+
+	if err := cli.Execute([]string{"agent", "send-message", "gone-worker", "--ttl", "30m", "Are you there?"}); err != nil {
+		t.Fatalf("send-message --ttl failed: %v", err)
+	}
+	msgMgr := messages.NewManager(paths.MessagesDir)
+	msgs, err := msgMgr.List(repoName, "gone-worker")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("List() = %v, %v; want 1 message", msgs, err)
+	}
+	msg := msgs[0]
+	if msg.ExpiresAt == nil || msg.ExpiresAt.Sub(msg.Timestamp) != 30*time.Minute {
+		t.Errorf("ExpiresAt = %v, want 30m after sending", msg.ExpiresAt)
+	}
+
+	if err := msgMgr.DeadLetter(repoName, "gone-worker", msg, "recipient gone-worker no longer exists"); err != nil {
+		t.Fatalf("DeadLetter() failed: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"agent", "dead-letters", "--repo", repoName},
+		{"agent", "dead-letters", "show", msg.ID, "--repo", repoName},
+	} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("Execute(%v) failed: %v", args, err)
+		}
+	}
+
+	if err := cli.Execute([]string{"agent", "dead-letters", "resend", msg.ID, "--to", "supervisor", "--repo", repoName}); err != nil {
+		t.Fatalf("dead-letters resend failed: %v", err)
+	}
+	if _, err := msgMgr.Get(repoName, "supervisor", msg.ID); err != nil {
+		t.Errorf("resent message should be in supervisor's inbox: %v", err)
+	}
+	if dead, _ := msgMgr.ListDeadLetters(repoName); len(dead) != 0 {
+		t.Errorf("dead letters after resend = %d, want 0", len(dead))
+	}
+
+	other, err := msgMgr.Send(repoName, "supervisor", "gone-worker", "Never mind")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if err := msgMgr.DeadLetter(repoName, "gone-worker", other, "test"); err != nil {
+		t.Fatalf("DeadLetter() failed: %v", err)
+	}
+	if err := cli.Execute([]string{"agent", "dead-letters", "purge", other.ID, "--repo", repoName}); err != nil {
+		t.Fatalf("dead-letters purge failed: %v", err)
+	}
+	if _, err := msgMgr.GetDeadLetter(repoName, other.ID); err == nil {
+		t.Error("purged dead letter should be gone")
+	}
+
+	invalid := [][]string{
+		{"agent", "send-message", "supervisor", "--ttl", "soon", "Hi"},
+		{"agent", "dead-letters", "show", "--repo", repoName},
+		{"agent", "dead-letters", "show", "msg-missing", "--repo", repoName},
+		{"agent", "dead-letters", "resend", "msg-missing", "--repo", repoName},
+		{"agent", "dead-letters", "purge", "msg-missing", "--repo", repoName},
+	}
+	for _, args := range invalid {
+		if err := cli.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
+	}
+}
+
 func TestCLISendMessageFallbackWhenDaemonUnavailable(t *testing.T) {
 	// This test verifies that send-message works even when the daemon
 	// socket is unavailable (the socket call is best-effort)
@@ -953,6 +1225,176 @@ func TestCLIWorkCreateWithRealTmux(t *testing.T) {
 	}
 }
 
+func TestCLIWorkWithBase(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoName := "test-repo"
+	repoPath := paths.RepoDir(repoName)
+	setupTestRepo(t, repoPath)
+
+	cmd := exec.Command("git", "branch", "release/2.3")
+	cmd.Dir = repoPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	tmuxSession := "mc-test-base"
+	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: tmuxSession,
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"work", "Backport fix", "--name", "release-worker", "--base", "release/2.3", "--repo", repoName}); err != nil {
+		t.Fatalf("work --base failed: %v", err)
+	}
+
+	agent, exists := d.GetState().GetAgent(repoName, "release-worker")
+	if !exists {
+		t.Fatal("Worker should exist in state")
+	}
+	if agent.BaseBranch != "release/2.3" {
+		t.Errorf("agent.BaseBranch = %q, want release/2.3", agent.BaseBranch)
+	}
+	if base := worktree.NewManager(repoPath).BranchBase("multiclaude/release-worker"); base != "release/2.3" {
+		t.Errorf("recorded branch base = %q, want release/2.3", base)
+	}
+
+	if err := cli.Execute([]string{"work", "Nowhere", "--name", "lost-worker", "--base", "release/9.9", "--repo", repoName}); err == nil {
+		t.Error("work --base should fail for a branch that doesn't exist")
+	}
+	if _, exists := d.GetState().GetAgent(repoName, "lost-worker"); exists {
+		t.Error("no worker should be created for an unknown base")
+	}
+
+	// --default-branch round-trips through config
+	if err := cli.Execute([]string{"config", repoName, "--default-branch=develop"}); err != nil {
+		t.Fatalf("config --default-branch failed: %v", err)
+	}
+	updated, _ := d.GetState().GetRepo(repoName)
+	if updated.DefaultBranch != "develop" {
+		t.Errorf("DefaultBranch = %q, want develop", updated.DefaultBranch)
+	}
+}
+
+func TestCLIWorkQueue(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoName := "test-repo"
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+		MaxWorkers:  1,
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.GetState().AddAgent(repoName, "busy-worker", state.Agent{
+		Type:       state.AgentTypeWorker,
+		TmuxWindow: "busy-worker",
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	// At the limit, so work queues the tasks instead of starting workers
+	for _, task := range []string{"First queued task", "Second queued task"} {
+		if err := cli.Execute([]string{"work", task, "--repo", repoName}); err != nil {
+			t.Fatalf("work %q failed: %v", task, err)
+		}
+	}
+	queue, err := d.GetState().GetTaskQueue(repoName)
+	if err != nil {
+		t.Fatalf("GetTaskQueue() failed: %v", err)
+	}
+	if len(queue) != 2 || queue[0].Task != "First queued task" {
+		t.Fatalf("queue = %+v, want both tasks in order", queue)
+	}
+	if count, _ := d.GetState().ActiveWorkerCount(repoName); count != 1 {
+		t.Errorf("active workers = %d, want 1 (no new workers started)", count)
+	}
+
+	if err := cli.Execute([]string{"work", "queue", "--repo", repoName}); err != nil {
+		t.Errorf("work queue failed: %v", err)
+	}
+	if err := cli.Execute([]string{"work", "queue", "move", queue[1].ID, "1", "--repo", repoName}); err != nil {
+		t.Fatalf("work queue move failed: %v", err)
+	}
+	if err := cli.Execute([]string{"work", "queue", "cancel", queue[0].ID, "--repo", repoName}); err != nil {
+		t.Fatalf("work queue cancel failed: %v", err)
+	}
+
+	remaining, _ := d.GetState().GetTaskQueue(repoName)
+	if len(remaining) != 1 || remaining[0].ID != queue[1].ID {
+		t.Errorf("queue after move and cancel = %+v, want only %s", remaining, queue[1].ID)
+	}
+
+	if err := cli.Execute([]string{"work", "queue", "move", queue[1].ID, "first", "--repo", repoName}); err == nil {
+		t.Error("work queue move should reject a non-numeric position")
+	}
+	if err := cli.Execute([]string{"work", "queue", "cancel", "task-missing", "--repo", repoName}); err == nil {
+		t.Error("work queue cancel should fail for an unknown task")
+	}
+
+	// --after always queues, recording the dependency
+	if err := cli.Execute([]string{"work", "Build on busy-worker", "--after", "busy-worker", "--repo", repoName}); err != nil {
+		t.Fatalf("work --after failed: %v", err)
+	}
+	remaining, _ = d.GetState().GetTaskQueue(repoName)
+	if len(remaining) != 2 || remaining[1].After != "busy-worker" {
+		t.Errorf("queue after work --after = %+v", remaining)
+	}
+	if err := cli.Execute([]string{"work", "Build on nothing", "--after", "no-such-worker", "--repo", repoName}); err == nil {
+		t.Error("work --after should fail for an unknown worker")
+	}
+
+	// A task waiting on another PR says which branch it will start from
+	if err := d.GetState().SetDefaultBranch(repoName, "develop"); err != nil {
+		t.Fatalf("SetDefaultBranch() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		queued client.QueuedTask
+		want   string
+	}{
+		{client.QueuedTask{After: "busy-worker"}, "develop"},
+		{client.QueuedTask{After: "busy-worker", Base: "release"}, "release"},
+		{client.QueuedTask{After: "busy-worker", Base: "release", Branch: "feature"}, "feature"},
+	} {
+		if got := cli.queuedTaskStartRef(repoName, &tc.queued); got != tc.want {
+			t.Errorf("queuedTaskStartRef(%+v) = %q, want %q", tc.queued, got, tc.want)
+		}
+	}
+
+	// --max-workers round-trips through config
+	if err := cli.Execute([]string{"config", repoName, "--max-workers=3"}); err != nil {
+		t.Fatalf("config --max-workers failed: %v", err)
+	}
+	updated, _ := d.GetState().GetRepo(repoName)
+	if updated.MaxWorkers != 3 {
+		t.Errorf("MaxWorkers = %d, want 3", updated.MaxWorkers)
+	}
+	if err := cli.Execute([]string{"config", repoName, "--max-workers=-1"}); err == nil {
+		t.Error("config should reject a negative --max-workers")
+	}
+}
+
 func TestCLICleanupCommand(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -987,6 +1429,103 @@ func TestCLIRepairCommand(t *testing.T) {
 	}
 }
 
+func TestCLIRepairMigrateOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := config.NewTestPaths(tmpDir)
+	cli := NewWithPaths(paths)
+
+	legacy := `{"repos": {"app": {"github_url": "https://github.com/example/app", "tmux_session": "mc-app", "agents": null}}}`
+	if err := os.WriteFile(paths.StateFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	if err := cli.Execute([]string{"repair", "--migrate-only"}); err != nil {
+		t.Fatalf("repair --migrate-only failed: %v", err)
+	}
+
+	if backup, err := os.ReadFile(state.BackupPath(paths.StateFile, 0)); err != nil || string(backup) != legacy {
+		t.Errorf("backup = %q, %v, want the legacy state", backup, err)
+	}
+	st, err := state.Load(paths.StateFile)
+	if err != nil {
+		t.Fatalf("Failed to load migrated state: %v", err)
+	}
+	if st.SchemaVersion != state.CurrentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", st.SchemaVersion, state.CurrentSchemaVersion)
+	}
+
+	// Running it again is a no-op
+	if err := cli.Execute([]string{"repair", "--migrate-only"}); err != nil {
+		t.Errorf("second repair --migrate-only failed: %v", err)
+	}
+}
+
+func TestCLIStateRestoreWhileDaemonStopped(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := config.NewTestPaths(tmpDir)
+	cli := NewWithPaths(paths)
+
+	st := state.New(paths.StateFile)
+	if err := st.AddRepo("app", &state.Repository{TmuxSession: "mc-app", Agents: map[string]state.Agent{
+		"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+	}}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	msgPath := filepath.Join(paths.AgentMessagesDir("app", "supervisor"), "msg-1.json")
+	if err := os.MkdirAll(filepath.Dir(msgPath), 0755); err != nil {
+		t.Fatalf("Failed to create messages directory: %v", err)
+	}
+	if err := os.WriteFile(msgPath, []byte(`{"id":"msg-1"}`), 0644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	snap, err := snapshot.NewManager(paths.SnapshotsDir()).Create(paths.StateFile, paths.MessagesDir, snapshot.ReasonPeriodic)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	// Damage the state file and lose the messages
+	damaged := []byte(`{"repos": {"app": `)
+	if err := os.WriteFile(paths.StateFile, damaged, 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	if err := os.RemoveAll(paths.MessagesDir); err != nil {
+		t.Fatalf("Failed to remove messages: %v", err)
+	}
+
+	if err := cli.Execute([]string{"state", "snapshots"}); err != nil {
+		t.Errorf("state snapshots failed: %v", err)
+	}
+	if err := cli.Execute([]string{"state", "diff", snap.ID}); err != nil {
+		t.Errorf("state diff failed: %v", err)
+	}
+	if err := cli.Execute([]string{"state", "diff", "missing"}); err == nil {
+		t.Error("state diff of an unknown snapshot should fail")
+	}
+	if err := cli.Execute([]string{"state", "restore", snap.ID, "--yes"}); err != nil {
+		t.Fatalf("state restore failed: %v", err)
+	}
+
+	view, err := state.LoadView(paths.StateFile)
+	if err != nil {
+		t.Fatalf("restored state doesn't load: %v", err)
+	}
+	if _, ok := view.GetAgent("app", "supervisor"); !ok {
+		t.Error("restored state is missing the supervisor")
+	}
+	if _, err := os.Stat(msgPath); err != nil {
+		t.Errorf("message was not restored: %v", err)
+	}
+
+	// The damaged file was kept in a snapshot of its own
+	snapshots, err := snapshot.NewManager(paths.SnapshotsDir()).List()
+	if err != nil || len(snapshots) != 2 || snapshots[0].Reason != snapshot.ReasonRestore {
+		t.Fatalf("snapshots = %+v, %v, want a backup taken before the restore", snapshots, err)
+	}
+	if backup, _ := os.ReadFile(snapshots[0].StatePath()); string(backup) != string(damaged) {
+		t.Errorf("backup state = %q, want the damaged file", backup)
+	}
+}
+
 func TestCLIDocsCommand(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -1708,81 +2247,250 @@ func TestCLIWorkspaceConnectMissingName(t *testing.T) {
 	if err == nil {
 		t.Error("workspace connect without name should fail")
 	}
-}
+}
+
+// Config and additional tests from PR #81
+
+func TestCLIConfigRepoNoArgs(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Config with no args should show help/error
+	err := cli.Execute([]string{"config"})
+	if err == nil {
+		t.Error("config with no args should fail")
+	}
+}
+
+func TestCLIConfigRepoShow(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Add a repo with specific config
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+		MergeQueueConfig: state.MergeQueueConfig{
+			Enabled:   true,
+			TrackMode: state.TrackModeAuthor,
+		},
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Show config should work
+	err := cli.Execute([]string{"config", "test-repo"})
+	if err != nil {
+		t.Errorf("config show failed: %v", err)
+	}
+}
+
+func TestCLIConfigRepoUpdateViaSocket(t *testing.T) {
+	_, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Add a repo
+	repo := &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      "mc-test-repo",
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Update config via socket directly (tests the actual update mechanism)
+	client := socket.NewClient(d.GetPaths().DaemonSock)
+	resp, err := client.Send(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":       "test-repo",
+			"mq_enabled": false,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to send update_repo_config: %v", err)
+	}
+	if !resp.Success {
+		t.Errorf("update_repo_config failed: %s", resp.Error)
+	}
+
+	// Verify the update took effect
+	updatedRepo, _ := d.GetState().GetRepo("test-repo")
+	if updatedRepo.MergeQueueConfig.Enabled != false {
+		t.Error("MergeQueueConfig.Enabled should be false after update")
+	}
+}
+
+func TestCLIConfigRepoActivity(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"config", "test-repo", "--idle-minutes=5", "--stuck-minutes=15", "--escalate-stuck=false"}); err != nil {
+		t.Fatalf("config update failed: %v", err)
+	}
+
+	cfg, err := d.GetState().GetActivityConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetActivityConfig() failed: %v", err)
+	}
+	want := state.ActivityConfig{IdleMinutes: 5, StuckMinutes: 15, EscalateStuck: false}
+	if cfg != want {
+		t.Errorf("activity config = %+v, want %+v", cfg, want)
+	}
+
+	if err := cli.Execute([]string{"config", "test-repo", "--idle-minutes=soon"}); err == nil {
+		t.Error("config should reject a non-numeric --idle-minutes")
+	}
+}
+
+func TestCLIConfigRepoWakePolicy(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"config", "test-repo", "--wake-type=worker", "--wake-interval=15", "--wake-quiet-hours=22:00-07:00", "--wake-only-when=idle,pr_activity"}); err != nil {
+		t.Fatalf("config update failed: %v", err)
+	}
 
-// Config and additional tests from PR #81
+	policy, err := d.GetState().GetWakePolicy("test-repo", state.AgentTypeWorker)
+	if err != nil {
+		t.Fatalf("GetWakePolicy() failed: %v", err)
+	}
+	if policy.IntervalMinutes != 15 || policy.QuietHours != "22:00-07:00" || len(policy.OnlyWhen) != 2 || policy.OnlyWhen[1] != state.WakeWhenPRActivity {
+		t.Errorf("worker policy = %+v", policy)
+	}
 
-func TestCLIConfigRepoNoArgs(t *testing.T) {
-	cli, _, cleanup := setupTestEnvironment(t)
-	defer cleanup()
+	// "none" clears quiet hours and conditions
+	if err := cli.Execute([]string{"config", "test-repo", "--wake-type=worker", "--wake-quiet-hours=none", "--wake-only-when=none"}); err != nil {
+		t.Fatalf("config update failed: %v", err)
+	}
+	policy, _ = d.GetState().GetWakePolicy("test-repo", state.AgentTypeWorker)
+	if policy.QuietHours != "" || len(policy.OnlyWhen) != 0 || policy.IntervalMinutes != 15 {
+		t.Errorf("worker policy after clearing = %+v", policy)
+	}
 
-	// Config with no args should show help/error
-	err := cli.Execute([]string{"config"})
-	if err == nil {
-		t.Error("config with no args should fail")
+	invalid := [][]string{
+		{"config", "test-repo", "--wake-interval=5"},
+		{"config", "test-repo", "--wake-type=workspace", "--wake-interval=5"},
+		{"config", "test-repo", "--wake-type=worker"},
+		{"config", "test-repo", "--wake-type=worker", "--wake-interval=often"},
+		{"config", "test-repo", "--wake-type=worker", "--wake-only-when=sometimes"},
+	}
+	for _, args := range invalid {
+		if err := cli.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
 	}
 }
 
-func TestCLIConfigRepoShow(t *testing.T) {
+func TestCLIConfigRepoClaudeOptions(t *testing.T) {
 	cli, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	// Add a repo with specific config
 	repo := &state.Repository{
 		GithubURL:   "https://github.com/test/repo",
 		TmuxSession: "mc-test-repo",
 		Agents:      make(map[string]state.Agent),
-		MergeQueueConfig: state.MergeQueueConfig{
-			Enabled:   true,
-			TrackMode: state.TrackModeAuthor,
-		},
 	}
 	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
 
-	// Show config should work
-	err := cli.Execute([]string{"config", "test-repo"})
+	if err := cli.Execute([]string{"config", "test-repo", "--claude-type=review", "--model=opus", "--claude-args=--verbose --max-turns 50", "--claude-env=GOFLAGS=-mod=mod,HTTPS_PROXY=http://proxy:3128"}); err != nil {
+		t.Fatalf("config update failed: %v", err)
+	}
+
+	opts, err := d.GetState().GetClaudeOptions("test-repo", state.AgentTypeReview)
 	if err != nil {
-		t.Errorf("config show failed: %v", err)
+		t.Fatalf("GetClaudeOptions() failed: %v", err)
+	}
+	if opts.Model != "opus" || len(opts.Args) != 3 || opts.Env["HTTPS_PROXY"] != "http://proxy:3128" {
+		t.Errorf("review options = %+v", opts)
+	}
+
+	// "none" clears a field and leaves the others
+	if err := cli.Execute([]string{"config", "test-repo", "--claude-type=review", "--claude-args=none"}); err != nil {
+		t.Fatalf("config update failed: %v", err)
+	}
+	opts, _ = d.GetState().GetClaudeOptions("test-repo", state.AgentTypeReview)
+	if opts.Model != "opus" || len(opts.Args) != 0 || len(opts.Env) != 2 {
+		t.Errorf("review options after clearing args = %+v", opts)
+	}
+
+	invalid := [][]string{
+		{"config", "test-repo", "--model=opus"},
+		{"config", "test-repo", "--claude-type=reviewer", "--model=opus"},
+		{"config", "test-repo", "--claude-type=worker"},
+		{"config", "test-repo", "--claude-type=worker", "--claude-env=GOFLAGS"},
+	}
+	for _, args := range invalid {
+		if err := cli.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
 	}
 }
 
-func TestCLIConfigRepoUpdateViaSocket(t *testing.T) {
-	_, d, cleanup := setupTestEnvironment(t)
+func TestCLIWorkClaudeOptions(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	// Add a repo
+	// A full worker slot keeps the task in the queue so its options can be checked
+	repoName := "test-repo"
 	repo := &state.Repository{
-		GithubURL:        "https://github.com/test/repo",
-		TmuxSession:      "mc-test-repo",
-		Agents:           make(map[string]state.Agent),
-		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+		MaxWorkers:  1,
 	}
-	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
+	if err := d.GetState().AddAgent(repoName, "busy-worker", state.Agent{
+		Type:       state.AgentTypeWorker,
+		TmuxWindow: "busy-worker",
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
 
-	// Update config via socket directly (tests the actual update mechanism)
-	client := socket.NewClient(d.GetPaths().DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "update_repo_config",
-		Args: map[string]interface{}{
-			"name":       "test-repo",
-			"mq_enabled": false,
-		},
-	})
-	if err != nil {
-		t.Fatalf("Failed to send update_repo_config: %v", err)
+	if err := cli.Execute([]string{"work", "Use a bigger model", "--model", "opus", "--claude-args=--verbose", "--claude-env", "GOFLAGS=-mod=mod", "--repo", repoName}); err != nil {
+		t.Fatalf("work --model failed: %v", err)
 	}
-	if !resp.Success {
-		t.Errorf("update_repo_config failed: %s", resp.Error)
+
+	queue, _ := d.GetState().GetTaskQueue(repoName)
+	if len(queue) != 1 || queue[0].Claude == nil {
+		t.Fatalf("queue = %+v, want one task with Claude options", queue)
+	}
+	if got := queue[0].Claude; got.Model != "opus" || len(got.Args) != 1 || got.Args[0] != "--verbose" || got.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("queued task Claude options = %+v", got)
 	}
 
-	// Verify the update took effect
-	updatedRepo, _ := d.GetState().GetRepo("test-repo")
-	if updatedRepo.MergeQueueConfig.Enabled != false {
-		t.Error("MergeQueueConfig.Enabled should be false after update")
+	if err := cli.Execute([]string{"work", "Bad env", "--claude-env", "NOT-VALID=1", "--repo", repoName}); err == nil {
+		t.Error("work should reject an invalid --claude-env name")
+	}
+	if err := cli.Execute([]string{"work", "No args", "--claude-args", "--verbose", "--repo", repoName}); err == nil {
+		t.Error("work should reject --claude-args without a value")
 	}
 }
 
@@ -2140,6 +2848,11 @@ func TestInitRepoNameParsing(t *testing.T) {
 			url:       "https://github.com/user/repo///",
 			wantError: false, // TrimRight removes all trailing slashes
 		},
+		{
+			name:      "local bare repo path",
+			url:       "/srv/git/app.git",
+			wantError: false, // Named after the bare repo, for the local forge
+		},
 		{
 			name:        "URL that is just slashes",
 			url:         "///",
@@ -2809,15 +3522,15 @@ func TestSpawnAgentFromFile(t *testing.T) {
 			args:      []string{"--class", "ephemeral", "--prompt-file", "/tmp/prompt.md"},
 			wantError: "--name is required",
 		},
-		{
-			name:      "missing class flag",
-			args:      []string{"--name", "test-agent", "--prompt-file", "/tmp/prompt.md"},
-			wantError: "--class is required",
-		},
 		{
 			name:      "missing prompt-file flag",
 			args:      []string{"--name", "test-agent", "--class", "ephemeral"},
-			wantError: "--prompt-file is required",
+			wantError: "--definition or --prompt-file is required",
+		},
+		{
+			name:      "definition and prompt-file together",
+			args:      []string{"--definition", "worker", "--prompt-file", "/tmp/prompt.md"},
+			wantError: "cannot be used together",
 		},
 		{
 			name:      "invalid class value",
@@ -2879,6 +3592,63 @@ func TestSpawnAgentFromFilePromptNotFound(t *testing.T) {
 	}
 }
 
+func TestSpawnAgentFromDefinition(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	tmuxSession := "mc-test-definition"
+	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	repoName := "test-repo"
+	if err := os.MkdirAll(cli.paths.RepoDir(repoName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetState().AddRepo(repoName, &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: tmuxSession,
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	agentsDir := cli.paths.RepoAgentsDir(repoName)
+	if err := os.MkdirAll(agentsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "---\ntype: persistent\nmodel: opus\n---\n# Triager\n\nTriage issues.\n"
+	if err := os.WriteFile(filepath.Join(agentsDir, "triager.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli.Execute([]string{"agents", "list", "--repo", repoName}); err != nil {
+		t.Fatalf("agents list failed: %v", err)
+	}
+
+	// The name and class come from the definition
+	if err := cli.Execute([]string{"agents", "spawn", "--definition", "triager", "--repo", repoName}); err != nil {
+		t.Fatalf("agents spawn --definition failed: %v", err)
+	}
+
+	agent, exists := d.GetState().GetAgent(repoName, "triager")
+	if !exists {
+		t.Fatal("triager should be registered")
+	}
+	if agent.Type != state.AgentTypeGenericPersistent || agent.Definition != "triager" {
+		t.Errorf("agent = %+v, want a generic persistent agent from the triager definition", agent)
+	}
+	if agent.Claude == nil || agent.Claude.Model != "opus" {
+		t.Errorf("agent.Claude = %+v, want model opus", agent.Claude)
+	}
+}
+
 func TestResetAgentDefinitions(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -3188,9 +3958,10 @@ func TestCLIGetPRStatusForBranch(t *testing.T) {
 	// Create a test repo
 	repoPath := cli.paths.RepoDir("pr-status-test")
 	setupTestRepo(t, repoPath)
+	f := forge.NewGitHub(github.NewClient("gh"), repoPath)
 
 	t.Run("returns existing PR URL when provided", func(t *testing.T) {
-		status, link := cli.getPRStatusForBranch(repoPath, "test-branch", "https://github.com/test/repo/pull/123")
+		status, link := cli.getPRStatusForBranch(f, "test-branch", "https://github.com/test/repo/pull/123")
 		if status != "unknown" {
 			t.Errorf("status = %v, want unknown", status)
 		}
@@ -3200,7 +3971,7 @@ func TestCLIGetPRStatusForBranch(t *testing.T) {
 	})
 
 	t.Run("returns no-pr when branch is empty", func(t *testing.T) {
-		status, link := cli.getPRStatusForBranch(repoPath, "", "")
+		status, link := cli.getPRStatusForBranch(f, "", "")
 		if status != "no-pr" {
 			t.Errorf("status = %v, want no-pr", status)
 		}
@@ -3210,7 +3981,7 @@ func TestCLIGetPRStatusForBranch(t *testing.T) {
 	})
 
 	t.Run("handles branch with no PR", func(t *testing.T) {
-		status, link := cli.getPRStatusForBranch(repoPath, "nonexistent-branch", "")
+		status, link := cli.getPRStatusForBranch(f, "nonexistent-branch", "")
 		if status != "no-pr" {
 			t.Errorf("status = %v, want no-pr", status)
 		}
@@ -3220,6 +3991,122 @@ func TestCLIGetPRStatusForBranch(t *testing.T) {
 	})
 }
 
+// TestCLIPRCommandsLocalForge tests pr create, list and merge against a repo
+// cloned from a local bare repository
+func TestCLIPRCommandsLocalForge(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	bare := filepath.Join(cli.paths.Root, "app.git")
+	repoPath := cli.paths.RepoDir("local-repo")
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	run(cli.paths.Root, "init", "-q", "--bare", "-b", "main", bare)
+	setupTestRepo(t, repoPath)
+	run(repoPath, "remote", "add", "origin", bare)
+	run(repoPath, "push", "-q", "origin", "HEAD:main")
+	run(repoPath, "checkout", "-q", "-b", "work/calm-otter")
+	if err := os.WriteFile(filepath.Join(repoPath, "login.go"), []byte("package app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	run(repoPath, "add", "login.go")
+	run(repoPath, "commit", "-q", "-m", "Add login")
+	run(repoPath, "push", "-q", "origin", "work/calm-otter")
+
+	repo := &state.Repository{
+		GithubURL:     bare,
+		TmuxSession:   "mc-local-repo",
+		Agents:        make(map[string]state.Agent),
+		DefaultBranch: "main",
+	}
+	if err := d.GetState().AddRepo("local-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if !cli.usesLocalForge("local-repo") {
+		t.Error("usesLocalForge() = false for a repo cloned from a path")
+	}
+
+	if err := cli.Execute([]string{"pr", "create", "--repo", "local-repo", "--head", "work/calm-otter", "--title", "Add login"}); err != nil {
+		t.Fatalf("pr create failed: %v", err)
+	}
+	local := forge.NewLocal(bare)
+	pr, err := local.PRForBranch("work/calm-otter")
+	if err != nil || pr == nil || pr.Number != 1 || pr.Base != "main" || pr.State != forge.StateOpen {
+		t.Fatalf("PR for work/calm-otter = %+v, %v, want open PR #1 into main", pr, err)
+	}
+
+	if err := cli.Execute([]string{"pr", "list", "--repo", "local-repo", "--state", "all"}); err != nil {
+		t.Errorf("pr list failed: %v", err)
+	}
+	if err := cli.Execute([]string{"pr", "list", "--repo", "local-repo", "--state", "draft"}); err == nil {
+		t.Error("pr list with an unknown state should fail")
+	}
+	if err := cli.Execute([]string{"pr", "merge", "--repo", "local-repo"}); err == nil {
+		t.Error("pr merge without a number should fail")
+	}
+
+	// Reviews and checks show up on the PR
+	if err := cli.Execute([]string{"pr", "review", "1", "--request-changes", "--body", "Needs tests", "--repo", "local-repo"}); err != nil {
+		t.Fatalf("pr review failed: %v", err)
+	}
+	if err := cli.Execute([]string{"pr", "check", "1", "--name", "test", "--state", "failure", "--repo", "local-repo"}); err != nil {
+		t.Fatalf("pr check failed: %v", err)
+	}
+	pr, _ = local.GetPR(1)
+	if pr.CIStatus != forge.CIFailing || pr.ReviewDecision != forge.ReviewChangesRequested || len(pr.Reviews) != 1 || pr.Reviews[0].Body != "Needs tests" {
+		t.Errorf("PR #1 = %+v, want failing test and changes requested", pr)
+	}
+	if err := cli.Execute([]string{"pr", "review", "1", "--approve", "--comment", "--repo", "local-repo"}); err == nil {
+		t.Error("pr review with two verdicts should fail")
+	}
+	if err := cli.Execute([]string{"pr", "check", "work/calm-otter", "--name", "test", "--state", "passed", "--repo", "local-repo"}); err == nil {
+		t.Error("pr check with an unknown state should fail")
+	}
+	if err := cli.Execute([]string{"pr", "check", "work/calm-otter", "--name", "test", "--state", "success", "--repo", "local-repo"}); err != nil {
+		t.Fatalf("pr check of a branch failed: %v", err)
+	}
+	if err := cli.Execute([]string{"pr", "review", "1", "--approve", "--repo", "local-repo"}); err != nil {
+		t.Fatalf("pr review failed: %v", err)
+	}
+	if pr, _ := local.GetPR(1); pr.CIStatus != forge.CIPassing || pr.ReviewDecision != forge.ReviewApproved {
+		t.Errorf("PR #1 = %+v, want passing and approved", pr)
+	}
+	if err := cli.Execute([]string{"pr", "view", "1", "--repo", "local-repo"}); err != nil {
+		t.Errorf("pr view failed: %v", err)
+	}
+	if err := cli.Execute([]string{"pr", "diff", "1", "--repo", "local-repo"}); err != nil {
+		t.Errorf("pr diff failed: %v", err)
+	}
+
+	// Reviewers look PRs up on the local forge and are told to use multiclaude pr
+	if err := cli.Execute([]string{"review", "7", "--repo", "local-repo"}); err == nil || !strings.Contains(err.Error(), "PR #7") {
+		t.Errorf("review of a missing PR error = %v, want a lookup failure", err)
+	}
+	promptFile, err := cli.writePromptFile(repoPath, state.AgentTypeReview, "review-1")
+	if err != nil {
+		t.Fatalf("writePromptFile() failed: %v", err)
+	}
+	if prompt, _ := os.ReadFile(promptFile); !strings.Contains(string(prompt), "multiclaude pr review") {
+		t.Error("reviewer prompt for a local forge repo should list the multiclaude pr commands")
+	}
+
+	if err := cli.Execute([]string{"pr", "merge", "1", "--squash", "--repo", "local-repo"}); err != nil {
+		t.Fatalf("pr merge failed: %v", err)
+	}
+	if pr, _ := local.PRForBranch("work/calm-otter"); pr == nil || pr.State != forge.StateMerged {
+		t.Errorf("PR #1 = %+v after pr merge, want merged", pr)
+	}
+	if status, link := cli.getPRStatusForBranch(local, "work/calm-otter", ""); status != "merged" || link != "#1" {
+		t.Errorf("getPRStatusForBranch() = %s, %s, want merged #1", status, link)
+	}
+}
+
 // TestParseDuration tests the parseDuration utility function
 func TestParseDuration(t *testing.T) {
 	tests := []struct {
@@ -3589,3 +4476,200 @@ func TestLoadStateFunction(t *testing.T) {
 		}
 	})
 }
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		input     string
+		want      time.Time
+		wantError bool
+	}{
+		{"minutes", "30m", now.Add(-30 * time.Minute), false},
+		{"compound duration", "1h30m", now.Add(-90 * time.Minute), false},
+		{"days", "2d", now.Add(-48 * time.Hour), false},
+		{"rfc3339", "2024-01-15T02:00:00Z", time.Date(2024, 1, 15, 2, 0, 0, 0, time.UTC), false},
+		{"date and time", "2024-01-15 02:00", time.Date(2024, 1, 15, 2, 0, 0, 0, time.Local), false},
+		{"date only", "2024-01-15", time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local), false},
+		{"garbage", "yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSince(tt.input, now)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("parseSince(%q) expected error, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSince(%q) unexpected error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatEventData(t *testing.T) {
+	got := formatEventData(map[string]interface{}{
+		"task":       "Fix the\nlogin bug",
+		"agent_type": "worker",
+		"empty":      "",
+	})
+	want := `agent_type=worker task="Fix the login bug"`
+	if got != want {
+		t.Errorf("formatEventData() = %q, want %q", got, want)
+	}
+}
+
+func TestCLIEvents(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	journal := events.NewJournal(cli.paths.EventsJournal())
+	for _, e := range []events.Event{
+		events.NewAgentStartedEvent("repo-a", "swift-eagle", "worker", "Fix bug"),
+		events.NewWorkerStuckEvent("repo-a", "swift-eagle", 30),
+		events.NewAgentStartedEvent("repo-b", "calm-fox", "worker", "Add docs"),
+	} {
+		e.Timestamp = time.Now()
+		if err := journal.Append(e); err != nil {
+			t.Fatalf("Failed to append event: %v", err)
+		}
+	}
+
+	got, err := cli.queryEvents(client.EventQuery{Repo: "repo-a", Types: []string{"worker_stuck"}})
+	if err != nil {
+		t.Fatalf("queryEvents() failed: %v", err)
+	}
+	if len(got) != 1 || got[0].AgentName != "swift-eagle" || got[0].Data["duration_minutes"] != float64(30) {
+		t.Errorf("queryEvents() = %+v, want the swift-eagle worker_stuck event", got)
+	}
+
+	if err := cli.Execute([]string{"events", "--agent", "calm-fox", "--since", "1h"}); err != nil {
+		t.Errorf("events command failed: %v", err)
+	}
+	if err := cli.Execute([]string{"events", "--since", "not-a-time"}); err == nil {
+		t.Error("events command should reject an invalid --since")
+	}
+}
+
+func TestFollowCursor(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	journal := events.NewJournal(cli.paths.EventsJournal())
+	start := time.Now()
+	appendAt := func(ts time.Time, e events.Event) {
+		t.Helper()
+		e.Timestamp = ts
+		if err := journal.Append(e); err != nil {
+			t.Fatalf("Failed to append event: %v", err)
+		}
+	}
+	poll := func(cursor *followCursor) []events.Event {
+		t.Helper()
+		evts, err := cli.queryEvents(client.EventQuery{Since: cursor.last})
+		if err != nil {
+			t.Fatalf("queryEvents() failed: %v", err)
+		}
+		return cursor.next(evts)
+	}
+
+	cursor := newFollowCursor(start)
+	appendAt(start, events.NewAgentStartedEvent("repo-a", "swift-eagle", "worker", "Fix bug"))
+	if got := poll(cursor); len(got) != 1 {
+		t.Fatalf("first poll = %+v, want 1 event", got)
+	}
+
+	// Events sharing the last timestamp printed still show up, once
+	appendAt(start, events.NewAgentStartedEvent("repo-a", "calm-fox", "worker", "Add docs"))
+	appendAt(start, events.NewWorkerStuckEvent("repo-a", "swift-eagle", 30))
+	got := poll(cursor)
+	if len(got) != 2 || got[0].AgentName != "calm-fox" || got[1].Type != events.EventWorkerStuck {
+		t.Errorf("second poll = %+v, want calm-fox started and swift-eagle stuck", got)
+	}
+	if got := poll(cursor); len(got) != 0 {
+		t.Errorf("poll without new events = %+v, want none", got)
+	}
+
+	appendAt(start.Add(time.Second), events.NewAgentStartedEvent("repo-a", "swift-eagle", "worker", "Fix bug"))
+	if got := poll(cursor); len(got) != 1 || !got[0].Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("poll after a later event = %+v, want only that event", got)
+	}
+}
+
+func TestFormatIdleDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "<1m"},
+		{12 * time.Minute, "12m"},
+		{2*time.Hour + 5*time.Minute, "2h5m"},
+	}
+	for _, tt := range tests {
+		if got := formatIdleDuration(tt.d); got != tt.want {
+			t.Errorf("formatIdleDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIdleCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent client.Agent
+		want  string
+	}{
+		{"not sampled", client.Agent{}, "-"},
+		{"active", client.Agent{IdleSeconds: 90, Activity: "active"}, "1m"},
+		{"stuck", client.Agent{IdleSeconds: 2400, Activity: "stuck"}, "40m (stuck)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatIdleCell(tt.agent).Text; got != tt.want {
+				t.Errorf("formatIdleCell() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIWatch(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-watch",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	sub, err := cli.subscribeEvents(client.SubscribeRequest{Repo: "test-repo", Agent: "calm-fox"})
+	if err != nil {
+		t.Fatalf("subscribeEvents() failed: %v", err)
+	}
+	defer sub.Close()
+
+	if err := d.GetState().AddAgent("test-repo", "calm-fox", state.Agent{Type: state.AgentTypeWorker, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	e, err := sub.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if e.Type != string(events.EventStateChanged) || e.AgentName != "calm-fox" || e.Data["change"] != "agent_added" {
+		t.Errorf("watched event = %+v, want agent_added for calm-fox", e)
+	}
+
+	if err := cli.Execute([]string{"watch", "--repo", "no-such-repo"}); err == nil {
+		t.Error("watch should fail for an unknown repo")
+	}
+}
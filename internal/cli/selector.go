@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/pkg/client"
 )
 
 // SelectableItem represents an item that can be selected from a list
@@ -97,6 +98,10 @@ func formatAgentStatusCell(status string) format.ColoredCell {
 		return format.ColorCell(format.ColoredStatus(format.StatusCompleted), nil)
 	case "stopped":
 		return format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+	case "crash-looping":
+		return format.ColorCell(format.Red.Sprintf("%s crash-looping", format.StatusIcon(format.StatusError)), nil)
+	case "needs-rebase":
+		return format.ColorCell(format.Yellow.Sprintf("%s needs-rebase", format.StatusIcon(format.StatusWarning)), nil)
 	default:
 		return format.ColorCell(format.ColoredStatus(format.StatusIdle), nil)
 	}
@@ -104,7 +109,7 @@ func formatAgentStatusCell(status string) format.ColoredCell {
 
 // agentsToSelectableItems converts a list of agents to selectable items,
 // filtering by the specified types. If types is empty, all agents are included.
-func agentsToSelectableItems(agents []interface{}, types []string) []SelectableItem {
+func agentsToSelectableItems(agents []client.Agent, types []string) []SelectableItem {
 	var items []SelectableItem
 	typeSet := make(map[string]bool)
 	for _, t := range types {
@@ -112,50 +117,39 @@ func agentsToSelectableItems(agents []interface{}, types []string) []SelectableI
 	}
 
 	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-
-			// Filter by type if specified
-			if len(typeSet) > 0 && !typeSet[agentType] {
-				continue
-			}
-
-			// Build description from available fields
-			var desc string
-			if task, ok := agentMap["task"].(string); ok && task != "" {
-				desc = format.Truncate(task, 50)
-			} else if status, ok := agentMap["status"].(string); ok {
-				desc = status
-			}
-
-			items = append(items, SelectableItem{
-				Name:        name,
-				Description: desc,
-			})
+		// Filter by type if specified
+		if len(typeSet) > 0 && !typeSet[agent.Type] {
+			continue
 		}
+
+		// Build description from available fields
+		desc := agent.Status
+		if agent.Task != "" {
+			desc = format.Truncate(agent.Task, 50)
+		}
+
+		items = append(items, SelectableItem{
+			Name:        agent.Name,
+			Description: desc,
+		})
 	}
 	return items
 }
 
 // reposToSelectableItems converts a list of repos to selectable items.
-func reposToSelectableItems(repos []interface{}) []SelectableItem {
+func reposToSelectableItems(repos []client.Repo) []SelectableItem {
 	var items []SelectableItem
 	for _, repo := range repos {
-		if repoMap, ok := repo.(map[string]interface{}); ok {
-			name, _ := repoMap["name"].(string)
-
-			// Build description from agent count
-			var desc string
-			if totalAgents, ok := repoMap["total_agents"].(float64); ok && totalAgents > 0 {
-				desc = fmt.Sprintf("%d agents", int(totalAgents))
-			}
-
-			items = append(items, SelectableItem{
-				Name:        name,
-				Description: desc,
-			})
+		// Build description from agent count
+		var desc string
+		if repo.TotalAgents > 0 {
+			desc = fmt.Sprintf("%d agents", repo.TotalAgents)
 		}
+
+		items = append(items, SelectableItem{
+			Name:        repo.Name,
+			Description: desc,
+		})
 	}
 	return items
 }
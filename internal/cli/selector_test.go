@@ -3,6 +3,8 @@ package cli
 import (
 	"strings"
 	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/client"
 )
 
 func TestSelectableItem(t *testing.T) {
@@ -21,28 +23,11 @@ func TestSelectableItem(t *testing.T) {
 }
 
 func TestAgentsToSelectableItems(t *testing.T) {
-	agents := []interface{}{
-		map[string]interface{}{
-			"name":   "worker-1",
-			"type":   "worker",
-			"task":   "Fix bug in login",
-			"status": "running",
-		},
-		map[string]interface{}{
-			"name":   "worker-2",
-			"type":   "worker",
-			"status": "completed",
-		},
-		map[string]interface{}{
-			"name":   "default",
-			"type":   "workspace",
-			"status": "idle",
-		},
-		map[string]interface{}{
-			"name":   "supervisor",
-			"type":   "supervisor",
-			"status": "running",
-		},
+	agents := []client.Agent{
+		{Name: "worker-1", Type: "worker", Task: "Fix bug in login", Status: "running"},
+		{Name: "worker-2", Type: "worker", Status: "completed"},
+		{Name: "default", Type: "workspace", Status: "idle"},
+		{Name: "supervisor", Type: "supervisor", Status: "running"},
 	}
 
 	// Test filtering by worker type
@@ -96,18 +81,10 @@ func TestAgentsToSelectableItems(t *testing.T) {
 }
 
 func TestReposToSelectableItems(t *testing.T) {
-	repos := []interface{}{
-		map[string]interface{}{
-			"name":         "repo1",
-			"total_agents": float64(5),
-		},
-		map[string]interface{}{
-			"name":         "repo2",
-			"total_agents": float64(0),
-		},
-		map[string]interface{}{
-			"name": "repo3",
-		},
+	repos := []client.Repo{
+		{Name: "repo1", TotalAgents: 5},
+		{Name: "repo2", TotalAgents: 0},
+		{Name: "repo3"},
 	}
 
 	items := reposToSelectableItems(repos)
@@ -150,53 +127,25 @@ func TestAgentsToSelectableItems_EmptyInput(t *testing.T) {
 	}
 
 	// Test with empty slice
-	items = agentsToSelectableItems([]interface{}{}, nil)
+	items = agentsToSelectableItems([]client.Agent{}, nil)
 	if len(items) != 0 {
 		t.Errorf("expected 0 items for empty input, got %d", len(items))
 	}
 }
 
-func TestAgentsToSelectableItems_MalformedData(t *testing.T) {
-	// Test with non-map data (should be skipped)
-	agents := []interface{}{
-		"not a map",
-		123,
-		nil,
-		map[string]interface{}{
-			"name": "valid-agent",
-			"type": "worker",
-		},
-	}
-
-	items := agentsToSelectableItems(agents, nil)
-	if len(items) != 1 {
-		t.Errorf("expected 1 valid item, got %d", len(items))
-	}
-	if items[0].Name != "valid-agent" {
-		t.Errorf("expected valid-agent, got %s", items[0].Name)
-	}
-}
-
 func TestAgentsToSelectableItems_MissingFields(t *testing.T) {
-	// Test agents with missing or wrong type fields
-	agents := []interface{}{
-		map[string]interface{}{
-			// Missing name
-			"type": "worker",
-		},
-		map[string]interface{}{
-			"name": "agent-with-numeric-type",
-			"type": 123, // Wrong type
-		},
-		map[string]interface{}{
-			"name": "valid",
-			"type": "worker",
-		},
+	// Test agents with missing fields
+	agents := []client.Agent{
+		{Type: "worker"}, // Missing name
+		{Name: "agent-without-type"},
+		{Name: "valid", Type: "worker"},
 	}
 
 	items := agentsToSelectableItems(agents, []string{"worker"})
-	// First agent has no name (empty string), second has wrong type assertion
-	// Only third should be matched with type filter
+	// The agent without a type doesn't match the filter
+	if len(items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(items))
+	}
 	found := false
 	for _, item := range items {
 		if item.Name == "valid" {
@@ -212,12 +161,8 @@ func TestAgentsToSelectableItems_LongTaskDescription(t *testing.T) {
 	// Test that long task descriptions are truncated
 	longTask := strings.Repeat("a", 100) // 100 character task
 
-	agents := []interface{}{
-		map[string]interface{}{
-			"name": "agent",
-			"type": "worker",
-			"task": longTask,
-		},
+	agents := []client.Agent{
+		{Name: "agent", Type: "worker", Task: longTask},
 	}
 
 	items := agentsToSelectableItems(agents, nil)
@@ -233,18 +178,9 @@ func TestAgentsToSelectableItems_LongTaskDescription(t *testing.T) {
 
 func TestAgentsToSelectableItems_StatusFallback(t *testing.T) {
 	// Test that status is used as fallback when task is empty
-	agents := []interface{}{
-		map[string]interface{}{
-			"name":   "agent-1",
-			"type":   "worker",
-			"task":   "",
-			"status": "running",
-		},
-		map[string]interface{}{
-			"name":   "agent-2",
-			"type":   "worker",
-			"status": "completed",
-		},
+	agents := []client.Agent{
+		{Name: "agent-1", Type: "worker", Task: "", Status: "running"},
+		{Name: "agent-2", Type: "worker", Status: "completed"},
 	}
 
 	items := agentsToSelectableItems(agents, nil)
@@ -268,84 +204,17 @@ func TestReposToSelectableItems_EmptyInput(t *testing.T) {
 	}
 
 	// Test with empty slice
-	items = reposToSelectableItems([]interface{}{})
+	items = reposToSelectableItems([]client.Repo{})
 	if len(items) != 0 {
 		t.Errorf("expected 0 items for empty input, got %d", len(items))
 	}
 }
 
-func TestReposToSelectableItems_MalformedData(t *testing.T) {
-	// Test with non-map data (should be skipped)
-	repos := []interface{}{
-		"not a map",
-		123,
-		nil,
-		map[string]interface{}{
-			"name":         "valid-repo",
-			"total_agents": float64(3),
-		},
-	}
-
-	items := reposToSelectableItems(repos)
-	if len(items) != 1 {
-		t.Errorf("expected 1 valid item, got %d", len(items))
-	}
-	if items[0].Name != "valid-repo" {
-		t.Errorf("expected valid-repo, got %s", items[0].Name)
-	}
-	if items[0].Description != "3 agents" {
-		t.Errorf("expected '3 agents', got %s", items[0].Description)
-	}
-}
-
-func TestReposToSelectableItems_WrongTypeFields(t *testing.T) {
-	// Test repos with wrong type fields
-	repos := []interface{}{
-		map[string]interface{}{
-			"name":         123, // Wrong type for name
-			"total_agents": float64(5),
-		},
-		map[string]interface{}{
-			"name":         "repo-with-string-agents",
-			"total_agents": "5", // Wrong type for total_agents
-		},
-		map[string]interface{}{
-			"name":         "valid-repo",
-			"total_agents": float64(10),
-		},
-	}
-
-	items := reposToSelectableItems(repos)
-	// First repo has wrong name type (empty string), second has wrong agents type
-	// All should produce items but with potentially empty fields
-
-	// Find valid-repo
-	var validItem *SelectableItem
-	for i := range items {
-		if items[i].Name == "valid-repo" {
-			validItem = &items[i]
-			break
-		}
-	}
-
-	if validItem == nil {
-		t.Error("expected to find 'valid-repo'")
-	} else if validItem.Description != "10 agents" {
-		t.Errorf("expected '10 agents', got %s", validItem.Description)
-	}
-}
-
 func TestAgentsToSelectableItems_FilterNotMatching(t *testing.T) {
 	// Test filtering with a type that doesn't exist
-	agents := []interface{}{
-		map[string]interface{}{
-			"name": "worker-1",
-			"type": "worker",
-		},
-		map[string]interface{}{
-			"name": "supervisor",
-			"type": "supervisor",
-		},
+	agents := []client.Agent{
+		{Name: "worker-1", Type: "worker"},
+		{Name: "supervisor", Type: "supervisor"},
 	}
 
 	items := agentsToSelectableItems(agents, []string{"nonexistent-type"})
@@ -362,6 +231,8 @@ func TestFormatAgentStatusCell(t *testing.T) {
 		{"running", "running"},
 		{"completed", "completed"},
 		{"stopped", "stopped"},
+		{"crash-looping", "crash-looping"},
+		{"needs-rebase", "needs-rebase"},
 		{"idle", "idle"},
 		{"", "idle"},        // Default case
 		{"unknown", "idle"}, // Unknown status defaults to idle
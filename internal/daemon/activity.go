@@ -0,0 +1,231 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// activityCheckInterval is how often agent output logs are sampled
+const activityCheckInterval = time.Minute
+
+// Activity states reported for agents
+const (
+	activityActive = "active"
+	activityIdle   = "idle"
+	activityStuck  = "stuck"
+)
+
+// agentActivity tracks the growth of an agent's pane output log between checks
+type agentActivity struct {
+	logSize    int64     // Size of the log at the last check
+	lastOutput time.Time // When the log last grew
+	lastGrowth int64     // Bytes written between the last two checks
+
+	// nudged is set when the daemon typed a wake nudge into the pane since the
+	// last check. Output in that window is the nudge echo (or a reply to it),
+	// not progress, so it doesn't reset the idle timer.
+	nudged bool
+
+	idleNotified  bool // agent_idle already emitted for this idle period
+	stuckNotified bool // worker_stuck already emitted for this idle period
+}
+
+// activityLoop periodically samples agent output to detect idle and stuck agents
+func (d *Daemon) activityLoop() {
+	d.periodicLoop("activity", activityCheckInterval, nil, d.checkActivity)
+}
+
+// TriggerActivityCheck triggers an immediate activity check (for testing)
+func (d *Daemon) TriggerActivityCheck() {
+	d.checkActivity()
+}
+
+// checkActivity samples every agent's output log and emits agent_idle and
+// worker_stuck when output has stopped for longer than the repo's thresholds
+func (d *Daemon) checkActivity() {
+	d.logger.Debug("Checking agent activity")
+
+	now := time.Now()
+	seen := make(map[string]bool)
+
+	repos := d.state.GetAllRepos()
+	for repoName, repo := range repos {
+		cfg := activityConfigOrDefault(repo.ActivityConfig)
+		for agentName, agent := range repo.Agents {
+			// Workspace output only reflects the user's own typing
+			if agent.Type == state.AgentTypeWorkspace {
+				continue
+			}
+			seen[agentKey(repoName, agentName)] = true
+			d.updateAgentActivity(repoName, agentName, agent, cfg, now)
+		}
+	}
+
+	// Forget agents that no longer exist
+	d.activityMu.Lock()
+	for key := range d.activity {
+		if !seen[key] {
+			delete(d.activity, key)
+		}
+	}
+	d.activityMu.Unlock()
+}
+
+// updateAgentActivity records a new sample of an agent's output log.
+// Agents without an output log are skipped since their activity is unknown.
+func (d *Daemon) updateAgentActivity(repoName, agentName string, agent state.Agent, cfg state.ActivityConfig, now time.Time) {
+	info, err := os.Stat(d.paths.AgentLogFile(repoName, agentName, isWorkerLog(agent.Type)))
+	if err != nil {
+		return
+	}
+
+	key := agentKey(repoName, agentName)
+
+	d.activityMu.Lock()
+	a, exists := d.activity[key]
+	if !exists {
+		// First sample: the log's mtime is the best guess at the last output,
+		// which also carries idle time across daemon restarts
+		a = &agentActivity{logSize: info.Size(), lastOutput: info.ModTime()}
+		d.activity[key] = a
+	} else {
+		growth := info.Size() - a.logSize
+		if growth < 0 {
+			// The log was rotated or truncated
+			growth = info.Size()
+		}
+		a.logSize = info.Size()
+		a.lastGrowth = growth
+		if growth > 0 && !a.nudged {
+			a.lastOutput = now
+		}
+		a.nudged = false
+	}
+
+	idle := now.Sub(a.lastOutput)
+	idleThreshold := time.Duration(cfg.IdleMinutes) * time.Minute
+	stuckThreshold := time.Duration(cfg.StuckMinutes) * time.Minute
+
+	if idle < idleThreshold {
+		// Output resumed - allow the events to fire again next time
+		a.idleNotified = false
+		a.stuckNotified = false
+	}
+	emitIdle := idle >= idleThreshold && !a.idleNotified
+	emitStuck := agent.Type == state.AgentTypeWorker && idle >= stuckThreshold && !a.stuckNotified
+	if emitIdle {
+		a.idleNotified = true
+	}
+	if emitStuck {
+		a.stuckNotified = true
+	}
+	d.activityMu.Unlock()
+
+	if emitIdle {
+		d.logger.Info("Agent %s in repo %s is idle (no output for %s)", agentName, repoName, idle.Round(time.Second))
+		d.eventBus.Emit(events.NewAgentIdleEvent(repoName, agentName, int(idle.Seconds())))
+	}
+	if emitStuck {
+		d.logger.Warn("Worker %s in repo %s appears stuck (no output for %s)", agentName, repoName, idle.Round(time.Second))
+		d.eventBus.Emit(events.NewWorkerStuckEvent(repoName, agentName, int(idle.Minutes())))
+		if cfg.EscalateStuck {
+			d.escalateStuckWorker(repoName, agentName, agent, idle)
+		}
+	}
+}
+
+// markNudged records that a wake nudge was typed into an agent's pane
+func (d *Daemon) markNudged(repoName, agentName string) {
+	d.activityMu.Lock()
+	defer d.activityMu.Unlock()
+	if a, ok := d.activity[agentKey(repoName, agentName)]; ok {
+		a.nudged = true
+	}
+}
+
+// activitySnapshot is a point-in-time view of an agent's output activity
+type activitySnapshot struct {
+	Idle       time.Duration // Time since the agent last produced output
+	Status     string        // active, idle, or stuck
+	LastOutput time.Time     // When the agent last produced output
+	LastGrowth int64         // Bytes written between the last two checks
+}
+
+// getAgentActivity returns an agent's current activity. ok is false if the
+// agent's output hasn't been sampled yet.
+func (d *Daemon) getAgentActivity(repoName, agentName string, agentType state.AgentType, cfg state.ActivityConfig) (snap activitySnapshot, ok bool) {
+	d.activityMu.Lock()
+	a, exists := d.activity[agentKey(repoName, agentName)]
+	if exists {
+		snap.LastOutput = a.lastOutput
+		snap.LastGrowth = a.lastGrowth
+	}
+	d.activityMu.Unlock()
+
+	if !exists {
+		return snap, false
+	}
+
+	cfg = activityConfigOrDefault(cfg)
+	snap.Idle = time.Since(snap.LastOutput)
+	switch {
+	case agentType == state.AgentTypeWorker && snap.Idle >= time.Duration(cfg.StuckMinutes)*time.Minute:
+		snap.Status = activityStuck
+	case snap.Idle >= time.Duration(cfg.IdleMinutes)*time.Minute:
+		snap.Status = activityIdle
+	default:
+		snap.Status = activityActive
+	}
+	return snap, true
+}
+
+// escalateStuckWorker asks the supervisor to look at a worker that stopped
+// producing output. Repos without a supervisor only get the event.
+func (d *Daemon) escalateStuckWorker(repoName, agentName string, agent state.Agent, idle time.Duration) {
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+
+	msg := fmt.Sprintf("Worker %s has produced no output for %d minutes and may be stuck.\n"+
+		"Task: %s\n\n"+
+		"Check on it with: multiclaude attach %s\n"+
+		"If it cannot make progress, message it with guidance or restart it with: multiclaude agent restart %s",
+		agentName, int(idle.Minutes()), taskTitle(agent.Task), agentName, agentName)
+
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to escalate stuck worker %s to supervisor: %v", agentName, err)
+		return
+	}
+	d.logger.Info("Escalated stuck worker %s to supervisor in repo %s", agentName, repoName)
+}
+
+// activityConfigOrDefault returns cfg, or the defaults if it was never set
+func activityConfigOrDefault(cfg state.ActivityConfig) state.ActivityConfig {
+	if cfg.IdleMinutes == 0 {
+		return state.DefaultActivityConfig()
+	}
+	return cfg
+}
+
+// isWorkerLog returns true if an agent type's output is logged under the workers directory
+func isWorkerLog(agentType state.AgentType) bool {
+	return agentType == state.AgentTypeWorker || agentType == state.AgentTypeReview
+}
+
+// setupOutputCapture pipes an agent's tmux pane to its output log so its
+// activity can be tracked. Failure only disables tracking, so it is not fatal.
+func (d *Daemon) setupOutputCapture(repoName, tmuxSession, agentName string, agentType state.AgentType) {
+	logFile := d.paths.AgentLogFile(repoName, agentName, isWorkerLog(agentType))
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		d.logger.Warn("Failed to create output directory for %s: %v", agentName, err)
+		return
+	}
+	if err := d.tmux.StartPipePane(d.ctx, tmuxSession, agentName, logFile); err != nil {
+		d.logger.Warn("Failed to set up output capture for %s: %v", agentName, err)
+	}
+}
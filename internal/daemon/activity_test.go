@@ -0,0 +1,334 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// writeAgentLog writes an agent's output log and sets its mtime
+func writeAgentLog(t *testing.T, d *Daemon, agentName string, agentType state.AgentType, content string, mtime time.Time) string {
+	t.Helper()
+	logFile := d.paths.AgentLogFile("test-repo", agentName, isWorkerLog(agentType))
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		t.Fatalf("Failed to create log dir: %v", err)
+	}
+	if err := os.WriteFile(logFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	if err := os.Chtimes(logFile, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set log mtime: %v", err)
+	}
+	return logFile
+}
+
+// appendAgentLog appends output to an agent's log
+func appendAgentLog(t *testing.T, logFile, content string) {
+	t.Helper()
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("Failed to append to log: %v", err)
+	}
+}
+
+func TestActivityStuckWorkerEmitsAndEscalates(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	worker := state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "swift-eagle", Task: "Fix the login bug\nDetails...", CreatedAt: time.Now()}
+	supervisor := state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", CreatedAt: time.Now()}
+	if err := d.state.AddAgent("test-repo", "swift-eagle", worker); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "supervisor", supervisor); err != nil {
+		t.Fatalf("Failed to add supervisor: %v", err)
+	}
+
+	// Worker's last output was 45 minutes ago; supervisor is active
+	writeAgentLog(t, d, "swift-eagle", state.AgentTypeWorker, "working...", time.Now().Add(-45*time.Minute))
+	writeAgentLog(t, d, "supervisor", state.AgentTypeSupervisor, "hello", time.Now())
+
+	wait := captureEvents(t, d)
+	d.TriggerActivityCheck()
+	// A second check in the same idle period must not re-emit or re-escalate
+	d.TriggerActivityCheck()
+
+	captured := wait(2)
+	time.Sleep(200 * time.Millisecond)
+	captured = wait(len(captured))
+	if len(captured) != 2 {
+		t.Fatalf("captured %d events, want 2 (agent_idle, worker_stuck): %v", len(captured), captured)
+	}
+	if _, ok := findEvent(captured, events.EventAgentIdle); !ok {
+		t.Error("agent_idle not emitted")
+	}
+	stuck, ok := findEvent(captured, events.EventWorkerStuck)
+	if !ok {
+		t.Fatal("worker_stuck not emitted")
+	}
+	if stuck.AgentName != "swift-eagle" {
+		t.Errorf("worker_stuck agent = %s, want swift-eagle", stuck.AgentName)
+	}
+
+	msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+	if err != nil {
+		t.Fatalf("Failed to list supervisor messages: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("supervisor has %d messages, want 1 escalation", len(msgs))
+	}
+	if msgs[0].From != "daemon" || !strings.Contains(msgs[0].Body, "swift-eagle") || !strings.Contains(msgs[0].Body, "Fix the login bug") {
+		t.Errorf("escalation message = %+v", msgs[0])
+	}
+}
+
+func TestActivityEscalationDisabled(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.state.UpdateActivityConfig("test-repo", state.ActivityConfig{IdleMinutes: 10, StuckMinutes: 30, EscalateStuck: false}); err != nil {
+		t.Fatalf("Failed to update activity config: %v", err)
+	}
+	for name, agentType := range map[string]state.AgentType{"swift-eagle": state.AgentTypeWorker, "supervisor": state.AgentTypeSupervisor} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType, TmuxWindow: name, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+	}
+	writeAgentLog(t, d, "swift-eagle", state.AgentTypeWorker, "working...", time.Now().Add(-45*time.Minute))
+
+	d.TriggerActivityCheck()
+
+	msgs, _ := d.getMessageManager().List("test-repo", "supervisor")
+	if len(msgs) != 0 {
+		t.Errorf("supervisor has %d messages, want none with escalation disabled", len(msgs))
+	}
+}
+
+func TestActivityOutputGrowthResetsIdle(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	cfg := state.DefaultActivityConfig()
+	agent := state.Agent{Type: state.AgentTypeWorker}
+	start := time.Now().Add(-20 * time.Minute)
+	logFile := writeAgentLog(t, d, "worker-1", agent.Type, "output", start)
+
+	wait := captureEvents(t, d)
+
+	// First sample picks up the mtime: idle for 20 minutes
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now())
+	snap, ok := d.getAgentActivity("test-repo", "worker-1", agent.Type, cfg)
+	if !ok {
+		t.Fatal("activity should be tracked after the first sample")
+	}
+	if snap.Status != activityIdle {
+		t.Errorf("status = %s, want %s", snap.Status, activityIdle)
+	}
+
+	// New output makes it active again and records the growth
+	appendAgentLog(t, logFile, "more output")
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now())
+	snap, _ = d.getAgentActivity("test-repo", "worker-1", agent.Type, cfg)
+	if snap.Status != activityActive {
+		t.Errorf("status after output = %s, want %s", snap.Status, activityActive)
+	}
+	if snap.LastGrowth != int64(len("more output")) {
+		t.Errorf("LastGrowth = %d, want %d", snap.LastGrowth, len("more output"))
+	}
+
+	// Going idle again fires agent_idle a second time
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now().Add(15*time.Minute))
+
+	captured := wait(2)
+	count := 0
+	for _, e := range captured {
+		if e.Type == events.EventAgentIdle {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("agent_idle emitted %d times, want 2 (once per idle period)", count)
+	}
+}
+
+func TestActivityNudgeEchoIsNotProgress(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	cfg := state.DefaultActivityConfig()
+	agent := state.Agent{Type: state.AgentTypeWorker}
+	logFile := writeAgentLog(t, d, "worker-1", agent.Type, "output", time.Now().Add(-20*time.Minute))
+
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now())
+
+	// The daemon nudges the agent, and the nudge shows up in the pane
+	d.markNudged("test-repo", "worker-1")
+	appendAgentLog(t, logFile, "Status check: Update on your progress?")
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now())
+
+	snap, _ := d.getAgentActivity("test-repo", "worker-1", agent.Type, cfg)
+	if snap.Status != activityIdle {
+		t.Errorf("status after nudge echo = %s, want %s", snap.Status, activityIdle)
+	}
+
+	// Output in a later window counts again
+	appendAgentLog(t, logFile, "real work")
+	d.updateAgentActivity("test-repo", "worker-1", agent, cfg, time.Now())
+	snap, _ = d.getAgentActivity("test-repo", "worker-1", agent.Type, cfg)
+	if snap.Status != activityActive {
+		t.Errorf("status after real output = %s, want %s", snap.Status, activityActive)
+	}
+}
+
+func TestActivityThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		agentType  state.AgentType
+		cfg        state.ActivityConfig
+		idleFor    time.Duration
+		wantStatus string
+	}{
+		{"active worker", state.AgentTypeWorker, state.DefaultActivityConfig(), 5 * time.Minute, activityActive},
+		{"idle worker", state.AgentTypeWorker, state.DefaultActivityConfig(), 15 * time.Minute, activityIdle},
+		{"stuck worker", state.AgentTypeWorker, state.DefaultActivityConfig(), 45 * time.Minute, activityStuck},
+		{"supervisor is never stuck", state.AgentTypeSupervisor, state.DefaultActivityConfig(), 45 * time.Minute, activityIdle},
+		{"custom idle threshold", state.AgentTypeWorker, state.ActivityConfig{IdleMinutes: 2, StuckMinutes: 5}, 3 * time.Minute, activityIdle},
+		{"custom stuck threshold", state.AgentTypeWorker, state.ActivityConfig{IdleMinutes: 2, StuckMinutes: 5}, 6 * time.Minute, activityStuck},
+		{"unset config uses defaults", state.AgentTypeWorker, state.ActivityConfig{}, 15 * time.Minute, activityIdle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := setupTestDaemon(t)
+			defer cleanup()
+
+			writeAgentLog(t, d, "agent", tt.agentType, "output", time.Now().Add(-tt.idleFor))
+			cfg := activityConfigOrDefault(tt.cfg)
+			d.updateAgentActivity("test-repo", "agent", state.Agent{Type: tt.agentType}, cfg, time.Now())
+
+			snap, ok := d.getAgentActivity("test-repo", "agent", tt.agentType, tt.cfg)
+			if !ok {
+				t.Fatal("activity not tracked")
+			}
+			if snap.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", snap.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestActivityCheckSkipsWorkspaceAndForgetsRemovedAgents(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	for name, agentType := range map[string]state.AgentType{"workspace": state.AgentTypeWorkspace, "worker-1": state.AgentTypeWorker} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType, TmuxWindow: name, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add agent: %v", err)
+		}
+		writeAgentLog(t, d, name, agentType, "output", time.Now())
+	}
+
+	d.TriggerActivityCheck()
+
+	if _, ok := d.getAgentActivity("test-repo", "workspace", state.AgentTypeWorkspace, state.ActivityConfig{}); ok {
+		t.Error("workspace activity should not be tracked")
+	}
+	if _, ok := d.getAgentActivity("test-repo", "worker-1", state.AgentTypeWorker, state.ActivityConfig{}); !ok {
+		t.Fatal("worker activity should be tracked")
+	}
+
+	if err := d.state.RemoveAgent("test-repo", "worker-1"); err != nil {
+		t.Fatalf("Failed to remove agent: %v", err)
+	}
+	d.TriggerActivityCheck()
+
+	if _, ok := d.getAgentActivity("test-repo", "worker-1", state.AgentTypeWorker, state.ActivityConfig{}); ok {
+		t.Error("removed agent's activity should be forgotten")
+	}
+}
+
+func TestListAgentsIncludesActivity(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.state.AddAgent("test-repo", "worker-1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	writeAgentLog(t, d, "worker-1", state.AgentTypeWorker, "output", time.Now().Add(-15*time.Minute))
+	d.TriggerActivityCheck()
+
+	resp := d.handleRequest(socket.Request{
+		Command: "list_agents",
+		Args:    map[string]interface{}{"repo": "test-repo", "rich": true},
+	})
+	if !resp.Success {
+		t.Fatalf("list_agents failed: %s", resp.Error)
+	}
+
+	agents, ok := resp.Data.([]map[string]interface{})
+	if !ok || len(agents) != 1 {
+		t.Fatalf("unexpected list_agents data: %#v", resp.Data)
+	}
+	if agents[0]["activity"] != activityIdle {
+		t.Errorf("activity = %v, want %s", agents[0]["activity"], activityIdle)
+	}
+	if idle, _ := agents[0]["idle_seconds"].(int); idle < 14*60 {
+		t.Errorf("idle_seconds = %v, want about 900", agents[0]["idle_seconds"])
+	}
+}
+
+func TestRepoConfigActivitySettings(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["idle_minutes"] != 10 || data["stuck_minutes"] != 30 || data["escalate_stuck"] != true {
+		t.Errorf("default activity config = %v", data)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":           "test-repo",
+		"idle_minutes":   float64(5),
+		"stuck_minutes":  float64(20),
+		"escalate_stuck": false,
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	cfg, _ := d.state.GetActivityConfig("test-repo")
+	want := state.ActivityConfig{IdleMinutes: 5, StuckMinutes: 20, EscalateStuck: false}
+	if cfg != want {
+		t.Errorf("activity config = %+v, want %+v", cfg, want)
+	}
+
+	invalid := []map[string]interface{}{
+		{"name": "test-repo", "idle_minutes": float64(0)},
+		{"name": "test-repo", "stuck_minutes": float64(2)}, // less than idle_minutes
+	}
+	for _, args := range invalid {
+		resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: args})
+		if resp.Success {
+			t.Errorf("update_repo_config(%v) should fail", args)
+		}
+	}
+}
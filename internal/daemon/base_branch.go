@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// repoDefaultBranch returns the branch workers in a repo are based on by
+// default. Repos added before the default branch was recorded have it
+// detected from origin and stored; "" means it couldn't be determined.
+func (d *Daemon) repoDefaultBranch(repoName string) string {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return ""
+	}
+	if repo.DefaultBranch != "" {
+		return repo.DefaultBranch
+	}
+
+	branch, err := worktree.NewManager(d.paths.RepoDir(repoName)).GetDefaultBranch("origin")
+	if err != nil {
+		d.logger.Debug("Could not detect default branch for %s: %v", repoName, err)
+		return ""
+	}
+	if err := d.state.SetDefaultBranch(repoName, branch); err != nil {
+		d.logger.Warn("Failed to record default branch for %s: %v", repoName, err)
+	}
+	return branch
+}
+
+// workerStartPoint returns the ref a worker based on base starts from and the
+// base it ends up with. An empty base means the repo's default branch; if
+// that isn't known either, the worker starts from HEAD with no base.
+func (d *Daemon) workerStartPoint(repoName string, wt *worktree.Manager, base string) (string, string, error) {
+	if base != "" {
+		ref, err := wt.ResolveStartPoint("origin", base)
+		return ref, base, err
+	}
+
+	base = d.repoDefaultBranch(repoName)
+	if base == "" {
+		return "HEAD", "", nil
+	}
+	ref, err := wt.ResolveStartPoint("origin", base)
+	if err != nil {
+		// Repos without the branch (e.g. no remote yet) start from HEAD
+		return "HEAD", base, nil
+	}
+	return ref, base, nil
+}
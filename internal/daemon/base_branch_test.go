@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestRepoConfigDefaultBranch(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":           "test-repo",
+		"default_branch": "develop",
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	repo, _ := d.state.GetRepo("test-repo")
+	if repo.DefaultBranch != "develop" {
+		t.Errorf("DefaultBranch = %q, want develop", repo.DefaultBranch)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	if branch := resp.Data.(map[string]interface{})["default_branch"]; branch != "develop" {
+		t.Errorf("get_repo_config default_branch = %v, want develop", branch)
+	}
+
+	for _, branch := range []string{"", "-x", "bad..name"} {
+		resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+			"name":           "test-repo",
+			"default_branch": branch,
+		}})
+		if resp.Success {
+			t.Errorf("update_repo_config(default_branch=%q) should fail", branch)
+		}
+	}
+}
+
+func TestStartQueuedTaskWithBase(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	cmd := exec.Command("git", "branch", "release/2.3")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create branch: %v\n%s", err, output)
+	}
+	if err := d.state.SetDefaultBranch("test-repo", "main"); err != nil {
+		t.Fatalf("Failed to set default branch: %v", err)
+	}
+
+	enqueueTestTasks(t, d,
+		state.QueuedTask{ID: "task-1", Task: "Backport fix", Name: "release-worker", Base: "release/2.3"},
+		state.QueuedTask{ID: "task-2", Task: "New feature", Name: "main-worker"},
+	)
+	d.startQueuedTasks("test-repo")
+
+	wt := worktree.NewManager(repoDir)
+	tests := []struct {
+		worker string
+		base   string
+	}{
+		{"release-worker", "release/2.3"},
+		{"main-worker", "main"},
+	}
+	for _, tt := range tests {
+		agent, exists := d.state.GetAgent("test-repo", tt.worker)
+		if !exists {
+			t.Fatalf("%s should have been started", tt.worker)
+		}
+		if agent.BaseBranch != tt.base {
+			t.Errorf("%s BaseBranch = %q, want %q", tt.worker, agent.BaseBranch, tt.base)
+		}
+		if base := wt.BranchBase("multiclaude/" + tt.worker); base != tt.base {
+			t.Errorf("%s recorded branch base = %q, want %q", tt.worker, base, tt.base)
+		}
+
+		prompt, err := os.ReadFile(filepath.Join(d.paths.Root, "prompts", tt.worker+".md"))
+		if err != nil {
+			t.Fatalf("Failed to read prompt: %v", err)
+		}
+		if !strings.Contains(string(prompt), "gh pr create --base "+tt.base) {
+			t.Errorf("%s prompt should target base %s", tt.worker, tt.base)
+		}
+	}
+}
+
+func TestEnqueueTaskRejectsInvalidBase(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+		"repo": "test-repo",
+		"task": "Do something",
+		"base": "bad..name",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "invalid base") {
+		t.Errorf("enqueue_task should reject an invalid base, got success=%v error=%q", resp.Success, resp.Error)
+	}
+}
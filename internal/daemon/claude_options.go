@@ -0,0 +1,206 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// claudeOptionsAgentTypes are the agent types whose Claude options can be configured
+var claudeOptionsAgentTypes = []state.AgentType{
+	state.AgentTypeSupervisor,
+	state.AgentTypeWorker,
+	state.AgentTypeMergeQueue,
+	state.AgentTypeWorkspace,
+	state.AgentTypeReview,
+	state.AgentTypeGenericPersistent,
+}
+
+// claudeOptionsData converts Claude options to their socket representation
+func claudeOptionsData(opts state.ClaudeOptions) map[string]interface{} {
+	args := opts.Args
+	if args == nil {
+		args = []string{}
+	}
+	env := opts.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	return map[string]interface{}{
+		"model": opts.Model,
+		"args":  args,
+		"env":   env,
+	}
+}
+
+// repoClaudeOptionsData returns a repo's configured Claude options keyed by
+// agent type. Types without options are left out.
+func repoClaudeOptionsData(repo *state.Repository) map[string]interface{} {
+	data := make(map[string]interface{}, len(repo.ClaudeOptions))
+	for agentType, opts := range repo.ClaudeOptions {
+		data[string(agentType)] = claudeOptionsData(opts)
+	}
+	return data
+}
+
+// applyClaudeOptionsArgs replaces the fields present in a socket request's
+// Claude options with the given values. args may be a list or a
+// space-separated string; env may be an object or a comma-separated list of
+// NAME=value pairs.
+func applyClaudeOptionsArgs(opts state.ClaudeOptions, args map[string]interface{}) (state.ClaudeOptions, error) {
+	if model, ok := args["model"].(string); ok {
+		opts.Model = strings.TrimSpace(model)
+	}
+
+	switch extra := args["args"].(type) {
+	case []interface{}:
+		opts.Args = nil
+		for _, v := range extra {
+			arg, ok := v.(string)
+			if !ok {
+				return opts, fmt.Errorf("args must be a list of strings")
+			}
+			opts.Args = append(opts.Args, arg)
+		}
+	case string:
+		opts.Args = strings.Fields(extra)
+	case nil:
+	default:
+		return opts, fmt.Errorf("args must be a list of strings")
+	}
+
+	switch env := args["env"].(type) {
+	case map[string]interface{}:
+		opts.Env = nil
+		for name, v := range env {
+			value, ok := v.(string)
+			if !ok {
+				return opts, fmt.Errorf("env value for %s must be a string", name)
+			}
+			if opts.Env == nil {
+				opts.Env = make(map[string]string, len(env))
+			}
+			opts.Env[name] = value
+		}
+	case string:
+		parsed, err := parseEnvList(env)
+		if err != nil {
+			return opts, err
+		}
+		opts.Env = parsed
+	case nil:
+	default:
+		return opts, fmt.Errorf("env must be an object of strings")
+	}
+
+	return opts, opts.Validate()
+}
+
+// parseEnvList parses "NAME=value,NAME2=value2" into a map
+func parseEnvList(s string) (map[string]string, error) {
+	var env map[string]string
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid env entry %q: expected NAME=value", pair)
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[strings.TrimSpace(name)] = value
+	}
+	return env, nil
+}
+
+// claudeOptionsArg parses the optional "claude" argument of a socket request
+func claudeOptionsArg(reqArgs map[string]interface{}) (state.ClaudeOptions, error) {
+	raw, ok := reqArgs["claude"]
+	if !ok || raw == nil {
+		return state.ClaudeOptions{}, nil
+	}
+	args, ok := raw.(map[string]interface{})
+	if !ok {
+		return state.ClaudeOptions{}, fmt.Errorf("claude must be an object with model, args and env")
+	}
+	opts, err := applyClaudeOptionsArgs(state.ClaudeOptions{}, args)
+	if err != nil {
+		return state.ClaudeOptions{}, fmt.Errorf("claude options: %w", err)
+	}
+	return opts, nil
+}
+
+// updateClaudeOptions applies the claude_options argument of update_repo_config.
+// A null entry clears an agent type's options.
+func (d *Daemon) updateClaudeOptions(repoName string, arg interface{}) error {
+	options, ok := arg.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("claude_options must be an object keyed by agent type")
+	}
+
+	typeNames := make([]string, 0, len(options))
+	for typeName := range options {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	updatedOptions := make(map[state.AgentType]state.ClaudeOptions, len(typeNames))
+	for _, typeName := range typeNames {
+		agentType := state.AgentType(typeName)
+		if !isClaudeOptionsAgentType(agentType) {
+			return fmt.Errorf("invalid agent type for claude options: %q", typeName)
+		}
+
+		var updated state.ClaudeOptions
+		if raw := options[typeName]; raw != nil {
+			args, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("claude options for %s must be an object", typeName)
+			}
+			current, err := d.state.GetClaudeOptions(repoName, agentType)
+			if err != nil {
+				return err
+			}
+			updated, err = applyClaudeOptionsArgs(current, args)
+			if err != nil {
+				return fmt.Errorf("claude options for %s: %w", typeName, err)
+			}
+		}
+
+		updatedOptions[agentType] = updated
+	}
+
+	// Nothing is saved unless every entry is valid
+	if err := d.state.UpdateAllClaudeOptions(repoName, updatedOptions); err != nil {
+		return err
+	}
+	for _, typeName := range typeNames {
+		updated := updatedOptions[state.AgentType(typeName)]
+		d.logger.Info("Updated claude options for %s in repo %s: model=%q, args=%v, env=%d vars", typeName, repoName, updated.Model, updated.Args, len(updated.Env))
+	}
+	return nil
+}
+
+// isClaudeOptionsAgentType returns true if agentType has configurable Claude options
+func isClaudeOptionsAgentType(agentType state.AgentType) bool {
+	for _, t := range claudeOptionsAgentTypes {
+		if t == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+// launchClaudeOptions returns the options to start an agent with: the repo's
+// defaults for its type with the per-spawn override applied on top
+func (d *Daemon) launchClaudeOptions(repoName string, agentType state.AgentType, override state.ClaudeOptions) (state.ClaudeOptions, error) {
+	defaults, err := d.state.GetClaudeOptions(repoName, agentType)
+	if err != nil {
+		return state.ClaudeOptions{}, err
+	}
+	return defaults.Merge(override), nil
+}
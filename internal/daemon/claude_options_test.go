@@ -0,0 +1,271 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// recordingTerminal is a claude.TerminalRunner that records the commands it is sent
+type recordingTerminal struct {
+	commands []string
+}
+
+func (r *recordingTerminal) SendKeys(ctx context.Context, session, window, text string) error {
+	r.commands = append(r.commands, text)
+	return nil
+}
+
+func (r *recordingTerminal) SendKeysLiteral(ctx context.Context, session, window, text string) error {
+	return nil
+}
+
+func (r *recordingTerminal) SendEnter(ctx context.Context, session, window string) error {
+	return nil
+}
+
+func (r *recordingTerminal) SendKeysLiteralWithEnter(ctx context.Context, session, window, text string) error {
+	return nil
+}
+
+func (r *recordingTerminal) GetPanePID(ctx context.Context, session, window string) (int, error) {
+	return 4242, nil
+}
+
+func (r *recordingTerminal) StartPipePane(ctx context.Context, session, window, outputFile string) error {
+	return nil
+}
+
+func (r *recordingTerminal) StopPipePane(ctx context.Context, session, window string) error {
+	return nil
+}
+
+func TestRepoConfigClaudeOptions(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo",
+		"claude_options": map[string]interface{}{
+			"review": map[string]interface{}{
+				"model": "opus",
+				"args":  []interface{}{"--verbose"},
+				"env":   map[string]interface{}{"HTTPS_PROXY": "http://proxy:3128"},
+			},
+			"worker": map[string]interface{}{
+				"model": "sonnet",
+				"args":  "--verbose --max-turns 50",
+				"env":   "GOFLAGS=-mod=mod,CGO_ENABLED=0",
+			},
+		},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	worker, _ := d.state.GetClaudeOptions("test-repo", state.AgentTypeWorker)
+	want := state.ClaudeOptions{
+		Model: "sonnet",
+		Args:  []string{"--verbose", "--max-turns", "50"},
+		Env:   map[string]string{"GOFLAGS": "-mod=mod", "CGO_ENABLED": "0"},
+	}
+	if !reflect.DeepEqual(worker, want) {
+		t.Errorf("worker options = %+v, want %+v", worker, want)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	options, ok := resp.Data.(map[string]interface{})["claude_options"].(map[string]interface{})
+	if !ok || len(options) != 2 {
+		t.Fatalf("get_repo_config claude_options = %v", resp.Data.(map[string]interface{})["claude_options"])
+	}
+	if review := options["review"].(map[string]interface{}); review["model"] != "opus" {
+		t.Errorf("review options = %v", review)
+	}
+
+	// Fields that weren't provided keep their previous values; null clears a type
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo",
+		"claude_options": map[string]interface{}{
+			"worker": map[string]interface{}{"model": "haiku"},
+			"review": nil,
+		},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	worker, _ = d.state.GetClaudeOptions("test-repo", state.AgentTypeWorker)
+	if worker.Model != "haiku" || len(worker.Args) != 3 || len(worker.Env) != 2 {
+		t.Errorf("worker options after partial update = %+v", worker)
+	}
+	if review, _ := d.state.GetClaudeOptions("test-repo", state.AgentTypeReview); !review.IsZero() {
+		t.Errorf("review options should be cleared, got %+v", review)
+	}
+
+	invalid := []map[string]interface{}{
+		{"reviewer": map[string]interface{}{"model": "opus"}},
+		{"worker": "opus"},
+		{"worker": map[string]interface{}{"env": "GOFLAGS"}},
+		{"worker": map[string]interface{}{"env": map[string]interface{}{"NOT-VALID": "x"}}},
+		{"worker": map[string]interface{}{"args": []interface{}{float64(1)}}},
+	}
+	for _, options := range invalid {
+		resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+			"name":           "test-repo",
+			"claude_options": options,
+		}})
+		if resp.Success {
+			t.Errorf("update_repo_config(%v) should fail", options)
+		}
+	}
+
+	// An invalid entry rejects the whole update, including valid entries
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo",
+		"claude_options": map[string]interface{}{
+			"review": map[string]interface{}{"model": "opus"},
+			"worker": map[string]interface{}{"env": "GOFLAGS"},
+		},
+	}})
+	if resp.Success {
+		t.Error("update_repo_config with invalid worker options should fail")
+	}
+	if review, _ := d.state.GetClaudeOptions("test-repo", state.AgentTypeReview); !review.IsZero() {
+		t.Errorf("review options = %+v, want them unchanged after a rejected update", review)
+	}
+}
+
+func TestAddAgentPersistsClaudeOptions(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+		"repo":          "test-repo",
+		"agent":         "worker-1",
+		"type":          "worker",
+		"worktree_path": "/tmp/wt",
+		"tmux_window":   "worker-1",
+		"claude": map[string]interface{}{
+			"model": "opus",
+			"args":  []interface{}{"--verbose"},
+			"env":   map[string]interface{}{"GOFLAGS": "-mod=mod"},
+		},
+	}})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+
+	agent, _ := d.state.GetAgent("test-repo", "worker-1")
+	if agent.Claude == nil || agent.Claude.Model != "opus" || agent.Claude.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("agent.Claude = %+v", agent.Claude)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+		"repo":          "test-repo",
+		"agent":         "worker-2",
+		"type":          "worker",
+		"worktree_path": "/tmp/wt2",
+		"tmux_window":   "worker-2",
+		"claude":        map[string]interface{}{"env": map[string]interface{}{"1BAD": "x"}},
+	}})
+	if resp.Success {
+		t.Error("add_agent with an invalid env var name should fail")
+	}
+}
+
+func TestStartQueuedTaskClaudeOptions(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	if err := d.state.UpdateClaudeOptions("test-repo", state.AgentTypeWorker, state.ClaudeOptions{
+		Model: "sonnet",
+		Env:   map[string]string{"GOFLAGS": "-mod=mod"},
+	}); err != nil {
+		t.Fatalf("Failed to set claude options: %v", err)
+	}
+	enqueueTestTasks(t, d,
+		state.QueuedTask{ID: "task-1", Task: "Use defaults", Name: "default-worker"},
+		state.QueuedTask{ID: "task-2", Task: "Override model", Name: "opus-worker", Claude: &state.ClaudeOptions{
+			Model: "opus",
+			Args:  []string{"--verbose"},
+		}},
+	)
+
+	d.startQueuedTasks("test-repo")
+
+	defaults, _ := d.state.GetAgent("test-repo", "default-worker")
+	if defaults.Claude == nil || defaults.Claude.Model != "sonnet" || defaults.Claude.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("default-worker Claude options = %+v", defaults.Claude)
+	}
+
+	override, _ := d.state.GetAgent("test-repo", "opus-worker")
+	want := &state.ClaudeOptions{
+		Model: "opus",
+		Args:  []string{"--verbose"},
+		Env:   map[string]string{"GOFLAGS": "-mod=mod"},
+	}
+	if !reflect.DeepEqual(override.Claude, want) {
+		t.Errorf("opus-worker Claude options = %+v, want %+v", override.Claude, want)
+	}
+}
+
+func TestRestartAgentUsesClaudeOptions(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	terminal := &recordingTerminal{}
+	d.claudeRunner = claude.NewRunner(claude.WithTerminal(terminal), claude.WithStartupDelay(0))
+
+	promptDir := filepath.Join(d.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		t.Fatalf("Failed to create prompt dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(promptDir, "supervisor.md"), []byte("prompt"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+
+	agent := state.Agent{
+		Type:         state.AgentTypeSupervisor,
+		WorktreePath: t.TempDir(),
+		TmuxWindow:   "supervisor",
+		SessionID:    "session-123",
+		CreatedAt:    time.Now(),
+		Claude: &state.ClaudeOptions{
+			Model: "opus",
+			Args:  []string{"--verbose"},
+			Env:   map[string]string{"GOFLAGS": "-mod=mod"},
+		},
+	}
+	if err := d.state.AddAgent("test-repo", "supervisor", agent); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	repo, _ := d.state.GetRepo("test-repo")
+	if err := d.restartAgent("test-repo", "supervisor", agent, repo); err != nil {
+		t.Fatalf("restartAgent() failed: %v", err)
+	}
+
+	if len(terminal.commands) != 1 {
+		t.Fatalf("terminal got %d commands, want 1: %v", len(terminal.commands), terminal.commands)
+	}
+	cmd := terminal.commands[0]
+	for _, want := range []string{"env GOFLAGS=-mod=mod ", "--session-id session-123", "--model opus", " --verbose"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("restart command %q should contain %q", cmd, want)
+		}
+	}
+}
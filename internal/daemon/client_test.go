@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/client"
+)
+
+// TestClientRoundTrip checks the typed client against the daemon's handlers,
+// so a renamed argument or response field breaks here rather than in the CLI
+func TestClientRoundTrip(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.server.Start(); err != nil {
+		t.Fatalf("Failed to start socket server: %v", err)
+	}
+	defer d.server.Stop()
+	go d.server.Serve()
+
+	c := client.New(d.paths.DaemonSock)
+
+	result, err := c.Ping()
+	if err != nil {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	if result.ProtocolVersion != client.ProtocolVersion {
+		t.Errorf("Ping() protocol version = %d, want %d", result.ProtocolVersion, client.ProtocolVersion)
+	}
+
+	if err := c.AddRepo(client.AddRepoRequest{
+		Name:              "test-repo",
+		GithubURL:         "https://github.com/test/repo",
+		TmuxSession:       "mc-test-repo",
+		MergeQueueEnabled: client.Bool(false),
+		DefaultBranch:     "develop",
+	}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := c.AddAgent(client.AddAgentRequest{
+		Repo:         "test-repo",
+		Agent:        "worker-1",
+		Type:         client.AgentTypeWorker,
+		WorktreePath: "/tmp/wt",
+		TmuxWindow:   "worker-1",
+		Task:         "Fix the bug",
+		BaseBranch:   "release",
+		Claude:       &client.ClaudeOptions{Model: "opus"},
+	}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if !status.Running || status.Repos != 1 || status.Agents != 1 {
+		t.Errorf("Status() = %+v, want 1 repo and 1 agent", status)
+	}
+
+	names, err := c.ListRepos()
+	if err != nil || !reflect.DeepEqual(names, []string{"test-repo"}) {
+		t.Errorf("ListRepos() = %v, %v, want [test-repo]", names, err)
+	}
+	repos, err := c.ListReposDetailed()
+	if err != nil || len(repos) != 1 || repos[0].TotalAgents != 1 || repos[0].WorkerCount != 1 {
+		t.Errorf("ListReposDetailed() = %+v, %v, want test-repo with one worker", repos, err)
+	}
+
+	agents, err := c.ListAgentsDetailed("test-repo")
+	if err != nil {
+		t.Fatalf("ListAgentsDetailed() failed: %v", err)
+	}
+	if len(agents) != 1 {
+		t.Fatalf("ListAgentsDetailed() returned %d agents, want 1", len(agents))
+	}
+	agent := agents[0]
+	if agent.Name != "worker-1" || agent.Task != "Fix the bug" || agent.BaseBranch != "release" ||
+		agent.Claude == nil || agent.Claude.Model != "opus" || agent.CreatedAt.IsZero() || agent.Status == "" {
+		t.Errorf("ListAgentsDetailed() = %+v, want worker-1 with its task, base branch, model and status", agent)
+	}
+
+	if err := c.UpdateRepoConfig(client.UpdateRepoConfigRequest{
+		Name:         "test-repo",
+		MaxWorkers:   client.Int(3),
+		WakePolicies: map[string]client.WakePolicyUpdate{"worker": {IntervalMinutes: client.Int(20), OnlyWhen: []string{"idle"}}},
+		ClaudeOptions: map[string]*client.ClaudeOptionsUpdate{
+			"worker": {Model: client.String("sonnet"), Args: []string{"--verbose"}},
+		},
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+	config, err := c.GetRepoConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetRepoConfig() failed: %v", err)
+	}
+	if config.MergeQueueEnabled || config.MaxWorkers != 3 || config.DefaultBranch != "develop" {
+		t.Errorf("GetRepoConfig() = %+v, want merge queue off, 3 workers, default branch develop", config)
+	}
+	if policy := config.WakePolicies["worker"]; policy.IntervalMinutes != 20 || !reflect.DeepEqual(policy.OnlyWhen, []string{"idle"}) {
+		t.Errorf("worker wake policy = %+v, want every 20m only when idle", policy)
+	}
+	if opts := config.ClaudeOptions["worker"]; opts.Model != "sonnet" || !reflect.DeepEqual(opts.Args, []string{"--verbose"}) {
+		t.Errorf("worker Claude options = %+v, want sonnet with --verbose", opts)
+	}
+
+	// Clearing uses empty values and leaves other fields alone
+	if err := c.UpdateRepoConfig(client.UpdateRepoConfigRequest{
+		Name:          "test-repo",
+		WakePolicies:  map[string]client.WakePolicyUpdate{"worker": {OnlyWhen: []string{}}},
+		ClaudeOptions: map[string]*client.ClaudeOptionsUpdate{"worker": {Args: []string{}}},
+	}); err != nil {
+		t.Fatalf("UpdateRepoConfig() failed: %v", err)
+	}
+	config, err = c.GetRepoConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetRepoConfig() failed: %v", err)
+	}
+	if policy := config.WakePolicies["worker"]; policy.IntervalMinutes != 20 || len(policy.OnlyWhen) != 0 {
+		t.Errorf("worker wake policy = %+v, want every 20m with no conditions", policy)
+	}
+	if opts := config.ClaudeOptions["worker"]; opts.Model != "sonnet" || len(opts.Args) != 0 {
+		t.Errorf("worker Claude options = %+v, want sonnet without args", opts)
+	}
+
+	if _, err := c.GetCurrentRepo(); err == nil {
+		t.Error("GetCurrentRepo() should fail when no repo is set")
+	}
+	if err := c.SetCurrentRepo("test-repo"); err != nil {
+		t.Fatalf("SetCurrentRepo() failed: %v", err)
+	}
+	if current, err := c.GetCurrentRepo(); err != nil || current != "test-repo" {
+		t.Errorf("GetCurrentRepo() = %q, %v, want test-repo", current, err)
+	}
+
+	queue, err := c.ListTaskQueue("test-repo")
+	if err != nil || queue.MaxWorkers != 3 || queue.ActiveWorkers != 1 || len(queue.Tasks) != 0 {
+		t.Errorf("ListTaskQueue() = %+v, %v, want an empty queue with 1/3 workers", queue, err)
+	}
+
+	evts, err := c.QueryEvents(client.EventQuery{Repo: "test-repo", Types: []string{"agent_started"}})
+	if err != nil {
+		t.Fatalf("QueryEvents() failed: %v", err)
+	}
+	if len(evts) != 1 || evts[0].AgentName != "worker-1" || evts[0].Timestamp.IsZero() {
+		t.Errorf("QueryEvents() = %+v, want agent_started for worker-1", evts)
+	}
+
+	if err := c.RemoveAgent("test-repo", "worker-1"); err != nil {
+		t.Fatalf("RemoveAgent() failed: %v", err)
+	}
+	if err := c.RemoveRepo("test-repo"); err != nil {
+		t.Fatalf("RemoveRepo() failed: %v", err)
+	}
+
+	// Rejected requests carry the daemon's message
+	_, err = c.ListAgents("test-repo")
+	var reqErr *client.RequestError
+	if !errors.As(err, &reqErr) || reqErr.Message == "" {
+		t.Errorf("ListAgents() on a removed repo = %v, want a RequestError", err)
+	}
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/google/uuid"
+)
+
+// recordRebaseConflict marks a worker whose worktree refresh hit conflicts
+// as needing a rebase, and tells it which files conflict and which upstream
+// commits caused it. A worker is told again only if the conflicting files
+// change.
+func (d *Daemon) recordRebaseConflict(repoName, agentName string, agent state.Agent, remote, baseBranch string, result worktree.RefreshResult) {
+	d.logger.Warn("Worktree refresh for %s/%s has conflicts in: %v", repoName, agentName, result.ConflictFiles)
+
+	if agent.NeedsRebase && reflect.DeepEqual(agent.RebaseConflicts, result.ConflictFiles) {
+		return
+	}
+	if err := d.state.SetAgentNeedsRebase(repoName, agentName, result.ConflictFiles); err != nil {
+		d.logger.Error("Failed to mark %s/%s as needing a rebase: %v", repoName, agentName, err)
+		return
+	}
+
+	upstream := fmt.Sprintf("%s/%s", remote, baseBranch)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your branch %s can't be synced with %s automatically: rebasing it conflicts.\n\n", result.Branch, upstream)
+	fmt.Fprintf(&b, "Conflicting files:\n%s\n\n", formatFileList(result.ConflictFiles))
+	if len(result.ConflictCommits) > 0 {
+		fmt.Fprintf(&b, "Upstream commits that changed them:\n%s\n\n", formatFileList(result.ConflictCommits))
+	}
+	fmt.Fprintf(&b, "To resolve:\n"+
+		"  git fetch %s\n"+
+		"  git rebase %s\n"+
+		"  (fix the conflicts, git add the files, git rebase --continue)\n"+
+		"  git push --force-with-lease origin %s\n\n"+
+		"Your worktree was left as it was.", remote, upstream, result.Branch)
+
+	_, err := d.getMessageManager().SendWithOptions(repoName, "daemon", agentName, b.String(), messages.SendOptions{
+		Subject: "Rebase needed: conflicts with " + upstream,
+	})
+	if err != nil {
+		d.logger.Error("Failed to send rebase conflict message to %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// clearNeedsRebase clears a worker's needs-rebase flag once its branch is in
+// sync with its base again
+func (d *Daemon) clearNeedsRebase(repoName, agentName string) {
+	if err := d.state.SetAgentNeedsRebase(repoName, agentName, nil); err != nil {
+		d.logger.Error("Failed to clear needs-rebase for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	d.logger.Info("Worker %s/%s no longer needs a rebase", repoName, agentName)
+}
+
+// queueConflictResolver queues a worker to rebase a completed worker's branch
+// onto its base and push it back to the same branch, so the completed
+// worker's PR can merge. It starts once a worker slot is free.
+func (d *Daemon) queueConflictResolver(repoName, agentName string, agent state.Agent, branch string) {
+	base := agent.BaseBranch
+	if base == "" {
+		base = d.repoDefaultBranch(repoName)
+	}
+
+	task := state.QueuedTask{
+		ID: fmt.Sprintf("task-%s", uuid.New().String()[:8]),
+		Task: fmt.Sprintf("Resolve the rebase conflicts on branch %s, left by worker %s after it completed. "+
+			"Rebase the branch onto the latest %s, resolve the conflicts in %s keeping the intent of both sides, "+
+			"make sure it builds and the tests pass, then push with: git push --force-with-lease origin %s",
+			branch, agentName, base, strings.Join(agent.RebaseConflicts, ", "), branch),
+		Branch:     branch,
+		Base:       agent.BaseBranch,
+		PushTo:     branch,
+		EnqueuedAt: time.Now(),
+		Claude:     agent.Claude,
+	}
+	if _, err := d.state.EnqueueTask(repoName, task); err != nil {
+		d.logger.Error("Failed to queue conflict resolution for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	d.logger.Info("Queued task %s to resolve rebase conflicts on %s for completed worker %s/%s", task.ID, branch, repoName, agentName)
+
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	msg := fmt.Sprintf("Worker %s completed, but its branch %s conflicts with %s in:\n%s\n\n"+
+		"Queued task %s to rebase it and resolve the conflicts. Cancel it with: multiclaude work queue cancel %s",
+		agentName, branch, base, formatFileList(agent.RebaseConflicts), task.ID, task.ID)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to notify supervisor about conflict resolution for %s: %v", agentName, err)
+	}
+}
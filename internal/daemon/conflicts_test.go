@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestRefreshConflictsNeedRebase(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	run(repoDir, "remote", "add", "origin", repoDir)
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     "https://github.com/test/repo",
+		TmuxSession:   "mc-test-repo",
+		DefaultBranch: "main",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	addOverlapWorker(t, d, repoDir, "calm-otter", map[string]string{"README.md": "# Otter\n"})
+	addOverlapWorker(t, d, repoDir, "tidy-heron", map[string]string{"README.md": "# Heron\n"})
+	run(repoDir, "commit", "-q", "--allow-empty", "-m", "Unrelated change")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Upstream\n"), 0644); err != nil {
+		t.Fatalf("Failed to write README.md: %v", err)
+	}
+	run(repoDir, "commit", "-q", "-am", "Rewrite README")
+
+	d.TriggerWorktreeRefresh()
+
+	for _, name := range []string{"calm-otter", "tidy-heron"} {
+		agent, _ := d.state.GetAgent("test-repo", name)
+		if !agent.NeedsRebase || !reflect.DeepEqual(agent.RebaseConflicts, []string{"README.md"}) {
+			t.Errorf("%s = %+v, want it to need a rebase because of README.md", name, agent)
+		}
+	}
+
+	msgMgr := d.getMessageManager()
+	msgs, _ := msgMgr.List("test-repo", "calm-otter")
+	if len(msgs) != 1 {
+		t.Fatalf("calm-otter has %d message(s), want 1", len(msgs))
+	}
+	if !strings.Contains(msgs[0].Subject, "origin/main") {
+		t.Errorf("message subject = %q, want the upstream branch", msgs[0].Subject)
+	}
+	if body := msgs[0].Body; !strings.Contains(body, "README.md") || !strings.Contains(body, "Rewrite README") || strings.Contains(body, "Unrelated change") {
+		t.Errorf("message body = %q, want the conflicting file and only the commit that caused it", body)
+	}
+
+	// The same conflict isn't reported twice
+	d.TriggerWorktreeRefresh()
+	if msgs, _ := msgMgr.List("test-repo", "calm-otter"); len(msgs) != 1 {
+		t.Errorf("calm-otter has %d message(s) after a second refresh, want 1", len(msgs))
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("list_agents failed: %s", resp.Error)
+	}
+	for _, detail := range resp.Data.([]map[string]interface{}) {
+		if detail["name"] == "calm-otter" && detail["needs_rebase"] != true {
+			t.Errorf("list_agents calm-otter = %v, want needs_rebase", detail)
+		}
+	}
+
+	// Rebasing the branch by hand clears the flag
+	heronPath := d.paths.AgentWorktree("test-repo", "tidy-heron")
+	run(heronPath, "reset", "-q", "--hard", "origin/main")
+	d.TriggerWorktreeRefresh()
+	if agent, _ := d.state.GetAgent("test-repo", "tidy-heron"); agent.NeedsRebase || agent.RebaseConflicts != nil {
+		t.Errorf("tidy-heron = %+v after rebasing, want no rebase needed", agent)
+	}
+
+	// Completed workers whose branch still conflicts are handed to a
+	// conflict-resolution worker when the repo allows it
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "resolve_conflicts": true}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	otter, _ := d.state.GetAgent("test-repo", "calm-otter")
+	otter.ReadyForCleanup = true
+	if err := d.state.UpdateAgent("test-repo", "calm-otter", otter); err != nil {
+		t.Fatalf("Failed to update agent: %v", err)
+	}
+	d.cleanupDeadAgents(map[string][]string{"test-repo": {"calm-otter"}})
+
+	queue, err := d.state.GetTaskQueue("test-repo")
+	if err != nil || len(queue) != 1 {
+		t.Fatalf("task queue = %+v, %v, want one conflict-resolution task", queue, err)
+	}
+	if task := queue[0]; task.Branch != "work/calm-otter" || task.PushTo != "work/calm-otter" || !strings.Contains(task.Task, "README.md") {
+		t.Errorf("queued task = %+v, want one rebasing work/calm-otter", task)
+	}
+	if msgs, _ := msgMgr.List("test-repo", "supervisor"); len(msgs) != 1 || !strings.Contains(msgs[0].Body, queue[0].ID) {
+		t.Errorf("supervisor messages = %+v, want one about the queued task", msgs)
+	}
+}
+
+func TestCompletedConflictsNotResolvedByDefault(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.state.AddAgent("test-repo", "calm-otter", state.Agent{
+		Type:            state.AgentTypeWorker,
+		TmuxWindow:      "calm-otter",
+		ReadyForCleanup: true,
+		NeedsRebase:     true,
+		RebaseConflicts: []string{"README.md"},
+	}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+	d.cleanupDeadAgents(map[string][]string{"test-repo": {"calm-otter"}})
+
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 0 {
+		t.Errorf("task queue = %+v, want no conflict-resolution task unless resolve_conflicts is set", queue)
+	}
+}
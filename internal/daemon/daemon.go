@@ -16,14 +16,18 @@ import (
 	"github.com/dlorenc/multiclaude/internal/agents"
 	"github.com/dlorenc/multiclaude/internal/diagnostics"
 	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/forge"
+	"github.com/dlorenc/multiclaude/internal/github"
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/logging"
 	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
 	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/client"
 	"github.com/dlorenc/multiclaude/pkg/config"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
@@ -32,18 +36,65 @@ import (
 type Daemon struct {
 	paths        *config.Paths
 	state        *state.State
+	stateLock    *state.FileLock
 	tmux         *tmux.Client
 	logger       *logging.Logger
 	server       *socket.Server
 	pidFile      *PIDFile
 	claudeRunner *claude.Runner
 	eventBus     *events.Bus
+	journal      *events.Journal
+	snapshots    *snapshot.Manager
+	github       *github.Client
+
+	// Dead PIDs already reported, so agent_failed fires once per crash
+	eventMu    sync.Mutex
+	failedPIDs map[string]int // repo/agent -> PID already reported as failed
+
+	// Per-agent output activity sampled by the activity loop
+	activityMu sync.Mutex
+	activity   map[string]*agentActivity // repo/agent -> activity
+
+	// File overlaps between active workers, found by the overlap loop
+	overlapMu sync.Mutex
+	overlaps  map[string][]workerOverlap // repo/agent -> overlaps with other workers
+
+	// When each repo's upstream was last checked by the fork/upstream sync loop
+	upstreamMu      sync.Mutex
+	upstreamChecked map[string]time.Time // repo -> last check
+
+	// Serializes message delivery between the router loop and the message watcher
+	routeMu sync.Mutex
+
+	// Agents with messages backing off after a failed delivery, for the
+	// message watcher to retry when the backoff ends
+	messageRetries chan messageRetry
+
+	// Tells the message watcher to watch the messages directory afresh, after
+	// a snapshot restore replaced it
+	messageRewatch chan struct{}
+
+	// Serializes starting queued tasks so a worker slot is never filled twice
+	queueMu sync.Mutex
+
+	// Serializes worktree refreshes between the refresh loop and upstream syncs
+	refreshMu sync.Mutex
+
+	// Signals the state watch loop that state was saved, and the repos it
+	// last saw, for broadcasting state_changed events to subscribers
+	stateSaved      chan struct{}
+	stateSnapshotMu sync.Mutex
+	stateSnapshot   map[string]repoSnapshot
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
+// stateLockTimeout is how long New waits for another process, such as an
+// exiting daemon, to release the state file's lock
+const stateLockTimeout = 5 * time.Second
+
 // New creates a new daemon instance
 func New(paths *config.Paths) (*Daemon, error) {
 	// Ensure directories exist
@@ -57,33 +108,62 @@ func New(paths *config.Paths) (*Daemon, error) {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
 
-	// Load or create state
-	st, err := state.Load(paths.StateFile)
+	// Hold the state file's lock while running, so other processes change
+	// state through the daemon instead of overwriting its saves
+	stateLock, err := state.LockFile(paths.StateFile, stateLockTimeout)
 	if err != nil {
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+
+	// Load state, bringing the file up to the current schema first
+	st, migration, err := state.LoadMigrated(paths.StateFile)
+	if err != nil {
+		stateLock.Unlock()
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
+	if migration.Migrated() {
+		logger.Info("Migrated state from schema version %d to %d (backup: %s)", migration.From, migration.To, migration.BackupPath)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	tmuxClient := tmux.NewClient()
 
-	// Initialize event bus with current hook configuration
+	// Initialize event bus with current hook configuration.
+	// Every emitted event is also recorded in the event journal.
 	eventBus := events.NewBus(st.GetHookConfig())
+	journal := events.NewJournal(paths.EventsJournal())
+	eventBus.SetJournal(journal)
 
 	d := &Daemon{
-		paths:        paths,
-		state:        st,
-		tmux:         tmuxClient,
-		logger:       logger,
-		pidFile:      NewPIDFile(paths.DaemonPID),
-		claudeRunner: claude.NewRunner(claude.WithTerminal(tmuxClient)),
-		eventBus:     eventBus,
-		ctx:          ctx,
-		cancel:       cancel,
-	}
+		paths:           paths,
+		state:           st,
+		stateLock:       stateLock,
+		tmux:            tmuxClient,
+		logger:          logger,
+		pidFile:         NewPIDFile(paths.DaemonPID),
+		claudeRunner:    claude.NewRunner(claude.WithTerminal(tmuxClient)),
+		eventBus:        eventBus,
+		journal:         journal,
+		snapshots:       snapshot.NewManager(paths.SnapshotsDir()),
+		github:          github.NewClient("gh"),
+		failedPIDs:      make(map[string]int),
+		activity:        make(map[string]*agentActivity),
+		upstreamChecked: make(map[string]time.Time),
+		stateSaved:      make(chan struct{}, 1),
+		messageRetries:  make(chan messageRetry, messageRetryBuffer),
+		messageRewatch:  make(chan struct{}, 1),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	// Watch state saves so subscribers see state changes
+	d.stateSnapshot = snapshotRepos(st.GetAllRepos())
+	st.SetOnSave(d.notifyStateSaved)
 
 	// Create socket server
 	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.handleRequest))
+	d.server.HandleStream("subscribe", d.handleSubscribe)
 
 	return d, nil
 }
@@ -114,13 +194,19 @@ func (d *Daemon) Start() error {
 	d.restoreTrackedRepos()
 
 	// Start core loops after restore completes
-	d.wg.Add(6)
+	d.wg.Add(12)
 	go d.healthCheckLoop()
 	go d.messageRouterLoop()
+	go d.messageWatchLoop()
 	go d.wakeLoop()
+	go d.activityLoop()
 	go d.serverLoop()
 	go d.worktreeRefreshLoop()
 	go d.forkUpstreamSyncLoop()
+	go d.stateWatchLoop()
+	go d.snapshotLoop()
+	go d.overlapLoop()
+	go d.prTrackingLoop()
 
 	return nil
 }
@@ -195,6 +281,9 @@ func (d *Daemon) Stop() error {
 	if err := d.state.Save(); err != nil {
 		d.logger.Error("Failed to save state: %v", err)
 	}
+	if err := d.stateLock.Unlock(); err != nil {
+		d.logger.Error("Failed to unlock state: %v", err)
+	}
 
 	// Remove PID file
 	if err := d.pidFile.Remove(); err != nil {
@@ -340,18 +429,19 @@ func (d *Daemon) checkAgentHealth() {
 			// Check if process is alive (if we have a PID)
 			if agent.PID > 0 {
 				if !isProcessAlive(agent.PID) {
-					d.logger.Warn("Agent %s process (PID %d) not running", agentName, agent.PID)
+					exitReason := fmt.Sprintf("process %d exited", agent.PID)
+					if !agent.CrashLooping {
+						d.logger.Warn("Agent %s process (PID %d) not running", agentName, agent.PID)
+					}
+					d.reportAgentFailure(repoName, agentName, agent, exitReason)
 
 					// For persistent agents (supervisor, merge-queue, workspace, generic-persistent), attempt auto-restart
-					if agent.Type == state.AgentTypeSupervisor || agent.Type == state.AgentTypeMergeQueue || agent.Type == state.AgentTypeWorkspace || agent.Type == state.AgentTypeGenericPersistent {
-						d.logger.Info("Attempting to auto-restart agent %s", agentName)
-						if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-							d.logger.Error("Failed to restart agent %s: %v", agentName, err)
-						} else {
-							d.logger.Info("Successfully restarted agent %s", agentName)
-						}
-					}
 					// For transient agents (workers, review), don't auto-restart - they complete and clean up
+					if isAutoRestartAgent(agent.Type) {
+						d.autoRestartAgent(repoName, agentName, agent, repo, exitReason)
+					}
+				} else {
+					d.clearStableRestarts(repoName, agentName, agent)
 				}
 			}
 		}
@@ -364,6 +454,9 @@ func (d *Daemon) checkAgentHealth() {
 
 	// Clean up orphaned worktrees
 	d.cleanupOrphanedWorktrees()
+
+	// Fill worker slots freed by completed or cleaned up workers
+	d.startAllQueuedTasks()
 }
 
 // messageRouterLoop watches for new messages and delivers them
@@ -375,9 +468,6 @@ func (d *Daemon) messageRouterLoop() {
 func (d *Daemon) routeMessages() {
 	d.logger.Debug("Routing messages")
 
-	// Get messages manager
-	msgMgr := d.getMessageManager()
-
 	// Get a snapshot of repos to avoid concurrent map access
 	repos := d.state.GetAllRepos()
 
@@ -385,47 +475,127 @@ func (d *Daemon) routeMessages() {
 	for repoName, repo := range repos {
 		// Check each agent for messages
 		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
+			d.deliverPendingMessages(repoName, repo.TmuxSession, agentName, agent)
+		}
+	}
+}
 
-			// Get unread messages (pending or delivered but not yet read)
-			unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
-			if err != nil {
-				d.logger.Error("Failed to list messages for %s/%s: %v", repoName, agentName, err)
-				continue
-			}
+// deliverPendingMessages delivers an agent's pending messages to its tmux window.
+// Both the router loop and the message watcher deliver through here, so
+// deliveries are serialized to keep a message from being typed twice.
+func (d *Daemon) deliverPendingMessages(repoName, tmuxSession, agentName string, agent state.Agent) {
+	// Skip workspace agent - it should only receive direct user input
+	if agent.Type == state.AgentTypeWorkspace {
+		return
+	}
 
-			// Deliver each pending message
-			for _, msg := range unreadMsgs {
-				if msg.Status != messages.StatusPending {
-					// Already delivered, skip
-					continue
-				}
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
 
-				// Format message for delivery
-				messageText := fmt.Sprintf("📨 Message from %s: %s", msg.From, msg.Body)
+	msgMgr := d.getMessageManager()
 
-				// Send via tmux using atomic method to avoid race conditions
-				// where Enter might be lost between separate exec calls (issue #63)
-				if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, messageText); err != nil {
-					d.logger.Error("Failed to deliver message %s to %s/%s: %v", msg.ID, repoName, agentName, err)
-					continue
-				}
+	// Get unread messages (pending or delivered but not yet read)
+	unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
+	if err != nil {
+		d.logger.Error("Failed to list messages for %s/%s: %v", repoName, agentName, err)
+		return
+	}
 
-				// Mark as delivered
-				if err := msgMgr.UpdateStatus(repoName, agentName, msg.ID, messages.StatusDelivered); err != nil {
-					d.logger.Error("Failed to update message %s status: %v", msg.ID, err)
-					continue
-				}
+	// Messages still backing off are retried when the earliest backoff ends
+	var nextRetry time.Time
+	defer func() {
+		if !nextRetry.IsZero() {
+			d.scheduleMessageRetry(repoName, agentName, nextRetry)
+		}
+	}()
+	retryAt := func(at time.Time) {
+		if nextRetry.IsZero() || at.Before(nextRetry) {
+			nextRetry = at
+		}
+	}
 
-				d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoName, agentName)
+	// Deliver each pending message, urgent ones first
+	now := time.Now()
+	messages.SortForDelivery(unreadMsgs)
+	for _, msg := range unreadMsgs {
+		if msg.Status != messages.StatusPending {
+			// Already delivered, skip
+			continue
+		}
+
+		if msg.Expired(now) {
+			if err := msgMgr.DeadLetter(repoName, agentName, msg, "expired before delivery"); err != nil {
+				d.logger.Error("Failed to dead-letter expired message %s: %v", msg.ID, err)
+			} else {
+				d.logger.Warn("Message %s from %s to %s/%s expired before delivery, moved to dead letters", msg.ID, msg.From, repoName, agentName)
 			}
+			continue
+		}
+
+		// Still backing off after a failed attempt
+		if !msg.DeliveryDue(now) {
+			retryAt(*msg.NextAttemptAt)
+			continue
+		}
+
+		messageText := formatMessageDelivery(msg)
+
+		// Send via tmux using atomic method to avoid race conditions
+		// where Enter might be lost between separate exec calls (issue #63)
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, tmuxSession, agent.TmuxWindow, messageText); err != nil {
+			d.logger.Error("Failed to deliver message %s to %s/%s (attempt %d): %v", msg.ID, repoName, agentName, msg.Attempts+1, err)
+			dead, recordErr := msgMgr.RecordDeliveryFailure(repoName, agentName, msg.ID, err)
+			if recordErr != nil {
+				d.logger.Error("Failed to record delivery failure for message %s: %v", msg.ID, recordErr)
+			} else if dead {
+				d.logger.Warn("Message %s to %s/%s moved to dead letters after %d failed attempts", msg.ID, repoName, agentName, messages.MaxDeliveryAttempts)
+			} else {
+				retryAt(now.Add(messages.RetryBackoff(msg.Attempts + 1)))
+			}
+			continue
+		}
+
+		// Mark as delivered
+		if err := msgMgr.UpdateStatus(repoName, agentName, msg.ID, messages.StatusDelivered); err != nil {
+			d.logger.Error("Failed to update message %s status: %v", msg.ID, err)
+			continue
+		}
+
+		d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoName, agentName)
+		messageType := "direct"
+		if msg.BroadcastID != "" {
+			messageType = "broadcast"
 		}
+		d.eventBus.Emit(events.NewMessageSentEvent(repoName, msg.From, agentName, messageType, msg.Body))
 	}
 }
 
+// formatMessageDelivery formats a message for typing into an agent's pane.
+// The message ID and thread context let the agent reply in the same thread.
+func formatMessageDelivery(msg *messages.Message) string {
+	prefix := "📨 Message"
+	if msg.Priority == messages.PriorityUrgent {
+		prefix = "🚨 URGENT message"
+	}
+
+	tags := []string{msg.ID}
+	if msg.ReplyTo != "" {
+		tags = append(tags, "reply to "+msg.ReplyTo)
+	}
+	if thread := msg.Thread(); thread != msg.ID && thread != msg.ReplyTo {
+		tags = append(tags, "thread "+thread)
+	}
+	if msg.BroadcastID != "" {
+		tags = append(tags, "broadcast")
+	}
+
+	text := fmt.Sprintf("%s from %s [%s]: ", prefix, msg.From, strings.Join(tags, ", "))
+	if msg.Subject != "" {
+		text += msg.Subject + " — "
+	}
+	return text + msg.Body
+}
+
 // getMessageManager returns a message manager instance
 func (d *Daemon) getMessageManager() *messages.Manager {
 	return messages.NewManager(d.paths.MessagesDir)
@@ -433,57 +603,43 @@ func (d *Daemon) getMessageManager() *messages.Manager {
 
 // wakeLoop periodically wakes agents with status checks
 func (d *Daemon) wakeLoop() {
-	d.periodicLoop("wake", 2*time.Minute, nil, d.wakeAgents)
+	d.periodicLoop("wake", wakeCheckInterval, nil, d.wakeAgents)
 }
 
-// wakeAgents sends periodic nudges to agents
+// wakeAgents sends status-check nudges to agents according to their own
+// wake policy or their repo's policy for the agent type
 func (d *Daemon) wakeAgents() {
 	d.logger.Debug("Waking agents")
 
 	now := time.Now()
+	prs := d.newPRActivityReader()
 
 	// Get a snapshot of repos to avoid concurrent map access
 	repos := d.state.GetAllRepos()
 	for repoName, repo := range repos {
 		for agentName, agent := range repo.Agents {
-			// Skip workspace agent - it should only receive direct user input
-			if agent.Type == state.AgentTypeWorkspace {
-				continue
-			}
-
-			// Skip if nudged recently (within last 2 minutes)
-			if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < 2*time.Minute {
+			policy := repo.WakePolicyForAgent(agent)
+			reason, ok := d.shouldWake(repoName, agentName, agent, repo, policy, now, prs)
+			if !ok {
 				continue
 			}
 
-			// Send wake message based on agent type
-			var message string
-			switch agent.Type {
-			case state.AgentTypeSupervisor:
-				message = "Status check: Review worker progress and check merge queue."
-			case state.AgentTypeMergeQueue:
-				message = "Status check: Review open PRs and check CI status."
-			case state.AgentTypeWorker:
-				message = "Status check: Update on your progress?"
-			case state.AgentTypeReview:
-				message = "Status check: Update on your review progress?"
-			case state.AgentTypeGenericPersistent:
-				message = "Status check: Update on your progress?"
-			}
-
 			// Send message using atomic method to avoid race conditions (issue #63)
-			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, message); err != nil {
+			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, policy.Message); err != nil {
 				d.logger.Error("Failed to send wake message to agent %s: %v", agentName, err)
 				continue
 			}
 
+			// The nudge echo isn't progress, so don't let it reset the idle timer
+			d.markNudged(repoName, agentName)
+
 			// Update last nudge time
 			agent.LastNudge = now
 			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
 				d.logger.Error("Failed to update agent %s last nudge: %v", agentName, err)
 			}
 
-			d.logger.Debug("Woke agent %s in repo %s", agentName, repoName)
+			d.logger.Debug("Woke agent %s in repo %s (%s)", agentName, repoName, reason)
 		}
 	}
 }
@@ -517,8 +673,11 @@ func (d *Daemon) worktreeRefreshLoop() {
 	}
 }
 
-// refreshWorktrees syncs worker worktrees that are behind main
+// refreshWorktrees syncs worker worktrees that are behind their base branch
 func (d *Daemon) refreshWorktrees() {
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+
 	d.logger.Debug("Checking worker worktrees for refresh")
 
 	repos := d.state.GetAllRepos()
@@ -539,10 +698,13 @@ func (d *Daemon) refreshWorktrees() {
 			continue
 		}
 
-		mainBranch, err := wt.GetDefaultBranch(remote)
-		if err != nil {
-			d.logger.Debug("Could not get default branch for %s: %v", repoName, err)
-			continue
+		defaultBranch := repo.DefaultBranch
+		if defaultBranch == "" {
+			defaultBranch, err = wt.GetDefaultBranch(remote)
+			if err != nil {
+				d.logger.Debug("Could not get default branch for %s: %v", repoName, err)
+				continue
+			}
 		}
 
 		// Fetch from remote to have latest state
@@ -568,8 +730,14 @@ func (d *Daemon) refreshWorktrees() {
 				continue
 			}
 
+			// Workers are kept in sync with the branch they were based on
+			baseBranch := agent.BaseBranch
+			if baseBranch == "" {
+				baseBranch = defaultBranch
+			}
+
 			// Check worktree state
-			wtState, err := worktree.GetWorktreeState(agent.WorktreePath, remote, mainBranch)
+			wtState, err := worktree.GetWorktreeState(agent.WorktreePath, remote, baseBranch)
 			if err != nil {
 				d.logger.Debug("Could not get worktree state for %s/%s: %v", repoName, agentName, err)
 				continue
@@ -577,17 +745,20 @@ func (d *Daemon) refreshWorktrees() {
 
 			// Skip if can't refresh (detached HEAD, mid-rebase, mid-merge, on main, or up to date)
 			if !wtState.CanRefresh {
+				if wtState.UpToDate && agent.NeedsRebase {
+					d.clearNeedsRebase(repoName, agentName)
+				}
 				d.logger.Debug("Skipping refresh for %s/%s: %s", repoName, agentName, wtState.RefreshReason)
 				continue
 			}
 
 			// Refresh the worktree
 			d.logger.Info("Refreshing worktree for %s/%s (%d commits behind)", repoName, agentName, wtState.CommitsBehind)
-			result := worktree.RefreshWorktree(agent.WorktreePath, remote, mainBranch)
+			result := worktree.RefreshWorktree(agent.WorktreePath, remote, baseBranch)
 
 			if result.Error != nil {
 				if result.HasConflicts {
-					d.logger.Warn("Worktree refresh for %s/%s has conflicts in: %v", repoName, agentName, result.ConflictFiles)
+					d.recordRebaseConflict(repoName, agentName, agent, remote, baseBranch, result)
 				} else {
 					d.logger.Error("Failed to refresh worktree for %s/%s: %v", repoName, agentName, result.Error)
 				}
@@ -595,10 +766,13 @@ func (d *Daemon) refreshWorktrees() {
 				d.logger.Debug("Worktree refresh for %s/%s skipped: %s", repoName, agentName, result.SkipReason)
 			} else {
 				d.logger.Info("Refreshed worktree for %s/%s: rebased %d commits", repoName, agentName, result.CommitsRebased)
+				if agent.NeedsRebase {
+					d.clearNeedsRebase(repoName, agentName)
+				}
 
 				// Notify the agent that their worktree was refreshed
 				msgMgr := d.getMessageManager()
-				msg := fmt.Sprintf("Your worktree has been automatically synced with main (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", result.CommitsRebased)
+				msg := fmt.Sprintf("Your worktree has been automatically synced with %s (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", baseBranch, result.CommitsRebased)
 				if _, err := msgMgr.Send(repoName, "daemon", agentName, msg); err != nil {
 					d.logger.Debug("Could not send refresh notification to %s/%s: %v", repoName, agentName, err)
 				}
@@ -618,7 +792,7 @@ func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 
 	switch req.Command {
 	case "ping":
-		return socket.Response{Success: true, Data: "pong"}
+		return d.handlePing(req)
 
 	case "status":
 		return d.handleStatus(req)
@@ -685,12 +859,42 @@ func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 		go d.routeMessages()
 		return socket.Response{Success: true, Data: "Message routing triggered"}
 
+	case "resolve_recipients":
+		return d.handleResolveRecipients(req)
+
 	case "task_history":
 		return d.handleTaskHistory(req)
 
+	case "events_query":
+		return d.handleEventsQuery(req)
+
 	case "spawn_agent":
 		return d.handleSpawnAgent(req)
 
+	case "create_worker":
+		return d.handleCreateWorker(req)
+
+	case "enqueue_task":
+		return d.handleEnqueueTask(req)
+
+	case "list_task_queue":
+		return d.handleListTaskQueue(req)
+
+	case "move_queued_task":
+		return d.handleMoveQueuedTask(req)
+
+	case "cancel_queued_task":
+		return d.handleCancelQueuedTask(req)
+
+	case "record_upstream_sync":
+		return d.handleRecordUpstreamSync(req)
+
+	case "sync_upstream":
+		return d.handleSyncUpstream(req)
+
+	case "restore_snapshot":
+		return d.handleRestoreSnapshot(req)
+
 	default:
 		return socket.Response{
 			Success: false,
@@ -699,6 +903,22 @@ func (d *Daemon) handleRequest(req socket.Request) socket.Response {
 	}
 }
 
+// handlePing answers a liveness check. Clients that send their
+// protocol_version get the daemon's protocol versions back for the handshake;
+// a bare ping still answers "pong".
+func (d *Daemon) handlePing(req socket.Request) socket.Response {
+	if _, ok := req.Args["protocol_version"]; !ok {
+		return socket.Response{Success: true, Data: "pong"}
+	}
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"protocol_version":     client.ProtocolVersion,
+			"min_protocol_version": client.MinProtocolVersion,
+		},
+	}
+}
+
 // handleStatus returns daemon status
 func (d *Daemon) handleStatus(req socket.Request) socket.Response {
 	repos := d.state.ListRepos()
@@ -825,11 +1045,15 @@ func (d *Daemon) handleAddRepo(req socket.Request) socket.Response {
 		}
 	}
 
+	// Branch workers start from by default, detected by the CLI at init
+	defaultBranch, _ := req.Args["default_branch"].(string)
+
 	repo := &state.Repository{
 		GithubURL:        githubURL,
 		TmuxSession:      tmuxSession,
 		Agents:           make(map[string]state.Agent),
 		MergeQueueConfig: mqConfig,
+		DefaultBranch:    defaultBranch,
 		UpstreamConfig:   upstreamConfig,
 	}
 
@@ -853,6 +1077,15 @@ func (d *Daemon) handleRemoveRepo(req socket.Request) socket.Response {
 		return errResp
 	}
 
+	if _, exists := d.state.GetRepo(name); !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
+	}
+
+	// Keep the repo's agents and task history restorable
+	if snap, err := d.takeSnapshot(snapshot.ReasonRemoveRepo); err == nil {
+		d.logger.Info("Snapshot %s taken before removing repository %s", snap.ID, name)
+	}
+
 	if err := d.state.RemoveRepo(name); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
@@ -916,11 +1149,26 @@ func (d *Daemon) handleAddAgent(req socket.Request) socket.Response {
 		agent.Task = task
 	}
 
+	// Optional base branch for workers; empty means the repo's default branch
+	if base, ok := req.Args["base_branch"].(string); ok {
+		agent.BaseBranch = base
+	}
+
+	// Optional Claude options the agent was started with, used on restart
+	claudeOpts, err := claudeOptionsArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if !claudeOpts.IsZero() {
+		agent.Claude = &claudeOpts
+	}
+
 	if err := d.state.AddAgent(repoName, agentName, agent); err != nil {
 		return socket.Response{Success: false, Error: err.Error()}
 	}
 
 	d.logger.Info("Added agent %s to repo %s", agentName, repoName)
+	d.emitAgentStarted(repoName, agentName, agent)
 	return socket.Response{Success: true}
 }
 
@@ -941,6 +1189,10 @@ func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
 	}
 
 	d.logger.Info("Removed agent %s from repo %s", agentName, repoName)
+
+	// A removed worker frees a slot for the next queued task
+	d.startQueuedTasksAsync(repoName)
+
 	return socket.Response{Success: true}
 }
 
@@ -978,6 +1230,25 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 			"task":          agent.Task,
 			"created_at":    agent.CreatedAt,
 		}
+		if agent.RestartCount > 0 || agent.CrashLooping {
+			detail["restart_count"] = agent.RestartCount
+			detail["last_restart"] = agent.LastRestart
+			detail["last_exit_reason"] = agent.LastExitReason
+			detail["crash_looping"] = agent.CrashLooping
+		}
+		if agent.Claude != nil {
+			detail["claude"] = claudeOptionsData(*agent.Claude)
+		}
+		if agent.Definition != "" {
+			detail["definition"] = agent.Definition
+		}
+		if agent.BaseBranch != "" {
+			detail["base_branch"] = agent.BaseBranch
+		}
+		if agent.NeedsRebase {
+			detail["needs_rebase"] = true
+			detail["rebase_conflicts"] = agent.RebaseConflicts
+		}
 
 		// Add rich status information if requested
 		if rich {
@@ -985,10 +1256,14 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 			status := "unknown"
 			if agent.ReadyForCleanup {
 				status = "completed"
+			} else if agent.CrashLooping {
+				status = "crash-looping"
 			} else if repoExists {
 				// Check if window exists (means agent is running)
 				hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-				if err == nil && hasWindow {
+				if err == nil && hasWindow && agent.NeedsRebase {
+					status = "needs-rebase"
+				} else if err == nil && hasWindow {
 					status = "running"
 				} else {
 					status = "stopped"
@@ -1016,6 +1291,29 @@ func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
 			}
 			detail["messages_total"] = len(allMsgs)
 			detail["messages_pending"] = pendingCount
+
+			// Add output activity if it has been sampled
+			if repoExists {
+				if snap, ok := d.getAgentActivity(repoName, agentName, agent.Type, repo.ActivityConfig); ok {
+					detail["activity"] = snap.Status
+					detail["idle_seconds"] = int(snap.Idle.Seconds())
+					detail["last_output_at"] = snap.LastOutput
+					detail["output_growth_bytes"] = snap.LastGrowth
+				}
+			}
+
+			// Add file overlaps with other workers
+			if overlaps := d.getWorkerOverlaps(repoName, agentName); len(overlaps) > 0 {
+				overlapDetails := make([]map[string]interface{}, 0, len(overlaps))
+				for _, overlap := range overlaps {
+					overlapDetails = append(overlapDetails, map[string]interface{}{
+						"worker":    overlap.Worker,
+						"files":     overlap.Files,
+						"conflicts": overlap.Conflicts,
+					})
+				}
+				detail["overlaps"] = overlapDetails
+			}
 		}
 
 		agentDetails = append(agentDetails, detail)
@@ -1057,6 +1355,7 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 	}
 
 	d.logger.Info("Agent %s/%s marked as ready for cleanup", repoName, agentName)
+	d.eventBus.Emit(events.NewTaskCompleteEvent(repoName, agentName, agent.Task, agent.Summary, agent.FailureReason))
 
 	// Notify supervisor and merge-queue that worker or review agent completed
 	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
@@ -1115,12 +1414,26 @@ func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
 	}
 
 	force, _ := req.Args["force"].(bool)
+	reset, _ := req.Args["reset"].(bool)
 
 	agent, exists := d.state.GetAgent(repoName, agentName)
 	if !exists {
 		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)}
 	}
 
+	// A crash-looping agent is only restarted once someone clears its history.
+	// The reset sticks even if this restart fails, so the health check's
+	// automatic restarts pick the agent up again.
+	if agent.CrashLooping && !reset {
+		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is crash-looping (%d restarts, last exit: %s) - fix the cause, then use --reset to clear its restart history and restart it", agentName, agent.RestartCount, agent.LastExitReason)}
+	}
+	if reset {
+		if err := d.state.ResetAgentRestarts(repoName, agentName); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("failed to reset restart history: %v", err)}
+		}
+		d.logger.Info("Reset restart history of agent %s", agentName)
+	}
+
 	// Check if agent is marked for cleanup (completed)
 	if agent.ReadyForCleanup {
 		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
@@ -1274,11 +1587,34 @@ func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
 		mqConfig = state.DefaultMergeQueueConfig()
 	}
 
+	psConfig := repo.PRShepherdConfig
+	if psConfig.TrackMode == "" {
+		psConfig = state.DefaultPRShepherdConfig()
+	}
+
+	activityConfig := activityConfigOrDefault(repo.ActivityConfig)
+
+	syncStrategy := ""
+	if repo.UpstreamConfig != nil {
+		syncStrategy = repo.UpstreamConfig.SyncStrategy
+	}
+
 	return socket.Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"mq_enabled":    mqConfig.Enabled,
-			"mq_track_mode": string(mqConfig.TrackMode),
+			"mq_enabled":        mqConfig.Enabled,
+			"mq_track_mode":     string(mqConfig.TrackMode),
+			"ps_enabled":        psConfig.Enabled,
+			"ps_track_mode":     string(psConfig.TrackMode),
+			"idle_minutes":      activityConfig.IdleMinutes,
+			"stuck_minutes":     activityConfig.StuckMinutes,
+			"escalate_stuck":    activityConfig.EscalateStuck,
+			"wake_policies":     wakePoliciesData(repo),
+			"claude_options":    repoClaudeOptionsData(repo),
+			"max_workers":       repo.MaxWorkers,
+			"default_branch":    d.repoDefaultBranch(name),
+			"resolve_conflicts": repo.ResolveConflicts,
+			"sync_strategy":     syncStrategy,
 		},
 	}
 }
@@ -1323,6 +1659,125 @@ func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
 		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s", name, currentMQConfig.Enabled, currentMQConfig.TrackMode)
 	}
 
+	// Update PR shepherd config with provided values
+	currentPSConfig, err := d.state.GetPRShepherdConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	psUpdated := false
+	if psEnabled, ok := req.Args["ps_enabled"].(bool); ok {
+		currentPSConfig.Enabled = psEnabled
+		psUpdated = true
+	}
+	if psTrackMode, ok := req.Args["ps_track_mode"].(string); ok {
+		trackMode, err := state.ParseTrackMode(psTrackMode)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		currentPSConfig.TrackMode = trackMode
+		psUpdated = true
+	}
+
+	if psUpdated {
+		if err := d.state.UpdatePRShepherdConfig(name, currentPSConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated PR shepherd config for repo %s: enabled=%v, track=%s", name, currentPSConfig.Enabled, currentPSConfig.TrackMode)
+	}
+
+	// Update activity thresholds with provided values
+	currentActivityConfig, err := d.state.GetActivityConfig(name)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	activityUpdated := false
+	if idle, ok := req.Args["idle_minutes"].(float64); ok {
+		if idle < 1 {
+			return socket.Response{Success: false, Error: "idle_minutes must be at least 1"}
+		}
+		currentActivityConfig.IdleMinutes = int(idle)
+		activityUpdated = true
+	}
+	if stuck, ok := req.Args["stuck_minutes"].(float64); ok {
+		if stuck < 1 {
+			return socket.Response{Success: false, Error: "stuck_minutes must be at least 1"}
+		}
+		currentActivityConfig.StuckMinutes = int(stuck)
+		activityUpdated = true
+	}
+	if escalate, ok := req.Args["escalate_stuck"].(bool); ok {
+		currentActivityConfig.EscalateStuck = escalate
+		activityUpdated = true
+	}
+
+	if activityUpdated {
+		if currentActivityConfig.StuckMinutes < currentActivityConfig.IdleMinutes {
+			return socket.Response{Success: false, Error: fmt.Sprintf("stuck_minutes (%d) must not be less than idle_minutes (%d)", currentActivityConfig.StuckMinutes, currentActivityConfig.IdleMinutes)}
+		}
+		if err := d.state.UpdateActivityConfig(name, currentActivityConfig); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated activity config for repo %s: idle=%dm, stuck=%dm, escalate=%v", name, currentActivityConfig.IdleMinutes, currentActivityConfig.StuckMinutes, currentActivityConfig.EscalateStuck)
+	}
+
+	if wakePolicies, ok := req.Args["wake_policies"]; ok {
+		if err := d.updateWakePolicies(name, wakePolicies); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+	}
+
+	if claudeOptions, ok := req.Args["claude_options"]; ok {
+		if err := d.updateClaudeOptions(name, claudeOptions); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+	}
+
+	if defaultBranch, ok := req.Args["default_branch"].(string); ok {
+		if err := worktree.ValidateBranchName(defaultBranch); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if err := d.state.SetDefaultBranch(name, defaultBranch); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated default branch for repo %s: %s", name, defaultBranch)
+	}
+
+	if maxWorkers, ok := req.Args["max_workers"].(float64); ok {
+		if err := d.state.SetMaxWorkers(name, int(maxWorkers)); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated max workers for repo %s: %d", name, int(maxWorkers))
+
+		// A higher limit may free slots for queued tasks
+		d.startQueuedTasksAsync(name)
+	}
+
+	if resolveConflicts, ok := req.Args["resolve_conflicts"].(bool); ok {
+		if err := d.state.SetResolveConflicts(name, resolveConflicts); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated conflict resolution for repo %s: %v", name, resolveConflicts)
+	}
+
+	if strategy, ok := req.Args["sync_strategy"].(string); ok {
+		if strategy != "" && strategy != state.SyncStrategyMerge && strategy != state.SyncStrategyRebase {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid sync strategy: %s (must be merge, rebase, or empty)", strategy)}
+		}
+		upstream, err := d.state.GetUpstreamConfig(name)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		if upstream == nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository %q does not have upstream tracking enabled", name)}
+		}
+		updated := *upstream
+		updated.SyncStrategy = strategy
+		if err := d.state.SetUpstreamConfig(name, &updated); err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
+		}
+		d.logger.Info("Updated upstream sync strategy for repo %s: %q", name, strategy)
+	}
+
 	return socket.Response{Success: true}
 }
 
@@ -1360,6 +1815,11 @@ func (d *Daemon) handleClearCurrentRepo(req socket.Request) socket.Response {
 	return socket.Response{Success: true}
 }
 
+// appendToSliceMap appends value to the slice stored under key
+func appendToSliceMap(m map[string][]string, key, value string) {
+	m[key] = append(m[key], value)
+}
+
 // cleanupDeadAgents removes dead agents from state
 func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 	for repoName, agentNames := range deadAgents {
@@ -1402,6 +1862,15 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 				d.eventBus.Emit(events.NewAgentStoppedEvent(repoName, agentName, reason))
 			}
 
+			// A completed worker's branch that still conflicts with its base is
+			// handed to a conflict-resolution worker once its worktree is gone
+			resolveBranch := ""
+			if agent.Type == state.AgentTypeWorker && agent.ReadyForCleanup && agent.NeedsRebase && agent.FailureReason == "" && repo.ResolveConflicts {
+				if b, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
+					resolveBranch = b
+				}
+			}
+
 			// Clean up worktree if it exists (workers and review agents have worktrees)
 			if agent.WorktreePath != "" && (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) {
 				repoPath := d.paths.RepoDir(repoName)
@@ -1412,6 +1881,9 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 					d.logger.Info("Removed worktree for dead agent: %s", agent.WorktreePath)
 				}
 			}
+			if resolveBranch != "" {
+				d.queueConflictResolver(repoName, agentName, agent, resolveBranch)
+			}
 
 			// Clean up message directory
 			msgMgr := d.getMessageManager()
@@ -1446,13 +1918,26 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 		Name:          agentName,
 		Task:          agent.Task,
 		Branch:        branch,
-		Status:        status, // Will be updated when displaying if a PR exists
+		Status:        status,
 		Summary:       agent.Summary,
 		FailureReason: agent.FailureReason,
 		CreatedAt:     agent.CreatedAt,
 		CompletedAt:   time.Now(),
 	}
 
+	// The status comes from the worker's tracked PR, if it had one, and
+	// checkTaskPRs keeps it up to date after the worker is gone. Carrying the
+	// PR over also keeps it from being reported as new again
+	if agent.PR != nil {
+		entry.PRNumber = agent.PR.Number
+		entry.PRURL = agent.PR.URL
+		entry.CIStatus = agent.PR.CIStatus
+		entry.ReviewStatus = agent.PR.ReviewStatus
+		if status != state.TaskStatusFailed {
+			entry.Status = agent.PR.Status
+		}
+	}
+
 	if err := d.state.AddTaskHistory(repoName, entry); err != nil {
 		d.logger.Warn("Failed to record task history for %s: %v", agentName, err)
 	} else {
@@ -1490,6 +1975,8 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 			"status":         string(entry.Status),
 			"summary":        entry.Summary,
 			"failure_reason": entry.FailureReason,
+			"ci_status":      entry.CIStatus,
+			"review_status":  entry.ReviewStatus,
 			"created_at":     entry.CreatedAt,
 			"completed_at":   entry.CompletedAt,
 		}
@@ -1498,13 +1985,15 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 	return socket.Response{Success: true, Data: result}
 }
 
-// handleSpawnAgent spawns a new agent with an inline prompt (no hardcoded type).
-// This is used by the supervisor to spawn agents based on markdown definitions.
+// handleSpawnAgent spawns a new agent from an agent definition or an inline
+// prompt (no hardcoded type). This is used by the supervisor to spawn agents
+// based on markdown definitions.
 // Args:
 //   - repo: repository name
-//   - name: agent name (used for tmux window and worktree)
-//   - class: "persistent" or "ephemeral"
-//   - prompt: full prompt text to use as system prompt
+//   - definition: name of an agent definition to spawn (instead of prompt)
+//   - name: agent name (used for tmux window and worktree); defaults to the definition name
+//   - class: "persistent" or "ephemeral"; optional when the definition declares its type
+//   - prompt: full prompt text to use as system prompt, optionally with YAML frontmatter
 //   - task: optional task description (for ephemeral/worker agents)
 func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
@@ -1512,138 +2001,59 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		return errResp
 	}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "name", "agent name is required")
-	if !ok {
-		return errResp
-	}
-
-	agentClass, errResp, ok := getRequiredStringArg(req.Args, "class", "agent class is required (persistent or ephemeral)")
-	if !ok {
-		return errResp
-	}
-
-	promptText, errResp, ok := getRequiredStringArg(req.Args, "prompt", "prompt text is required")
-	if !ok {
-		return errResp
-	}
-
-	// Validate class
-	if agentClass != "persistent" && agentClass != "ephemeral" {
-		return socket.Response{
-			Success: false,
-			Error:   fmt.Sprintf("invalid agent class %q: must be 'persistent' or 'ephemeral'", agentClass),
-		}
-	}
-
-	// Get optional task
-	task, _ := req.Args["task"].(string)
-
-	// Get repository
-	repo, exists := d.state.GetRepo(repoName)
-	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
-	}
+	var spec spawnSpec
+	spec.definition, _ = req.Args["definition"].(string)
+	spec.name, _ = req.Args["name"].(string)
+	spec.class, _ = req.Args["class"].(string)
+	spec.task, _ = req.Args["task"].(string)
+	promptText, _ := req.Args["prompt"].(string)
 
-	// Check if agent already exists
-	if _, exists := d.state.GetAgent(repoName, agentName); exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent %q already exists in repository %q", agentName, repoName)}
-	}
-
-	// Determine agent type based on class
-	var agentType state.AgentType
-	if agentClass == "persistent" {
-		// For persistent agents, use specific type if known or generic persistent
-		if agentName == "merge-queue" {
-			agentType = state.AgentTypeMergeQueue
-		} else {
-			agentType = state.AgentTypeGenericPersistent
-		}
-	} else {
-		// Ephemeral agents are workers or reviewers
-		if strings.Contains(strings.ToLower(agentName), "review") {
-			agentType = state.AgentTypeReview
-		} else {
-			agentType = state.AgentTypeWorker
+	switch {
+	case spec.definition != "" && promptText != "":
+		return socket.Response{Success: false, Error: "specify either a definition or a prompt, not both"}
+	case spec.definition != "":
+		def, err := d.readAgentDefinition(repoName, spec.definition)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
 		}
-	}
-
-	// Create worktree for the agent
-	repoPath := d.paths.RepoDir(repoName)
-	worktreePath := d.paths.AgentWorktree(repoName, agentName)
-
-	wt := worktree.NewManager(repoPath)
-
-	// Create worktree - persistent agents use repo dir, ephemeral get their own branch
-	if agentClass == "persistent" {
-		// Persistent agents work directly in the repo directory
-		worktreePath = repoPath
-	} else {
-		// Ephemeral agents get their own worktree with a new branch
-		branchName := fmt.Sprintf("multiclaude/%s", agentName)
-		if err := wt.CreateNewBranch(worktreePath, branchName, "HEAD"); err != nil {
-			return socket.Response{Success: false, Error: fmt.Sprintf("failed to create worktree: %v", err)}
+		spec.prompt, spec.meta = def.Content, def.Metadata
+		if spec.name == "" {
+			spec.name = def.Name
 		}
-	}
-
-	// Create tmux window with working directory
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", agentName, "-c", worktreePath)
-	if err := cmd.Run(); err != nil {
-		// Clean up worktree on failure (only for ephemeral agents that have their own worktree)
-		if agentClass != "persistent" {
-			wt.Remove(worktreePath, true)
+	case promptText != "":
+		meta, body, err := agents.ParseFrontmatter(promptText)
+		if err != nil {
+			return socket.Response{Success: false, Error: err.Error()}
 		}
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to create tmux window: %v", err)}
-	}
-
-	// Write prompt to file
-	promptDir := filepath.Join(d.paths.Root, "prompts")
-	if err := os.MkdirAll(promptDir, 0755); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to create prompt directory: %v", err)}
-	}
-
-	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
-	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to write prompt file: %v", err)}
-	}
-
-	// Copy hooks config
-	if err := hooks.CopyConfig(repoPath, worktreePath); err != nil {
-		d.logger.Warn("Failed to copy hooks config: %v", err)
+		spec.prompt, spec.meta = body, meta
+	default:
+		return socket.Response{Success: false, Error: "prompt text is required"}
 	}
 
-	// Start Claude in the tmux window
-	cfg := agentStartConfig{
-		agentName:  agentName,
-		agentType:  agentType,
-		promptFile: promptPath,
-		workDir:    worktreePath,
+	if spec.name == "" {
+		return socket.Response{Success: false, Error: "agent name is required"}
 	}
 
-	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
-		// Clean up on failure
-		d.tmux.KillWindow(d.ctx, repo.TmuxSession, agentName)
-		if agentClass != "persistent" {
-			wt.Remove(worktreePath, true)
-		}
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to start agent: %v", err)}
+	// Get optional Claude options, applied on top of the repo's defaults for the type
+	claudeOpts, err := claudeOptionsArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
 	}
+	spec.claude = claudeOpts
 
-	// Update task if provided
-	if task != "" {
-		agent, _ := d.state.GetAgent(repoName, agentName)
-		agent.Task = task
-		d.state.UpdateAgent(repoName, agentName, agent)
+	result, err := d.spawnAgent(repoName, spec)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
 	}
 
-	d.logger.Info("Spawned agent %s/%s (class=%s, type=%s)", repoName, agentName, agentClass, agentType)
-
 	return socket.Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"name":          agentName,
-			"class":         agentClass,
-			"type":          string(agentType),
-			"worktree_path": worktreePath,
+			"name":          spec.name,
+			"class":         result.class,
+			"type":          string(result.agentType),
+			"worktree_path": result.worktreePath,
+			"definition":    spec.definition,
 		},
 	}
 }
@@ -1882,6 +2292,9 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 		}
 	}
 
+	// Start the persistent agents whose definitions ask to auto-start
+	d.startAutoStartAgents(repoName)
+
 	return nil
 }
 
@@ -1918,6 +2331,10 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 
 	for i, def := range definitions {
 		sb.WriteString(fmt.Sprintf("--- Agent Definition %d: %s (source: %s) ---\n", i+1, def.Name, def.Source))
+		if summary := definitionSummary(def.Metadata); summary != "" {
+			sb.WriteString(summary)
+			sb.WriteString("\n")
+		}
 
 		// For merge-queue, prepend the tracking mode configuration if enabled
 		if def.Name == "merge-queue" && mqConfig.Enabled {
@@ -1931,11 +2348,12 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 	}
 
 	sb.WriteString("Review these definitions and determine which agents to spawn.\n")
-	sb.WriteString("For each agent, decide:\n")
-	sb.WriteString("- Class: Is it persistent (long-running, auto-restarts) or ephemeral (task-based, cleans up)?\n")
+	sb.WriteString("Agents marked auto-start are started by the daemon; don't spawn them again.\n")
+	sb.WriteString("For the other agents, decide:\n")
+	sb.WriteString("- Class: Is it persistent (long-running, auto-restarts) or ephemeral (task-based, cleans up)? Use the declared type if there is one.\n")
 	sb.WriteString("- Spawn now: Should this agent start immediately on repository init?\n\n")
-	sb.WriteString("To spawn an agent, save the prompt to a file and use:\n")
-	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --name <agent-name> --class <persistent|ephemeral> --prompt-file <file>\n", repoName))
+	sb.WriteString("To spawn an agent from its definition, use:\n")
+	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --definition <definition-name> [--name <agent-name>] [--class <persistent|ephemeral>] [--task <task>]\n", repoName))
 
 	// Send message to supervisor
 	msgMgr := d.getMessageManager()
@@ -1962,6 +2380,17 @@ type agentStartConfig struct {
 	agentType  state.AgentType
 	promptFile string
 	workDir    string
+	task       string
+	// initialMessage is typed into Claude once it has started, e.g. a worker's task
+	initialMessage string
+	// claude overrides the repo's Claude options for the agent type
+	claude state.ClaudeOptions
+	// definition is the agent definition the agent was spawned from, if any
+	definition string
+	// wakePolicy overrides the repo's wake policy for the agent type
+	wakePolicy *state.WakePolicy
+	// baseBranch is the branch a worker started from and opens its PR against
+	baseBranch string
 }
 
 // startAgentWithConfig is the unified agent start function that handles all common logic
@@ -1978,6 +2407,11 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		d.logger.Warn("Failed to copy hooks config: %v", err)
 	}
 
+	claudeOpts, err := d.launchClaudeOptions(repoName, cfg.agentType, cfg.claude)
+	if err != nil {
+		return err
+	}
+
 	var pid int
 
 	// Skip actual Claude startup in test mode
@@ -1988,9 +2422,16 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 			return fmt.Errorf("failed to resolve claude binary: %w", err)
 		}
 
+		d.setupOutputCapture(repoName, repo.TmuxSession, cfg.agentName, cfg.agentType)
+
 		// Build CLI command
-		claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
-			binaryPath, sessionID, cfg.promptFile)
+		runner := claude.NewRunner(claude.WithBinaryPath(binaryPath))
+		claudeCmd := runner.BuildCommand(sessionID, claude.Config{
+			SystemPromptFile: cfg.promptFile,
+			Model:            claudeOpts.Model,
+			ExtraArgs:        claudeOpts.Args,
+			Env:              claudeOpts.Env,
+		})
 
 		// Send command to tmux window
 		target := fmt.Sprintf("%s:%s", repo.TmuxSession, cfg.agentName)
@@ -2007,6 +2448,14 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		if err != nil {
 			return fmt.Errorf("failed to get Claude PID: %w", err)
 		}
+
+		if cfg.initialMessage != "" {
+			// Wait a bit more for Claude to fully initialize
+			time.Sleep(1 * time.Second)
+			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, cfg.agentName, cfg.initialMessage); err != nil {
+				return fmt.Errorf("failed to send initial message to Claude: %w", err)
+			}
+		}
 	}
 
 	// Register agent with state
@@ -2016,14 +2465,22 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		TmuxWindow:   cfg.agentName,
 		SessionID:    sessionID,
 		PID:          pid,
+		Task:         cfg.task,
+		Definition:   cfg.definition,
+		WakePolicy:   cfg.wakePolicy,
+		BaseBranch:   cfg.baseBranch,
 		CreatedAt:    time.Now(),
 	}
+	if !claudeOpts.IsZero() {
+		agent.Claude = &claudeOpts
+	}
 
 	if err := d.state.AddAgent(repoName, cfg.agentName, agent); err != nil {
 		return fmt.Errorf("failed to register agent: %w", err)
 	}
 
 	d.logger.Info("Started and registered agent %s/%s", repoName, cfg.agentName)
+	d.emitAgentStarted(repoName, cfg.agentName, agent)
 	return nil
 }
 
@@ -2052,6 +2509,11 @@ func (d *Daemon) writePromptFileWithPrefix(repoName string, agentType state.Agen
 		return "", fmt.Errorf("failed to get prompt: %w", err)
 	}
 
+	return d.writePromptText(agentName, prefix, promptText)
+}
+
+// writePromptText writes an agent's prompt file with an optional prefix and returns the path
+func (d *Daemon) writePromptText(agentName, prefix, promptText string) (string, error) {
 	// Prepend prefix if provided
 	if prefix != "" {
 		promptText = prefix + "\n\n" + promptText
@@ -2100,13 +2562,21 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 		}
 	}
 
-	// Restart Claude using the runner
+	d.setupOutputCapture(repoName, repo.TmuxSession, agentName, agent.Type)
+
+	// Restart Claude using the runner, with the options the agent was started with
 	// Note: Slash commands are embedded in prompts, not via CLAUDE_CONFIG_DIR
-	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
+	config := claude.Config{
 		SessionID:        agent.SessionID,
 		Resume:           hasHistory,
 		SystemPromptFile: promptFile,
-	})
+	}
+	if agent.Claude != nil {
+		config.Model = agent.Claude.Model
+		config.ExtraArgs = agent.Claude.Args
+		config.Env = agent.Claude.Env
+	}
+	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, config)
 	if err != nil {
 		return fmt.Errorf("failed to restart Claude: %w", err)
 	}
@@ -2120,9 +2590,14 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 	return nil
 }
 
-// writePromptFile writes the agent prompt to a file and returns the path
+// writePromptFile writes the agent prompt to a file and returns the path.
+// Agents of repos on the local forge are told to use multiclaude pr.
 func (d *Daemon) writePromptFile(repoName string, agentType state.AgentType, agentName string) (string, error) {
-	return d.writePromptFileWithPrefix(repoName, agentType, agentName, "")
+	prefix := ""
+	if repo, exists := d.state.GetRepo(repoName); exists && forge.IsLocal(repo.GithubURL) {
+		prefix = prompts.GenerateLocalForgePrompt()
+	}
+	return d.writePromptFileWithPrefix(repoName, agentType, agentName, prefix)
 }
 
 // isProcessAlive checks if a process is running
@@ -2247,6 +2722,28 @@ func (d *Daemon) rotateLogsIfNeeded() {
 	if err != nil {
 		d.logger.Error("Failed to walk output directory for log rotation: %v", err)
 	}
+
+	d.rotateJournalIfNeeded()
+}
+
+// rotateJournalIfNeeded rotates the event journal once it exceeds
+// events.DefaultJournalMaxSize and prunes the oldest rotated journals
+func (d *Daemon) rotateJournalIfNeeded() {
+	journalPath := d.journal.Path()
+	info, err := os.Stat(journalPath)
+	if err != nil || info.Size() <= events.DefaultJournalMaxSize {
+		return
+	}
+
+	if err := d.rotateLog(journalPath); err != nil {
+		d.logger.Error("Failed to rotate event journal: %v", err)
+		return
+	}
+	d.logger.Info("Rotated event journal (was %d bytes)", info.Size())
+
+	if err := d.journal.Prune(events.DefaultJournalKeep); err != nil {
+		d.logger.Error("Failed to prune event journals: %v", err)
+	}
 }
 
 // rotateLog rotates a single log file by renaming it with a timestamp suffix
@@ -2447,13 +2944,35 @@ func (d *Daemon) handleUpdateHookConfig(req socket.Request) socket.Response {
 	return socket.Response{Success: true}
 }
 
-// forkUpstreamSyncLoop monitors fork/upstream divergence and CI status
+// handleRecordUpstreamSync records that a repository's default branch was
+// synced with upstream. Args: repo, sha (the upstream commit merged).
+func (d *Daemon) handleRecordUpstreamSync(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	sha, errResp, ok := getRequiredStringArg(req.Args, "sha", "upstream commit SHA is required")
+	if !ok {
+		return errResp
+	}
+
+	if err := d.state.UpdateSyncTime(repoName, time.Now(), sha); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Recorded upstream sync for %s at %s", repoName, sha)
+	return socket.Response{Success: true}
+}
+
+// forkUpstreamSyncLoop monitors fork/upstream divergence and CI status, and
+// syncs forks with upstream, checking each repo every SyncInterval minutes
 func (d *Daemon) forkUpstreamSyncLoop() {
 	defer d.wg.Done()
 	d.logger.Info("Starting fork/upstream sync loop")
 
-	// Default check interval is 30 minutes, but can be configured per-repo
-	ticker := time.NewTicker(30 * time.Minute)
+	// Repos are checked when their own interval is up
+	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	// Run once immediately on startup (after a short delay to let things settle)
@@ -2466,7 +2985,7 @@ func (d *Daemon) forkUpstreamSyncLoop() {
 	for {
 		select {
 		case <-ticker.C:
-			d.checkForkUpstreamStatus()
+			d.checkForkUpstreams(false)
 		case <-d.ctx.Done():
 			d.logger.Info("Fork/upstream sync loop stopped")
 			return
@@ -2476,32 +2995,48 @@ func (d *Daemon) forkUpstreamSyncLoop() {
 
 // checkForkUpstreamStatus checks fork/upstream status for all repos with upstream tracking
 func (d *Daemon) checkForkUpstreamStatus() {
+	d.checkForkUpstreams(true)
+}
+
+// checkForkUpstreams syncs repos with upstream tracking that have a sync
+// strategy and checks their divergence and CI status. Unless all is set,
+// only repos whose sync interval is up are checked.
+func (d *Daemon) checkForkUpstreams(all bool) {
 	d.logger.Debug("Checking fork/upstream status")
 
+	now := time.Now()
 	repos := d.state.GetAllRepos()
 	for repoName, repo := range repos {
 		if repo.UpstreamConfig == nil || !repo.UpstreamConfig.SyncEnabled {
 			continue // Skip repos without upstream tracking
 		}
+		if !d.upstreamDue(repoName, repo.UpstreamConfig, now, all) {
+			continue
+		}
 
 		d.logger.Debug("Checking upstream status for repo: %s", repoName)
 
+		// Sync first, so divergence reflects it
+		if strategy := repo.UpstreamConfig.SyncStrategy; strategy != "" {
+			if _, err := d.syncUpstream(repoName, repo, strategy, true); err != nil {
+				d.logger.Warn("Failed to sync %s with upstream: %v", repoName, err)
+			}
+		}
+
 		// Check divergence (is fork behind upstream?)
 		divergence := d.checkUpstreamDivergence(repoName, repo)
 
-		// For now, we'll use simplified CI status checking
-		// In a full implementation, this would query GitHub API
-		forkCI := state.CILayerStatus{
-			Status:     "unknown",
-			LastCheck:  time.Now(),
-			LastCommit: "",
+		// Check CI on the head of the fork's and upstream's default branches
+		var prev state.DualCIStatus
+		if status, err := d.state.GetDualCIStatus(repoName); err == nil && status != nil {
+			prev = *status
 		}
+		forkCI := d.checkCILayer(repoName, "fork", repo.GithubURL, d.repoDefaultBranch(repoName), prev.ForkCI)
+		upstreamCI := d.checkCILayer(repoName, "upstream", repo.UpstreamConfig.UpstreamURL, d.upstreamDefaultBranch(repoName, repo), prev.UpstreamCI)
 
-		upstreamCI := state.CILayerStatus{
-			Status:     "unknown",
-			LastCheck:  time.Now(),
-			LastCommit: "",
-		}
+		// Emit CI events for layers whose status changed since the last check
+		d.emitCITransition(repoName, "fork", prev.ForkCI, forkCI)
+		d.emitCITransition(repoName, "upstream", prev.UpstreamCI, upstreamCI)
 
 		// Update state
 		if err := d.state.UpdateDualCIStatus(repoName, forkCI, upstreamCI, divergence); err != nil {
@@ -2526,19 +3061,17 @@ func (d *Daemon) checkUpstreamDivergence(repoName string, repo *state.Repository
 		return 0
 	}
 
-	// Try main branch first
-	upstreamBranch := fmt.Sprintf("%s/main", repo.UpstreamConfig.UpstreamRemote)
+	// Compare against upstream's copy of the default branch
+	upstreamBranch, err := worktree.NewManager(repoPath).UpstreamBranchRef(repo.UpstreamConfig.UpstreamRemote, repo.DefaultBranch)
+	if err != nil {
+		d.logger.Warn("Failed to find upstream branch for %s: %v", repoName, err)
+		return 0
+	}
 	cmd = exec.Command("git", "-C", repoPath, "rev-list", "--count", "HEAD.."+upstreamBranch)
 	output, err := cmd.Output()
 	if err != nil {
-		// Try master if main doesn't exist
-		upstreamBranch = fmt.Sprintf("%s/master", repo.UpstreamConfig.UpstreamRemote)
-		cmd = exec.Command("git", "-C", repoPath, "rev-list", "--count", "HEAD.."+upstreamBranch)
-		output, err = cmd.Output()
-		if err != nil {
-			d.logger.Warn("Failed to check divergence for %s: %v", repoName, err)
-			return 0
-		}
+		d.logger.Warn("Failed to check divergence for %s: %v", repoName, err)
+		return 0
 	}
 
 	countStr := strings.TrimSpace(string(output))
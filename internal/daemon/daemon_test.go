@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -1181,6 +1182,216 @@ func TestMessageRoutingWithRealTmux(t *testing.T) {
 	}
 }
 
+func TestMessageRoutingDeliversUrgentFirst(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Note: In CI environments, tmux may be installed but unable to create sessions (no TTY)
+	sessionName := "mc-test-priority"
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), sessionName)
+
+	if err := tmuxClient.CreateWindow(context.Background(), sessionName, "worker1"); err != nil {
+		t.Fatalf("Failed to create worker window: %v", err)
+	}
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "worker1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	for _, m := range []struct {
+		body     string
+		priority messages.Priority
+	}{
+		{"first-low", messages.PriorityLow},
+		{"second-normal", messages.PriorityNormal},
+		{"third-urgent", messages.PriorityUrgent},
+	} {
+		if _, err := msgMgr.SendWithOptions("test-repo", "supervisor", "worker1", m.body, messages.SendOptions{Priority: m.priority}); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond) // distinct timestamps
+	}
+
+	d.TriggerMessageRouting()
+
+	out, err := exec.Command("tmux", "capture-pane", "-p", "-t", sessionName+":worker1").Output()
+	if err != nil {
+		t.Fatalf("Failed to capture pane: %v", err)
+	}
+	pane := string(out)
+	urgent, normal, low := strings.Index(pane, "third-urgent"), strings.Index(pane, "second-normal"), strings.Index(pane, "first-low")
+	if urgent < 0 || normal < 0 || low < 0 {
+		t.Fatalf("not all messages were delivered, pane:\n%s", pane)
+	}
+	if !(urgent < normal && normal < low) {
+		t.Errorf("delivery order should be urgent, normal, low; pane:\n%s", pane)
+	}
+}
+
+func TestFormatMessageDelivery(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  messages.Message
+		want string
+	}{
+		{
+			name: "plain",
+			msg:  messages.Message{ID: "msg-1", From: "supervisor", Body: "Hello"},
+			want: "📨 Message from supervisor [msg-1]: Hello",
+		},
+		{
+			name: "urgent with subject",
+			msg:  messages.Message{ID: "msg-1", ThreadID: "msg-1", From: "supervisor", Body: "Stop", Priority: messages.PriorityUrgent, Subject: "CI broken"},
+			want: "🚨 URGENT message from supervisor [msg-1]: CI broken — Stop",
+		},
+		{
+			name: "direct reply",
+			msg:  messages.Message{ID: "msg-2", ThreadID: "msg-1", ReplyTo: "msg-1", From: "worker1", Body: "Done"},
+			want: "📨 Message from worker1 [msg-2, reply to msg-1]: Done",
+		},
+		{
+			name: "reply deeper in thread",
+			msg:  messages.Message{ID: "msg-3", ThreadID: "msg-1", ReplyTo: "msg-2", From: "supervisor", Body: "Thanks"},
+			want: "📨 Message from supervisor [msg-3, reply to msg-2, thread msg-1]: Thanks",
+		},
+		{
+			name: "broadcast",
+			msg:  messages.Message{ID: "msg-4", ThreadID: "msg-4", From: "supervisor", Body: "Main is broken", BroadcastID: "bcast-1"},
+			want: "📨 Message from supervisor [msg-4, broadcast]: Main is broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatMessageDelivery(&tt.msg); got != tt.want {
+				t.Errorf("formatMessageDelivery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageRoutingRetriesFailedDelivery(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// No tmux session exists, so every delivery attempt fails
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-no-such-session",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "worker1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "hello")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	d.TriggerMessageRouting()
+
+	got, err := msgMgr.Get("test-repo", "worker1", msg.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if got.Status != messages.StatusPending || got.Attempts != 1 || got.LastError == "" || got.NextAttemptAt == nil {
+		t.Fatalf("after failed delivery: %+v", got)
+	}
+
+	// The message watcher is asked to retry when the backoff ends
+	select {
+	case retry := <-d.messageRetries:
+		if retry.target.agent != "worker1" || retry.at.Sub(*got.NextAttemptAt).Abs() > time.Second {
+			t.Errorf("retry = %+v, want worker1 at %s", retry, got.NextAttemptAt)
+		}
+	default:
+		t.Error("a failed delivery should schedule a retry")
+	}
+
+	// A second pass during the backoff doesn't retry
+	d.TriggerMessageRouting()
+	if got, _ := msgMgr.Get("test-repo", "worker1", msg.ID); got.Attempts != 1 {
+		t.Errorf("Attempts = %d during backoff, want 1", got.Attempts)
+	}
+
+	// Once retries are exhausted the message is dead-lettered
+	for i := got.Attempts; i < messages.MaxDeliveryAttempts; i++ {
+		// Skip the backoff
+		past := time.Now().Add(-time.Second)
+		got.NextAttemptAt = &past
+		data, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("Failed to marshal message: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(d.paths.MessagesDir, "test-repo", "worker1", msg.ID+".json"), data, 0644); err != nil {
+			t.Fatalf("Failed to rewrite message: %v", err)
+		}
+		d.TriggerMessageRouting()
+		if next, err := msgMgr.Get("test-repo", "worker1", msg.ID); err == nil {
+			got = next
+		}
+	}
+
+	dead, err := msgMgr.ListDeadLetters("test-repo")
+	if err != nil {
+		t.Fatalf("Failed to list dead letters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != msg.ID || dead[0].Attempts != messages.MaxDeliveryAttempts {
+		t.Fatalf("dead letters = %+v, want the message after %d attempts", dead, messages.MaxDeliveryAttempts)
+	}
+	if _, err := msgMgr.Get("test-repo", "worker1", msg.ID); err == nil {
+		t.Error("dead-lettered message should be removed from the inbox")
+	}
+}
+
+func TestMessageRoutingDeadLettersExpiredMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	if err := d.state.AddAgent("test-repo", "worker1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	msgMgr := messages.NewManager(d.paths.MessagesDir)
+	msg, err := msgMgr.SendWithOptions("test-repo", "supervisor", "worker1", "too late", messages.SendOptions{TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	d.TriggerMessageRouting()
+
+	letter, err := msgMgr.GetDeadLetter("test-repo", msg.ID)
+	if err != nil {
+		t.Fatalf("expired message should be dead-lettered: %v", err)
+	}
+	if letter.DeadReason != "expired before delivery" || letter.Attempts != 0 {
+		t.Errorf("dead letter = %+v", letter)
+	}
+}
+
 func TestWakeLoopUpdatesNudgeTime(t *testing.T) {
 	tmuxClient := tmux.NewClient()
 	if !tmuxClient.IsTmuxAvailable() {
@@ -1725,6 +1936,37 @@ func TestWritePromptFileWorker(t *testing.T) {
 	}
 }
 
+func TestWritePromptFileLocalForge(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "test-repo"
+	if err := os.MkdirAll(d.paths.RepoDir(repoName), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	repo := &state.Repository{
+		GithubURL:   "/srv/git/app.git",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// Regenerated prompts keep the local forge instructions
+	promptPath, err := d.writePromptFile(repoName, "review", "review-1")
+	if err != nil {
+		t.Fatalf("writePromptFile() failed: %v", err)
+	}
+	content, err := os.ReadFile(promptPath)
+	if err != nil {
+		t.Fatalf("Failed to read prompt file: %v", err)
+	}
+	if !strings.Contains(string(content), "multiclaude pr review") {
+		t.Error("Prompt for a local forge repo should list the multiclaude pr commands")
+	}
+}
+
 func TestCopyHooksConfig(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
@@ -0,0 +1,288 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/agents"
+	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// spawnSpec describes an agent spawned from a definition or an inline prompt
+type spawnSpec struct {
+	name string
+	// class is "persistent" or "ephemeral"; when empty it comes from meta.Type
+	class  string
+	prompt string
+	task   string
+	// definition is the name of the agent definition the agent is spawned from, if any
+	definition string
+	meta       agents.Metadata
+	// claude overrides the repo's and the definition's Claude options
+	claude state.ClaudeOptions
+}
+
+// spawnResult describes an agent that was spawned
+type spawnResult struct {
+	class        string
+	agentType    state.AgentType
+	worktreePath string
+}
+
+// definitionClass maps a definition's frontmatter type to an agent class
+func definitionClass(defType string) string {
+	switch defType {
+	case agents.TypePersistent:
+		return "persistent"
+	case agents.TypeTransient:
+		return "ephemeral"
+	}
+	return ""
+}
+
+// resolveSpawnClass returns the class to spawn an agent with, checking that
+// an explicit class agrees with the type its definition declares
+func resolveSpawnClass(class string, meta agents.Metadata) (string, error) {
+	declared := definitionClass(meta.Type)
+	if class == "" {
+		class = declared
+	}
+	if class == "" {
+		return "", fmt.Errorf("agent class is required (persistent or ephemeral)")
+	}
+	if class != "persistent" && class != "ephemeral" {
+		return "", fmt.Errorf("invalid agent class %q: must be 'persistent' or 'ephemeral'", class)
+	}
+	if declared != "" && declared != class {
+		return "", fmt.Errorf("agent class %q conflicts with the definition's type %q", class, meta.Type)
+	}
+	return class, nil
+}
+
+// readAgentDefinitions reads the agent definitions available to a repository
+func (d *Daemon) readAgentDefinitions(repoName string) ([]agents.Definition, error) {
+	reader := agents.NewReader(d.paths.RepoAgentsDir(repoName), d.paths.RepoDir(repoName))
+	definitions, err := reader.ReadAllDefinitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent definitions: %w", err)
+	}
+	return definitions, nil
+}
+
+// readAgentDefinition returns the named agent definition of a repository
+func (d *Daemon) readAgentDefinition(repoName, name string) (agents.Definition, error) {
+	definitions, err := d.readAgentDefinitions(repoName)
+	if err != nil {
+		return agents.Definition{}, err
+	}
+	def, ok := agents.FindDefinition(definitions, name)
+	if !ok {
+		return agents.Definition{}, fmt.Errorf("agent definition %q not found", name)
+	}
+	return def, nil
+}
+
+// definitionWakePolicy returns the wake policy an agent spawned from a
+// definition runs with, or nil if the definition doesn't override its type's
+func definitionWakePolicy(repo *state.Repository, agentType state.AgentType, meta agents.Metadata) *state.WakePolicy {
+	minutes, hasInterval := meta.WakeIntervalMinutes()
+	if meta.WakeMessage == "" && !hasInterval {
+		return nil
+	}
+	policy := repo.WakePolicyFor(agentType)
+	if meta.WakeMessage != "" {
+		policy.Message = meta.WakeMessage
+	}
+	if hasInterval {
+		policy.IntervalMinutes = minutes
+	}
+	return &policy
+}
+
+// countDefinitionInstances returns how many agents of a repository were spawned from a definition
+func countDefinitionInstances(repo *state.Repository, definition string) int {
+	count := 0
+	for _, agent := range repo.Agents {
+		if agent.Definition == definition {
+			count++
+		}
+	}
+	return count
+}
+
+// spawnAgent creates the worktree and tmux window for an agent and starts
+// Claude in it with the given prompt
+func (d *Daemon) spawnAgent(repoName string, spec spawnSpec) (spawnResult, error) {
+	class, err := resolveSpawnClass(spec.class, spec.meta)
+	if err != nil {
+		return spawnResult{}, err
+	}
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return spawnResult{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if _, exists := d.state.GetAgent(repoName, spec.name); exists {
+		return spawnResult{}, fmt.Errorf("agent %q already exists in repository %q", spec.name, repoName)
+	}
+
+	if missing := spec.meta.MissingTools(); len(missing) > 0 {
+		return spawnResult{}, fmt.Errorf("missing required tools: %s", strings.Join(missing, ", "))
+	}
+
+	if spec.definition != "" && spec.meta.MaxInstances > 0 {
+		if count := countDefinitionInstances(repo, spec.definition); count >= spec.meta.MaxInstances {
+			return spawnResult{}, fmt.Errorf("agent definition %q already has %d of %d allowed instances running", spec.definition, count, spec.meta.MaxInstances)
+		}
+	}
+
+	// Determine agent type based on class
+	var agentType state.AgentType
+	if class == "persistent" {
+		// For persistent agents, use specific type if known or generic persistent
+		if spec.name == "merge-queue" {
+			agentType = state.AgentTypeMergeQueue
+		} else {
+			agentType = state.AgentTypeGenericPersistent
+		}
+	} else {
+		// Ephemeral agents are workers or reviewers
+		if strings.Contains(strings.ToLower(spec.name), "review") {
+			agentType = state.AgentTypeReview
+		} else {
+			agentType = state.AgentTypeWorker
+		}
+	}
+
+	// Create worktree for the agent
+	repoPath := d.paths.RepoDir(repoName)
+	worktreePath := d.paths.AgentWorktree(repoName, spec.name)
+
+	wt := worktree.NewManager(repoPath)
+
+	// Create worktree - persistent agents use repo dir, ephemeral get their own branch
+	if class == "persistent" {
+		// Persistent agents work directly in the repo directory
+		worktreePath = repoPath
+	} else {
+		// Ephemeral agents get their own worktree with a new branch
+		branchName := fmt.Sprintf("multiclaude/%s", spec.name)
+		if err := wt.CreateNewBranch(worktreePath, branchName, "HEAD"); err != nil {
+			return spawnResult{}, fmt.Errorf("failed to create worktree: %v", err)
+		}
+	}
+
+	// Create tmux window with working directory
+	cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", spec.name, "-c", worktreePath)
+	if err := cmd.Run(); err != nil {
+		// Clean up worktree on failure (only for ephemeral agents that have their own worktree)
+		if class != "persistent" {
+			wt.Remove(worktreePath, true)
+		}
+		return spawnResult{}, fmt.Errorf("failed to create tmux window: %v", err)
+	}
+
+	// Write prompt to file
+	promptDir := filepath.Join(d.paths.Root, "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		return spawnResult{}, fmt.Errorf("failed to create prompt directory: %v", err)
+	}
+
+	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", spec.name))
+	if err := os.WriteFile(promptPath, []byte(spec.prompt), 0644); err != nil {
+		return spawnResult{}, fmt.Errorf("failed to write prompt file: %v", err)
+	}
+
+	// Copy hooks config
+	if err := hooks.CopyConfig(repoPath, worktreePath); err != nil {
+		d.logger.Warn("Failed to copy hooks config: %v", err)
+	}
+
+	// Start Claude in the tmux window. The definition's model sits between the
+	// repo's defaults and the per-spawn override.
+	cfg := agentStartConfig{
+		agentName:  spec.name,
+		agentType:  agentType,
+		promptFile: promptPath,
+		workDir:    worktreePath,
+		task:       spec.task,
+		claude:     state.ClaudeOptions{Model: spec.meta.Model}.Merge(spec.claude),
+		definition: spec.definition,
+		wakePolicy: definitionWakePolicy(repo, agentType, spec.meta),
+	}
+
+	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
+		// Clean up on failure
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, spec.name)
+		if class != "persistent" {
+			wt.Remove(worktreePath, true)
+		}
+		return spawnResult{}, fmt.Errorf("failed to start agent: %v", err)
+	}
+
+	d.logger.Info("Spawned agent %s/%s (class=%s, type=%s, definition=%q)", repoName, spec.name, class, agentType, spec.definition)
+
+	return spawnResult{class: class, agentType: agentType, worktreePath: worktreePath}, nil
+}
+
+// startAutoStartAgents spawns the persistent agents whose definitions ask to
+// be started with the repository and that aren't already running
+func (d *Daemon) startAutoStartAgents(repoName string) {
+	definitions, err := d.readAgentDefinitions(repoName)
+	if err != nil {
+		d.logger.Warn("Failed to read agent definitions for %s: %v", repoName, err)
+		return
+	}
+
+	for _, def := range definitions {
+		if !def.Metadata.AutoStart {
+			continue
+		}
+		if _, exists := d.state.GetAgent(repoName, def.Name); exists {
+			continue
+		}
+		_, err := d.spawnAgent(repoName, spawnSpec{
+			name:       def.Name,
+			prompt:     def.Content,
+			definition: def.Name,
+			meta:       def.Metadata,
+		})
+		if err != nil {
+			d.logger.Error("Failed to auto-start agent %s/%s: %v", repoName, def.Name, err)
+		}
+	}
+}
+
+// definitionSummary describes a definition's frontmatter for the supervisor,
+// or returns "" if it has none
+func definitionSummary(meta agents.Metadata) string {
+	if meta.IsZero() {
+		return ""
+	}
+	var parts []string
+	if meta.Type != "" {
+		parts = append(parts, "type: "+meta.Type)
+	}
+	if meta.AutoStart {
+		parts = append(parts, "auto-start: yes (started by the daemon)")
+	}
+	if meta.Model != "" {
+		parts = append(parts, "model: "+meta.Model)
+	}
+	if minutes, ok := meta.WakeIntervalMinutes(); ok {
+		parts = append(parts, fmt.Sprintf("wake interval: %dm", minutes))
+	}
+	if meta.MaxInstances > 0 {
+		parts = append(parts, fmt.Sprintf("max instances: %d", meta.MaxInstances))
+	}
+	if len(meta.RequiredTools) > 0 {
+		parts = append(parts, "required tools: "+strings.Join(meta.RequiredTools, ", "))
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// writeTestDefinition writes an agent definition to the test repo's local agents dir
+func writeTestDefinition(t *testing.T, d *Daemon, name, content string) {
+	t.Helper()
+	dir := d.paths.RepoAgentsDir("test-repo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create agents dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write definition: %v", err)
+	}
+}
+
+func TestSpawnAgentFromDefinition(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	writeTestDefinition(t, d, "triager", `---
+type: persistent
+model: opus
+wake_message: "Any new issues?"
+wake_interval: 15m
+max_instances: 1
+---
+# Triager
+
+Triage issues.
+`)
+
+	resp := d.handleRequest(socket.Request{Command: "spawn_agent", Args: map[string]interface{}{
+		"repo":       "test-repo",
+		"definition": "triager",
+	}})
+	if !resp.Success {
+		t.Fatalf("spawn_agent failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["name"] != "triager" || data["class"] != "persistent" || data["definition"] != "triager" {
+		t.Errorf("spawn_agent data = %v", data)
+	}
+
+	agent, exists := d.state.GetAgent("test-repo", "triager")
+	if !exists {
+		t.Fatal("triager should be registered")
+	}
+	if agent.Definition != "triager" {
+		t.Errorf("agent.Definition = %q, want triager", agent.Definition)
+	}
+	if agent.Claude == nil || agent.Claude.Model != "opus" {
+		t.Errorf("agent.Claude = %+v, want model opus", agent.Claude)
+	}
+	repo, _ := d.state.GetRepo("test-repo")
+	policy := repo.WakePolicyForAgent(agent)
+	if policy.Message != "Any new issues?" || policy.IntervalMinutes != 15 {
+		t.Errorf("wake policy = %+v", policy)
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(d.paths.Root, "prompts", "triager.md"))
+	if err != nil {
+		t.Fatalf("Failed to read prompt file: %v", err)
+	}
+	if strings.Contains(string(prompt), "max_instances") || !strings.HasPrefix(string(prompt), "# Triager") {
+		t.Errorf("prompt file should hold the body without frontmatter, got %q", prompt)
+	}
+
+	// max_instances counts every agent spawned from the definition
+	resp = d.handleRequest(socket.Request{Command: "spawn_agent", Args: map[string]interface{}{
+		"repo":       "test-repo",
+		"definition": "triager",
+		"name":       "triager-2",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "allowed instances") {
+		t.Errorf("second spawn should hit max_instances, got success=%v error=%q", resp.Success, resp.Error)
+	}
+}
+
+func TestSpawnAgentDefinitionErrors(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	writeTestDefinition(t, d, "releaser", "---\ntype: transient\nrequired_tools: [multiclaude-no-such-tool]\n---\n# Releaser\n")
+	writeTestDefinition(t, d, "watcher", "---\ntype: persistent\n---\n# Watcher\n")
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError string
+	}{
+		{"unknown definition", map[string]interface{}{"definition": "nope"}, "not found"},
+		{"missing tools", map[string]interface{}{"definition": "releaser"}, "missing required tools: multiclaude-no-such-tool"},
+		{"class conflict", map[string]interface{}{"definition": "watcher", "class": "ephemeral"}, "conflicts"},
+		{"definition and prompt", map[string]interface{}{"definition": "watcher", "prompt": "# Watcher"}, "not both"},
+		{"invalid frontmatter", map[string]interface{}{"name": "x", "prompt": "---\ntype: forever\n---\n"}, "invalid type"},
+		{"prompt without class", map[string]interface{}{"name": "x", "prompt": "# Agent"}, "agent class is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["repo"] = "test-repo"
+			resp := d.handleRequest(socket.Request{Command: "spawn_agent", Args: tt.args})
+			if resp.Success {
+				t.Fatal("spawn_agent should fail")
+			}
+			if !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("error = %q, want to contain %q", resp.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestSpawnAgentInlinePromptFrontmatter(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	resp := d.handleRequest(socket.Request{Command: "spawn_agent", Args: map[string]interface{}{
+		"repo":   "test-repo",
+		"name":   "doc-writer",
+		"prompt": "---\ntype: transient\nmodel: haiku\n---\n# Doc Writer\n",
+		"task":   "Document the API",
+	}})
+	if !resp.Success {
+		t.Fatalf("spawn_agent failed: %s", resp.Error)
+	}
+	if class := resp.Data.(map[string]interface{})["class"]; class != "ephemeral" {
+		t.Errorf("class = %v, want ephemeral from the transient type", class)
+	}
+
+	agent, _ := d.state.GetAgent("test-repo", "doc-writer")
+	if agent.Claude == nil || agent.Claude.Model != "haiku" {
+		t.Errorf("agent.Claude = %+v, want model haiku", agent.Claude)
+	}
+	if agent.Definition != "" || agent.WakePolicy != nil {
+		t.Errorf("inline prompt agent should have no definition or wake policy, got %q, %+v", agent.Definition, agent.WakePolicy)
+	}
+}
+
+func TestStartAutoStartAgents(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	writeTestDefinition(t, d, "triager", "---\ntype: persistent\nauto_start: true\n---\n# Triager\n")
+	writeTestDefinition(t, d, "helper", "---\ntype: persistent\n---\n# Helper\n")
+
+	d.startAutoStartAgents("test-repo")
+	d.startAutoStartAgents("test-repo")
+
+	repo, _ := d.state.GetRepo("test-repo")
+	if len(repo.Agents) != 1 {
+		t.Fatalf("expected only the auto-start agent, got %d agents", len(repo.Agents))
+	}
+	if agent, exists := repo.Agents["triager"]; !exists || agent.Definition != "triager" {
+		t.Errorf("triager should be auto-started from its definition, got %+v", repo.Agents)
+	}
+}
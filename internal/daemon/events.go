@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// agentKey returns the key used to track per-agent event state
+func agentKey(repoName, agentName string) string {
+	return repoName + "/" + agentName
+}
+
+// emitAgentStarted emits agent_started for a newly registered agent, plus
+// task_assigned if the agent was created with a task
+func (d *Daemon) emitAgentStarted(repoName, agentName string, agent state.Agent) {
+	d.eventBus.Emit(events.NewAgentStartedEvent(repoName, agentName, string(agent.Type), agent.Task))
+	if agent.Task != "" {
+		d.eventBus.Emit(events.NewTaskAssignedEvent(repoName, agentName, agent.Task))
+	}
+}
+
+// reportAgentFailure emits agent_failed for an agent whose process died.
+// Each dead PID is only reported once, so repeated health checks stay quiet.
+func (d *Daemon) reportAgentFailure(repoName, agentName string, agent state.Agent, reason string) {
+	key := agentKey(repoName, agentName)
+
+	d.eventMu.Lock()
+	if d.failedPIDs[key] == agent.PID {
+		d.eventMu.Unlock()
+		return
+	}
+	d.failedPIDs[key] = agent.PID
+	d.eventMu.Unlock()
+
+	d.eventBus.Emit(events.NewAgentFailedEvent(repoName, agentName, string(agent.Type), reason))
+}
+
+// emitTaskStatusTransition emits PR lifecycle events when a task history
+// entry moves from prev to the new status and PR info
+func (d *Daemon) emitTaskStatusTransition(repoName string, prev state.TaskHistoryEntry, status state.TaskStatus, prURL string, prNumber int) {
+	if prNumber == 0 {
+		prNumber = prev.PRNumber
+	}
+	if prURL == "" {
+		prURL = prev.PRURL
+	}
+	title := taskTitle(prev.Task)
+
+	// A PR is "created" the first time we learn about it
+	if prev.PRNumber == 0 && prev.PRURL == "" && (prNumber > 0 || prURL != "") {
+		d.eventBus.Emit(events.NewPRCreatedEvent(repoName, prev.Name, prNumber, title, prURL))
+	}
+
+	if status == prev.Status {
+		return
+	}
+
+	switch status {
+	case state.TaskStatusMerged:
+		event := events.NewPRMergedEvent(repoName, prNumber, title)
+		event.AgentName = prev.Name
+		d.eventBus.Emit(event)
+	case state.TaskStatusClosed:
+		event := events.NewPRClosedEvent(repoName, prNumber, title)
+		event.AgentName = prev.Name
+		d.eventBus.Emit(event)
+	}
+}
+
+// emitCITransition emits ci_failed or ci_passed when a CI layer changes status.
+// The first observation of a passing layer, or of one that had no checks, is
+// not a transition and stays quiet.
+func (d *Daemon) emitCITransition(repoName, layer string, prev, curr state.CILayerStatus) {
+	if curr.Status == prev.Status {
+		return
+	}
+
+	var event events.Event
+	switch curr.Status {
+	case "failing":
+		event = events.NewCIFailedEvent(repoName, 0, layer)
+	case "passing":
+		if prev.Status == "" || prev.Status == "unknown" {
+			return
+		}
+		event = events.NewCIPassedEvent(repoName, 0, layer)
+	default:
+		return
+	}
+
+	event.Data["layer"] = layer
+	event.Data["commit"] = curr.LastCommit
+	if curr.CheckURL != "" {
+		event.Data["check_url"] = curr.CheckURL
+	}
+	d.eventBus.Emit(event)
+}
+
+// taskTitle returns the first line of a task description for use as a PR title
+func taskTitle(task string) string {
+	if idx := strings.Index(task, "\n"); idx != -1 {
+		return task[:idx]
+	}
+	return task
+}
+
+// handleEventsQuery returns events from the journal matching the filter.
+// Args (all optional):
+//   - repo: only events for this repository
+//   - agent: only events for this agent
+//   - type: comma-separated event types
+//   - since: RFC3339 timestamp; only events at or after it
+//   - limit: only the most recent N matching events
+func (d *Daemon) handleEventsQuery(req socket.Request) socket.Response {
+	filter := events.Filter{}
+	filter.RepoName, _ = req.Args["repo"].(string)
+	filter.AgentName, _ = req.Args["agent"].(string)
+	if types, ok := req.Args["type"].(string); ok {
+		filter.Types = events.ParseTypes(types)
+	}
+	if since, ok := req.Args["since"].(string); ok && since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid since timestamp %q: expected RFC3339", since)}
+		}
+		filter.Since = t
+	}
+	if l, ok := req.Args["limit"].(float64); ok {
+		filter.Limit = int(l)
+	} else if l, ok := req.Args["limit"].(int); ok {
+		filter.Limit = l
+	}
+
+	result, err := d.journal.Query(filter)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if result == nil {
+		result = []events.Event{}
+	}
+
+	return socket.Response{Success: true, Data: result}
+}
@@ -0,0 +1,321 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// captureEvents configures the daemon's event bus with a hook that appends
+// each event's JSON to a file, and returns a function that waits for events.
+func captureEvents(t *testing.T, d *Daemon) func(min int) []events.Event {
+	t.Helper()
+
+	dir := t.TempDir()
+	hookScript := filepath.Join(dir, "capture.sh")
+	outputFile := filepath.Join(dir, "events.jsonl")
+
+	hookContent := `#!/bin/bash
+echo "$2" >> ` + outputFile + `
+`
+	if err := os.WriteFile(hookScript, []byte(hookContent), 0755); err != nil {
+		t.Fatalf("Failed to write hook script: %v", err)
+	}
+	d.eventBus.UpdateConfig(events.HookConfig{OnEvent: hookScript})
+
+	return func(min int) []events.Event {
+		t.Helper()
+		var captured []events.Event
+		for i := 0; i < 40; i++ {
+			captured = nil
+			data, _ := os.ReadFile(outputFile)
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				if line == "" {
+					continue
+				}
+				var e events.Event
+				if err := json.Unmarshal([]byte(line), &e); err == nil {
+					captured = append(captured, e)
+				}
+			}
+			if len(captured) >= min {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return captured
+	}
+}
+
+// findEvent returns the first captured event of the given type
+func findEvent(captured []events.Event, eventType events.EventType) (events.Event, bool) {
+	for _, e := range captured {
+		if e.Type == eventType {
+			return e, true
+		}
+	}
+	return events.Event{}, false
+}
+
+func addEventTestRepo(t *testing.T, d *Daemon) {
+	t.Helper()
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+}
+
+func TestAddAgentEmitsStartedAndTaskAssigned(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	wait := captureEvents(t, d)
+
+	resp := d.handleRequest(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         "worker-1",
+			"type":          "worker",
+			"worktree_path": "/tmp/wt",
+			"tmux_window":   "worker-1",
+			"task":          "Fix the bug",
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+
+	captured := wait(2)
+
+	started, ok := findEvent(captured, events.EventAgentStarted)
+	if !ok {
+		t.Fatalf("agent_started not emitted, got %v", captured)
+	}
+	if started.AgentName != "worker-1" || started.Data["agent_type"] != "worker" {
+		t.Errorf("agent_started = %+v, want worker-1/worker", started)
+	}
+
+	assigned, ok := findEvent(captured, events.EventTaskAssigned)
+	if !ok {
+		t.Fatalf("task_assigned not emitted, got %v", captured)
+	}
+	if assigned.Data["task"] != "Fix the bug" {
+		t.Errorf("task_assigned task = %v, want Fix the bug", assigned.Data["task"])
+	}
+}
+
+func TestCompleteAgentEmitsTaskComplete(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.state.AddAgent("test-repo", "worker-1", state.Agent{
+		Type:       state.AgentTypeWorker,
+		TmuxWindow: "worker-1",
+		Task:       "Fix the bug",
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	wait := captureEvents(t, d)
+
+	resp := d.handleRequest(socket.Request{
+		Command: "complete_agent",
+		Args: map[string]interface{}{
+			"repo":    "test-repo",
+			"agent":   "worker-1",
+			"summary": "Fixed it",
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("complete_agent failed: %s", resp.Error)
+	}
+
+	e, ok := findEvent(wait(1), events.EventTaskComplete)
+	if !ok {
+		t.Fatal("task_complete not emitted")
+	}
+	if e.Data["summary"] != "Fixed it" || e.Data["success"] != true {
+		t.Errorf("task_complete data = %v", e.Data)
+	}
+}
+
+func TestEmitTaskStatusTransition(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	wait := captureEvents(t, d)
+
+	entry := func(name string, status state.TaskStatus, prNumber int) state.TaskHistoryEntry {
+		e := state.TaskHistoryEntry{Name: name, Task: "Task for " + name + "\nwith details", Status: status}
+		if prNumber > 0 {
+			e.PRNumber = prNumber
+			e.PRURL = "https://github.com/test/repo/pull/1"
+		}
+		return e
+	}
+	prURL := "https://github.com/test/repo/pull/1"
+
+	d.emitTaskStatusTransition("test-repo", entry("merged-worker", state.TaskStatusUnknown, 0), state.TaskStatusOpen, prURL, 1)
+	d.emitTaskStatusTransition("test-repo", entry("merged-worker", state.TaskStatusOpen, 1), state.TaskStatusMerged, prURL, 1)
+	d.emitTaskStatusTransition("test-repo", entry("closed-worker", state.TaskStatusUnknown, 0), state.TaskStatusClosed, prURL, 2)
+	// No change, no events
+	d.emitTaskStatusTransition("test-repo", entry("open-worker", state.TaskStatusOpen, 3), state.TaskStatusOpen, prURL, 3)
+
+	captured := wait(4)
+	counts := make(map[events.EventType]int)
+	for _, e := range captured {
+		counts[e.Type]++
+	}
+	if counts[events.EventPRCreated] != 2 {
+		t.Errorf("pr_created count = %d, want 2", counts[events.EventPRCreated])
+	}
+	if counts[events.EventPRMerged] != 1 {
+		t.Errorf("pr_merged count = %d, want 1", counts[events.EventPRMerged])
+	}
+	if counts[events.EventPRClosed] != 1 {
+		t.Errorf("pr_closed count = %d, want 1", counts[events.EventPRClosed])
+	}
+
+	merged, _ := findEvent(captured, events.EventPRMerged)
+	if merged.AgentName != "merged-worker" {
+		t.Errorf("pr_merged agent = %s, want merged-worker", merged.AgentName)
+	}
+	if merged.Data["title"] != "Task for merged-worker" {
+		t.Errorf("pr_merged title = %v, want first line of task", merged.Data["title"])
+	}
+}
+
+func TestEmitCITransition(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	wait := captureEvents(t, d)
+
+	// First observation of a passing layer is quiet
+	d.emitCITransition("test-repo", "fork", state.CILayerStatus{}, state.CILayerStatus{Status: "passing"})
+	d.emitCITransition("test-repo", "fork", state.CILayerStatus{Status: "passing"}, state.CILayerStatus{Status: "failing", LastCommit: "abc123"})
+	d.emitCITransition("test-repo", "fork", state.CILayerStatus{Status: "failing"}, state.CILayerStatus{Status: "passing", LastCommit: "def456"})
+
+	captured := wait(2)
+	if len(captured) != 2 {
+		t.Fatalf("captured %d events, want 2: %v", len(captured), captured)
+	}
+
+	failed, ok := findEvent(captured, events.EventCIFailed)
+	if !ok {
+		t.Fatal("ci_failed not emitted")
+	}
+	if failed.Data["layer"] != "fork" || failed.Data["commit"] != "abc123" {
+		t.Errorf("ci_failed data = %v", failed.Data)
+	}
+	if _, ok := findEvent(captured, events.EventCIPassed); !ok {
+		t.Error("ci_passed not emitted")
+	}
+}
+
+func TestReportAgentFailureOncePerPID(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	wait := captureEvents(t, d)
+
+	agent := state.Agent{Type: state.AgentTypeSupervisor, PID: 12345}
+	d.reportAgentFailure("test-repo", "supervisor", agent, "process 12345 exited")
+	d.reportAgentFailure("test-repo", "supervisor", agent, "process 12345 exited")
+
+	captured := wait(1)
+	// Give any duplicate hook invocation time to land
+	time.Sleep(200 * time.Millisecond)
+	captured = wait(len(captured))
+
+	if len(captured) != 1 {
+		t.Fatalf("captured %d events, want 1: %v", len(captured), captured)
+	}
+	if captured[0].Type != events.EventAgentFailed || captured[0].Data["reason"] != "process 12345 exited" {
+		t.Errorf("agent_failed = %+v", captured[0])
+	}
+}
+
+func TestEventsQueryReturnsJournaledEvents(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.eventBus.Emit(events.NewAgentStartedEvent("repo-a", "swift-eagle", "worker", "Fix bug"))
+	d.eventBus.Emit(events.NewWorkerStuckEvent("repo-a", "swift-eagle", 30))
+	d.eventBus.Emit(events.NewAgentStartedEvent("repo-b", "calm-fox", "worker", "Add docs"))
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantCount int
+	}{
+		{"all", map[string]interface{}{}, 3},
+		{"by repo", map[string]interface{}{"repo": "repo-a"}, 2},
+		{"by agent and type", map[string]interface{}{"agent": "swift-eagle", "type": "worker_stuck"}, 1},
+		{"multiple types", map[string]interface{}{"type": "agent_started,worker_stuck"}, 3},
+		{"limit", map[string]interface{}{"limit": float64(1)}, 1},
+		{"since in future", map[string]interface{}{"since": time.Now().Add(time.Hour).Format(time.RFC3339)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.handleRequest(socket.Request{Command: "events_query", Args: tt.args})
+			if !resp.Success {
+				t.Fatalf("events_query failed: %s", resp.Error)
+			}
+			got, ok := resp.Data.([]events.Event)
+			if !ok {
+				t.Fatalf("Data type = %T, want []events.Event", resp.Data)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("got %d events, want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "events_query", Args: map[string]interface{}{"since": "yesterday"}})
+	if resp.Success {
+		t.Error("events_query should reject an invalid since timestamp")
+	}
+}
+
+func TestRotateJournalIfNeeded(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	journalPath := d.paths.EventsJournal()
+	big := make([]byte, events.DefaultJournalMaxSize+1)
+	if err := os.WriteFile(journalPath, big, 0644); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	d.rotateLogsIfNeeded()
+
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Error("oversized journal should have been rotated away")
+	}
+	rotated, _ := filepath.Glob(journalPath + ".*")
+	if len(rotated) != 1 {
+		t.Fatalf("found %d rotated journals, want 1", len(rotated))
+	}
+
+	// New events go to a fresh journal
+	d.eventBus.Emit(events.NewAgentStartedEvent("repo", "worker", "worker", ""))
+	info, err := os.Stat(journalPath)
+	if err != nil {
+		t.Fatalf("journal should be recreated on next emit: %v", err)
+	}
+	if info.Size() >= events.DefaultJournalMaxSize {
+		t.Error("new journal should be small")
+	}
+}
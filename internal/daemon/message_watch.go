@@ -0,0 +1,241 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// messageWatchDebounce batches the create and write events of a single
+// message file (and bursts of messages) into one delivery pass per agent
+const messageWatchDebounce = 100 * time.Millisecond
+
+// messageRetryBuffer is how many retry requests can wait for the message
+// watcher. Requests beyond it are dropped and left to the router's poll.
+const messageRetryBuffer = 64
+
+// messageTarget identifies an agent whose messages need delivering
+type messageTarget struct {
+	repo  string
+	agent string
+}
+
+// messageRetry asks the message watcher to deliver an agent's messages again
+// once a failed delivery's backoff ends
+type messageRetry struct {
+	target messageTarget
+	at     time.Time
+}
+
+// scheduleMessageRetry asks the message watcher to retry an agent's messages
+// at the given time. It never blocks; if the watcher isn't running, the
+// router's poll retries instead.
+func (d *Daemon) scheduleMessageRetry(repoName, agentName string, at time.Time) {
+	select {
+	case d.messageRetries <- messageRetry{target: messageTarget{repo: repoName, agent: agentName}, at: at}:
+	default:
+	}
+}
+
+// rewatchMessages asks the message watcher to drop its watches and walk the
+// messages directory again, e.g. after the directory was replaced. It never
+// blocks.
+func (d *Daemon) rewatchMessages() {
+	select {
+	case d.messageRewatch <- struct{}{}:
+	default:
+	}
+}
+
+// messageWatchLoop watches the messages directory and delivers new messages
+// as soon as they are written, and retries failed deliveries when their
+// backoff ends. The router loop keeps polling as a fallback, so if the
+// watcher can't be set up, delivery just falls back to the poll.
+func (d *Daemon) messageWatchLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting message watch loop")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		d.logger.Warn("Failed to create message watcher, relying on polling: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Agents whose message directories changed, waiting for the debounce timer
+	pending := make(map[string]messageTarget) // repo/agent -> target
+	debounce := time.NewTimer(messageWatchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	// Agents whose messages are backing off, retried by the retry timer
+	retries := make(map[string]messageRetry) // repo/agent -> earliest retry
+	retryTimer := time.NewTimer(0)
+	retryTimer.Stop()
+	defer retryTimer.Stop()
+
+	// Directories are watched individually, so new repo and agent
+	// directories are added as they appear
+	if d.watchMessageDir(watcher, d.paths.MessagesDir, pending) {
+		debounce.Reset(messageWatchDebounce)
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			if d.handleMessageEvent(watcher, event, pending) {
+				debounce.Reset(messageWatchDebounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			d.logger.Warn("Message watcher error: %v", err)
+
+		case <-debounce.C:
+			for key, target := range pending {
+				delete(pending, key)
+				d.routeAgentMessages(target.repo, target.agent)
+			}
+
+		case <-d.messageRewatch:
+			// The watched directories may be gone; late events for their
+			// old watches are dropped once the watches are removed
+			for _, path := range watcher.WatchList() {
+				watcher.Remove(path)
+			}
+			if d.watchMessageDir(watcher, d.paths.MessagesDir, pending) {
+				debounce.Reset(messageWatchDebounce)
+			}
+
+		case retry := <-d.messageRetries:
+			key := agentKey(retry.target.repo, retry.target.agent)
+			if current, ok := retries[key]; !ok || retry.at.Before(current.at) {
+				retries[key] = retry
+				resetRetryTimer(retryTimer, retries)
+			}
+
+		case <-retryTimer.C:
+			now := time.Now()
+			for key, retry := range retries {
+				if !retry.at.After(now) {
+					delete(retries, key)
+					d.routeAgentMessages(retry.target.repo, retry.target.agent)
+				}
+			}
+			resetRetryTimer(retryTimer, retries)
+
+		case <-d.ctx.Done():
+			d.logger.Info("message watch loop stopped")
+			return
+		}
+	}
+}
+
+// resetRetryTimer sets the retry timer to fire at the earliest scheduled retry
+func resetRetryTimer(timer *time.Timer, retries map[string]messageRetry) {
+	timer.Stop()
+	var earliest time.Time
+	for _, retry := range retries {
+		if earliest.IsZero() || retry.at.Before(earliest) {
+			earliest = retry.at
+		}
+	}
+	if !earliest.IsZero() {
+		timer.Reset(time.Until(earliest))
+	}
+}
+
+// handleMessageEvent records the agent affected by a filesystem event and
+// returns true if a delivery pass should be scheduled
+func (d *Daemon) handleMessageEvent(watcher *fsnotify.Watcher, event fsnotify.Event, pending map[string]messageTarget) bool {
+	rel, err := filepath.Rel(d.paths.MessagesDir, event.Name)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if strings.HasPrefix(parts[0], ".") {
+		// Not a repository, e.g. the dead-letter folder
+		return false
+	}
+
+	switch len(parts) {
+	case 1, 2:
+		// A new repo or agent directory. Files may have been written before
+		// the watch was added, so it is scanned as well.
+		if event.Op&fsnotify.Create == 0 {
+			return false
+		}
+		if info, err := os.Stat(event.Name); err != nil || !info.IsDir() {
+			return false
+		}
+		return d.watchMessageDir(watcher, event.Name, pending)
+	case 3:
+		if filepath.Ext(parts[2]) != ".json" {
+			return false
+		}
+		pending[agentKey(parts[0], parts[1])] = messageTarget{repo: parts[0], agent: parts[1]}
+		return true
+	}
+	return false
+}
+
+// watchMessageDir adds watches for a messages directory and the repo and
+// agent directories below it, queueing every agent directory it finds.
+// It returns true if any agent was queued.
+func (d *Daemon) watchMessageDir(watcher *fsnotify.Watcher, dir string, pending map[string]messageTarget) bool {
+	queued := false
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(d.paths.MessagesDir, path)
+		if err != nil {
+			return nil
+		}
+		depth := 0
+		if rel != "." {
+			depth = len(strings.Split(rel, string(filepath.Separator)))
+		}
+		if depth > 2 || (depth > 0 && strings.HasPrefix(entry.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			d.logger.Warn("Failed to watch messages directory %s: %v", path, err)
+			return nil
+		}
+		if depth == 2 {
+			parts := strings.Split(rel, string(filepath.Separator))
+			pending[agentKey(parts[0], parts[1])] = messageTarget{repo: parts[0], agent: parts[1]}
+			queued = true
+		}
+		return nil
+	})
+	if err != nil {
+		d.logger.Warn("Failed to scan messages directory %s: %v", dir, err)
+	}
+	return queued
+}
+
+// routeAgentMessages delivers pending messages for a single agent
+func (d *Daemon) routeAgentMessages(repoName, agentName string) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return
+	}
+	d.deliverPendingMessages(repoName, repo.TmuxSession, agentName, agent)
+}
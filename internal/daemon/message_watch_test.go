@@ -0,0 +1,238 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// messageDeliveryTimeout is the latency the message watcher must deliver within
+const messageDeliveryTimeout = time.Second
+
+// startMessageWatcher runs the message watch loop until the returned stop func is called
+func startMessageWatcher(d *Daemon) (stop func()) {
+	d.wg.Add(1)
+	go d.messageWatchLoop()
+	return func() {
+		d.cancel()
+		d.wg.Wait()
+	}
+}
+
+// setupWatchTestAgents creates a tmux session with a window per agent and registers them
+func setupWatchTestAgents(t *testing.T, d *Daemon, sessionName string, agents map[string]state.AgentType) {
+	t.Helper()
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	// Note: In CI environments, tmux may be installed but unable to create sessions (no TTY)
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	t.Cleanup(func() { tmuxClient.KillSession(context.Background(), sessionName) })
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	for name, agentType := range agents {
+		if err := tmuxClient.CreateWindow(context.Background(), sessionName, name); err != nil {
+			t.Fatalf("Failed to create %s window: %v", name, err)
+		}
+		agent := state.Agent{Type: agentType, TmuxWindow: name, CreatedAt: time.Now()}
+		if err := d.state.AddAgent("test-repo", name, agent); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+}
+
+// waitForDelivery waits for a message to be delivered and returns how long it took
+func waitForDelivery(t *testing.T, msgMgr *messages.Manager, agentName string, msg *messages.Message) time.Duration {
+	t.Helper()
+	deadline := time.Now().Add(messageDeliveryTimeout)
+	for time.Now().Before(deadline) {
+		got, err := msgMgr.Get("test-repo", agentName, msg.ID)
+		if err == nil && got.Status == messages.StatusDelivered {
+			return time.Since(msg.Timestamp)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("message %s to %s was not delivered within %s", msg.ID, agentName, messageDeliveryTimeout)
+	return 0
+}
+
+func TestMessageWatcherDeliversNewMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	setupWatchTestAgents(t, d, "mc-test-watch", map[string]state.AgentType{
+		"supervisor": state.AgentTypeSupervisor,
+		"worker1":    state.AgentTypeWorker,
+	})
+
+	stop := startMessageWatcher(d)
+	defer stop()
+
+	msgMgr := d.getMessageManager()
+
+	// The first message creates the repo and agent directories
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "Hello worker!")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	t.Logf("first message delivered in %s", waitForDelivery(t, msgMgr, "worker1", msg))
+
+	// Later messages land in an already-watched directory
+	msg, err = msgMgr.Send("test-repo", "supervisor", "worker1", "Another one")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	t.Logf("second message delivered in %s", waitForDelivery(t, msgMgr, "worker1", msg))
+
+	// A new agent directory in an existing repo directory
+	msg, err = msgMgr.Send("test-repo", "worker1", "supervisor", "Done")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	t.Logf("reply delivered in %s", waitForDelivery(t, msgMgr, "supervisor", msg))
+}
+
+func TestMessageWatcherDeliversMessagesSentBeforeStart(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	setupWatchTestAgents(t, d, "mc-test-watch-backlog", map[string]state.AgentType{
+		"worker1": state.AgentTypeWorker,
+	})
+
+	msgMgr := d.getMessageManager()
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "Sent while the daemon was down")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	msg.Timestamp = time.Now()
+
+	stop := startMessageWatcher(d)
+	defer stop()
+
+	waitForDelivery(t, msgMgr, "worker1", msg)
+}
+
+func TestMessageWatcherDeliversAfterSnapshotRestore(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	setupWatchTestAgents(t, d, "mc-test-watch-restore", map[string]state.AgentType{
+		"supervisor": state.AgentTypeSupervisor,
+		"worker1":    state.AgentTypeWorker,
+	})
+
+	stop := startMessageWatcher(d)
+	defer stop()
+
+	msgMgr := d.getMessageManager()
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "Before the restore")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	waitForDelivery(t, msgMgr, "worker1", msg)
+	// Let the pass the delivery's status write schedules run first, so it
+	// can't deliver the next message
+	time.Sleep(3 * messageWatchDebounce)
+
+	snap, err := d.takeSnapshot(snapshot.ReasonPeriodic)
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	resp := d.handleRequest(socket.Request{Command: "restore_snapshot", Args: map[string]interface{}{"id": snap.ID}})
+	if !resp.Success {
+		t.Fatalf("restore_snapshot failed: %s", resp.Error)
+	}
+
+	// The restore replaced the watched directories
+	msg, err = msgMgr.Send("test-repo", "supervisor", "worker1", "After the restore")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	waitForDelivery(t, msgMgr, "worker1", msg)
+}
+
+func TestMessageWatcherSkipsWorkspace(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	setupWatchTestAgents(t, d, "mc-test-watch-workspace", map[string]state.AgentType{
+		"workspace": state.AgentTypeWorkspace,
+		"worker1":   state.AgentTypeWorker,
+	})
+
+	stop := startMessageWatcher(d)
+	defer stop()
+
+	msgMgr := d.getMessageManager()
+	skipped, err := msgMgr.Send("test-repo", "supervisor", "workspace", "Not for the workspace pane")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	delivered, err := msgMgr.Send("test-repo", "supervisor", "worker1", "For the worker")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	waitForDelivery(t, msgMgr, "worker1", delivered)
+
+	got, err := msgMgr.Get("test-repo", "workspace", skipped.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if got.Status != messages.StatusPending {
+		t.Errorf("workspace message status = %s, want pending", got.Status)
+	}
+}
+
+func TestMessageWatcherRetriesWhenBackoffEnds(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	setupWatchTestAgents(t, d, "mc-test-watch-retry", map[string]state.AgentType{
+		"worker1": state.AgentTypeWorker,
+	})
+
+	// A message backing off after a failed attempt, due shortly
+	msgMgr := d.getMessageManager()
+	msg, err := msgMgr.Send("test-repo", "supervisor", "worker1", "Try again")
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	retryAt := time.Now().Add(300 * time.Millisecond)
+	msg.Attempts = 1
+	msg.NextAttemptAt = &retryAt
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.paths.MessagesDir, "test-repo", "worker1", msg.ID+".json"), data, 0644); err != nil {
+		t.Fatalf("Failed to rewrite message: %v", err)
+	}
+
+	stop := startMessageWatcher(d)
+	defer stop()
+
+	// Delivered when the backoff ends, not on the router's next poll
+	msg.Timestamp = time.Now()
+	waitForDelivery(t, msgMgr, "worker1", msg)
+	if time.Now().Before(retryAt) {
+		t.Error("message was delivered before its backoff ended")
+	}
+}
@@ -0,0 +1,326 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// overlapCheckInterval is how often workers' changed files are compared
+const overlapCheckInterval = 5 * time.Minute
+
+// maxOverlapFilesListed caps the files listed in overlap messages
+const maxOverlapFilesListed = 10
+
+// workerOverlap is a set of files a worker and another active worker both changed
+type workerOverlap struct {
+	Worker    string   // The other worker
+	Files     []string // Files both workers changed since their base
+	Conflicts []string // Files predicted to conflict if both branches merge
+}
+
+// overlapPair is an overlap between two workers, a before b
+type overlapPair struct {
+	a, b      string
+	files     []string
+	conflicts []string
+	// conflictsUnknown is set if conflicts couldn't be predicted this time
+	conflictsUnknown bool
+}
+
+// overlapLoop periodically looks for workers changing the same files
+func (d *Daemon) overlapLoop() {
+	d.periodicLoop("overlap", overlapCheckInterval, nil, d.checkOverlaps)
+}
+
+// TriggerOverlapCheck triggers an immediate overlap check (for testing)
+func (d *Daemon) TriggerOverlapCheck() {
+	d.checkOverlaps()
+}
+
+// checkOverlaps compares the files changed by each repository's active
+// workers and warns the supervisor and both workers when two of them start
+// changing the same files, or when their changes start to conflict. Which
+// overlaps were warned about is kept in state, so restarts don't repeat
+// warnings, and workers whose changes can't be listed keep their overlaps
+// from the last check.
+func (d *Daemon) checkOverlaps() {
+	d.logger.Debug("Checking worker file overlaps")
+
+	d.overlapMu.Lock()
+	previous := d.overlaps
+	d.overlapMu.Unlock()
+
+	current := make(map[string][]workerOverlap)
+	add := func(repoName, a, b string, overlap workerOverlap) {
+		current[agentKey(repoName, a)] = append(current[agentKey(repoName, a)],
+			workerOverlap{Worker: b, Files: overlap.Files, Conflicts: overlap.Conflicts})
+		current[agentKey(repoName, b)] = append(current[agentKey(repoName, b)],
+			workerOverlap{Worker: a, Files: overlap.Files, Conflicts: overlap.Conflicts})
+	}
+
+	for repoName, repo := range d.state.GetAllRepos() {
+		pairs, unknown := d.findOverlaps(repoName, repo)
+
+		// worker -> other worker -> predicted to conflict
+		notified := make(map[string]map[string]bool)
+		record := func(a, b string, conflicts bool) {
+			for _, w := range [][2]string{{a, b}, {b, a}} {
+				if notified[w[0]] == nil {
+					notified[w[0]] = make(map[string]bool)
+				}
+				notified[w[0]][w[1]] = conflicts
+			}
+		}
+
+		// Overlaps of workers whose changes couldn't be listed stay as they were
+		for name, agent := range repo.Agents {
+			for other, conflicts := range agent.NotifiedOverlaps {
+				if _, exists := repo.Agents[other]; exists && (unknown[name] || unknown[other]) {
+					record(name, other, conflicts)
+				}
+			}
+		}
+		for name := range unknown {
+			for _, overlap := range previous[agentKey(repoName, name)] {
+				// An overlap between two such workers is carried by both; add it once
+				if _, exists := repo.Agents[overlap.Worker]; !exists || (unknown[overlap.Worker] && overlap.Worker < name) {
+					continue
+				}
+				add(repoName, name, overlap.Worker, overlap)
+			}
+		}
+
+		for _, pair := range pairs {
+			told, wasNotified := repo.Agents[pair.a].NotifiedOverlaps[pair.b]
+			if pair.conflictsUnknown {
+				// Keep what the last check found rather than warning again once
+				// conflicts can be predicted
+				if prev, ok := d.getOverlap(repoName, pair.a, pair.b); ok {
+					pair.conflicts = prev.Conflicts
+				}
+			}
+			conflicts := len(pair.conflicts) > 0 || (pair.conflictsUnknown && told)
+			if !wasNotified || (conflicts && !told) {
+				d.notifyOverlap(repoName, pair)
+			}
+			record(pair.a, pair.b, conflicts)
+			add(repoName, pair.a, pair.b, workerOverlap{Files: pair.files, Conflicts: pair.conflicts})
+		}
+
+		for name, agent := range repo.Agents {
+			if agent.Type != state.AgentTypeWorker || sameOverlaps(agent.NotifiedOverlaps, notified[name]) {
+				continue
+			}
+			if err := d.state.SetAgentNotifiedOverlaps(repoName, name, notified[name]); err != nil {
+				d.logger.Error("Failed to record overlaps for %s/%s: %v", repoName, name, err)
+			}
+		}
+	}
+
+	for _, overlaps := range current {
+		sort.Slice(overlaps, func(i, j int) bool { return overlaps[i].Worker < overlaps[j].Worker })
+	}
+
+	d.overlapMu.Lock()
+	d.overlaps = current
+	d.overlapMu.Unlock()
+}
+
+// sameOverlaps returns true if two notified overlap sets are equal
+func sameOverlaps(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for worker, conflicts := range a {
+		if other, ok := b[worker]; !ok || other != conflicts {
+			return false
+		}
+	}
+	return true
+}
+
+// findOverlaps returns the pairs of active workers in a repository whose
+// branches changed the same files, ordered by worker name, and the active
+// workers whose changes couldn't be listed
+func (d *Daemon) findOverlaps(repoName string, repo *state.Repository) ([]overlapPair, map[string]bool) {
+	repoPath := d.paths.RepoDir(repoName)
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	wt := worktree.NewManager(repoPath)
+
+	var names []string
+	for name, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorker && !agent.ReadyForCleanup && agent.WorktreePath != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	unknown := make(map[string]bool)
+	allUnknown := func() ([]overlapPair, map[string]bool) {
+		for _, name := range names {
+			unknown[name] = true
+		}
+		return nil, unknown
+	}
+
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		d.logger.Debug("Could not get remote for %s: %v", repoName, err)
+		return allUnknown()
+	}
+	defaultBranch := repo.DefaultBranch
+	if defaultBranch == "" {
+		if defaultBranch, err = wt.GetDefaultBranch(remote); err != nil {
+			d.logger.Debug("Could not get default branch for %s: %v", repoName, err)
+			return allUnknown()
+		}
+	}
+
+	type workerChanges struct {
+		name   string
+		branch string
+		files  map[string]bool
+	}
+	var workers []workerChanges
+
+	for _, name := range names {
+		agent := repo.Agents[name]
+		branch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+		if err != nil {
+			d.logger.Debug("Could not get branch for %s/%s: %v", repoName, name, err)
+			unknown[name] = true
+			continue
+		}
+		base := agent.BaseBranch
+		if base == "" {
+			base = defaultBranch
+		}
+		baseRef, err := wt.ResolveStartPoint(remote, base)
+		if err != nil {
+			d.logger.Debug("Could not resolve base %s for %s/%s: %v", base, repoName, name, err)
+			unknown[name] = true
+			continue
+		}
+		files, err := wt.ChangedFiles(branch, baseRef)
+		if err != nil {
+			d.logger.Debug("Could not list changed files for %s/%s: %v", repoName, name, err)
+			unknown[name] = true
+			continue
+		}
+		if len(files) == 0 {
+			continue
+		}
+		changed := make(map[string]bool, len(files))
+		for _, f := range files {
+			changed[f] = true
+		}
+		workers = append(workers, workerChanges{name: name, branch: branch, files: changed})
+	}
+
+	var pairs []overlapPair
+	for i, a := range workers {
+		for _, b := range workers[i+1:] {
+			var shared []string
+			for f := range a.files {
+				if b.files[f] {
+					shared = append(shared, f)
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+			sort.Strings(shared)
+
+			conflicts, err := wt.PredictConflicts(a.branch, b.branch)
+			if err != nil {
+				d.logger.Debug("Could not predict conflicts between %s and %s: %v", a.name, b.name, err)
+			}
+			pairs = append(pairs, overlapPair{a: a.name, b: b.name, files: shared, conflicts: conflicts, conflictsUnknown: err != nil})
+		}
+	}
+	return pairs, unknown
+}
+
+// getOverlap returns the overlap between two workers found by the last check
+func (d *Daemon) getOverlap(repoName, workerA, workerB string) (workerOverlap, bool) {
+	for _, overlap := range d.getWorkerOverlaps(repoName, workerA) {
+		if overlap.Worker == workerB {
+			return overlap, true
+		}
+	}
+	return workerOverlap{}, false
+}
+
+// getWorkerOverlaps returns a worker's overlaps with other workers found by
+// the last check
+func (d *Daemon) getWorkerOverlaps(repoName, agentName string) []workerOverlap {
+	d.overlapMu.Lock()
+	defer d.overlapMu.Unlock()
+	return d.overlaps[agentKey(repoName, agentName)]
+}
+
+// notifyOverlap messages both workers and the supervisor about an overlap
+func (d *Daemon) notifyOverlap(repoName string, pair overlapPair) {
+	if len(pair.conflicts) > 0 {
+		d.logger.Warn("Workers %s and %s in repo %s are predicted to conflict in: %v", pair.a, pair.b, repoName, pair.conflicts)
+	} else {
+		d.logger.Info("Workers %s and %s in repo %s changed the same files: %v", pair.a, pair.b, repoName, pair.files)
+	}
+
+	msgMgr := d.getMessageManager()
+	for _, worker := range [][2]string{{pair.a, pair.b}, {pair.b, pair.a}} {
+		msg := fmt.Sprintf("Heads up: worker %s has also changed %s you changed:\n%s",
+			worker[1], pluralFiles(len(pair.files)), formatFileList(pair.files))
+		if len(pair.conflicts) > 0 {
+			msg += fmt.Sprintf("\n\nYour branches are predicted to conflict in:\n%s\n\n"+
+				"Coordinate with them (multiclaude agent send-message %s \"...\") or keep your changes to these files minimal.",
+				formatFileList(pair.conflicts), worker[1])
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", worker[0], msg); err != nil {
+			d.logger.Error("Failed to notify worker %s of overlap: %v", worker[0], err)
+		}
+	}
+
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	msg := fmt.Sprintf("Workers %s and %s have both changed %s:\n%s",
+		pair.a, pair.b, pluralFiles(len(pair.files)), formatFileList(pair.files))
+	if len(pair.conflicts) > 0 {
+		msg += fmt.Sprintf("\n\nTheir PRs are predicted to conflict in:\n%s\n\n"+
+			"Consider having one of them wait for the other's PR to merge, or narrowing one task.",
+			formatFileList(pair.conflicts))
+	}
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to notify supervisor of overlap: %v", err)
+	}
+}
+
+// pluralFiles returns "1 file" or "N files"
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// formatFileList formats files as an indented list, capped at
+// maxOverlapFilesListed
+func formatFileList(files []string) string {
+	var b strings.Builder
+	for i, f := range files {
+		if i == maxOverlapFilesListed {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(files)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s\n", f)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// addOverlapWorker creates a worker worktree on a new branch, commits files
+// to it and registers the worker
+func addOverlapWorker(t *testing.T, d *Daemon, repoDir, name string, files map[string]string) {
+	t.Helper()
+	wtPath := filepath.Join(d.paths.WorktreesDir, "test-repo", name)
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	run(repoDir, "worktree", "add", "-q", "-b", "work/"+name, wtPath, "main")
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(wtPath, file), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+		run(wtPath, "add", file)
+	}
+	run(wtPath, "commit", "-q", "-m", "Work on "+name)
+
+	if err := d.state.AddAgent("test-repo", name, state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: wtPath,
+		TmuxWindow:   name,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+}
+
+func TestCheckOverlaps(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	cmd := exec.Command("git", "remote", "add", "origin", "https://github.com/test/repo")
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to add remote: %v", err)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     "https://github.com/test/repo",
+		TmuxSession:   "mc-test-repo",
+		DefaultBranch: "main",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	// calm-otter and swift-eagle rewrite README.md differently; swift-eagle
+	// and tidy-heron add the same util.go, which merges cleanly
+	addOverlapWorker(t, d, repoDir, "calm-otter", map[string]string{"README.md": "# Otter\n", "otter.go": "package otter\n"})
+	addOverlapWorker(t, d, repoDir, "swift-eagle", map[string]string{"README.md": "# Eagle\n", "util.go": "package util\n"})
+	addOverlapWorker(t, d, repoDir, "tidy-heron", map[string]string{"util.go": "package util\n"})
+	addOverlapWorker(t, d, repoDir, "lone-wolf", map[string]string{"wolf.go": "package wolf\n"})
+
+	d.TriggerOverlapCheck()
+
+	got := d.getWorkerOverlaps("test-repo", "swift-eagle")
+	want := []workerOverlap{
+		{Worker: "calm-otter", Files: []string{"README.md"}, Conflicts: []string{"README.md"}},
+		{Worker: "tidy-heron", Files: []string{"util.go"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("swift-eagle overlaps = %+v, want %+v", got, want)
+	}
+	if got := d.getWorkerOverlaps("test-repo", "lone-wolf"); len(got) != 0 {
+		t.Errorf("lone-wolf overlaps = %+v, want none", got)
+	}
+
+	msgMgr := d.getMessageManager()
+	countMessages := func(agent string) int {
+		msgs, err := msgMgr.List("test-repo", agent)
+		if err != nil {
+			t.Fatalf("Failed to list messages for %s: %v", agent, err)
+		}
+		return len(msgs)
+	}
+	for agent, want := range map[string]int{"calm-otter": 1, "swift-eagle": 2, "tidy-heron": 1, "lone-wolf": 0, "supervisor": 2} {
+		if got := countMessages(agent); got != want {
+			t.Errorf("%s has %d message(s), want %d", agent, got, want)
+		}
+	}
+	otterMsgs, _ := msgMgr.List("test-repo", "calm-otter")
+	if len(otterMsgs) == 1 && (!strings.Contains(otterMsgs[0].Body, "swift-eagle") || !strings.Contains(otterMsgs[0].Body, "predicted to conflict")) {
+		t.Errorf("calm-otter message = %q, want the conflict with swift-eagle", otterMsgs[0].Body)
+	}
+	if len(otterMsgs) == 1 && !strings.Contains(otterMsgs[0].Body, `multiclaude agent send-message swift-eagle "..."`) {
+		t.Errorf("calm-otter message = %q, want a send-message command for swift-eagle", otterMsgs[0].Body)
+	}
+
+	// Overlaps that continue aren't reported again
+	d.TriggerOverlapCheck()
+	if got := countMessages("supervisor"); got != 2 {
+		t.Errorf("supervisor has %d message(s) after a second check, want 2", got)
+	}
+
+	// Nor after a restart, which loses the overlaps found so far
+	d.overlapMu.Lock()
+	d.overlaps = nil
+	d.overlapMu.Unlock()
+	d.TriggerOverlapCheck()
+	if got := countMessages("supervisor"); got != 2 {
+		t.Errorf("supervisor has %d message(s) after a restart, want 2", got)
+	}
+
+	// A worker whose changes can't be listed keeps its overlaps
+	heron, _ := d.state.GetAgent("test-repo", "tidy-heron")
+	if err := os.Rename(heron.WorktreePath, heron.WorktreePath+".moved"); err != nil {
+		t.Fatalf("Failed to move worktree: %v", err)
+	}
+	d.TriggerOverlapCheck()
+	if got := d.getWorkerOverlaps("test-repo", "swift-eagle"); !reflect.DeepEqual(got, want) {
+		t.Errorf("swift-eagle overlaps while tidy-heron is unreadable = %+v, want %+v", got, want)
+	}
+	if err := os.Rename(heron.WorktreePath+".moved", heron.WorktreePath); err != nil {
+		t.Fatalf("Failed to restore worktree: %v", err)
+	}
+	d.TriggerOverlapCheck()
+	if got := countMessages("supervisor"); got != 2 {
+		t.Errorf("supervisor has %d message(s) after tidy-heron is readable again, want 2", got)
+	}
+
+	// A completed worker no longer counts
+	otter, _ := d.state.GetAgent("test-repo", "calm-otter")
+	otter.ReadyForCleanup = true
+	if err := d.state.UpdateAgent("test-repo", "calm-otter", otter); err != nil {
+		t.Fatalf("Failed to update agent: %v", err)
+	}
+	d.TriggerOverlapCheck()
+	if got := d.getWorkerOverlaps("test-repo", "calm-otter"); len(got) != 0 {
+		t.Errorf("completed worker overlaps = %+v, want none", got)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "test-repo", "rich": true}})
+	if !resp.Success {
+		t.Fatalf("list_agents failed: %s", resp.Error)
+	}
+	for _, detail := range resp.Data.([]map[string]interface{}) {
+		if detail["name"] != "tidy-heron" {
+			continue
+		}
+		overlaps, _ := detail["overlaps"].([]map[string]interface{})
+		if len(overlaps) != 1 || overlaps[0]["worker"] != "swift-eagle" {
+			t.Errorf("tidy-heron overlaps = %v, want one with swift-eagle", detail["overlaps"])
+		}
+	}
+}
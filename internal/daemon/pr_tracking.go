@@ -0,0 +1,222 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/forge"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// prCheckInterval is how often the PRs of workers and completed tasks are
+// looked up
+const prCheckInterval = 5 * time.Minute
+
+// prTrackingWindow is how long after a task completes its branch is looked
+// up before it's recorded as having no PR
+const prTrackingWindow = 7 * 24 * time.Hour
+
+// prTrackingLoop periodically looks up the PRs of running workers' and
+// completed tasks' branches and records their status
+func (d *Daemon) prTrackingLoop() {
+	d.periodicLoop("PR tracking", prCheckInterval, nil, d.checkTaskPRs)
+}
+
+// forgeFor returns the forge hosting a repo's PRs: the local forge for repos
+// cloned from a path, GitHub otherwise
+func (d *Daemon) forgeFor(repoName string, repo *state.Repository) forge.Forge {
+	return forge.New(repo.GithubURL, d.paths.RepoDir(repoName), d.github)
+}
+
+// TriggerPRCheck triggers an immediate PR lookup (for testing)
+func (d *Daemon) TriggerPRCheck() {
+	d.checkTaskPRs()
+}
+
+// checkTaskPRs looks up the PR of every running worker and every task whose
+// PR may still change. Only the most recent task with a given name is
+// tracked, since that's the one status updates apply to.
+func (d *Daemon) checkTaskPRs() {
+	now := time.Now()
+	for repoName, repo := range d.state.GetAllRepos() {
+		repoPath := d.paths.RepoDir(repoName)
+		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+			continue
+		}
+
+		f := d.forgeFor(repoName, repo)
+		merged := false
+		seen := make(map[string]bool)
+
+		// A running worker is the most recent task with its name
+		for name, agent := range repo.Agents {
+			if agent.Type != state.AgentTypeWorker || agent.WorktreePath == "" {
+				continue
+			}
+			seen[name] = true
+			branch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+			if err != nil {
+				d.logger.Debug("Failed to get branch for %s/%s: %v", repoName, name, err)
+				continue
+			}
+			if d.trackTaskPR(f, repoName, workerPREntry(name, agent, branch), now, true) {
+				merged = true
+			}
+		}
+
+		for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+			entry := repo.TaskHistory[i]
+			if seen[entry.Name] {
+				continue
+			}
+			seen[entry.Name] = true
+			if !needsPRCheck(entry) {
+				continue
+			}
+			if d.trackTaskPR(f, repoName, entry, now, false) {
+				merged = true
+			}
+		}
+
+		// Merged PRs may unblock queued tasks
+		if merged {
+			d.startQueuedTasks(repoName)
+		}
+	}
+}
+
+// workerPREntry returns a running worker's PR as a task history entry, so it
+// is tracked like a completed task's
+func workerPREntry(name string, agent state.Agent, branch string) state.TaskHistoryEntry {
+	entry := state.TaskHistoryEntry{
+		Name:      name,
+		Task:      agent.Task,
+		Branch:    branch,
+		Status:    state.TaskStatusUnknown,
+		CreatedAt: agent.CreatedAt,
+	}
+	if agent.PR != nil {
+		entry.PRNumber = agent.PR.Number
+		entry.PRURL = agent.PR.URL
+		entry.Status = agent.PR.Status
+		entry.CIStatus = agent.PR.CIStatus
+		entry.ReviewStatus = agent.PR.ReviewStatus
+	}
+	return entry
+}
+
+// needsPRCheck reports whether a task's PR may still change. Merged, closed
+// and failed tasks, and tasks already recorded as having no PR, are final.
+func needsPRCheck(entry state.TaskHistoryEntry) bool {
+	if entry.Branch == "" {
+		return false
+	}
+	switch entry.Status {
+	case state.TaskStatusMerged, state.TaskStatusClosed, state.TaskStatusFailed, state.TaskStatusNoPR:
+		return false
+	}
+	return true
+}
+
+// trackTaskPR looks up the PR for a task's branch and records any change in
+// its status, CI or reviews, emitting events for the transitions. A running
+// worker's PR (live) is recorded on the worker rather than in task history.
+// It returns whether the PR was just merged.
+func (d *Daemon) trackTaskPR(f forge.Forge, repoName string, entry state.TaskHistoryEntry, now time.Time, live bool) bool {
+	pr, err := f.PRForBranch(entry.Branch)
+	if err != nil {
+		d.logger.Debug("Failed to look up PR for %s/%s (%s): %v", repoName, entry.Name, entry.Branch, err)
+		return false
+	}
+
+	if pr == nil {
+		if !live && entry.Status != state.TaskStatusOpen && !entry.CompletedAt.IsZero() && now.Sub(entry.CompletedAt) > prTrackingWindow {
+			if err := d.state.UpdateTaskHistoryStatus(repoName, entry.Name, state.TaskStatusNoPR, "", 0); err != nil {
+				d.logger.Error("Failed to update task history for %s/%s: %v", repoName, entry.Name, err)
+			}
+		}
+		return false
+	}
+
+	status := taskStatusForPR(pr.State)
+	statusChanged := status != entry.Status || pr.URL != entry.PRURL || pr.Number != entry.PRNumber
+	checksChanged := pr.CIStatus != entry.CIStatus || pr.ReviewDecision != entry.ReviewStatus
+
+	if live {
+		if !statusChanged && !checksChanged {
+			return false
+		}
+		if err := d.state.SetAgentPR(repoName, entry.Name, state.AgentPR{
+			Number:       pr.Number,
+			URL:          pr.URL,
+			Status:       status,
+			CIStatus:     pr.CIStatus,
+			ReviewStatus: pr.ReviewDecision,
+		}); err != nil {
+			d.logger.Error("Failed to record PR for %s/%s: %v", repoName, entry.Name, err)
+			return false
+		}
+	}
+
+	if statusChanged {
+		if !live {
+			if err := d.state.UpdateTaskHistoryStatus(repoName, entry.Name, status, pr.URL, pr.Number); err != nil {
+				d.logger.Error("Failed to update task history for %s/%s: %v", repoName, entry.Name, err)
+				return false
+			}
+		}
+		d.logger.Info("PR #%d for %s/%s is %s", pr.Number, repoName, entry.Name, status)
+		d.emitTaskStatusTransition(repoName, entry, status, pr.URL, pr.Number)
+	}
+
+	if checksChanged {
+		if !live {
+			if err := d.state.UpdateTaskHistoryChecks(repoName, entry.Name, pr.CIStatus, pr.ReviewDecision); err != nil {
+				d.logger.Error("Failed to update task history checks for %s/%s: %v", repoName, entry.Name, err)
+			}
+		}
+		d.emitPRCITransition(repoName, entry, pr)
+	}
+
+	return status == state.TaskStatusMerged && entry.Status != state.TaskStatusMerged
+}
+
+// emitPRCITransition emits ci_failed or ci_passed when a task's PR checks
+// change status. Unlike a CI layer, a PR found already passing is reported:
+// its checks are news to whoever is waiting on the PR.
+func (d *Daemon) emitPRCITransition(repoName string, prev state.TaskHistoryEntry, pr *forge.PR) {
+	if pr.CIStatus == prev.CIStatus {
+		return
+	}
+
+	var event events.Event
+	switch pr.CIStatus {
+	case forge.CIFailing:
+		event = events.NewCIFailedEvent(repoName, pr.Number, strings.Join(pr.FailedChecks, ", "))
+	case forge.CIPassing:
+		event = events.NewCIPassedEvent(repoName, pr.Number, "")
+	default:
+		return
+	}
+
+	event.AgentName = prev.Name
+	event.Data["url"] = pr.URL
+	d.eventBus.Emit(event)
+}
+
+// taskStatusForPR maps a PR state to a task status
+func taskStatusForPR(prState string) state.TaskStatus {
+	switch prState {
+	case forge.StateOpen:
+		return state.TaskStatusOpen
+	case forge.StateMerged:
+		return state.TaskStatusMerged
+	case forge.StateClosed:
+		return state.TaskStatusClosed
+	default:
+		return state.TaskStatusUnknown
+	}
+}
@@ -0,0 +1,285 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/forge"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// useFakeGh points the daemon's GitHub client at a gh script that prints
+// the PR list set for a branch with setPRs, or no PRs
+func useFakeGh(t *testing.T, d *Daemon) (setPRs func(branch, json string)) {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gh")
+	script := "#!/bin/sh\n" +
+		"# gh pr list --head <branch> ...\n" +
+		"f=\"" + dir + "/$(echo \"$4\" | tr / _).json\"\n" +
+		"if [ -f \"$f\" ]; then cat \"$f\"; else echo '[]'; fi\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	d.github = github.NewClient(bin)
+
+	return func(branch, json string) {
+		t.Helper()
+		path := filepath.Join(dir, strings.ReplaceAll(branch, "/", "_")+".json")
+		if err := os.WriteFile(path, []byte(json), 0644); err != nil {
+			t.Fatalf("Failed to write fake PRs: %v", err)
+		}
+	}
+}
+
+// journalEventTypes returns the types of the journaled events for the test repo
+func journalEventTypes(t *testing.T, d *Daemon) []events.EventType {
+	t.Helper()
+	journaled, err := d.journal.Query(events.Filter{RepoName: "test-repo"})
+	if err != nil {
+		t.Fatalf("Failed to query journal: %v", err)
+	}
+	var types []events.EventType
+	for _, e := range journaled {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestCheckTaskPRs(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	setPRs := useFakeGh(t, d)
+
+	now := time.Now()
+	for _, entry := range []state.TaskHistoryEntry{
+		{Name: "calm-otter", Task: "Add login\nDetails", Branch: "work/calm-otter", Status: state.TaskStatusUnknown, CompletedAt: now},
+		{Name: "old-heron", Task: "Old task", Branch: "work/old-heron", Status: state.TaskStatusUnknown, CompletedAt: now.Add(-2 * prTrackingWindow)},
+		{Name: "sad-wolf", Task: "Broken", Branch: "work/sad-wolf", Status: state.TaskStatusFailed, CompletedAt: now},
+	} {
+		if err := d.state.AddTaskHistory("test-repo", entry); err != nil {
+			t.Fatalf("Failed to add task history: %v", err)
+		}
+	}
+	// A failed task's branch isn't looked up even if it has a PR
+	setPRs("work/sad-wolf", `[{"number": 7, "url": "https://github.com/test/repo/pull/7", "state": "OPEN"}]`)
+
+	getEntry := func(name string) state.TaskHistoryEntry {
+		t.Helper()
+		history, _ := d.state.GetTaskHistory("test-repo", 0)
+		for _, entry := range history {
+			if entry.Name == name {
+				return entry
+			}
+		}
+		t.Fatalf("no task history for %s", name)
+		return state.TaskHistoryEntry{}
+	}
+
+	// No PR yet: recent tasks keep waiting, old ones have none
+	d.TriggerPRCheck()
+	if got := getEntry("calm-otter").Status; got != state.TaskStatusUnknown {
+		t.Errorf("calm-otter status = %s, want unknown while it has no PR", got)
+	}
+	if got := getEntry("old-heron").Status; got != state.TaskStatusNoPR {
+		t.Errorf("old-heron status = %s, want no-pr after the tracking window", got)
+	}
+
+	// The PR is opened with failing checks
+	setPRs("work/calm-otter", `[{"number": 42, "url": "https://github.com/test/repo/pull/42", "title": "Add login", "state": "OPEN",
+		"reviewDecision": "REVIEW_REQUIRED",
+		"statusCheckRollup": [{"name": "test", "status": "COMPLETED", "conclusion": "FAILURE"}]}]`)
+	d.TriggerPRCheck()
+	entry := getEntry("calm-otter")
+	if entry.Status != state.TaskStatusOpen || entry.PRNumber != 42 || entry.PRURL != "https://github.com/test/repo/pull/42" {
+		t.Errorf("calm-otter = %+v, want open PR #42", entry)
+	}
+	if entry.CIStatus != github.CIFailing || entry.ReviewStatus != "REVIEW_REQUIRED" {
+		t.Errorf("calm-otter checks = %q, %q, want failing, REVIEW_REQUIRED", entry.CIStatus, entry.ReviewStatus)
+	}
+	if got := getEntry("sad-wolf"); got.Status != state.TaskStatusFailed || got.PRNumber != 0 {
+		t.Errorf("sad-wolf = %+v, want it left failed", got)
+	}
+
+	// Nothing changed, so nothing is emitted again
+	d.TriggerPRCheck()
+
+	// Checks pass, then the PR merges
+	setPRs("work/calm-otter", `[{"number": 42, "url": "https://github.com/test/repo/pull/42", "title": "Add login", "state": "OPEN",
+		"reviewDecision": "APPROVED",
+		"statusCheckRollup": [{"name": "test", "status": "COMPLETED", "conclusion": "SUCCESS"}]}]`)
+	d.TriggerPRCheck()
+	setPRs("work/calm-otter", `[{"number": 42, "url": "https://github.com/test/repo/pull/42", "title": "Add login", "state": "MERGED",
+		"reviewDecision": "APPROVED",
+		"statusCheckRollup": [{"name": "test", "status": "COMPLETED", "conclusion": "SUCCESS"}]}]`)
+	d.TriggerPRCheck()
+	if got := getEntry("calm-otter"); got.Status != state.TaskStatusMerged || got.CIStatus != github.CIPassing || got.ReviewStatus != "APPROVED" {
+		t.Errorf("calm-otter = %+v, want merged with passing checks", got)
+	}
+
+	want := []events.EventType{events.EventPRCreated, events.EventCIFailed, events.EventCIPassed, events.EventPRMerged}
+	got := journalEventTypes(t, d)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	journaled, _ := d.journal.Query(events.Filter{RepoName: "test-repo", Types: []events.EventType{events.EventCIFailed}})
+	if len(journaled) == 1 && (journaled[0].AgentName != "calm-otter" || journaled[0].Data["job_name"] != "test") {
+		t.Errorf("ci_failed = %+v, want calm-otter's failed test check", journaled[0])
+	}
+
+	// Merged tasks aren't looked up again
+	setPRs("work/calm-otter", "not json")
+	d.TriggerPRCheck()
+	if got := getEntry("calm-otter").Status; got != state.TaskStatusMerged {
+		t.Errorf("calm-otter status = %s after merging, want merged", got)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "task_history", Args: map[string]interface{}{"repo": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("task_history failed: %s", resp.Error)
+	}
+	for _, item := range resp.Data.([]map[string]interface{}) {
+		if item["name"] == "calm-otter" && (item["ci_status"] != "passing" || item["review_status"] != "APPROVED") {
+			t.Errorf("task_history calm-otter = %v, want its checks and review", item)
+		}
+	}
+}
+
+func TestCheckTaskPRsLocalForge(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	run("clone", "-q", "--bare", repoDir, bare)
+	run("remote", "add", "origin", bare)
+	run("checkout", "-q", "-b", "work/calm-otter")
+	run("commit", "-q", "--allow-empty", "-m", "Add login")
+	run("push", "-q", "origin", "work/calm-otter")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     bare,
+		TmuxSession:   "mc-test-repo",
+		DefaultBranch: "main",
+		Agents:        make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.state.AddTaskHistory("test-repo", state.TaskHistoryEntry{
+		Name: "calm-otter", Task: "Add login", Branch: "work/calm-otter", Status: state.TaskStatusUnknown, CompletedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add task history: %v", err)
+	}
+	// PRs are never looked up on GitHub for a local repo
+	setPRs := useFakeGh(t, d)
+	setPRs("work/calm-otter", "not json")
+
+	local := forge.NewLocal(bare)
+	if _, err := local.CreatePR(forge.CreateOptions{Head: "work/calm-otter", Base: "main", Title: "Add login"}); err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if err := local.SetCheck("work/calm-otter", forge.Check{Name: "test", State: "failure"}); err != nil {
+		t.Fatalf("SetCheck() failed: %v", err)
+	}
+	d.TriggerPRCheck()
+	history, _ := d.state.GetTaskHistory("test-repo", 0)
+	if entry := history[0]; entry.Status != state.TaskStatusOpen || entry.PRNumber != 1 || entry.CIStatus != forge.CIFailing {
+		t.Errorf("calm-otter = %+v, want open local PR #1 with failing checks", entry)
+	}
+
+	if err := local.MergePR(1, forge.MergeOptions{}); err != nil {
+		t.Fatalf("MergePR() failed: %v", err)
+	}
+	d.TriggerPRCheck()
+	history, _ = d.state.GetTaskHistory("test-repo", 0)
+	if entry := history[0]; entry.Status != state.TaskStatusMerged {
+		t.Errorf("calm-otter status = %s, want merged", entry.Status)
+	}
+
+	want := []events.EventType{events.EventPRCreated, events.EventCIFailed, events.EventPRMerged}
+	if got := journalEventTypes(t, d); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestCheckTaskPRsRunningWorker(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+
+	wtPath := filepath.Join(d.paths.WorktreesDir, "test-repo", "busy-bee")
+	cmd := exec.Command("git", "worktree", "add", "-q", "-b", "work/busy-bee", wtPath, "main")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git worktree add failed: %v\n%s", err, output)
+	}
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"busy-bee": {Type: state.AgentTypeWorker, Task: "Add search", WorktreePath: wtPath, TmuxWindow: "busy-bee", CreatedAt: time.Now()},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	setPRs := useFakeGh(t, d)
+
+	// The worker opens its PR with passing checks
+	setPRs("work/busy-bee", `[{"number": 9, "url": "https://github.com/test/repo/pull/9", "title": "Add search", "state": "OPEN",
+		"statusCheckRollup": [{"name": "test", "status": "COMPLETED", "conclusion": "SUCCESS"}]}]`)
+	d.TriggerPRCheck()
+	worker, _ := d.state.GetAgent("test-repo", "busy-bee")
+	if worker.PR == nil || worker.PR.Number != 9 || worker.PR.Status != state.TaskStatusOpen || worker.PR.CIStatus != github.CIPassing {
+		t.Errorf("busy-bee PR = %+v, want open PR #9 with passing checks", worker.PR)
+	}
+
+	// A push breaks CI while the worker is still running
+	setPRs("work/busy-bee", `[{"number": 9, "url": "https://github.com/test/repo/pull/9", "title": "Add search", "state": "OPEN",
+		"statusCheckRollup": [{"name": "test", "status": "COMPLETED", "conclusion": "FAILURE"}]}]`)
+	d.TriggerPRCheck()
+	worker, _ = d.state.GetAgent("test-repo", "busy-bee")
+	if worker.PR == nil || worker.PR.CIStatus != github.CIFailing {
+		t.Errorf("busy-bee PR = %+v, want failing checks", worker.PR)
+	}
+
+	want := []events.EventType{events.EventPRCreated, events.EventCIPassed, events.EventCIFailed}
+	if got := journalEventTypes(t, d); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	journaled, _ := d.journal.Query(events.Filter{RepoName: "test-repo", Types: []events.EventType{events.EventCIFailed}})
+	if len(journaled) != 1 || journaled[0].AgentName != "busy-bee" {
+		t.Errorf("ci_failed = %+v, want one for busy-bee", journaled)
+	}
+
+	// Once the worker ends, its history entry carries on from what was seen
+	d.recordTaskHistory("test-repo", "busy-bee", worker)
+	if err := d.state.RemoveAgent("test-repo", "busy-bee"); err != nil {
+		t.Fatalf("Failed to remove worker: %v", err)
+	}
+	d.TriggerPRCheck()
+	history, _ := d.state.GetTaskHistory("test-repo", 0)
+	if len(history) != 1 || history[0].PRNumber != 9 || history[0].Status != state.TaskStatusOpen || history[0].CIStatus != github.CIFailing {
+		t.Errorf("history = %+v, want busy-bee's open PR #9 with failing checks", history)
+	}
+	if got := journalEventTypes(t, d); !reflect.DeepEqual(got, want) {
+		t.Errorf("events after the worker ended = %v, want no more than %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// resolveRecipients expands a recipient expression against a repository's
+// agents. Plain agent names are returned as-is; groups and globs match the
+// agents in the map, never including the sender or the workspace, which
+// only takes direct user input. Names are returned sorted.
+func resolveRecipients(to string, agents map[string]state.Agent, from string) ([]string, error) {
+	if !messages.IsGroupRecipient(to) {
+		return []string{to}, nil
+	}
+
+	var match func(name string, agent state.Agent) bool
+	switch to {
+	case messages.RecipientAll:
+		match = func(string, state.Agent) bool { return true }
+	case messages.RecipientWorkers:
+		match = func(_ string, agent state.Agent) bool { return agent.Type == state.AgentTypeWorker }
+	case messages.RecipientPersistent:
+		match = func(_ string, agent state.Agent) bool { return agent.Type.IsPersistent() }
+	default:
+		if strings.HasPrefix(to, "@") {
+			return nil, fmt.Errorf("unknown recipient group %q (valid groups: %s, %s, %s)", to, messages.RecipientAll, messages.RecipientWorkers, messages.RecipientPersistent)
+		}
+		if _, err := path.Match(to, ""); err != nil {
+			return nil, fmt.Errorf("invalid recipient pattern %q: %w", to, err)
+		}
+		match = func(name string, _ state.Agent) bool {
+			ok, _ := path.Match(to, name)
+			return ok
+		}
+	}
+
+	var recipients []string
+	for name, agent := range agents {
+		if name == from || agent.Type == state.AgentTypeWorkspace {
+			continue
+		}
+		if match(name, agent) {
+			recipients = append(recipients, name)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no agents match %q", to)
+	}
+
+	sort.Strings(recipients)
+	return recipients, nil
+}
+
+// handleResolveRecipients expands a recipient group or glob into the names
+// of the agents a message to it should go to
+func (d *Daemon) handleResolveRecipients(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	to, errResp, ok := getRequiredStringArg(req.Args, "to", "recipient is required")
+	if !ok {
+		return errResp
+	}
+	from, _ := req.Args["from"].(string)
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+	}
+
+	recipients, err := resolveRecipients(to, repo.Agents, from)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: recipients}
+}
@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestResolveRecipients(t *testing.T) {
+	agents := map[string]state.Agent{
+		"supervisor":    {Type: state.AgentTypeSupervisor},
+		"merge-queue":   {Type: state.AgentTypeMergeQueue},
+		"workspace":     {Type: state.AgentTypeWorkspace},
+		"clever-fox":    {Type: state.AgentTypeWorker},
+		"swift-eagle":   {Type: state.AgentTypeWorker},
+		"review-pr-12":  {Type: state.AgentTypeReview},
+		"review-pr-15":  {Type: state.AgentTypeReview},
+		"docs-bot":      {Type: state.AgentTypeGenericPersistent},
+		"calm-platypus": {Type: state.AgentTypeWorker},
+	}
+
+	tests := []struct {
+		name    string
+		to      string
+		from    string
+		want    []string
+		wantErr bool
+	}{
+		{"plain name", "supervisor", "clever-fox", []string{"supervisor"}, false},
+		{"plain name not in state", "future-agent", "supervisor", []string{"future-agent"}, false},
+		{"workers excludes sender", "@workers", "clever-fox", []string{"calm-platypus", "swift-eagle"}, false},
+		{"persistent excludes workspace", "@persistent", "clever-fox", []string{"docs-bot", "merge-queue", "supervisor"}, false},
+		{"all", "@all", "supervisor", []string{"calm-platypus", "clever-fox", "docs-bot", "merge-queue", "review-pr-12", "review-pr-15", "swift-eagle"}, false},
+		{"glob", "review-*", "supervisor", []string{"review-pr-12", "review-pr-15"}, false},
+		{"glob single char", "review-pr-1?", "supervisor", []string{"review-pr-12", "review-pr-15"}, false},
+		{"glob never matches workspace", "work*", "supervisor", nil, true},
+		{"workers from supervisor", "@workers", "supervisor", []string{"calm-platypus", "clever-fox", "swift-eagle"}, false},
+		{"unknown group", "@everyone", "supervisor", nil, true},
+		{"bad glob", "review-[", "supervisor", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRecipients(tt.to, agents, tt.from)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRecipients(%q) error = %v, wantErr %v", tt.to, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRecipients(%q) = %v, want %v", tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleResolveRecipients(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	for name, agentType := range map[string]state.AgentType{
+		"supervisor":  state.AgentTypeSupervisor,
+		"clever-fox":  state.AgentTypeWorker,
+		"swift-eagle": state.AgentTypeWorker,
+	} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType}); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	resp := d.handleRequest(socket.Request{
+		Command: "resolve_recipients",
+		Args:    map[string]interface{}{"repo": "test-repo", "to": "@workers", "from": "clever-fox"},
+	})
+	if !resp.Success || !reflect.DeepEqual(resp.Data, []string{"swift-eagle"}) {
+		t.Errorf("resolve_recipients = %+v, want swift-eagle", resp)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"to": "@workers"},
+		{"repo": "test-repo"},
+		{"repo": "missing", "to": "@workers"},
+		{"repo": "test-repo", "to": "@everyone"},
+	} {
+		if resp := d.handleRequest(socket.Request{Command: "resolve_recipients", Args: args}); resp.Success {
+			t.Errorf("resolve_recipients %v should fail", args)
+		}
+	}
+}
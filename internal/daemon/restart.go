@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+const (
+	// maxAutoRestarts is how many times in a row a persistent agent is
+	// restarted before it is considered crash-looping
+	maxAutoRestarts = 5
+
+	// restartBackoffBase is the wait before the second restart in a row.
+	// It doubles with each further restart, up to restartBackoffMax.
+	restartBackoffBase = 2 * time.Minute
+	restartBackoffMax  = 30 * time.Minute
+
+	// restartStableAfter is how long a restarted agent has to stay up
+	// before its restart count is cleared
+	restartStableAfter = 15 * time.Minute
+)
+
+// restartBackoff returns how long to wait after the last automatic restart
+// before restarting an agent again. The first restart is immediate.
+func restartBackoff(restarts int) time.Duration {
+	if restarts <= 0 {
+		return 0
+	}
+	backoff := restartBackoffBase
+	for i := 1; i < restarts; i++ {
+		backoff *= 2
+		if backoff >= restartBackoffMax {
+			return restartBackoffMax
+		}
+	}
+	return backoff
+}
+
+// isAutoRestartAgent returns true for persistent agent types, which the
+// daemon restarts when their process exits. Workers and reviewers complete
+// and get cleaned up instead.
+func isAutoRestartAgent(agentType state.AgentType) bool {
+	switch agentType {
+	case state.AgentTypeSupervisor, state.AgentTypeMergeQueue, state.AgentTypeWorkspace, state.AgentTypeGenericPersistent:
+		return true
+	}
+	return false
+}
+
+// autoRestartAgent restarts a persistent agent whose process exited, backing
+// off exponentially between restarts. After maxAutoRestarts restarts without
+// the agent staying up, it is marked crash-looping and left stopped until
+// someone runs `multiclaude agent restart --reset`.
+func (d *Daemon) autoRestartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository, exitReason string) {
+	if agent.CrashLooping {
+		d.logger.Debug("Agent %s is crash-looping, not restarting", agentName)
+		return
+	}
+
+	if agent.RestartCount >= maxAutoRestarts {
+		d.markCrashLooping(repoName, agentName, agent, exitReason)
+		return
+	}
+
+	if wait := restartBackoff(agent.RestartCount); time.Since(agent.LastRestart) < wait {
+		d.logger.Debug("Agent %s exited, waiting until %s before restart %d", agentName, agent.LastRestart.Add(wait).Format(time.RFC3339), agent.RestartCount+1)
+		return
+	}
+
+	// Failed restarts count too, so an agent that can't start at all still
+	// ends up crash-looping instead of being retried forever
+	if err := d.state.RecordAgentRestart(repoName, agentName, exitReason, time.Now()); err != nil {
+		d.logger.Error("Failed to record restart of agent %s: %v", agentName, err)
+		return
+	}
+
+	d.logger.Info("Attempting to auto-restart agent %s (restart %d of %d)", agentName, agent.RestartCount+1, maxAutoRestarts)
+	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
+		d.logger.Error("Failed to restart agent %s: %v", agentName, err)
+	} else {
+		d.logger.Info("Successfully restarted agent %s", agentName)
+	}
+}
+
+// markCrashLooping stops automatic restarts of an agent, emits agent_failed
+// and lets the supervisor know
+func (d *Daemon) markCrashLooping(repoName, agentName string, agent state.Agent, exitReason string) {
+	if err := d.state.MarkAgentCrashLooping(repoName, agentName, exitReason); err != nil {
+		d.logger.Error("Failed to mark agent %s as crash-looping: %v", agentName, err)
+		return
+	}
+
+	reason := fmt.Sprintf("crash-looping after %d restarts, last exit: %s", agent.RestartCount, exitReason)
+	d.logger.Error("Agent %s in repo %s is %s; automatic restarts stopped", agentName, repoName, reason)
+	d.eventBus.Emit(events.NewAgentFailedEvent(repoName, agentName, string(agent.Type), reason))
+
+	if agentName == "supervisor" {
+		return
+	}
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	msg := fmt.Sprintf("Agent %s keeps exiting and is no longer being restarted (%s).\n\n"+
+		"Check its output with: multiclaude logs %s\n"+
+		"Once the cause is fixed, restart it with: multiclaude agent restart %s --reset",
+		agentName, reason, agentName, agentName)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to notify supervisor about crash-looping agent %s: %v", agentName, err)
+	}
+}
+
+// clearStableRestarts resets the restart count of an agent that has stayed
+// up long enough since its last automatic restart
+func (d *Daemon) clearStableRestarts(repoName, agentName string, agent state.Agent) {
+	if agent.RestartCount == 0 || agent.CrashLooping || time.Since(agent.LastRestart) < restartStableAfter {
+		return
+	}
+	if err := d.state.ResetAgentRestarts(repoName, agentName); err != nil {
+		d.logger.Warn("Failed to reset restart count of agent %s: %v", agentName, err)
+		return
+	}
+	d.logger.Info("Agent %s has been stable for %s, reset its restart count", agentName, restartStableAfter)
+}
@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		restarts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 8 * time.Minute},
+		{4, 16 * time.Minute},
+		{5, 30 * time.Minute},
+		{20, 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := restartBackoff(tt.restarts); got != tt.want {
+			t.Errorf("restartBackoff(%d) = %v, want %v", tt.restarts, got, tt.want)
+		}
+	}
+}
+
+func TestAutoRestartAgentCrashLoop(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	wait := captureEvents(t, d)
+
+	// No tmux session, so every restart attempt fails the way a crashing
+	// agent would
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-no-such-session",
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	for name, agentType := range map[string]state.AgentType{"supervisor": state.AgentTypeSupervisor, "merge-queue": state.AgentTypeMergeQueue} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType, TmuxWindow: name, PID: 99999999, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	restart := func() state.Agent {
+		t.Helper()
+		agent, _ := d.state.GetAgent("test-repo", "merge-queue")
+		d.autoRestartAgent("test-repo", "merge-queue", agent, repo, "process 99999999 exited")
+		agent, _ = d.state.GetAgent("test-repo", "merge-queue")
+		return agent
+	}
+	// rewind moves the last restart back past the current backoff
+	rewind := func() {
+		t.Helper()
+		agent, _ := d.state.GetAgent("test-repo", "merge-queue")
+		agent.LastRestart = time.Now().Add(-restartBackoff(agent.RestartCount) - time.Second)
+		if err := d.state.UpdateAgent("test-repo", "merge-queue", agent); err != nil {
+			t.Fatalf("Failed to update agent: %v", err)
+		}
+	}
+
+	agent := restart()
+	if agent.RestartCount != 1 || agent.LastExitReason != "process 99999999 exited" || agent.LastRestart.IsZero() {
+		t.Fatalf("after first restart: %+v", agent)
+	}
+	if agent = restart(); agent.RestartCount != 1 {
+		t.Errorf("restart within the backoff should wait, RestartCount = %d", agent.RestartCount)
+	}
+
+	for i := 2; i <= maxAutoRestarts; i++ {
+		rewind()
+		if agent = restart(); agent.RestartCount != i {
+			t.Fatalf("RestartCount = %d, want %d", agent.RestartCount, i)
+		}
+	}
+	if agent.CrashLooping {
+		t.Fatal("agent should not be crash-looping until restarts are exhausted")
+	}
+
+	rewind()
+	agent = restart()
+	if !agent.CrashLooping || agent.RestartCount != maxAutoRestarts {
+		t.Fatalf("agent should be crash-looping after %d restarts: %+v", maxAutoRestarts, agent)
+	}
+	rewind()
+	if agent = restart(); agent.RestartCount != maxAutoRestarts {
+		t.Errorf("crash-looping agent should not be restarted, RestartCount = %d", agent.RestartCount)
+	}
+
+	failed, ok := findEvent(wait(1), events.EventAgentFailed)
+	if !ok {
+		t.Fatal("agent_failed not emitted")
+	}
+	if reason, _ := failed.Data["reason"].(string); failed.AgentName != "merge-queue" || !strings.Contains(reason, "crash-looping") {
+		t.Errorf("agent_failed = %+v", failed)
+	}
+	msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "--reset") {
+		t.Errorf("supervisor should be told once about the crash loop, got %v, %v", msgs, err)
+	}
+
+	// A manual restart needs --reset
+	resp := d.handleRequest(socket.Request{Command: "restart_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "merge-queue",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "--reset") {
+		t.Errorf("restart_agent without reset = %+v, want a crash-looping error", resp)
+	}
+	d.handleRequest(socket.Request{Command: "restart_agent", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "merge-queue", "reset": true,
+	}})
+	agent, _ = d.state.GetAgent("test-repo", "merge-queue")
+	if agent.CrashLooping || agent.RestartCount != 0 {
+		t.Errorf("restart_agent with reset should clear the restart history: %+v", agent)
+	}
+	if agent.LastExitReason == "" {
+		t.Error("reset should keep the last exit reason")
+	}
+}
+
+func TestClearStableRestarts(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	agents := map[string]state.Agent{
+		"stable":   {Type: state.AgentTypeSupervisor, RestartCount: 3, LastRestart: time.Now().Add(-restartStableAfter - time.Minute)},
+		"recent":   {Type: state.AgentTypeMergeQueue, RestartCount: 3, LastRestart: time.Now().Add(-time.Minute)},
+		"stuck-on": {Type: state.AgentTypeWorkspace, RestartCount: 5, CrashLooping: true, LastRestart: time.Now().Add(-time.Hour)},
+	}
+	for name, agent := range agents {
+		if err := d.state.AddAgent("test-repo", name, agent); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		d.clearStableRestarts("test-repo", name, agent)
+	}
+
+	want := map[string]int{"stable": 0, "recent": 3, "stuck-on": 5}
+	for name, count := range want {
+		agent, _ := d.state.GetAgent("test-repo", name)
+		if agent.RestartCount != count {
+			t.Errorf("%s RestartCount = %d, want %d", name, agent.RestartCount, count)
+		}
+	}
+}
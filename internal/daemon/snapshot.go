@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dlorenc/multiclaude/internal/snapshot"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// snapshotLoop snapshots state and messages at startup and every
+// snapshot.DefaultInterval
+func (d *Daemon) snapshotLoop() {
+	d.periodicLoop("snapshot", snapshot.DefaultInterval,
+		func() { d.takeSnapshot(snapshot.ReasonStartup) },
+		func() { d.takeSnapshot(snapshot.ReasonPeriodic) })
+}
+
+// takeSnapshot snapshots the state file and message directories, then prunes
+// the oldest snapshots beyond snapshot.DefaultKeep
+func (d *Daemon) takeSnapshot(reason string) (*snapshot.Snapshot, error) {
+	// A fresh daemon may not have written its state file yet
+	if _, err := os.Stat(d.paths.StateFile); os.IsNotExist(err) {
+		if err := d.state.Save(); err != nil {
+			return nil, fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+
+	snap, err := d.snapshots.Create(d.paths.StateFile, d.paths.MessagesDir, reason)
+	if err != nil {
+		d.logger.Error("Failed to snapshot state (%s): %v", reason, err)
+		return nil, err
+	}
+	d.logger.Debug("Took state snapshot %s (%s)", snap.ID, reason)
+
+	if err := d.snapshots.Prune(snapshot.DefaultKeep); err != nil {
+		d.logger.Error("Failed to prune state snapshots: %v", err)
+	}
+	return snap, nil
+}
+
+// handleRestoreSnapshot replaces the state and message directories with a
+// snapshot's. Message delivery and queued task starts are paused meanwhile,
+// and the current state is snapshotted first so the restore can be undone.
+// Args: id.
+func (d *Daemon) handleRestoreSnapshot(req socket.Request) socket.Response {
+	id, errResp, ok := getRequiredStringArg(req.Args, "id", "snapshot ID is required")
+	if !ok {
+		return errResp
+	}
+
+	snap, err := d.snapshots.Get(id)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	restored, err := snap.LoadState()
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.queueMu.Lock()
+	defer d.queueMu.Unlock()
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+
+	backup, err := d.takeSnapshot(snapshot.ReasonRestore)
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("failed to snapshot current state: %v", err)}
+	}
+
+	if err := d.state.Restore(restored); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.eventBus.UpdateConfig(d.state.GetHookConfig())
+
+	// The messages directory is moved even when the restore fails and puts it
+	// back, so its watches always need renewing
+	err = snap.RestoreMessages(d.paths.MessagesDir)
+	d.rewatchMessages()
+	if err != nil {
+		return socket.Response{Success: false, Error: fmt.Sprintf("restored state, but failed to restore messages (undo with snapshot %s): %v", backup.ID, err)}
+	}
+
+	d.logger.Info("Restored state snapshot %s (previous state saved as snapshot %s)", snap.ID, backup.ID)
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"restored": snap.ID,
+		"backup":   backup.ID,
+	}}
+}
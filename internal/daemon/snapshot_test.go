@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// TestRestoreSnapshotAfterRemoveRepo checks that an accidental remove_repo
+// can be undone from the snapshot taken before it, messages included
+func TestRestoreSnapshotAfterRemoveRepo(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.state.AddAgent("test-repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if err := d.state.UpdateHookConfig(events.HookConfig{OnEvent: "/bin/true"}); err != nil {
+		t.Fatalf("UpdateHookConfig() failed: %v", err)
+	}
+	msgPath := filepath.Join(d.paths.AgentMessagesDir("test-repo", "supervisor"), "msg-1.json")
+	if err := os.MkdirAll(filepath.Dir(msgPath), 0755); err != nil {
+		t.Fatalf("Failed to create messages directory: %v", err)
+	}
+	if err := os.WriteFile(msgPath, []byte(`{"id":"msg-1"}`), 0644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "remove_repo", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("remove_repo failed: %s", resp.Error)
+	}
+	if err := os.RemoveAll(d.paths.RepoMessagesDir("test-repo")); err != nil {
+		t.Fatalf("Failed to remove messages: %v", err)
+	}
+	if err := d.state.UpdateHookConfig(events.HookConfig{}); err != nil {
+		t.Fatalf("UpdateHookConfig() failed: %v", err)
+	}
+
+	snapshots, err := d.snapshots.List()
+	if err != nil || len(snapshots) != 1 || snapshots[0].Reason != snapshot.ReasonRemoveRepo {
+		t.Fatalf("snapshots = %+v, %v, want one taken before the removal", snapshots, err)
+	}
+	removed := snapshots[0].ID
+
+	resp = d.handleRequest(socket.Request{Command: "restore_snapshot", Args: map[string]interface{}{"id": removed}})
+	if !resp.Success {
+		t.Fatalf("restore_snapshot failed: %s", resp.Error)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if data["restored"] != removed || data["backup"] == "" {
+		t.Errorf("restore_snapshot data = %v, want the restored and backup IDs", resp.Data)
+	}
+
+	if _, ok := d.state.GetAgent("test-repo", "supervisor"); !ok {
+		t.Error("restored state is missing the supervisor")
+	}
+	if _, err := os.Stat(msgPath); err != nil {
+		t.Errorf("message was not restored: %v", err)
+	}
+	if got := d.state.GetHookConfig().OnEvent; got != "/bin/true" {
+		t.Errorf("hook config OnEvent = %q, want the snapshot's", got)
+	}
+	view, err := state.LoadView(d.paths.StateFile)
+	if err != nil {
+		t.Fatalf("LoadView() failed: %v", err)
+	}
+	if _, ok := view.GetRepo("test-repo"); !ok {
+		t.Error("restored state was not saved")
+	}
+
+	// The state it replaced was snapshotted, so the restore can be undone
+	backup, err := d.snapshots.Get(data["backup"].(string))
+	if err != nil || backup.Reason != snapshot.ReasonRestore {
+		t.Fatalf("backup snapshot = %+v, %v, want one taken before the restore", backup, err)
+	}
+	backupState, err := backup.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() failed: %v", err)
+	}
+	if _, ok := backupState.GetRepo("test-repo"); ok {
+		t.Error("backup snapshot should hold the state without the removed repo")
+	}
+}
+
+func TestRestoreSnapshotErrors(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"id": "20260101-000000"},
+		{"id": "../state.json"},
+	} {
+		if resp := d.handleRequest(socket.Request{Command: "restore_snapshot", Args: args}); resp.Success {
+			t.Errorf("restore_snapshot with %v should fail", args)
+		}
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "remove_repo", Args: map[string]interface{}{"name": "missing"}})
+	if resp.Success {
+		t.Error("remove_repo of an unknown repository should fail")
+	}
+	if snapshots, _ := d.snapshots.List(); len(snapshots) != 0 {
+		t.Errorf("remove_repo of an unknown repository took %d snapshot(s), want none", len(snapshots))
+	}
+}
+
+func TestSnapshotPruning(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for i := 0; i < snapshot.DefaultKeep+2; i++ {
+		if _, err := d.takeSnapshot(snapshot.ReasonPeriodic); err != nil {
+			t.Fatalf("takeSnapshot() failed: %v", err)
+		}
+	}
+	if snapshots, _ := d.snapshots.List(); len(snapshots) != snapshot.DefaultKeep {
+		t.Errorf("daemon kept %d snapshots, want %d", len(snapshots), snapshot.DefaultKeep)
+	}
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/client"
+)
+
+// TestConcurrentCLIAndDaemonWrites checks that changes sent by CLI processes
+// and the daemon's own changes all reach the state file, and that a CLI
+// can't bypass a running daemon and overwrite its state
+func TestConcurrentCLIAndDaemonWrites(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	if err := d.server.Start(); err != nil {
+		t.Fatalf("Failed to start socket server: %v", err)
+	}
+	defer d.server.Stop()
+	go d.server.Serve()
+
+	const writes = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*writes)
+	for i := 0; i < writes; i++ {
+		wg.Add(3)
+		// A CLI process registering an agent through the socket
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("cli-%d", i)
+			errs <- client.New(d.paths.DaemonSock).AddAgent(client.AddAgentRequest{
+				Repo:         "test-repo",
+				Agent:        name,
+				Type:         client.AgentTypeWorker,
+				WorktreePath: "/tmp/" + name,
+				TmuxWindow:   name,
+			})
+		}(i)
+		// The daemon changing state itself
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("daemon-%d", i)
+			errs <- d.state.AddAgent("test-repo", name, state.Agent{Type: state.AgentTypeWorker, TmuxWindow: name, CreatedAt: time.Now()})
+		}(i)
+		// A CLI process falling back to the state file directly
+		go func(i int) {
+			defer wg.Done()
+			err := state.Update(d.paths.StateFile, 10*time.Millisecond, func(st *state.State) error {
+				return st.AddAgent("test-repo", fmt.Sprintf("direct-%d", i), state.Agent{Type: state.AgentTypeWorker})
+			})
+			if !errors.Is(err, state.ErrLocked) {
+				errs <- fmt.Errorf("state.Update() while the daemon runs = %v, want ErrLocked", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	view, err := state.LoadView(d.paths.StateFile)
+	if err != nil {
+		t.Fatalf("LoadView() failed: %v", err)
+	}
+	agents, err := view.ListAgents("test-repo")
+	if err != nil {
+		t.Fatalf("ListAgents() failed: %v", err)
+	}
+	if len(agents) != 2*writes {
+		t.Errorf("state file has %d agents, want %d", len(agents), 2*writes)
+	}
+	for i := 0; i < writes; i++ {
+		for _, name := range []string{fmt.Sprintf("cli-%d", i), fmt.Sprintf("daemon-%d", i)} {
+			if _, ok := view.GetAgent("test-repo", name); !ok {
+				t.Errorf("agent %s missing from the state file", name)
+			}
+		}
+	}
+}
+
+func TestRecordUpstreamSync(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "record_upstream_sync", Args: map[string]interface{}{
+		"repo": "test-repo",
+		"sha":  "abc123",
+	}})
+	if !resp.Success {
+		t.Fatalf("record_upstream_sync failed: %s", resp.Error)
+	}
+	status, err := d.state.GetDualCIStatus("test-repo")
+	if err != nil || status == nil || status.LastSyncSHA != "abc123" || status.LastSyncTime.IsZero() {
+		t.Errorf("dual CI status = %+v, %v, want the sync recorded", status, err)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "record_upstream_sync", Args: map[string]interface{}{"repo": "test-repo"}})
+	if resp.Success {
+		t.Error("record_upstream_sync without a sha should fail")
+	}
+}
@@ -0,0 +1,231 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// handleSubscribe streams events to a client until it disconnects.
+// Args (all optional):
+//   - repo: only events for this repository
+//   - agent: only events for this agent
+//   - type: event types, as a comma-separated string or a list
+//
+// The first line is a Response; every line after it is an events.Event.
+// A subscriber that falls too far behind is disconnected.
+func (d *Daemon) handleSubscribe(req socket.Request, stream *socket.Stream) {
+	filter := events.Filter{}
+	filter.RepoName, _ = req.Args["repo"].(string)
+	filter.AgentName, _ = req.Args["agent"].(string)
+	switch types := req.Args["type"].(type) {
+	case string:
+		filter.Types = events.ParseTypes(types)
+	case []interface{}:
+		for _, t := range types {
+			s, ok := t.(string)
+			if !ok {
+				stream.Send(socket.Response{Success: false, Error: "type must be a list of strings"})
+				return
+			}
+			filter.Types = append(filter.Types, events.EventType(s))
+		}
+	}
+
+	if filter.RepoName != "" {
+		if _, exists := d.state.GetRepo(filter.RepoName); !exists {
+			stream.Send(socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", filter.RepoName)})
+			return
+		}
+	}
+
+	sub := d.eventBus.Subscribe(filter, 0)
+	defer d.eventBus.Unsubscribe(sub)
+
+	types := make([]string, len(filter.Types))
+	for i, t := range filter.Types {
+		types[i] = string(t)
+	}
+	if err := stream.Send(socket.Response{Success: true, Data: map[string]interface{}{
+		"repo":  filter.RepoName,
+		"agent": filter.AgentName,
+		"types": types,
+	}}); err != nil {
+		return
+	}
+	d.logger.Debug("Subscriber connected (repo=%q, agent=%q, types=%v)", filter.RepoName, filter.AgentName, types)
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				if sub.Overrun() {
+					d.logger.Warn("Disconnecting subscriber that fell %d events behind", events.DefaultSubscriptionBuffer)
+				}
+				return
+			}
+			if err := stream.Send(event); err != nil {
+				d.logger.Debug("Subscriber write failed: %v", err)
+				return
+			}
+		case <-stream.Done():
+			d.logger.Debug("Subscriber disconnected")
+			return
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// notifyStateSaved is the state's save hook. It runs with the state locked,
+// so it only signals the state watch loop, coalescing bursts of saves.
+func (d *Daemon) notifyStateSaved() {
+	select {
+	case d.stateSaved <- struct{}{}:
+	default:
+	}
+}
+
+// stateWatchLoop broadcasts state_changed events for each state save
+func (d *Daemon) stateWatchLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting state watch loop")
+
+	for {
+		select {
+		case <-d.stateSaved:
+			d.broadcastStateChanges()
+		case <-d.ctx.Done():
+			d.logger.Info("State watch loop stopped")
+			return
+		}
+	}
+}
+
+// repoSnapshot holds a repository's JSON fields for diffing, with its agents separate
+type repoSnapshot struct {
+	fields map[string]json.RawMessage
+	agents map[string]map[string]json.RawMessage
+}
+
+// broadcastStateChanges compares the state with the last snapshot and
+// broadcasts a state_changed event for each repository and agent that changed
+func (d *Daemon) broadcastStateChanges() {
+	current := snapshotRepos(d.state.GetAllRepos())
+
+	d.stateSnapshotMu.Lock()
+	previous := d.stateSnapshot
+	d.stateSnapshot = current
+	d.stateSnapshotMu.Unlock()
+
+	for _, event := range diffSnapshots(previous, current) {
+		d.eventBus.Broadcast(event)
+	}
+}
+
+// snapshotRepos captures repositories for diffSnapshots
+func snapshotRepos(repos map[string]*state.Repository) map[string]repoSnapshot {
+	snapshot := make(map[string]repoSnapshot, len(repos))
+	for name, repo := range repos {
+		fields := jsonFields(repo)
+		delete(fields, "agents")
+		agents := make(map[string]map[string]json.RawMessage, len(repo.Agents))
+		for agentName, agent := range repo.Agents {
+			agents[agentName] = jsonFields(agent)
+		}
+		snapshot[name] = repoSnapshot{fields: fields, agents: agents}
+	}
+	return snapshot
+}
+
+// jsonFields returns the top-level JSON fields of v
+func jsonFields(v interface{}) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if data, err := json.Marshal(v); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// changedFields returns the sorted names of fields that differ between a and b
+func changedFields(a, b map[string]json.RawMessage) []string {
+	var changed []string
+	for k, va := range a {
+		if vb, ok := b[k]; !ok || !bytes.Equal(va, vb) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// diffSnapshots returns state_changed events for the differences between
+// two snapshots, ordered by repository and agent name
+func diffSnapshots(previous, current map[string]repoSnapshot) []events.Event {
+	names := make(map[string]bool)
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []events.Event
+	for _, repoName := range sorted {
+		prev, hadRepo := previous[repoName]
+		curr, hasRepo := current[repoName]
+		switch {
+		case !hadRepo:
+			changes = append(changes, events.NewStateChangedEvent(repoName, "", "repo_added", nil))
+		case !hasRepo:
+			changes = append(changes, events.NewStateChangedEvent(repoName, "", "repo_removed", nil))
+			continue
+		default:
+			if fields := changedFields(prev.fields, curr.fields); len(fields) > 0 {
+				changes = append(changes, events.NewStateChangedEvent(repoName, "", "repo_updated", fields))
+			}
+		}
+
+		agentNames := make([]string, 0, len(prev.agents)+len(curr.agents))
+		for name := range prev.agents {
+			agentNames = append(agentNames, name)
+		}
+		for name := range curr.agents {
+			if _, ok := prev.agents[name]; !ok {
+				agentNames = append(agentNames, name)
+			}
+		}
+		sort.Strings(agentNames)
+
+		for _, agentName := range agentNames {
+			prevAgent, hadAgent := prev.agents[agentName]
+			currAgent, hasAgent := curr.agents[agentName]
+			switch {
+			case !hadAgent:
+				changes = append(changes, events.NewStateChangedEvent(repoName, agentName, "agent_added", nil))
+			case !hasAgent:
+				changes = append(changes, events.NewStateChangedEvent(repoName, agentName, "agent_removed", nil))
+			default:
+				if fields := changedFields(prevAgent, currAgent); len(fields) > 0 {
+					changes = append(changes, events.NewStateChangedEvent(repoName, agentName, "agent_updated", fields))
+				}
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// nextEvent reads the next event from a subscription, failing the test after a timeout
+func nextEvent(t *testing.T, sub *socket.Subscription) events.Event {
+	t.Helper()
+
+	type result struct {
+		event events.Event
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var e events.Event
+		err := sub.Next(&e)
+		ch <- result{e, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatalf("Next() failed: %v", r.err)
+		}
+		return r.event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return events.Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	// Take in the repo now so the watch loop can't report it after subscribing
+	d.broadcastStateChanges()
+
+	// Only the socket server and state watch loop are needed
+	if err := d.server.Start(); err != nil {
+		t.Fatalf("Failed to start socket server: %v", err)
+	}
+	defer d.server.Stop()
+	go d.server.Serve()
+	d.wg.Add(1)
+	go d.stateWatchLoop()
+	defer d.cancel()
+
+	client := socket.NewClient(d.paths.DaemonSock)
+	sub, resp, err := client.Subscribe(socket.Request{Command: "subscribe", Args: map[string]interface{}{
+		"repo": "test-repo",
+		"type": "agent_started,state_changed",
+	}})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("subscribe failed: %s", resp.Error)
+	}
+	defer sub.Close()
+
+	// A state change is streamed with the fields that changed
+	if err := d.state.AddAgent("test-repo", "worker-1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	e := nextEvent(t, sub)
+	if e.Type != events.EventStateChanged || e.AgentName != "worker-1" || e.Data["change"] != "agent_added" {
+		t.Errorf("first event = %+v, want agent_added state change for worker-1", e)
+	}
+
+	if err := d.state.UpdateAgentPID("test-repo", "worker-1", 4242); err != nil {
+		t.Fatalf("Failed to update PID: %v", err)
+	}
+	e = nextEvent(t, sub)
+	if e.Data["change"] != "agent_updated" || !reflect.DeepEqual(e.Data["fields"], []interface{}{"pid"}) {
+		t.Errorf("update event = %+v, want agent_updated with fields [pid]", e)
+	}
+
+	// Lifecycle events are filtered by repo and type
+	d.eventBus.Emit(events.NewAgentIdleEvent("test-repo", "worker-1", 60))
+	d.eventBus.Emit(events.NewAgentStartedEvent("other-repo", "worker-2", "worker", "task"))
+	d.eventBus.Emit(events.NewAgentStartedEvent("test-repo", "worker-3", "worker", "task"))
+	e = nextEvent(t, sub)
+	if e.Type != events.EventAgentStarted || e.AgentName != "worker-3" {
+		t.Errorf("lifecycle event = %+v, want agent_started for test-repo/worker-3", e)
+	}
+
+	// Disconnecting removes the subscription
+	sub.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for d.eventBus.SubscriberCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := d.eventBus.SubscriberCount(); got != 0 {
+		t.Errorf("SubscriberCount() = %d after disconnect, want 0", got)
+	}
+
+	// Unknown repos are rejected up front
+	sub, resp, err = client.Subscribe(socket.Request{Command: "subscribe", Args: map[string]interface{}{"repo": "nope"}})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	if sub != nil || resp.Success {
+		t.Errorf("subscribe to an unknown repo should fail, got %+v", resp)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]*state.Repository{
+		"app": {
+			TmuxSession: "mc-app",
+			Agents: map[string]state.Agent{
+				"supervisor": {Type: state.AgentTypeSupervisor, PID: 1},
+				"worker-1":   {Type: state.AgentTypeWorker, PID: 2},
+			},
+		},
+		"old": {TmuxSession: "mc-old", Agents: map[string]state.Agent{}},
+	}
+	after := map[string]*state.Repository{
+		"app": {
+			TmuxSession: "mc-app",
+			MaxWorkers:  2,
+			Agents: map[string]state.Agent{
+				"supervisor": {Type: state.AgentTypeSupervisor, PID: 1},
+				"worker-1":   {Type: state.AgentTypeWorker, PID: 3, ReadyForCleanup: true},
+				"worker-2":   {Type: state.AgentTypeWorker},
+			},
+		},
+		"new": {TmuxSession: "mc-new", Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor},
+		}},
+	}
+
+	var got []string
+	for _, e := range diffSnapshots(snapshotRepos(before), snapshotRepos(after)) {
+		entry := e.RepoName + "/" + e.AgentName + " " + e.Data["change"].(string)
+		if fields, ok := e.Data["fields"].([]string); ok {
+			for _, f := range fields {
+				entry += " " + f
+			}
+		}
+		got = append(got, entry)
+	}
+	want := []string{
+		"app/ repo_updated max_workers",
+		"app/worker-1 agent_updated pid ready_for_cleanup",
+		"app/worker-2 agent_added",
+		"new/ repo_added",
+		"new/supervisor agent_added",
+		"old/ repo_removed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSnapshots() =\n%v\nwant\n%v", got, want)
+	}
+
+	if changes := diffSnapshots(snapshotRepos(after), snapshotRepos(after)); len(changes) != 0 {
+		t.Errorf("identical snapshots should have no changes, got %v", changes)
+	}
+}
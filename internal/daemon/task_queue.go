@@ -0,0 +1,662 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/forge"
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/google/uuid"
+)
+
+// Queued tasks that fail to start are retried after queuedTaskRetryDelay,
+// doubling with each failure, until they have failed maxQueuedTaskAttempts
+// times
+const (
+	queuedTaskRetryDelay  = time.Minute
+	maxQueuedTaskAttempts = 5
+)
+
+// startQueuedTasks starts queued tasks for a repository until no task is
+// ready or the repo's worker limit is reached. Tasks are started in queue
+// order, skipping those still waiting on a dependency or a retry. A task
+// that fails to start keeps its place in the queue with the error, and the
+// tasks behind it are started instead.
+func (d *Daemon) startQueuedTasks(repoName string) {
+	d.queueMu.Lock()
+	defer d.queueMu.Unlock()
+
+	for {
+		repo, exists := d.state.GetAllRepos()[repoName]
+		if !exists || len(repo.TaskQueue) == 0 {
+			return
+		}
+
+		active, err := d.state.ActiveWorkerCount(repoName)
+		if err != nil {
+			return
+		}
+		if repo.MaxWorkers > 0 && active >= repo.MaxWorkers {
+			d.logger.Debug("Repo %s at worker limit (%d/%d), %d task(s) queued", repoName, active, repo.MaxWorkers, len(repo.TaskQueue))
+			return
+		}
+
+		next, found := d.nextReadyTask(repoName, repo)
+		if !found {
+			return
+		}
+		task, err := d.state.RemoveQueuedTask(repoName, next.ID)
+		if err != nil {
+			return
+		}
+
+		workerName, err := d.startQueuedTask(repoName, repo, task)
+		if err != nil {
+			d.requeueFailedTask(repoName, task, err)
+			continue
+		}
+		d.logger.Info("Started queued task %s in repo %s as worker %s", task.ID, repoName, workerName)
+
+		// Tasks waiting on this one now wait on its worker's PR
+		if err := d.state.RetargetTaskDependencies(repoName, task.ID, workerName); err != nil {
+			d.logger.Error("Failed to update dependencies on task %s: %v", task.ID, err)
+		}
+	}
+}
+
+// requeueFailedTask puts a task that failed to start back in the queue to
+// be retried later, or tells the supervisor once it has failed too often
+func (d *Daemon) requeueFailedTask(repoName string, task state.QueuedTask, err error) {
+	d.logger.Error("Failed to start queued task %s in repo %s: %v", task.ID, repoName, err)
+	task.LastError = err.Error()
+	task.Attempts++
+	task.RetryAt = time.Time{}
+	if task.Attempts < maxQueuedTaskAttempts {
+		task.RetryAt = time.Now().Add(queuedTaskRetryDelay << (task.Attempts - 1))
+	}
+	if err := d.state.RequeueTask(repoName, task); err != nil {
+		d.logger.Error("Failed to requeue task %s: %v", task.ID, err)
+		return
+	}
+	if task.Attempts < maxQueuedTaskAttempts {
+		return
+	}
+
+	d.logger.Warn("Queued task %s in repo %s failed to start %d times, giving up", task.ID, repoName, task.Attempts)
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	msg := fmt.Sprintf("Queued task %s failed to start %d times and won't be retried: %s\n"+
+		"Task: %s\n\n"+
+		"Cancel it with: multiclaude work queue cancel %s\n"+
+		"Then re-queue it once the problem is fixed.",
+		task.ID, task.Attempts, task.LastError, taskTitle(task.Task), task.ID)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to notify supervisor about task %s: %v", task.ID, err)
+	}
+}
+
+// startQueuedTasksAsync starts queued tasks in the background for socket
+// handlers. The goroutine is tracked by d.wg, so shutdown waits for any
+// worker it is creating, and does nothing once shutdown has begun.
+func (d *Daemon) startQueuedTasksAsync(repoName string) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		if d.ctx.Err() != nil {
+			return
+		}
+		d.startQueuedTasks(repoName)
+	}()
+}
+
+// nextReadyTask returns the first queued task whose dependency, if any, has
+// merged and that isn't waiting to be retried. Tasks whose dependency can
+// never merge are flagged along the way.
+func (d *Daemon) nextReadyTask(repoName string, repo *state.Repository) (state.QueuedTask, bool) {
+	now := time.Now()
+	for _, task := range repo.TaskQueue {
+		if task.Attempts >= maxQueuedTaskAttempts || now.Before(task.RetryAt) {
+			continue
+		}
+		status, reason := taskDependencyStatus(repo, task.After)
+		switch status {
+		case dependencyReady:
+			return task, true
+		case dependencyFailed:
+			if task.LastError != reason {
+				d.flagBlockedTask(repoName, task, reason)
+			}
+		}
+	}
+	return state.QueuedTask{}, false
+}
+
+// dependencyStatus is how far a queued task's dependency has got
+type dependencyStatus int
+
+const (
+	// dependencyReady means there is no dependency or its PR has merged
+	dependencyReady dependencyStatus = iota
+	// dependencyPending means the dependency is queued, running, or has an open PR
+	dependencyPending
+	// dependencyFailed means the dependency ended without merging
+	dependencyFailed
+)
+
+// taskDependencyStatus checks a dependency on a queued task ID or worker
+// name, returning a reason when it can never merge
+func taskDependencyStatus(repo *state.Repository, after string) (dependencyStatus, string) {
+	if after == "" {
+		return dependencyReady, ""
+	}
+
+	for _, queued := range repo.TaskQueue {
+		if queued.ID == after {
+			return dependencyPending, ""
+		}
+	}
+	if agent, exists := repo.Agents[after]; exists {
+		if agent.Type != state.AgentTypeWorker {
+			return dependencyFailed, fmt.Sprintf("%s is a %s, not a worker", after, agent.Type)
+		}
+		return dependencyPending, ""
+	}
+
+	// The most recent history entry wins if a name was reused
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		entry := repo.TaskHistory[i]
+		if entry.Name != after {
+			continue
+		}
+		switch entry.Status {
+		case state.TaskStatusMerged:
+			return dependencyReady, ""
+		case state.TaskStatusClosed, state.TaskStatusFailed, state.TaskStatusNoPR:
+			return dependencyFailed, fmt.Sprintf("dependency %s ended %s without merging", after, entry.Status)
+		default:
+			return dependencyPending, ""
+		}
+	}
+
+	return dependencyFailed, fmt.Sprintf("dependency %s no longer exists", after)
+}
+
+// flagBlockedTask records why a queued task's dependency can never merge and
+// tells the supervisor. The task stays queued until it is cancelled.
+func (d *Daemon) flagBlockedTask(repoName string, task state.QueuedTask, reason string) {
+	if err := d.state.SetQueuedTaskError(repoName, task.ID, reason); err != nil {
+		d.logger.Error("Failed to flag queued task %s: %v", task.ID, err)
+		return
+	}
+	d.logger.Warn("Queued task %s in repo %s is blocked: %s", task.ID, repoName, reason)
+
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	msg := fmt.Sprintf("Queued task %s will not start: %s.\n"+
+		"Task: %s\n\n"+
+		"Cancel it with: multiclaude work queue cancel %s\n"+
+		"Or re-queue it without the dependency once the work is ready for it.",
+		task.ID, reason, taskTitle(task.Task), task.ID)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to notify supervisor about blocked task %s: %v", task.ID, err)
+	}
+}
+
+// startAllQueuedTasks starts queued tasks in every repository with free worker slots
+func (d *Daemon) startAllQueuedTasks() {
+	for _, repoName := range d.state.ListRepos() {
+		d.startQueuedTasks(repoName)
+	}
+}
+
+// startQueuedTask creates the worker for a queued task
+func (d *Daemon) startQueuedTask(repoName string, repo *state.Repository, task state.QueuedTask) (string, error) {
+	worker, err := d.createWorker(repoName, repo, workerSpec{
+		name:          task.Name,
+		task:          task.Task,
+		branch:        task.Branch,
+		base:          task.Base,
+		pushTo:        task.PushTo,
+		claude:        queuedTaskClaudeOptions(task),
+		documentation: task.Documentation,
+	})
+	if err != nil {
+		return "", err
+	}
+	return worker.name, nil
+}
+
+// workerSpec describes a worker to create
+type workerSpec struct {
+	// name is generated if empty
+	name string
+	task string
+	// branch is the ref to start from instead of the base branch
+	branch string
+	base   string
+	// pushTo is an existing branch the worker pushes to instead of its own
+	pushTo string
+	claude state.ClaudeOptions
+	// documentation is the CLI reference appended to the worker's prompt
+	documentation string
+}
+
+// createdWorker describes a worker createWorker started
+type createdWorker struct {
+	name         string
+	branch       string
+	base         string
+	worktreePath string
+}
+
+// createWorker creates a worker: a worktree on a new branch, a tmux window,
+// and a Claude session given the task as its first message. Both
+// `multiclaude work` and the task queue create workers this way.
+func (d *Daemon) createWorker(repoName string, repo *state.Repository, spec workerSpec) (createdWorker, error) {
+	workerName := spec.name
+	if workerName == "" {
+		workerName = d.generateWorkerName(repoName)
+	}
+	if _, exists := d.state.GetAgent(repoName, workerName); exists {
+		return createdWorker{}, fmt.Errorf("agent %q already exists in repository %q", workerName, repoName)
+	}
+
+	repoPath := d.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+
+	// Start from the latest code; fetch failures (e.g. offline) fall back to local refs
+	if err := wt.FetchRemote("origin"); err != nil {
+		d.logger.Warn("Failed to fetch origin for worker %s/%s: %v", repoName, workerName, err)
+	}
+	startBranch, base, err := d.workerStartPoint(repoName, wt, spec.base)
+	if err != nil {
+		return createdWorker{}, fmt.Errorf("invalid base: %w", err)
+	}
+	if spec.branch != "" {
+		startBranch = spec.branch
+	}
+
+	branchName := spec.pushTo
+	if branchName == "" {
+		branchName = fmt.Sprintf("multiclaude/%s", workerName)
+	}
+	wtPath := d.paths.AgentWorktree(repoName, workerName)
+	localBranch, _ := wt.BranchExists(branchName)
+	if spec.pushTo != "" && spec.branch == spec.pushTo && localBranch {
+		// Continue on the local branch itself, e.g. a completed worker's
+		// branch handed to a conflict-resolution worker
+		err = wt.Create(wtPath, branchName)
+	} else {
+		err = wt.CreateNewBranch(wtPath, branchName, startBranch)
+	}
+	if err != nil {
+		return createdWorker{}, fmt.Errorf("failed to create worktree: %w", err)
+	}
+	if base != "" {
+		if err := wt.SetBranchBase(branchName, base); err != nil {
+			d.logger.Warn("Failed to record base branch for %s: %v", branchName, err)
+		}
+	}
+
+	hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+	if err == nil && !hasSession {
+		err = d.tmux.CreateSession(d.ctx, repo.TmuxSession, true)
+	}
+	if err != nil {
+		wt.Remove(wtPath, true)
+		return createdWorker{}, fmt.Errorf("failed to prepare tmux session: %w", err)
+	}
+
+	if err := d.tmux.CreateWindowInDir(d.ctx, repo.TmuxSession, workerName, wtPath); err != nil {
+		wt.Remove(wtPath, true)
+		return createdWorker{}, fmt.Errorf("failed to create tmux window: %w", err)
+	}
+
+	promptFile, err := d.writeWorkerPromptFile(repoName, workerName, spec.pushTo, base, spec.documentation)
+	if err == nil {
+		err = d.startAgentWithConfig(repoName, repo, agentStartConfig{
+			agentName:      workerName,
+			agentType:      state.AgentTypeWorker,
+			promptFile:     promptFile,
+			workDir:        wtPath,
+			task:           spec.task,
+			initialMessage: fmt.Sprintf("Task: %s", spec.task),
+			claude:         spec.claude,
+			baseBranch:     base,
+		})
+	}
+	if err != nil {
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, workerName)
+		wt.Remove(wtPath, true)
+		return createdWorker{}, err
+	}
+
+	return createdWorker{name: workerName, branch: branchName, base: base, worktreePath: wtPath}, nil
+}
+
+// queuedTaskClaudeOptions returns the Claude options a queued task was enqueued with
+func queuedTaskClaudeOptions(task state.QueuedTask) state.ClaudeOptions {
+	if task.Claude == nil {
+		return state.ClaudeOptions{}
+	}
+	return *task.Claude
+}
+
+// writeWorkerPromptFile writes a worker's prompt from the repo's worker
+// definition, with PR iteration instructions when pushing to an existing
+// branch, and local forge instructions for repos without GitHub
+func (d *Daemon) writeWorkerPromptFile(repoName, workerName, pushTo, base, documentation string) (string, error) {
+	promptText, err := prompts.GetWorkerDefinition(d.paths.RepoAgentsDir(repoName), d.paths.RepoDir(repoName))
+	if err != nil {
+		return "", err
+	}
+	if documentation != "" {
+		promptText += fmt.Sprintf("\n\n---\n\n%s", documentation)
+	}
+	if slashCommands := prompts.GetSlashCommandsPrompt(); slashCommands != "" {
+		promptText += fmt.Sprintf("\n\n---\n\n%s", slashCommands)
+	}
+
+	prefix := ""
+	if base != "" {
+		prefix = prompts.GenerateBaseBranchPrompt(base)
+	}
+	if pushTo != "" {
+		prefix = prompts.GeneratePushToPrompt(pushTo) + prefix
+	}
+	if repo, exists := d.state.GetRepo(repoName); exists && forge.IsLocal(repo.GithubURL) {
+		prefix += prompts.GenerateLocalForgePrompt()
+	}
+	return d.writePromptText(workerName, strings.TrimSpace(prefix), promptText)
+}
+
+// generateWorkerName returns a generated worker name not already used in the repo
+func (d *Daemon) generateWorkerName(repoName string) string {
+	name := names.Generate()
+	for i := 0; i < 10; i++ {
+		if _, exists := d.state.GetAgent(repoName, name); !exists {
+			break
+		}
+		name = names.Generate()
+	}
+	return name
+}
+
+// queuedTaskData converts a queued task to its socket representation
+func queuedTaskData(task state.QueuedTask, position int) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":          task.ID,
+		"position":    position,
+		"task":        task.Task,
+		"name":        task.Name,
+		"branch":      task.Branch,
+		"base":        task.Base,
+		"push_to":     task.PushTo,
+		"after":       task.After,
+		"enqueued_at": task.EnqueuedAt.Format(time.RFC3339),
+		"last_error":  task.LastError,
+		"attempts":    task.Attempts,
+	}
+	if !task.RetryAt.IsZero() {
+		data["retry_at"] = task.RetryAt.Format(time.RFC3339)
+	}
+	if task.Claude != nil {
+		data["claude"] = claudeOptionsData(*task.Claude)
+	}
+	return data
+}
+
+// handleEnqueueTask adds a worker task to a repository's queue. The task
+// starts right away if the repository has a free worker slot and the task
+// isn't waiting on another task's PR.
+func (d *Daemon) handleEnqueueTask(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	taskText, errResp, ok := getRequiredStringArg(req.Args, "task", "task description is required")
+	if !ok {
+		return errResp
+	}
+
+	task := state.QueuedTask{
+		ID:         fmt.Sprintf("task-%s", uuid.New().String()[:8]),
+		Task:       taskText,
+		EnqueuedAt: time.Now(),
+	}
+	task.Name, _ = req.Args["name"].(string)
+	task.Branch, _ = req.Args["branch"].(string)
+	task.Base, _ = req.Args["base"].(string)
+	task.PushTo, _ = req.Args["push_to"].(string)
+	task.After, _ = req.Args["after"].(string)
+	task.Documentation, _ = req.Args["documentation"].(string)
+
+	if task.Base != "" {
+		if err := worktree.ValidateBranchName(task.Base); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid base: %v", err)}
+		}
+	}
+
+	claudeOpts, err := claudeOptionsArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if !claudeOpts.IsZero() {
+		task.Claude = &claudeOpts
+	}
+
+	if task.Name != "" {
+		if _, exists := d.state.GetAgent(repoName, task.Name); exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("agent %q already exists in repository %q", task.Name, repoName)}
+		}
+	}
+
+	if task.After != "" {
+		repo, exists := d.state.GetAllRepos()[repoName]
+		if !exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+		}
+		if _, reason := taskDependencyStatus(repo, task.After); reason != "" {
+			return socket.Response{Success: false, Error: fmt.Sprintf("cannot wait on %q: %s", task.After, reason)}
+		}
+	}
+
+	position, err := d.state.EnqueueTask(repoName, task)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Queued task %s in repo %s at position %d: %s", task.ID, repoName, position, task.Task)
+
+	d.startQueuedTasksAsync(repoName)
+
+	return socket.Response{Success: true, Data: queuedTaskData(task, position)}
+}
+
+// handleCreateWorker creates a worker right away if the repository has a
+// free worker slot. At max_workers, or with tasks already waiting, the worker
+// is queued instead and the response holds the queued task.
+func (d *Daemon) handleCreateWorker(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	taskText, errResp, ok := getRequiredStringArg(req.Args, "task", "task description is required")
+	if !ok {
+		return errResp
+	}
+
+	spec := workerSpec{task: taskText}
+	spec.name, _ = req.Args["name"].(string)
+	spec.branch, _ = req.Args["branch"].(string)
+	spec.base, _ = req.Args["base"].(string)
+	spec.pushTo, _ = req.Args["push_to"].(string)
+	spec.documentation, _ = req.Args["documentation"].(string)
+
+	if spec.base != "" {
+		if err := worktree.ValidateBranchName(spec.base); err != nil {
+			return socket.Response{Success: false, Error: fmt.Sprintf("invalid base: %v", err)}
+		}
+	}
+
+	claudeOpts, err := claudeOptionsArg(req.Args)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	spec.claude = claudeOpts
+
+	// Checking the limit and creating the worker under queueMu keeps
+	// concurrent creates and queued starts from taking the same slot
+	d.queueMu.Lock()
+	defer d.queueMu.Unlock()
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+	}
+	active, err := d.state.ActiveWorkerCount(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	if repo.MaxWorkers > 0 && (active >= repo.MaxWorkers || len(repo.TaskQueue) > 0) {
+		return d.queueWorker(repoName, spec)
+	}
+
+	worker, err := d.createWorker(repoName, repo, spec)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	data := map[string]interface{}{
+		"name":          worker.name,
+		"branch":        worker.branch,
+		"base":          worker.base,
+		"worktree_path": worker.worktreePath,
+		"tmux_session":  repo.TmuxSession,
+	}
+	if agent, exists := d.state.GetAgent(repoName, worker.name); exists && agent.Claude != nil {
+		data["claude"] = claudeOptionsData(*agent.Claude)
+	}
+	return socket.Response{Success: true, Data: data}
+}
+
+// queueWorker queues a worker that can't start yet because the repository is
+// at its worker limit
+func (d *Daemon) queueWorker(repoName string, spec workerSpec) socket.Response {
+	if spec.name != "" {
+		if _, exists := d.state.GetAgent(repoName, spec.name); exists {
+			return socket.Response{Success: false, Error: fmt.Sprintf("agent %q already exists in repository %q", spec.name, repoName)}
+		}
+	}
+
+	task := state.QueuedTask{
+		ID:            fmt.Sprintf("task-%s", uuid.New().String()[:8]),
+		Task:          spec.task,
+		Name:          spec.name,
+		Branch:        spec.branch,
+		Base:          spec.base,
+		PushTo:        spec.pushTo,
+		EnqueuedAt:    time.Now(),
+		Documentation: spec.documentation,
+	}
+	if !spec.claude.IsZero() {
+		claudeOpts := spec.claude
+		task.Claude = &claudeOpts
+	}
+
+	position, err := d.state.EnqueueTask(repoName, task)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	d.logger.Info("Repo %s is at its worker limit, queued task %s at position %d: %s", repoName, task.ID, position, task.Task)
+
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"queued": queuedTaskData(task, position),
+	}}
+}
+
+// handleListTaskQueue returns a repository's queued tasks and worker usage
+func (d *Daemon) handleListTaskQueue(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+	}
+	active, err := d.state.ActiveWorkerCount(repoName)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	tasks := make([]interface{}, len(repo.TaskQueue))
+	for i, task := range repo.TaskQueue {
+		tasks[i] = queuedTaskData(task, i+1)
+	}
+
+	return socket.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"tasks":          tasks,
+			"max_workers":    repo.MaxWorkers,
+			"active_workers": active,
+		},
+	}
+}
+
+// handleMoveQueuedTask moves a queued task to a new position in the queue
+func (d *Daemon) handleMoveQueuedTask(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	taskID, errResp, ok := getRequiredStringArg(req.Args, "id", "task ID is required")
+	if !ok {
+		return errResp
+	}
+
+	position, ok := req.Args["position"].(float64)
+	if !ok {
+		return socket.Response{Success: false, Error: "position is required"}
+	}
+
+	if err := d.state.MoveQueuedTask(repoName, taskID, int(position)); err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Moved queued task %s in repo %s to position %d", taskID, repoName, int(position))
+	return socket.Response{Success: true}
+}
+
+// handleCancelQueuedTask removes a task from a repository's queue
+func (d *Daemon) handleCancelQueuedTask(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+
+	taskID, errResp, ok := getRequiredStringArg(req.Args, "id", "task ID is required")
+	if !ok {
+		return errResp
+	}
+
+	task, err := d.state.RemoveQueuedTask(repoName, taskID)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+
+	d.logger.Info("Cancelled queued task %s in repo %s: %s", taskID, repoName, task.Task)
+	return socket.Response{Success: true, Data: queuedTaskData(task, 0)}
+}
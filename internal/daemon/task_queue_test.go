@@ -0,0 +1,576 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// setupQueueTestRepo registers test-repo with a real tmux session and git repo
+// so queued tasks can be started as workers
+func setupQueueTestRepo(t *testing.T, d *Daemon, maxWorkers int) string {
+	t.Helper()
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+
+	sessionName := "mc-test-queue"
+	if err := d.tmux.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	t.Cleanup(func() { d.tmux.KillSession(context.Background(), sessionName) })
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: sessionName,
+		Agents:      make(map[string]state.Agent),
+		MaxWorkers:  maxWorkers,
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	return sessionName
+}
+
+func enqueueTestTasks(t *testing.T, d *Daemon, tasks ...state.QueuedTask) {
+	t.Helper()
+	for _, task := range tasks {
+		if task.EnqueuedAt.IsZero() {
+			task.EnqueuedAt = time.Now()
+		}
+		if _, err := d.state.EnqueueTask("test-repo", task); err != nil {
+			t.Fatalf("Failed to enqueue %s: %v", task.ID, err)
+		}
+	}
+}
+
+func TestStartQueuedTasksRespectsMaxWorkers(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	sessionName := setupQueueTestRepo(t, d, 2)
+
+	if err := d.state.AddAgent("test-repo", "busy-bee", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "busy-bee", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+	enqueueTestTasks(t, d,
+		state.QueuedTask{ID: "task-1", Task: "First task", Name: "first-worker", Documentation: "CLI REFERENCE"},
+		state.QueuedTask{ID: "task-2", Task: "Second task", Name: "second-worker"},
+		state.QueuedTask{ID: "task-3", Task: "Third task"},
+	)
+
+	// One slot is free
+	d.startQueuedTasks("test-repo")
+
+	worker, exists := d.state.GetAgent("test-repo", "first-worker")
+	if !exists {
+		t.Fatal("first queued task should have started")
+	}
+	if worker.Type != state.AgentTypeWorker || worker.Task != "First task" {
+		t.Errorf("started worker = %+v", worker)
+	}
+	if _, err := os.Stat(worker.WorktreePath); err != nil {
+		t.Errorf("worker worktree should exist: %v", err)
+	}
+	if hasWindow, _ := d.tmux.HasWindow(context.Background(), sessionName, "first-worker"); !hasWindow {
+		t.Error("worker tmux window should exist")
+	}
+	prompt, err := os.ReadFile(filepath.Join(d.paths.Root, "prompts", "first-worker.md"))
+	if err != nil || !strings.Contains(string(prompt), "CLI REFERENCE") {
+		t.Errorf("queued worker prompt should include the documentation, err=%v", err)
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 2 || queue[0].ID != "task-2" {
+		t.Fatalf("queue after first pass = %+v", queue)
+	}
+
+	// A completed worker frees its slot
+	busy, _ := d.state.GetAgent("test-repo", "busy-bee")
+	busy.ReadyForCleanup = true
+	if err := d.state.UpdateAgent("test-repo", "busy-bee", busy); err != nil {
+		t.Fatalf("Failed to update worker: %v", err)
+	}
+	d.startQueuedTasks("test-repo")
+
+	if _, exists := d.state.GetAgent("test-repo", "second-worker"); !exists {
+		t.Error("second queued task should start once a worker completes")
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 1 || queue[0].ID != "task-3" {
+		t.Errorf("queue after second pass = %+v", queue)
+	}
+
+	// Removing the limit starts the rest, with a generated name
+	if err := d.state.SetMaxWorkers("test-repo", 0); err != nil {
+		t.Fatalf("Failed to set max workers: %v", err)
+	}
+	d.startQueuedTasks("test-repo")
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 0 {
+		t.Errorf("queue should be empty without a limit, got %+v", queue)
+	}
+	if count, _ := d.state.ActiveWorkerCount("test-repo"); count != 3 {
+		t.Errorf("active workers = %d, want 3", count)
+	}
+}
+
+func TestStartQueuedTasksRequeuesFailures(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	if err := d.state.AddAgent("test-repo", "taken", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "taken", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	enqueueTestTasks(t, d,
+		state.QueuedTask{ID: "task-1", Task: "Clashing name", Name: "taken"},
+		state.QueuedTask{ID: "task-2", Task: "Starts despite the failure", Name: "next-worker"},
+	)
+
+	d.startQueuedTasks("test-repo")
+
+	// The failed task keeps its place and waits to be retried, and the
+	// task behind it starts
+	if _, exists := d.state.GetAgent("test-repo", "next-worker"); !exists {
+		t.Error("the task behind a failed task should start")
+	}
+	queue, _ := d.state.GetTaskQueue("test-repo")
+	if len(queue) != 1 || queue[0].ID != "task-1" {
+		t.Fatalf("failed task should stay queued, got %+v", queue)
+	}
+	if !strings.Contains(queue[0].LastError, "already exists") || queue[0].Attempts != 1 {
+		t.Errorf("task = %+v, want one failed attempt with the start failure", queue[0])
+	}
+	if wait := time.Until(queue[0].RetryAt); wait <= 0 || wait > queuedTaskRetryDelay {
+		t.Errorf("RetryAt = %v, want about %v from now", queue[0].RetryAt, queuedTaskRetryDelay)
+	}
+
+	// Not retried before RetryAt
+	d.startQueuedTasks("test-repo")
+	if queue, _ := d.state.GetTaskQueue("test-repo"); queue[0].Attempts != 1 {
+		t.Errorf("task retried before its retry time: %+v", queue[0])
+	}
+
+	// Retried with a growing delay until it has failed too often
+	for attempt := 2; attempt <= maxQueuedTaskAttempts; attempt++ {
+		task, _ := d.state.RemoveQueuedTask("test-repo", "task-1")
+		task.RetryAt = time.Now().Add(-time.Second)
+		if err := d.state.RequeueTask("test-repo", task); err != nil {
+			t.Fatalf("Failed to requeue task: %v", err)
+		}
+		d.startQueuedTasks("test-repo")
+
+		queue, _ := d.state.GetTaskQueue("test-repo")
+		if queue[0].Attempts != attempt {
+			t.Fatalf("attempts = %d, want %d", queue[0].Attempts, attempt)
+		}
+		if attempt < maxQueuedTaskAttempts && time.Until(queue[0].RetryAt) <= queuedTaskRetryDelay {
+			t.Errorf("attempt %d RetryAt = %v, want a longer delay", attempt, queue[0].RetryAt)
+		}
+	}
+	queue, _ = d.state.GetTaskQueue("test-repo")
+	if !queue[0].RetryAt.IsZero() {
+		t.Errorf("RetryAt = %v after the last attempt, want none", queue[0].RetryAt)
+	}
+	if _, ok := d.nextReadyTask("test-repo", d.state.GetAllRepos()["test-repo"]); ok {
+		t.Error("a task that failed too often shouldn't be ready")
+	}
+}
+
+func TestCreateWorker(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	sessionName := setupQueueTestRepo(t, d, 2)
+
+	if err := d.state.AddAgent("test-repo", "busy-bee", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "busy-bee", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "create_worker", Args: map[string]interface{}{
+		"repo":          "test-repo",
+		"task":          "Fix the login bug",
+		"name":          "direct-worker",
+		"documentation": "CLI REFERENCE",
+		"claude":        map[string]interface{}{"model": "opus"},
+	}})
+	if !resp.Success {
+		t.Fatalf("create_worker failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["name"] != "direct-worker" || data["branch"] != "multiclaude/direct-worker" || data["tmux_session"] != sessionName {
+		t.Errorf("create_worker data = %v", data)
+	}
+
+	worker, exists := d.state.GetAgent("test-repo", "direct-worker")
+	if !exists {
+		t.Fatal("worker should be registered")
+	}
+	if worker.Task != "Fix the login bug" || worker.WorktreePath != data["worktree_path"] {
+		t.Errorf("worker = %+v", worker)
+	}
+	if worker.Claude == nil || worker.Claude.Model != "opus" {
+		t.Errorf("worker Claude options = %+v", worker.Claude)
+	}
+	if hasWindow, _ := d.tmux.HasWindow(context.Background(), sessionName, "direct-worker"); !hasWindow {
+		t.Error("worker tmux window should exist")
+	}
+	prompt, err := os.ReadFile(filepath.Join(d.paths.Root, "prompts", "direct-worker.md"))
+	if err != nil || !strings.Contains(string(prompt), "CLI REFERENCE") {
+		t.Errorf("worker prompt should include the documentation, err=%v", err)
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 0 {
+		t.Errorf("create_worker with a free slot shouldn't queue, got %+v", queue)
+	}
+
+	// At the limit the worker is queued, with everything it was created with
+	resp = d.handleRequest(socket.Request{Command: "create_worker", Args: map[string]interface{}{
+		"repo":          "test-repo",
+		"task":          "Fix the logout bug",
+		"name":          "waiting-worker",
+		"documentation": "CLI REFERENCE",
+	}})
+	if !resp.Success {
+		t.Fatalf("create_worker at the limit failed: %s", resp.Error)
+	}
+	queued, ok := resp.Data.(map[string]interface{})["queued"].(map[string]interface{})
+	if !ok || queued["name"] != "waiting-worker" || queued["position"] != 1 {
+		t.Errorf("create_worker at the limit data = %v, want a queued task", resp.Data)
+	}
+	if _, exists := d.state.GetAgent("test-repo", "waiting-worker"); exists {
+		t.Error("create_worker at the limit shouldn't start a worker")
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 1 || queue[0].Documentation != "CLI REFERENCE" {
+		t.Errorf("queue = %+v, want the worker's task with its documentation", queue)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"repo": "test-repo"},
+		{"repo": "test-repo", "task": "Clash", "name": "busy-bee"},
+		{"repo": "test-repo", "task": "Bad base", "base": "bad..base"},
+		{"repo": "missing", "task": "Anything"},
+	} {
+		if resp := d.handleRequest(socket.Request{Command: "create_worker", Args: args}); resp.Success {
+			t.Errorf("create_worker %v should fail", args)
+		}
+	}
+}
+
+func TestCreateWorkerConcurrentLimit(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 1)
+
+	// Two creates racing for the last slot: one starts, the other queues
+	var wg sync.WaitGroup
+	for _, name := range []string{"racer-one", "racer-two"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			resp := d.handleRequest(socket.Request{Command: "create_worker", Args: map[string]interface{}{
+				"repo": "test-repo",
+				"task": "Race for the slot",
+				"name": name,
+			}})
+			if !resp.Success {
+				t.Errorf("create_worker %s failed: %s", name, resp.Error)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if count, _ := d.state.ActiveWorkerCount("test-repo"); count != 1 {
+		t.Errorf("active workers = %d, want 1", count)
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 1 {
+		t.Errorf("queue = %+v, want the losing worker queued", queue)
+	}
+}
+
+func TestEnqueueTaskStartIsTracked(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	resp := d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+		"repo": "test-repo",
+		"task": "Start in the background",
+		"name": "background-worker",
+	}})
+	if !resp.Success {
+		t.Fatalf("enqueue_task failed: %s", resp.Error)
+	}
+
+	// Shutdown waits on d.wg, so the worker must exist once it's done
+	d.wg.Wait()
+	if _, exists := d.state.GetAgent("test-repo", "background-worker"); !exists {
+		t.Error("queued task should have started before d.wg was released")
+	}
+
+	// Nothing starts once shutdown has begun
+	d.cancel()
+	enqueueTestTasks(t, d, state.QueuedTask{ID: "task-late", Task: "Too late", Name: "late-worker"})
+	d.startQueuedTasksAsync("test-repo")
+	d.wg.Wait()
+	if _, exists := d.state.GetAgent("test-repo", "late-worker"); exists {
+		t.Error("no task should start after shutdown began")
+	}
+}
+
+func TestTaskQueueSocketCommands(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	// At the limit, so enqueued tasks stay queued
+	if err := d.state.SetMaxWorkers("test-repo", 1); err != nil {
+		t.Fatalf("Failed to set max workers: %v", err)
+	}
+	if err := d.state.AddAgent("test-repo", "worker-1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add worker: %v", err)
+	}
+
+	var ids []string
+	for _, task := range []string{"Fix the login bug", "Add dark mode", "Write docs"} {
+		resp := d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+			"repo":   "test-repo",
+			"task":   task,
+			"branch": "origin/develop",
+		}})
+		if !resp.Success {
+			t.Fatalf("enqueue_task failed: %s", resp.Error)
+		}
+		data := resp.Data.(map[string]interface{})
+		if data["position"] != len(ids)+1 || data["branch"] != "origin/develop" {
+			t.Errorf("enqueue_task data = %v", data)
+		}
+		ids = append(ids, data["id"].(string))
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "move_queued_task", Args: map[string]interface{}{
+		"repo": "test-repo", "id": ids[2], "position": float64(1),
+	}})
+	if !resp.Success {
+		t.Fatalf("move_queued_task failed: %s", resp.Error)
+	}
+	resp = d.handleRequest(socket.Request{Command: "cancel_queued_task", Args: map[string]interface{}{
+		"repo": "test-repo", "id": ids[0],
+	}})
+	if !resp.Success {
+		t.Fatalf("cancel_queued_task failed: %s", resp.Error)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "list_task_queue", Args: map[string]interface{}{"repo": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("list_task_queue failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["max_workers"] != 1 || data["active_workers"] != 1 {
+		t.Errorf("list_task_queue usage = %v", data)
+	}
+	tasks := data["tasks"].([]interface{})
+	if len(tasks) != 2 {
+		t.Fatalf("list_task_queue tasks = %v", tasks)
+	}
+	if first := tasks[0].(map[string]interface{}); first["id"] != ids[2] || first["task"] != "Write docs" || first["position"] != 1 {
+		t.Errorf("first queued task = %v", first)
+	}
+
+	invalid := []socket.Request{
+		{Command: "enqueue_task", Args: map[string]interface{}{"repo": "test-repo"}},
+		{Command: "enqueue_task", Args: map[string]interface{}{"repo": "test-repo", "task": "Clash", "name": "worker-1"}},
+		{Command: "enqueue_task", Args: map[string]interface{}{"repo": "missing", "task": "Anything"}},
+		{Command: "move_queued_task", Args: map[string]interface{}{"repo": "test-repo", "id": ids[1]}},
+		{Command: "move_queued_task", Args: map[string]interface{}{"repo": "test-repo", "id": "task-missing", "position": float64(1)}},
+		{Command: "cancel_queued_task", Args: map[string]interface{}{"repo": "test-repo", "id": ids[0]}},
+		{Command: "list_task_queue", Args: map[string]interface{}{"repo": "missing"}},
+	}
+	for _, req := range invalid {
+		if resp := d.handleRequest(req); resp.Success {
+			t.Errorf("%s(%v) should fail", req.Command, req.Args)
+		}
+	}
+}
+
+func TestRepoConfigMaxWorkers(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":        "test-repo",
+		"max_workers": float64(10),
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	if got := resp.Data.(map[string]interface{})["max_workers"]; got != 10 {
+		t.Errorf("max_workers = %v, want 10", got)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":        "test-repo",
+		"max_workers": float64(-1),
+	}})
+	if resp.Success {
+		t.Error("update_repo_config should reject a negative max_workers")
+	}
+}
+
+func TestTaskDependencyStatus(t *testing.T) {
+	repo := &state.Repository{
+		Agents: map[string]state.Agent{
+			"running-worker": {Type: state.AgentTypeWorker},
+			"supervisor":     {Type: state.AgentTypeSupervisor},
+		},
+		TaskQueue: []state.QueuedTask{{ID: "task-queued"}},
+		TaskHistory: []state.TaskHistoryEntry{
+			{Name: "merged-worker", Status: state.TaskStatusMerged},
+			{Name: "open-worker", Status: state.TaskStatusOpen},
+			{Name: "done-worker", Status: state.TaskStatusUnknown},
+			{Name: "closed-worker", Status: state.TaskStatusClosed},
+			{Name: "failed-worker", Status: state.TaskStatusFailed},
+			{Name: "reused-worker", Status: state.TaskStatusClosed},
+			{Name: "reused-worker", Status: state.TaskStatusMerged},
+		},
+	}
+
+	tests := []struct {
+		after string
+		want  dependencyStatus
+	}{
+		{"", dependencyReady},
+		{"merged-worker", dependencyReady},
+		{"reused-worker", dependencyReady},
+		{"task-queued", dependencyPending},
+		{"running-worker", dependencyPending},
+		{"open-worker", dependencyPending},
+		{"done-worker", dependencyPending},
+		{"closed-worker", dependencyFailed},
+		{"failed-worker", dependencyFailed},
+		{"supervisor", dependencyFailed},
+		{"missing", dependencyFailed},
+	}
+
+	for _, tt := range tests {
+		got, reason := taskDependencyStatus(repo, tt.after)
+		if got != tt.want {
+			t.Errorf("taskDependencyStatus(%q) = %v, want %v", tt.after, got, tt.want)
+		}
+		if (got == dependencyFailed) != (reason != "") {
+			t.Errorf("taskDependencyStatus(%q) reason = %q", tt.after, reason)
+		}
+	}
+}
+
+func TestStartQueuedTasksWaitsForDependencies(t *testing.T) {
+	d, _, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	setupQueueTestRepo(t, d, 0)
+
+	if err := d.state.AddAgent("test-repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to add supervisor: %v", err)
+	}
+	if err := d.state.AddTaskHistory("test-repo", state.TaskHistoryEntry{Name: "abandoned", Status: state.TaskStatusClosed}); err != nil {
+		t.Fatalf("Failed to add history: %v", err)
+	}
+	enqueueTestTasks(t, d,
+		state.QueuedTask{ID: "task-refactor", Task: "Refactor X", Name: "refactor-x"},
+		state.QueuedTask{ID: "task-build", Task: "Build Y on X", Name: "build-y", After: "task-refactor"},
+		state.QueuedTask{ID: "task-orphan", Task: "Follow up", After: "abandoned"},
+	)
+
+	// Only the task without a dependency starts; its dependents now wait on its worker
+	d.startQueuedTasks("test-repo")
+
+	if _, exists := d.state.GetAgent("test-repo", "refactor-x"); !exists {
+		t.Fatal("task without a dependency should start")
+	}
+	queue, _ := d.state.GetTaskQueue("test-repo")
+	if len(queue) != 2 || queue[0].ID != "task-build" || queue[0].After != "refactor-x" {
+		t.Fatalf("queue after first pass = %+v", queue)
+	}
+	if !strings.Contains(queue[1].LastError, "closed") {
+		t.Errorf("task after a closed PR should be flagged, LastError = %q", queue[1].LastError)
+	}
+	msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "task-orphan") {
+		t.Errorf("supervisor should be told about the blocked task once, got %v, %v", msgs, err)
+	}
+
+	// The worker finishes and opens a PR: still waiting
+	if err := d.state.RemoveAgent("test-repo", "refactor-x"); err != nil {
+		t.Fatalf("Failed to remove worker: %v", err)
+	}
+	if err := d.state.AddTaskHistory("test-repo", state.TaskHistoryEntry{Name: "refactor-x", Status: state.TaskStatusOpen}); err != nil {
+		t.Fatalf("Failed to add history: %v", err)
+	}
+	d.startQueuedTasks("test-repo")
+	if _, exists := d.state.GetAgent("test-repo", "build-y"); exists {
+		t.Fatal("dependent task should wait until the PR merges")
+	}
+
+	// The PR merges
+	if err := d.state.UpdateTaskHistoryStatus("test-repo", "refactor-x", state.TaskStatusMerged, "", 0); err != nil {
+		t.Fatalf("Failed to update history: %v", err)
+	}
+	d.startQueuedTasks("test-repo")
+	if _, exists := d.state.GetAgent("test-repo", "build-y"); !exists {
+		t.Error("dependent task should start once the PR merges")
+	}
+	queue, _ = d.state.GetTaskQueue("test-repo")
+	if len(queue) != 1 || queue[0].ID != "task-orphan" {
+		t.Errorf("only the blocked task should remain queued, got %+v", queue)
+	}
+	if msgs, _ := d.getMessageManager().List("test-repo", "supervisor"); len(msgs) != 1 {
+		t.Errorf("blocked task should only be reported once, got %d messages", len(msgs))
+	}
+}
+
+func TestEnqueueTaskValidatesDependency(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	// At the limit, so enqueued tasks stay queued
+	if err := d.state.SetMaxWorkers("test-repo", 1); err != nil {
+		t.Fatalf("Failed to set max workers: %v", err)
+	}
+	for name, agentType := range map[string]state.AgentType{"supervisor": state.AgentTypeSupervisor, "worker-1": state.AgentTypeWorker} {
+		if err := d.state.AddAgent("test-repo", name, state.Agent{Type: agentType, TmuxWindow: name, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+		"repo": "test-repo", "task": "Build on worker-1", "after": "worker-1",
+	}})
+	if !resp.Success {
+		t.Fatalf("enqueue_task after a running worker failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["after"] != "worker-1" {
+		t.Errorf("enqueue_task after = %v", data["after"])
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+		"repo": "test-repo", "task": "Build on the queued task", "after": data["id"],
+	}})
+	if !resp.Success {
+		t.Errorf("enqueue_task after a queued task failed: %s", resp.Error)
+	}
+
+	for _, after := range []string{"supervisor", "no-such-worker"} {
+		resp := d.handleRequest(socket.Request{Command: "enqueue_task", Args: map[string]interface{}{
+			"repo": "test-repo", "task": "Anything", "after": after,
+		}})
+		if resp.Success {
+			t.Errorf("enqueue_task after %q should fail", after)
+		}
+	}
+}
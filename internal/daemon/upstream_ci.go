@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/fork"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// checkCILayer looks up the checks on the head of branch in the GitHub
+// repository at repoURL. If they can't be looked up, the previous status is
+// kept so a transient gh failure doesn't look like a CI transition.
+func (d *Daemon) checkCILayer(repoName, layer, repoURL, branch string, prev state.CILayerStatus) state.CILayerStatus {
+	owner, name, err := fork.ParseGitHubURL(repoURL)
+	if err != nil {
+		d.logger.Debug("Skipping %s CI check for %s: %v", layer, repoName, err)
+		return prev
+	}
+	if branch == "" {
+		d.logger.Debug("Skipping %s CI check for %s: default branch unknown", layer, repoName)
+		return prev
+	}
+
+	checks, err := d.github.CommitChecks(d.paths.RepoDir(repoName), owner+"/"+name, branch)
+	if err != nil {
+		d.logger.Warn("Failed to check %s CI for %s: %v", layer, repoName, err)
+		return prev
+	}
+
+	now := time.Now()
+	curr := state.CILayerStatus{
+		Status:     checks.Status,
+		LastCheck:  now,
+		LastCommit: checks.SHA,
+		CheckURL:   checks.URL,
+	}
+	if curr.Status == "" {
+		curr.Status = "unknown" // No checks ran on the commit
+	}
+	if curr.Status == github.CIFailing {
+		curr.FailingSince = &now
+		if prev.Status == github.CIFailing && prev.FailingSince != nil {
+			curr.FailingSince = prev.FailingSince
+		}
+	}
+	return curr
+}
+
+// upstreamDefaultBranch returns the name of the upstream's default branch,
+// falling back to the fork's if the upstream's can't be found
+func (d *Daemon) upstreamDefaultBranch(repoName string, repo *state.Repository) string {
+	remote := repo.UpstreamConfig.UpstreamRemote
+	ref, err := worktree.NewManager(d.paths.RepoDir(repoName)).UpstreamBranchRef(remote, repo.DefaultBranch)
+	if err != nil {
+		return d.repoDefaultBranch(repoName)
+	}
+	return strings.TrimPrefix(ref, remote+"/")
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// useFakeGhAPI points the daemon's GitHub client at a gh script whose
+// "gh api <path>" prints the response set for path with setResponse, and
+// fails for paths without one
+func useFakeGhAPI(t *testing.T, d *Daemon) (setResponse func(path, json string)) {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gh")
+	script := "#!/bin/sh\n" +
+		"f=\"" + dir + "/$(echo \"$2\" | tr '/?=' '___').json\"\n" +
+		"if [ -f \"$f\" ]; then cat \"$f\"; else echo 'gh: Not Found (HTTP 404)' >&2; exit 1; fi\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	d.github = github.NewClient(bin)
+
+	return func(path, json string) {
+		t.Helper()
+		file := filepath.Join(dir, strings.NewReplacer("/", "_", "?", "_", "=", "_").Replace(path)+".json")
+		if err := os.WriteFile(file, []byte(json), 0644); err != nil {
+			t.Fatalf("Failed to write fake response: %v", err)
+		}
+	}
+}
+
+func TestCheckForkUpstreamCI(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     "https://github.com/me/repo",
+		TmuxSession:   "mc-test-repo",
+		DefaultBranch: "main",
+		Agents:        make(map[string]state.Agent),
+		UpstreamConfig: &state.UpstreamConfig{
+			UpstreamURL:    "https://github.com/them/repo",
+			UpstreamRemote: "upstream",
+			ForkRemote:     "origin",
+			SyncEnabled:    true,
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := os.MkdirAll(d.paths.RepoDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	setResponse := useFakeGhAPI(t, d)
+	setChecks := func(ownerRepo, sha, conclusion string) {
+		t.Helper()
+		setResponse("repos/"+ownerRepo+"/commits/main/status", `{"sha": "`+sha+`", "statuses": []}`)
+		setResponse("repos/"+ownerRepo+"/commits/main/check-runs?per_page=100", `{"check_runs": [
+			{"name": "test", "status": "completed", "conclusion": "`+conclusion+`", "html_url": "https://github.com/`+ownerRepo+`/runs/1"}]}`)
+	}
+
+	// Both layers pass; first observations stay quiet
+	setChecks("me/repo", "aaaaaaaa1", "success")
+	setChecks("them/repo", "bbbbbbbb1", "success")
+	d.checkForkUpstreamStatus()
+	status, _ := d.state.GetDualCIStatus("test-repo")
+	if status == nil || status.ForkCI.Status != "passing" || status.ForkCI.LastCommit != "aaaaaaaa1" || status.UpstreamCI.Status != "passing" {
+		t.Fatalf("dual CI status = %+v, want both layers passing", status)
+	}
+
+	// The fork breaks
+	setChecks("me/repo", "aaaaaaaa2", "failure")
+	d.checkForkUpstreamStatus()
+	status, _ = d.state.GetDualCIStatus("test-repo")
+	fork := status.ForkCI
+	if fork.Status != "failing" || fork.FailingSince == nil || fork.CheckURL != "https://github.com/me/repo/runs/1" {
+		t.Errorf("fork CI = %+v, want failing with its check URL", fork)
+	}
+	failingSince := *fork.FailingSince
+
+	// Still failing on the next commit, and gh failing for the upstream keeps
+	// its last status
+	setChecks("me/repo", "aaaaaaaa3", "failure")
+	setResponse("repos/them/repo/commits/main/status", "not json")
+	d.checkForkUpstreamStatus()
+	status, _ = d.state.GetDualCIStatus("test-repo")
+	if fork := status.ForkCI; fork.LastCommit != "aaaaaaaa3" || fork.FailingSince == nil || !fork.FailingSince.Equal(failingSince) {
+		t.Errorf("fork CI = %+v, want failing since %v", fork, failingSince)
+	}
+	if upstream := status.UpstreamCI; upstream.Status != "passing" || upstream.LastCommit != "bbbbbbbb1" {
+		t.Errorf("upstream CI = %+v, want the last status kept", upstream)
+	}
+
+	// The fork is fixed
+	setChecks("me/repo", "aaaaaaaa4", "success")
+	d.checkForkUpstreamStatus()
+	status, _ = d.state.GetDualCIStatus("test-repo")
+	if fork := status.ForkCI; fork.Status != "passing" || fork.FailingSince != nil {
+		t.Errorf("fork CI = %+v, want passing", fork)
+	}
+
+	journaled, err := d.journal.Query(events.Filter{RepoName: "test-repo"})
+	if err != nil {
+		t.Fatalf("Failed to query journal: %v", err)
+	}
+	var got []string
+	for _, e := range journaled {
+		got = append(got, string(e.Type)+":"+e.Data["layer"].(string))
+	}
+	if want := []string{"ci_failed:fork", "ci_passed:fork"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
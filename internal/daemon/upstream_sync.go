@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/google/uuid"
+)
+
+// defaultSyncInterval is how often a repo's upstream is checked when its
+// sync interval isn't set
+const defaultSyncInterval = 30 * time.Minute
+
+// upstreamSync is the outcome of syncing a fork's default branch with upstream
+type upstreamSync struct {
+	UpstreamSHA string   // The upstream commit synced with
+	SHA         string   // The fork's new head, if it was pushed
+	UpToDate    bool     // The fork already had the upstream commit
+	Conflicts   []string // Files that conflicted, if the sync didn't happen
+}
+
+// upstreamDue reports whether a repo's upstream should be checked, given
+// its sync interval, and if so records it as checked now. force checks it
+// regardless of the interval.
+func (d *Daemon) upstreamDue(repoName string, config *state.UpstreamConfig, now time.Time, force bool) bool {
+	interval := defaultSyncInterval
+	if config.SyncInterval > 0 {
+		interval = time.Duration(config.SyncInterval) * time.Minute
+	}
+
+	d.upstreamMu.Lock()
+	defer d.upstreamMu.Unlock()
+	if last, ok := d.upstreamChecked[repoName]; ok && now.Sub(last) < interval && !force {
+		return false
+	}
+	d.upstreamChecked[repoName] = now
+	return true
+}
+
+// syncUpstream brings the fork's default branch up to date with upstream by
+// merging or rebasing in a temporary worktree, pushes it to the fork, then
+// moves the local branch along and refreshes worker worktrees.
+//
+// Conflicts are recorded in the repo's dual CI status and nothing is pushed.
+// Scheduled syncs don't retry an upstream commit that already conflicted;
+// the first conflict with it is reported to the supervisor, and queued for
+// a worker to resolve if the repo resolves conflicts.
+func (d *Daemon) syncUpstream(repoName string, repo *state.Repository, strategy string, scheduled bool) (upstreamSync, error) {
+	var result upstreamSync
+	config := repo.UpstreamConfig
+	if config == nil {
+		return result, fmt.Errorf("repository %q does not have upstream tracking enabled", repoName)
+	}
+	forkRemote := config.ForkRemote
+	if forkRemote == "" {
+		forkRemote = "origin"
+	}
+	branch := d.repoDefaultBranch(repoName)
+	if branch == "" {
+		return result, fmt.Errorf("could not determine the default branch of %s", repoName)
+	}
+
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	for _, remote := range []string{config.UpstreamRemote, forkRemote} {
+		if err := wt.FetchRemote(remote); err != nil {
+			return result, err
+		}
+	}
+	upstreamRef, err := wt.UpstreamBranchRef(config.UpstreamRemote, branch)
+	if err != nil {
+		return result, fmt.Errorf("failed to find upstream branch: %w", err)
+	}
+	forkRef := fmt.Sprintf("%s/%s", forkRemote, branch)
+	if result.UpstreamSHA, err = wt.ResolveCommit(upstreamRef); err != nil {
+		return result, err
+	}
+	forkSHA, err := wt.ResolveCommit(forkRef)
+	if err != nil {
+		return result, err
+	}
+
+	if wt.IsAncestor(result.UpstreamSHA, forkSHA) {
+		result.UpToDate = true
+		return result, nil
+	}
+	if scheduled && repo.DualCIStatus != nil && repo.DualCIStatus.SyncConflictSHA == result.UpstreamSHA {
+		result.Conflicts = repo.DualCIStatus.SyncConflicts
+		return result, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "multiclaude-sync-*")
+	if err != nil {
+		return result, fmt.Errorf("failed to create sync directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	synced, err := wt.SyncBranch(filepath.Join(tmpDir, "worktree"), forkRef, upstreamRef, strategy)
+	if err != nil {
+		return result, err
+	}
+	if synced.HasConflicts {
+		result.Conflicts = synced.ConflictFiles
+		d.logger.Warn("Upstream sync of %s conflicts with %s in: %v", repoName, upstreamRef, synced.ConflictFiles)
+		if err := d.state.SetSyncConflicts(repoName, result.UpstreamSHA, synced.ConflictFiles); err != nil {
+			d.logger.Error("Failed to record upstream sync conflicts for %s: %v", repoName, err)
+		}
+		if scheduled {
+			d.reportSyncConflict(repoName, repo, branch, upstreamRef, result.UpstreamSHA, synced)
+		}
+		return result, nil
+	}
+
+	// A rebase rewrites the fork's commits, so only replace the branch if
+	// nothing was pushed to it meanwhile
+	expected := ""
+	if strategy == state.SyncStrategyRebase {
+		expected = forkSHA
+	}
+	if err := wt.PushCommit(forkRemote, synced.SHA, branch, expected); err != nil {
+		return result, fmt.Errorf("failed to push synced %s: %w", branch, err)
+	}
+	result.SHA = synced.SHA
+	d.logger.Info("Synced %s %s with %s (%s): now at %s", repoName, branch, upstreamRef, strategy, shortSHA(synced.SHA))
+
+	if err := d.state.UpdateSyncTime(repoName, time.Now(), result.UpstreamSHA); err != nil {
+		d.logger.Error("Failed to record upstream sync for %s: %v", repoName, err)
+	}
+	if err := wt.FetchRemote(forkRemote); err != nil {
+		d.logger.Debug("Could not fetch %s after syncing %s: %v", forkRemote, repoName, err)
+	}
+	if moved, err := wt.AdvanceBranch(branch, forkSHA, synced.SHA); err != nil {
+		d.logger.Warn("Could not move local %s of %s to the synced commit: %v", branch, repoName, err)
+	} else if !moved {
+		d.logger.Debug("Local %s of %s has diverged from %s; leaving it", branch, repoName, forkRef)
+	}
+
+	d.refreshWorktrees()
+	return result, nil
+}
+
+// reportSyncConflict tells the supervisor that an upstream sync conflicted
+// and, if the repo resolves conflicts, queues a worker to do the merge
+func (d *Daemon) reportSyncConflict(repoName string, repo *state.Repository, branch, upstreamRef, upstreamSHA string, synced worktree.SyncResult) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The automatic sync of %s with %s (%s) conflicts, so nothing was pushed.\n\n", branch, upstreamRef, shortSHA(upstreamSHA))
+	fmt.Fprintf(&b, "Conflicting files:\n%s\n\n", formatFileList(synced.ConflictFiles))
+	if len(synced.ConflictCommits) > 0 {
+		fmt.Fprintf(&b, "Upstream commits that changed them:\n%s\n\n", formatFileList(synced.ConflictCommits))
+	}
+
+	if repo.ResolveConflicts {
+		task := state.QueuedTask{
+			ID: fmt.Sprintf("task-%s", uuid.New().String()[:8]),
+			Task: fmt.Sprintf("Sync %s with upstream: merge %s (%s) into your branch, resolve the conflicts in %s keeping the fork's changes working, "+
+				"make sure it builds and the tests pass, then create a PR into %s.",
+				branch, upstreamRef, shortSHA(upstreamSHA), strings.Join(synced.ConflictFiles, ", "), branch),
+			Base:       branch,
+			EnqueuedAt: time.Now(),
+		}
+		if _, err := d.state.EnqueueTask(repoName, task); err != nil {
+			d.logger.Error("Failed to queue upstream conflict resolution for %s: %v", repoName, err)
+		} else {
+			d.logger.Info("Queued task %s to resolve upstream sync conflicts for %s", task.ID, repoName)
+			fmt.Fprintf(&b, "Queued task %s to merge upstream and resolve the conflicts in a PR. Cancel it with: multiclaude work queue cancel %s", task.ID, task.ID)
+		}
+	} else {
+		fmt.Fprintf(&b, "Merge %s into %s and resolve the conflicts in a PR, or queue a worker to do it automatically next time with: multiclaude config %s --resolve-conflicts=true", upstreamRef, branch, repoName)
+	}
+
+	if _, exists := d.state.GetAgent(repoName, "supervisor"); !exists {
+		return
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", b.String()); err != nil {
+		d.logger.Error("Failed to notify supervisor about upstream sync conflicts for %s: %v", repoName, err)
+	}
+}
+
+// handleSyncUpstream syncs a repo's default branch with upstream now.
+// Args: repo, strategy (optional: "merge" or "rebase", defaulting to the
+// repo's sync strategy, or merge).
+func (d *Daemon) handleSyncUpstream(req socket.Request) socket.Response {
+	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
+	if !ok {
+		return errResp
+	}
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+	}
+
+	strategy, _ := req.Args["strategy"].(string)
+	if strategy == "" && repo.UpstreamConfig != nil {
+		strategy = repo.UpstreamConfig.SyncStrategy
+	}
+	if strategy == "" {
+		strategy = state.SyncStrategyMerge
+	}
+	if strategy != state.SyncStrategyMerge && strategy != state.SyncStrategyRebase {
+		return socket.Response{Success: false, Error: fmt.Sprintf("invalid sync strategy: %s (must be merge or rebase)", strategy)}
+	}
+
+	result, err := d.syncUpstream(repoName, repo, strategy, false)
+	if err != nil {
+		return socket.Response{Success: false, Error: err.Error()}
+	}
+	return socket.Response{Success: true, Data: map[string]interface{}{
+		"upstream_sha": result.UpstreamSHA,
+		"sha":          result.SHA,
+		"up_to_date":   result.UpToDate,
+		"conflicts":    result.Conflicts,
+	}}
+}
+
+// shortSHA abbreviates a commit SHA for messages
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
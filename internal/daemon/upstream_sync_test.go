@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// setupUpstreamSyncRepo sets up the test repo as a fork: bare "origin" and
+// "upstream" remotes that both start at main. It returns a git runner and a
+// function that commits files onto a remote's main without touching the
+// local checkout.
+func setupUpstreamSyncRepo(t *testing.T, d *Daemon, repoDir string) (run func(args ...string) string, commitTo func(remote, message string, files map[string]string)) {
+	t.Helper()
+	run = func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	remotesDir := t.TempDir()
+	for _, remote := range []string{"origin", "upstream"} {
+		bare := filepath.Join(remotesDir, remote+".git")
+		run("init", "-q", "--bare", bare)
+		run("remote", "add", remote, bare)
+		run("push", "-q", remote, "main")
+	}
+	run("fetch", "-q", "--all")
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{
+		GithubURL:     "https://github.com/me/repo",
+		TmuxSession:   "mc-test-repo",
+		DefaultBranch: "main",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	commitTo = func(remote, message string, files map[string]string) {
+		t.Helper()
+		clone := filepath.Join(t.TempDir(), "clone")
+		run("clone", "-q", "--branch", "main", filepath.Join(remotesDir, remote+".git"), clone)
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(clone, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		for _, args := range [][]string{
+			{"add", "-A"},
+			{"-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-q", "-m", message},
+			{"push", "-q", "origin", "main"},
+		} {
+			run(append([]string{"-C", clone}, args...)...)
+		}
+	}
+	return run, commitTo
+}
+
+func TestSyncUpstream(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	useFakeGhAPI(t, d)
+	run, commitTo := setupUpstreamSyncRepo(t, d, repoDir)
+
+	// Syncing needs upstream tracking
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "sync_strategy": "merge"}})
+	if resp.Success {
+		t.Error("update_repo_config sync_strategy should fail without upstream tracking")
+	}
+	if err := d.state.SetUpstreamConfig("test-repo", &state.UpstreamConfig{
+		UpstreamURL:    "https://github.com/them/repo",
+		UpstreamRemote: "upstream",
+		ForkRemote:     "origin",
+		SyncEnabled:    true,
+	}); err != nil {
+		t.Fatalf("Failed to set upstream config: %v", err)
+	}
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "sync_strategy": "squash"}})
+	if resp.Success {
+		t.Error("update_repo_config should reject an unknown sync strategy")
+	}
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "sync_strategy": "merge"}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	resp = d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if got := resp.Data.(map[string]interface{})["sync_strategy"]; got != "merge" {
+		t.Errorf("get_repo_config sync_strategy = %v, want merge", got)
+	}
+
+	// The scheduled check merges upstream, pushes the fork and moves main
+	commitTo("upstream", "Upstream change", map[string]string{"upstream.txt": "upstream\n"})
+	commitTo("origin", "Fork change", map[string]string{"fork.txt": "fork\n"})
+	d.checkForkUpstreamStatus()
+
+	run("fetch", "-q", "--all")
+	forkHead := run("rev-parse", "origin/main")
+	if head := run("rev-parse", "HEAD"); head != forkHead {
+		t.Errorf("local main = %s, want the synced fork %s", head, forkHead)
+	}
+	run("merge-base", "--is-ancestor", "upstream/main", "origin/main")
+	for _, file := range []string{"fork.txt", "upstream.txt"} {
+		if _, err := os.Stat(filepath.Join(repoDir, file)); err != nil {
+			t.Errorf("%s missing from the checkout after syncing: %v", file, err)
+		}
+	}
+	status, _ := d.state.GetDualCIStatus("test-repo")
+	if status == nil || status.LastSyncTime.IsZero() || status.LastSyncSHA != run("rev-parse", "upstream/main") || status.DivergenceCount != 0 {
+		t.Errorf("dual CI status = %+v, want a recorded sync and no divergence", status)
+	}
+
+	// A manual rebase replays the fork's commits onto upstream
+	commitTo("upstream", "Another upstream change", map[string]string{"upstream2.txt": "upstream\n"})
+	commitTo("origin", "Another fork change", map[string]string{"fork2.txt": "fork\n"})
+	resp = d.handleRequest(socket.Request{Command: "sync_upstream", Args: map[string]interface{}{"repo": "test-repo", "strategy": "rebase"}})
+	if !resp.Success {
+		t.Fatalf("sync_upstream failed: %s", resp.Error)
+	}
+	data := resp.Data.(map[string]interface{})
+	run("fetch", "-q", "--all")
+	if data["sha"] != run("rev-parse", "origin/main") || data["upstream_sha"] != run("rev-parse", "upstream/main") {
+		t.Errorf("sync_upstream = %v, want the pushed fork and upstream commits", data)
+	}
+	run("merge-base", "--is-ancestor", "upstream/main", "origin/main")
+	if merges := run("rev-list", "--merges", "upstream/main..origin/main"); merges != "" {
+		t.Errorf("rebased fork has merge commits on top of upstream: %s", merges)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "sync_upstream", Args: map[string]interface{}{"repo": "test-repo"}})
+	if !resp.Success || resp.Data.(map[string]interface{})["up_to_date"] != true {
+		t.Errorf("sync_upstream after syncing = %+v, want up to date", resp)
+	}
+}
+
+func TestSyncUpstreamConflicts(t *testing.T) {
+	d, repoDir, cleanup := setupTestDaemonWithGitRepo(t)
+	defer cleanup()
+	useFakeGhAPI(t, d)
+	run, commitTo := setupUpstreamSyncRepo(t, d, repoDir)
+	if err := d.state.SetUpstreamConfig("test-repo", &state.UpstreamConfig{
+		UpstreamURL:    "https://github.com/them/repo",
+		UpstreamRemote: "upstream",
+		ForkRemote:     "origin",
+		SyncEnabled:    true,
+		SyncStrategy:   state.SyncStrategyRebase,
+	}); err != nil {
+		t.Fatalf("Failed to set upstream config: %v", err)
+	}
+
+	commitTo("upstream", "Rewrite README upstream", map[string]string{"README.md": "# Upstream\n"})
+	commitTo("origin", "Rewrite README in the fork", map[string]string{"README.md": "# Fork\n"})
+	run("fetch", "-q", "--all")
+	forkHead := run("rev-parse", "origin/main")
+
+	// Nothing is pushed or left half-rebased, and the supervisor hears once
+	d.checkForkUpstreamStatus()
+	d.checkForkUpstreamStatus()
+	run("fetch", "-q", "--all")
+	if head := run("rev-parse", "origin/main"); head != forkHead {
+		t.Errorf("origin/main = %s after a conflicting sync, want it unchanged", head)
+	}
+	if out := run("status", "--porcelain"); out != "" {
+		t.Errorf("repo has changes after a conflicting sync: %s", out)
+	}
+	status, _ := d.state.GetDualCIStatus("test-repo")
+	if status == nil || !reflect.DeepEqual(status.SyncConflicts, []string{"README.md"}) || status.SyncConflictSHA != run("rev-parse", "upstream/main") {
+		t.Errorf("dual CI status = %+v, want the README.md conflict recorded", status)
+	}
+	msgMgr := d.getMessageManager()
+	msgs, _ := msgMgr.List("test-repo", "supervisor")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "README.md") || !strings.Contains(msgs[0].Body, "Rewrite README upstream") {
+		t.Fatalf("supervisor messages = %+v, want one about the README.md conflict", msgs)
+	}
+	if queue, _ := d.state.GetTaskQueue("test-repo"); len(queue) != 0 {
+		t.Errorf("task queue = %+v, want nothing queued without resolve_conflicts", queue)
+	}
+
+	// A manual sync reports the conflict to the caller only
+	resp := d.handleRequest(socket.Request{Command: "sync_upstream", Args: map[string]interface{}{"repo": "test-repo", "strategy": "merge"}})
+	if !resp.Success {
+		t.Fatalf("sync_upstream failed: %s", resp.Error)
+	}
+	if got := resp.Data.(map[string]interface{})["conflicts"]; !reflect.DeepEqual(got, []string{"README.md"}) {
+		t.Errorf("sync_upstream conflicts = %v, want README.md", got)
+	}
+	if msgs, _ := msgMgr.List("test-repo", "supervisor"); len(msgs) != 1 {
+		t.Errorf("supervisor has %d message(s) after a manual sync, want 1", len(msgs))
+	}
+
+	// With conflict resolution on, a new upstream conflict queues a worker
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "test-repo", "resolve_conflicts": true}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	commitTo("upstream", "Rewrite README upstream again", map[string]string{"README.md": "# Upstream again\n"})
+	d.checkForkUpstreamStatus()
+
+	queue, err := d.state.GetTaskQueue("test-repo")
+	if err != nil || len(queue) != 1 {
+		t.Fatalf("task queue = %+v, %v, want one task resolving the sync", queue, err)
+	}
+	if task := queue[0]; task.Base != "main" || !strings.Contains(task.Task, "README.md") {
+		t.Errorf("queued task = %+v, want one merging upstream into main", task)
+	}
+	// List doesn't return messages in any particular order
+	msgs, _ = msgMgr.List("test-repo", "supervisor")
+	mentioned := 0
+	for _, msg := range msgs {
+		if strings.Contains(msg.Body, queue[0].ID) {
+			mentioned++
+		}
+	}
+	if len(msgs) != 2 || mentioned != 1 {
+		t.Errorf("supervisor messages = %+v, want a second one about the queued task", msgs)
+	}
+}
@@ -0,0 +1,253 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// wakeCheckInterval is how often wake policies are evaluated. Policies are
+// expressed in whole minutes, so this keeps nudges within a minute of due.
+const wakeCheckInterval = time.Minute
+
+// prActivityTypes are the journal events that count as PR activity for wake policies
+var prActivityTypes = []events.EventType{
+	events.EventPRCreated,
+	events.EventPRMerged,
+	events.EventPRClosed,
+	events.EventCIFailed,
+	events.EventCIPassed,
+}
+
+// shouldWake decides whether an agent is due a nudge under its wake policy.
+// It returns the condition that triggered the wake ("interval" when the
+// policy has no conditions) and whether to wake at all. prs answers the
+// pr_activity condition for every agent checked in the same pass.
+func (d *Daemon) shouldWake(repoName, agentName string, agent state.Agent, repo *state.Repository, policy state.WakePolicy, now time.Time, prs *prActivityReader) (string, bool) {
+	// Workspace agent only receives direct user input
+	if agent.Type == state.AgentTypeWorkspace || policy.IntervalMinutes <= 0 {
+		return "", false
+	}
+
+	if !agent.LastNudge.IsZero() && now.Sub(agent.LastNudge) < time.Duration(policy.IntervalMinutes)*time.Minute {
+		return "", false
+	}
+
+	if policy.InQuietHours(now) {
+		return "", false
+	}
+
+	if len(policy.OnlyWhen) == 0 {
+		return "interval", true
+	}
+
+	for _, cond := range policy.OnlyWhen {
+		switch cond {
+		case state.WakeWhenIdle:
+			if snap, ok := d.getAgentActivity(repoName, agentName, agent.Type, repo.ActivityConfig); ok && snap.Status != activityActive {
+				return cond, true
+			}
+		case state.WakeWhenUnreadMessages:
+			if unread, err := d.getMessageManager().ListUnread(repoName, agentName); err == nil && len(unread) > 0 {
+				return cond, true
+			}
+		case state.WakeWhenPRActivity:
+			if prs.hasActivity(repoName, agentName, agent, repo) {
+				return cond, true
+			}
+		}
+	}
+	return "", false
+}
+
+// prActivityReader answers whether agents have PR activity from one read of
+// the journal per repo, rather than one per agent, for a single wake check
+type prActivityReader struct {
+	d      *Daemon
+	latest map[string]time.Time // repo/agent, or repo/ for repo-wide events -> newest event
+	since  map[string]time.Time // repo -> start of the window already read
+}
+
+func (d *Daemon) newPRActivityReader() *prActivityReader {
+	return &prActivityReader{d: d, latest: make(map[string]time.Time), since: make(map[string]time.Time)}
+}
+
+// hasActivity returns true if the journal has PR or CI events relevant to
+// the agent since it was last nudged. Repo-wide events (no agent) count for
+// every agent; agent-specific events only count for that agent.
+func (r *prActivityReader) hasActivity(repoName, agentName string, agent state.Agent, repo *state.Repository) bool {
+	since := prActivitySince(agent)
+	if read, ok := r.since[repoName]; !ok || since.Before(read) {
+		r.read(repoName, repo, since)
+	}
+
+	for _, key := range []string{agentKey(repoName, ""), agentKey(repoName, agentName)} {
+		if latest, ok := r.latest[key]; ok && !latest.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// read loads a repo's PR activity since the earliest point any of its agents
+// needs, so the other agents are answered without reading again
+func (r *prActivityReader) read(repoName string, repo *state.Repository, since time.Time) {
+	for _, agent := range repo.Agents {
+		if s := prActivitySince(agent); s.Before(since) {
+			since = s
+		}
+	}
+	r.since[repoName] = since
+
+	evts, err := r.d.journal.Query(events.Filter{
+		RepoName: repoName,
+		Types:    prActivityTypes,
+		Since:    since,
+	})
+	if err != nil {
+		r.d.logger.Warn("Failed to query event journal for PR activity: %v", err)
+		return
+	}
+	for _, e := range evts {
+		key := agentKey(repoName, e.AgentName)
+		if e.Timestamp.After(r.latest[key]) {
+			r.latest[key] = e.Timestamp
+		}
+	}
+}
+
+// prActivitySince returns when PR activity starts counting for an agent
+func prActivitySince(agent state.Agent) time.Time {
+	if agent.LastNudge.IsZero() {
+		return agent.CreatedAt
+	}
+	return agent.LastNudge
+}
+
+// wakePolicyAgentTypes are the agent types whose wake policies can be configured
+var wakePolicyAgentTypes = []state.AgentType{
+	state.AgentTypeSupervisor,
+	state.AgentTypeWorker,
+	state.AgentTypeMergeQueue,
+	state.AgentTypeReview,
+	state.AgentTypeGenericPersistent,
+}
+
+// wakePoliciesData returns a repo's effective wake policies keyed by agent type
+func wakePoliciesData(repo *state.Repository) map[string]interface{} {
+	data := make(map[string]interface{}, len(wakePolicyAgentTypes))
+	for _, agentType := range wakePolicyAgentTypes {
+		policy := repo.WakePolicyFor(agentType)
+		onlyWhen := policy.OnlyWhen
+		if onlyWhen == nil {
+			onlyWhen = []string{}
+		}
+		data[string(agentType)] = map[string]interface{}{
+			"interval_minutes": policy.IntervalMinutes,
+			"quiet_hours":      policy.QuietHours,
+			"only_when":        onlyWhen,
+			"message":          policy.Message,
+		}
+	}
+	return data
+}
+
+// applyWakePolicyArgs merges the fields present in a socket request's policy
+// arguments into policy. only_when may be a list or a comma-separated string.
+func applyWakePolicyArgs(policy state.WakePolicy, args map[string]interface{}) (state.WakePolicy, error) {
+	if interval, ok := args["interval_minutes"].(float64); ok {
+		if interval < 0 {
+			return policy, fmt.Errorf("interval_minutes must not be negative")
+		}
+		policy.IntervalMinutes = int(interval)
+	}
+	if quiet, ok := args["quiet_hours"].(string); ok {
+		policy.QuietHours = quiet
+	}
+	switch onlyWhen := args["only_when"].(type) {
+	case []interface{}:
+		policy.OnlyWhen = nil
+		for _, v := range onlyWhen {
+			cond, ok := v.(string)
+			if !ok {
+				return policy, fmt.Errorf("only_when must be a list of strings")
+			}
+			policy.OnlyWhen = append(policy.OnlyWhen, cond)
+		}
+	case string:
+		policy.OnlyWhen = nil
+		for _, cond := range strings.Split(onlyWhen, ",") {
+			if cond = strings.TrimSpace(cond); cond != "" {
+				policy.OnlyWhen = append(policy.OnlyWhen, cond)
+			}
+		}
+	}
+	if message, ok := args["message"].(string); ok {
+		if strings.TrimSpace(message) == "" {
+			return policy, fmt.Errorf("message must not be empty")
+		}
+		policy.Message = message
+	}
+	return policy, policy.Validate()
+}
+
+// updateWakePolicies applies the wake_policies argument of update_repo_config.
+// Every entry is validated before any is saved, so an invalid entry leaves
+// all the repo's policies unchanged.
+func (d *Daemon) updateWakePolicies(repoName string, arg interface{}) error {
+	policies, ok := arg.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("wake_policies must be an object keyed by agent type")
+	}
+
+	typeNames := make([]string, 0, len(policies))
+	for typeName := range policies {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	updated := make(map[state.AgentType]state.WakePolicy, len(typeNames))
+	for _, typeName := range typeNames {
+		agentType := state.AgentType(typeName)
+		if !isWakePolicyAgentType(agentType) {
+			return fmt.Errorf("invalid agent type for wake policy: %q", typeName)
+		}
+		args, ok := policies[typeName].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("wake policy for %s must be an object", typeName)
+		}
+
+		current, err := d.state.GetWakePolicy(repoName, agentType)
+		if err != nil {
+			return err
+		}
+		policy, err := applyWakePolicyArgs(current, args)
+		if err != nil {
+			return fmt.Errorf("wake policy for %s: %w", typeName, err)
+		}
+		updated[agentType] = policy
+	}
+
+	if err := d.state.UpdateWakePolicies(repoName, updated); err != nil {
+		return err
+	}
+	for _, typeName := range typeNames {
+		policy := updated[state.AgentType(typeName)]
+		d.logger.Info("Updated wake policy for %s in repo %s: interval=%dm, quiet=%q, only_when=%v", typeName, repoName, policy.IntervalMinutes, policy.QuietHours, policy.OnlyWhen)
+	}
+	return nil
+}
+
+// isWakePolicyAgentType returns true if agentType has a configurable wake policy
+func isWakePolicyAgentType(agentType state.AgentType) bool {
+	for _, t := range wakePolicyAgentTypes {
+		if t == agentType {
+			return true
+		}
+	}
+	return false
+}
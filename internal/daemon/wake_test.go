@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/events"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// quietHoursAround returns quiet hours covering the hour before and after t
+func quietHoursAround(t time.Time) string {
+	return fmt.Sprintf("%s-%s", t.Add(-time.Hour).Format("15:04"), t.Add(time.Hour).Format("15:04"))
+}
+
+func TestShouldWakeSchedule(t *testing.T) {
+	now := time.Now()
+	worker := state.Agent{Type: state.AgentTypeWorker, CreatedAt: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name      string
+		agent     state.Agent
+		policy    state.WakePolicy
+		wantWake  bool
+		wantCause string
+	}{
+		{"never nudged", worker, state.WakePolicy{IntervalMinutes: 2}, true, "interval"},
+		{"interval elapsed", state.Agent{Type: state.AgentTypeWorker, LastNudge: now.Add(-3 * time.Minute)}, state.WakePolicy{IntervalMinutes: 2}, true, "interval"},
+		{"interval not elapsed", state.Agent{Type: state.AgentTypeWorker, LastNudge: now.Add(-3 * time.Minute)}, state.WakePolicy{IntervalMinutes: 5}, false, ""},
+		{"interval zero disables", worker, state.WakePolicy{IntervalMinutes: 0}, false, ""},
+		{"workspace never woken", state.Agent{Type: state.AgentTypeWorkspace}, state.WakePolicy{IntervalMinutes: 2}, false, ""},
+		{"quiet hours", worker, state.WakePolicy{IntervalMinutes: 2, QuietHours: quietHoursAround(now)}, false, ""},
+		{"outside quiet hours", worker, state.WakePolicy{IntervalMinutes: 2, QuietHours: quietHoursAround(now.Add(12 * time.Hour))}, true, "interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := setupTestDaemon(t)
+			defer cleanup()
+			addEventTestRepo(t, d)
+			repo, _ := d.state.GetRepo("test-repo")
+
+			cause, wake := d.shouldWake("test-repo", "agent", tt.agent, repo, tt.policy, now, d.newPRActivityReader())
+			if wake != tt.wantWake || cause != tt.wantCause {
+				t.Errorf("shouldWake() = (%q, %v), want (%q, %v)", cause, wake, tt.wantCause, tt.wantWake)
+			}
+		})
+	}
+}
+
+func TestShouldWakeOnlyWhenIdle(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	repo, _ := d.state.GetRepo("test-repo")
+
+	now := time.Now()
+	agent := state.Agent{Type: state.AgentTypeWorker}
+	policy := state.WakePolicy{IntervalMinutes: 2, OnlyWhen: []string{state.WakeWhenIdle}}
+
+	// Unsampled agents are not assumed idle
+	if _, wake := d.shouldWake("test-repo", "busy", agent, repo, policy, now, d.newPRActivityReader()); wake {
+		t.Error("agent without activity data should not be woken")
+	}
+
+	writeAgentLog(t, d, "busy", state.AgentTypeWorker, "output", now.Add(-time.Minute))
+	d.updateAgentActivity("test-repo", "busy", agent, state.DefaultActivityConfig(), now)
+	if _, wake := d.shouldWake("test-repo", "busy", agent, repo, policy, now, d.newPRActivityReader()); wake {
+		t.Error("active agent should not be woken")
+	}
+
+	writeAgentLog(t, d, "quiet", state.AgentTypeWorker, "output", now.Add(-15*time.Minute))
+	d.updateAgentActivity("test-repo", "quiet", agent, state.DefaultActivityConfig(), now)
+	if cause, wake := d.shouldWake("test-repo", "quiet", agent, repo, policy, now, d.newPRActivityReader()); !wake || cause != state.WakeWhenIdle {
+		t.Errorf("shouldWake() = (%q, %v), want idle agent woken", cause, wake)
+	}
+}
+
+func TestShouldWakeOnlyWhenUnreadMessages(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	repo, _ := d.state.GetRepo("test-repo")
+
+	agent := state.Agent{Type: state.AgentTypeSupervisor}
+	policy := state.WakePolicy{IntervalMinutes: 2, OnlyWhen: []string{state.WakeWhenUnreadMessages}}
+
+	if _, wake := d.shouldWake("test-repo", "supervisor", agent, repo, policy, time.Now(), d.newPRActivityReader()); wake {
+		t.Error("agent without unread messages should not be woken")
+	}
+
+	if _, err := d.getMessageManager().Send("test-repo", "worker-1", "supervisor", "Done with my task"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if cause, wake := d.shouldWake("test-repo", "supervisor", agent, repo, policy, time.Now(), d.newPRActivityReader()); !wake || cause != state.WakeWhenUnreadMessages {
+		t.Errorf("shouldWake() = (%q, %v), want agent with unread messages woken", cause, wake)
+	}
+}
+
+func TestShouldWakeOnlyWhenPRActivity(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+	repo, _ := d.state.GetRepo("test-repo")
+
+	agent := state.Agent{Type: state.AgentTypeWorker, LastNudge: time.Now().Add(-10 * time.Minute)}
+	policy := state.WakePolicy{IntervalMinutes: 2, OnlyWhen: []string{state.WakeWhenPRActivity}}
+
+	// Events from before the last nudge are not new activity
+	old := events.NewCIFailedEvent("test-repo", 1, "lint")
+	old.Timestamp = agent.LastNudge.Add(-time.Minute)
+	d.eventBus.Emit(old)
+	if _, wake := d.shouldWake("test-repo", "worker-a", agent, repo, policy, time.Now(), d.newPRActivityReader()); wake {
+		t.Error("PR activity before the last nudge should not wake the agent")
+	}
+
+	// Another agent's PR doesn't count, but it does for that agent, answered
+	// from the same read of the journal
+	d.eventBus.Emit(events.NewPRCreatedEvent("test-repo", "worker-b", 2, "Other work", "https://github.com/test/repo/pull/2"))
+	prs := d.newPRActivityReader()
+	if _, wake := d.shouldWake("test-repo", "worker-a", agent, repo, policy, time.Now(), prs); wake {
+		t.Error("another agent's PR should not wake the agent")
+	}
+	if _, wake := d.shouldWake("test-repo", "worker-b", agent, repo, policy, time.Now(), prs); !wake {
+		t.Error("an agent's own PR should wake it")
+	}
+
+	// Repo-wide CI results count for everyone
+	d.eventBus.Emit(events.NewCIPassedEvent("test-repo", 3, "tests"))
+	if cause, wake := d.shouldWake("test-repo", "worker-a", agent, repo, policy, time.Now(), d.newPRActivityReader()); !wake || cause != state.WakeWhenPRActivity {
+		t.Errorf("shouldWake() = (%q, %v), want agent woken by CI activity", cause, wake)
+	}
+}
+
+func TestRepoConfigWakePolicies(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	addEventTestRepo(t, d)
+
+	resp := d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "test-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	policies, ok := resp.Data.(map[string]interface{})["wake_policies"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("get_repo_config should include wake_policies, got %v", resp.Data)
+	}
+	supervisor := policies["supervisor"].(map[string]interface{})
+	if supervisor["interval_minutes"] != 2 || supervisor["message"] != state.DefaultWakePolicy(state.AgentTypeSupervisor).Message {
+		t.Errorf("default supervisor policy = %v", supervisor)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo",
+		"wake_policies": map[string]interface{}{
+			"worker": map[string]interface{}{
+				"interval_minutes": float64(10),
+				"quiet_hours":      "22:00-07:00",
+				"only_when":        []interface{}{"idle", "unread_messages"},
+			},
+		},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	policy, _ := d.state.GetWakePolicy("test-repo", state.AgentTypeWorker)
+	if policy.IntervalMinutes != 10 || policy.QuietHours != "22:00-07:00" || len(policy.OnlyWhen) != 2 {
+		t.Errorf("worker policy = %+v", policy)
+	}
+	// Fields that weren't provided keep their previous values
+	if policy.Message != state.DefaultWakePolicy(state.AgentTypeWorker).Message {
+		t.Errorf("worker message = %q, want default", policy.Message)
+	}
+
+	invalid := []map[string]interface{}{
+		{"workspace": map[string]interface{}{"interval_minutes": float64(5)}},
+		{"worker": map[string]interface{}{"interval_minutes": float64(-1)}},
+		{"worker": map[string]interface{}{"quiet_hours": "late"}},
+		{"worker": map[string]interface{}{"only_when": "sometimes"}},
+		{"worker": map[string]interface{}{"message": " "}},
+	}
+	for _, policies := range invalid {
+		resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+			"name":          "test-repo",
+			"wake_policies": policies,
+		}})
+		if resp.Success {
+			t.Errorf("update_repo_config(%v) should fail", policies)
+		}
+	}
+
+	// An invalid entry rejects the whole update, including valid entries
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "test-repo",
+		"wake_policies": map[string]interface{}{
+			"merge-queue": map[string]interface{}{"interval_minutes": float64(20)},
+			"supervisor":  map[string]interface{}{"interval_minutes": float64(20)},
+			"worker":      map[string]interface{}{"quiet_hours": "late"},
+		},
+	}})
+	if resp.Success {
+		t.Error("update_repo_config with an invalid worker policy should fail")
+	}
+	for _, agentType := range []state.AgentType{state.AgentTypeMergeQueue, state.AgentTypeSupervisor} {
+		if policy, _ := d.state.GetWakePolicy("test-repo", agentType); policy.IntervalMinutes == 20 {
+			t.Errorf("%s policy = %+v, want it unchanged after a rejected update", agentType, policy)
+		}
+	}
+	if policy, _ := d.state.GetWakePolicy("test-repo", state.AgentTypeWorker); policy.IntervalMinutes != 10 {
+		t.Errorf("worker policy = %+v, want it unchanged after a rejected update", policy)
+	}
+}
@@ -19,7 +19,7 @@ type StateReader struct {
 	paths    []string
 	watcher  *fsnotify.Watcher
 	mu       sync.RWMutex
-	states   map[string]*state.State
+	states   map[string]*state.View
 	onChange func()
 }
 
@@ -33,7 +33,7 @@ func NewStateReader(paths []string) (*StateReader, error) {
 	reader := &StateReader{
 		paths:   paths,
 		watcher: watcher,
-		states:  make(map[string]*state.State),
+		states:  make(map[string]*state.View),
 	}
 
 	// Initial read of all state files
@@ -63,7 +63,7 @@ func (r *StateReader) readState(path string) error {
 		path = filepath.Join(home, path[1:])
 	}
 
-	s, err := state.Load(path)
+	s, err := state.LoadView(path)
 	if err != nil {
 		return err
 	}
@@ -128,8 +128,8 @@ func (r *StateReader) GetAggregatedState() *AggregatedState {
 
 		machineState := &MachineState{
 			Path:        path,
-			Repos:       s.Repos,
-			CurrentRepo: s.CurrentRepo,
+			Repos:       s.GetAllRepos(),
+			CurrentRepo: s.GetCurrentRepo(),
 		}
 
 		agg.Machines[machineName] = machineState
@@ -139,7 +139,7 @@ func (r *StateReader) GetAggregatedState() *AggregatedState {
 }
 
 // GetState returns the state for a specific path
-func (r *StateReader) GetState(path string) (*state.State, bool) {
+func (r *StateReader) GetState(path string) (*state.View, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	s, ok := r.states[path]
@@ -43,11 +43,11 @@ func TestNewStateReader(t *testing.T) {
 		t.Fatalf("state not found for path: %s", statePath)
 	}
 
-	if len(loadedState.Repos) != 1 {
-		t.Errorf("expected 1 repo, got %d", len(loadedState.Repos))
+	if repos := loadedState.ListRepos(); len(repos) != 1 {
+		t.Errorf("expected 1 repo, got %d", len(repos))
 	}
 
-	if _, ok := loadedState.Repos["test-repo"]; !ok {
+	if _, ok := loadedState.GetRepo("test-repo"); !ok {
 		t.Errorf("expected repo 'test-repo' not found")
 	}
 }
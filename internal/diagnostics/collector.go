@@ -304,7 +304,7 @@ func (c *Collector) collectDaemon() DaemonInfo {
 
 // collectStatistics gathers agent and repository statistics
 func (c *Collector) collectStatistics() StatisticsInfo {
-	st, err := state.Load(c.paths.StateFile)
+	st, err := state.LoadView(c.paths.StateFile)
 	if err != nil {
 		return StatisticsInfo{}
 	}
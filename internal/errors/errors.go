@@ -137,6 +137,17 @@ func DaemonCommunicationFailed(operation string, cause error) *CLIError {
 	}
 }
 
+// DaemonVersionMismatch creates an error for a daemon whose socket protocol
+// version is incompatible with the CLI's
+func DaemonVersionMismatch(cause error) *CLIError {
+	return &CLIError{
+		Category:   CategoryConnection,
+		Message:    "daemon protocol version is incompatible with this CLI",
+		Cause:      cause,
+		Suggestion: "multiclaude daemon stop && multiclaude daemon start",
+	}
+}
+
 // InvalidUsage creates an error for invalid command usage
 func InvalidUsage(usage string) *CLIError {
 	return &CLIError{
@@ -177,7 +188,7 @@ func InvalidPRURL() *CLIError {
 	return &CLIError{
 		Category:   CategoryUsage,
 		Message:    "invalid PR URL format",
-		Suggestion: "use format: https://github.com/owner/repo/pull/123",
+		Suggestion: "use a PR number or format: https://github.com/owner/repo/pull/123",
 	}
 }
 
@@ -134,6 +134,25 @@ func TestDaemonCommunicationFailed(t *testing.T) {
 	}
 }
 
+func TestDaemonVersionMismatch(t *testing.T) {
+	cause := errors.New("daemon speaks protocol version 0")
+	err := DaemonVersionMismatch(cause)
+
+	if err.Category != CategoryConnection {
+		t.Error("should have CategoryConnection")
+	}
+	if err.Cause != cause {
+		t.Error("should wrap cause")
+	}
+
+	formatted := Format(err)
+	if !strings.Contains(formatted, "protocol version 0") {
+		t.Errorf("expected cause in message, got: %s", formatted)
+	}
+	if !strings.Contains(formatted, "daemon stop") {
+		t.Errorf("expected restart suggestion, got: %s", formatted)
+	}
+}
 func TestNotInRepo(t *testing.T) {
 	err := NotInRepo()
 	formatted := Format(err)
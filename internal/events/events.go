@@ -1,6 +1,7 @@
 package events
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os/exec"
@@ -36,6 +37,9 @@ const (
 
 	// Worker events
 	EventWorkerStuck EventType = "worker_stuck"
+
+	// State events are only broadcast to subscribers, never journaled
+	EventStateChanged EventType = "state_changed"
 )
 
 // Event represents a lifecycle event in multiclaude
@@ -64,10 +68,13 @@ type HookConfig struct {
 	OnMessageSent   string `json:"on_message_sent,omitempty"`
 }
 
-// Bus is the event bus that emits events to configured hooks
+// Bus is the event bus that emits events to configured hooks and
+// subscribers, and records them in the event journal, if one is set
 type Bus struct {
-	config HookConfig
-	mu     sync.RWMutex
+	config      HookConfig
+	journal     *Journal
+	mu          sync.RWMutex
+	subscribers subscribers
 }
 
 // NewBus creates a new event bus with the given configuration
@@ -84,17 +91,30 @@ func (b *Bus) UpdateConfig(config HookConfig) {
 	b.config = config
 }
 
-// Emit emits an event to configured hooks
-// This is fire-and-forget - no retries, no delivery guarantees
+// SetJournal sets the journal that every emitted event is appended to
+func (b *Bus) SetJournal(journal *Journal) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.journal = journal
+}
+
+// withTimestamp sets an event's timestamp if not already set
+func withTimestamp(event Event) Event {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	return event
+}
+
+// Emit records an event in the journal and emits it to subscribers and
+// configured hooks. Hooks are fire-and-forget - no retries, no delivery guarantees
 func (b *Bus) Emit(event Event) {
 	b.mu.RLock()
 	config := b.config
+	journal := b.journal
 	b.mu.RUnlock()
 
-	// Set timestamp if not already set
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
-	}
+	event = withTimestamp(event)
 
 	// Marshal event to JSON
 	eventJSON, err := json.Marshal(event)
@@ -103,6 +123,14 @@ func (b *Bus) Emit(event Event) {
 		return
 	}
 
+	// Journal writes are synchronous so queries see events in emit order.
+	// A failed write must not block hooks, so the error is dropped.
+	if journal != nil {
+		_ = journal.Append(event)
+	}
+
+	b.publish(event)
+
 	// Call generic hook if configured
 	if config.OnEvent != "" {
 		go b.callHook(config.OnEvent, event.Type, eventJSON)
@@ -122,7 +150,9 @@ func (b *Bus) callHook(hookPath string, eventType EventType, eventJSON []byte) {
 	defer cancel()
 
 	// Execute hook: hook_script event_type event_json
+	// The event JSON is also written to stdin for hooks that prefer to read it there
 	cmd := exec.CommandContext(ctx, hookPath, string(eventType), string(eventJSON))
+	cmd.Stdin = bytes.NewReader(eventJSON)
 
 	// Fire and forget - we don't wait for output or check errors
 	// Users are responsible for their own error handling in hooks
@@ -194,6 +224,19 @@ func NewAgentIdleEvent(repoName, agentName string, durationSeconds int) Event {
 	}
 }
 
+// NewAgentFailedEvent creates an agent_failed event
+func NewAgentFailedEvent(repoName, agentName, agentType, reason string) Event {
+	return Event{
+		Type:      EventAgentFailed,
+		RepoName:  repoName,
+		AgentName: agentName,
+		Data: map[string]interface{}{
+			"agent_type": agentType,
+			"reason":     reason,
+		},
+	}
+}
+
 // NewPRCreatedEvent creates a pr_created event
 func NewPRCreatedEvent(repoName, agentName string, prNumber int, title, url string) Event {
 	return Event{
@@ -220,6 +263,18 @@ func NewPRMergedEvent(repoName string, prNumber int, title string) Event {
 	}
 }
 
+// NewPRClosedEvent creates a pr_closed event
+func NewPRClosedEvent(repoName string, prNumber int, title string) Event {
+	return Event{
+		Type:     EventPRClosed,
+		RepoName: repoName,
+		Data: map[string]interface{}{
+			"pr_number": prNumber,
+			"title":     title,
+		},
+	}
+}
+
 // NewTaskAssignedEvent creates a task_assigned event
 func NewTaskAssignedEvent(repoName, agentName, task string) Event {
 	return Event{
@@ -232,6 +287,21 @@ func NewTaskAssignedEvent(repoName, agentName, task string) Event {
 	}
 }
 
+// NewTaskCompleteEvent creates a task_complete event
+func NewTaskCompleteEvent(repoName, agentName, task, summary, failureReason string) Event {
+	return Event{
+		Type:      EventTaskComplete,
+		RepoName:  repoName,
+		AgentName: agentName,
+		Data: map[string]interface{}{
+			"task":           task,
+			"summary":        summary,
+			"failure_reason": failureReason,
+			"success":        failureReason == "",
+		},
+	}
+}
+
 // NewMessageSentEvent creates a message_sent event
 func NewMessageSentEvent(repoName, from, to, messageType, body string) Event {
 	return Event{
@@ -258,6 +328,37 @@ func NewCIFailedEvent(repoName string, prNumber int, jobName string) Event {
 	}
 }
 
+// NewCIPassedEvent creates a ci_passed event
+func NewCIPassedEvent(repoName string, prNumber int, jobName string) Event {
+	return Event{
+		Type:     EventCIPassed,
+		RepoName: repoName,
+		Data: map[string]interface{}{
+			"pr_number": prNumber,
+			"job_name":  jobName,
+		},
+	}
+}
+
+// NewStateChangedEvent creates a state_changed event. For agent changes,
+// change is "agent_added", "agent_removed", or "agent_updated" and fields
+// lists the agent's changed state fields; for the repository itself it is
+// "repo_added", "repo_removed", or "repo_updated".
+func NewStateChangedEvent(repoName, agentName, change string, fields []string) Event {
+	data := map[string]interface{}{
+		"change": change,
+	}
+	if len(fields) > 0 {
+		data["fields"] = fields
+	}
+	return Event{
+		Type:      EventStateChanged,
+		RepoName:  repoName,
+		AgentName: agentName,
+		Data:      data,
+	}
+}
+
 // NewWorkerStuckEvent creates a worker_stuck event
 func NewWorkerStuckEvent(repoName, agentName string, durationMinutes int) Event {
 	return Event{
@@ -187,3 +187,110 @@ echo "$2" > ` + prOutputFile + `
 		t.Error("PR hook did not create output file")
 	}
 }
+
+func TestNewAgentFailedEvent(t *testing.T) {
+	event := NewAgentFailedEvent("test-repo", "supervisor", "supervisor", "process exited")
+
+	if event.Type != EventAgentFailed {
+		t.Errorf("Type = %s, want %s", event.Type, EventAgentFailed)
+	}
+	if event.AgentName != "supervisor" {
+		t.Errorf("AgentName = %s, want supervisor", event.AgentName)
+	}
+	if event.Data["agent_type"] != "supervisor" {
+		t.Errorf("Data[agent_type] = %v, want supervisor", event.Data["agent_type"])
+	}
+	if event.Data["reason"] != "process exited" {
+		t.Errorf("Data[reason] = %v, want process exited", event.Data["reason"])
+	}
+}
+
+func TestNewPRClosedEvent(t *testing.T) {
+	event := NewPRClosedEvent("test-repo", 7, "Abandoned change")
+
+	if event.Type != EventPRClosed {
+		t.Errorf("Type = %s, want %s", event.Type, EventPRClosed)
+	}
+	if event.Data["pr_number"] != 7 {
+		t.Errorf("Data[pr_number] = %v, want 7", event.Data["pr_number"])
+	}
+	if event.Data["title"] != "Abandoned change" {
+		t.Errorf("Data[title] = %v, want Abandoned change", event.Data["title"])
+	}
+}
+
+func TestNewTaskCompleteEvent(t *testing.T) {
+	event := NewTaskCompleteEvent("test-repo", "test-agent", "Fix bug", "Fixed it", "")
+
+	if event.Type != EventTaskComplete {
+		t.Errorf("Type = %s, want %s", event.Type, EventTaskComplete)
+	}
+	if event.Data["summary"] != "Fixed it" {
+		t.Errorf("Data[summary] = %v, want Fixed it", event.Data["summary"])
+	}
+	if event.Data["success"] != true {
+		t.Errorf("Data[success] = %v, want true", event.Data["success"])
+	}
+
+	failed := NewTaskCompleteEvent("test-repo", "test-agent", "Fix bug", "", "tests failing")
+	if failed.Data["success"] != false {
+		t.Errorf("Data[success] = %v, want false for failed task", failed.Data["success"])
+	}
+	if failed.Data["failure_reason"] != "tests failing" {
+		t.Errorf("Data[failure_reason] = %v, want tests failing", failed.Data["failure_reason"])
+	}
+}
+
+func TestNewCIPassedEvent(t *testing.T) {
+	event := NewCIPassedEvent("test-repo", 42, "test-suite")
+
+	if event.Type != EventCIPassed {
+		t.Errorf("Type = %s, want %s", event.Type, EventCIPassed)
+	}
+	if event.Data["pr_number"] != 42 {
+		t.Errorf("Data[pr_number] = %v, want 42", event.Data["pr_number"])
+	}
+	if event.Data["job_name"] != "test-suite" {
+		t.Errorf("Data[job_name] = %v, want test-suite", event.Data["job_name"])
+	}
+}
+
+func TestBusEmitWritesEventToStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	hookScript := filepath.Join(tmpDir, "stdin-hook.sh")
+	outputFile := filepath.Join(tmpDir, "stdin.json")
+
+	hookContent := `#!/bin/bash
+cat > ` + outputFile + `.tmp && mv ` + outputFile + `.tmp ` + outputFile + `
+`
+	if err := os.WriteFile(hookScript, []byte(hookContent), 0755); err != nil {
+		t.Fatalf("Failed to write hook script: %v", err)
+	}
+
+	bus := NewBus(HookConfig{OnEvent: hookScript})
+	bus.Emit(NewTaskAssignedEvent("test-repo", "test-agent", "Test task"))
+
+	var data []byte
+	var err error
+	for i := 0; i < 20; i++ {
+		time.Sleep(50 * time.Millisecond)
+		data, err = os.ReadFile(outputFile)
+		if err == nil && len(data) > 0 {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("Hook did not receive event on stdin: %v", err)
+	}
+
+	var savedEvent Event
+	if err := json.Unmarshal(data, &savedEvent); err != nil {
+		t.Fatalf("Failed to unmarshal stdin event: %v (data: %s)", err, string(data))
+	}
+	if savedEvent.Type != EventTaskAssigned {
+		t.Errorf("Stdin event Type = %s, want %s", savedEvent.Type, EventTaskAssigned)
+	}
+	if savedEvent.Data["task"] != "Test task" {
+		t.Errorf("Stdin event Data[task] = %v, want Test task", savedEvent.Data["task"])
+	}
+}
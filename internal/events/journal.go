@@ -0,0 +1,207 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJournalMaxSize is the size at which the journal is rotated (10MB)
+	DefaultJournalMaxSize = 10 * 1024 * 1024
+	// DefaultJournalKeep is how many rotated journal files are kept
+	DefaultJournalKeep = 5
+)
+
+// Filter selects events from the journal. Zero-valued fields match everything.
+type Filter struct {
+	RepoName  string
+	AgentName string
+	Types     []EventType
+	Since     time.Time
+	Limit     int // Return only the most recent N matching events (0 = all)
+}
+
+// Matches returns true if the event passes the filter
+func (f Filter) Matches(event Event) bool {
+	if f.RepoName != "" && event.RepoName != f.RepoName {
+		return false
+	}
+	if f.AgentName != "" && event.AgentName != f.AgentName {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if len(f.Types) > 0 {
+		for _, t := range f.Types {
+			if event.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Journal is an append-only JSONL log of events.
+// Rotated journals are kept alongside it with a timestamp suffix
+// (events.jsonl.20060102-150405) and are included in queries.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJournal creates a journal that writes to path
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Path returns the path of the active journal file
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Append writes an event to the journal as a single JSON line
+func (j *Journal) Append(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write event journal: %w", err)
+	}
+	return nil
+}
+
+// Files returns the rotated journal files (oldest first) followed by the
+// active journal. Files that don't exist are omitted.
+func (j *Journal) Files() ([]string, error) {
+	rotated, err := filepath.Glob(j.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	// Timestamp suffixes sort chronologically
+	sort.Strings(rotated)
+
+	files := rotated
+	if _, err := os.Stat(j.path); err == nil {
+		files = append(files, j.path)
+	}
+	return files, nil
+}
+
+// Query returns the events matching the filter in chronological order
+func (j *Journal) Query(filter Filter) ([]Event, error) {
+	files, err := j.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Event
+	for _, file := range files {
+		// Skip whole rotated files that end before the window starts
+		if !filter.Since.IsZero() && file != j.path {
+			if info, err := os.Stat(file); err == nil && info.ModTime().Before(filter.Since) {
+				continue
+			}
+		}
+
+		matched, err := readJournalFile(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matched...)
+	}
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result, nil
+}
+
+// Prune removes the oldest rotated journals, keeping at most keep of them
+func (j *Journal) Prune(keep int) error {
+	files, err := j.Files()
+	if err != nil {
+		return err
+	}
+
+	var rotated []string
+	for _, f := range files {
+		if f != j.path {
+			rotated = append(rotated, f)
+		}
+	}
+
+	for len(rotated) > keep {
+		if err := os.Remove(rotated[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// readJournalFile reads the matching events from a single journal file.
+// Malformed lines (e.g. a partial write before a crash) are skipped.
+func readJournalFile(path string, filter Filter) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open event journal: %w", err)
+	}
+	defer f.Close()
+
+	var result []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		if filter.Matches(event) {
+			result = append(result, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event journal: %w", err)
+	}
+	return result, nil
+}
+
+// ParseTypes parses a comma-separated list of event types
+func ParseTypes(s string) []EventType {
+	var types []EventType
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			types = append(types, EventType(part))
+		}
+	}
+	return types
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalAppendAndQuery(t *testing.T) {
+	journal := NewJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+
+	base := time.Date(2024, 1, 15, 2, 0, 0, 0, time.UTC)
+	emitted := []Event{
+		NewAgentStartedEvent("repo-a", "swift-eagle", "worker", "Fix bug"),
+		NewAgentIdleEvent("repo-a", "swift-eagle", 600),
+		NewAgentStartedEvent("repo-b", "calm-fox", "worker", "Add docs"),
+		NewWorkerStuckEvent("repo-a", "swift-eagle", 30),
+	}
+	for i := range emitted {
+		emitted[i].Timestamp = base.Add(time.Duration(i) * time.Hour)
+		if err := journal.Append(emitted[i]); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    Filter
+		wantTypes []EventType
+	}{
+		{"all", Filter{}, []EventType{EventAgentStarted, EventAgentIdle, EventAgentStarted, EventWorkerStuck}},
+		{"by repo", Filter{RepoName: "repo-b"}, []EventType{EventAgentStarted}},
+		{"by agent", Filter{AgentName: "swift-eagle"}, []EventType{EventAgentStarted, EventAgentIdle, EventWorkerStuck}},
+		{"by type", Filter{Types: []EventType{EventAgentIdle, EventWorkerStuck}}, []EventType{EventAgentIdle, EventWorkerStuck}},
+		{"since is inclusive", Filter{Since: base.Add(time.Hour)}, []EventType{EventAgentIdle, EventAgentStarted, EventWorkerStuck}},
+		{"limit keeps most recent", Filter{Limit: 2}, []EventType{EventAgentStarted, EventWorkerStuck}},
+		{"combined", Filter{RepoName: "repo-a", Since: base.Add(90 * time.Minute)}, []EventType{EventWorkerStuck}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := journal.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+			if len(got) != len(tt.wantTypes) {
+				t.Fatalf("Query() returned %d events, want %d: %+v", len(got), len(tt.wantTypes), got)
+			}
+			for i, e := range got {
+				if e.Type != tt.wantTypes[i] {
+					t.Errorf("event[%d].Type = %s, want %s", i, e.Type, tt.wantTypes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJournalQueryMissingFile(t *testing.T) {
+	journal := NewJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+
+	got, err := journal.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Query() returned %d events, want 0", len(got))
+	}
+}
+
+func TestJournalSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	content := `{"type":"agent_started","timestamp":"2024-01-15T02:00:00Z","repo_name":"r"}
+{"type":"agent_st
+{"type":"agent_stopped","timestamp":"2024-01-15T03:00:00Z","repo_name":"r"}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	got, err := NewJournal(path).Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Query() returned %d events, want 2", len(got))
+	}
+}
+
+func TestJournalQueryIncludesRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	journal := NewJournal(path)
+
+	old := NewAgentStartedEvent("repo", "old-worker", "worker", "")
+	old.Timestamp = time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	if err := journal.Append(old); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := os.Rename(path, path+".20240114-000000"); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+
+	current := NewAgentStartedEvent("repo", "new-worker", "worker", "")
+	current.Timestamp = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := journal.Append(current); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+
+	got, err := journal.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Query() returned %d events, want 2", len(got))
+	}
+	if got[0].AgentName != "old-worker" || got[1].AgentName != "new-worker" {
+		t.Errorf("Query() order = [%s, %s], want [old-worker, new-worker]", got[0].AgentName, got[1].AgentName)
+	}
+}
+
+func TestJournalPrune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	journal := NewJournal(path)
+
+	rotated := []string{
+		path + ".20240101-000000",
+		path + ".20240102-000000",
+		path + ".20240103-000000",
+	}
+	for _, f := range append(rotated, path) {
+		if err := os.WriteFile(f, []byte("{}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", f, err)
+		}
+	}
+
+	if err := journal.Prune(2); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+
+	if _, err := os.Stat(rotated[0]); !os.IsNotExist(err) {
+		t.Error("oldest rotated journal should have been removed")
+	}
+	for _, f := range append(rotated[1:], path) {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s should still exist: %v", filepath.Base(f), err)
+		}
+	}
+}
+
+func TestBusEmitAppendsToJournal(t *testing.T) {
+	journal := NewJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+	bus := NewBus(HookConfig{})
+	bus.SetJournal(journal)
+
+	bus.Emit(NewTaskAssignedEvent("test-repo", "test-agent", "Test task"))
+
+	// Journal writes are synchronous, so no waiting is needed
+	got, err := journal.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Query() returned %d events, want 1", len(got))
+	}
+	if got[0].Type != EventTaskAssigned {
+		t.Errorf("Type = %s, want %s", got[0].Type, EventTaskAssigned)
+	}
+	if got[0].Timestamp.IsZero() {
+		t.Error("journaled event should have a timestamp")
+	}
+}
+
+func TestParseTypes(t *testing.T) {
+	got := ParseTypes("agent_idle, worker_stuck,,")
+	if len(got) != 2 || got[0] != EventAgentIdle || got[1] != EventWorkerStuck {
+		t.Errorf("ParseTypes() = %v, want [agent_idle worker_stuck]", got)
+	}
+}
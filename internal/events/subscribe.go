@@ -0,0 +1,108 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSubscriptionBuffer is how many events a subscriber can fall behind
+// before its subscription is closed
+const DefaultSubscriptionBuffer = 256
+
+// Subscription receives the events on a bus that match its filter.
+// The filter's Since and Limit fields are ignored.
+type Subscription struct {
+	// C delivers matching events. It is closed when the subscription ends,
+	// either by Unsubscribe or because the subscriber fell too far behind.
+	C <-chan Event
+
+	ch      chan Event
+	filter  Filter
+	closed  bool
+	overrun bool
+}
+
+// Overrun returns true if the subscription was closed because the subscriber
+// didn't keep up. Only valid once C has been closed.
+func (s *Subscription) Overrun() bool {
+	return s.overrun
+}
+
+// subscribers is the set of subscriptions on a bus
+type subscribers struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscribe returns a subscription to events matching filter. Events are
+// buffered up to buffer (DefaultSubscriptionBuffer if <= 0); a subscriber
+// that falls further behind is dropped rather than slowing down the bus.
+func (b *Bus) Subscribe(filter Filter, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	b.subscribers.mu.Lock()
+	defer b.subscribers.mu.Unlock()
+	if b.subscribers.subs == nil {
+		b.subscribers.subs = make(map[*Subscription]struct{})
+	}
+	b.subscribers.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe ends a subscription and closes its channel
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.subscribers.mu.Lock()
+	defer b.subscribers.mu.Unlock()
+	b.closeSubscription(sub)
+}
+
+// SubscriberCount returns the number of active subscriptions
+func (b *Bus) SubscriberCount() int {
+	b.subscribers.mu.Lock()
+	defer b.subscribers.mu.Unlock()
+	return len(b.subscribers.subs)
+}
+
+// Broadcast sends an event to subscribers only. It is used for events too
+// frequent to journal or hand to hooks, such as state changes.
+func (b *Bus) Broadcast(event Event) {
+	b.publish(withTimestamp(event))
+}
+
+// publish delivers an event to every matching subscription
+func (b *Bus) publish(event Event) {
+	b.subscribers.mu.Lock()
+	defer b.subscribers.mu.Unlock()
+
+	for sub := range b.subscribers.subs {
+		if !sub.filter.matchesLive(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.overrun = true
+			b.closeSubscription(sub)
+		}
+	}
+}
+
+// closeSubscription removes a subscription; the caller holds subscribers.mu
+func (b *Bus) closeSubscription(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers.subs, sub)
+	close(sub.ch)
+}
+
+// matchesLive is Matches without the time window, for events as they happen
+func (f Filter) matchesLive(event Event) bool {
+	f.Since = time.Time{}
+	return f.Matches(event)
+}
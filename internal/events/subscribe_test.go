@@ -0,0 +1,95 @@
+package events
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBusSubscribe(t *testing.T) {
+	bus := NewBus(HookConfig{})
+	journal := NewJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+	bus.SetJournal(journal)
+
+	all := bus.Subscribe(Filter{}, 0)
+	repoOnly := bus.Subscribe(Filter{RepoName: "repo-a", Types: []EventType{EventAgentStarted, EventStateChanged}}, 0)
+	if got := bus.SubscriberCount(); got != 2 {
+		t.Fatalf("SubscriberCount() = %d, want 2", got)
+	}
+
+	bus.Emit(NewAgentStartedEvent("repo-a", "worker-1", "worker", "task"))
+	bus.Emit(NewAgentStartedEvent("repo-b", "worker-2", "worker", "task"))
+	bus.Emit(NewAgentIdleEvent("repo-a", "worker-1", 60))
+	bus.Broadcast(NewStateChangedEvent("repo-a", "worker-1", "agent_updated", []string{"pid"}))
+
+	want := []EventType{EventAgentStarted, EventAgentStarted, EventAgentIdle, EventStateChanged}
+	for i, wantType := range want {
+		select {
+		case e := <-all.C:
+			if e.Type != wantType {
+				t.Errorf("event %d type = %s, want %s", i, e.Type, wantType)
+			}
+			if e.Timestamp.IsZero() {
+				t.Errorf("event %d should have a timestamp", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+
+	for _, wantType := range []EventType{EventAgentStarted, EventStateChanged} {
+		e := <-repoOnly.C
+		if e.Type != wantType || e.RepoName != "repo-a" {
+			t.Errorf("filtered subscription got %s for %s, want %s for repo-a", e.Type, e.RepoName, wantType)
+		}
+	}
+	select {
+	case e := <-repoOnly.C:
+		t.Errorf("filtered subscription got unexpected event %+v", e)
+	default:
+	}
+
+	// Broadcast events reach subscribers but not the journal
+	journaled, err := journal.Query(Filter{Types: []EventType{EventStateChanged}})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(journaled) != 0 {
+		t.Errorf("state_changed should not be journaled, got %d", len(journaled))
+	}
+
+	bus.Unsubscribe(all)
+	bus.Unsubscribe(all)
+	if _, ok := <-all.C; ok {
+		t.Error("channel should be closed after Unsubscribe")
+	}
+	if all.Overrun() {
+		t.Error("Unsubscribe should not report an overrun")
+	}
+	if got := bus.SubscriberCount(); got != 1 {
+		t.Errorf("SubscriberCount() after Unsubscribe = %d, want 1", got)
+	}
+}
+
+func TestBusSubscribeOverrun(t *testing.T) {
+	bus := NewBus(HookConfig{})
+	slow := bus.Subscribe(Filter{}, 2)
+
+	for i := 0; i < 3; i++ {
+		bus.Broadcast(NewStateChangedEvent("repo", "", "repo_updated", nil))
+	}
+
+	count := 0
+	for range slow.C {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("slow subscriber got %d buffered events, want 2", count)
+	}
+	if !slow.Overrun() {
+		t.Error("Overrun() should be true for a subscriber that fell behind")
+	}
+	if got := bus.SubscriberCount(); got != 0 {
+		t.Errorf("SubscriberCount() = %d, want 0 after the overrun", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package forge abstracts where a repository's pull requests live: GitHub,
+// through the gh CLI, or a local forge that keeps PRs, reviews and check
+// results as files next to a bare repository, so multiclaude can run
+// against internal git servers and without network.
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+)
+
+// PR states
+const (
+	StateOpen   = github.StateOpen
+	StateMerged = github.StateMerged
+	StateClosed = github.StateClosed
+)
+
+// Combined CI statuses of a PR's checks. A PR without checks has an empty
+// CI status.
+const (
+	CIPassing = github.CIPassing
+	CIFailing = github.CIFailing
+	CIPending = github.CIPending
+)
+
+// Review decisions. A PR without reviews has an empty review decision.
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+)
+
+// ReviewCommented is the state of a review that only comments
+const ReviewCommented = "COMMENTED"
+
+// Check states
+const (
+	CheckSuccess = "success"
+	CheckFailure = "failure"
+	CheckError   = "error"
+	CheckPending = "pending"
+)
+
+// Merge methods
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+// PR is a pull request and the state of its checks and reviews
+type PR struct {
+	Number int
+	URL    string
+	Title  string
+	// Head and Base are the branches the PR merges from and into
+	Head string
+	Base string
+	// State is StateOpen, StateMerged or StateClosed
+	State string
+	// CIStatus is CIPassing, CIFailing, CIPending, or empty if there are no checks
+	CIStatus string
+	// FailedChecks are the names of the failed checks, sorted
+	FailedChecks []string
+	// ReviewDecision is ReviewApproved, ReviewChangesRequested, another
+	// forge-specific decision such as GitHub's "REVIEW_REQUIRED", or empty
+	ReviewDecision string
+	// Reviews are the PR's reviews, oldest first, on forges that list them
+	// with the PR
+	Reviews []Review
+}
+
+// Review is a review of a PR
+type Review struct {
+	// Author is recorded by forges that don't authenticate users
+	Author string `json:"author"`
+	// State is ReviewApproved, ReviewChangesRequested or ReviewCommented
+	State       string    `json:"state"`
+	Body        string    `json:"body,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Check is the result of a check on a commit
+type Check struct {
+	Name string `json:"name"`
+	// State is CheckSuccess, CheckFailure, CheckError or CheckPending
+	State string `json:"state"`
+	URL   string `json:"url,omitempty"`
+}
+
+// CreateOptions describe a PR to open
+type CreateOptions struct {
+	// Head is the pushed branch to merge, Base the branch to merge it into
+	Head  string
+	Base  string
+	Title string
+	Body  string
+	// Author is recorded by forges that don't authenticate users
+	Author string
+}
+
+// MergeOptions control how a PR is merged
+type MergeOptions struct {
+	// Method is MergeMethodMerge, MergeMethodSquash or MergeMethodRebase
+	Method string
+	// DeleteBranch deletes the head branch after merging
+	DeleteBranch bool
+}
+
+// Forge creates, looks up, reviews and merges a repository's PRs, and
+// records check results on its commits
+type Forge interface {
+	// Name is "github" or "local"
+	Name() string
+
+	// CreatePR opens a PR and returns it
+	CreatePR(opts CreateOptions) (*PR, error)
+
+	// ListPRs returns up to limit PRs in state (StateOpen, StateMerged,
+	// StateClosed, or empty for all), newest first
+	ListPRs(state string, limit int) ([]*PR, error)
+
+	// GetPR returns PR number
+	GetPR(number int) (*PR, error)
+
+	// PRForBranch returns the most recent PR whose head is branch, or nil if
+	// there is none
+	PRForBranch(branch string) (*PR, error)
+
+	// DiffPR returns the diff between PR number's base and head
+	DiffPR(number int) (string, error)
+
+	// MergePR merges PR number into its base
+	MergePR(number int, opts MergeOptions) error
+
+	// AddReview submits a review of PR number
+	AddReview(number int, review Review) error
+
+	// SetCheck records the result of a check on ref, a branch or commit,
+	// replacing any earlier result of the same check
+	SetCheck(ref string, check Check) error
+}
+
+// New returns the forge for a repository cloned from remoteURL and checked
+// out at repoPath: a local forge if remoteURL is a path on this machine,
+// GitHub through gh otherwise
+func New(remoteURL, repoPath string, gh *github.Client) Forge {
+	if path, ok := LocalPath(remoteURL); ok {
+		return NewLocal(path)
+	}
+	return NewGitHub(gh, repoPath)
+}
+
+// IsLocal reports whether a repository cloned from remoteURL uses the local
+// forge
+func IsLocal(remoteURL string) bool {
+	_, ok := LocalPath(remoteURL)
+	return ok
+}
+
+// LocalPath returns the path of the repository at remoteURL if it's an
+// absolute path or a file:// URL
+func LocalPath(remoteURL string) (string, bool) {
+	if strings.HasPrefix(remoteURL, "file://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil || u.Path == "" {
+			return "", false
+		}
+		return filepath.Clean(u.Path), true
+	}
+	if filepath.IsAbs(remoteURL) {
+		return filepath.Clean(remoteURL), true
+	}
+	return "", false
+}
+
+// validateCheckState returns an error if state isn't one of the check states
+func validateCheckState(state string) error {
+	switch state {
+	case CheckSuccess, CheckFailure, CheckError, CheckPending:
+		return nil
+	}
+	return fmt.Errorf("unknown check state %q (must be success, failure, error or pending)", state)
+}
@@ -0,0 +1,37 @@
+package forge
+
+import (
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		path string
+	}{
+		{url: "https://github.com/owner/repo", want: "github"},
+		{url: "git@github.com:owner/repo.git", want: "github"},
+		{url: "ssh://git.internal/app.git", want: "github"},
+		{url: "/srv/git/app.git", want: "local", path: "/srv/git/app.git"},
+		{url: "file:///srv/git/app.git/", want: "local", path: "/srv/git/app.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			f := New(tt.url, t.TempDir(), github.NewClient(""))
+			if f.Name() != tt.want || IsLocal(tt.url) != (tt.want == "local") {
+				t.Errorf("New(%q) = %s forge, want %s", tt.url, f.Name(), tt.want)
+			}
+			if path, _ := LocalPath(tt.url); path != tt.path {
+				t.Errorf("LocalPath(%q) = %q, want %q", tt.url, path, tt.path)
+			}
+		})
+	}
+
+	if got := LocalDir("/srv/git/app.git"); got != "/srv/git/app.forge" {
+		t.Errorf("LocalDir() = %q, want /srv/git/app.forge", got)
+	}
+}
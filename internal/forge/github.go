@@ -0,0 +1,135 @@
+package forge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+)
+
+// GitHub is the forge for repositories on GitHub, driven through gh in a
+// checkout of the repository
+type GitHub struct {
+	client   *github.Client
+	repoPath string
+}
+
+// NewGitHub returns a GitHub forge running client in the checkout at repoPath
+func NewGitHub(client *github.Client, repoPath string) *GitHub {
+	return &GitHub{client: client, repoPath: repoPath}
+}
+
+// Name returns "github"
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// CreatePR opens a PR with gh pr create. The author is whoever gh is
+// logged in as.
+func (g *GitHub) CreatePR(opts CreateOptions) (*PR, error) {
+	pr, err := g.client.CreatePR(g.repoPath, opts.Head, opts.Base, opts.Title, opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(pr), nil
+}
+
+// ListPRs lists PRs with gh pr list
+func (g *GitHub) ListPRs(state string, limit int) ([]*PR, error) {
+	ghState := strings.ToLower(state)
+	if ghState == "" {
+		ghState = "all"
+	}
+	prs, err := g.client.ListPRs(g.repoPath, ghState, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*PR, 0, len(prs))
+	for _, pr := range prs {
+		result = append(result, fromGitHub(pr))
+	}
+	return result, nil
+}
+
+// GetPR looks up a PR with gh pr view
+func (g *GitHub) GetPR(number int) (*PR, error) {
+	pr, err := g.client.ViewPR(g.repoPath, fmt.Sprint(number))
+	if err != nil {
+		return nil, err
+	}
+	return fromGitHub(pr), nil
+}
+
+// PRForBranch looks up the PR for branch with gh pr list
+func (g *GitHub) PRForBranch(branch string) (*PR, error) {
+	pr, err := g.client.PRForBranch(g.repoPath, branch)
+	if err != nil || pr == nil {
+		return nil, err
+	}
+	return fromGitHub(pr), nil
+}
+
+// MergePR merges a PR with gh pr merge
+func (g *GitHub) MergePR(number int, opts MergeOptions) error {
+	method := opts.Method
+	if method == "" {
+		method = MergeMethodMerge
+	}
+	switch method {
+	case MergeMethodMerge, MergeMethodSquash, MergeMethodRebase:
+	default:
+		return fmt.Errorf("unknown merge method %q (must be merge, squash or rebase)", method)
+	}
+	return g.client.MergePR(g.repoPath, number, method, opts.DeleteBranch)
+}
+
+// DiffPR returns a PR's diff from gh pr diff
+func (g *GitHub) DiffPR(number int) (string, error) {
+	return g.client.DiffPR(g.repoPath, number)
+}
+
+// AddReview submits a review with gh pr review as whoever gh is logged in
+// as. GitHub needs a body to request changes or comment.
+func (g *GitHub) AddReview(number int, review Review) error {
+	var action string
+	switch review.State {
+	case ReviewApproved:
+		action = "approve"
+	case ReviewChangesRequested:
+		action = "request-changes"
+	case ReviewCommented:
+		action = "comment"
+	default:
+		return fmt.Errorf("unknown review state %q", review.State)
+	}
+	if review.Body == "" && action != "approve" {
+		return fmt.Errorf("GitHub needs a body to %s", strings.ReplaceAll(action, "-", " "))
+	}
+	return g.client.ReviewPR(g.repoPath, number, action, review.Body)
+}
+
+// SetCheck sets a commit status on ref through the GitHub API. Checks run
+// by GitHub Actions report themselves; this is for checks run elsewhere.
+func (g *GitHub) SetCheck(ref string, check Check) error {
+	if check.Name == "" {
+		return fmt.Errorf("a check needs a name")
+	}
+	if err := validateCheckState(check.State); err != nil {
+		return err
+	}
+	return g.client.SetCommitStatus(g.repoPath, ref, check.Name, check.State, check.URL)
+}
+
+func fromGitHub(pr *github.PR) *PR {
+	return &PR{
+		Number:         pr.Number,
+		URL:            pr.URL,
+		Title:          pr.Title,
+		Head:           pr.Head,
+		Base:           pr.Base,
+		State:          pr.State,
+		CIStatus:       pr.CIStatus,
+		FailedChecks:   pr.FailedChecks,
+		ReviewDecision: pr.ReviewDecision,
+	}
+}
@@ -0,0 +1,496 @@
+package forge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// Local is the forge for a bare repository on this machine. Its data lives
+// in a directory next to the repository (see LocalDir):
+//
+//	prs/<number>.json           PRs and their reviews
+//	checks/<sha>/<name>.json    Check results for a commit
+//
+// CI scripts report results by writing check files, or with SetCheck
+// (multiclaude pr check).
+// Merging needs git 2.38 or later for merge-tree --write-tree.
+type Local struct {
+	repoPath string
+	dir      string
+}
+
+// localPR is a PR as stored on the local forge
+type localPR struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	Head      string    `json:"head"`
+	Base      string    `json:"base"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	Reviews   []Review  `json:"reviews,omitempty"`
+
+	// Set when the PR is merged, since the head branch may be deleted
+	MergedAt    *time.Time `json:"merged_at,omitempty"`
+	HeadSHA     string     `json:"head_sha,omitempty"`
+	MergeCommit string     `json:"merge_commit,omitempty"`
+}
+
+// NewLocal returns the local forge for the bare repository at repoPath
+func NewLocal(repoPath string) *Local {
+	return &Local{repoPath: repoPath, dir: LocalDir(repoPath)}
+}
+
+// LocalDir returns the directory the local forge keeps its data in for the
+// bare repository at repoPath: next to it, with a .forge extension instead
+// of .git
+func LocalDir(repoPath string) string {
+	return strings.TrimSuffix(filepath.Clean(repoPath), ".git") + ".forge"
+}
+
+// Name returns "local"
+func (l *Local) Name() string {
+	return "local"
+}
+
+// CreatePR records a PR from a branch pushed to the repository
+func (l *Local) CreatePR(opts CreateOptions) (*PR, error) {
+	if opts.Head == "" || opts.Base == "" || opts.Title == "" {
+		return nil, fmt.Errorf("a PR needs a head branch, a base branch and a title")
+	}
+	if opts.Head == opts.Base {
+		return nil, fmt.Errorf("head and base are both %s", opts.Head)
+	}
+	if _, err := l.resolveBranch(opts.Head); err != nil {
+		return nil, fmt.Errorf("branch %s isn't on the forge; push it first: git push -u origin %s", opts.Head, opts.Head)
+	}
+	if _, err := l.resolveBranch(opts.Base); err != nil {
+		return nil, fmt.Errorf("base branch %s doesn't exist on the forge", opts.Base)
+	}
+
+	prs, err := l.readPRs()
+	if err != nil {
+		return nil, err
+	}
+	next := 1
+	for _, pr := range prs {
+		if pr.State == StateOpen && pr.Head == opts.Head && pr.Base == opts.Base {
+			return nil, fmt.Errorf("PR #%d from %s into %s is already open", pr.Number, opts.Head, opts.Base)
+		}
+		if pr.Number >= next {
+			next = pr.Number + 1
+		}
+	}
+
+	pr := &localPR{
+		Title:     opts.Title,
+		Body:      opts.Body,
+		Author:    opts.Author,
+		Head:      opts.Head,
+		Base:      opts.Base,
+		State:     StateOpen,
+		CreatedAt: time.Now(),
+	}
+	if err := l.createPR(pr, next); err != nil {
+		return nil, err
+	}
+	return l.toPR(pr), nil
+}
+
+// ListPRs returns the forge's PRs, newest first
+func (l *Local) ListPRs(state string, limit int) ([]*PR, error) {
+	prs, err := l.readPRs()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*PR
+	for i := len(prs) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		if state == "" || strings.EqualFold(prs[i].State, state) {
+			result = append(result, l.toPR(prs[i]))
+		}
+	}
+	return result, nil
+}
+
+// GetPR returns PR number
+func (l *Local) GetPR(number int) (*PR, error) {
+	pr, err := l.readPR(number)
+	if err != nil {
+		return nil, err
+	}
+	return l.toPR(pr), nil
+}
+
+// PRForBranch returns the newest PR from branch
+func (l *Local) PRForBranch(branch string) (*PR, error) {
+	prs, err := l.readPRs()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(prs) - 1; i >= 0; i-- {
+		if prs[i].Head == branch {
+			return l.toPR(prs[i]), nil
+		}
+	}
+	return nil, nil
+}
+
+// DiffPR returns the changes a PR's head makes since it branched off its
+// base. Merged PRs are diffed at the commit they were merged from.
+func (l *Local) DiffPR(number int) (string, error) {
+	pr, err := l.readPR(number)
+	if err != nil {
+		return "", err
+	}
+	head := pr.HeadSHA
+	if head == "" {
+		if head, err = l.resolveBranch(pr.Head); err != nil {
+			return "", fmt.Errorf("head branch %s of PR #%d no longer exists", pr.Head, number)
+		}
+	}
+	base, err := l.resolveBranch(pr.Base)
+	if err != nil {
+		return "", fmt.Errorf("base branch %s of PR #%d no longer exists", pr.Base, number)
+	}
+	// Not git(), which trims the diff's final newline
+	cmd := exec.Command("git", "diff", base+"..."+head)
+	cmd.Dir = l.repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to diff PR #%d: %w", number, err)
+	}
+	return string(output), nil
+}
+
+// MergePR merges a PR's head into its base in the bare repository, with a
+// merge commit or a squashed commit. Rebase merges aren't supported. A PR
+// that conflicts with its base isn't merged.
+func (l *Local) MergePR(number int, opts MergeOptions) error {
+	pr, err := l.readPR(number)
+	if err != nil {
+		return err
+	}
+	if pr.State != StateOpen {
+		return fmt.Errorf("PR #%d is already %s", number, strings.ToLower(pr.State))
+	}
+	method := opts.Method
+	if method == "" {
+		method = MergeMethodMerge
+	}
+	if method != MergeMethodMerge && method != MergeMethodSquash {
+		return fmt.Errorf("the local forge can't %s PRs (use merge or squash)", method)
+	}
+
+	headSHA, err := l.resolveBranch(pr.Head)
+	if err != nil {
+		return fmt.Errorf("head branch %s of PR #%d no longer exists", pr.Head, number)
+	}
+	baseSHA, err := l.resolveBranch(pr.Base)
+	if err != nil {
+		return fmt.Errorf("base branch %s of PR #%d no longer exists", pr.Base, number)
+	}
+
+	conflicts, err := worktree.NewManager(l.repoPath).PredictConflicts(baseSHA, headSHA)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("PR #%d conflicts with %s in %s; rebase %s onto %s and push it", number, pr.Base, strings.Join(conflicts, ", "), pr.Head, pr.Base)
+	}
+	tree, err := l.git("merge-tree", "--write-tree", baseSHA, headSHA)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"commit-tree", strings.Fields(tree)[0], "-p", baseSHA}
+	if method == MergeMethodSquash {
+		args = append(args, "-m", fmt.Sprintf("%s (#%d)", pr.Title, number))
+		if pr.Body != "" {
+			args = append(args, "-m", pr.Body)
+		}
+	} else {
+		args = append(args, "-p", headSHA, "-m", fmt.Sprintf("Merge PR #%d from %s", number, pr.Head), "-m", pr.Title)
+	}
+	commit, err := l.gitEnv(l.commitIdentity(), args...)
+	if err != nil {
+		return err
+	}
+	if _, err := l.git("update-ref", "refs/heads/"+pr.Base, commit, baseSHA); err != nil {
+		return fmt.Errorf("failed to update %s, which may have moved while merging: %w", pr.Base, err)
+	}
+
+	now := time.Now()
+	pr.State = StateMerged
+	pr.MergedAt = &now
+	pr.HeadSHA = headSHA
+	pr.MergeCommit = commit
+	if err := l.writePR(pr); err != nil {
+		return err
+	}
+
+	if opts.DeleteBranch {
+		if _, err := l.git("update-ref", "-d", "refs/heads/"+pr.Head, headSHA); err != nil {
+			return fmt.Errorf("merged PR #%d but failed to delete %s: %w", number, pr.Head, err)
+		}
+	}
+	return nil
+}
+
+// AddReview records a review of PR number
+func (l *Local) AddReview(number int, review Review) error {
+	switch review.State {
+	case ReviewApproved, ReviewChangesRequested, ReviewCommented:
+	default:
+		return fmt.Errorf("unknown review state %q", review.State)
+	}
+	pr, err := l.readPR(number)
+	if err != nil {
+		return err
+	}
+	if review.SubmittedAt.IsZero() {
+		review.SubmittedAt = time.Now()
+	}
+	pr.Reviews = append(pr.Reviews, review)
+	return l.writePR(pr)
+}
+
+// SetCheck records the result of a check on ref, a branch or commit,
+// replacing any earlier result of the same check
+func (l *Local) SetCheck(ref string, check Check) error {
+	if check.Name == "" {
+		return fmt.Errorf("a check needs a name")
+	}
+	if err := validateCheckState(check.State); err != nil {
+		return err
+	}
+	sha, err := l.git("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	dir := filepath.Join(l.dir, "checks", sha)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checks directory: %w", err)
+	}
+	data, err := json.MarshalIndent(check, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, strings.ReplaceAll(check.Name, "/", "_")+".json"), data)
+}
+
+// toPR returns the PR with its checks and review decision
+func (l *Local) toPR(pr *localPR) *PR {
+	result := &PR{
+		Number:         pr.Number,
+		URL:            "file://" + l.prPath(pr.Number),
+		Title:          pr.Title,
+		Head:           pr.Head,
+		Base:           pr.Base,
+		State:          pr.State,
+		ReviewDecision: reviewDecision(pr.Reviews),
+		Reviews:        pr.Reviews,
+	}
+
+	sha := pr.HeadSHA
+	if pr.State == StateOpen {
+		sha, _ = l.resolveBranch(pr.Head)
+	}
+	if sha != "" {
+		result.CIStatus, result.FailedChecks = combineChecks(l.checks(sha))
+	}
+	return result
+}
+
+// checks returns the check results recorded for commit sha
+func (l *Local) checks(sha string) []Check {
+	files, _ := filepath.Glob(filepath.Join(l.dir, "checks", sha, "*.json"))
+	var checks []Check
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var check Check
+		if err := json.Unmarshal(data, &check); err == nil {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// combineChecks returns the CI status of a commit's checks
+func combineChecks(checks []Check) (string, []string) {
+	results := make([]github.CheckResult, 0, len(checks))
+	for _, check := range checks {
+		result := github.CheckResult{Name: check.Name, Result: CIPending}
+		switch strings.ToLower(check.State) {
+		case CheckSuccess:
+			result.Result = CIPassing
+		case CheckFailure, CheckError:
+			result.Result = CIFailing
+		}
+		results = append(results, result)
+	}
+	return github.CombineChecks(results)
+}
+
+// reviewDecision combines each reviewer's latest approval or change request:
+// any change request wins over approvals
+func reviewDecision(reviews []Review) string {
+	latest := make(map[string]string)
+	for _, review := range reviews {
+		if review.State == ReviewApproved || review.State == ReviewChangesRequested {
+			latest[review.Author] = review.State
+		}
+	}
+
+	decision := ""
+	for _, state := range latest {
+		if state == ReviewChangesRequested {
+			return ReviewChangesRequested
+		}
+		decision = ReviewApproved
+	}
+	return decision
+}
+
+func (l *Local) prPath(number int) string {
+	return filepath.Join(l.dir, "prs", fmt.Sprintf("%d.json", number))
+}
+
+// readPRs returns the forge's PRs in number order. PRs that can't be read,
+// such as one still being created, are skipped.
+func (l *Local) readPRs() ([]*localPR, error) {
+	entries, err := os.ReadDir(filepath.Join(l.dir, "prs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PRs: %w", err)
+	}
+
+	var prs []*localPR
+	for _, entry := range entries {
+		number, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if pr, err := l.readPR(number); err == nil {
+			prs = append(prs, pr)
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].Number < prs[j].Number })
+	return prs, nil
+}
+
+func (l *Local) readPR(number int) (*localPR, error) {
+	data, err := os.ReadFile(l.prPath(number))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("PR #%d not found", number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PR #%d: %w", number, err)
+	}
+	var pr localPR
+	if err := json.Unmarshal(data, &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse PR #%d: %w", number, err)
+	}
+	return &pr, nil
+}
+
+// createPR stores a new PR under the first free number from next
+func (l *Local) createPR(pr *localPR, next int) error {
+	if err := os.MkdirAll(filepath.Join(l.dir, "prs"), 0755); err != nil {
+		return fmt.Errorf("failed to create PRs directory: %w", err)
+	}
+	for number := next; ; number++ {
+		f, err := os.OpenFile(l.prPath(number), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create PR: %w", err)
+		}
+		f.Close()
+
+		pr.Number = number
+		return l.writePR(pr)
+	}
+}
+
+func (l *Local) writePR(pr *localPR) error {
+	data, err := json.MarshalIndent(pr, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.prPath(pr.Number), data)
+}
+
+// writeFileAtomic replaces path with data, so readers never see it half
+// written
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// resolveBranch returns the commit branch points to
+func (l *Local) resolveBranch(branch string) (string, error) {
+	return l.git("rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+}
+
+// commitIdentity returns the environment to commit as multiclaude if the
+// repository has no identity configured
+func (l *Local) commitIdentity() []string {
+	if _, err := l.git("config", "user.email"); err == nil {
+		return nil
+	}
+	return []string{
+		"GIT_AUTHOR_NAME=multiclaude", "GIT_AUTHOR_EMAIL=multiclaude@localhost",
+		"GIT_COMMITTER_NAME=multiclaude", "GIT_COMMITTER_EMAIL=multiclaude@localhost",
+	}
+}
+
+// git runs git in the repository and returns its trimmed output
+func (l *Local) git(args ...string) (string, error) {
+	return l.gitEnv(nil, args...)
+}
+
+// gitEnv runs git with extra environment variables
+func (l *Local) gitEnv(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = l.repoPath
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,204 @@
+package forge
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupLocalForge returns a local forge for a bare repository whose main
+// has a README, and a function that pushes a branch off main changing files
+func setupLocalForge(t *testing.T) (*Local, func(branch string, files map[string]string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "app.git")
+	clone := filepath.Join(dir, "clone")
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	commit := func(files map[string]string, message string) {
+		t.Helper()
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(clone, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		run("-C", clone, "add", "-A")
+		run("-C", clone, "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-q", "-m", message)
+	}
+
+	run("init", "-q", "--bare", "-b", "main", bare)
+	run("clone", "-q", bare, clone)
+	commit(map[string]string{"README.md": "# App\n"}, "Initial commit")
+	run("-C", clone, "push", "-q", "origin", "HEAD:main")
+
+	push := func(branch string, files map[string]string) string {
+		t.Helper()
+		run("-C", clone, "checkout", "-q", "-B", branch, "origin/main")
+		commit(files, "Change on "+branch)
+		run("-C", clone, "push", "-q", "-f", "origin", branch)
+		run("-C", clone, "fetch", "-q", "origin")
+		return run("-C", clone, "rev-parse", "HEAD")
+	}
+	return NewLocal(bare), push
+}
+
+func TestLocalForgePRs(t *testing.T) {
+	f, push := setupLocalForge(t)
+
+	if _, err := f.CreatePR(CreateOptions{Head: "work/calm-otter", Base: "main", Title: "Add login"}); err == nil || !strings.Contains(err.Error(), "push it first") {
+		t.Errorf("CreatePR() of an unpushed branch error = %v, want a hint to push", err)
+	}
+
+	sha := push("work/calm-otter", map[string]string{"login.go": "package app\n"})
+	pr, err := f.CreatePR(CreateOptions{Head: "work/calm-otter", Base: "main", Title: "Add login", Body: "Adds login", Author: "calm-otter"})
+	if err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if pr.Number != 1 || pr.State != StateOpen || pr.CIStatus != "" || pr.ReviewDecision != "" || pr.URL != "file://"+filepath.Join(LocalDir(f.repoPath), "prs", "1.json") {
+		t.Errorf("CreatePR() = %+v, want open PR #1 without checks or reviews", pr)
+	}
+	if _, err := f.CreatePR(CreateOptions{Head: "work/calm-otter", Base: "main", Title: "Again"}); err == nil {
+		t.Error("CreatePR() for a branch with an open PR should fail")
+	}
+	push("work/tidy-heron", map[string]string{"logout.go": "package app\n"})
+	if _, err := f.CreatePR(CreateOptions{Head: "work/tidy-heron", Base: "main", Title: "Add logout"}); err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+
+	// Checks on the head commit and reviews show up on the PR
+	if err := f.SetCheck("work/calm-otter", Check{Name: "test", State: "failure"}); err != nil {
+		t.Fatalf("SetCheck() failed: %v", err)
+	}
+	if err := f.SetCheck(sha, Check{Name: "lint", State: "success"}); err != nil {
+		t.Fatalf("SetCheck() failed: %v", err)
+	}
+	if err := f.AddReview(1, Review{Author: "reviewer", State: ReviewChangesRequested}); err != nil {
+		t.Fatalf("AddReview() failed: %v", err)
+	}
+	pr, err = f.PRForBranch("work/calm-otter")
+	if err != nil || pr == nil {
+		t.Fatalf("PRForBranch() = %+v, %v, want PR #1", pr, err)
+	}
+	if pr.CIStatus != CIFailing || !reflect.DeepEqual(pr.FailedChecks, []string{"test"}) || pr.ReviewDecision != ReviewChangesRequested {
+		t.Errorf("PR #1 = %+v, want failing test and changes requested", pr)
+	}
+
+	if err := f.SetCheck(sha, Check{Name: "test", State: "success"}); err != nil {
+		t.Fatalf("SetCheck() failed: %v", err)
+	}
+	if err := f.AddReview(1, Review{Author: "reviewer", State: ReviewApproved}); err != nil {
+		t.Fatalf("AddReview() failed: %v", err)
+	}
+	pr, _ = f.PRForBranch("work/calm-otter")
+	if pr.CIStatus != CIPassing || pr.ReviewDecision != ReviewApproved {
+		t.Errorf("PR #1 = %+v, want passing and approved", pr)
+	}
+
+	if err := f.AddReview(1, Review{Author: "reviewer", State: "LGTM"}); err == nil {
+		t.Error("AddReview() with an unknown state should fail")
+	}
+	if err := f.SetCheck(sha, Check{Name: "test", State: "passed"}); err == nil {
+		t.Error("SetCheck() with an unknown state should fail")
+	}
+	if pr, err := f.GetPR(1); err != nil || pr.Head != "work/calm-otter" || pr.CIStatus != CIPassing {
+		t.Errorf("GetPR(1) = %+v, %v, want passing PR from work/calm-otter", pr, err)
+	}
+	if _, err := f.GetPR(7); err == nil {
+		t.Error("GetPR() of a missing PR should fail")
+	}
+	if diff, err := f.DiffPR(1); err != nil || !strings.Contains(diff, "+++ b/login.go") || strings.Contains(diff, "logout.go") {
+		t.Errorf("DiffPR(1) = %q, %v, want only login.go", diff, err)
+	}
+
+	prs, err := f.ListPRs(StateOpen, 0)
+	if err != nil || len(prs) != 2 || prs[0].Number != 2 || prs[1].Number != 1 {
+		t.Errorf("ListPRs(open) = %+v, %v, want PRs 2 and 1", prs, err)
+	}
+	if prs, _ := f.ListPRs("", 1); len(prs) != 1 || prs[0].Number != 2 {
+		t.Errorf("ListPRs(all, 1) = %+v, want PR 2", prs)
+	}
+	if pr, err := f.PRForBranch("work/missing"); err != nil || pr != nil {
+		t.Errorf("PRForBranch() of a branch without PRs = %+v, %v, want none", pr, err)
+	}
+}
+
+func TestLocalForgeMerge(t *testing.T) {
+	f, push := setupLocalForge(t)
+	git := func(args ...string) string {
+		t.Helper()
+		output, err := f.git(args...)
+		if err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+		return output
+	}
+
+	// A merge commit, keeping the branch
+	head := push("work/calm-otter", map[string]string{"login.go": "package app\n"})
+	push("work/tidy-heron", map[string]string{"logout.go": "package app\n"})
+	push("work/sad-wolf", map[string]string{"README.md": "# Wolf\n"})
+	for _, branch := range []string{"work/calm-otter", "work/tidy-heron", "work/sad-wolf"} {
+		if _, err := f.CreatePR(CreateOptions{Head: branch, Base: "main", Title: "Work on " + branch, Body: "Details"}); err != nil {
+			t.Fatalf("CreatePR() failed: %v", err)
+		}
+	}
+	if err := f.MergePR(1, MergeOptions{Method: MergeMethodRebase}); err == nil {
+		t.Error("MergePR() with rebase should fail on the local forge")
+	}
+	if err := f.MergePR(1, MergeOptions{}); err != nil {
+		t.Fatalf("MergePR() failed: %v", err)
+	}
+	if parents := git("rev-list", "--parents", "-n", "1", "main"); !strings.HasSuffix(parents, head) || len(strings.Fields(parents)) != 3 {
+		t.Errorf("main = %s, want a merge of %s", parents, head)
+	}
+	git("rev-parse", "--verify", "refs/heads/work/calm-otter")
+	if pr, _ := f.PRForBranch("work/calm-otter"); pr.State != StateMerged {
+		t.Errorf("PR #1 state = %s, want merged", pr.State)
+	}
+	if err := f.MergePR(1, MergeOptions{}); err == nil {
+		t.Error("MergePR() of a merged PR should fail")
+	}
+
+	// A squash, deleting the branch
+	if err := f.MergePR(2, MergeOptions{Method: MergeMethodSquash, DeleteBranch: true}); err != nil {
+		t.Fatalf("MergePR() failed: %v", err)
+	}
+	if subject := git("log", "-1", "--format=%s %P", "main"); !strings.HasPrefix(subject, "Work on work/tidy-heron (#2) ") || len(strings.Fields(subject)) != 5 {
+		t.Errorf("main = %q, want a squashed commit with one parent", subject)
+	}
+	git("cat-file", "-e", "main:logout.go")
+	if _, err := f.git("rev-parse", "--verify", "--quiet", "refs/heads/work/tidy-heron"); err == nil {
+		t.Error("work/tidy-heron should be deleted after merging")
+	}
+
+	// A conflicting PR isn't merged
+	before := git("rev-parse", "main")
+	push("work/other", map[string]string{"README.md": "# Other\n"})
+	if _, err := f.CreatePR(CreateOptions{Head: "work/other", Base: "main", Title: "Other README"}); err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if err := f.MergePR(4, MergeOptions{}); err != nil {
+		t.Fatalf("MergePR() failed: %v", err)
+	}
+	if err := f.MergePR(3, MergeOptions{}); err == nil || !strings.Contains(err.Error(), "README.md") {
+		t.Errorf("MergePR() of a conflicting PR error = %v, want the conflicting file", err)
+	}
+	if pr, _ := f.PRForBranch("work/sad-wolf"); pr.State != StateOpen {
+		t.Errorf("PR #3 state = %s after a conflict, want open", pr.State)
+	}
+	if after := git("rev-parse", "main~1"); after != before {
+		t.Errorf("main moved to %s, want only PR #4 merged on %s", after, before)
+	}
+}
@@ -0,0 +1,395 @@
+// Package github creates, looks up and merges pull requests and their checks
+// through the gh CLI.
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// PR states as reported by gh
+const (
+	StateOpen   = "OPEN"
+	StateMerged = "MERGED"
+	StateClosed = "CLOSED"
+)
+
+// Combined CI statuses of a PR's checks. A PR without checks has an empty
+// CI status.
+const (
+	CIPassing = "passing"
+	CIFailing = "failing"
+	CIPending = "pending"
+)
+
+// prFields are the PR fields requested from gh
+const prFields = "number,url,title,state,headRefName,baseRefName,reviewDecision,statusCheckRollup"
+
+// PR is a pull request and the state of its checks and reviews
+type PR struct {
+	Number int
+	URL    string
+	Title  string
+	// Head and Base are the branches the PR merges from and into
+	Head string
+	Base string
+	// State is StateOpen, StateMerged or StateClosed
+	State string
+	// CIStatus is CIPassing, CIFailing, CIPending, or empty if there are no checks
+	CIStatus string
+	// FailedChecks are the names of the failed checks, sorted
+	FailedChecks []string
+	// ReviewDecision is "APPROVED", "CHANGES_REQUESTED", "REVIEW_REQUIRED",
+	// or empty if reviews aren't required
+	ReviewDecision string
+}
+
+// Checks is the combined status of the checks on a commit
+type Checks struct {
+	SHA string
+	// Status is CIPassing, CIFailing, CIPending, or empty if there are no checks
+	Status string
+	// FailedChecks are the names of the failed checks, sorted
+	FailedChecks []string
+	// URL links to the first failed check, or to any check if none failed
+	URL string
+}
+
+// Client runs gh. Tests can point it at a fake binary.
+type Client struct {
+	bin string
+}
+
+// NewClient returns a client running the gh binary bin, "gh" if empty
+func NewClient(bin string) *Client {
+	if bin == "" {
+		bin = "gh"
+	}
+	return &Client{bin: bin}
+}
+
+// PRForBranch returns the most recent PR whose head is branch in the
+// repository checked out at repoPath, or nil if there is none
+func (c *Client) PRForBranch(repoPath, branch string) (*PR, error) {
+	output, err := c.run(repoPath, "pr", "list", "--head", branch, "--state", "all",
+		"--json", prFields, "--limit", "1")
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []prJSON
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse gh pr list output: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return prs[0].toPR(), nil
+}
+
+// ListPRs returns up to limit PRs in state ("open", "closed", "merged" or
+// "all"), newest first
+func (c *Client) ListPRs(repoPath, state string, limit int) ([]*PR, error) {
+	output, err := c.run(repoPath, "pr", "list", "--state", state,
+		"--json", prFields, "--limit", fmt.Sprint(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var prs []prJSON
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse gh pr list output: %w", err)
+	}
+	result := make([]*PR, 0, len(prs))
+	for _, pr := range prs {
+		result = append(result, pr.toPR())
+	}
+	return result, nil
+}
+
+// ViewPR returns the PR identified by ref: a number, URL or branch
+func (c *Client) ViewPR(repoPath, ref string) (*PR, error) {
+	output, err := c.run(repoPath, "pr", "view", ref, "--json", prFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr prJSON
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse gh pr view output: %w", err)
+	}
+	return pr.toPR(), nil
+}
+
+// CreatePR opens a PR from the pushed branch head into base and returns it
+func (c *Client) CreatePR(repoPath, head, base, title, body string) (*PR, error) {
+	output, err := c.run(repoPath, "pr", "create", "--head", head, "--base", base, "--title", title, "--body", body)
+	if err != nil {
+		return nil, err
+	}
+
+	// gh prints the new PR's URL last
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return c.ViewPR(repoPath, strings.TrimSpace(lines[len(lines)-1]))
+}
+
+// MergePR merges PR number with method ("merge", "squash" or "rebase"),
+// deleting its branch afterwards if deleteBranch is set
+func (c *Client) MergePR(repoPath string, number int, method string, deleteBranch bool) error {
+	args := []string{"pr", "merge", fmt.Sprint(number), "--" + method}
+	if deleteBranch {
+		args = append(args, "--delete-branch")
+	}
+	_, err := c.run(repoPath, args...)
+	return err
+}
+
+// ReviewPR submits a review of PR number. action is "approve",
+// "request-changes" or "comment".
+func (c *Client) ReviewPR(repoPath string, number int, action, body string) error {
+	args := []string{"pr", "review", fmt.Sprint(number), "--" + action}
+	if body != "" {
+		args = append(args, "--body", body)
+	}
+	_, err := c.run(repoPath, args...)
+	return err
+}
+
+// DiffPR returns the diff of PR number
+func (c *Client) DiffPR(repoPath string, number int) (string, error) {
+	output, err := c.run(repoPath, "pr", "diff", fmt.Sprint(number))
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// SetCommitStatus sets the commit status context on ref, a branch or commit
+// SHA, in the GitHub repository the checkout at repoPath was cloned from.
+// state is "success", "failure", "error" or "pending".
+func (c *Client) SetCommitStatus(repoPath, ref, context, state, targetURL string) error {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.api(repoPath, "repos/{owner}/{repo}/commits/"+ref, &commit); err != nil {
+		return err
+	}
+
+	args := []string{"api", "--method", "POST", "repos/{owner}/{repo}/statuses/" + commit.SHA,
+		"-f", "state=" + state, "-f", "context=" + context}
+	if targetURL != "" {
+		args = append(args, "-f", "target_url="+targetURL)
+	}
+	_, err := c.run(repoPath, args...)
+	return err
+}
+
+// CommitChecks returns the combined status of the check runs and commit
+// statuses on ref, a branch or commit SHA, in the GitHub repository
+// ownerRepo ("owner/name")
+func (c *Client) CommitChecks(repoPath, ownerRepo, ref string) (*Checks, error) {
+	path := fmt.Sprintf("repos/%s/commits/%s", ownerRepo, ref)
+
+	var status struct {
+		SHA      string `json:"sha"`
+		Statuses []struct {
+			Context   string `json:"context"`
+			State     string `json:"state"`
+			TargetURL string `json:"target_url"`
+		} `json:"statuses"`
+	}
+	if err := c.api(repoPath, path+"/status", &status); err != nil {
+		return nil, err
+	}
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := c.api(repoPath, path+"/check-runs?per_page=100", &runs); err != nil {
+		return nil, err
+	}
+
+	var checks []checkJSON
+	for _, run := range runs.CheckRuns {
+		checks = append(checks, checkJSON{Name: run.Name, Status: run.Status, Conclusion: run.Conclusion, DetailsURL: run.HTMLURL})
+	}
+	for _, st := range status.Statuses {
+		checks = append(checks, checkJSON{Context: st.Context, State: st.State, TargetURL: st.TargetURL})
+	}
+
+	result := &Checks{SHA: status.SHA, URL: checksURL(checks)}
+	result.Status, result.FailedChecks = combineChecks(checks)
+	return result, nil
+}
+
+// api runs gh api on path and decodes its JSON output into v
+func (c *Client) api(dir, path string, v interface{}) error {
+	output, err := c.run(dir, "api", path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse gh api %s output: %w", path, err)
+	}
+	return nil
+}
+
+// run runs gh in dir and returns its stdout
+func (c *Client) run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command(c.bin, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("gh %s: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("gh %s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// prJSON is a PR in gh's JSON output
+type prJSON struct {
+	Number            int         `json:"number"`
+	URL               string      `json:"url"`
+	Title             string      `json:"title"`
+	State             string      `json:"state"`
+	HeadRefName       string      `json:"headRefName"`
+	BaseRefName       string      `json:"baseRefName"`
+	ReviewDecision    string      `json:"reviewDecision"`
+	StatusCheckRollup []checkJSON `json:"statusCheckRollup"`
+}
+
+// checkJSON is a check run or a commit status context in a PR's status
+// check rollup
+type checkJSON struct {
+	// Check runs
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	DetailsURL string `json:"detailsUrl"`
+	// Status contexts
+	Context   string `json:"context"`
+	State     string `json:"state"`
+	TargetURL string `json:"targetUrl"`
+}
+
+func (p prJSON) toPR() *PR {
+	pr := &PR{
+		Number:         p.Number,
+		URL:            p.URL,
+		Title:          p.Title,
+		Head:           p.HeadRefName,
+		Base:           p.BaseRefName,
+		State:          strings.ToUpper(p.State),
+		ReviewDecision: p.ReviewDecision,
+	}
+	pr.CIStatus, pr.FailedChecks = combineChecks(p.StatusCheckRollup)
+	return pr
+}
+
+// CheckResult is the outcome of a single check: CIPassing, CIFailing or
+// CIPending
+type CheckResult struct {
+	Name   string
+	Result string
+}
+
+// CombineChecks returns the CI status of a set of checks: failing if any
+// failed, pending if any are still running, passing otherwise. The names
+// of the failed checks are returned sorted.
+func CombineChecks(checks []CheckResult) (string, []string) {
+	if len(checks) == 0 {
+		return "", nil
+	}
+
+	var failed []string
+	pending := false
+	for _, check := range checks {
+		switch check.Result {
+		case CIFailing:
+			failed = append(failed, check.Name)
+		case CIPending:
+			pending = true
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		sort.Strings(failed)
+		return CIFailing, failed
+	case pending:
+		return CIPending, nil
+	default:
+		return CIPassing, nil
+	}
+}
+
+// combineChecks returns the CI status of gh's check runs and status contexts
+func combineChecks(checks []checkJSON) (string, []string) {
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		name := check.Name
+		if name == "" {
+			name = check.Context
+		}
+		results = append(results, CheckResult{Name: name, Result: checkResult(check)})
+	}
+	return CombineChecks(results)
+}
+
+// checksURL returns the URL of the first failed check, or of the first
+// check with a URL if none failed
+func checksURL(checks []checkJSON) string {
+	first := ""
+	for _, check := range checks {
+		url := check.DetailsURL
+		if url == "" {
+			url = check.TargetURL
+		}
+		if url == "" {
+			continue
+		}
+		if checkResult(check) == CIFailing {
+			return url
+		}
+		if first == "" {
+			first = url
+		}
+	}
+	return first
+}
+
+// checkResult returns whether a single check passed, failed or is pending.
+// Skipped and neutral check runs count as passed.
+func checkResult(check checkJSON) string {
+	if check.Context != "" || check.State != "" {
+		switch strings.ToUpper(check.State) {
+		case "SUCCESS":
+			return CIPassing
+		case "FAILURE", "ERROR":
+			return CIFailing
+		default:
+			return CIPending
+		}
+	}
+
+	if strings.ToUpper(check.Status) != "COMPLETED" {
+		return CIPending
+	}
+	switch strings.ToUpper(check.Conclusion) {
+	case "SUCCESS", "NEUTRAL", "SKIPPED":
+		return CIPassing
+	default:
+		return CIFailing
+	}
+}
@@ -0,0 +1,270 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFakeGh writes a gh script that prints output and records its
+// arguments in args.txt next to it
+func writeFakeGh(t *testing.T, output string, exitCode int) (bin, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "gh")
+	argsFile = filepath.Join(dir, "args.txt")
+	outFile := filepath.Join(dir, "output.json")
+	if err := os.WriteFile(outFile, []byte(output), 0644); err != nil {
+		t.Fatalf("Failed to write fake output: %v", err)
+	}
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\ncat %s\n", argsFile, outFile)
+	if exitCode != 0 {
+		script += fmt.Sprintf("echo 'gh: not logged in' >&2\nexit %d\n", exitCode)
+	}
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	return bin, argsFile
+}
+
+func TestPRForBranch(t *testing.T) {
+	bin, argsFile := writeFakeGh(t, `[{
+		"number": 42,
+		"url": "https://github.com/owner/repo/pull/42",
+		"title": "Add login",
+		"state": "OPEN",
+		"reviewDecision": "APPROVED",
+		"statusCheckRollup": [
+			{"__typename": "CheckRun", "name": "test", "status": "COMPLETED", "conclusion": "FAILURE"},
+			{"__typename": "CheckRun", "name": "lint", "status": "COMPLETED", "conclusion": "SUCCESS"},
+			{"__typename": "StatusContext", "context": "ci/build", "state": "PENDING"}
+		]
+	}]`, 0)
+
+	pr, err := NewClient(bin).PRForBranch(t.TempDir(), "multiclaude/calm-otter")
+	if err != nil {
+		t.Fatalf("PRForBranch() failed: %v", err)
+	}
+	want := &PR{
+		Number:         42,
+		URL:            "https://github.com/owner/repo/pull/42",
+		Title:          "Add login",
+		State:          StateOpen,
+		CIStatus:       CIFailing,
+		FailedChecks:   []string{"test"},
+		ReviewDecision: "APPROVED",
+	}
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("PRForBranch() = %+v, want %+v", pr, want)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "pr list --head multiclaude/calm-otter --state all") {
+		t.Errorf("gh was run with %q", args)
+	}
+}
+
+func TestPRForBranchNone(t *testing.T) {
+	bin, _ := writeFakeGh(t, "[]", 0)
+	pr, err := NewClient(bin).PRForBranch(t.TempDir(), "multiclaude/calm-otter")
+	if err != nil || pr != nil {
+		t.Errorf("PRForBranch() = %+v, %v, want no PR", pr, err)
+	}
+}
+
+func TestPRForBranchError(t *testing.T) {
+	bin, _ := writeFakeGh(t, "", 1)
+	_, err := NewClient(bin).PRForBranch(t.TempDir(), "multiclaude/calm-otter")
+	if err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("PRForBranch() error = %v, want gh's error", err)
+	}
+
+	if _, err := NewClient(filepath.Join(t.TempDir(), "missing")).PRForBranch(t.TempDir(), "main"); err == nil {
+		t.Error("PRForBranch() with a missing gh should fail")
+	}
+}
+
+func TestCombineChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []checkJSON
+		wantStatus string
+		wantFailed []string
+	}{
+		{"no checks", nil, "", nil},
+		{"all passed", []checkJSON{
+			{Name: "test", Status: "COMPLETED", Conclusion: "SUCCESS"},
+			{Name: "docs", Status: "COMPLETED", Conclusion: "SKIPPED"},
+			{Context: "ci/build", State: "SUCCESS"},
+		}, CIPassing, nil},
+		{"running", []checkJSON{
+			{Name: "test", Status: "IN_PROGRESS"},
+			{Name: "lint", Status: "COMPLETED", Conclusion: "SUCCESS"},
+		}, CIPending, nil},
+		{"failures win over pending", []checkJSON{
+			{Name: "test", Status: "QUEUED"},
+			{Name: "lint", Status: "COMPLETED", Conclusion: "TIMED_OUT"},
+			{Context: "ci/build", State: "ERROR"},
+		}, CIFailing, []string{"ci/build", "lint"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, failed := combineChecks(tt.checks)
+			if status != tt.wantStatus || !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("combineChecks() = %q, %v, want %q, %v", status, failed, tt.wantStatus, tt.wantFailed)
+			}
+		})
+	}
+}
+
+// writeFakeGhAPI writes a gh script whose "gh api <path>" prints
+// responses[path], failing for paths without a response
+func writeFakeGhAPI(t *testing.T, responses map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gh")
+	script := "#!/bin/sh\ncase \"$2\" in\n"
+	i := 0
+	for path, response := range responses {
+		i++
+		file := filepath.Join(dir, fmt.Sprintf("response%d.json", i))
+		if err := os.WriteFile(file, []byte(response), 0644); err != nil {
+			t.Fatalf("Failed to write fake response: %v", err)
+		}
+		script += fmt.Sprintf("  %q) cat %s ;;\n", path, file)
+	}
+	script += "  *) echo 'gh: Not Found (HTTP 404)' >&2; exit 1 ;;\nesac\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	return bin
+}
+
+func TestCommitChecks(t *testing.T) {
+	bin := writeFakeGhAPI(t, map[string]string{
+		"repos/owner/repo/commits/main/status": `{"sha": "abc123", "statuses": [
+			{"context": "ci/build", "state": "success", "target_url": "https://ci.example.com/build/1"}
+		]}`,
+		"repos/owner/repo/commits/main/check-runs?per_page=100": `{"check_runs": [
+			{"name": "lint", "status": "completed", "conclusion": "success", "html_url": "https://github.com/owner/repo/runs/1"},
+			{"name": "test", "status": "completed", "conclusion": "failure", "html_url": "https://github.com/owner/repo/runs/2"}
+		]}`,
+		"repos/owner/repo/commits/dev/status":                   `{"sha": "def456", "statuses": []}`,
+		"repos/owner/repo/commits/dev/check-runs?per_page=100":  `{"check_runs": []}`,
+		"repos/owner/repo/commits/next/status":                  `{"sha": "789abc", "statuses": []}`,
+		"repos/owner/repo/commits/next/check-runs?per_page=100": `{"check_runs": [{"name": "test", "status": "in_progress", "html_url": "https://github.com/owner/repo/runs/3"}]}`,
+	})
+	client := NewClient(bin)
+
+	checks, err := client.CommitChecks(t.TempDir(), "owner/repo", "main")
+	if err != nil {
+		t.Fatalf("CommitChecks() failed: %v", err)
+	}
+	want := &Checks{SHA: "abc123", Status: CIFailing, FailedChecks: []string{"test"}, URL: "https://github.com/owner/repo/runs/2"}
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("CommitChecks(main) = %+v, want %+v", checks, want)
+	}
+
+	checks, err = client.CommitChecks(t.TempDir(), "owner/repo", "dev")
+	if err != nil || checks.SHA != "def456" || checks.Status != "" || checks.URL != "" {
+		t.Errorf("CommitChecks(dev) = %+v, %v, want no checks", checks, err)
+	}
+
+	checks, err = client.CommitChecks(t.TempDir(), "owner/repo", "next")
+	if err != nil || checks.Status != CIPending || checks.URL != "https://github.com/owner/repo/runs/3" {
+		t.Errorf("CommitChecks(next) = %+v, %v, want pending", checks, err)
+	}
+
+	if _, err := client.CommitChecks(t.TempDir(), "owner/repo", "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("CommitChecks(missing) error = %v, want gh's error", err)
+	}
+}
+
+func TestListPRs(t *testing.T) {
+	bin, argsFile := writeFakeGh(t, `[
+		{"number": 43, "url": "https://github.com/owner/repo/pull/43", "title": "Add logout", "state": "OPEN", "headRefName": "work/tidy-heron", "baseRefName": "main"},
+		{"number": 42, "url": "https://github.com/owner/repo/pull/42", "title": "Add login", "state": "MERGED", "headRefName": "work/calm-otter", "baseRefName": "main"}
+	]`, 0)
+
+	prs, err := NewClient(bin).ListPRs(t.TempDir(), "all", 20)
+	if err != nil {
+		t.Fatalf("ListPRs() failed: %v", err)
+	}
+	if len(prs) != 2 || prs[0].Number != 43 || prs[0].Head != "work/tidy-heron" || prs[0].Base != "main" || prs[1].State != StateMerged {
+		t.Errorf("ListPRs() = %+v, want PRs 43 and 42", prs)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "pr list --state all") || !strings.Contains(string(args), "--limit 20") {
+		t.Errorf("gh was run with %q", args)
+	}
+}
+
+func TestCreateAndMergePR(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gh")
+	argsFile := filepath.Join(dir, "args.txt")
+	script := "#!/bin/sh\necho \"$@\" >> " + argsFile + "\ncase \"$2\" in\n" +
+		"  create) echo 'Creating pull request for work/calm-otter into main'; echo 'https://github.com/owner/repo/pull/42' ;;\n" +
+		"  view) echo '{\"number\": 42, \"url\": \"https://github.com/owner/repo/pull/42\", \"title\": \"Add login\", \"state\": \"OPEN\", \"headRefName\": \"work/calm-otter\", \"baseRefName\": \"main\"}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	client := NewClient(bin)
+
+	pr, err := client.CreatePR(t.TempDir(), "work/calm-otter", "main", "Add login", "")
+	if err != nil {
+		t.Fatalf("CreatePR() failed: %v", err)
+	}
+	if pr.Number != 42 || pr.State != StateOpen || pr.Head != "work/calm-otter" {
+		t.Errorf("CreatePR() = %+v, want open PR #42", pr)
+	}
+
+	if err := client.MergePR(t.TempDir(), 42, "squash", true); err != nil {
+		t.Fatalf("MergePR() failed: %v", err)
+	}
+	args, _ := os.ReadFile(argsFile)
+	want := "pr create --head work/calm-otter --base main --title Add login --body \n" +
+		"pr view https://github.com/owner/repo/pull/42 --json " + prFields + "\n" +
+		"pr merge 42 --squash --delete-branch\n"
+	if string(args) != want {
+		t.Errorf("gh was run with:\n%s\nwant:\n%s", args, want)
+	}
+}
+
+func TestReviewDiffAndSetCommitStatus(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gh")
+	argsFile := filepath.Join(dir, "args.txt")
+	script := "#!/bin/sh\necho \"$@\" >> " + argsFile + "\ncase \"$1 $2\" in\n" +
+		"  'pr diff') echo '+++ b/login.go' ;;\n" +
+		"  'api repos/{owner}/{repo}/commits/work/calm-otter') echo '{\"sha\": \"abc123\"}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake gh: %v", err)
+	}
+	client := NewClient(bin)
+
+	if err := client.ReviewPR(t.TempDir(), 42, "request-changes", "Needs tests"); err != nil {
+		t.Fatalf("ReviewPR() failed: %v", err)
+	}
+	if diff, err := client.DiffPR(t.TempDir(), 42); err != nil || diff != "+++ b/login.go\n" {
+		t.Errorf("DiffPR() = %q, %v, want the diff", diff, err)
+	}
+	if err := client.SetCommitStatus(t.TempDir(), "work/calm-otter", "ci/test", "failure", ""); err != nil {
+		t.Fatalf("SetCommitStatus() failed: %v", err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	want := "pr review 42 --request-changes --body Needs tests\n" +
+		"pr diff 42\n" +
+		"api repos/{owner}/{repo}/commits/work/calm-otter\n" +
+		"api --method POST repos/{owner}/{repo}/statuses/abc123 -f state=failure -f context=ci/test\n"
+	if string(args) != want {
+		t.Errorf("gh was run with:\n%s\nwant:\n%s", args, want)
+	}
+}
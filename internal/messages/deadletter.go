@@ -0,0 +1,161 @@
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxDeliveryAttempts is how many failed deliveries a message gets
+	// before it is moved to the dead-letter folder
+	MaxDeliveryAttempts = 5
+
+	// retryBaseDelay and retryMaxDelay bound the backoff between attempts
+	retryBaseDelay = 15 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+
+	// deadLetterDirName holds undeliverable messages, per repository. The dot
+	// keeps it from being mistaken for a repository's message directory.
+	deadLetterDirName = ".dead-letters"
+)
+
+// RetryBackoff returns how long to wait before the next delivery attempt
+// after the given number of failed attempts
+func RetryBackoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// RecordDeliveryFailure counts a failed delivery attempt and schedules the
+// next one. Once MaxDeliveryAttempts is reached the message is dead-lettered,
+// and dead is true.
+func (m *Manager) RecordDeliveryFailure(repoName, agentName, messageID string, deliveryErr error) (dead bool, err error) {
+	msg, err := m.Get(repoName, agentName, messageID)
+	if err != nil {
+		return false, err
+	}
+
+	msg.Attempts++
+	msg.LastError = deliveryErr.Error()
+	if msg.Attempts >= MaxDeliveryAttempts {
+		reason := fmt.Sprintf("delivery failed after %d attempts: %v", msg.Attempts, deliveryErr)
+		return true, m.DeadLetter(repoName, agentName, msg, reason)
+	}
+
+	next := time.Now().Add(RetryBackoff(msg.Attempts))
+	msg.NextAttemptAt = &next
+	return false, m.write(repoName, agentName, msg)
+}
+
+// DeadLetter moves a message out of an agent's inbox into the repository's
+// dead-letter folder, recording why it could not be delivered
+func (m *Manager) DeadLetter(repoName, agentName string, msg *Message, reason string) error {
+	now := time.Now()
+	msg.DeadReason = reason
+	msg.DeadAt = &now
+
+	dir := m.deadLetterDir(repoName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, msg.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+
+	return m.Delete(repoName, agentName, msg.ID)
+}
+
+// ListDeadLetters returns a repository's dead letters, oldest first
+func (m *Manager) ListDeadLetters(repoName string) ([]*Message, error) {
+	dir := m.deadLetterDir(repoName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Message{}, nil
+		}
+		return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+	}
+
+	var dead []*Message
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		msg, err := readMessageFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			// Skip invalid messages
+			continue
+		}
+		dead = append(dead, msg)
+	}
+
+	sort.SliceStable(dead, func(i, j int) bool {
+		return dead[i].DeadAt != nil && dead[j].DeadAt != nil && dead[i].DeadAt.Before(*dead[j].DeadAt)
+	})
+	return dead, nil
+}
+
+// GetDeadLetter retrieves a dead letter by message ID
+func (m *Manager) GetDeadLetter(repoName, messageID string) (*Message, error) {
+	return readMessageFile(filepath.Join(m.deadLetterDir(repoName), messageID+".json"))
+}
+
+// ResendDeadLetter puts a dead letter back into an inbox as a fresh pending
+// message, to its original recipient or to a new one if to is set. A message
+// that had a TTL gets the same TTL again from now.
+func (m *Manager) ResendDeadLetter(repoName, messageID, to string) (*Message, error) {
+	msg, err := m.GetDeadLetter(repoName, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if to != "" {
+		msg.To = to
+	}
+	if msg.ExpiresAt != nil {
+		msg.ExpiresAt = expiresAt(time.Now(), msg.ExpiresAt.Sub(msg.Timestamp))
+	}
+	msg.Status = StatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = nil
+	msg.LastError = ""
+	msg.DeadReason = ""
+	msg.DeadAt = nil
+
+	if err := m.write(repoName, msg.To, msg); err != nil {
+		return nil, err
+	}
+	if err := m.DeleteDeadLetter(repoName, messageID); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// DeleteDeadLetter permanently removes a dead letter
+func (m *Manager) DeleteDeadLetter(repoName, messageID string) error {
+	path := filepath.Join(m.deadLetterDir(repoName), messageID+".json")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// deadLetterDir returns the dead-letter directory for a repository
+func (m *Manager) deadLetterDir(repoName string) string {
+	return filepath.Join(m.messagesRoot, deadLetterDirName, repoName)
+}
@@ -0,0 +1,206 @@
+package messages
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 15 * time.Second},
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{10, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := RetryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("RetryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRecordDeliveryFailure(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	msg, err := m.Send("repo", "supervisor", "worker1", "hello")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	deliveryErr := errors.New("can't find window")
+	for i := 1; i < MaxDeliveryAttempts; i++ {
+		dead, err := m.RecordDeliveryFailure("repo", "worker1", msg.ID, deliveryErr)
+		if err != nil {
+			t.Fatalf("RecordDeliveryFailure() failed: %v", err)
+		}
+		if dead {
+			t.Fatalf("message dead-lettered after %d attempts, want %d", i, MaxDeliveryAttempts)
+		}
+
+		got, err := m.Get("repo", "worker1", msg.ID)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if got.Attempts != i || got.LastError != deliveryErr.Error() {
+			t.Errorf("after %d failures: Attempts = %d, LastError = %q", i, got.Attempts, got.LastError)
+		}
+		if got.DeliveryDue(time.Now()) {
+			t.Error("message should be backing off after a failed delivery")
+		}
+		if !got.DeliveryDue(time.Now().Add(RetryBackoff(i) + time.Second)) {
+			t.Error("message should be due once the backoff has passed")
+		}
+	}
+
+	dead, err := m.RecordDeliveryFailure("repo", "worker1", msg.ID, deliveryErr)
+	if err != nil {
+		t.Fatalf("RecordDeliveryFailure() failed: %v", err)
+	}
+	if !dead {
+		t.Fatalf("message should be dead-lettered after %d attempts", MaxDeliveryAttempts)
+	}
+
+	if _, err := m.Get("repo", "worker1", msg.ID); err == nil {
+		t.Error("dead-lettered message should be removed from the inbox")
+	}
+
+	letter, err := m.GetDeadLetter("repo", msg.ID)
+	if err != nil {
+		t.Fatalf("GetDeadLetter() failed: %v", err)
+	}
+	if letter.Attempts != MaxDeliveryAttempts || letter.DeadAt == nil || letter.DeadReason == "" {
+		t.Errorf("dead letter = %+v", letter)
+	}
+}
+
+func TestMessageExpiry(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	msg, err := m.SendWithOptions("repo", "supervisor", "worker1", "hello", SendOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("SendWithOptions() failed: %v", err)
+	}
+	if msg.ExpiresAt == nil || !msg.ExpiresAt.Equal(msg.Timestamp.Add(time.Hour)) {
+		t.Fatalf("ExpiresAt = %v, want an hour after %v", msg.ExpiresAt, msg.Timestamp)
+	}
+	if msg.Expired(time.Now()) {
+		t.Error("message should not be expired yet")
+	}
+	if !msg.Expired(time.Now().Add(2 * time.Hour)) {
+		t.Error("message should be expired after its TTL")
+	}
+
+	forever, err := m.Send("repo", "supervisor", "worker1", "no ttl")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if forever.ExpiresAt != nil || forever.Expired(time.Now().Add(365*24*time.Hour)) {
+		t.Error("message without a TTL should never expire")
+	}
+}
+
+func TestResendDeadLetter(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	msg, err := m.SendWithOptions("repo", "supervisor", "worker1", "hello", SendOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("SendWithOptions() failed: %v", err)
+	}
+	msg.Attempts = 3
+	msg.LastError = "boom"
+	if err := m.DeadLetter("repo", "worker1", msg, "test"); err != nil {
+		t.Fatalf("DeadLetter() failed: %v", err)
+	}
+
+	dead, err := m.ListDeadLetters("repo")
+	if err != nil {
+		t.Fatalf("ListDeadLetters() failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != msg.ID || dead[0].DeadReason != "test" {
+		t.Fatalf("ListDeadLetters() = %+v", dead)
+	}
+
+	resent, err := m.ResendDeadLetter("repo", msg.ID, "worker2")
+	if err != nil {
+		t.Fatalf("ResendDeadLetter() failed: %v", err)
+	}
+	if resent.To != "worker2" || resent.Status != StatusPending || resent.Attempts != 0 || resent.DeadAt != nil {
+		t.Errorf("resent message = %+v", resent)
+	}
+	// The original TTL starts over
+	if resent.ExpiresAt == nil || resent.ExpiresAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("resent ExpiresAt = %v, want about an hour from now", resent.ExpiresAt)
+	}
+
+	if _, err := m.Get("repo", "worker2", msg.ID); err != nil {
+		t.Errorf("resent message should be in the new recipient's inbox: %v", err)
+	}
+	if dead, _ := m.ListDeadLetters("repo"); len(dead) != 0 {
+		t.Errorf("dead letter should be removed after resend, got %d", len(dead))
+	}
+
+	if _, err := m.ResendDeadLetter("repo", "msg-missing", ""); err == nil {
+		t.Error("ResendDeadLetter() should fail for an unknown message")
+	}
+}
+
+func TestListDeadLettersEmpty(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	dead, err := m.ListDeadLetters("repo")
+	if err != nil {
+		t.Fatalf("ListDeadLetters() failed: %v", err)
+	}
+	if len(dead) != 0 {
+		t.Errorf("ListDeadLetters() = %d messages, want 0", len(dead))
+	}
+}
+
+func TestCleanupOrphanedDeadLetters(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir)
+
+	pending, err := m.Send("repo", "supervisor", "gone", "never read")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	delivered, err := m.Send("repo", "supervisor", "gone", "already seen")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if err := m.UpdateStatus("repo", "gone", delivered.ID, StatusDelivered); err != nil {
+		t.Fatalf("UpdateStatus() failed: %v", err)
+	}
+
+	count, err := m.CleanupOrphaned("repo", []string{"supervisor"})
+	if err != nil {
+		t.Fatalf("CleanupOrphaned() failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("CleanupOrphaned() = %d, want 1", count)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "repo", "gone")); !os.IsNotExist(err) {
+		t.Error("orphaned agent directory should be removed")
+	}
+
+	dead, err := m.ListDeadLetters("repo")
+	if err != nil {
+		t.Fatalf("ListDeadLetters() failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != pending.ID {
+		t.Fatalf("only the undelivered message should be dead-lettered, got %+v", dead)
+	}
+	if dead[0].DeadReason != "recipient gone no longer exists" {
+		t.Errorf("DeadReason = %q", dead[0].DeadReason)
+	}
+}
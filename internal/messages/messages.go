@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +21,39 @@ const (
 	StatusAcked     Status = "acked"
 )
 
+// Priority controls the order in which pending messages are delivered
+type Priority string
+
+const (
+	PriorityUrgent Priority = "urgent"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// ParsePriority parses a priority name. An empty string is normal priority.
+func ParsePriority(s string) (Priority, error) {
+	switch Priority(s) {
+	case PriorityUrgent, PriorityNormal, PriorityLow:
+		return Priority(s), nil
+	case "":
+		return PriorityNormal, nil
+	}
+	return "", fmt.Errorf("invalid priority: %q (must be 'urgent', 'normal', or 'low')", s)
+}
+
+// rank orders priorities for delivery; lower ranks are delivered first.
+// Messages written before priorities existed have none and count as normal.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityUrgent:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
 // Message represents a message between agents
 type Message struct {
 	ID        string     `json:"id"`
@@ -29,6 +63,57 @@ type Message struct {
 	Body      string     `json:"body"`
 	Status    Status     `json:"status"`
 	AckedAt   *time.Time `json:"acked_at,omitempty"`
+
+	// ThreadID groups a conversation; it is the ID of the message that started it
+	ThreadID string `json:"thread_id,omitempty"`
+	// ReplyTo is the ID of the message this one answers
+	ReplyTo  string   `json:"reply_to,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	Subject  string   `json:"subject,omitempty"`
+
+	// BroadcastID is shared by every copy of a message sent to a group
+	BroadcastID string `json:"broadcast_id,omitempty"`
+
+	// ExpiresAt is when an undelivered message is given up on and dead-lettered
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Attempts counts failed deliveries; NextAttemptAt holds back the next one
+	Attempts      int        `json:"attempts,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+
+	// Set on dead letters: why and when the message was given up on
+	DeadReason string     `json:"dead_reason,omitempty"`
+	DeadAt     *time.Time `json:"dead_at,omitempty"`
+}
+
+// Thread returns the message's thread ID. Messages that predate threading
+// are each their own thread.
+func (msg *Message) Thread() string {
+	if msg.ThreadID != "" {
+		return msg.ThreadID
+	}
+	return msg.ID
+}
+
+// Expired returns true if the message has a TTL that has passed
+func (msg *Message) Expired(now time.Time) bool {
+	return msg.ExpiresAt != nil && now.After(*msg.ExpiresAt)
+}
+
+// DeliveryDue returns true if the message isn't waiting out a retry backoff
+func (msg *Message) DeliveryDue(now time.Time) bool {
+	return msg.NextAttemptAt == nil || !now.Before(*msg.NextAttemptAt)
+}
+
+// SendOptions are optional message attributes for SendWithOptions
+type SendOptions struct {
+	// ReplyTo is the ID of a message in the sender's inbox being answered.
+	// The reply joins that message's thread and inherits its subject.
+	ReplyTo  string
+	Priority Priority
+	Subject  string
+	// TTL limits how long the message may wait for delivery; zero means forever
+	TTL time.Duration
 }
 
 // Manager handles message filesystem operations
@@ -41,15 +126,53 @@ func NewManager(messagesRoot string) *Manager {
 	return &Manager{messagesRoot: messagesRoot}
 }
 
+// expiresAt returns the expiry for a message sent at now with a TTL, or nil if
+// the TTL is zero
+func expiresAt(now time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	t := now.Add(ttl)
+	return &t
+}
+
 // Send creates a new message file
 func (m *Manager) Send(repoName, from, to, body string) (*Message, error) {
+	return m.SendWithOptions(repoName, from, to, body, SendOptions{})
+}
+
+// SendWithOptions creates a new message file with a priority, subject,
+// reply-to, or TTL. A message that isn't a reply starts a new thread.
+func (m *Manager) SendWithOptions(repoName, from, to, body string, opts SendOptions) (*Message, error) {
+	priority, err := ParsePriority(string(opts.Priority))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	msg := &Message{
 		ID:        fmt.Sprintf("msg-%s", uuid.New().String()[:13]),
 		From:      from,
 		To:        to,
-		Timestamp: time.Now(),
+		Timestamp: now,
 		Body:      body,
 		Status:    StatusPending,
+		Priority:  priority,
+		Subject:   opts.Subject,
+		ExpiresAt: expiresAt(now, opts.TTL),
+	}
+	msg.ThreadID = msg.ID
+
+	if opts.ReplyTo != "" {
+		parent, err := m.Get(repoName, from, opts.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("reply-to message %s not found in %s's messages: %w", opts.ReplyTo, from, err)
+		}
+		msg.ReplyTo = parent.ID
+		msg.ThreadID = parent.Thread()
+		if msg.Subject == "" {
+			msg.Subject = parent.Subject
+		}
 	}
 
 	if err := m.write(repoName, to, msg); err != nil {
@@ -59,6 +182,45 @@ func (m *Manager) Send(repoName, from, to, body string) (*Message, error) {
 	return msg, nil
 }
 
+// Broadcast sends a copy of a message to each recipient. The copies share a
+// broadcast ID, and each starts its own thread so replies stay one-to-one.
+func (m *Manager) Broadcast(repoName, from string, to []string, body string, opts SendOptions) ([]*Message, error) {
+	if opts.ReplyTo != "" {
+		return nil, fmt.Errorf("broadcast messages cannot be replies")
+	}
+	priority, err := ParsePriority(string(opts.Priority))
+	if err != nil {
+		return nil, err
+	}
+
+	broadcastID := fmt.Sprintf("bcast-%s", uuid.New().String()[:13])
+	now := time.Now()
+
+	sent := make([]*Message, 0, len(to))
+	for _, recipient := range to {
+		msg := &Message{
+			ID:          fmt.Sprintf("msg-%s", uuid.New().String()[:13]),
+			From:        from,
+			To:          recipient,
+			Timestamp:   now,
+			Body:        body,
+			Status:      StatusPending,
+			Priority:    priority,
+			Subject:     opts.Subject,
+			BroadcastID: broadcastID,
+			ExpiresAt:   expiresAt(now, opts.TTL),
+		}
+		msg.ThreadID = msg.ID
+
+		if err := m.write(repoName, recipient, msg); err != nil {
+			return sent, fmt.Errorf("failed to send to %s: %w", recipient, err)
+		}
+		sent = append(sent, msg)
+	}
+
+	return sent, nil
+}
+
 // List returns all messages for an agent
 func (m *Manager) List(repoName, agentName string) ([]*Message, error) {
 	dir := m.agentDir(repoName, agentName)
@@ -161,6 +323,41 @@ func (m *Manager) ListUnread(repoName, agentName string) ([]*Message, error) {
 	return unread, nil
 }
 
+// ListRepo returns the messages of every agent in a repository
+func (m *Manager) ListRepo(repoName string) ([]*Message, error) {
+	entries, err := os.ReadDir(filepath.Join(m.messagesRoot, repoName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Message{}, nil
+		}
+		return nil, fmt.Errorf("failed to read messages directory: %w", err)
+	}
+
+	var all []*Message
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		msgs, err := m.List(repoName, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, msgs...)
+	}
+
+	return all, nil
+}
+
+// SortForDelivery orders messages urgent first, then oldest first
+func SortForDelivery(msgs []*Message) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		if ri, rj := msgs[i].Priority.rank(), msgs[j].Priority.rank(); ri != rj {
+			return ri < rj
+		}
+		return msgs[i].Timestamp.Before(msgs[j].Timestamp)
+	})
+}
+
 // agentDir returns the directory path for an agent's messages
 func (m *Manager) agentDir(repoName, agentName string) string {
 	return filepath.Join(m.messagesRoot, repoName, agentName)
@@ -193,7 +390,11 @@ func (m *Manager) write(repoName, agentName string, msg *Message) error {
 
 // read reads a message from disk
 func (m *Manager) read(repoName, agentName, filename string) (*Message, error) {
-	path := filepath.Join(m.agentDir(repoName, agentName), filename)
+	return readMessageFile(filepath.Join(m.agentDir(repoName, agentName), filename))
+}
+
+// readMessageFile reads a message file at path
+func readMessageFile(path string) (*Message, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read message file: %w", err)
@@ -207,7 +408,8 @@ func (m *Manager) read(repoName, agentName, filename string) (*Message, error) {
 	return &msg, nil
 }
 
-// CleanupOrphaned removes message directories for non-existent agents
+// CleanupOrphaned removes message directories for non-existent agents.
+// Messages that were never delivered are dead-lettered rather than discarded.
 func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, error) {
 	repoDir := filepath.Join(m.messagesRoot, repoName)
 
@@ -232,6 +434,20 @@ func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, e
 
 		if !validAgentMap[entry.Name()] {
 			// This is an orphaned agent directory
+			msgs, err := m.List(repoName, entry.Name())
+			if err != nil {
+				continue
+			}
+			reason := fmt.Sprintf("recipient %s no longer exists", entry.Name())
+			for _, msg := range msgs {
+				if msg.Status != StatusPending {
+					continue
+				}
+				if err := m.DeadLetter(repoName, entry.Name(), msg, reason); err != nil {
+					return count, err
+				}
+			}
+
 			path := filepath.Join(repoDir, entry.Name())
 			if err := os.RemoveAll(path); err == nil {
 				count++
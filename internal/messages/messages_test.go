@@ -546,3 +546,136 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestSendWithOptionsThreading(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	question, err := m.SendWithOptions("test-repo", "supervisor", "worker1", "Which approach?", SendOptions{Subject: "Auth refactor"})
+	if err != nil {
+		t.Fatalf("SendWithOptions() failed: %v", err)
+	}
+	if question.ThreadID != question.ID {
+		t.Errorf("new message ThreadID = %q, want its own ID %q", question.ThreadID, question.ID)
+	}
+	if question.Priority != PriorityNormal {
+		t.Errorf("default Priority = %q, want normal", question.Priority)
+	}
+
+	// worker1 answers the message in its inbox
+	reply, err := m.SendWithOptions("test-repo", "worker1", "supervisor", "The second one", SendOptions{ReplyTo: question.ID})
+	if err != nil {
+		t.Fatalf("SendWithOptions() reply failed: %v", err)
+	}
+	if reply.ReplyTo != question.ID || reply.ThreadID != question.ID {
+		t.Errorf("reply ReplyTo = %q, ThreadID = %q, want both %q", reply.ReplyTo, reply.ThreadID, question.ID)
+	}
+	if reply.Subject != "Auth refactor" {
+		t.Errorf("reply Subject = %q, want inherited subject", reply.Subject)
+	}
+
+	// Replies to replies stay in the original thread
+	followUp, err := m.SendWithOptions("test-repo", "supervisor", "worker1", "Go ahead", SendOptions{ReplyTo: reply.ID, Subject: "Approved"})
+	if err != nil {
+		t.Fatalf("SendWithOptions() follow-up failed: %v", err)
+	}
+	if followUp.ThreadID != question.ID || followUp.Subject != "Approved" {
+		t.Errorf("follow-up ThreadID = %q, Subject = %q", followUp.ThreadID, followUp.Subject)
+	}
+
+	// Fields survive a round trip through disk
+	got, err := m.Get("test-repo", "worker1", followUp.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.ThreadID != followUp.ThreadID || got.ReplyTo != reply.ID || got.Priority != PriorityNormal {
+		t.Errorf("loaded message = %+v", got)
+	}
+
+	all, err := m.ListRepo("test-repo")
+	if err != nil {
+		t.Fatalf("ListRepo() failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("ListRepo() returned %d messages, want 3", len(all))
+	}
+}
+
+func TestSendWithOptionsErrors(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	if _, err := m.SendWithOptions("test-repo", "supervisor", "worker1", "Hi", SendOptions{Priority: "critical"}); err == nil {
+		t.Error("SendWithOptions() should reject an unknown priority")
+	}
+
+	if _, err := m.SendWithOptions("test-repo", "worker1", "supervisor", "Hi", SendOptions{ReplyTo: "msg-missing"}); err == nil {
+		t.Error("SendWithOptions() should reject a reply to a missing message")
+	}
+}
+
+func TestSortForDelivery(t *testing.T) {
+	base := time.Now()
+	msgs := []*Message{
+		{ID: "low", Priority: PriorityLow, Timestamp: base},
+		{ID: "legacy", Timestamp: base.Add(time.Second)},
+		{ID: "normal-old", Priority: PriorityNormal, Timestamp: base},
+		{ID: "urgent-new", Priority: PriorityUrgent, Timestamp: base.Add(2 * time.Second)},
+		{ID: "urgent-old", Priority: PriorityUrgent, Timestamp: base.Add(time.Second)},
+	}
+
+	SortForDelivery(msgs)
+
+	want := []string{"urgent-old", "urgent-new", "normal-old", "legacy", "low"}
+	for i, msg := range msgs {
+		if msg.ID != want[i] {
+			t.Errorf("msgs[%d] = %s, want %s", i, msg.ID, want[i])
+		}
+	}
+}
+
+func TestMessageThreadLegacy(t *testing.T) {
+	msg := &Message{ID: "msg-old"}
+	if msg.Thread() != "msg-old" {
+		t.Errorf("Thread() = %q, want message ID for messages without a thread", msg.Thread())
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	m := NewManager(t.TempDir())
+
+	sent, err := m.Broadcast("test-repo", "supervisor", []string{"worker1", "worker2"}, "main is broken, stop pushing", SendOptions{Priority: PriorityUrgent})
+	if err != nil {
+		t.Fatalf("Broadcast() failed: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("Broadcast() sent %d messages, want 2", len(sent))
+	}
+	if sent[0].BroadcastID == "" || sent[0].BroadcastID != sent[1].BroadcastID {
+		t.Errorf("broadcast IDs = %q, %q, want a shared ID", sent[0].BroadcastID, sent[1].BroadcastID)
+	}
+	if sent[0].ID == sent[1].ID || sent[0].ThreadID == sent[1].ThreadID {
+		t.Error("each recipient should get its own message and thread")
+	}
+
+	for i, recipient := range []string{"worker1", "worker2"} {
+		got, err := m.Get("test-repo", recipient, sent[i].ID)
+		if err != nil {
+			t.Fatalf("Get() for %s failed: %v", recipient, err)
+		}
+		if got.To != recipient || got.Priority != PriorityUrgent || got.BroadcastID != sent[0].BroadcastID {
+			t.Errorf("%s message = %+v", recipient, got)
+		}
+	}
+
+	// A recipient can reply to its copy like any other message
+	reply, err := m.SendWithOptions("test-repo", "worker2", "supervisor", "Stopped", SendOptions{ReplyTo: sent[1].ID})
+	if err != nil {
+		t.Fatalf("reply failed: %v", err)
+	}
+	if reply.ThreadID != sent[1].ID || reply.BroadcastID != "" {
+		t.Errorf("reply ThreadID = %q, BroadcastID = %q", reply.ThreadID, reply.BroadcastID)
+	}
+
+	if _, err := m.Broadcast("test-repo", "supervisor", []string{"worker1"}, "Hi", SendOptions{ReplyTo: sent[0].ID}); err == nil {
+		t.Error("Broadcast() should reject reply-to")
+	}
+}
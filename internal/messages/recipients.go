@@ -0,0 +1,20 @@
+package messages
+
+import "strings"
+
+// Group recipient expressions for broadcast messages. The daemon resolves
+// them against a repository's agents; Broadcast takes the resulting names.
+const (
+	// RecipientAll addresses every agent except the workspace
+	RecipientAll = "@all"
+	// RecipientWorkers addresses every worker
+	RecipientWorkers = "@workers"
+	// RecipientPersistent addresses the supervisor, merge-queue and other persistent agents
+	RecipientPersistent = "@persistent"
+)
+
+// IsGroupRecipient returns true if a recipient is a group name or a glob
+// that has to be resolved into agent names
+func IsGroupRecipient(to string) bool {
+	return strings.HasPrefix(to, "@") || strings.ContainsAny(to, "*?[")
+}
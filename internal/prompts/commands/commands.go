@@ -25,7 +25,7 @@ type CommandInfo struct {
 
 // AvailableCommands lists all available slash commands
 var AvailableCommands = []CommandInfo{
-	{Name: "refresh", Filename: "refresh.md", Description: "Sync worktree with main branch"},
+	{Name: "refresh", Filename: "refresh.md", Description: "Sync worktree with your base branch"},
 	{Name: "status", Filename: "status.md", Description: "Show system status"},
 	{Name: "workers", Filename: "workers.md", Description: "List active workers"},
 	{Name: "messages", Filename: "messages.md", Description: "Check inter-agent messages"},
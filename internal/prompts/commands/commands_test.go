@@ -14,7 +14,7 @@ func TestGetCommand(t *testing.T) {
 	}{
 		{
 			name:    "refresh",
-			want:    "Sync worktree with main branch",
+			want:    "Sync worktree with your base branch",
 			wantErr: false,
 		},
 		{
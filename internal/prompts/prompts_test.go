@@ -333,8 +333,9 @@ func TestGetSlashCommandsPromptContainsCLICommands(t *testing.T) {
 		command     string
 		description string
 	}{
-		{"git fetch origin main", "/refresh should include fetch from origin/main"},
-		{"git rebase origin/main", "/refresh should include rebase onto origin/main"},
+		{"multiclaudeBase", "/refresh should look up the recorded base branch"},
+		{"git fetch origin <base>", "/refresh should include fetch of the base branch from origin"},
+		{"git rebase origin/<base>", "/refresh should include rebase onto the base branch on origin"},
 	}
 
 	// Commands expected in /workers
@@ -0,0 +1,97 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dlorenc/multiclaude/internal/agents"
+	"github.com/dlorenc/multiclaude/internal/templates"
+)
+
+// GetWorkerDefinition returns the worker prompt from a repository's agent
+// definitions. If the local agents directory doesn't exist yet, the default
+// templates are copied there first.
+func GetWorkerDefinition(localAgentsDir, repoPath string) (string, error) {
+	reader := agents.NewReader(localAgentsDir, repoPath)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		definitions, err := reader.ReadAllDefinitions()
+		if err != nil {
+			return "", fmt.Errorf("failed to read agent definitions: %w", err)
+		}
+		for _, def := range definitions {
+			if def.Name == "worker" {
+				return def.Content, nil
+			}
+		}
+
+		// Copy templates to local agents dir if it doesn't exist, then retry
+		if _, err := os.Stat(localAgentsDir); !os.IsNotExist(err) {
+			break
+		}
+		if err := templates.CopyAgentTemplates(localAgentsDir); err != nil {
+			return "", fmt.Errorf("failed to copy agent templates: %w", err)
+		}
+	}
+
+	return "", fmt.Errorf("no worker agent definition found")
+}
+
+// GeneratePushToPrompt returns the instructions for a worker that iterates
+// on an existing PR branch instead of opening a new PR
+func GeneratePushToPrompt(branch string) string {
+	return fmt.Sprintf(`## PR Iteration Mode
+
+**IMPORTANT: You are iterating on an existing PR, not creating a new one.**
+
+Instead of creating a new PR, push your changes to the existing branch: %s
+
+When your work is ready:
+1. Commit your changes
+2. Push to origin: git push origin %s
+3. Signal completion with: multiclaude agent complete
+
+Do NOT create a new PR. The existing PR will be updated automatically when you push.
+
+---
+
+`, branch, branch)
+}
+
+// GenerateBaseBranchPrompt returns the instructions for a worker whose work
+// is based on branch: where it started and where its PR goes
+func GenerateBaseBranchPrompt(branch string) string {
+	return fmt.Sprintf(`## Base Branch
+
+Your worktree was created from the %[1]s branch. Open your PR against it:
+
+    gh pr create --base %[1]s
+
+To pick up newer changes, rebase onto origin/%[1]s, not main.
+
+---
+
+`, branch)
+}
+
+// GenerateLocalForgePrompt returns the instructions for agents of a repo
+// whose PRs are on the local forge, where gh doesn't work
+func GenerateLocalForgePrompt() string {
+	return `## Local Forge
+
+This repository's PRs live on a local forge, not GitHub, so gh pr commands don't work here. Use multiclaude's instead:
+
+    multiclaude pr create --title "<title>" --body "<description>" [--base <branch>]
+    multiclaude pr list [--state open|merged|closed|all]
+    multiclaude pr view <number>
+    multiclaude pr diff <number>
+    multiclaude pr review <number> --approve|--request-changes|--comment [--body "<text>"]
+    multiclaude pr check <number|branch> --name <check> --state success|failure|error|pending [--url <url>]
+    multiclaude pr merge <number> [--squash] [--delete-branch]
+
+Push your branch before creating its PR. Wherever these instructions use gh pr create, list, view, checks, diff, review, comment or merge, use the matching command above: pr view shows a PR's checks and reviews, and comments are reviews with --comment. Labels aren't supported; leave a comment instead.
+
+---
+
+`
+}
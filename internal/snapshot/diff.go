@@ -0,0 +1,179 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// Change kinds reported by Diff
+const (
+	RepoAdded    = "repo_added"
+	RepoRemoved  = "repo_removed"
+	RepoUpdated  = "repo_updated"
+	AgentAdded   = "agent_added"
+	AgentRemoved = "agent_removed"
+	AgentUpdated = "agent_updated"
+)
+
+// Change is one difference between two states
+type Change struct {
+	Kind   string   `json:"kind"`
+	Repo   string   `json:"repo"`
+	Agent  string   `json:"agent,omitempty"`
+	Fields []string `json:"fields,omitempty"` // JSON names of changed fields, for updates
+}
+
+// Diff returns the changes that turn state from into state to, ordered by
+// repository and agent name. A changed default repository is reported as a
+// repo_updated change with an empty Repo.
+func Diff(from, to *state.View) []Change {
+	var changes []Change
+	if from.GetCurrentRepo() != to.GetCurrentRepo() {
+		changes = append(changes, Change{Kind: RepoUpdated, Fields: []string{"current_repo"}})
+	}
+
+	fromRepos := from.GetAllRepos()
+	toRepos := to.GetAllRepos()
+	for _, repoName := range sortedKeys(fromRepos, toRepos) {
+		before, hadRepo := fromRepos[repoName]
+		after, hasRepo := toRepos[repoName]
+		switch {
+		case !hadRepo:
+			changes = append(changes, Change{Kind: RepoAdded, Repo: repoName})
+			continue
+		case !hasRepo:
+			changes = append(changes, Change{Kind: RepoRemoved, Repo: repoName})
+			continue
+		}
+
+		beforeFields := jsonFields(before)
+		afterFields := jsonFields(after)
+		delete(beforeFields, "agents")
+		delete(afterFields, "agents")
+		if fields := changedFields(beforeFields, afterFields); len(fields) > 0 {
+			changes = append(changes, Change{Kind: RepoUpdated, Repo: repoName, Fields: fields})
+		}
+
+		for _, agentName := range sortedKeys(before.Agents, after.Agents) {
+			beforeAgent, hadAgent := before.Agents[agentName]
+			afterAgent, hasAgent := after.Agents[agentName]
+			switch {
+			case !hadAgent:
+				changes = append(changes, Change{Kind: AgentAdded, Repo: repoName, Agent: agentName})
+			case !hasAgent:
+				changes = append(changes, Change{Kind: AgentRemoved, Repo: repoName, Agent: agentName})
+			default:
+				if fields := changedFields(jsonFields(beforeAgent), jsonFields(afterAgent)); len(fields) > 0 {
+					changes = append(changes, Change{Kind: AgentUpdated, Repo: repoName, Agent: agentName, Fields: fields})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// MessageDiff lists the message files that differ between two message
+// directories, as paths relative to them (repo/agent/file)
+type MessageDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty reports whether the message directories are the same
+func (d MessageDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffMessages compares the message directories from and to
+func DiffMessages(from, to string) (MessageDiff, error) {
+	var diff MessageDiff
+	fromFiles, err := listFiles(from)
+	if err != nil {
+		return diff, err
+	}
+	toFiles, err := listFiles(to)
+	if err != nil {
+		return diff, err
+	}
+
+	for _, rel := range sortedKeys(fromFiles, toFiles) {
+		_, inFrom := fromFiles[rel]
+		_, inTo := toFiles[rel]
+		switch {
+		case !inFrom:
+			diff.Added = append(diff.Added, rel)
+		case !inTo:
+			diff.Removed = append(diff.Removed, rel)
+		default:
+			a, errA := os.ReadFile(filepath.Join(from, rel))
+			b, errB := os.ReadFile(filepath.Join(to, rel))
+			if errA != nil || errB != nil || !bytes.Equal(a, b) {
+				diff.Changed = append(diff.Changed, rel)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// listFiles returns the regular files under dir, keyed by relative path
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files[rel] = true
+		}
+		return nil
+	})
+	return files, err
+}
+
+// sortedKeys returns the union of the keys of a and b, sorted
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonFields returns the top-level JSON fields of v
+func jsonFields(v interface{}) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if data, err := json.Marshal(v); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// changedFields returns the sorted names of fields that differ between a and b
+func changedFields(a, b map[string]json.RawMessage) []string {
+	var changed []string
+	for _, k := range sortedKeys(a, b) {
+		if va, vb := a[k], b[k]; !bytes.Equal(va, vb) {
+			changed = append(changed, k)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,292 @@
+// Package snapshot keeps rotating copies of the state file and message
+// directories, so damaged state or an accidental removal can be rolled back.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+const (
+	// DefaultInterval is how often the daemon takes a snapshot
+	DefaultInterval = time.Hour
+	// DefaultKeep is how many snapshots are kept (two days of hourly snapshots)
+	DefaultKeep = 48
+
+	// idFormat is the UTC timestamp snapshots are named after. It sorts
+	// chronologically.
+	idFormat = "20060102-150405"
+
+	metadataFile = "snapshot.json"
+	stateFile    = "state.json"
+	messagesDir  = "messages"
+)
+
+// Reasons recorded with a snapshot
+const (
+	ReasonStartup    = "startup"
+	ReasonPeriodic   = "periodic"
+	ReasonRemoveRepo = "before repo removal"
+	ReasonRestore    = "before restore"
+)
+
+// Snapshot is a copy of the state file and message directories taken at one
+// point in time
+type Snapshot struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+
+	dir string
+}
+
+// StatePath returns the path of the snapshot's copy of the state file
+func (s *Snapshot) StatePath() string {
+	return filepath.Join(s.dir, stateFile)
+}
+
+// MessagesDir returns the path of the snapshot's copy of the message directories
+func (s *Snapshot) MessagesDir() string {
+	return filepath.Join(s.dir, messagesDir)
+}
+
+// LoadState loads the snapshot's state, migrated in memory if it was taken
+// by an older version
+func (s *Snapshot) LoadState() (*state.View, error) {
+	view, err := state.LoadView(s.StatePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", s.ID, err)
+	}
+	return view, nil
+}
+
+// Manager stores snapshots in a directory, one subdirectory per snapshot
+type Manager struct {
+	dir string
+	now func() time.Time
+}
+
+// NewManager creates a manager that stores snapshots in dir
+func NewManager(dir string) *Manager {
+	return &Manager{dir: dir, now: time.Now}
+}
+
+// Create snapshots the state file at statePath and the message directories
+// under msgDir. A missing message directory is snapshotted as empty.
+func (m *Manager) Create(statePath, msgDir, reason string) (*Snapshot, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+
+	createdAt := m.now().UTC()
+	id, err := m.newID(createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the snapshot under a temporary name so a crash never leaves a
+	// partial snapshot that looks complete
+	tmpDir, err := os.MkdirTemp(m.dir, ".tmp-"+id+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, stateFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot state: %w", err)
+	}
+	if err := copyDir(msgDir, filepath.Join(tmpDir, messagesDir)); err != nil {
+		return nil, fmt.Errorf("failed to copy messages: %w", err)
+	}
+
+	snap := &Snapshot{ID: id, CreatedAt: createdAt, Reason: reason}
+	metadata, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, metadataFile), metadata, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+
+	snap.dir = filepath.Join(m.dir, id)
+	if err := os.Rename(tmpDir, snap.dir); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// newID returns an unused snapshot ID for a snapshot taken at t
+func (m *Manager) newID(t time.Time) (string, error) {
+	base := t.Format(idFormat)
+	for i := 1; i < 100; i++ {
+		id := base
+		if i > 1 {
+			id = fmt.Sprintf("%s-%02d", base, i)
+		}
+		if _, err := os.Stat(filepath.Join(m.dir, id)); os.IsNotExist(err) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("too many snapshots at %s", base)
+}
+
+// List returns the snapshots, newest first. Directories without snapshot
+// metadata (such as an interrupted Create) are skipped.
+func (m *Manager) List() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshots directory: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		snap, err := m.read(entry.Name())
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID > snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+// Get returns the snapshot with the given ID
+func (m *Manager) Get(id string) (*Snapshot, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid snapshot ID %q", id)
+	}
+	snap, err := m.read(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %q not found", id)
+		}
+		return nil, err
+	}
+	return snap, nil
+}
+
+// read loads a snapshot's metadata
+func (m *Manager) read(id string) (*Snapshot, error) {
+	dir := filepath.Join(m.dir, id)
+	data, err := os.ReadFile(filepath.Join(dir, metadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", id, err)
+	}
+	snap.ID = id
+	snap.dir = dir
+	return &snap, nil
+}
+
+// Prune removes the oldest snapshots, keeping at most keep of them
+func (m *Manager) Prune(keep int) error {
+	snapshots, err := m.List()
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > keep {
+		oldest := snapshots[len(snapshots)-1]
+		if err := os.RemoveAll(oldest.dir); err != nil {
+			return fmt.Errorf("failed to remove snapshot %s: %w", oldest.ID, err)
+		}
+		snapshots = snapshots[:len(snapshots)-1]
+	}
+	return nil
+}
+
+// RestoreMessages replaces the message directories under msgDir with the
+// snapshot's copy. Callers must make sure nothing delivers messages meanwhile.
+func (s *Snapshot) RestoreMessages(msgDir string) error {
+	staged := msgDir + ".restore"
+	old := msgDir + ".old"
+	os.RemoveAll(staged)
+	os.RemoveAll(old)
+
+	if err := copyDir(s.MessagesDir(), staged); err != nil {
+		os.RemoveAll(staged)
+		return fmt.Errorf("failed to copy snapshot messages: %w", err)
+	}
+	if err := os.Rename(msgDir, old); err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(staged)
+		return fmt.Errorf("failed to move current messages aside: %w", err)
+	}
+	if err := os.Rename(staged, msgDir); err != nil {
+		// Put the current messages back rather than leaving none
+		os.Rename(old, msgDir)
+		return fmt.Errorf("failed to restore messages: %w", err)
+	}
+	return os.RemoveAll(old)
+}
+
+// copyDir recursively copies the regular files under src to dst. A missing
+// src produces an empty dst.
+func copyDir(src, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies a single file
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
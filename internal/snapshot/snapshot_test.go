@@ -0,0 +1,222 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// writeState saves a state with the given repos (each with a supervisor) to path
+func writeState(t *testing.T, path string, repos ...string) *state.State {
+	t.Helper()
+	s := state.New(path)
+	for _, name := range repos {
+		if err := s.AddRepo(name, &state.Repository{TmuxSession: "mc-" + name, Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+		}}); err != nil {
+			t.Fatalf("AddRepo() failed: %v", err)
+		}
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	return s
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestCreateAndList(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	msgDir := filepath.Join(dir, "messages")
+	writeState(t, statePath, "app")
+	writeFile(t, filepath.Join(msgDir, "app", "supervisor", "msg-1.json"), `{"id":"msg-1"}`)
+
+	m := NewManager(filepath.Join(dir, "snapshots"))
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	first, err := m.Create(statePath, msgDir, ReasonStartup)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if first.ID != "20260102-030405" {
+		t.Errorf("ID = %q, want the creation timestamp", first.ID)
+	}
+	// A second snapshot in the same second gets a suffix
+	second, err := m.Create(statePath, msgDir, ReasonPeriodic)
+	if err != nil {
+		t.Fatalf("second Create() failed: %v", err)
+	}
+	if second.ID != "20260102-030405-02" {
+		t.Errorf("second ID = %q, want a suffixed ID", second.ID)
+	}
+	now = now.Add(time.Hour)
+	third, err := m.Create(statePath, filepath.Join(dir, "missing"), ReasonPeriodic)
+	if err != nil {
+		t.Fatalf("Create() without messages failed: %v", err)
+	}
+
+	// Leftovers of an interrupted Create are ignored
+	if err := os.MkdirAll(filepath.Join(dir, "snapshots", ".tmp-20260102-050000-1"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	list, err := m.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var ids []string
+	for _, snap := range list {
+		ids = append(ids, snap.ID)
+	}
+	if want := []string{third.ID, second.ID, first.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("List() = %v, want newest first %v", ids, want)
+	}
+
+	got, err := m.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.Reason != ReasonStartup || !got.CreatedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Get() = %+v, want the startup snapshot", got)
+	}
+	view, err := got.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() failed: %v", err)
+	}
+	if _, ok := view.GetAgent("app", "supervisor"); !ok {
+		t.Error("snapshot state is missing the supervisor")
+	}
+	if _, err := os.Stat(filepath.Join(got.MessagesDir(), "app", "supervisor", "msg-1.json")); err != nil {
+		t.Errorf("snapshot is missing the message: %v", err)
+	}
+
+	for _, id := range []string{"missing", "../snapshots", ".tmp-20260102-050000-1", ""} {
+		if _, err := m.Get(id); err == nil {
+			t.Errorf("Get(%q) should fail", id)
+		}
+	}
+
+	if _, err := m.Create(filepath.Join(dir, "missing.json"), msgDir, ReasonPeriodic); err == nil {
+		t.Error("Create() without a state file should fail")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	writeState(t, statePath)
+
+	m := NewManager(filepath.Join(dir, "snapshots"))
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		if _, err := m.Create(statePath, filepath.Join(dir, "messages"), ReasonPeriodic); err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	if err := m.Prune(2); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	list, _ := m.List()
+	if len(list) != 2 || list[0].ID != "20260102-040000" || list[1].ID != "20260102-030000" {
+		t.Errorf("after Prune(2) List() = %+v, want the two newest", list)
+	}
+}
+
+func TestRestoreMessages(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	msgDir := filepath.Join(dir, "messages")
+	writeState(t, statePath, "app")
+	writeFile(t, filepath.Join(msgDir, "app", "supervisor", "msg-1.json"), "one")
+
+	m := NewManager(filepath.Join(dir, "snapshots"))
+	snap, err := m.Create(statePath, msgDir, ReasonPeriodic)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	writeFile(t, filepath.Join(msgDir, "app", "supervisor", "msg-1.json"), "changed")
+	writeFile(t, filepath.Join(msgDir, "app", "worker", "msg-2.json"), "two")
+	if err := os.RemoveAll(filepath.Join(msgDir, "app", "supervisor")); err != nil {
+		t.Fatalf("Failed to remove messages: %v", err)
+	}
+	writeFile(t, filepath.Join(msgDir, "app", "supervisor", "msg-3.json"), "three")
+
+	diff, err := DiffMessages(msgDir, snap.MessagesDir())
+	if err != nil {
+		t.Fatalf("DiffMessages() failed: %v", err)
+	}
+	want := MessageDiff{
+		Added:   []string{filepath.Join("app", "supervisor", "msg-1.json")},
+		Removed: []string{filepath.Join("app", "supervisor", "msg-3.json"), filepath.Join("app", "worker", "msg-2.json")},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffMessages() = %+v, want %+v", diff, want)
+	}
+
+	if err := snap.RestoreMessages(msgDir); err != nil {
+		t.Fatalf("RestoreMessages() failed: %v", err)
+	}
+	diff, err = DiffMessages(msgDir, snap.MessagesDir())
+	if err != nil || !diff.Empty() {
+		t.Errorf("after RestoreMessages() DiffMessages() = %+v, %v, want no differences", diff, err)
+	}
+	for _, leftover := range []string{msgDir + ".restore", msgDir + ".old"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("RestoreMessages() left %s behind", leftover)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	current := writeState(t, filepath.Join(dir, "current.json"), "app", "removed")
+	snapshot := writeState(t, filepath.Join(dir, "snapshot.json"), "app", "added")
+
+	if err := current.AddAgent("app", "worker", state.Agent{Type: state.AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if err := snapshot.AddAgent("app", "reviewer", state.Agent{Type: state.AgentTypeReview}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if err := snapshot.UpdateAgentPID("app", "supervisor", 4242); err != nil {
+		t.Fatalf("UpdateAgentPID() failed: %v", err)
+	}
+	if err := snapshot.SetCurrentRepo("app"); err != nil {
+		t.Fatalf("SetCurrentRepo() failed: %v", err)
+	}
+
+	got := Diff(current.View(), snapshot.View())
+	want := []Change{
+		{Kind: RepoUpdated, Fields: []string{"current_repo"}},
+		{Kind: RepoAdded, Repo: "added"},
+		{Kind: AgentAdded, Repo: "app", Agent: "reviewer"},
+		{Kind: AgentUpdated, Repo: "app", Agent: "supervisor", Fields: []string{"pid"}},
+		{Kind: AgentRemoved, Repo: "app", Agent: "worker"},
+		{Kind: RepoRemoved, Repo: "removed"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if changes := Diff(current.View(), current.View()); len(changes) != 0 {
+		t.Errorf("Diff() of identical states = %+v, want none", changes)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"time"
 )
 
 // Request represents a request sent to the daemon
@@ -53,11 +54,59 @@ func (c *Client) Send(req Request) (*Response, error) {
 	return &resp, nil
 }
 
+// Subscription reads the newline-delimited JSON values a streaming request
+// receives after its initial response
+type Subscription struct {
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+// Subscribe sends a streaming request, such as subscribe, and returns the
+// daemon's initial response. If the request succeeded, the connection stays
+// open and the returned subscription reads the values that follow; otherwise
+// the subscription is nil.
+func (c *Client) Subscribe(req Request) (*Subscription, *Response, error) {
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	dec := json.NewDecoder(conn)
+	var resp Response
+	if err := dec.Decode(&resp); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !resp.Success {
+		conn.Close()
+		return nil, &resp, nil
+	}
+
+	return &Subscription{conn: conn, dec: dec}, &resp, nil
+}
+
+// Next reads the next value into v. It returns io.EOF when the daemon ends
+// the stream.
+func (s *Subscription) Next(v interface{}) error {
+	return s.dec.Decode(v)
+}
+
+// Close ends the subscription. A Next blocked in another goroutine returns an error.
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
+
 // Server listens on a Unix socket for requests
 type Server struct {
 	socketPath string
 	listener   net.Listener
 	handler    Handler
+	streams    map[string]StreamHandlerFunc
 }
 
 // Handler processes requests
@@ -73,14 +122,64 @@ func (f HandlerFunc) Handle(req Request) Response {
 	return f(req)
 }
 
+// StreamHandlerFunc handles a request that keeps its connection open. It
+// sends the initial Response followed by any number of values on the
+// stream, and returns when it is done or the client disconnects.
+type StreamHandlerFunc func(req Request, stream *Stream)
+
+// streamWriteTimeout is how long a write to a streaming client may block
+// before the client is considered gone
+const streamWriteTimeout = 10 * time.Second
+
+// Stream writes newline-delimited JSON values to a streaming client
+type Stream struct {
+	conn net.Conn
+	enc  *json.Encoder
+	done chan struct{}
+}
+
+// newStream wraps a connection, watching it for the client going away
+func newStream(conn net.Conn) *Stream {
+	stream := &Stream{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		done: make(chan struct{}),
+	}
+	go func() {
+		// Clients don't send anything after the request; a read only
+		// returns once they disconnect or the connection is closed
+		io.Copy(io.Discard, conn)
+		close(stream.done)
+	}()
+	return stream
+}
+
+// Send writes one value as a line of JSON
+func (s *Stream) Send(v interface{}) error {
+	s.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return s.enc.Encode(v)
+}
+
+// Done is closed when the client disconnects
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
 // NewServer creates a new socket server
 func NewServer(socketPath string, handler Handler) *Server {
 	return &Server{
 		socketPath: socketPath,
 		handler:    handler,
+		streams:    make(map[string]StreamHandlerFunc),
 	}
 }
 
+// HandleStream registers a handler for a streaming command. It must be
+// called before Serve.
+func (s *Server) HandleStream(command string, handler StreamHandlerFunc) {
+	s.streams[command] = handler
+}
+
 // Start starts the socket server
 func (s *Server) Start() error {
 	// Remove stale socket file if exists
@@ -147,6 +246,11 @@ func (s *Server) handleConnection(conn net.Conn) {
 		return
 	}
 
+	if stream, ok := s.streams[req.Command]; ok {
+		stream(req, newStream(conn))
+		return
+	}
+
 	resp := s.handler.Handle(req)
 	if err := json.NewEncoder(conn).Encode(resp); err != nil {
 		// Can't send error response at this point
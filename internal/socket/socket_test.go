@@ -346,3 +346,76 @@ func TestServerStopRemovesSocket(t *testing.T) {
 		t.Error("Socket file should be removed after Stop()")
 	}
 }
+
+func TestServerStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "test.sock")
+
+	handler := HandlerFunc(func(req Request) Response {
+		return Response{Success: true, Data: "plain"}
+	})
+
+	server := NewServer(sockPath, handler)
+	disconnected := make(chan struct{})
+	server.HandleStream("count", func(req Request, stream *Stream) {
+		if req.Args["to"] == nil {
+			stream.Send(Response{Success: false, Error: "missing 'to'"})
+			return
+		}
+		stream.Send(Response{Success: true, Data: "counting"})
+		for i := 1; i <= int(req.Args["to"].(float64)); i++ {
+			if err := stream.Send(map[string]int{"n": i}); err != nil {
+				return
+			}
+		}
+		<-stream.Done()
+		close(disconnected)
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	client := NewClient(sockPath)
+
+	sub, resp, err := client.Subscribe(Request{Command: "count", Args: map[string]interface{}{"to": 3}})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	if !resp.Success || resp.Data != "counting" {
+		t.Fatalf("initial response = %+v", resp)
+	}
+	for want := 1; want <= 3; want++ {
+		var v map[string]int
+		if err := sub.Next(&v); err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		if v["n"] != want {
+			t.Errorf("Next() = %v, want n=%d", v, want)
+		}
+	}
+
+	// Closing the subscription tells the handler the client is gone
+	sub.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Error("stream handler should see the client disconnect")
+	}
+
+	// A failed initial response returns no subscription
+	sub, resp, err = client.Subscribe(Request{Command: "count"})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	if sub != nil || resp.Success || resp.Error != "missing 'to'" {
+		t.Errorf("Subscribe() = %v, %+v; want no subscription and the error", sub, resp)
+	}
+
+	// Other commands still use the regular handler
+	plain, err := client.Send(Request{Command: "other"})
+	if err != nil || plain.Data != "plain" {
+		t.Errorf("Send() = %+v, %v", plain, err)
+	}
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the state file's lock
+var ErrLocked = errors.New("state file is locked by another multiclaude process (is the daemon running?)")
+
+// lockRetryInterval is how often LockFile retries a held lock
+const lockRetryInterval = 50 * time.Millisecond
+
+// LockPath returns the lock file guarding the state file at path
+func LockPath(path string) string {
+	return path + ".lock"
+}
+
+// FileLock is an exclusive lock on a state file. The daemon holds it for as
+// long as it runs, so other processes can only change the state file directly
+// while the daemon is down; otherwise they must go through the daemon.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile acquires the lock on the state file at path, retrying until
+// timeout if another process holds it. It returns an error wrapping ErrLocked
+// if the lock is still held after timeout.
+func LockFile(path string, timeout time.Duration) (*FileLock, error) {
+	file, err := os.OpenFile(LockPath(path), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return &FileLock{file: file}, nil
+		}
+		if err != syscall.EWOULDBLOCK {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock state file: %w", err)
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, ErrLocked
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock state file: %w", err)
+	}
+	return l.file.Close()
+}
+
+// Update changes the state file at path directly: it takes the lock, loads
+// the state, applies fn and saves the result. It's the fallback for changing
+// state while the daemon is down; while the daemon runs it holds the lock and
+// Update fails with ErrLocked after timeout.
+func Update(path string, timeout time.Duration, fn func(*State) error) error {
+	lock, err := LockFile(path, timeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	s, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.Save()
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	lock, err := LockFile(statePath, 0)
+	if err != nil {
+		t.Fatalf("LockFile() failed: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := LockFile(statePath, 100*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Errorf("second LockFile() error = %v, want ErrLocked", err)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("second LockFile() gave up after %v, want it to retry until the timeout", waited)
+	}
+
+	// A waiting locker gets the lock once it's released
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Unlock()
+	}()
+	second, err := LockFile(statePath, 5*time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() after Unlock failed: %v", err)
+	}
+	second.Unlock()
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := Update(statePath, time.Second, func(s *State) error {
+		return s.AddRepo("app", &Repository{TmuxSession: "mc-app"})
+	}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	// Each Update stands in for a separate command changing the file while
+	// the daemon is down; none of them may lose another's change
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(statePath, 10*time.Second, func(s *State) error {
+				name := fmt.Sprintf("worker-%d", i)
+				return s.AddAgent("app", name, Agent{Type: AgentTypeWorker, TmuxWindow: name})
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Update() failed: %v", err)
+		}
+	}
+
+	view, err := LoadView(statePath)
+	if err != nil {
+		t.Fatalf("LoadView() failed: %v", err)
+	}
+	if agents, _ := view.ListAgents("app"); len(agents) != writers {
+		t.Errorf("state has %d agents, want %d", len(agents), writers)
+	}
+}
+
+func TestUpdateFailsWhileLocked(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	lock, err := LockFile(statePath, 0)
+	if err != nil {
+		t.Fatalf("LockFile() failed: %v", err)
+	}
+	defer lock.Unlock()
+
+	called := false
+	err = Update(statePath, 50*time.Millisecond, func(s *State) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrLocked) || called {
+		t.Errorf("Update() = %v (fn called: %v), want ErrLocked without calling fn", err, called)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("Update() must not write the state file without the lock")
+	}
+}
+
+func TestLoadViewLeavesFileAlone(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	legacy := []byte(`{"repos": {"app": {"tmux_session": "mc-app", "agents": null}}}`)
+	if err := os.WriteFile(statePath, legacy, 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	view, err := LoadView(statePath)
+	if err != nil {
+		t.Fatalf("LoadView() failed: %v", err)
+	}
+	if view.SchemaVersion() != CurrentSchemaVersion {
+		t.Errorf("SchemaVersion() = %d, want the migrated version %d", view.SchemaVersion(), CurrentSchemaVersion)
+	}
+	if repo, ok := view.GetRepo("app"); !ok || repo.Agents == nil {
+		t.Errorf("GetRepo() = %+v, %v, want the migrated repo", repo, ok)
+	}
+
+	if got, _ := os.ReadFile(statePath); string(got) != string(legacy) {
+		t.Error("LoadView() must not write the state file")
+	}
+	if _, err := os.Stat(BackupPath(statePath, 0)); !os.IsNotExist(err) {
+		t.Error("LoadView() must not write a migration backup")
+	}
+}
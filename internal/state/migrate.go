@@ -0,0 +1,212 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CurrentSchemaVersion is the state file schema this binary reads and writes.
+// To change the schema, append a migration to migrations and bump this.
+const CurrentSchemaVersion = 2
+
+// ErrSchemaTooNew is returned when the state file was written by a newer
+// version of multiclaude than this one
+var ErrSchemaTooNew = errors.New("state file was written by a newer version of multiclaude")
+
+// migration upgrades a state document from schema Version-1 to Version.
+// Migrations work on the raw JSON document rather than the State struct so
+// that they keep working as the structs change.
+type migration struct {
+	Version     int
+	Description string
+	Apply       func(doc map[string]interface{}) error
+}
+
+// migrations is the ordered migration registry. State files without a
+// schema_version are version 0.
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "fill in merge queue defaults for repos added before merge queue config",
+		Apply:       migrateMergeQueueDefaults,
+	},
+	{
+		Version:     2,
+		Description: "give every repo an agents map and every task history entry a status",
+		Apply:       migrateAgentsAndTaskStatus,
+	},
+}
+
+// MigrationResult describes a migration of the state file
+type MigrationResult struct {
+	// From and To are the schema versions before and after; equal if nothing ran
+	From int
+	To   int
+	// Applied describes each migration that ran, in order
+	Applied []string
+	// BackupPath is where the pre-migration file was saved, if any migration ran
+	BackupPath string
+}
+
+// Migrated returns true if any migration ran
+func (r MigrationResult) Migrated() bool {
+	return len(r.Applied) > 0
+}
+
+// BackupPath returns where the state file at path is backed up before it is
+// migrated from schema version from
+func BackupPath(path string, from int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, from)
+}
+
+// Migrate brings the state file at path up to CurrentSchemaVersion, backing
+// up the original first. A missing state file needs no migration.
+func Migrate(path string) (MigrationResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return MigrationResult{From: CurrentSchemaVersion, To: CurrentSchemaVersion}, nil
+		}
+		return MigrationResult{}, fmt.Errorf("failed to read state file: %w", err)
+	}
+	_, result, err := migrateFile(path, data)
+	return result, err
+}
+
+// migrateFile migrates data read from the state file at path. If any
+// migration runs, the original is written to BackupPath and the migrated
+// state replaces the file. It returns the data to load.
+func migrateFile(path string, data []byte) ([]byte, MigrationResult, error) {
+	migrated, result, err := migrateData(data)
+	if err != nil || !result.Migrated() {
+		return data, result, err
+	}
+
+	result.BackupPath = BackupPath(path, result.From)
+	if err := atomicWrite(result.BackupPath, data); err != nil {
+		return nil, result, fmt.Errorf("failed to back up state file before migration: %w", err)
+	}
+	if err := atomicWrite(path, migrated); err != nil {
+		return nil, result, fmt.Errorf("failed to write migrated state file: %w", err)
+	}
+	return migrated, result, nil
+}
+
+// migrateData applies the migrations the state document in data needs and
+// returns the migrated document
+func migrateData(data []byte) ([]byte, MigrationResult, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written so PIDs and the like round-trip exactly
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, MigrationResult{}, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if doc == nil {
+		return nil, MigrationResult{}, fmt.Errorf("failed to parse state file: not a JSON object")
+	}
+
+	from, err := schemaVersion(doc)
+	if err != nil {
+		return nil, MigrationResult{}, err
+	}
+	result := MigrationResult{From: from, To: from}
+	if from > CurrentSchemaVersion {
+		return nil, result, fmt.Errorf("%w: schema version %d, this version supports up to %d; upgrade multiclaude",
+			ErrSchemaTooNew, from, CurrentSchemaVersion)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= from {
+			continue
+		}
+		if err := m.Apply(doc); err != nil {
+			return nil, result, fmt.Errorf("state migration to schema version %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+		result.To = m.Version
+		result.Applied = append(result.Applied, m.Description)
+	}
+	if !result.Migrated() {
+		return data, result, nil
+	}
+
+	doc["schema_version"] = result.To
+	migrated, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to marshal migrated state: %w", err)
+	}
+	return migrated, result, nil
+}
+
+// schemaVersion returns the document's schema_version, 0 if it has none
+func schemaVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	number, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid schema_version in state file: %v", raw)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid schema_version in state file: %v", raw)
+	}
+	return int(version), nil
+}
+
+// repoDocs returns the repository objects in a state document
+func repoDocs(doc map[string]interface{}) map[string]map[string]interface{} {
+	repos := make(map[string]map[string]interface{})
+	raw, _ := doc["repos"].(map[string]interface{})
+	for name, r := range raw {
+		if repo, ok := r.(map[string]interface{}); ok {
+			repos[name] = repo
+		}
+	}
+	return repos
+}
+
+// migrateMergeQueueDefaults stores the default merge queue config for repos
+// whose config has no track mode. Such repos predate the config, and the
+// daemon has been treating them as having the defaults.
+func migrateMergeQueueDefaults(doc map[string]interface{}) error {
+	defaults := DefaultMergeQueueConfig()
+	for _, repo := range repoDocs(doc) {
+		config, _ := repo["merge_queue_config"].(map[string]interface{})
+		if trackMode, _ := config["track_mode"].(string); trackMode != "" {
+			continue
+		}
+		repo["merge_queue_config"] = map[string]interface{}{
+			"enabled":    defaults.Enabled,
+			"track_mode": string(defaults.TrackMode),
+		}
+	}
+	return nil
+}
+
+// migrateAgentsAndTaskStatus replaces a missing or null agents map with an
+// empty one, which adding an agent requires, and marks task history entries
+// recorded without a status as unknown
+func migrateAgentsAndTaskStatus(doc map[string]interface{}) error {
+	for _, repo := range repoDocs(doc) {
+		if _, ok := repo["agents"].(map[string]interface{}); !ok {
+			repo["agents"] = map[string]interface{}{}
+		}
+
+		history, _ := repo["task_history"].([]interface{})
+		for _, e := range history {
+			entry, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if status, _ := entry["status"].(string); status == "" {
+				entry["status"] = string(TaskStatusUnknown)
+			}
+		}
+	}
+	return nil
+}
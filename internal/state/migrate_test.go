@@ -0,0 +1,210 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the migration golden files in testdata")
+
+// checkGolden compares got with the golden file at path, or rewrites the
+// golden file when the tests run with -update
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output doesn't match %s (run with -update to accept)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// TestMigrationGolden applies each migration on its own to
+// testdata/migrations/v<N>.before.json and compares the result with
+// v<N>.after.json
+func TestMigrationGolden(t *testing.T) {
+	for _, m := range migrations {
+		m := m
+		t.Run(fmt.Sprintf("v%d", m.Version), func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", "migrations", fmt.Sprintf("v%d.before.json", m.Version)))
+			if err != nil {
+				t.Fatalf("Every migration needs a golden input: %v", err)
+			}
+			var doc map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(input))
+			decoder.UseNumber()
+			if err := decoder.Decode(&doc); err != nil {
+				t.Fatalf("Failed to parse input: %v", err)
+			}
+			if version, _ := schemaVersion(doc); version != m.Version-1 {
+				t.Fatalf("input schema version = %d, want %d", version, m.Version-1)
+			}
+
+			if err := m.Apply(doc); err != nil {
+				t.Fatalf("migration failed: %v", err)
+			}
+			doc["schema_version"] = m.Version
+			got, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				t.Fatalf("Failed to marshal output: %v", err)
+			}
+			checkGolden(t, filepath.Join("testdata", "migrations", fmt.Sprintf("v%d.after.json", m.Version)), append(got, '\n'))
+
+			// The migrated document must load into the current structs
+			var s State
+			if err := json.Unmarshal(got, &s); err != nil {
+				t.Errorf("migrated state doesn't load: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrationRegistry(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Description == "" || m.Apply == nil {
+			t.Errorf("migration %d needs a description and an Apply func", m.Version)
+		}
+	}
+	if last := migrations[len(migrations)-1].Version; last != CurrentSchemaVersion {
+		t.Errorf("last migration is version %d, want CurrentSchemaVersion %d", last, CurrentSchemaVersion)
+	}
+}
+
+func TestLoadMigratesLegacyState(t *testing.T) {
+	original, err := os.ReadFile(filepath.Join("testdata", "migrations", "v0-state.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(statePath, original, 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	s, result, err := LoadMigrated(statePath)
+	if err != nil {
+		t.Fatalf("LoadMigrated() failed: %v", err)
+	}
+	if result.From != 0 || result.To != CurrentSchemaVersion || result.BackupPath != BackupPath(statePath, 0) {
+		t.Errorf("LoadMigrated() result = %+v, want a migration from 0 with a backup", result)
+	}
+	if s.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", s.SchemaVersion, CurrentSchemaVersion)
+	}
+	mq, err := s.GetMergeQueueConfig("app")
+	if err != nil || mq != DefaultMergeQueueConfig() {
+		t.Errorf("GetMergeQueueConfig() = %+v, %v, want the defaults", mq, err)
+	}
+	history, _ := s.GetTaskHistory("app", 0)
+	if len(history) != 1 || history[0].Status != TaskStatusUnknown {
+		t.Errorf("task history = %+v, want one entry with unknown status", history)
+	}
+	agent, ok := s.GetAgent("app", "supervisor")
+	if !ok || agent.PID != 4242 {
+		t.Errorf("supervisor = %+v, want it kept with its PID", agent)
+	}
+
+	migrated, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("Failed to read migrated state: %v", err)
+	}
+	checkGolden(t, filepath.Join("testdata", "migrations", "v0-state.migrated.json"), append(migrated, '\n'))
+
+	backup, err := os.ReadFile(BackupPath(statePath, 0))
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if !bytes.Equal(backup, original) {
+		t.Error("backup doesn't match the pre-migration state file")
+	}
+
+	// Loading again finds nothing to migrate
+	if err := os.Remove(BackupPath(statePath, 0)); err != nil {
+		t.Fatalf("Failed to remove backup: %v", err)
+	}
+	if _, result, err := LoadMigrated(statePath); err != nil || result.Migrated() {
+		t.Fatalf("second LoadMigrated() = %+v, %v, want nothing to do", result, err)
+	}
+	if _, err := os.Stat(BackupPath(statePath, 0)); !os.IsNotExist(err) {
+		t.Error("second Load() should not migrate or back up again")
+	}
+}
+
+func TestLoadRejectsNewerSchema(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	data := fmt.Sprintf(`{"schema_version": %d, "repos": {}}`, CurrentSchemaVersion+1)
+	if err := os.WriteFile(statePath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	if _, err := Load(statePath); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Load() error = %v, want ErrSchemaTooNew", err)
+	}
+	if got, _ := os.ReadFile(statePath); string(got) != data {
+		t.Error("Load() must not modify a state file from a newer version")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := Migrate(filepath.Join(dir, "missing.json"))
+	if err != nil || result.Migrated() {
+		t.Errorf("Migrate() on a missing file = %+v, %v, want nothing to do", result, err)
+	}
+
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(statePath, []byte(`{"repos": {"app": {"agents": null}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	result, err = Migrate(statePath)
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if result.From != 0 || result.To != CurrentSchemaVersion || len(result.Applied) != len(migrations) {
+		t.Errorf("Migrate() = %+v, want every migration from 0", result)
+	}
+	if result.BackupPath != BackupPath(statePath, 0) {
+		t.Errorf("BackupPath = %q, want %q", result.BackupPath, BackupPath(statePath, 0))
+	}
+
+	result, err = Migrate(statePath)
+	if err != nil || result.Migrated() || result.From != CurrentSchemaVersion {
+		t.Errorf("second Migrate() = %+v, %v, want nothing to do", result, err)
+	}
+
+	if err := os.WriteFile(statePath, []byte(`{"schema_version": "two"}`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	if _, err := Migrate(statePath); err == nil {
+		t.Error("Migrate() should reject an invalid schema_version")
+	}
+}
+
+func TestNewStateSavesSchemaVersion(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := New(statePath).Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	result, err := Migrate(statePath)
+	if err != nil || result.Migrated() {
+		t.Errorf("Migrate() on a new state = %+v, %v, want nothing to do", result, err)
+	}
+}
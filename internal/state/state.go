@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -79,6 +81,202 @@ func DefaultMergeQueueConfig() MergeQueueConfig {
 	}
 }
 
+// PRShepherdConfig holds configuration for the PR shepherd agent, which
+// looks after PRs in fork mode
+type PRShepherdConfig struct {
+	// Enabled determines whether the PR shepherd agent should run (default: true)
+	Enabled bool `json:"enabled"`
+	// TrackMode determines which PRs to track: "all", "author", or "assigned" (default: "author")
+	TrackMode TrackMode `json:"track_mode"`
+}
+
+// DefaultPRShepherdConfig returns the default PR shepherd configuration
+func DefaultPRShepherdConfig() PRShepherdConfig {
+	return PRShepherdConfig{
+		Enabled:   true,
+		TrackMode: TrackModeAuthor,
+	}
+}
+
+// ForkConfig records whether a repository is a fork and which upstream it
+// was forked from
+type ForkConfig struct {
+	// IsFork is true when the repository was detected as a fork
+	IsFork bool `json:"is_fork"`
+	// UpstreamURL, UpstreamOwner and UpstreamRepo identify the upstream repository
+	UpstreamURL   string `json:"upstream_url,omitempty"`
+	UpstreamOwner string `json:"upstream_owner,omitempty"`
+	UpstreamRepo  string `json:"upstream_repo,omitempty"`
+	// ForceForkMode runs the repository in fork mode even if it isn't a fork
+	ForceForkMode bool `json:"force_fork_mode,omitempty"`
+}
+
+// ActivityConfig holds the thresholds used to decide that an agent is idle or
+// stuck based on the output captured from its tmux pane
+type ActivityConfig struct {
+	// IdleMinutes is how long an agent can go without output before it is idle (default: 10)
+	IdleMinutes int `json:"idle_minutes"`
+	// StuckMinutes is how long a worker can go without output before it is stuck (default: 30)
+	StuckMinutes int `json:"stuck_minutes"`
+	// EscalateStuck sends the supervisor a message when a worker becomes stuck (default: true)
+	EscalateStuck bool `json:"escalate_stuck"`
+}
+
+// DefaultActivityConfig returns the default activity configuration
+func DefaultActivityConfig() ActivityConfig {
+	return ActivityConfig{
+		IdleMinutes:   10,
+		StuckMinutes:  30,
+		EscalateStuck: true,
+	}
+}
+
+// Wake conditions for WakePolicy.OnlyWhen
+const (
+	// WakeWhenIdle wakes an agent that has stopped producing output
+	WakeWhenIdle = "idle"
+	// WakeWhenUnreadMessages wakes an agent that has unread messages
+	WakeWhenUnreadMessages = "unread_messages"
+	// WakeWhenPRActivity wakes an agent when PR or CI events occurred since its last nudge
+	WakeWhenPRActivity = "pr_activity"
+)
+
+// WakePolicy controls when the daemon sends status-check nudges to an agent type
+type WakePolicy struct {
+	// IntervalMinutes is the minimum time between nudges. 0 disables nudges.
+	IntervalMinutes int `json:"interval_minutes"`
+	// QuietHours is a local time range ("22:00-07:00") during which no nudges are sent
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// OnlyWhen limits nudges to times when at least one of the conditions holds
+	// (idle, unread_messages, pr_activity). Empty means nudge every interval.
+	OnlyWhen []string `json:"only_when,omitempty"`
+	// Message is the text typed into the agent's pane
+	Message string `json:"message"`
+}
+
+// DefaultWakePolicy returns the default wake policy for an agent type.
+// Workspace agents only receive direct user input and are never nudged.
+func DefaultWakePolicy(agentType AgentType) WakePolicy {
+	policy := WakePolicy{IntervalMinutes: 2}
+	switch agentType {
+	case AgentTypeSupervisor:
+		policy.Message = "Status check: Review worker progress and check merge queue."
+	case AgentTypeMergeQueue:
+		policy.Message = "Status check: Review open PRs and check CI status."
+	case AgentTypeReview:
+		policy.Message = "Status check: Update on your review progress?"
+	case AgentTypeWorkspace:
+		policy.IntervalMinutes = 0
+	default:
+		policy.Message = "Status check: Update on your progress?"
+	}
+	return policy
+}
+
+// Validate checks that the policy's quiet hours and conditions are well-formed
+func (p WakePolicy) Validate() error {
+	if p.IntervalMinutes < 0 {
+		return fmt.Errorf("interval_minutes must not be negative")
+	}
+	if p.QuietHours != "" {
+		if _, _, err := parseQuietHours(p.QuietHours); err != nil {
+			return err
+		}
+	}
+	for _, cond := range p.OnlyWhen {
+		switch cond {
+		case WakeWhenIdle, WakeWhenUnreadMessages, WakeWhenPRActivity:
+		default:
+			return fmt.Errorf("invalid wake condition: %q (valid conditions: %s, %s, %s)", cond, WakeWhenIdle, WakeWhenUnreadMessages, WakeWhenPRActivity)
+		}
+	}
+	return nil
+}
+
+// InQuietHours returns true if t falls within the policy's quiet hours.
+// Ranges that end before they start wrap around midnight.
+func (p WakePolicy) InQuietHours(t time.Time) bool {
+	if p.QuietHours == "" {
+		return false
+	}
+	start, end, err := parseQuietHours(p.QuietHours)
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseQuietHours parses "HH:MM-HH:MM" into minutes since midnight
+func parseQuietHours(s string) (start, end int, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid quiet hours %q: expected HH:MM-HH:MM", s)
+	}
+	var minutes [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid quiet hours %q: expected HH:MM-HH:MM", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// ClaudeOptions are extra launch options for an agent's Claude process
+type ClaudeOptions struct {
+	// Model is passed to claude as --model; empty uses Claude's default
+	Model string `json:"model,omitempty"`
+	// Args are extra command-line arguments appended to the claude command
+	Args []string `json:"args,omitempty"`
+	// Env holds environment variables set for the claude process
+	Env map[string]string `json:"env,omitempty"`
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsZero returns true if no options are set
+func (o ClaudeOptions) IsZero() bool {
+	return o.Model == "" && len(o.Args) == 0 && len(o.Env) == 0
+}
+
+// Validate checks that environment variable names are valid
+func (o ClaudeOptions) Validate() error {
+	for name := range o.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name: %q", name)
+		}
+	}
+	return nil
+}
+
+// Merge returns the options with override applied on top: override's model
+// wins if set, its args are appended and its env vars replace ones with the
+// same name
+func (o ClaudeOptions) Merge(override ClaudeOptions) ClaudeOptions {
+	merged := ClaudeOptions{Model: o.Model}
+	if override.Model != "" {
+		merged.Model = override.Model
+	}
+	if len(o.Args)+len(override.Args) > 0 {
+		merged.Args = append(append([]string{}, o.Args...), override.Args...)
+	}
+	if len(o.Env)+len(override.Env) > 0 {
+		merged.Env = make(map[string]string, len(o.Env)+len(override.Env))
+		for k, v := range o.Env {
+			merged.Env[k] = v
+		}
+		for k, v := range override.Env {
+			merged.Env[k] = v
+		}
+	}
+	return merged
+}
+
 // TaskStatus represents the status of a completed task
 type TaskStatus string
 
@@ -109,6 +307,29 @@ type TaskHistoryEntry struct {
 	FailureReason string     `json:"failure_reason,omitempty"` // Why the task failed (if applicable)
 	CreatedAt     time.Time  `json:"created_at"`               // When the task was started
 	CompletedAt   time.Time  `json:"completed_at,omitempty"`   // When the task was completed
+
+	// Set by the daemon's PR tracking
+	CIStatus     string `json:"ci_status,omitempty"`     // Combined PR checks: "passing", "failing", "pending", or empty
+	ReviewStatus string `json:"review_status,omitempty"` // PR review decision, e.g. "APPROVED"
+}
+
+// QueuedTask is a worker task waiting for a free worker slot
+type QueuedTask struct {
+	ID         string    `json:"id"`
+	Task       string    `json:"task"`                 // Task description
+	Name       string    `json:"name,omitempty"`       // Requested worker name; generated if empty
+	Branch     string    `json:"branch,omitempty"`     // Ref to start from (default: the base branch on origin)
+	Base       string    `json:"base,omitempty"`       // Branch the worker's PR targets (default: the repo's default branch)
+	PushTo     string    `json:"push_to,omitempty"`    // Existing PR branch to push to instead of opening a PR
+	After      string    `json:"after,omitempty"`      // Worker or queued task ID whose PR must merge first
+	EnqueuedAt time.Time `json:"enqueued_at"`          // When the task was queued
+	LastError  string    `json:"last_error,omitempty"` // Why the task can't start: a failed start or a failed dependency
+	Attempts   int       `json:"attempts,omitempty"`   // Failed attempts to start the task
+	RetryAt    time.Time `json:"retry_at,omitempty"`   // When a task that failed to start is tried again
+	// Claude launch options for the worker, on top of the repo's worker defaults
+	Claude *ClaudeOptions `json:"claude,omitempty"`
+	// CLI reference appended to the worker's prompt, as for workers created directly
+	Documentation string `json:"documentation,omitempty"`
 }
 
 // Agent represents an agent's state
@@ -124,6 +345,40 @@ type Agent struct {
 	CreatedAt       time.Time `json:"created_at"`
 	LastNudge       time.Time `json:"last_nudge,omitempty"`
 	ReadyForCleanup bool      `json:"ready_for_cleanup,omitempty"` // Only for workers
+	RestartCount    int       `json:"restart_count,omitempty"`     // Automatic restarts since the agent last ran stably
+	LastRestart     time.Time `json:"last_restart,omitempty"`      // When the daemon last restarted the agent
+	LastExitReason  string    `json:"last_exit_reason,omitempty"`  // Why the agent's process last exited
+	CrashLooping    bool      `json:"crash_looping,omitempty"`     // Automatic restarts stopped after repeated crashes
+	// Claude launch options the agent was started with, reused on restart
+	Claude *ClaudeOptions `json:"claude,omitempty"`
+	// Definition is the agent definition the agent was spawned from, if any
+	Definition string `json:"definition,omitempty"`
+	// BaseBranch is the branch a worker started from and opens its PR against;
+	// empty means the repo's default branch
+	BaseBranch string `json:"base_branch,omitempty"`
+	// WakePolicy overrides the repo's wake policy for the agent type, e.g.
+	// from the agent's definition
+	WakePolicy *WakePolicy `json:"wake_policy,omitempty"`
+	// NeedsRebase is set when the daemon couldn't sync a worker's branch with
+	// its base because of conflicts; RebaseConflicts are the conflicting files
+	NeedsRebase     bool     `json:"needs_rebase,omitempty"`
+	RebaseConflicts []string `json:"rebase_conflicts,omitempty"`
+	// NotifiedOverlaps are the workers this worker was told it changed the
+	// same files as, each mapped to whether their branches were predicted to
+	// conflict at the last overlap check
+	NotifiedOverlaps map[string]bool `json:"notified_overlaps,omitempty"`
+	// PR is a running worker's pull request as last seen by the daemon's PR
+	// tracking; it moves to the worker's task history when the worker ends
+	PR *AgentPR `json:"pr,omitempty"`
+}
+
+// AgentPR is the pull request a running worker opened for its branch
+type AgentPR struct {
+	Number       int        `json:"number,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	Status       TaskStatus `json:"status"`
+	CIStatus     string     `json:"ci_status,omitempty"`     // Combined PR checks: "passing", "failing", "pending", or empty
+	ReviewStatus string     `json:"review_status,omitempty"` // PR review decision, e.g. "APPROVED"
 }
 
 // UpstreamConfig holds configuration for fork/upstream tracking
@@ -133,8 +388,19 @@ type UpstreamConfig struct {
 	ForkRemote     string `json:"fork_remote"`     // Usually "origin"
 	SyncEnabled    bool   `json:"sync_enabled"`    // Enable fork/upstream sync
 	SyncInterval   int    `json:"sync_interval"`   // Minutes between sync checks (default: 30)
+
+	// SyncStrategy is how the daemon brings the fork's default branch up to
+	// date with upstream: SyncStrategyMerge, SyncStrategyRebase, or empty to
+	// only measure divergence
+	SyncStrategy string `json:"sync_strategy,omitempty"`
 }
 
+// Automatic upstream sync strategies
+const (
+	SyncStrategyMerge  = "merge"
+	SyncStrategyRebase = "rebase"
+)
+
 // CILayerStatus represents CI status for one layer (fork or upstream)
 type CILayerStatus struct {
 	Status       string     `json:"status"`                  // "passing", "failing", "pending", "unknown"
@@ -151,6 +417,10 @@ type DualCIStatus struct {
 	LastSyncTime    time.Time     `json:"last_sync_time"`   // Last time we synced with upstream
 	LastSyncSHA     string        `json:"last_sync_sha"`    // Last upstream commit synced
 	DivergenceCount int           `json:"divergence_count"` // Commits fork is behind upstream
+
+	// Set when an automatic sync conflicted, until a sync succeeds
+	SyncConflictSHA string   `json:"sync_conflict_sha,omitempty"` // Upstream commit the sync conflicted with
+	SyncConflicts   []string `json:"sync_conflicts,omitempty"`    // Files that conflicted
 }
 
 // Repository represents a tracked repository's state
@@ -160,6 +430,23 @@ type Repository struct {
 	Agents           map[string]Agent   `json:"agents"`
 	TaskHistory      []TaskHistoryEntry `json:"task_history,omitempty"`
 	MergeQueueConfig MergeQueueConfig   `json:"merge_queue_config,omitempty"`
+	PRShepherdConfig PRShepherdConfig   `json:"pr_shepherd_config,omitempty"`
+	ForkConfig       ForkConfig         `json:"fork_config,omitempty"`
+	ActivityConfig   ActivityConfig     `json:"activity_config,omitempty"`
+	// Per agent type wake policies; types without an entry use DefaultWakePolicy
+	WakePolicies map[AgentType]WakePolicy `json:"wake_policies,omitempty"`
+	// Per agent type Claude launch options (model, extra args, env)
+	ClaudeOptions map[AgentType]ClaudeOptions `json:"claude_options,omitempty"`
+	// MaxWorkers limits how many workers run at once; 0 means no limit
+	MaxWorkers int `json:"max_workers,omitempty"`
+	// TaskQueue holds worker tasks waiting for a free slot, next task first
+	TaskQueue []QueuedTask `json:"task_queue,omitempty"`
+	// DefaultBranch is the branch workers start from and open PRs against
+	// unless they're given another base; detected at init, empty means detect on use
+	DefaultBranch string `json:"default_branch,omitempty"`
+	// ResolveConflicts spawns a worker to rebase a completed worker's branch
+	// that still has conflicts with its base
+	ResolveConflicts bool `json:"resolve_conflicts,omitempty"`
 	// Dual-layer CI tracking for fork/upstream workflows
 	UpstreamConfig *UpstreamConfig `json:"upstream_config,omitempty"`
 	DualCIStatus   *DualCIStatus   `json:"dual_ci_status,omitempty"`
@@ -167,35 +454,69 @@ type Repository struct {
 
 // State represents the entire daemon state
 type State struct {
-	Repos       map[string]*Repository `json:"repos"`
-	CurrentRepo string                 `json:"current_repo,omitempty"`
-	Hooks       events.HookConfig      `json:"hooks,omitempty"` // Global hook configuration
-	mu          sync.RWMutex
-	path        string
+	// SchemaVersion is the schema the file was written with; see CurrentSchemaVersion
+	SchemaVersion int                    `json:"schema_version"`
+	Repos         map[string]*Repository `json:"repos"`
+	CurrentRepo   string                 `json:"current_repo,omitempty"`
+	Hooks         events.HookConfig      `json:"hooks,omitempty"` // Global hook configuration
+	mu            sync.RWMutex
+	path          string
+	onSave        func()
 }
 
 // New creates a new empty state
 func New(path string) *State {
 	return &State{
-		Repos: make(map[string]*Repository),
-		path:  path,
+		SchemaVersion: CurrentSchemaVersion,
+		Repos:         make(map[string]*Repository),
+		path:          path,
 	}
 }
 
-// Load loads state from disk
+// Load loads state from disk, migrating it to CurrentSchemaVersion first if
+// it is older. It returns an error wrapping ErrSchemaTooNew if the file was
+// written by a newer version of multiclaude.
+//
+// Loaded state is written back on every change, so only the process holding
+// the state file's lock (see LockFile) may load it: the daemon, or a command
+// changing state through Update while the daemon is down. Everything else
+// should use LoadView.
 func Load(path string) (*State, error) {
+	s, _, err := LoadMigrated(path)
+	return s, err
+}
+
+// LoadMigrated is Load, also returning the migration it ran so the caller
+// can report it
+func LoadMigrated(path string) (*State, MigrationResult, error) {
+	return load(path, true)
+}
+
+// load reads the state file, migrating it if needed. The migrated file is
+// written back only if persist is set.
+func load(path string, persist bool) (*State, MigrationResult, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// No state file, return empty state
-			return New(path), nil
+			return New(path), MigrationResult{From: CurrentSchemaVersion, To: CurrentSchemaVersion}, nil
 		}
-		return nil, fmt.Errorf("failed to read state file: %w", err)
+		return nil, MigrationResult{}, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var result MigrationResult
+	if persist {
+		data, result, err = migrateFile(path, data)
+	} else {
+		data, result, err = migrateData(data)
+	}
+	if err != nil {
+		return nil, result, err
 	}
 
 	var s State
 	if err := json.Unmarshal(data, &s); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		return nil, result, fmt.Errorf("failed to parse state file: %w", err)
 	}
 
 	s.path = path
@@ -205,7 +526,7 @@ func Load(path string) (*State, error) {
 		s.Repos = make(map[string]*Repository)
 	}
 
-	return &s, nil
+	return &s, result, nil
 }
 
 // atomicWrite writes data to a file atomically using a temp file and rename.
@@ -253,7 +574,54 @@ func (s *State) Save() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return atomicWrite(s.path, data)
+	return s.writeLocked(data)
+}
+
+// SetOnSave registers fn to be called after every successful save. It is
+// called with the state lock held, so it must not call back into the state.
+func (s *State) SetOnSave(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSave = fn
+}
+
+// Restore replaces the state's contents with a copy of v, such as a snapshot,
+// and saves it. The state keeps its own path and save hook.
+func (s *State) Restore(v *View) error {
+	v.s.mu.RLock()
+	data, err := json.Marshal(v.s)
+	v.s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal restored state: %w", err)
+	}
+	var restored State
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("failed to copy restored state: %w", err)
+	}
+	if restored.Repos == nil {
+		restored.Repos = make(map[string]*Repository)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SchemaVersion = CurrentSchemaVersion
+	s.Repos = restored.Repos
+	s.CurrentRepo = restored.CurrentRepo
+	s.Hooks = restored.Hooks
+	return s.saveUnlocked()
+}
+
+// writeLocked writes marshaled state to disk and notifies the save hook.
+// The caller holds s.mu.
+func (s *State) writeLocked(data []byte) error {
+	if err := atomicWrite(s.path, data); err != nil {
+		return err
+	}
+	if s.onSave != nil {
+		s.onSave()
+	}
+	return nil
 }
 
 // AddRepo adds a new repository to the state
@@ -364,6 +732,26 @@ func (s *State) GetAllRepos() map[string]*Repository {
 			TmuxSession:      repo.TmuxSession,
 			Agents:           make(map[string]Agent, len(repo.Agents)),
 			MergeQueueConfig: repo.MergeQueueConfig,
+			PRShepherdConfig: repo.PRShepherdConfig,
+			ForkConfig:       repo.ForkConfig,
+			ActivityConfig:   repo.ActivityConfig,
+			MaxWorkers:       repo.MaxWorkers,
+			DefaultBranch:    repo.DefaultBranch,
+			ResolveConflicts: repo.ResolveConflicts,
+		}
+		// Copy wake policies
+		if repo.WakePolicies != nil {
+			repoCopy.WakePolicies = make(map[AgentType]WakePolicy, len(repo.WakePolicies))
+			for agentType, policy := range repo.WakePolicies {
+				repoCopy.WakePolicies[agentType] = policy
+			}
+		}
+		// Copy Claude options
+		if repo.ClaudeOptions != nil {
+			repoCopy.ClaudeOptions = make(map[AgentType]ClaudeOptions, len(repo.ClaudeOptions))
+			for agentType, opts := range repo.ClaudeOptions {
+				repoCopy.ClaudeOptions[agentType] = opts
+			}
 		}
 		// Copy agents
 		for agentName, agent := range repo.Agents {
@@ -374,6 +762,20 @@ func (s *State) GetAllRepos() map[string]*Repository {
 			repoCopy.TaskHistory = make([]TaskHistoryEntry, len(repo.TaskHistory))
 			copy(repoCopy.TaskHistory, repo.TaskHistory)
 		}
+		// Copy task queue
+		if repo.TaskQueue != nil {
+			repoCopy.TaskQueue = make([]QueuedTask, len(repo.TaskQueue))
+			copy(repoCopy.TaskQueue, repo.TaskQueue)
+		}
+		// Copy upstream tracking
+		if repo.UpstreamConfig != nil {
+			upstream := *repo.UpstreamConfig
+			repoCopy.UpstreamConfig = &upstream
+		}
+		if repo.DualCIStatus != nil {
+			ciStatus := *repo.DualCIStatus
+			repoCopy.DualCIStatus = &ciStatus
+		}
 		repos[name] = repoCopy
 	}
 	return repos
@@ -435,6 +837,139 @@ func (s *State) UpdateAgentPID(repoName, agentName string, pid int) error {
 	return s.saveUnlocked()
 }
 
+// RecordAgentRestart records an automatic restart of an agent whose process exited
+func (s *State) RecordAgentRestart(repoName, agentName, exitReason string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.RestartCount++
+	agent.LastRestart = at
+	agent.LastExitReason = exitReason
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// MarkAgentCrashLooping stops automatic restarts of an agent that keeps exiting
+func (s *State) MarkAgentCrashLooping(repoName, agentName, exitReason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.CrashLooping = true
+	agent.LastExitReason = exitReason
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// ResetAgentRestarts clears an agent's restart count and crash-looping flag.
+// The last restart time and exit reason are kept for reference.
+func (s *State) ResetAgentRestarts(repoName, agentName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.RestartCount = 0
+	agent.CrashLooping = false
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// SetAgentNeedsRebase records the files that stopped a worker's branch from
+// being synced with its base; no files clears the flag
+func (s *State) SetAgentNeedsRebase(repoName, agentName string, conflicts []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.NeedsRebase = len(conflicts) > 0
+	agent.RebaseConflicts = conflicts
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// SetAgentNotifiedOverlaps replaces the overlaps a worker was told about
+func (s *State) SetAgentNotifiedOverlaps(repoName, agentName string, overlaps map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.NotifiedOverlaps = nil
+	if len(overlaps) > 0 {
+		agent.NotifiedOverlaps = make(map[string]bool, len(overlaps))
+		for worker, conflicts := range overlaps {
+			agent.NotifiedOverlaps[worker] = conflicts
+		}
+	}
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
+// SetAgentPR records the pull request of a running worker
+func (s *State) SetAgentPR(repoName, agentName string, pr AgentPR) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.PR = &pr
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
 // RemoveAgent removes an agent from a repository
 func (s *State) RemoveAgent(repoName, agentName string) error {
 	s.mu.Lock()
@@ -511,8 +1046,25 @@ func (s *State) UpdateMergeQueueConfig(repoName string, config MergeQueueConfig)
 	return s.saveUnlocked()
 }
 
-// AddTaskHistory adds a completed task to the repository's history
-func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
+// GetPRShepherdConfig returns the PR shepherd config for a repository
+func (s *State) GetPRShepherdConfig(repoName string) (PRShepherdConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return PRShepherdConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Return default config if not set (for backward compatibility)
+	if repo.PRShepherdConfig.TrackMode == "" {
+		return DefaultPRShepherdConfig(), nil
+	}
+	return repo.PRShepherdConfig, nil
+}
+
+// UpdatePRShepherdConfig updates the PR shepherd config for a repository
+func (s *State) UpdatePRShepherdConfig(repoName string, config PRShepherdConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -521,41 +1073,24 @@ func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	repo.TaskHistory = append(repo.TaskHistory, entry)
+	repo.PRShepherdConfig = config
 	return s.saveUnlocked()
 }
 
-// GetTaskHistory returns the task history for a repository, optionally limited to N entries
-func (s *State) GetTaskHistory(repoName string, limit int) ([]TaskHistoryEntry, error) {
+// GetForkConfig returns the fork config for a repository
+func (s *State) GetForkConfig(repoName string) (ForkConfig, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	repo, exists := s.Repos[repoName]
 	if !exists {
-		return nil, fmt.Errorf("repository %q not found", repoName)
-	}
-
-	history := repo.TaskHistory
-	if history == nil {
-		return []TaskHistoryEntry{}, nil
-	}
-
-	// Return most recent first
-	result := make([]TaskHistoryEntry, len(history))
-	for i, entry := range history {
-		result[len(history)-1-i] = entry
-	}
-
-	// Apply limit if specified
-	if limit > 0 && len(result) > limit {
-		result = result[:limit]
+		return ForkConfig{}, fmt.Errorf("repository %q not found", repoName)
 	}
-
-	return result, nil
+	return repo.ForkConfig, nil
 }
 
-// UpdateTaskHistoryStatus updates the status and PR info for a task by name
-func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskStatus, prURL string, prNumber int) error {
+// UpdateForkConfig updates the fork config for a repository
+func (s *State) UpdateForkConfig(repoName string, config ForkConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -564,14 +1099,221 @@ func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskSt
 		return fmt.Errorf("repository %q not found", repoName)
 	}
 
-	// Find the most recent entry with this name and update it
-	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
-		if repo.TaskHistory[i].Name == taskName {
-			repo.TaskHistory[i].Status = status
-			if prURL != "" {
-				repo.TaskHistory[i].PRURL = prURL
-			}
-			if prNumber > 0 {
+	repo.ForkConfig = config
+	return s.saveUnlocked()
+}
+
+// IsForkMode reports whether a repository runs in fork mode: it's a fork,
+// or fork mode is forced. It returns false for unknown repositories.
+func (s *State) IsForkMode(repoName string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return false
+	}
+	return repo.ForkConfig.IsFork || repo.ForkConfig.ForceForkMode
+}
+
+// GetActivityConfig returns the activity config for a repository
+func (s *State) GetActivityConfig(repoName string) (ActivityConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return ActivityConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Return default config if not set (for backward compatibility)
+	if repo.ActivityConfig.IdleMinutes == 0 {
+		return DefaultActivityConfig(), nil
+	}
+	return repo.ActivityConfig, nil
+}
+
+// UpdateActivityConfig updates the activity config for a repository
+func (s *State) UpdateActivityConfig(repoName string, config ActivityConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ActivityConfig = config
+	return s.saveUnlocked()
+}
+
+// WakePolicyFor returns the wake policy for an agent type in this repository
+func (r *Repository) WakePolicyFor(agentType AgentType) WakePolicy {
+	if policy, ok := r.WakePolicies[agentType]; ok {
+		return policy
+	}
+	return DefaultWakePolicy(agentType)
+}
+
+// BaseBranchFor returns the branch an agent works against: its own base
+// branch if it has one, otherwise the repo's default branch. It returns ""
+// if neither is known.
+func (r *Repository) BaseBranchFor(agent Agent) string {
+	if agent.BaseBranch != "" {
+		return agent.BaseBranch
+	}
+	return r.DefaultBranch
+}
+
+// WakePolicyForAgent returns the wake policy for an agent: its own policy
+// if it has one, otherwise the policy for its type
+func (r *Repository) WakePolicyForAgent(agent Agent) WakePolicy {
+	if agent.WakePolicy != nil {
+		return *agent.WakePolicy
+	}
+	return r.WakePolicyFor(agent.Type)
+}
+
+// GetWakePolicy returns the wake policy for an agent type in a repository
+func (s *State) GetWakePolicy(repoName string, agentType AgentType) (WakePolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return WakePolicy{}, fmt.Errorf("repository %q not found", repoName)
+	}
+	return repo.WakePolicyFor(agentType), nil
+}
+
+// UpdateWakePolicy sets the wake policy for an agent type in a repository
+func (s *State) UpdateWakePolicy(repoName string, agentType AgentType, policy WakePolicy) error {
+	return s.UpdateWakePolicies(repoName, map[AgentType]WakePolicy{agentType: policy})
+}
+
+// UpdateWakePolicies sets the wake policies for several agent types in a
+// repository in a single write
+func (s *State) UpdateWakePolicies(repoName string, policies map[AgentType]WakePolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if repo.WakePolicies == nil {
+		repo.WakePolicies = make(map[AgentType]WakePolicy, len(policies))
+	}
+	for agentType, policy := range policies {
+		repo.WakePolicies[agentType] = policy
+	}
+	return s.saveUnlocked()
+}
+
+// GetClaudeOptions returns the Claude launch options for an agent type in a repository
+func (s *State) GetClaudeOptions(repoName string, agentType AgentType) (ClaudeOptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return ClaudeOptions{}, fmt.Errorf("repository %q not found", repoName)
+	}
+	return repo.ClaudeOptions[agentType], nil
+}
+
+// UpdateClaudeOptions sets the Claude launch options for an agent type in a
+// repository. Empty options remove the entry.
+func (s *State) UpdateClaudeOptions(repoName string, agentType AgentType, opts ClaudeOptions) error {
+	return s.UpdateAllClaudeOptions(repoName, map[AgentType]ClaudeOptions{agentType: opts})
+}
+
+// UpdateAllClaudeOptions sets the Claude launch options for several agent
+// types in a repository in a single write. Empty options remove the entry.
+func (s *State) UpdateAllClaudeOptions(repoName string, options map[AgentType]ClaudeOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for agentType, opts := range options {
+		if opts.IsZero() {
+			delete(repo.ClaudeOptions, agentType)
+			continue
+		}
+		if repo.ClaudeOptions == nil {
+			repo.ClaudeOptions = make(map[AgentType]ClaudeOptions)
+		}
+		repo.ClaudeOptions[agentType] = opts
+	}
+	return s.saveUnlocked()
+}
+
+// AddTaskHistory adds a completed task to the repository's history
+func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TaskHistory = append(repo.TaskHistory, entry)
+	return s.saveUnlocked()
+}
+
+// GetTaskHistory returns the task history for a repository, optionally limited to N entries
+func (s *State) GetTaskHistory(repoName string, limit int) ([]TaskHistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	history := repo.TaskHistory
+	if history == nil {
+		return []TaskHistoryEntry{}, nil
+	}
+
+	// Return most recent first
+	result := make([]TaskHistoryEntry, len(history))
+	for i, entry := range history {
+		result[len(history)-1-i] = entry
+	}
+
+	// Apply limit if specified
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// UpdateTaskHistoryStatus updates the status and PR info for a task by name
+func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskStatus, prURL string, prNumber int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	// Find the most recent entry with this name and update it
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		if repo.TaskHistory[i].Name == taskName {
+			repo.TaskHistory[i].Status = status
+			if prURL != "" {
+				repo.TaskHistory[i].PRURL = prURL
+			}
+			if prNumber > 0 {
 				repo.TaskHistory[i].PRNumber = prNumber
 			}
 			return s.saveUnlocked()
@@ -581,6 +1323,27 @@ func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskSt
 	return fmt.Errorf("task %q not found in history", taskName)
 }
 
+// UpdateTaskHistoryChecks records the CI and review status of a task's PR
+func (s *State) UpdateTaskHistoryChecks(repoName, taskName, ciStatus, reviewStatus string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i := len(repo.TaskHistory) - 1; i >= 0; i-- {
+		if repo.TaskHistory[i].Name == taskName {
+			repo.TaskHistory[i].CIStatus = ciStatus
+			repo.TaskHistory[i].ReviewStatus = reviewStatus
+			return s.saveUnlocked()
+		}
+	}
+
+	return fmt.Errorf("task %q not found in history", taskName)
+}
+
 // UpdateTaskHistorySummary updates the summary and failure reason for a task by name
 func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureReason string) error {
 	s.mu.Lock()
@@ -609,6 +1372,241 @@ func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureRea
 	return fmt.Errorf("task %q not found in history", taskName)
 }
 
+// SetDefaultBranch records a repository's default branch
+func (s *State) SetDefaultBranch(repoName, branch string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.DefaultBranch = branch
+	return s.saveUnlocked()
+}
+
+// SetMaxWorkers sets how many workers may run at once in a repository (0 means no limit)
+func (s *State) SetMaxWorkers(repoName string, maxWorkers int) error {
+	if maxWorkers < 0 {
+		return fmt.Errorf("max workers must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.MaxWorkers = maxWorkers
+	return s.saveUnlocked()
+}
+
+// SetResolveConflicts sets whether conflict-resolution workers are spawned
+// for completed workers whose branches need a rebase
+func (s *State) SetResolveConflicts(repoName string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ResolveConflicts = enabled
+	return s.saveUnlocked()
+}
+
+// ActiveWorkerCount returns how many workers in a repository are still
+// working, i.e. have not signalled completion
+func (s *State) ActiveWorkerCount(repoName string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return 0, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	count := 0
+	for _, agent := range repo.Agents {
+		if agent.Type == AgentTypeWorker && !agent.ReadyForCleanup {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// EnqueueTask adds a task to the end of a repository's task queue and
+// returns its 1-based position
+func (s *State) EnqueueTask(repoName string, task QueuedTask) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return 0, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for _, queued := range repo.TaskQueue {
+		if queued.ID == task.ID {
+			return 0, fmt.Errorf("task %q is already queued", task.ID)
+		}
+	}
+
+	repo.TaskQueue = append(repo.TaskQueue, task)
+	return len(repo.TaskQueue), s.saveUnlocked()
+}
+
+// GetTaskQueue returns a copy of a repository's task queue, next task first
+func (s *State) GetTaskQueue(repoName string) ([]QueuedTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return nil, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	queue := make([]QueuedTask, len(repo.TaskQueue))
+	copy(queue, repo.TaskQueue)
+	return queue, nil
+}
+
+// DequeueTask removes and returns the next task in a repository's queue.
+// ok is false if the queue is empty.
+func (s *State) DequeueTask(repoName string) (task QueuedTask, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return QueuedTask{}, false, fmt.Errorf("repository %q not found", repoName)
+	}
+	if len(repo.TaskQueue) == 0 {
+		return QueuedTask{}, false, nil
+	}
+
+	task = repo.TaskQueue[0]
+	repo.TaskQueue = repo.TaskQueue[1:]
+	return task, true, s.saveUnlocked()
+}
+
+// RequeueTask puts a task back at the front of a repository's queue, e.g.
+// after it failed to start
+func (s *State) RequeueTask(repoName string, task QueuedTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TaskQueue = append([]QueuedTask{task}, repo.TaskQueue...)
+	return s.saveUnlocked()
+}
+
+// MoveQueuedTask moves a queued task to a 1-based position in the queue.
+// Positions past the end move the task to the back.
+func (s *State) MoveQueuedTask(repoName, taskID string, position int) error {
+	if position < 1 {
+		return fmt.Errorf("position must be at least 1")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	index := -1
+	for i, queued := range repo.TaskQueue {
+		if queued.ID == taskID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("task %q not found in queue", taskID)
+	}
+
+	task := repo.TaskQueue[index]
+	queue := append(repo.TaskQueue[:index:index], repo.TaskQueue[index+1:]...)
+	if position > len(queue) {
+		position = len(queue) + 1
+	}
+	queue = append(queue[:position-1], append([]QueuedTask{task}, queue[position-1:]...)...)
+	repo.TaskQueue = queue
+	return s.saveUnlocked()
+}
+
+// RemoveQueuedTask removes a task from a repository's queue and returns it
+func (s *State) RemoveQueuedTask(repoName, taskID string) (QueuedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return QueuedTask{}, fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i, queued := range repo.TaskQueue {
+		if queued.ID == taskID {
+			repo.TaskQueue = append(repo.TaskQueue[:i:i], repo.TaskQueue[i+1:]...)
+			return queued, s.saveUnlocked()
+		}
+	}
+	return QueuedTask{}, fmt.Errorf("task %q not found in queue", taskID)
+}
+
+// SetQueuedTaskError records why a queued task can't start
+func (s *State) SetQueuedTaskError(repoName, taskID, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i := range repo.TaskQueue {
+		if repo.TaskQueue[i].ID == taskID {
+			repo.TaskQueue[i].LastError = lastError
+			return s.saveUnlocked()
+		}
+	}
+	return fmt.Errorf("task %q not found in queue", taskID)
+}
+
+// RetargetTaskDependencies points queued tasks that wait on one dependency
+// at another, e.g. from a queued task's ID to the worker it started as
+func (s *State) RetargetTaskDependencies(repoName, from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	changed := false
+	for i := range repo.TaskQueue {
+		if repo.TaskQueue[i].After == from {
+			repo.TaskQueue[i].After = to
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.saveUnlocked()
+}
+
 // saveUnlocked saves state without acquiring lock (caller must hold lock)
 func (s *State) saveUnlocked() error {
 	data, err := json.MarshalIndent(s, "", "  ")
@@ -616,7 +1614,7 @@ func (s *State) saveUnlocked() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return atomicWrite(s.path, data)
+	return s.writeLocked(data)
 }
 
 // SetUpstreamConfig sets the upstream configuration for a repository
@@ -682,6 +1680,28 @@ func (s *State) UpdateSyncTime(repoName string, syncTime time.Time, syncSHA stri
 
 	repo.DualCIStatus.LastSyncTime = syncTime
 	repo.DualCIStatus.LastSyncSHA = syncSHA
+	repo.DualCIStatus.SyncConflictSHA = ""
+	repo.DualCIStatus.SyncConflicts = nil
+	return s.saveUnlocked()
+}
+
+// SetSyncConflicts records that syncing with upstream commit sha conflicted
+// in files. A successful sync recorded with UpdateSyncTime clears it.
+func (s *State) SetSyncConflicts(repoName, sha string, files []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if repo.DualCIStatus == nil {
+		repo.DualCIStatus = &DualCIStatus{}
+	}
+
+	repo.DualCIStatus.SyncConflictSHA = sha
+	repo.DualCIStatus.SyncConflicts = files
 	return s.saveUnlocked()
 }
 
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -1259,6 +1261,17 @@ func TestUpdateTaskHistoryStatus(t *testing.T) {
 	if history[0].PRNumber != 456 {
 		t.Errorf("Updated PRNumber = %d, want 456", history[0].PRNumber)
 	}
+
+	if err := s.UpdateTaskHistoryChecks("test-repo", "worker-1", "failing", "CHANGES_REQUESTED"); err != nil {
+		t.Fatalf("UpdateTaskHistoryChecks() failed: %v", err)
+	}
+	if err := s.UpdateTaskHistoryChecks("test-repo", "missing", "passing", ""); err == nil {
+		t.Error("UpdateTaskHistoryChecks() should fail for a nonexistent task")
+	}
+	history, _ = s.GetTaskHistory("test-repo", 10)
+	if got := history[0]; got.CIStatus != "failing" || got.ReviewStatus != "CHANGES_REQUESTED" {
+		t.Errorf("PR checks = %q, %q, want failing, CHANGES_REQUESTED", got.CIStatus, got.ReviewStatus)
+	}
 }
 
 func TestTaskHistoryPersistence(t *testing.T) {
@@ -2206,3 +2219,814 @@ func TestGetTaskHistoryNoLimit(t *testing.T) {
 		t.Errorf("GetTaskHistory() with limit=0 returned %d entries, want 5", len(history))
 	}
 }
+
+func TestActivityConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+
+	if _, err := s.GetActivityConfig("nonexistent"); err == nil {
+		t.Error("GetActivityConfig() should fail for nonexistent repo")
+	}
+	if err := s.UpdateActivityConfig("nonexistent", ActivityConfig{}); err == nil {
+		t.Error("UpdateActivityConfig() should fail for nonexistent repo")
+	}
+
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	// Unset config returns defaults
+	config, err := s.GetActivityConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetActivityConfig() failed: %v", err)
+	}
+	if config != DefaultActivityConfig() {
+		t.Errorf("GetActivityConfig() = %+v, want defaults %+v", config, DefaultActivityConfig())
+	}
+
+	newConfig := ActivityConfig{IdleMinutes: 5, StuckMinutes: 15, EscalateStuck: false}
+	if err := s.UpdateActivityConfig("test-repo", newConfig); err != nil {
+		t.Fatalf("UpdateActivityConfig() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	loadedConfig, err := loaded.GetActivityConfig("test-repo")
+	if err != nil {
+		t.Fatalf("GetActivityConfig() failed: %v", err)
+	}
+	if loadedConfig != newConfig {
+		t.Errorf("loaded config = %+v, want %+v", loadedConfig, newConfig)
+	}
+
+	// The config survives the GetAllRepos snapshot
+	if got := loaded.GetAllRepos()["test-repo"].ActivityConfig; got != newConfig {
+		t.Errorf("GetAllRepos() ActivityConfig = %+v, want %+v", got, newConfig)
+	}
+}
+
+func TestDefaultWakePolicy(t *testing.T) {
+	if got := DefaultWakePolicy(AgentTypeWorkspace); got.IntervalMinutes != 0 {
+		t.Errorf("workspace interval = %d, want 0 (never nudged)", got.IntervalMinutes)
+	}
+	for _, agentType := range []AgentType{AgentTypeSupervisor, AgentTypeWorker, AgentTypeMergeQueue, AgentTypeReview} {
+		policy := DefaultWakePolicy(agentType)
+		if policy.IntervalMinutes != 2 {
+			t.Errorf("%s interval = %d, want 2", agentType, policy.IntervalMinutes)
+		}
+		if policy.Message == "" {
+			t.Errorf("%s should have a default message", agentType)
+		}
+		if err := policy.Validate(); err != nil {
+			t.Errorf("%s default policy is invalid: %v", agentType, err)
+		}
+	}
+}
+
+func TestWakePolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  WakePolicy
+		wantErr bool
+	}{
+		{"empty", WakePolicy{}, false},
+		{"quiet hours", WakePolicy{QuietHours: "22:00-07:00"}, false},
+		{"conditions", WakePolicy{OnlyWhen: []string{WakeWhenIdle, WakeWhenUnreadMessages, WakeWhenPRActivity}}, false},
+		{"bad quiet hours format", WakePolicy{QuietHours: "22-07"}, true},
+		{"bad quiet hours time", WakePolicy{QuietHours: "25:00-07:00"}, true},
+		{"unknown condition", WakePolicy{OnlyWhen: []string{"sometimes"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWakePolicyInQuietHours(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 15, hour, min, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name  string
+		quiet string
+		t     time.Time
+		want  bool
+	}{
+		{"no quiet hours", "", at(3, 0), false},
+		{"inside same-day window", "12:00-13:30", at(13, 0), true},
+		{"end is exclusive", "12:00-13:30", at(13, 30), false},
+		{"before same-day window", "12:00-13:30", at(11, 59), false},
+		{"wraps midnight late", "22:00-07:00", at(23, 15), true},
+		{"wraps midnight early", "22:00-07:00", at(6, 59), true},
+		{"outside wrapping window", "22:00-07:00", at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := WakePolicy{QuietHours: tt.quiet}
+			if got := p.InQuietHours(tt.t); got != tt.want {
+				t.Errorf("InQuietHours(%s) = %v, want %v", tt.t.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWakePolicyPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+
+	if err := s.UpdateWakePolicy("nonexistent", AgentTypeWorker, WakePolicy{}); err == nil {
+		t.Error("UpdateWakePolicy() should fail for nonexistent repo")
+	}
+
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	policy := WakePolicy{
+		IntervalMinutes: 15,
+		QuietHours:      "22:00-07:00",
+		OnlyWhen:        []string{WakeWhenIdle},
+		Message:         "Anything blocking you?",
+	}
+	if err := s.UpdateWakePolicy("test-repo", AgentTypeWorker, policy); err != nil {
+		t.Fatalf("UpdateWakePolicy() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	got, err := loaded.GetWakePolicy("test-repo", AgentTypeWorker)
+	if err != nil {
+		t.Fatalf("GetWakePolicy() failed: %v", err)
+	}
+	if got.IntervalMinutes != 15 || got.QuietHours != "22:00-07:00" || len(got.OnlyWhen) != 1 || got.Message != policy.Message {
+		t.Errorf("loaded policy = %+v, want %+v", got, policy)
+	}
+
+	// Types without an entry keep their defaults
+	if got, _ := loaded.GetWakePolicy("test-repo", AgentTypeSupervisor); got.Message != DefaultWakePolicy(AgentTypeSupervisor).Message {
+		t.Errorf("supervisor policy = %+v, want defaults", got)
+	}
+
+	// The policies survive the GetAllRepos snapshot
+	if got := loaded.GetAllRepos()["test-repo"].WakePolicyFor(AgentTypeWorker); got.IntervalMinutes != 15 {
+		t.Errorf("GetAllRepos() worker policy = %+v, want interval 15", got)
+	}
+}
+
+func TestWakePolicyForAgent(t *testing.T) {
+	repo := &Repository{
+		WakePolicies: map[AgentType]WakePolicy{
+			AgentTypeGenericPersistent: {IntervalMinutes: 5, Message: "Repo policy"},
+		},
+	}
+
+	agent := Agent{Type: AgentTypeGenericPersistent}
+	if got := repo.WakePolicyForAgent(agent); got.Message != "Repo policy" {
+		t.Errorf("agent without a policy = %+v, want the repo's policy for its type", got)
+	}
+
+	agent.WakePolicy = &WakePolicy{IntervalMinutes: 30, Message: "Any new issues?"}
+	if got := repo.WakePolicyForAgent(agent); got.IntervalMinutes != 30 || got.Message != "Any new issues?" {
+		t.Errorf("agent with a policy = %+v, want its own policy", got)
+	}
+
+	// The agent's policy and definition survive a save and load
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	agent.Definition = "triager"
+	if err := s.AddAgent("test-repo", "triager", agent); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	got, _ := loaded.GetAgent("test-repo", "triager")
+	if got.Definition != "triager" || got.WakePolicy == nil || got.WakePolicy.IntervalMinutes != 30 {
+		t.Errorf("loaded agent = %+v", got)
+	}
+}
+
+func TestClaudeOptionsMerge(t *testing.T) {
+	defaults := ClaudeOptions{
+		Model: "sonnet",
+		Args:  []string{"--verbose"},
+		Env:   map[string]string{"GOFLAGS": "-mod=mod", "HTTPS_PROXY": "http://proxy:3128"},
+	}
+
+	tests := []struct {
+		name     string
+		override ClaudeOptions
+		want     ClaudeOptions
+	}{
+		{"no override", ClaudeOptions{}, defaults},
+		{
+			"override wins",
+			ClaudeOptions{Model: "opus", Args: []string{"--max-turns", "50"}, Env: map[string]string{"GOFLAGS": "-race"}},
+			ClaudeOptions{
+				Model: "opus",
+				Args:  []string{"--verbose", "--max-turns", "50"},
+				Env:   map[string]string{"GOFLAGS": "-race", "HTTPS_PROXY": "http://proxy:3128"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaults.Merge(tt.override); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Merging never modifies the defaults
+	if defaults.Env["GOFLAGS"] != "-mod=mod" || len(defaults.Args) != 1 {
+		t.Errorf("Merge() modified the receiver: %+v", defaults)
+	}
+	if got := (ClaudeOptions{}).Merge(ClaudeOptions{}); !got.IsZero() || got.Args != nil || got.Env != nil {
+		t.Errorf("merging empty options = %+v, want zero", got)
+	}
+}
+
+func TestClaudeOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"no env", nil, false},
+		{"valid names", map[string]string{"GOFLAGS": "", "_private": "x", "HTTP_PROXY2": "y"}, false},
+		{"leading digit", map[string]string{"1VAR": "x"}, true},
+		{"dash", map[string]string{"MY-VAR": "x"}, true},
+		{"empty name", map[string]string{"": "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClaudeOptions{Env: tt.env}.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClaudeOptionsPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+
+	if err := s.UpdateClaudeOptions("nonexistent", AgentTypeWorker, ClaudeOptions{Model: "opus"}); err == nil {
+		t.Error("UpdateClaudeOptions() should fail for nonexistent repo")
+	}
+
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	opts := ClaudeOptions{Model: "opus", Args: []string{"--verbose"}, Env: map[string]string{"GOFLAGS": "-mod=mod"}}
+	if err := s.UpdateClaudeOptions("test-repo", AgentTypeReview, opts); err != nil {
+		t.Fatalf("UpdateClaudeOptions() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "reviewer", Agent{Type: AgentTypeReview, Claude: &opts, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got, _ := loaded.GetClaudeOptions("test-repo", AgentTypeReview); !reflect.DeepEqual(got, opts) {
+		t.Errorf("loaded review options = %+v, want %+v", got, opts)
+	}
+	if got, _ := loaded.GetClaudeOptions("test-repo", AgentTypeWorker); !got.IsZero() {
+		t.Errorf("worker options = %+v, want none", got)
+	}
+	if agent, _ := loaded.GetAgent("test-repo", "reviewer"); agent.Claude == nil || !reflect.DeepEqual(*agent.Claude, opts) {
+		t.Errorf("loaded agent.Claude = %+v, want %+v", agent.Claude, opts)
+	}
+
+	// The options survive the GetAllRepos snapshot
+	if got := loaded.GetAllRepos()["test-repo"].ClaudeOptions[AgentTypeReview]; got.Model != "opus" {
+		t.Errorf("GetAllRepos() review options = %+v", got)
+	}
+
+	// Empty options remove the entry
+	if err := loaded.UpdateClaudeOptions("test-repo", AgentTypeReview, ClaudeOptions{}); err != nil {
+		t.Fatalf("UpdateClaudeOptions() failed: %v", err)
+	}
+	if _, exists := loaded.GetAllRepos()["test-repo"].ClaudeOptions[AgentTypeReview]; exists {
+		t.Error("empty options should remove the entry")
+	}
+}
+
+// queuedIDs returns the IDs of a task queue in order
+func queuedIDs(queue []QueuedTask) []string {
+	ids := make([]string, len(queue))
+	for i, task := range queue {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestAgentRestartHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "supervisor", Agent{Type: AgentTypeSupervisor}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	restartedAt := time.Now().Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		if err := s.RecordAgentRestart("test-repo", "supervisor", "process 123 exited", restartedAt); err != nil {
+			t.Fatalf("RecordAgentRestart() failed: %v", err)
+		}
+	}
+	if err := s.MarkAgentCrashLooping("test-repo", "supervisor", "process 456 exited"); err != nil {
+		t.Fatalf("MarkAgentCrashLooping() failed: %v", err)
+	}
+	if err := s.RecordAgentRestart("test-repo", "missing", "", restartedAt); err == nil {
+		t.Error("RecordAgentRestart() should fail for a nonexistent agent")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	agent, _ := loaded.GetAgent("test-repo", "supervisor")
+	if agent.RestartCount != 2 || !agent.LastRestart.Equal(restartedAt) || agent.LastExitReason != "process 456 exited" || !agent.CrashLooping {
+		t.Errorf("restart history = %+v", agent)
+	}
+
+	if err := s.ResetAgentRestarts("test-repo", "supervisor"); err != nil {
+		t.Fatalf("ResetAgentRestarts() failed: %v", err)
+	}
+	agent, _ = s.GetAgent("test-repo", "supervisor")
+	if agent.RestartCount != 0 || agent.CrashLooping {
+		t.Errorf("after reset: RestartCount = %d, CrashLooping = %v", agent.RestartCount, agent.CrashLooping)
+	}
+	if agent.LastExitReason == "" || agent.LastRestart.IsZero() {
+		t.Error("reset should keep the last restart and exit reason")
+	}
+}
+
+func TestTaskQueue(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+
+	if _, err := s.EnqueueTask("nonexistent", QueuedTask{ID: "task-1"}); err == nil {
+		t.Error("EnqueueTask() should fail for nonexistent repo")
+	}
+
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	for i, id := range []string{"task-a", "task-b", "task-c", "task-d"} {
+		position, err := s.EnqueueTask("test-repo", QueuedTask{ID: id, Task: "Do " + id, EnqueuedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("EnqueueTask(%s) failed: %v", id, err)
+		}
+		if position != i+1 {
+			t.Errorf("EnqueueTask(%s) position = %d, want %d", id, position, i+1)
+		}
+	}
+	if _, err := s.EnqueueTask("test-repo", QueuedTask{ID: "task-a"}); err == nil {
+		t.Error("EnqueueTask() should reject a duplicate ID")
+	}
+
+	// Reorder
+	if err := s.MoveQueuedTask("test-repo", "task-d", 1); err != nil {
+		t.Fatalf("MoveQueuedTask() failed: %v", err)
+	}
+	if err := s.MoveQueuedTask("test-repo", "task-a", 99); err != nil {
+		t.Fatalf("MoveQueuedTask() failed: %v", err)
+	}
+	queue, _ := s.GetTaskQueue("test-repo")
+	if got := strings.Join(queuedIDs(queue), ","); got != "task-d,task-b,task-c,task-a" {
+		t.Errorf("queue after moves = %s", got)
+	}
+	if err := s.MoveQueuedTask("test-repo", "task-x", 1); err == nil {
+		t.Error("MoveQueuedTask() should fail for an unknown task")
+	}
+	if err := s.MoveQueuedTask("test-repo", "task-b", 0); err == nil {
+		t.Error("MoveQueuedTask() should reject position 0")
+	}
+
+	// Cancel
+	cancelled, err := s.RemoveQueuedTask("test-repo", "task-b")
+	if err != nil {
+		t.Fatalf("RemoveQueuedTask() failed: %v", err)
+	}
+	if cancelled.Task != "Do task-b" {
+		t.Errorf("cancelled task = %+v", cancelled)
+	}
+	if _, err := s.RemoveQueuedTask("test-repo", "task-b"); err == nil {
+		t.Error("RemoveQueuedTask() should fail for a task no longer queued")
+	}
+
+	// Dequeue and requeue
+	next, ok, err := s.DequeueTask("test-repo")
+	if err != nil || !ok || next.ID != "task-d" {
+		t.Fatalf("DequeueTask() = %+v, %v, %v; want task-d", next, ok, err)
+	}
+	next.LastError = "failed to create worktree"
+	if err := s.RequeueTask("test-repo", next); err != nil {
+		t.Fatalf("RequeueTask() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	queue, _ = loaded.GetTaskQueue("test-repo")
+	if got := strings.Join(queuedIDs(queue), ","); got != "task-d,task-c,task-a" {
+		t.Errorf("loaded queue = %s", got)
+	}
+	if queue[0].LastError != "failed to create worktree" {
+		t.Errorf("requeued task LastError = %q", queue[0].LastError)
+	}
+	if got := loaded.GetAllRepos()["test-repo"].TaskQueue; len(got) != 3 {
+		t.Errorf("GetAllRepos() queue length = %d, want 3", len(got))
+	}
+
+	for range queue {
+		if _, ok, err := loaded.DequeueTask("test-repo"); !ok || err != nil {
+			t.Fatalf("DequeueTask() = %v, %v", ok, err)
+		}
+	}
+	if _, ok, err := loaded.DequeueTask("test-repo"); ok || err != nil {
+		t.Errorf("DequeueTask() on empty queue = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestTaskDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	for _, task := range []QueuedTask{
+		{ID: "task-refactor", Task: "Refactor X"},
+		{ID: "task-build", Task: "Build Y on X", After: "task-refactor"},
+		{ID: "task-docs", Task: "Document Y", After: "task-refactor"},
+	} {
+		if _, err := s.EnqueueTask("test-repo", task); err != nil {
+			t.Fatalf("EnqueueTask(%s) failed: %v", task.ID, err)
+		}
+	}
+
+	if err := s.RetargetTaskDependencies("test-repo", "task-refactor", "clever-fox"); err != nil {
+		t.Fatalf("RetargetTaskDependencies() failed: %v", err)
+	}
+	if err := s.SetQueuedTaskError("test-repo", "task-docs", "dependency clever-fox ended closed"); err != nil {
+		t.Fatalf("SetQueuedTaskError() failed: %v", err)
+	}
+	if err := s.SetQueuedTaskError("test-repo", "task-missing", "boom"); err == nil {
+		t.Error("SetQueuedTaskError() should fail for an unknown task")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	queue, _ := loaded.GetTaskQueue("test-repo")
+	if queue[0].After != "" || queue[1].After != "clever-fox" || queue[2].After != "clever-fox" {
+		t.Errorf("dependencies after retarget = %q, %q, %q", queue[0].After, queue[1].After, queue[2].After)
+	}
+	if queue[2].LastError != "dependency clever-fox ended closed" {
+		t.Errorf("LastError = %q", queue[2].LastError)
+	}
+}
+
+func TestMaxWorkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.SetMaxWorkers("test-repo", -1); err == nil {
+		t.Error("SetMaxWorkers() should reject a negative limit")
+	}
+	if err := s.SetMaxWorkers("test-repo", 3); err != nil {
+		t.Fatalf("SetMaxWorkers() failed: %v", err)
+	}
+
+	agents := map[string]Agent{
+		"supervisor": {Type: AgentTypeSupervisor},
+		"worker-1":   {Type: AgentTypeWorker},
+		"worker-2":   {Type: AgentTypeWorker},
+		"worker-3":   {Type: AgentTypeWorker, ReadyForCleanup: true},
+		"review-1":   {Type: AgentTypeReview},
+	}
+	for name, agent := range agents {
+		if err := s.AddAgent("test-repo", name, agent); err != nil {
+			t.Fatalf("AddAgent(%s) failed: %v", name, err)
+		}
+	}
+	if count, err := s.ActiveWorkerCount("test-repo"); err != nil || count != 2 {
+		t.Errorf("ActiveWorkerCount() = %d, %v; want 2", count, err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.GetAllRepos()["test-repo"].MaxWorkers; got != 3 {
+		t.Errorf("loaded MaxWorkers = %d, want 3", got)
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	repo := &Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test",
+		Agents:      make(map[string]Agent),
+	}
+	if err := s.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.SetDefaultBranch("missing", "develop"); err == nil {
+		t.Error("SetDefaultBranch() should fail for an unknown repo")
+	}
+	if err := s.SetDefaultBranch("test-repo", "develop"); err != nil {
+		t.Fatalf("SetDefaultBranch() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	got := loaded.GetAllRepos()["test-repo"]
+	if got.DefaultBranch != "develop" {
+		t.Errorf("loaded DefaultBranch = %q, want develop", got.DefaultBranch)
+	}
+
+	if base := got.BaseBranchFor(Agent{Type: AgentTypeWorker}); base != "develop" {
+		t.Errorf("BaseBranchFor() without a base = %q, want develop", base)
+	}
+	if base := got.BaseBranchFor(Agent{Type: AgentTypeWorker, BaseBranch: "release/2.3"}); base != "release/2.3" {
+		t.Errorf("BaseBranchFor() with a base = %q, want release/2.3", base)
+	}
+}
+
+func TestSetOnSave(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+
+	saves := 0
+	s.SetOnSave(func() { saves++ })
+
+	if err := s.AddRepo("test-repo", &Repository{TmuxSession: "mc-test"}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if saves != 2 {
+		t.Errorf("save hook called %d times, want 2", saves)
+	}
+
+	// Failed writes don't notify
+	s.path = filepath.Join(t.TempDir(), "missing", "dir", "state.json")
+	if err := s.Save(); err == nil {
+		t.Fatal("Save() to a missing directory should fail")
+	}
+	if saves != 2 {
+		t.Errorf("save hook called %d times after a failed save, want 2", saves)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := New(filepath.Join(dir, "snapshot.json"))
+	if err := snapshot.AddRepo("app", &Repository{TmuxSession: "mc-app", Agents: map[string]Agent{
+		"supervisor": {Type: AgentTypeSupervisor, TmuxWindow: "supervisor"},
+	}}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := snapshot.SetCurrentRepo("app"); err != nil {
+		t.Fatalf("SetCurrentRepo() failed: %v", err)
+	}
+
+	statePath := filepath.Join(dir, "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("other", &Repository{TmuxSession: "mc-other"}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	saves := 0
+	s.SetOnSave(func() { saves++ })
+
+	if err := s.Restore(snapshot.View()); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if saves != 1 {
+		t.Errorf("save hook called %d times, want 1", saves)
+	}
+	if repos := s.ListRepos(); len(repos) != 1 || repos[0] != "app" {
+		t.Errorf("ListRepos() = %v, want [app]", repos)
+	}
+	if s.GetCurrentRepo() != "app" {
+		t.Errorf("GetCurrentRepo() = %q, want app", s.GetCurrentRepo())
+	}
+
+	// The restored state is a copy, saved to the state's own path
+	if err := snapshot.AddAgent("app", "worker", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+	if _, ok := s.GetAgent("app", "worker"); ok {
+		t.Error("Restore() must copy the state, not share it")
+	}
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if _, ok := loaded.GetAgent("app", "supervisor"); !ok {
+		t.Error("restored state was not saved")
+	}
+}
+
+func TestAgentNeedsRebase(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "worker", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	if err := s.SetAgentNeedsRebase("test-repo", "worker", []string{"README.md"}); err != nil {
+		t.Fatalf("SetAgentNeedsRebase() failed: %v", err)
+	}
+	if err := s.SetAgentNeedsRebase("test-repo", "missing", []string{"README.md"}); err == nil {
+		t.Error("SetAgentNeedsRebase() should fail for a nonexistent agent")
+	}
+	if err := s.SetResolveConflicts("test-repo", true); err != nil {
+		t.Fatalf("SetResolveConflicts() failed: %v", err)
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	agent, _ := loaded.GetAgent("test-repo", "worker")
+	if !agent.NeedsRebase || len(agent.RebaseConflicts) != 1 || agent.RebaseConflicts[0] != "README.md" {
+		t.Errorf("agent = %+v, want it to need a rebase because of README.md", agent)
+	}
+	if !loaded.GetAllRepos()["test-repo"].ResolveConflicts {
+		t.Error("ResolveConflicts was not saved")
+	}
+
+	if err := s.SetAgentNeedsRebase("test-repo", "worker", nil); err != nil {
+		t.Fatalf("SetAgentNeedsRebase() failed: %v", err)
+	}
+	agent, _ = s.GetAgent("test-repo", "worker")
+	if agent.NeedsRebase || agent.RebaseConflicts != nil {
+		t.Errorf("agent = %+v after clearing, want no rebase needed", agent)
+	}
+}
+
+func TestSetAgentNotifiedOverlaps(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.AddAgent("test-repo", "worker", Agent{Type: AgentTypeWorker}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	overlaps := map[string]bool{"other": true}
+	if err := s.SetAgentNotifiedOverlaps("test-repo", "worker", overlaps); err != nil {
+		t.Fatalf("SetAgentNotifiedOverlaps() failed: %v", err)
+	}
+	overlaps["another"] = false
+	if err := s.SetAgentNotifiedOverlaps("test-repo", "missing", overlaps); err == nil {
+		t.Error("SetAgentNotifiedOverlaps() should fail for a nonexistent agent")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	agent, _ := loaded.GetAgent("test-repo", "worker")
+	if len(agent.NotifiedOverlaps) != 1 || !agent.NotifiedOverlaps["other"] {
+		t.Errorf("NotifiedOverlaps = %v, want only the conflicting overlap with other", agent.NotifiedOverlaps)
+	}
+
+	if err := s.SetAgentNotifiedOverlaps("test-repo", "worker", nil); err != nil {
+		t.Fatalf("SetAgentNotifiedOverlaps() failed: %v", err)
+	}
+	if agent, _ := s.GetAgent("test-repo", "worker"); agent.NotifiedOverlaps != nil {
+		t.Errorf("NotifiedOverlaps = %v after clearing, want none", agent.NotifiedOverlaps)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	s := New(statePath)
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	if err := s.SetSyncConflicts("test-repo", "abc123", []string{"go.mod"}); err != nil {
+		t.Fatalf("SetSyncConflicts() failed: %v", err)
+	}
+	if err := s.SetSyncConflicts("missing", "abc123", nil); err == nil {
+		t.Error("SetSyncConflicts() should fail for a nonexistent repo")
+	}
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	status, _ := loaded.GetDualCIStatus("test-repo")
+	if status == nil || status.SyncConflictSHA != "abc123" || len(status.SyncConflicts) != 1 || status.SyncConflicts[0] != "go.mod" {
+		t.Errorf("dual CI status = %+v, want the conflict with abc123 in go.mod", status)
+	}
+
+	// A successful sync clears the conflict
+	if err := s.UpdateSyncTime("test-repo", time.Now(), "def456"); err != nil {
+		t.Fatalf("UpdateSyncTime() failed: %v", err)
+	}
+	status, _ = s.GetDualCIStatus("test-repo")
+	if status.SyncConflictSHA != "" || status.SyncConflicts != nil || status.LastSyncSHA != "def456" {
+		t.Errorf("dual CI status = %+v after a sync, want no conflict", status)
+	}
+}
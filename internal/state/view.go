@@ -0,0 +1,59 @@
+package state
+
+// View is a read-only copy of the state. It can't be saved, so commands that
+// only inspect state never race the daemon's writes.
+type View struct {
+	s *State
+}
+
+// LoadView reads the state file at path without taking its lock. Older state
+// files are migrated in memory only; the file is left for the daemon to
+// migrate.
+func LoadView(path string) (*View, error) {
+	s, _, err := load(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return &View{s: s}, nil
+}
+
+// View returns a read-only view of the state
+func (s *State) View() *View {
+	return &View{s: s}
+}
+
+// SchemaVersion returns the schema version of the state
+func (v *View) SchemaVersion() int {
+	return v.s.SchemaVersion
+}
+
+// ListRepos returns all repository names
+func (v *View) ListRepos() []string {
+	return v.s.ListRepos()
+}
+
+// GetRepo returns a copy of a repository by name
+func (v *View) GetRepo(name string) (*Repository, bool) {
+	repo, exists := v.s.GetAllRepos()[name]
+	return repo, exists
+}
+
+// GetAllRepos returns a copy of all repositories
+func (v *View) GetAllRepos() map[string]*Repository {
+	return v.s.GetAllRepos()
+}
+
+// GetAgent returns an agent by name
+func (v *View) GetAgent(repoName, agentName string) (Agent, bool) {
+	return v.s.GetAgent(repoName, agentName)
+}
+
+// ListAgents returns the agent names in a repository
+func (v *View) ListAgents(repoName string) ([]string, error) {
+	return v.s.ListAgents(repoName)
+}
+
+// GetCurrentRepo returns the default repository, empty if none is set
+func (v *View) GetCurrentRepo() string {
+	return v.s.GetCurrentRepo()
+}
@@ -0,0 +1,65 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ChangedFiles returns the files changed on branch since it diverged from
+// base, sorted. Only committed changes are included.
+func (m *Manager) ChangedFiles(branch, base string) ([]string, error) {
+	output, err := m.runGit("diff", "--name-only", "--no-renames", fmt.Sprintf("%s...%s", base, branch))
+	if err != nil {
+		return nil, err
+	}
+	return splitFileList(string(output)), nil
+}
+
+// PredictConflicts returns the files that would conflict if branch1 and
+// branch2 were merged, sorted, without touching any worktree. It needs git
+// 2.38 or later for merge-tree --write-tree.
+func (m *Manager) PredictConflicts(branch1, branch2 string) ([]string, error) {
+	cmd := exec.Command("git", "merge-tree", "--write-tree", "--name-only", "--no-messages", branch1, branch2)
+	cmd.Dir = m.repoPath
+	output, err := cmd.Output()
+	if err == nil {
+		return nil, nil
+	}
+
+	// Exit status 1 with a tree on stdout means the merge has conflicts; the
+	// tree is followed by the conflicted files. Bad refs also exit with 1,
+	// but print nothing on stdout.
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || len(output) == 0 {
+		stderr := ""
+		if exitErr != nil {
+			stderr = string(exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("git merge-tree: %w\nOutput: %s", err, stderr)
+	}
+	lines := strings.SplitN(string(output), "\n", 2)
+	if len(lines) < 2 {
+		return nil, nil
+	}
+	return splitFileList(lines[1]), nil
+}
+
+// splitFileList parses newline-separated file names into a sorted list
+// without blanks or duplicates
+func splitFileList(output string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		files = append(files, line)
+	}
+	sort.Strings(files)
+	return files
+}
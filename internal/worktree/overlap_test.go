@@ -0,0 +1,102 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// commitFilesOnBranch checks out branch (creating it from main if needed), writes
+// files and commits them
+func commitFilesOnBranch(t *testing.T, repoPath, branch string, files map[string]string) {
+	t.Helper()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", branch).Run(); err != nil {
+		run("checkout", "-q", "-b", branch, "main")
+	} else {
+		run("checkout", "-q", branch)
+	}
+	for name, content := range files {
+		path := filepath.Join(repoPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		run("add", name)
+	}
+	run("commit", "-q", "-m", "Change on "+branch)
+	run("checkout", "-q", "main")
+}
+
+func TestChangedFiles(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	m := NewManager(repoPath)
+
+	commitFilesOnBranch(t, repoPath, "work/a", map[string]string{"pkg/a.go": "a\n", "README.md": "# A\n"})
+	// Changes on main since the branch diverged don't count
+	commitFilesOnBranch(t, repoPath, "main", map[string]string{"main.go": "main\n"})
+
+	files, err := m.ChangedFiles("work/a", "main")
+	if err != nil {
+		t.Fatalf("ChangedFiles() failed: %v", err)
+	}
+	if want := []string{"README.md", "pkg/a.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, want)
+	}
+
+	files, err = m.ChangedFiles("main", "main")
+	if err != nil || len(files) != 0 {
+		t.Errorf("ChangedFiles() of an unchanged branch = %v, %v, want none", files, err)
+	}
+
+	if _, err := m.ChangedFiles("missing", "main"); err == nil {
+		t.Error("ChangedFiles() of a missing branch should fail")
+	}
+}
+
+func TestPredictConflicts(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	m := NewManager(repoPath)
+
+	commitFilesOnBranch(t, repoPath, "work/a", map[string]string{"README.md": "# A\n", "a.go": "a\n"})
+	commitFilesOnBranch(t, repoPath, "work/b", map[string]string{"README.md": "# B\n", "b.go": "b\n"})
+	commitFilesOnBranch(t, repoPath, "work/c", map[string]string{"c.go": "c\n"})
+
+	conflicts, err := m.PredictConflicts("work/a", "work/b")
+	if err != nil {
+		t.Fatalf("PredictConflicts() failed: %v", err)
+	}
+	if want := []string{"README.md"}; !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("PredictConflicts() = %v, want %v", conflicts, want)
+	}
+
+	conflicts, err = m.PredictConflicts("work/a", "work/c")
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("PredictConflicts() of independent branches = %v, %v, want none", conflicts, err)
+	}
+
+	if _, err := m.PredictConflicts("work/a", "missing"); err == nil {
+		t.Error("PredictConflicts() with a missing branch should fail")
+	}
+
+	// Nothing was checked out or changed
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = repoPath
+	if output, _ := cmd.Output(); len(output) != 0 {
+		t.Errorf("PredictConflicts() changed the worktree:\n%s", output)
+	}
+}
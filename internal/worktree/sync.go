@@ -0,0 +1,136 @@
+package worktree
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SyncResult contains the result of syncing a branch with upstream
+type SyncResult struct {
+	// SHA is the synced commit, if there were no conflicts
+	SHA           string
+	HasConflicts  bool
+	ConflictFiles []string
+	// ConflictCommits are the upstream commits ("<short sha> <subject>")
+	// touching the conflicting files
+	ConflictCommits []string
+}
+
+// SyncBranch brings start, such as "origin/main", up to date with upstream,
+// such as "upstream/main", by merging or rebasing ("merge" or "rebase") in a
+// temporary detached worktree at path, which must not exist or be empty.
+// The worktree is removed afterwards, and a conflicting merge or rebase is
+// aborted first, so the repository is never left half-merged. The synced
+// commit isn't on any branch; push it with PushCommit.
+func (m *Manager) SyncBranch(path, start, upstream, strategy string) (SyncResult, error) {
+	var result SyncResult
+
+	var args, abort []string
+	switch strategy {
+	case "merge":
+		args = []string{"merge", "--no-edit", upstream}
+		abort = []string{"merge", "--abort"}
+	case "rebase":
+		args = []string{"rebase", upstream}
+		abort = []string{"rebase", "--abort"}
+	default:
+		return result, fmt.Errorf("unknown sync strategy %q (must be merge or rebase)", strategy)
+	}
+
+	if _, err := m.runGit("worktree", "add", "--detach", path, start); err != nil {
+		return result, fmt.Errorf("failed to create sync worktree: %w", err)
+	}
+	defer m.Remove(path, true)
+
+	cmd := exec.Command("git", append(m.commitIdentity(), args...)...)
+	cmd.Dir = path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		cmd = exec.Command("git", "diff", "--name-only", "--diff-filter=U")
+		cmd.Dir = path
+		conflictOutput, _ := cmd.Output()
+
+		abortCmd := exec.Command("git", abort...)
+		abortCmd.Dir = path
+		abortCmd.Run()
+
+		conflictFiles := strings.Fields(string(conflictOutput))
+		if len(conflictFiles) == 0 {
+			return result, fmt.Errorf("%s failed: %w\nOutput: %s", strategy, err, output)
+		}
+		result.HasConflicts = true
+		result.ConflictFiles = conflictFiles
+		result.ConflictCommits = upstreamCommits(path, upstream, conflictFiles)
+		return result, nil
+	}
+
+	cmd = exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return result, fmt.Errorf("failed to get synced commit: %w", err)
+	}
+	result.SHA = strings.TrimSpace(string(output))
+	return result, nil
+}
+
+// commitIdentity returns git options to commit as multiclaude if the
+// repository has no identity configured, so syncs work from a bare daemon
+func (m *Manager) commitIdentity() []string {
+	_, nameErr := m.runGit("config", "user.name")
+	_, emailErr := m.runGit("config", "user.email")
+	if nameErr == nil && emailErr == nil {
+		return nil
+	}
+	return []string{"-c", "user.name=multiclaude", "-c", "user.email=multiclaude@localhost"}
+}
+
+// PushCommit pushes sha to branch on remote. If expected is set, branch is
+// replaced only if it's still at expected, which allows a non-fast-forward
+// update such as a rebase.
+func (m *Manager) PushCommit(remote, sha, branch, expected string) error {
+	args := []string{"push"}
+	if expected != "" {
+		args = append(args, fmt.Sprintf("--force-with-lease=%s:%s", branch, expected))
+	}
+	args = append(args, remote, fmt.Sprintf("%s:refs/heads/%s", sha, branch))
+	_, err := m.runGit(args...)
+	return err
+}
+
+// ResolveCommit returns the SHA of the commit ref points to
+func (m *Manager) ResolveCommit(ref string) (string, error) {
+	output, err := m.runGit("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsAncestor reports whether ancestor is reachable from commit
+func (m *Manager) IsAncestor(ancestor, commit string) bool {
+	_, err := m.runGit("merge-base", "--is-ancestor", ancestor, commit)
+	return err == nil
+}
+
+// AdvanceBranch moves the local branch to commit to, if it's still at from
+// or to fast-forwards it. If the branch is checked out in the repository,
+// the checkout follows and local changes are kept; a change that would be
+// overwritten makes it fail instead. It reports whether the branch moved.
+func (m *Manager) AdvanceBranch(branch, from, to string) (bool, error) {
+	current, err := m.ResolveCommit("refs/heads/" + branch)
+	if err != nil || current == to || (current != from && !m.IsAncestor(current, to)) {
+		return false, nil
+	}
+
+	if checkedOut, err := GetCurrentBranch(m.repoPath); err == nil && checkedOut == branch {
+		if _, err := m.runGit("reset", "--keep", to); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if _, err := m.runGit("update-ref", "refs/heads/"+branch, to, current); err != nil {
+		return false, err
+	}
+	return true, nil
+}
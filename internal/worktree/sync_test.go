@@ -0,0 +1,132 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyncBranch(t *testing.T) {
+	run := func(t *testing.T, dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	// setup returns a repo whose origin/main has a fork-only commit and whose
+	// upstream-main branch has an upstream-only commit writing upstreamFile
+	setup := func(t *testing.T, upstreamFile string) (string, *Manager) {
+		t.Helper()
+		repoPath, cleanup := createTestRepoWithRemote(t)
+		t.Cleanup(cleanup)
+		commitFilesOnBranch(t, repoPath, "upstream-main", map[string]string{upstreamFile: "upstream\n"})
+		commitFilesOnBranch(t, repoPath, "main", map[string]string{"fork.txt": "fork\n"})
+		run(t, repoPath, "push", "-q", "origin", "main")
+		run(t, repoPath, "fetch", "-q", "origin")
+		return repoPath, NewManager(repoPath)
+	}
+
+	for _, strategy := range []string{"merge", "rebase"} {
+		t.Run(strategy, func(t *testing.T) {
+			repoPath, m := setup(t, "upstream.txt")
+			forkSHA := run(t, repoPath, "rev-parse", "origin/main")
+			syncPath := filepath.Join(t.TempDir(), "sync")
+
+			result, err := m.SyncBranch(syncPath, "origin/main", "upstream-main", strategy)
+			if err != nil || result.HasConflicts || result.SHA == "" {
+				t.Fatalf("SyncBranch() = %+v, %v, want a synced commit", result, err)
+			}
+			if _, err := os.Stat(syncPath); !os.IsNotExist(err) {
+				t.Error("sync worktree should be removed")
+			}
+			for _, file := range []string{"fork.txt", "upstream.txt"} {
+				run(t, repoPath, "cat-file", "-e", result.SHA+":"+file)
+			}
+
+			expected := ""
+			if strategy == "rebase" {
+				expected = forkSHA
+			}
+			if err := m.PushCommit("origin", result.SHA, "main", expected); err != nil {
+				t.Fatalf("PushCommit() failed: %v", err)
+			}
+			run(t, repoPath, "fetch", "-q", "origin")
+			if got := run(t, repoPath, "rev-parse", "origin/main"); got != result.SHA {
+				t.Errorf("origin/main = %s, want %s", got, result.SHA)
+			}
+
+			// The checked-out main follows the synced fork, but only from
+			// where it was
+			if moved, err := m.AdvanceBranch("main", result.SHA, forkSHA); err != nil || moved {
+				t.Errorf("AdvanceBranch() from the wrong commit = %v, %v, want no move", moved, err)
+			}
+			if moved, err := m.AdvanceBranch("main", forkSHA, result.SHA); err != nil || !moved {
+				t.Fatalf("AdvanceBranch() = %v, %v, want a move", moved, err)
+			}
+			if got := run(t, repoPath, "rev-parse", "HEAD"); got != result.SHA {
+				t.Errorf("HEAD = %s, want %s", got, result.SHA)
+			}
+			if _, err := os.Stat(filepath.Join(repoPath, "upstream.txt")); err != nil {
+				t.Errorf("checkout wasn't updated: %v", err)
+			}
+		})
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		repoPath, m := setup(t, "fork.txt")
+		syncPath := filepath.Join(t.TempDir(), "sync")
+
+		result, err := m.SyncBranch(syncPath, "origin/main", "upstream-main", "merge")
+		if err != nil {
+			t.Fatalf("SyncBranch() failed: %v", err)
+		}
+		if !result.HasConflicts || result.SHA != "" || !reflect.DeepEqual(result.ConflictFiles, []string{"fork.txt"}) {
+			t.Errorf("SyncBranch() = %+v, want a conflict in fork.txt", result)
+		}
+		if len(result.ConflictCommits) != 1 || !strings.Contains(result.ConflictCommits[0], "Change on upstream-main") {
+			t.Errorf("ConflictCommits = %v, want the upstream commit", result.ConflictCommits)
+		}
+		if _, err := os.Stat(syncPath); !os.IsNotExist(err) {
+			t.Error("sync worktree should be removed after a conflict")
+		}
+		if status := run(t, repoPath, "status", "--porcelain"); status != "" {
+			t.Errorf("repo has changes after a conflicting sync: %s", status)
+		}
+	})
+
+	t.Run("no identity", func(t *testing.T) {
+		repoPath, m := setup(t, "upstream.txt")
+		run(t, repoPath, "config", "--unset", "user.name")
+		run(t, repoPath, "config", "--unset", "user.email")
+		run(t, repoPath, "config", "user.useConfigOnly", "true")
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("XDG_CONFIG_HOME", home)
+		t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+		for _, strategy := range []string{"merge", "rebase"} {
+			result, err := m.SyncBranch(filepath.Join(t.TempDir(), "sync"), "origin/main", "upstream-main", strategy)
+			if err != nil || result.SHA == "" {
+				t.Fatalf("SyncBranch(%s) without an identity = %+v, %v, want a synced commit", strategy, result, err)
+			}
+			if got := run(t, repoPath, "log", "-1", "--format=%ce", result.SHA); got != "multiclaude@localhost" {
+				t.Errorf("%s committer = %q, want multiclaude@localhost", strategy, got)
+			}
+		}
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		_, m := setup(t, "upstream.txt")
+		if _, err := m.SyncBranch(filepath.Join(t.TempDir(), "sync"), "origin/main", "upstream-main", "squash"); err == nil {
+			t.Error("SyncBranch() with an unknown strategy should fail")
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -423,11 +424,81 @@ func (m *Manager) FetchRemote(remote string) error {
 	return err
 }
 
+// ValidateBranchName returns an error if name isn't a valid git branch name
+func ValidateBranchName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	if err := exec.Command("git", "check-ref-format", "--branch", name).Run(); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// ResolveStartPoint returns the ref to start a branch based on base from:
+// the remote's copy of base if it exists, otherwise the local branch
+func (m *Manager) ResolveStartPoint(remote, base string) (string, error) {
+	for _, ref := range []string{fmt.Sprintf("%s/%s", remote, base), base} {
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		cmd.Dir = m.repoPath
+		if err := cmd.Run(); err == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("base branch %q not found on %s or locally", base, remote)
+}
+
+// UpstreamBranchRef returns the remote-tracking ref for branch on remote,
+// falling back to the remote's default branch if the remote has no such branch
+func (m *Manager) UpstreamBranchRef(remote, branch string) (string, error) {
+	if branch != "" {
+		ref := fmt.Sprintf("%s/%s", remote, branch)
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+ref)
+		cmd.Dir = m.repoPath
+		if err := cmd.Run(); err == nil {
+			return ref, nil
+		}
+	}
+	defaultBranch, err := m.GetDefaultBranch(remote)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", remote, defaultBranch), nil
+}
+
+// SetBranchBase records the branch that branch was created from, so merged
+// branch cleanup checks it against the right base
+func (m *Manager) SetBranchBase(branch, base string) error {
+	_, err := m.runGit("config", fmt.Sprintf("branch.%s.multiclaudeBase", branch), base)
+	return err
+}
+
+// BranchBase returns the base recorded for branch by SetBranchBase, or "" if none
+func (m *Manager) BranchBase(branch string) string {
+	cmd := exec.Command("git", "config", "--get", fmt.Sprintf("branch.%s.multiclaudeBase", branch))
+	cmd.Dir = m.repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 // FindMergedUpstreamBranches finds local branches that have been merged into the upstream default branch.
 // It fetches from the upstream remote first to ensure we have the latest state.
 // The branchPrefix filters which branches to check (e.g., "multiclaude/" or "workspace/").
 // Returns a list of branch names that can be safely deleted.
 func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, error) {
+	return m.FindMergedBranches(branchPrefix, "")
+}
+
+// FindMergedBranches finds local branches that have been merged upstream.
+// Each branch is checked against the base recorded with SetBranchBase, or
+// defaultBranch if it has none; an empty defaultBranch is detected from the
+// upstream remote. It fetches from the upstream remote first to ensure we
+// have the latest state. The branchPrefix filters which branches to check.
+// Returns a list of branch names that can be safely deleted.
+func (m *Manager) FindMergedBranches(branchPrefix, defaultBranch string) ([]string, error) {
 	// Get the upstream remote name
 	remote, err := m.GetUpstreamRemote()
 	if err != nil {
@@ -440,37 +511,68 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 	}
 
 	// Get the default branch name
-	defaultBranch, err := m.GetDefaultBranch(remote)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get default branch: %w", err)
+	if defaultBranch == "" {
+		defaultBranch, err = m.GetDefaultBranch(remote)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default branch: %w", err)
+		}
 	}
 
-	// Get branches merged into upstream's default branch
-	upstreamRef := fmt.Sprintf("%s/%s", remote, defaultBranch)
-	cmd := exec.Command("git", "branch", "--merged", upstreamRef, "--format=%(refname:short)")
-	cmd.Dir = m.repoPath
-	output, err := cmd.Output()
+	// Group the candidate branches by the base they were created from
+	output, err := m.runGit("for-each-ref", "--format=%(refname:short)", "refs/heads/")
 	if err != nil {
-		return nil, fmt.Errorf("failed to list merged branches: %w", err)
+		return nil, fmt.Errorf("failed to list branches: %w", err)
 	}
-
-	// Filter branches by prefix
-	var mergedBranches []string
+	byBase := make(map[string][]string)
+	var bases []string
 	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
 		branch := strings.TrimSpace(line)
 		if branch == "" {
 			continue
 		}
 		// Skip the default branches themselves
-		if branch == "main" || branch == "master" {
+		if branch == defaultBranch || branch == "main" || branch == "master" {
 			continue
 		}
 		// Only include branches matching the prefix
 		if branchPrefix != "" && !strings.HasPrefix(branch, branchPrefix) {
 			continue
 		}
-		mergedBranches = append(mergedBranches, branch)
+		base := m.BranchBase(branch)
+		if base == "" {
+			base = defaultBranch
+		}
+		if _, ok := byBase[base]; !ok {
+			bases = append(bases, base)
+		}
+		byBase[base] = append(byBase[base], branch)
+	}
+
+	var mergedBranches []string
+	for _, base := range bases {
+		// Get branches merged into upstream's copy of the base
+		upstreamRef := fmt.Sprintf("%s/%s", remote, base)
+		cmd := exec.Command("git", "branch", "--merged", upstreamRef, "--format=%(refname:short)")
+		cmd.Dir = m.repoPath
+		output, err := cmd.Output()
+		if err != nil {
+			if base == defaultBranch {
+				return nil, fmt.Errorf("failed to list merged branches: %w", err)
+			}
+			// The base may have been deleted upstream; leave its branches alone
+			continue
+		}
+		merged := make(map[string]bool)
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			merged[strings.TrimSpace(line)] = true
+		}
+		for _, branch := range byBase[base] {
+			if merged[branch] && branch != base {
+				mergedBranches = append(mergedBranches, branch)
+			}
+		}
 	}
+	sort.Strings(mergedBranches)
 
 	return mergedBranches, nil
 }
@@ -613,6 +715,7 @@ type WorktreeState struct {
 	HasUncommitted bool
 	CommitsBehind  int  // Number of commits behind remote main
 	CommitsAhead   int  // Number of commits ahead of remote main
+	UpToDate       bool // True if the branch has every commit on remote main
 	CanRefresh     bool // True if worktree is in a state that can be safely refreshed
 	RefreshReason  string
 }
@@ -706,6 +809,7 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 
 	// If not behind, no need to refresh
 	if state.CommitsBehind == 0 {
+		state.UpToDate = true
 		state.CanRefresh = false
 		state.RefreshReason = "already up to date"
 	}
@@ -734,6 +838,10 @@ type RefreshResult struct {
 	Error          error
 	Skipped        bool
 	SkipReason     string
+
+	// ConflictCommits are the upstream commits ("<short sha> <subject>")
+	// touching the conflicting files that the branch doesn't have yet
+	ConflictCommits []string
 }
 
 // RefreshWorktree syncs a worktree with the latest changes from the main branch.
@@ -849,6 +957,8 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 			abortCmd := exec.Command("git", "rebase", "--abort")
 			abortCmd.Dir = worktreePath
 			abortCmd.Run()
+
+			result.ConflictCommits = upstreamCommits(worktreePath, fmt.Sprintf("%s/%s", remote, mainBranch), conflictFiles)
 		}
 		result.Error = fmt.Errorf("rebase failed: %w\nOutput: %s", rebaseErr, rebaseOutput)
 
@@ -884,6 +994,26 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	return result
 }
 
+// upstreamCommits lists the commits on upstream, but not HEAD, that touch
+// files, newest first, as "<short sha> <subject>"
+func upstreamCommits(worktreePath, upstream string, files []string) []string {
+	args := append([]string{"log", "--format=%h %s", "HEAD.." + upstream, "--"}, files...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = worktreePath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits
+}
+
 // RefreshWorktreeWithDefaults refreshes a worktree using the repository's default remote and branch
 func (m *Manager) RefreshWorktreeWithDefaults(worktreePath string) RefreshResult {
 	// Get the upstream remote
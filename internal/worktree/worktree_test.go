@@ -1968,6 +1968,45 @@ func TestRefreshWorktree(t *testing.T) {
 		}
 	})
 
+	t.Run("reports conflicts and the upstream commits behind them", func(t *testing.T) {
+		repoPath, cleanup := createTestRepo(t)
+		defer cleanup()
+
+		manager := NewManager(repoPath)
+
+		cmd := exec.Command("git", "remote", "add", "origin", repoPath)
+		cmd.Dir = repoPath
+		cmd.Run()
+
+		commitFilesOnBranch(t, repoPath, "feature/conflict", map[string]string{"README.md": "# Feature\n"})
+		wtPath := filepath.Join(repoPath, "wt-conflict")
+		if err := manager.Create(wtPath, "feature/conflict"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+		defer manager.Remove(wtPath, true)
+
+		// Only the commit touching README.md caused the conflict
+		commitFilesOnBranch(t, repoPath, "main", map[string]string{"README.md": "# Upstream\n"})
+		commitFilesOnBranch(t, repoPath, "main", map[string]string{"other.txt": "other\n"})
+
+		result := RefreshWorktree(wtPath, "origin", "main")
+
+		if !result.HasConflicts {
+			t.Fatalf("Expected conflicts, got error: %v", result.Error)
+		}
+		if len(result.ConflictFiles) != 1 || result.ConflictFiles[0] != "README.md" {
+			t.Errorf("ConflictFiles = %v, want [README.md]", result.ConflictFiles)
+		}
+		if len(result.ConflictCommits) != 1 || !strings.HasSuffix(result.ConflictCommits[0], " Change on main") {
+			t.Errorf("ConflictCommits = %v, want the commit that changed README.md", result.ConflictCommits)
+		}
+
+		// The rebase was aborted
+		if branch, err := GetCurrentBranch(wtPath); err != nil || branch != "feature/conflict" {
+			t.Errorf("worktree is on %q (%v) after the conflict, want feature/conflict", branch, err)
+		}
+	})
+
 	t.Run("handles fetch error", func(t *testing.T) {
 		repoPath, cleanup := createTestRepo(t)
 		defer cleanup()
@@ -2086,6 +2125,9 @@ func TestGetWorktreeState(t *testing.T) {
 		if state.IsMidMerge {
 			t.Error("Should not be mid-merge")
 		}
+		if !state.UpToDate {
+			t.Error("Should be up to date with main")
+		}
 	})
 
 	t.Run("detects detached HEAD", func(t *testing.T) {
@@ -2989,3 +3031,136 @@ func TestCleanupMergedBranchesWithRemoteDeletion(t *testing.T) {
 		}
 	})
 }
+
+// commitOnBranch adds a commit with a new file to branch, creating the branch from HEAD if needed
+func commitOnBranch(t *testing.T, repoPath, branch, file string) {
+	t.Helper()
+
+	for _, args := range [][]string{
+		{"checkout", "-B", branch},
+		{"commit", "--allow-empty", "-m", "Add " + file},
+		{"checkout", "main"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+}
+
+func TestResolveStartPoint(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	createBranch(t, repoPath, "develop")
+
+	// Without a remote the local branch is used
+	ref, err := manager.ResolveStartPoint("origin", "develop")
+	if err != nil || ref != "develop" {
+		t.Errorf("ResolveStartPoint(develop) = %q, %v, want develop", ref, err)
+	}
+
+	// Once the remote has it, the remote's copy wins
+	cmd := exec.Command("git", "remote", "add", "origin", repoPath)
+	cmd.Dir = repoPath
+	cmd.Run()
+	cmd = exec.Command("git", "fetch", "origin")
+	cmd.Dir = repoPath
+	cmd.Run()
+
+	ref, err = manager.ResolveStartPoint("origin", "develop")
+	if err != nil || ref != "origin/develop" {
+		t.Errorf("ResolveStartPoint(develop) = %q, %v, want origin/develop", ref, err)
+	}
+
+	if _, err := manager.ResolveStartPoint("origin", "release/9.9"); err == nil {
+		t.Error("ResolveStartPoint should fail for a branch that doesn't exist")
+	}
+}
+
+func TestUpstreamBranchRef(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createBranch(t, repoPath, "develop")
+	cmd := exec.Command("git", "remote", "add", "upstream", repoPath)
+	cmd.Dir = repoPath
+	cmd.Run()
+	cmd = exec.Command("git", "fetch", "upstream")
+	cmd.Dir = repoPath
+	cmd.Run()
+
+	manager := NewManager(repoPath)
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"develop", "upstream/develop"},
+		{"missing", "upstream/main"},
+		{"", "upstream/main"},
+	}
+	for _, tt := range tests {
+		got, err := manager.UpstreamBranchRef("upstream", tt.branch)
+		if err != nil || got != tt.want {
+			t.Errorf("UpstreamBranchRef(%q) = %q, %v, want %q", tt.branch, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindMergedBranchesUsesRecordedBase(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+
+	// release/2.3 is ahead of main; a worker branch based on it is merged
+	// into the release branch but not into main
+	commitOnBranch(t, repoPath, "release/2.3", "release.txt")
+	cmd := exec.Command("git", "branch", "multiclaude/release-fix", "release/2.3")
+	cmd.Dir = repoPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	cmd = exec.Command("git", "remote", "add", "origin", repoPath)
+	cmd.Dir = repoPath
+	cmd.Run()
+
+	merged, err := manager.FindMergedBranches("multiclaude/", "main")
+	if err != nil {
+		t.Fatalf("FindMergedBranches failed: %v", err)
+	}
+	if len(merged) != 0 {
+		t.Errorf("without a recorded base, merged = %v, want none", merged)
+	}
+
+	if err := manager.SetBranchBase("multiclaude/release-fix", "release/2.3"); err != nil {
+		t.Fatalf("SetBranchBase failed: %v", err)
+	}
+	if base := manager.BranchBase("multiclaude/release-fix"); base != "release/2.3" {
+		t.Errorf("BranchBase() = %q, want release/2.3", base)
+	}
+
+	merged, err = manager.FindMergedBranches("multiclaude/", "main")
+	if err != nil {
+		t.Fatalf("FindMergedBranches failed: %v", err)
+	}
+	if len(merged) != 1 || merged[0] != "multiclaude/release-fix" {
+		t.Errorf("with base release/2.3, merged = %v, want [multiclaude/release-fix]", merged)
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	for _, name := range []string{"main", "develop", "release/2.3"} {
+		if err := ValidateBranchName(name); err != nil {
+			t.Errorf("ValidateBranchName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "-rf", "two words", "bad..name"} {
+		if err := ValidateBranchName(name); err == nil {
+			t.Errorf("ValidateBranchName(%q) should fail", name)
+		}
+	}
+}
@@ -40,6 +40,8 @@ import (
 	"crypto/rand"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -203,6 +205,17 @@ type Config struct {
 	// This is useful for showing restart instructions or other information.
 	// If empty, no MOTD is displayed.
 	MOTD string
+
+	// Model selects the model Claude uses, e.g. "opus" or a full model name.
+	// This is passed via --model. If empty, Claude's default model is used.
+	Model string
+
+	// ExtraArgs are additional CLI arguments, appended after the standard flags.
+	ExtraArgs []string
+
+	// Env sets environment variables for the Claude process only,
+	// e.g. GOFLAGS or proxy settings.
+	Env map[string]string
 }
 
 // StartResult contains information about a started Claude instance.
@@ -301,6 +314,22 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 	// Claude Code only reads credentials from ~/.claude/.credentials.json
 	// regardless of CLAUDE_CONFIG_DIR setting. Slash commands go in ~/.claude/commands/.
 
+	// Set environment variables for this process only. env works the same
+	// in every shell, unlike the VAR=value prefix.
+	if len(cfg.Env) > 0 {
+		names := make([]string, 0, len(cfg.Env))
+		for name := range cfg.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		cmd += "env"
+		for _, name := range names {
+			cmd += " " + shellQuote(name+"="+cfg.Env[name])
+		}
+		cmd += " "
+	}
+
 	cmd += r.BinaryPath
 
 	// Add session ID or resume
@@ -320,9 +349,44 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 		cmd += fmt.Sprintf(" --append-system-prompt-file %s", cfg.SystemPromptFile)
 	}
 
+	// Add model
+	if cfg.Model != "" {
+		cmd += " --model " + shellQuote(cfg.Model)
+	}
+
+	// Add extra arguments
+	for _, arg := range cfg.ExtraArgs {
+		cmd += " " + shellQuote(arg)
+	}
+
 	return cmd
 }
 
+// BuildCommand returns the shell command that Start sends to the terminal
+// to launch Claude with the given session ID and configuration.
+func (r *Runner) BuildCommand(sessionID string, cfg Config) string {
+	return r.buildCommand(sessionID, cfg)
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything other than
+// characters that are safe unquoted
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-.,:/=@%+", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // SendMessage sends a message to a running Claude instance.
 // This properly handles multiline messages using paste-buffer and sends
 // text + Enter atomically to prevent race conditions.
@@ -528,6 +528,32 @@ func TestBuildCommand(t *testing.T) {
 				"CLAUDE_CONFIG_DIR",
 			},
 		},
+		{
+			name: "with model and extra args",
+			config: Config{
+				SessionID: "test-session",
+				Model:     "opus",
+				ExtraArgs: []string{"--verbose", "--add-dir", "/path/with space"},
+			},
+			contains: []string{
+				"--model opus",
+				"--verbose --add-dir '/path/with space'",
+			},
+			excludes: []string{
+				"env ",
+			},
+		},
+		{
+			name: "with env",
+			config: Config{
+				SessionID: "test-session",
+				WorkDir:   "/path/to/workdir",
+				Env:       map[string]string{"GOFLAGS": "-mod=mod", "HTTPS_PROXY": "http://proxy:8080", "NOTE": "it's here"},
+			},
+			contains: []string{
+				`cd "/path/to/workdir" && env GOFLAGS=-mod=mod HTTPS_PROXY=http://proxy:8080 'NOTE=it'\''s here' /path/to/claude --session-id`,
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -549,6 +575,16 @@ func TestBuildCommand(t *testing.T) {
 	}
 }
 
+func TestBuildCommandExported(t *testing.T) {
+	runner := NewRunner(WithBinaryPath("claude"))
+	cfg := Config{SystemPromptFile: "/tmp/prompt.md", Model: "sonnet"}
+
+	want := "claude --session-id abc --dangerously-skip-permissions --append-system-prompt-file /tmp/prompt.md --model sonnet"
+	if got := runner.BuildCommand("abc", cfg); got != want {
+		t.Errorf("BuildCommand() = %q, want %q", got, want)
+	}
+}
+
 func TestBuildCommandWithoutSkipPermissions(t *testing.T) {
 	runner := NewRunner(
 		WithBinaryPath("claude"),
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+)
+
+// ProtocolVersion is the version of the socket API this package speaks. It
+// is bumped when a command or field changes in a way older clients or
+// daemons can't handle.
+const ProtocolVersion = 1
+
+// MinProtocolVersion is the oldest protocol version this package can talk to
+const MinProtocolVersion = 1
+
+// Client talks to the multiclaude daemon over its Unix socket. Each call
+// opens its own connection, so a Client is safe for concurrent use.
+type Client struct {
+	socketPath string
+}
+
+// New returns a client for the daemon listening on socketPath, usually
+// ~/.multiclaude/daemon.sock
+func New(socketPath string) *Client {
+	return &Client{socketPath: socketPath}
+}
+
+// ConnectionError is returned when a request couldn't be delivered or its
+// response couldn't be read, most often because the daemon isn't running
+type ConnectionError struct {
+	Command string
+	Err     error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// RequestError is returned when the daemon rejects a request. Its message is
+// the daemon's error text.
+type RequestError struct {
+	Command string
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// VersionError is returned by [Client.Ping] when the daemon speaks a
+// protocol version this client can't use, or vice versa
+type VersionError struct {
+	// DaemonVersion is the daemon's protocol version; 0 for daemons that
+	// predate the handshake
+	DaemonVersion int
+	// DaemonMinVersion is the oldest client protocol version the daemon supports
+	DaemonMinVersion int
+}
+
+func (e *VersionError) Error() string {
+	if e.DaemonVersion < MinProtocolVersion {
+		return fmt.Sprintf("daemon speaks protocol version %d, but this client needs at least version %d - restart the daemon to upgrade it", e.DaemonVersion, MinProtocolVersion)
+	}
+	return fmt.Sprintf("this client speaks protocol version %d, but the daemon needs at least version %d - upgrade the client", ProtocolVersion, e.DaemonMinVersion)
+}
+
+// request is a command sent to the daemon
+type request struct {
+	Command string      `json:"command"`
+	Args    interface{} `json:"args,omitempty"`
+}
+
+// response is the daemon's reply; Data is decoded by the caller
+type response struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Call sends a command with the given arguments and decodes the response
+// data into result, which may be nil. The typed methods are built on it; it
+// is exported for commands this package doesn't wrap yet.
+func (c *Client) Call(command string, args interface{}, result interface{}) error {
+	conn, dec, err := c.send(command, args)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := readResponse(command, dec)
+	if err != nil {
+		return err
+	}
+	return decodeData(command, resp.Data, result)
+}
+
+// send connects to the daemon and writes a request
+func (c *Client) send(command string, args interface{}) (net.Conn, *json.Decoder, error) {
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return nil, nil, &ConnectionError{Command: command, Err: fmt.Errorf("failed to connect to daemon: %w", err)}
+	}
+	if err := json.NewEncoder(conn).Encode(request{Command: command, Args: args}); err != nil {
+		conn.Close()
+		return nil, nil, &ConnectionError{Command: command, Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	return conn, json.NewDecoder(conn), nil
+}
+
+// readResponse reads a response, turning a rejected request into a RequestError
+func readResponse(command string, dec *json.Decoder) (*response, error) {
+	var resp response
+	if err := dec.Decode(&resp); err != nil {
+		return nil, &ConnectionError{Command: command, Err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	if !resp.Success {
+		return nil, &RequestError{Command: command, Message: resp.Error}
+	}
+	return &resp, nil
+}
+
+// decodeData decodes response data into result, if both are present
+func decodeData(command string, data json.RawMessage, result interface{}) error {
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", command, err)
+	}
+	return nil
+}
+
+// PingResult is the daemon's side of the protocol handshake
+type PingResult struct {
+	// ProtocolVersion is the daemon's protocol version
+	ProtocolVersion int `json:"protocol_version"`
+	// MinProtocolVersion is the oldest client protocol version the daemon supports
+	MinProtocolVersion int `json:"min_protocol_version"`
+}
+
+// Ping checks that the daemon is running and that it speaks a compatible
+// protocol version. It returns a [*VersionError] if the versions don't
+// overlap, including for daemons that predate the handshake.
+func (c *Client) Ping() (*PingResult, error) {
+	var data json.RawMessage
+	if err := c.Call("ping", map[string]int{"protocol_version": ProtocolVersion}, &data); err != nil {
+		return nil, err
+	}
+
+	// Daemons without the handshake just answer "pong"
+	var result PingResult
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode ping response: %w", err)
+		}
+	}
+
+	if result.ProtocolVersion < MinProtocolVersion || ProtocolVersion < result.MinProtocolVersion {
+		return &result, &VersionError{DaemonVersion: result.ProtocolVersion, DaemonMinVersion: result.MinProtocolVersion}
+	}
+	return &result, nil
+}
+
+// Subscription reads events streamed by the daemon after [Client.Subscribe]
+type Subscription struct {
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+// Subscribe opens a stream of events matching req. Events that happen after
+// the call returns are delivered in order by [Subscription.Next].
+func (c *Client) Subscribe(req SubscribeRequest) (*Subscription, error) {
+	conn, dec, err := c.send("subscribe", req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readResponse("subscribe", dec); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Subscription{conn: conn, dec: dec}, nil
+}
+
+// Next blocks until the next event arrives. It returns io.EOF when the
+// daemon ends the stream, which happens when it stops or when the
+// subscriber falls too far behind.
+func (s *Subscription) Next() (Event, error) {
+	var e Event
+	if err := s.dec.Decode(&e); err != nil {
+		if err == io.EOF {
+			return e, io.EOF
+		}
+		return e, fmt.Errorf("failed to read event: %w", err)
+	}
+	return e, nil
+}
+
+// Close ends the subscription. A Next blocked in another goroutine returns an error.
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,250 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// fakeDaemon serves handler on a socket in a temp dir and returns a client for it
+func fakeDaemon(t *testing.T, handler socket.HandlerFunc) (*Client, *socket.Server) {
+	t.Helper()
+
+	sockPath := filepath.Join(t.TempDir(), "daemon.sock")
+	server := socket.NewServer(sockPath, handler)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+	go server.Serve()
+
+	return New(sockPath), server
+}
+
+// recordingDaemon answers every request with data and records the last one
+func recordingDaemon(t *testing.T, data interface{}) (*Client, *socket.Request) {
+	t.Helper()
+
+	var last socket.Request
+	c, _ := fakeDaemon(t, func(req socket.Request) socket.Response {
+		last = req
+		return socket.Response{Success: true, Data: data}
+	})
+	return c, &last
+}
+
+func TestCallErrors(t *testing.T) {
+	c, _ := fakeDaemon(t, func(req socket.Request) socket.Response {
+		return socket.Response{Success: false, Error: "repository 'nope' not found"}
+	})
+
+	_, err := c.ListAgents("nope")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("ListAgents() error = %v (%T), want *RequestError", err, err)
+	}
+	if reqErr.Command != "list_agents" || err.Error() != "repository 'nope' not found" {
+		t.Errorf("RequestError = %+v, want list_agents with the daemon's message", reqErr)
+	}
+
+	_, err = New(filepath.Join(t.TempDir(), "missing.sock")).Status()
+	var connErr *ConnectionError
+	if !errors.As(err, &connErr) {
+		t.Fatalf("Status() error = %v (%T), want *ConnectionError", err, err)
+	}
+}
+
+func TestRequestArgs(t *testing.T) {
+	c, last := recordingDaemon(t, nil)
+
+	tests := []struct {
+		name    string
+		call    func() error
+		command string
+		args    map[string]interface{}
+	}{
+		{
+			name: "add agent omits unset fields",
+			call: func() error {
+				return c.AddAgent(AddAgentRequest{Repo: "app", Agent: "w1", Type: AgentTypeWorker, WorktreePath: "/wt", TmuxWindow: "w1"})
+			},
+			command: "add_agent",
+			args:    map[string]interface{}{"repo": "app", "agent": "w1", "type": "worker", "worktree_path": "/wt", "tmux_window": "w1"},
+		},
+		{
+			name: "add repo sends explicit false",
+			call: func() error {
+				return c.AddRepo(AddRepoRequest{Name: "app", GithubURL: "https://github.com/o/app", TmuxSession: "mc-app", MergeQueueEnabled: Bool(false)})
+			},
+			command: "add_repo",
+			args:    map[string]interface{}{"name": "app", "github_url": "https://github.com/o/app", "tmux_session": "mc-app", "mq_enabled": false},
+		},
+		{
+			name: "update repo config distinguishes unset and cleared",
+			call: func() error {
+				return c.UpdateRepoConfig(UpdateRepoConfigRequest{
+					Name:         "app",
+					MaxWorkers:   Int(0),
+					WakePolicies: map[string]WakePolicyUpdate{"worker": {OnlyWhen: []string{}}},
+					ClaudeOptions: map[string]*ClaudeOptionsUpdate{
+						"worker":     {Model: String("opus")},
+						"supervisor": nil,
+					},
+				})
+			},
+			command: "update_repo_config",
+			args: map[string]interface{}{
+				"name":          "app",
+				"max_workers":   float64(0),
+				"wake_policies": map[string]interface{}{"worker": map[string]interface{}{"only_when": []interface{}{}}},
+				"claude_options": map[string]interface{}{
+					"worker":     map[string]interface{}{"model": "opus", "args": nil, "env": nil},
+					"supervisor": nil,
+				},
+			},
+		},
+		{
+			name: "events query joins types",
+			call: func() error {
+				_, err := c.QueryEvents(EventQuery{Repo: "app", Types: []string{"pr_created", "ci_failed"}, Since: time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)})
+				return err
+			},
+			command: "events_query",
+			args:    map[string]interface{}{"repo": "app", "type": "pr_created,ci_failed", "since": "2026-01-02T03:04:05.000000006Z"},
+		},
+		{
+			name:    "no args",
+			call:    c.TriggerCleanup,
+			command: "trigger_cleanup",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			if last.Command != tt.command {
+				t.Errorf("command = %q, want %q", last.Command, tt.command)
+			}
+			if !reflect.DeepEqual(last.Args, tt.args) {
+				t.Errorf("args = %#v, want %#v", last.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestResponseDecoding(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c, last := recordingDaemon(t, []map[string]interface{}{
+		{
+			"name":             "w1",
+			"type":             "worker",
+			"worktree_path":    "/wt/w1",
+			"tmux_window":      "w1",
+			"task":             "Fix the bug",
+			"created_at":       created,
+			"status":           "running",
+			"messages_pending": 2,
+			"activity":         "stuck",
+			"idle_seconds":     2400,
+			"claude":           map[string]interface{}{"model": "opus"},
+		},
+	})
+
+	agents, err := c.ListAgentsDetailed("app")
+	if err != nil {
+		t.Fatalf("ListAgentsDetailed() failed: %v", err)
+	}
+	if last.Args["rich"] != true {
+		t.Errorf("ListAgentsDetailed() args = %v, want rich", last.Args)
+	}
+	want := Agent{
+		Name:            "w1",
+		Type:            AgentTypeWorker,
+		WorktreePath:    "/wt/w1",
+		TmuxWindow:      "w1",
+		Task:            "Fix the bug",
+		CreatedAt:       created,
+		Claude:          &ClaudeOptions{Model: "opus"},
+		Status:          "running",
+		MessagesPending: 2,
+		Activity:        "stuck",
+		IdleSeconds:     2400,
+	}
+	if len(agents) != 1 || !reflect.DeepEqual(agents[0], want) {
+		t.Errorf("ListAgentsDetailed() = %+v, want [%+v]", agents, want)
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        interface{}
+		wantVersion int
+		wantErr     bool
+	}{
+		{"compatible", map[string]int{"protocol_version": ProtocolVersion, "min_protocol_version": MinProtocolVersion}, ProtocolVersion, false},
+		{"daemon before the handshake", "pong", 0, true},
+		{"daemon needs a newer client", map[string]int{"protocol_version": ProtocolVersion + 1, "min_protocol_version": ProtocolVersion + 1}, ProtocolVersion + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, last := recordingDaemon(t, tt.data)
+
+			result, err := c.Ping()
+			if last.Args["protocol_version"] != float64(ProtocolVersion) {
+				t.Errorf("Ping() args = %v, want protocol_version %d", last.Args, ProtocolVersion)
+			}
+			var versionErr *VersionError
+			if got := errors.As(err, &versionErr); got != tt.wantErr {
+				t.Fatalf("Ping() error = %v, want VersionError: %v", err, tt.wantErr)
+			}
+			if result == nil || result.ProtocolVersion != tt.wantVersion {
+				t.Errorf("Ping() = %+v, want protocol version %d", result, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c, server := fakeDaemon(t, func(req socket.Request) socket.Response {
+		return socket.Response{Success: false, Error: "unknown command"}
+	})
+	server.HandleStream("subscribe", func(req socket.Request, stream *socket.Stream) {
+		if req.Args["repo"] != "app" || !reflect.DeepEqual(req.Args["type"], []interface{}{"agent_started"}) {
+			stream.Send(socket.Response{Success: false, Error: "unexpected filter"})
+			return
+		}
+		stream.Send(socket.Response{Success: true})
+		stream.Send(map[string]interface{}{"type": "agent_started", "repo_name": "app", "agent_name": "w1"})
+	})
+
+	sub, err := c.Subscribe(SubscribeRequest{Repo: "app", Types: []string{"agent_started"}})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	defer sub.Close()
+
+	e, err := sub.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if e.Type != "agent_started" || e.AgentName != "w1" {
+		t.Errorf("Next() = %+v, want agent_started for w1", e)
+	}
+	if _, err := sub.Next(); err != io.EOF {
+		t.Errorf("Next() after the stream ends = %v, want io.EOF", err)
+	}
+
+	// A rejected subscription is a RequestError
+	_, err = c.Subscribe(SubscribeRequest{Repo: "other"})
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Errorf("Subscribe() error = %v, want *RequestError", err)
+	}
+}
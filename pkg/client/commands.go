@@ -0,0 +1,308 @@
+package client
+
+import (
+	"strings"
+	"time"
+)
+
+// Status returns the daemon's status
+func (c *Client) Status() (*Status, error) {
+	var status Status
+	if err := c.Call("status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Stop asks the daemon to shut down
+func (c *Client) Stop() error {
+	return c.Call("stop", nil, nil)
+}
+
+// ListRepos returns the names of the tracked repositories
+func (c *Client) ListRepos() ([]string, error) {
+	var names []string
+	if err := c.Call("list_repos", nil, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// ListReposDetailed returns the tracked repositories with agent counts and
+// tmux session health
+func (c *Client) ListReposDetailed() ([]Repo, error) {
+	var repos []Repo
+	if err := c.Call("list_repos", map[string]bool{"rich": true}, &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// AddRepo registers a repository
+func (c *Client) AddRepo(req AddRepoRequest) error {
+	return c.Call("add_repo", req, nil)
+}
+
+// RemoveRepo unregisters a repository. Its agents, worktrees and tmux
+// session are left to the caller.
+func (c *Client) RemoveRepo(name string) error {
+	return c.Call("remove_repo", map[string]string{"name": name}, nil)
+}
+
+// AddAgent registers an agent
+func (c *Client) AddAgent(req AddAgentRequest) error {
+	return c.Call("add_agent", req, nil)
+}
+
+// RemoveAgent unregisters an agent
+func (c *Client) RemoveAgent(repo, agent string) error {
+	return c.Call("remove_agent", map[string]string{"repo": repo, "agent": agent}, nil)
+}
+
+// ListAgents returns a repository's agents
+func (c *Client) ListAgents(repo string) ([]Agent, error) {
+	return c.listAgents(repo, false)
+}
+
+// ListAgentsDetailed returns a repository's agents with their status,
+// branch, message counts and output activity
+func (c *Client) ListAgentsDetailed(repo string) ([]Agent, error) {
+	return c.listAgents(repo, true)
+}
+
+func (c *Client) listAgents(repo string, rich bool) ([]Agent, error) {
+	args := struct {
+		Repo string `json:"repo"`
+		Rich bool   `json:"rich,omitempty"`
+	}{repo, rich}
+	var agents []Agent
+	if err := c.Call("list_agents", args, &agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// CompleteAgent marks an agent as done so the daemon cleans it up and
+// notifies the supervisor
+func (c *Client) CompleteAgent(req CompleteAgentRequest) error {
+	return c.Call("complete_agent", req, nil)
+}
+
+// RestartAgent restarts an agent's Claude process in its tmux window
+func (c *Client) RestartAgent(req RestartAgentRequest) (*RestartAgentResult, error) {
+	var result RestartAgentResult
+	if err := c.Call("restart_agent", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TriggerCleanup runs the daemon's health check, cleaning up dead agents
+func (c *Client) TriggerCleanup() error {
+	return c.Call("trigger_cleanup", nil, nil)
+}
+
+// RepairState removes agents whose tmux windows are gone and cleans up
+// orphaned worktrees and message directories
+func (c *Client) RepairState() (*RepairResult, error) {
+	var result RepairResult
+	if err := c.Call("repair_state", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetRepoConfig returns a repository's configuration
+func (c *Client) GetRepoConfig(name string) (*RepoConfig, error) {
+	var config RepoConfig
+	if err := c.Call("get_repo_config", map[string]string{"name": name}, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// UpdateRepoConfig changes a repository's configuration
+func (c *Client) UpdateRepoConfig(req UpdateRepoConfigRequest) error {
+	return c.Call("update_repo_config", req, nil)
+}
+
+// GetHookConfig returns the event hook configuration
+func (c *Client) GetHookConfig() (*HookConfig, error) {
+	var config HookConfig
+	if err := c.Call("get_hook_config", nil, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// UpdateHookConfig replaces the event hook configuration
+func (c *Client) UpdateHookConfig(config HookConfig) error {
+	return c.Call("update_hook_config", config, nil)
+}
+
+// SetCurrentRepo sets the repository commands use by default
+func (c *Client) SetCurrentRepo(name string) error {
+	return c.Call("set_current_repo", map[string]string{"name": name}, nil)
+}
+
+// GetCurrentRepo returns the default repository. It fails if none is set.
+func (c *Client) GetCurrentRepo() (string, error) {
+	var name string
+	if err := c.Call("get_current_repo", nil, &name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// ClearCurrentRepo unsets the default repository
+func (c *Client) ClearCurrentRepo() error {
+	return c.Call("clear_current_repo", nil, nil)
+}
+
+// RouteMessages delivers pending messages now instead of at the next poll
+func (c *Client) RouteMessages() error {
+	return c.Call("route_messages", nil, nil)
+}
+
+// ResolveRecipients expands a recipient group (@all, @workers, @persistent)
+// or name glob into the sorted names of the agents it addresses in a
+// repository, leaving out from and the workspace
+func (c *Client) ResolveRecipients(repo, to, from string) ([]string, error) {
+	args := struct {
+		Repo string `json:"repo"`
+		To   string `json:"to"`
+		From string `json:"from,omitempty"`
+	}{repo, to, from}
+	var recipients []string
+	if err := c.Call("resolve_recipients", args, &recipients); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// TaskHistory returns a repository's most recent completed tasks, newest
+// first. A limit of 0 uses the daemon's default of 10.
+func (c *Client) TaskHistory(repo string, limit int) ([]TaskHistoryEntry, error) {
+	args := struct {
+		Repo  string `json:"repo"`
+		Limit int    `json:"limit,omitempty"`
+	}{repo, limit}
+	var history []TaskHistoryEntry
+	if err := c.Call("task_history", args, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// QueryEvents returns events from the daemon's journal, oldest first
+func (c *Client) QueryEvents(query EventQuery) ([]Event, error) {
+	args := struct {
+		Repo  string `json:"repo,omitempty"`
+		Agent string `json:"agent,omitempty"`
+		Type  string `json:"type,omitempty"`
+		Since string `json:"since,omitempty"`
+		Limit int    `json:"limit,omitempty"`
+	}{
+		Repo:  query.Repo,
+		Agent: query.Agent,
+		Type:  strings.Join(query.Types, ","),
+		Limit: query.Limit,
+	}
+	if !query.Since.IsZero() {
+		args.Since = query.Since.Format(time.RFC3339Nano)
+	}
+
+	var evts []Event
+	if err := c.Call("events_query", args, &evts); err != nil {
+		return nil, err
+	}
+	return evts, nil
+}
+
+// SpawnAgent starts an agent from a definition or prompt
+func (c *Client) SpawnAgent(req SpawnAgentRequest) (*SpawnAgentResult, error) {
+	var result SpawnAgentResult
+	if err := c.Call("spawn_agent", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateWorker creates a worker right away, or queues it if the repository
+// is at its worker limit (see CreatedWorker.Queued)
+func (c *Client) CreateWorker(req CreateWorkerRequest) (*CreatedWorker, error) {
+	var worker CreatedWorker
+	if err := c.Call("create_worker", req, &worker); err != nil {
+		return nil, err
+	}
+	return &worker, nil
+}
+
+// EnqueueTask queues a worker task. It starts right away if the repository
+// has a free worker slot.
+func (c *Client) EnqueueTask(req EnqueueTaskRequest) (*QueuedTask, error) {
+	var task QueuedTask
+	if err := c.Call("enqueue_task", req, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTaskQueue returns a repository's queued tasks
+func (c *Client) ListTaskQueue(repo string) (*TaskQueue, error) {
+	var queue TaskQueue
+	if err := c.Call("list_task_queue", map[string]string{"repo": repo}, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+// MoveQueuedTask moves a queued task to a position in the queue, 1 being next
+func (c *Client) MoveQueuedTask(repo, id string, position int) error {
+	args := struct {
+		Repo     string `json:"repo"`
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+	}{repo, id, position}
+	return c.Call("move_queued_task", args, nil)
+}
+
+// CancelQueuedTask removes a task from the queue and returns it
+func (c *Client) CancelQueuedTask(repo, id string) (*QueuedTask, error) {
+	var task QueuedTask
+	if err := c.Call("cancel_queued_task", map[string]string{"repo": repo, "id": id}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// RecordUpstreamSync records that a repository's default branch was synced
+// with upstream at commit sha
+func (c *Client) RecordUpstreamSync(repo, sha string) error {
+	return c.Call("record_upstream_sync", map[string]string{"repo": repo, "sha": sha}, nil)
+}
+
+// SyncUpstream syncs a repository's default branch with upstream and pushes
+// it to the fork. strategy is "merge" or "rebase"; empty uses the
+// repository's sync strategy, or merge.
+func (c *Client) SyncUpstream(repo, strategy string) (*UpstreamSyncResult, error) {
+	args := map[string]string{"repo": repo}
+	if strategy != "" {
+		args["strategy"] = strategy
+	}
+	var result UpstreamSyncResult
+	if err := c.Call("sync_upstream", args, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RestoreSnapshot replaces the daemon's state and message directories with
+// the snapshot id's, after snapshotting the current ones
+func (c *Client) RestoreSnapshot(id string) (*RestoreSnapshotResult, error) {
+	var result RestoreSnapshotResult
+	if err := c.Call("restore_snapshot", map[string]string{"id": id}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,82 @@
+// Package client is a typed Go client for the multiclaude daemon's socket API.
+//
+// The daemon listens on a Unix socket (usually ~/.multiclaude/daemon.sock)
+// for newline-delimited JSON requests. This package wraps every command with
+// request and response structs, so tools built on it are checked against the
+// protocol at compile time instead of building argument maps by hand. The
+// multiclaude CLI itself uses it.
+//
+// # Installation
+//
+//	go get github.com/dlorenc/multiclaude/pkg/client
+//
+// # Example Usage
+//
+//	package main
+//
+//	import (
+//	    "fmt"
+//	    "log"
+//	    "os"
+//	    "path/filepath"
+//
+//	    "github.com/dlorenc/multiclaude/pkg/client"
+//	)
+//
+//	func main() {
+//	    home, _ := os.UserHomeDir()
+//	    c := client.New(filepath.Join(home, ".multiclaude", "daemon.sock"))
+//
+//	    // Check the daemon is running and speaks a compatible protocol
+//	    if _, err := c.Ping(); err != nil {
+//	        log.Fatal(err)
+//	    }
+//
+//	    agents, err := c.ListAgentsDetailed("my-repo")
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    for _, agent := range agents {
+//	        fmt.Printf("%s (%s): %s\n", agent.Name, agent.Type, agent.Status)
+//	    }
+//	}
+//
+// # Errors
+//
+// Methods return a [*ConnectionError] when the daemon can't be reached and a
+// [*RequestError] carrying the daemon's message when it rejects a request.
+// Use errors.As to tell them apart.
+//
+// # Protocol Versions
+//
+// [Client.Ping] exchanges protocol versions with the daemon and returns a
+// [*VersionError] if they are incompatible, for example when a long-running
+// daemon is older than the client. [ProtocolVersion] is bumped when a command
+// or field changes incompatibly; adding optional fields doesn't bump it.
+//
+// # Optional Fields
+//
+// Request fields that distinguish "unset" from a zero value are pointers; use
+// [Bool], [Int] and [String] to set them. In [UpdateRepoConfigRequest], nil
+// fields are left unchanged and empty, non-nil lists or maps clear a value.
+//
+// # Events
+//
+// [Client.QueryEvents] reads the daemon's event journal, and [Client.Subscribe]
+// streams events as they happen:
+//
+//	sub, err := c.Subscribe(client.SubscribeRequest{Repo: "my-repo"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer sub.Close()
+//	for {
+//	    e, err := sub.Next()
+//	    if err != nil {
+//	        break // io.EOF when the daemon ends the stream
+//	    }
+//	    fmt.Println(e.Type, e.AgentName)
+//	}
+//
+// Commands without a typed method can be sent with [Client.Call].
+package client
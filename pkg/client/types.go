@@ -0,0 +1,432 @@
+package client
+
+import "time"
+
+// Bool returns a pointer to v, for optional request fields
+func Bool(v bool) *bool { return &v }
+
+// Int returns a pointer to v, for optional request fields
+func Int(v int) *int { return &v }
+
+// String returns a pointer to v, for optional request fields
+func String(v string) *string { return &v }
+
+// Agent types
+const (
+	AgentTypeSupervisor        = "supervisor"
+	AgentTypeWorker            = "worker"
+	AgentTypeMergeQueue        = "merge-queue"
+	AgentTypeWorkspace         = "workspace"
+	AgentTypeReview            = "review"
+	AgentTypeGenericPersistent = "generic-persistent"
+)
+
+// Status is the daemon's status
+type Status struct {
+	Running    bool   `json:"running"`
+	PID        int    `json:"pid"`
+	Repos      int    `json:"repos"`
+	Agents     int    `json:"agents"`
+	SocketPath string `json:"socket_path"`
+}
+
+// Repo summarizes a tracked repository
+type Repo struct {
+	Name           string `json:"name"`
+	GithubURL      string `json:"github_url"`
+	TmuxSession    string `json:"tmux_session"`
+	TotalAgents    int    `json:"total_agents"`
+	WorkerCount    int    `json:"worker_count"`
+	SessionHealthy bool   `json:"session_healthy"`
+}
+
+// AddRepoRequest registers a cloned repository with the daemon
+type AddRepoRequest struct {
+	Name        string `json:"name"`
+	GithubURL   string `json:"github_url"`
+	TmuxSession string `json:"tmux_session"`
+
+	// MergeQueueEnabled defaults to true
+	MergeQueueEnabled *bool `json:"mq_enabled,omitempty"`
+	// MergeQueueTrackMode is "all" (the default), "author" or "assigned"
+	MergeQueueTrackMode string `json:"mq_track_mode,omitempty"`
+
+	// Fork settings, used when UpstreamURL is set
+	UpstreamURL    string `json:"upstream_url,omitempty"`
+	UpstreamRemote string `json:"upstream_remote,omitempty"`
+	ForkRemote     string `json:"fork_remote,omitempty"`
+	SyncEnabled    *bool  `json:"sync_enabled,omitempty"`
+	SyncInterval   int    `json:"sync_interval,omitempty"`
+
+	// DefaultBranch is the branch workers start from by default
+	DefaultBranch string `json:"default_branch,omitempty"`
+}
+
+// ClaudeOptions are the Claude CLI options an agent is started with
+type ClaudeOptions struct {
+	Model string            `json:"model,omitempty"`
+	Args  []string          `json:"args,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+// IsZero returns true if no options are set
+func (o ClaudeOptions) IsZero() bool {
+	return o.Model == "" && len(o.Args) == 0 && len(o.Env) == 0
+}
+
+// AddAgentRequest registers an agent the caller has started
+type AddAgentRequest struct {
+	Repo         string `json:"repo"`
+	Agent        string `json:"agent"`
+	Type         string `json:"type"`
+	WorktreePath string `json:"worktree_path"`
+	TmuxWindow   string `json:"tmux_window"`
+
+	// SessionID is generated by the daemon if empty
+	SessionID string `json:"session_id,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Task      string `json:"task,omitempty"`
+	// BaseBranch is the branch a worker works against; empty means the repo's default
+	BaseBranch string `json:"base_branch,omitempty"`
+	// Claude is remembered so restarts use the same options
+	Claude *ClaudeOptions `json:"claude,omitempty"`
+}
+
+// Agent describes an agent. The fields under "Detailed" are only set by
+// [Client.ListAgentsDetailed], and the activity fields only once the daemon
+// has sampled the agent's output.
+type Agent struct {
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	WorktreePath string         `json:"worktree_path"`
+	TmuxWindow   string         `json:"tmux_window"`
+	Task         string         `json:"task"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Definition   string         `json:"definition,omitempty"`
+	BaseBranch   string         `json:"base_branch,omitempty"`
+	Claude       *ClaudeOptions `json:"claude,omitempty"`
+
+	// Restart history, set once the agent has been restarted
+	RestartCount   int       `json:"restart_count,omitempty"`
+	LastRestart    time.Time `json:"last_restart"`
+	LastExitReason string    `json:"last_exit_reason,omitempty"`
+	CrashLooping   bool      `json:"crash_looping,omitempty"`
+
+	// Set while the daemon can't sync a worker's branch with its base
+	// because of conflicts in RebaseConflicts
+	NeedsRebase     bool     `json:"needs_rebase,omitempty"`
+	RebaseConflicts []string `json:"rebase_conflicts,omitempty"`
+
+	// Detailed: "running", "stopped", "completed", "crash-looping",
+	// "needs-rebase" or "unknown"
+	Status          string `json:"status,omitempty"`
+	Branch          string `json:"branch,omitempty"`
+	MessagesTotal   int    `json:"messages_total,omitempty"`
+	MessagesPending int    `json:"messages_pending,omitempty"`
+
+	// Detailed: "active", "idle" or "stuck", empty if not sampled yet
+	Activity          string    `json:"activity,omitempty"`
+	IdleSeconds       int       `json:"idle_seconds,omitempty"`
+	LastOutputAt      time.Time `json:"last_output_at"`
+	OutputGrowthBytes int64     `json:"output_growth_bytes,omitempty"`
+
+	// Detailed: files this worker and other active workers both changed
+	Overlaps []WorkerOverlap `json:"overlaps,omitempty"`
+}
+
+// WorkerOverlap is a set of files two workers both changed since their base
+type WorkerOverlap struct {
+	// Worker is the other worker
+	Worker string   `json:"worker"`
+	Files  []string `json:"files"`
+	// Conflicts are the files predicted to conflict if both branches merge
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// CompleteAgentRequest marks an agent's work as done
+type CompleteAgentRequest struct {
+	Repo          string `json:"repo"`
+	Agent         string `json:"agent"`
+	Summary       string `json:"summary,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// RestartAgentRequest restarts an agent's Claude process
+type RestartAgentRequest struct {
+	Repo  string `json:"repo"`
+	Agent string `json:"agent"`
+	// Force restarts an agent that is still running
+	Force bool `json:"force,omitempty"`
+	// Reset clears the restart history, required for crash-looping agents
+	Reset bool `json:"reset,omitempty"`
+}
+
+// RestartAgentResult is the outcome of a restart
+type RestartAgentResult struct {
+	Repo    string `json:"repo"`
+	Agent   string `json:"agent"`
+	PID     int    `json:"pid"`
+	Message string `json:"message"`
+}
+
+// RepairResult is the outcome of a state repair
+type RepairResult struct {
+	AgentsRemoved int `json:"agents_removed"`
+	IssuesFixed   int `json:"issues_fixed"`
+}
+
+// WakePolicy controls how often the daemon nudges an agent type
+type WakePolicy struct {
+	IntervalMinutes int      `json:"interval_minutes"`
+	QuietHours      string   `json:"quiet_hours"`
+	OnlyWhen        []string `json:"only_when"`
+	Message         string   `json:"message"`
+}
+
+// RepoConfig is a repository's configuration. WakePolicies and
+// ClaudeOptions are keyed by agent type.
+type RepoConfig struct {
+	MergeQueueEnabled   bool                     `json:"mq_enabled"`
+	MergeQueueTrackMode string                   `json:"mq_track_mode"`
+	IdleMinutes         int                      `json:"idle_minutes"`
+	StuckMinutes        int                      `json:"stuck_minutes"`
+	EscalateStuck       bool                     `json:"escalate_stuck"`
+	WakePolicies        map[string]WakePolicy    `json:"wake_policies"`
+	ClaudeOptions       map[string]ClaudeOptions `json:"claude_options"`
+	MaxWorkers          int                      `json:"max_workers"`
+	DefaultBranch       string                   `json:"default_branch"`
+	// ResolveConflicts spawns workers to rebase completed workers' conflicting
+	// branches and to merge upstream syncs that conflict
+	ResolveConflicts bool `json:"resolve_conflicts"`
+	// SyncStrategy is how the daemon syncs a fork with upstream: "merge",
+	// "rebase", or empty if it doesn't
+	SyncStrategy string `json:"sync_strategy"`
+}
+
+// UpdateRepoConfigRequest changes a repository's configuration. Nil fields
+// are left unchanged.
+type UpdateRepoConfigRequest struct {
+	Name string `json:"name"`
+
+	MergeQueueEnabled   *bool   `json:"mq_enabled,omitempty"`
+	MergeQueueTrackMode *string `json:"mq_track_mode,omitempty"`
+	IdleMinutes         *int    `json:"idle_minutes,omitempty"`
+	StuckMinutes        *int    `json:"stuck_minutes,omitempty"`
+	EscalateStuck       *bool   `json:"escalate_stuck,omitempty"`
+	// MaxWorkers limits concurrent workers; 0 removes the limit
+	MaxWorkers       *int    `json:"max_workers,omitempty"`
+	DefaultBranch    *string `json:"default_branch,omitempty"`
+	ResolveConflicts *bool   `json:"resolve_conflicts,omitempty"`
+	// SyncStrategy sets the upstream sync strategy; "" turns automatic sync off
+	SyncStrategy *string `json:"sync_strategy,omitempty"`
+
+	// WakePolicies updates the policies of the given agent types
+	WakePolicies map[string]WakePolicyUpdate `json:"wake_policies,omitempty"`
+	// ClaudeOptions updates the options of the given agent types; a nil
+	// entry clears a type's options
+	ClaudeOptions map[string]*ClaudeOptionsUpdate `json:"claude_options,omitempty"`
+}
+
+// WakePolicyUpdate changes a wake policy. Nil fields are left unchanged; an
+// empty, non-nil OnlyWhen clears the conditions.
+type WakePolicyUpdate struct {
+	IntervalMinutes *int     `json:"interval_minutes,omitempty"`
+	QuietHours      *string  `json:"quiet_hours,omitempty"`
+	OnlyWhen        []string `json:"only_when"`
+	Message         *string  `json:"message,omitempty"`
+}
+
+// ClaudeOptionsUpdate changes an agent type's Claude options. Nil fields are
+// left unchanged; an empty, non-nil Args or Env clears it.
+type ClaudeOptionsUpdate struct {
+	Model *string           `json:"model,omitempty"`
+	Args  []string          `json:"args"`
+	Env   map[string]string `json:"env"`
+}
+
+// HookConfig holds the commands run for events. Each field is a shell
+// command; OnEvent runs for every event.
+type HookConfig struct {
+	OnEvent         string `json:"on_event,omitempty"`
+	OnPRCreated     string `json:"on_pr_created,omitempty"`
+	OnAgentIdle     string `json:"on_agent_idle,omitempty"`
+	OnMergeComplete string `json:"on_merge_complete,omitempty"`
+	OnAgentStarted  string `json:"on_agent_started,omitempty"`
+	OnAgentStopped  string `json:"on_agent_stopped,omitempty"`
+	OnTaskAssigned  string `json:"on_task_assigned,omitempty"`
+	OnCIFailed      string `json:"on_ci_failed,omitempty"`
+	OnWorkerStuck   string `json:"on_worker_stuck,omitempty"`
+	OnMessageSent   string `json:"on_message_sent,omitempty"`
+}
+
+// Task statuses in the task history
+const (
+	TaskStatusOpen    = "open"
+	TaskStatusMerged  = "merged"
+	TaskStatusClosed  = "closed"
+	TaskStatusNoPR    = "no-pr"
+	TaskStatusFailed  = "failed"
+	TaskStatusUnknown = "unknown"
+)
+
+// TaskHistoryEntry is a completed worker task
+type TaskHistoryEntry struct {
+	Name          string    `json:"name"`
+	Task          string    `json:"task"`
+	Branch        string    `json:"branch"`
+	PRURL         string    `json:"pr_url"`
+	PRNumber      int       `json:"pr_number"`
+	Status        string    `json:"status"`
+	Summary       string    `json:"summary"`
+	FailureReason string    `json:"failure_reason"`
+	CreatedAt     time.Time `json:"created_at"`
+	CompletedAt   time.Time `json:"completed_at"`
+
+	// Set by the daemon's PR tracking
+	CIStatus     string `json:"ci_status"`     // "passing", "failing", "pending", or empty
+	ReviewStatus string `json:"review_status"` // PR review decision, e.g. "APPROVED"
+}
+
+// Event is a lifecycle or state change event
+type Event struct {
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	RepoName  string                 `json:"repo_name,omitempty"`
+	AgentName string                 `json:"agent_name,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// EventQuery selects events from the daemon's journal. Zero fields match
+// everything.
+type EventQuery struct {
+	Repo  string
+	Agent string
+	Types []string
+	// Since only matches events at or after this time
+	Since time.Time
+	// Limit only returns the most recent matching events
+	Limit int
+}
+
+// SubscribeRequest selects the events a subscription receives. Zero fields
+// match everything.
+type SubscribeRequest struct {
+	Repo  string   `json:"repo,omitempty"`
+	Agent string   `json:"agent,omitempty"`
+	Types []string `json:"type,omitempty"`
+}
+
+// SpawnAgentRequest starts an agent from a definition or an inline prompt.
+// Exactly one of Definition and Prompt must be set.
+type SpawnAgentRequest struct {
+	Repo       string `json:"repo"`
+	Definition string `json:"definition,omitempty"`
+	// Prompt is the agent's system prompt, optionally with YAML frontmatter
+	Prompt string `json:"prompt,omitempty"`
+	// Name defaults to the definition's name
+	Name string `json:"name,omitempty"`
+	// Class is "persistent" or "ephemeral"; optional when the definition declares it
+	Class  string         `json:"class,omitempty"`
+	Task   string         `json:"task,omitempty"`
+	Claude *ClaudeOptions `json:"claude,omitempty"`
+}
+
+// SpawnAgentResult describes a spawned agent
+type SpawnAgentResult struct {
+	Name         string `json:"name"`
+	Class        string `json:"class"`
+	Type         string `json:"type"`
+	WorktreePath string `json:"worktree_path"`
+	Definition   string `json:"definition"`
+}
+
+// CreateWorkerRequest creates a worker
+type CreateWorkerRequest struct {
+	Repo string `json:"repo"`
+	Task string `json:"task"`
+	// Name is generated if empty
+	Name   string `json:"name,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Base   string `json:"base,omitempty"`
+	PushTo string `json:"push_to,omitempty"`
+	// Documentation is appended to the worker's prompt
+	Documentation string         `json:"documentation,omitempty"`
+	Claude        *ClaudeOptions `json:"claude,omitempty"`
+}
+
+// CreatedWorker describes a worker the daemon created, or the task it
+// queued instead when the repository was at its worker limit
+type CreatedWorker struct {
+	Name         string         `json:"name"`
+	Branch       string         `json:"branch"`
+	Base         string         `json:"base"`
+	WorktreePath string         `json:"worktree_path"`
+	TmuxSession  string         `json:"tmux_session"`
+	Claude       *ClaudeOptions `json:"claude,omitempty"`
+	// Queued is set, and the other fields empty, if the worker was queued
+	Queued *QueuedTask `json:"queued,omitempty"`
+}
+
+// EnqueueTaskRequest adds a worker task to a repository's queue
+type EnqueueTaskRequest struct {
+	Repo string `json:"repo"`
+	Task string `json:"task"`
+	// Name is generated when the task starts if empty
+	Name   string `json:"name,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Base   string `json:"base,omitempty"`
+	PushTo string `json:"push_to,omitempty"`
+	// After holds the task until the named task's PR merges
+	After  string         `json:"after,omitempty"`
+	Claude *ClaudeOptions `json:"claude,omitempty"`
+	// Documentation is appended to the worker's prompt when the task starts
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// QueuedTask is a worker task waiting in a repository's queue
+type QueuedTask struct {
+	ID string `json:"id"`
+	// Position is 1 for the next task to start; 0 for cancelled tasks
+	Position   int       `json:"position"`
+	Task       string    `json:"task"`
+	Name       string    `json:"name"`
+	Branch     string    `json:"branch"`
+	Base       string    `json:"base"`
+	PushTo     string    `json:"push_to"`
+	After      string    `json:"after"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	LastError  string    `json:"last_error"`
+	// Attempts counts failed starts. RetryAt is when a task that failed to
+	// start is tried again, and zero once the daemon has given up on it.
+	Attempts int            `json:"attempts"`
+	RetryAt  time.Time      `json:"retry_at,omitempty"`
+	Claude   *ClaudeOptions `json:"claude,omitempty"`
+}
+
+// TaskQueue is a repository's task queue and worker usage
+type TaskQueue struct {
+	Tasks []QueuedTask `json:"tasks"`
+	// MaxWorkers is 0 when there is no limit
+	MaxWorkers    int `json:"max_workers"`
+	ActiveWorkers int `json:"active_workers"`
+}
+
+// UpstreamSyncResult is the outcome of syncing a fork with upstream
+type UpstreamSyncResult struct {
+	// UpstreamSHA is the upstream commit synced with
+	UpstreamSHA string `json:"upstream_sha"`
+	// SHA is the fork's new head, or empty if nothing was pushed
+	SHA string `json:"sha"`
+	// UpToDate is set if the fork already had the upstream commit
+	UpToDate bool `json:"up_to_date"`
+	// Conflicts are the files that conflicted, if the sync didn't happen
+	Conflicts []string `json:"conflicts"`
+}
+
+// RestoreSnapshotResult is the outcome of restoring a state snapshot
+type RestoreSnapshotResult struct {
+	// Restored is the ID of the snapshot that was restored
+	Restored string `json:"restored"`
+	// Backup is the ID of the snapshot of the state that was replaced
+	Backup string `json:"backup"`
+}
@@ -124,6 +124,18 @@ func (p *Paths) AgentCommandsDir(repoName, agentName string) string {
 	return filepath.Join(p.AgentClaudeConfigDir(repoName, agentName), "commands")
 }
 
+// EventsJournal returns the path to the daemon's event journal (events.jsonl).
+// Rotated journals live next to it with a timestamp suffix.
+func (p *Paths) EventsJournal() string {
+	return filepath.Join(p.Root, "events.jsonl")
+}
+
+// SnapshotsDir returns the directory holding state snapshots, one
+// subdirectory per snapshot
+func (p *Paths) SnapshotsDir() string {
+	return filepath.Join(p.Root, "snapshots")
+}
+
 // NewTestPaths creates a Paths instance for testing with all paths under tmpDir.
 // This eliminates duplicate test setup code and ensures consistent path configuration.
 func NewTestPaths(tmpDir string) *Paths {
@@ -213,6 +213,26 @@ func TestAgentCommandsDir(t *testing.T) {
 	}
 }
 
+func TestEventsJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &Paths{Root: tmpDir}
+
+	expected := filepath.Join(tmpDir, "events.jsonl")
+	if got := paths.EventsJournal(); got != expected {
+		t.Errorf("EventsJournal() = %q, want %q", got, expected)
+	}
+}
+
+func TestSnapshotsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &Paths{Root: tmpDir}
+
+	expected := filepath.Join(tmpDir, "snapshots")
+	if got := paths.SnapshotsDir(); got != expected {
+		t.Errorf("SnapshotsDir() = %q, want %q", got, expected)
+	}
+}
+
 func TestNewTestPaths(t *testing.T) {
 	tmpDir := t.TempDir()
 
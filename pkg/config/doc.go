@@ -36,6 +36,18 @@ func DirectoryDocs() []PathDoc {
 			Type:        "file",
 			Notes:       "Written atomically via temp file + rename. See StateDoc() for format details.",
 		},
+		{
+			Path:        "state.json.lock",
+			Description: "Lock file guarding state.json against concurrent writers",
+			Type:        "file",
+			Notes:       "The daemon holds an exclusive flock on it while running; commands that change state while the daemon is down take it briefly.",
+		},
+		{
+			Path:        "state.json.v<N>.bak",
+			Description: "Copy of state.json taken before migrating it from schema version N",
+			Type:        "file",
+			Notes:       "Written once per migration. Safe to delete once the migrated state works; restore it only with a multiclaude release that reads schema version N.",
+		},
 		{
 			Path:        "repos/",
 			Description: "Contains cloned git repositories (bare or working)",
@@ -84,6 +96,18 @@ func DirectoryDocs() []PathDoc {
 			Type:        "directory",
 			Notes:       "Contains msg-<uuid>.json files addressed to this agent.",
 		},
+		{
+			Path:        "messages/.dead-letters/<repo-name>/",
+			Description: "Messages that could not be delivered",
+			Type:        "directory",
+			Notes:       "Messages land here when they expire, fail delivery 5 times, or their recipient is removed. Inspect with 'multiclaude agent dead-letters'.",
+		},
+		{
+			Path:        "snapshots/",
+			Description: "Rotating snapshots of state.json and messages/",
+			Type:        "directory",
+			Notes:       "The daemon takes one at startup, every hour and before removing a repository, keeping the newest 48. Each snapshots/<id>/ holds state.json, messages/ and snapshot.json metadata. Inspect with 'multiclaude state snapshots'.",
+		},
 		{
 			Path:        "prompts/",
 			Description: "Generated prompt files for agents",
@@ -104,12 +128,15 @@ type StateFieldDoc struct {
 func StateDocs() []StateFieldDoc {
 	return []StateFieldDoc{
 		// Top level
+		{Field: "schema_version", Type: "int", Description: "State schema version; older files are migrated on load, newer ones are refused"},
 		{Field: "repos", Type: "map[string]*Repository", Description: "Map of repository name to repository state"},
 
 		// Repository fields
 		{Field: "repos.<name>.github_url", Type: "string", Description: "GitHub URL of the repository"},
 		{Field: "repos.<name>.tmux_session", Type: "string", Description: "Name of the tmux session for this repo"},
 		{Field: "repos.<name>.agents", Type: "map[string]Agent", Description: "Map of agent name to agent state"},
+		{Field: "repos.<name>.default_branch", Type: "string", Description: "Branch workers start from unless given a base (omitempty)"},
+		{Field: "repos.<name>.resolve_conflicts", Type: "bool", Description: "Whether completed workers' conflicting branches get a conflict-resolution worker (omitempty)"},
 
 		// Agent fields
 		{Field: "repos.<name>.agents.<name>.type", Type: "string", Description: "Agent type: supervisor, worker, merge-queue, or workspace"},
@@ -121,6 +148,18 @@ func StateDocs() []StateFieldDoc {
 		{Field: "repos.<name>.agents.<name>.created_at", Type: "time.Time", Description: "When the agent was created"},
 		{Field: "repos.<name>.agents.<name>.last_nudge", Type: "time.Time", Description: "Last time agent was nudged (omitempty)"},
 		{Field: "repos.<name>.agents.<name>.ready_for_cleanup", Type: "bool", Description: "Whether worker is ready to be cleaned up (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.restart_count", Type: "int", Description: "Automatic restarts since the agent last ran stably (persistent agents, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.last_restart", Type: "time.Time", Description: "When the daemon last restarted the agent (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.last_exit_reason", Type: "string", Description: "Why the agent's process last exited (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.crash_looping", Type: "bool", Description: "Whether automatic restarts stopped after repeated crashes (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.claude.model", Type: "string", Description: "Model Claude was started with via --model (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.claude.args", Type: "[]string", Description: "Extra claude CLI arguments the agent was started with (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.claude.env", Type: "map[string]string", Description: "Environment variables set for the agent's claude process (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.definition", Type: "string", Description: "Agent definition the agent was spawned from (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.wake_policy", Type: "object", Description: "Wake policy from the definition's frontmatter, overriding the repo's policy for the type (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.base_branch", Type: "string", Description: "Branch the worker is based on and opens its PR against (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.needs_rebase", Type: "bool", Description: "Whether the worker's branch conflicts with its base and must be rebased (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.rebase_conflicts", Type: "[]string", Description: "Files that conflicted when the daemon last tried to sync the branch (omitempty)"},
 	}
 }
 
@@ -141,5 +180,16 @@ func MessageDocs() []MessageFieldDoc {
 		{Field: "body", Type: "string", Description: "Message content (markdown text)"},
 		{Field: "status", Type: "string", Description: "Message status: pending, delivered, read, or acked"},
 		{Field: "acked_at", Type: "time.Time", Description: "When the message was acknowledged (omitempty)"},
+		{Field: "thread_id", Type: "string", Description: "ID of the message that started the conversation (omitempty; a message without one is its own thread)"},
+		{Field: "reply_to", Type: "string", Description: "ID of the message this one answers (omitempty)"},
+		{Field: "priority", Type: "string", Description: "Delivery priority: urgent, normal, or low (omitempty, defaults to normal). Urgent messages are delivered first"},
+		{Field: "subject", Type: "string", Description: "Optional subject; replies inherit it (omitempty)"},
+		{Field: "broadcast_id", Type: "string", Description: "Shared by every copy of a message sent to a group (`@workers`, `@persistent`, `@all`) or name glob (omitempty)"},
+		{Field: "expires_at", Type: "time.Time", Description: "When an undelivered message is dead-lettered instead (omitempty, set by `--ttl`)"},
+		{Field: "attempts", Type: "int", Description: "Failed delivery attempts so far (omitempty)"},
+		{Field: "next_attempt_at", Type: "time.Time", Description: "Earliest time of the next delivery attempt after a failure (omitempty)"},
+		{Field: "last_error", Type: "string", Description: "Error from the most recent failed delivery (omitempty)"},
+		{Field: "dead_reason", Type: "string", Description: "Why the message was dead-lettered (dead letters only)"},
+		{Field: "dead_at", Type: "time.Time", Description: "When the message was dead-lettered (dead letters only)"},
 	}
 }
@@ -186,6 +186,15 @@ func (c *Client) CreateWindow(ctx context.Context, session, windowName string) e
 	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
 }
 
+// CreateWindowInDir creates a new window in the specified session whose shell
+// starts in dir. The window is created in the background so attached clients
+// keep their current window.
+func (c *Client) CreateWindowInDir(ctx context.Context, session, windowName, dir string) error {
+	target := fmt.Sprintf("%s:", session)
+	cmd := c.tmuxCmd(ctx, "new-window", "-d", "-t", target, "-n", windowName, "-c", dir)
+	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
+}
+
 // HasWindow checks if a window with the given name exists in the session.
 // Uses exact matching via tmux format strings.
 func (c *Client) HasWindow(ctx context.Context, session, windowName string) (bool, error) {
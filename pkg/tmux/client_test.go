@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -242,6 +243,30 @@ func TestCreateWindow(t *testing.T) {
 	}
 }
 
+func TestCreateWindowInDir(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, sessionName)
+
+	dir := t.TempDir()
+	windowName := "test-dir-window"
+	if err := client.CreateWindowInDir(ctx, sessionName, windowName, dir); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	target := fmt.Sprintf("%s:%s", sessionName, windowName)
+	output, err := exec.Command("tmux", "display-message", "-p", "-t", target, "#{pane_current_path}").Output()
+	if err != nil {
+		t.Fatalf("Failed to get pane path: %v", err)
+	}
+	got, _ := filepath.EvalSymlinks(strings.TrimSpace(string(output)))
+	want, _ := filepath.EvalSymlinks(dir)
+	if got != want {
+		t.Errorf("window started in %q, want %q", got, want)
+	}
+}
+
 func TestHasWindow(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()